@@ -1,6 +1,11 @@
 package diamcircletoml
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 // DiamcircleTomlMaxSize is the maximum size of diamcircle.toml file
 const DiamcircleTomlMaxSize = 100 * 1024
@@ -34,6 +39,33 @@ type ClientInterface interface {
 // DefaultClient is a default client using the default parameters
 var DefaultClient = &Client{HTTP: http.DefaultClient}
 
+// Bool is a boolean field of a diamcircle.toml file. Besides TOML booleans it
+// decodes the strings "true" and "false", used by files written when such
+// fields were strings. An empty string decodes as false.
+type Bool bool
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (b *Bool) UnmarshalTOML(v interface{}) error {
+	switch value := v.(type) {
+	case bool:
+		*b = Bool(value)
+	case string:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			*b = false
+			return nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*b = Bool(parsed)
+	default:
+		return fmt.Errorf("invalid boolean %v", v)
+	}
+	return nil
+}
+
 type Principal struct {
 	Name                  string `toml:"name"`
 	Email                 string `toml:"email"`
@@ -45,6 +77,27 @@ type Principal struct {
 	VerificationPhotoHash string `toml:"verification_photo_hash"`
 }
 
+// Documentation holds the [DOCUMENTATION] table of a diamcircle.toml file.
+type Documentation struct {
+	OrgName                       string `toml:"ORG_NAME"`
+	OrgDba                        string `toml:"ORG_DBA"`
+	OrgUrl                        string `toml:"ORG_URL"`
+	OrgLogo                       string `toml:"ORG_LOGO"`
+	OrgDescription                string `toml:"ORG_DESCRIPTION"`
+	OrgPhysicalAddress            string `toml:"ORG_PHYSICAL_ADDRESS"`
+	OrgPhysicalAddressAttestation string `toml:"ORG_PHYSICAL_ADDRESS_ATTESTATION"`
+	OrgPhoneNumber                string `toml:"ORG_PHONE_NUMBER"`
+	OrgPhoneNumberAttestation     string `toml:"ORG_PHONE_NUMBER_ATTESTATION"`
+	OrgKeybase                    string `toml:"ORG_KEYBASE"`
+	OrgTwitter                    string `toml:"ORG_TWITTER"`
+	OrgGithub                     string `toml:"ORG_GITHUB"`
+	OrgOfficialEmail              string `toml:"ORG_OFFICIAL_EMAIL"`
+	OrgSupportEmail               string `toml:"ORG_SUPPORT_EMAIL"`
+	OrgLicensingAuthority         string `toml:"ORG_LICENSING_AUTHORITY"`
+	OrgLicenseType                string `toml:"ORG_LICENSE_TYPE"`
+	OrgLicenseNumber              string `toml:"ORG_LICENSE_NUMBER"`
+}
+
 type Currency struct {
 	Code                        string   `toml:"code"`
 	CodeTemplate                string   `toml:"code_template"`
@@ -59,12 +112,14 @@ type Currency struct {
 	MaxNumber                   int      `toml:"max_number"`
 	IsUnlimited                 bool     `toml:"is_unlimited"`
 	IsAssetAnchored             bool     `toml:"is_asset_anchored"`
+	AnchorAssetType             string   `toml:"anchor_asset_type"`
 	AnchorAsset                 string   `toml:"anchor_asset"`
+	AttestationOfReserve        string   `toml:"attestation_of_reserve"`
 	RedemptionInstructions      string   `toml:"redemption_instructions"`
 	CollateralAddresses         []string `toml:"collateral_addresses"`
 	CollateralAddressMessages   []string `toml:"collateral_address_messages"`
 	CollateralAddressSignatures []string `toml:"collateral_address_signatures"`
-	Regulated                   string   `toml:"regulated"`
+	Regulated                   Bool     `toml:"regulated"`
 	ApprovalServer              string   `toml:"APPROVAL_SERVER"`
 	ApprovalCriteria            string   `toml:"APPROVAL_CRITERIA"`
 }
@@ -80,38 +135,41 @@ type Validator struct {
 // SEP-1 commit
 // https://github.com/diamcircle/diamcircle-protocol/blob/f8993e36fa6b5b8bba1254c21c2174d250af4958/ecosystem/sep-0001.md
 type Response struct {
-	Version                       string      `toml:"VERSION"`
-	NetworkPassphrase             string      `toml:"NETWORK_PASSPHRASE"`
-	FederationServer              string      `toml:"FEDERATION_SERVER"`
-	AuthServer                    string      `toml:"AUTH_SERVER"`
-	TransferServer                string      `toml:"TRANSFER_SERVER"`
-	TransferServer0024            string      `toml:"TRANSFER_SERVER_0024"`
-	KycServer                     string      `toml:"KYC_SERVER"`
-	WebAuthEndpoint               string      `toml:"WEB_AUTH_ENDPOINT"`
-	SigningKey                    string      `toml:"SIGNING_KEY"`
-	AuroraUrl                    string      `toml:"HORIZON_URL"`
-	Accounts                      []string    `toml:"ACCOUNTS"`
-	UriRequestSigningKey          string      `toml:"URI_REQUEST_SIGNING_KEY"`
-	DirectPaymentServer           string      `toml:"DIRECT_PAYMENT_SERVER"`
-	OrgName                       string      `toml:"ORG_NAME"`
-	OrgDba                        string      `toml:"ORG_DBA"`
-	OrgUrl                        string      `toml:"ORG_URL"`
-	OrgLogo                       string      `toml:"ORG_LOGO"`
-	OrgDescription                string      `toml:"ORG_DESCRIPTION"`
-	OrgPhysicalAddress            string      `toml:"ORG_PHYSICAL_ADDRESS"`
-	OrgPhysicalAddressAttestation string      `toml:"ORG_PHYSICAL_ADDRESS_ATTESTATION"`
-	OrgPhoneNumber                string      `toml:"ORG_PHONE_NUMBER"`
-	OrgPhoneNumberAttestation     string      `toml:"ORG_PHONE_NUMBER_ATTESTATION"`
-	OrgKeybase                    string      `toml:"ORG_KEYBASE"`
-	OrgTwitter                    string      `toml:"ORG_TWITTER"`
-	OrgGithub                     string      `toml:"ORG_GITHUB"`
-	OrgOfficialEmail              string      `toml:"ORG_OFFICIAL_EMAIL"`
-	OrgLicensingAuthority         string      `toml:"ORG_LICENSING_AUTHORITY"`
-	OrgLicenseType                string      `toml:"ORG_LICENSE_TYPE"`
-	OrgLicenseNumber              string      `toml:"ORG_LICENSE_NUMBER"`
-	Principals                    []Principal `toml:"PRINCIPALS"`
-	Currencies                    []Currency  `toml:"CURRENCIES"`
-	Validators                    []Validator `toml:"VALIDATORS"`
+	Version                       string        `toml:"VERSION"`
+	NetworkPassphrase             string        `toml:"NETWORK_PASSPHRASE"`
+	FederationServer              string        `toml:"FEDERATION_SERVER"`
+	AuthServer                    string        `toml:"AUTH_SERVER"`
+	TransferServer                string        `toml:"TRANSFER_SERVER"`
+	TransferServer0024            string        `toml:"TRANSFER_SERVER_0024"`
+	TransferServerSep0024         string        `toml:"TRANSFER_SERVER_SEP0024"`
+	KycServer                     string        `toml:"KYC_SERVER"`
+	WebAuthEndpoint               string        `toml:"WEB_AUTH_ENDPOINT"`
+	SigningKey                    string        `toml:"SIGNING_KEY"`
+	AuroraUrl                     string        `toml:"HORIZON_URL"`
+	Accounts                      []string      `toml:"ACCOUNTS"`
+	UriRequestSigningKey          string        `toml:"URI_REQUEST_SIGNING_KEY"`
+	DirectPaymentServer           string        `toml:"DIRECT_PAYMENT_SERVER"`
+	AnchorQuoteServer             string        `toml:"ANCHOR_QUOTE_SERVER"`
+	Documentation                 Documentation `toml:"DOCUMENTATION"`
+	OrgName                       string        `toml:"ORG_NAME"`
+	OrgDba                        string        `toml:"ORG_DBA"`
+	OrgUrl                        string        `toml:"ORG_URL"`
+	OrgLogo                       string        `toml:"ORG_LOGO"`
+	OrgDescription                string        `toml:"ORG_DESCRIPTION"`
+	OrgPhysicalAddress            string        `toml:"ORG_PHYSICAL_ADDRESS"`
+	OrgPhysicalAddressAttestation string        `toml:"ORG_PHYSICAL_ADDRESS_ATTESTATION"`
+	OrgPhoneNumber                string        `toml:"ORG_PHONE_NUMBER"`
+	OrgPhoneNumberAttestation     string        `toml:"ORG_PHONE_NUMBER_ATTESTATION"`
+	OrgKeybase                    string        `toml:"ORG_KEYBASE"`
+	OrgTwitter                    string        `toml:"ORG_TWITTER"`
+	OrgGithub                     string        `toml:"ORG_GITHUB"`
+	OrgOfficialEmail              string        `toml:"ORG_OFFICIAL_EMAIL"`
+	OrgLicensingAuthority         string        `toml:"ORG_LICENSING_AUTHORITY"`
+	OrgLicenseType                string        `toml:"ORG_LICENSE_TYPE"`
+	OrgLicenseNumber              string        `toml:"ORG_LICENSE_NUMBER"`
+	Principals                    []Principal   `toml:"PRINCIPALS"`
+	Currencies                    []Currency    `toml:"CURRENCIES"`
+	Validators                    []Validator   `toml:"VALIDATORS"`
 }
 
 // GetDiamcircleToml returns diamcircle.toml file for a given domain
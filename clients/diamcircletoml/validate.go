@@ -0,0 +1,242 @@
+package diamcircletoml
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/diamcircle/go/strkey"
+)
+
+var (
+	assetCodeRegexp      = regexp.MustCompile(`^[a-zA-Z0-9]{1,12}$`)
+	assetCodeTemplRegexp = regexp.MustCompile(`^[a-zA-Z0-9?]{1,12}$`)
+	validatorAliasRegexp = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+	validCurrencyStatuses = map[string]bool{
+		"live":    true,
+		"dead":    true,
+		"test":    true,
+		"private": true,
+	}
+
+	validAnchorAssetTypes = map[string]bool{
+		"fiat":       true,
+		"crypto":     true,
+		"nft":        true,
+		"stock":      true,
+		"bond":       true,
+		"commodity":  true,
+		"realestate": true,
+		"other":      true,
+	}
+)
+
+// ValidationError describes a single way in which a diamcircle.toml file does
+// not conform to SEP-1.
+type ValidationError struct {
+	// Field is the toml path of the offending value, e.g. `CURRENCIES[2].issuer`.
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is returned by Response.Validate and lists every
+// violation found in a diamcircle.toml file.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the diamcircle.toml contents against SEP-1. It returns nil if
+// the file is valid and a ValidationErrors value otherwise. Only checks which
+// do not require network access are performed; use the diamcircle-toml-lint
+// tool to cross-check issuers and accounts against the network.
+func (r *Response) Validate() error {
+	v := &validator{}
+
+	if r.NetworkPassphrase == "" {
+		v.add("NETWORK_PASSPHRASE", "is required")
+	}
+
+	v.checkURL("FEDERATION_SERVER", r.FederationServer)
+	v.checkURL("AUTH_SERVER", r.AuthServer)
+	v.checkURL("TRANSFER_SERVER", r.TransferServer)
+	v.checkURL("TRANSFER_SERVER_0024", r.TransferServer0024)
+	v.checkURL("TRANSFER_SERVER_SEP0024", r.TransferServerSep0024)
+	v.checkURL("KYC_SERVER", r.KycServer)
+	v.checkURL("WEB_AUTH_ENDPOINT", r.WebAuthEndpoint)
+	v.checkURL("HORIZON_URL", r.AuroraUrl)
+	v.checkURL("DIRECT_PAYMENT_SERVER", r.DirectPaymentServer)
+	v.checkURL("ANCHOR_QUOTE_SERVER", r.AnchorQuoteServer)
+
+	v.checkAccountID("SIGNING_KEY", r.SigningKey)
+	v.checkAccountID("URI_REQUEST_SIGNING_KEY", r.UriRequestSigningKey)
+	for i, account := range r.Accounts {
+		if account == "" {
+			v.add(fmt.Sprintf("ACCOUNTS[%d]", i), "is empty")
+			continue
+		}
+		v.checkAccountID(fmt.Sprintf("ACCOUNTS[%d]", i), account)
+	}
+
+	if r.WebAuthEndpoint != "" && r.SigningKey == "" {
+		v.add("SIGNING_KEY", "is required when WEB_AUTH_ENDPOINT is set")
+	}
+
+	v.checkDocumentation(r.Documentation)
+
+	for i, principal := range r.Principals {
+		field := fmt.Sprintf("PRINCIPALS[%d]", i)
+		if principal.Name == "" {
+			v.add(field+".name", "is required")
+		}
+		if principal.Email != "" && !strings.Contains(principal.Email, "@") {
+			v.add(field+".email", "is not a valid email address")
+		}
+	}
+
+	for i, currency := range r.Currencies {
+		v.checkCurrency(fmt.Sprintf("CURRENCIES[%d]", i), currency)
+	}
+
+	aliases := map[string]bool{}
+	for i, validator := range r.Validators {
+		field := fmt.Sprintf("VALIDATORS[%d]", i)
+		if !validatorAliasRegexp.MatchString(validator.Alias) {
+			v.add(field+".ALIAS", "must match [a-z0-9-]{1,32}")
+		} else if aliases[validator.Alias] {
+			v.add(field+".ALIAS", "is not unique")
+		}
+		aliases[validator.Alias] = true
+
+		if validator.PublicKey == "" {
+			v.add(field+".PUBLIC_KEY", "is required")
+		} else {
+			v.checkAccountID(field+".PUBLIC_KEY", validator.PublicKey)
+		}
+		v.checkURL(field+".HISTORY", validator.History)
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(field, message string) {
+	v.errs = append(v.errs, ValidationError{Field: field, Message: message})
+}
+
+// checkURL reports an error if value is set and is not an absolute https URL.
+func (v *validator) checkURL(field, value string) {
+	if value == "" {
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.add(field, "is not a valid absolute URL")
+		return
+	}
+
+	if u.Scheme != "https" {
+		v.add(field, "must use https")
+	}
+}
+
+func (v *validator) checkAccountID(field, value string) {
+	if value == "" {
+		return
+	}
+
+	if !strkey.IsValidEd25519PublicKey(value) {
+		v.add(field, "is not a valid account ID")
+	}
+}
+
+func (v *validator) checkDocumentation(doc Documentation) {
+	v.checkURL("DOCUMENTATION.ORG_URL", doc.OrgUrl)
+	v.checkURL("DOCUMENTATION.ORG_LOGO", doc.OrgLogo)
+	v.checkURL("DOCUMENTATION.ORG_PHYSICAL_ADDRESS_ATTESTATION", doc.OrgPhysicalAddressAttestation)
+	v.checkURL("DOCUMENTATION.ORG_PHONE_NUMBER_ATTESTATION", doc.OrgPhoneNumberAttestation)
+
+	if doc.OrgOfficialEmail != "" && !strings.Contains(doc.OrgOfficialEmail, "@") {
+		v.add("DOCUMENTATION.ORG_OFFICIAL_EMAIL", "is not a valid email address")
+	}
+	if doc.OrgSupportEmail != "" && !strings.Contains(doc.OrgSupportEmail, "@") {
+		v.add("DOCUMENTATION.ORG_SUPPORT_EMAIL", "is not a valid email address")
+	}
+}
+
+func (v *validator) checkCurrency(field string, c Currency) {
+	switch {
+	case c.Code == "" && c.CodeTemplate == "":
+		v.add(field, "one of code or code_template is required")
+	case c.Code != "" && c.CodeTemplate != "":
+		v.add(field, "code and code_template are mutually exclusive")
+	case c.Code != "" && !assetCodeRegexp.MatchString(c.Code):
+		v.add(field+".code", "must be 1-12 alphanumeric characters")
+	case c.CodeTemplate != "" && !assetCodeTemplRegexp.MatchString(c.CodeTemplate):
+		v.add(field+".code_template", "must be 1-12 alphanumeric or '?' characters")
+	}
+
+	if c.Issuer == "" {
+		v.add(field+".issuer", "is required")
+	} else {
+		v.checkAccountID(field+".issuer", c.Issuer)
+	}
+
+	if c.Status != "" && !validCurrencyStatuses[c.Status] {
+		v.add(field+".status", "must be one of live, dead, test or private")
+	}
+
+	if c.DisplayDecimals < 0 || c.DisplayDecimals > 7 {
+		v.add(field+".display_decimals", "must be between 0 and 7")
+	}
+
+	if c.FixedNumber < 0 {
+		v.add(field+".fixed_number", "must not be negative")
+	}
+	if c.MaxNumber < 0 {
+		v.add(field+".max_number", "must not be negative")
+	}
+	if c.IsUnlimited && (c.FixedNumber > 0 || c.MaxNumber > 0) {
+		v.add(field+".is_unlimited", "conflicts with fixed_number or max_number")
+	}
+
+	v.checkURL(field+".image", c.Image)
+	v.checkURL(field+".attestation_of_reserve", c.AttestationOfReserve)
+
+	if c.AnchorAssetType != "" && !validAnchorAssetTypes[c.AnchorAssetType] {
+		v.add(field+".anchor_asset_type", "is not a recognized anchor asset type")
+	}
+	if c.IsAssetAnchored && c.AnchorAssetType == "" {
+		v.add(field+".anchor_asset_type", "is required when is_asset_anchored is true")
+	}
+
+	if len(c.CollateralAddressMessages) != 0 && len(c.CollateralAddressMessages) != len(c.CollateralAddresses) {
+		v.add(field+".collateral_address_messages", "must have one entry per collateral address")
+	}
+	if len(c.CollateralAddressSignatures) != 0 && len(c.CollateralAddressSignatures) != len(c.CollateralAddresses) {
+		v.add(field+".collateral_address_signatures", "must have one entry per collateral address")
+	}
+
+	if c.Regulated && c.ApprovalServer == "" {
+		v.add(field+".approval_server", "is required when regulated is true")
+	}
+	v.checkURL(field+".approval_server", c.ApprovalServer)
+}
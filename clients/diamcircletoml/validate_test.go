@@ -0,0 +1,130 @@
+package diamcircletoml
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validToml = `
+VERSION="2.0.0"
+NETWORK_PASSPHRASE="Test SDF Network ; September 2015"
+FEDERATION_SERVER="https://example.com/federation"
+WEB_AUTH_ENDPOINT="https://example.com/auth"
+SIGNING_KEY="GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"
+ACCOUNTS=["GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"]
+
+[DOCUMENTATION]
+ORG_NAME="Example"
+ORG_URL="https://example.com"
+ORG_OFFICIAL_EMAIL="info@example.com"
+
+[[PRINCIPALS]]
+name="Jane Jedidiah Johnson"
+email="jane@example.com"
+
+[[CURRENCIES]]
+code="USD"
+issuer="GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"
+status="live"
+display_decimals=2
+is_asset_anchored=true
+anchor_asset_type="fiat"
+regulated=true
+approval_server="https://example.com/approve"
+
+[[VALIDATORS]]
+ALIAS="example-au"
+PUBLIC_KEY="GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"
+HISTORY="https://history.example.com/"
+`
+
+func TestValidateValid(t *testing.T) {
+	var resp Response
+	_, err := toml.Decode(validToml, &resp)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Example", resp.Documentation.OrgName)
+	assert.Equal(t, "fiat", resp.Currencies[0].AnchorAssetType)
+	assert.True(t, bool(resp.Currencies[0].Regulated))
+	assert.Equal(t, "https://example.com/approve", resp.Currencies[0].ApprovalServer)
+
+	assert.NoError(t, resp.Validate())
+}
+
+func TestValidateInvalid(t *testing.T) {
+	resp := Response{
+		FederationServer: "http://example.com/federation",
+		WebAuthEndpoint:  "example.com/auth",
+		Accounts:         []string{"GABC"},
+		Documentation: Documentation{
+			OrgOfficialEmail: "not-an-email",
+		},
+		Currencies: []Currency{
+			{
+				Code:            "TOOLONGASSETCODE",
+				Issuer:          "GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5",
+				Status:          "unknown",
+				DisplayDecimals: 9,
+				Regulated:       true,
+			},
+			{
+				CodeTemplate: "CORN????????",
+			},
+		},
+		Validators: []Validator{
+			{Alias: "Bad Alias"},
+		},
+	}
+
+	err := resp.Validate()
+	require.Error(t, err)
+
+	verrs, ok := err.(ValidationErrors)
+	require.True(t, ok)
+
+	fields := map[string]string{}
+	for _, verr := range verrs {
+		fields[verr.Field] = verr.Message
+	}
+
+	assert.Equal(t, map[string]string{
+		"NETWORK_PASSPHRASE":               "is required",
+		"FEDERATION_SERVER":                "must use https",
+		"WEB_AUTH_ENDPOINT":                "is not a valid absolute URL",
+		"ACCOUNTS[0]":                      "is not a valid account ID",
+		"SIGNING_KEY":                      "is required when WEB_AUTH_ENDPOINT is set",
+		"DOCUMENTATION.ORG_OFFICIAL_EMAIL": "is not a valid email address",
+		"CURRENCIES[0].code":               "must be 1-12 alphanumeric characters",
+		"CURRENCIES[0].status":             "must be one of live, dead, test or private",
+		"CURRENCIES[0].display_decimals":   "must be between 0 and 7",
+		"CURRENCIES[0].approval_server":    "is required when regulated is true",
+		"CURRENCIES[1].issuer":             "is required",
+		"VALIDATORS[0].ALIAS":              "must match [a-z0-9-]{1,32}",
+		"VALIDATORS[0].PUBLIC_KEY":         "is required",
+	}, fields)
+}
+
+func TestDecodeStringRegulated(t *testing.T) {
+	for _, testCase := range []struct {
+		value    string
+		expected bool
+	}{
+		{`true`, true},
+		{`false`, false},
+		{`"true"`, true},
+		{`"false"`, false},
+		{`""`, false},
+	} {
+		var resp Response
+		_, err := toml.Decode("[[CURRENCIES]]\nregulated="+testCase.value, &resp)
+		require.NoError(t, err, testCase.value)
+		assert.Equal(t, testCase.expected, bool(resp.Currencies[0].Regulated), testCase.value)
+	}
+
+	var resp Response
+	_, err := toml.Decode("[[CURRENCIES]]\nregulated=\"maybe\"", &resp)
+	assert.Error(t, err)
+}
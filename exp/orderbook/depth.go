@@ -0,0 +1,68 @@
+package orderbook
+
+import (
+	"math"
+
+	"github.com/diamcircle/go/xdr"
+)
+
+// PoolPriceLevel is a price level synthesized from the reserves of a
+// constant product liquidity pool.
+type PoolPriceLevel struct {
+	// Amount is the amount of the disbursed asset the pool pays out at this
+	// level.
+	Amount xdr.Int64
+	// Cost is the amount of the deposited asset which must be put into the
+	// pool to receive Amount.
+	Cost xdr.Int64
+}
+
+// PoolPriceLevels synthesizes up to `levels` consecutive price levels from
+// a constant product liquidity pool holding `depositReserve` of the asset
+// deposited into the pool and `disburseReserve` of the asset paid out by it.
+//
+// Each level covers the amount paid out while the marginal price of the pool
+// rises by another `stepBips` basis points. The cost of each level is
+// computed with calculatePoolExpectation, so the levels add up to exactly
+// what the pool would charge for a single trade of the same total amount.
+// Levels are dropped when the reserves are too small to pay out anything at
+// that step, and generation stops if the computation overflows.
+func PoolPriceLevels(
+	depositReserve, disburseReserve xdr.Int64,
+	feeBips xdr.Int32,
+	stepBips uint32,
+	levels int,
+) []PoolPriceLevel {
+	if depositReserve <= 0 || disburseReserve <= 0 || stepBips == 0 {
+		return nil
+	}
+
+	var result []PoolPriceLevel
+	var paidOut, deposited xdr.Int64
+	step := 1 + float64(stepBips)/10000
+	// Ignoring fees, the product of the reserves is constant, so the
+	// marginal price grows with the inverse square of the remaining
+	// disbursed reserve: the price is multiplied by step^i once
+	// disburseReserve * (1 - step^(-i/2)) has been paid out.
+	for i := 1; len(result) < levels && i <= 10*levels; i++ {
+		target := float64(disburseReserve) * (1 - math.Pow(step, -float64(i)/2))
+		disbursed := xdr.Int64(target)
+		if disbursed <= paidOut {
+			continue
+		}
+		if disbursed >= disburseReserve {
+			break
+		}
+
+		cost, ok := calculatePoolExpectation(depositReserve, disburseReserve, disbursed, feeBips)
+		if !ok {
+			break
+		}
+		result = append(result, PoolPriceLevel{
+			Amount: disbursed - paidOut,
+			Cost:   cost - deposited,
+		})
+		paidOut, deposited = disbursed, cost
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolPriceLevels(t *testing.T) {
+	levels := PoolPriceLevels(1000000000, 1000000000, xdr.LiquidityPoolFeeV18, 100, 3)
+	assert.Equal(t, []PoolPriceLevel{
+		{Amount: 4962809, Cost: 5002570},
+		{Amount: 4938181, Cost: 5027521},
+		{Amount: 4913673, Cost: 5052595},
+	}, levels)
+
+	// the levels add up to a single trade of the same size
+	var amount, cost xdr.Int64
+	for _, level := range levels {
+		amount += level.Amount
+		cost += level.Cost
+	}
+	expected, ok := calculatePoolExpectation(1000000000, 1000000000, amount, xdr.LiquidityPoolFeeV18)
+	assert.True(t, ok)
+	assert.Equal(t, expected, cost)
+
+	// reserves too small to fill a level are skipped
+	assert.Equal(t, []PoolPriceLevel{
+		{Amount: 1, Cost: 2},
+		{Amount: 1, Cost: 1},
+	}, PoolPriceLevels(100, 100, xdr.LiquidityPoolFeeV18, 100, 2))
+
+	assert.Empty(t, PoolPriceLevels(0, 100, xdr.LiquidityPoolFeeV18, 100, 5))
+	assert.Empty(t, PoolPriceLevels(100, 100, xdr.LiquidityPoolFeeV18, 0, 5))
+}
@@ -3,6 +3,7 @@ package orderbook
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"sync"
 
@@ -46,6 +47,9 @@ type OBGraph interface {
 	RemoveLiquidityPool(pool xdr.LiquidityPoolEntry) OBGraph
 	Verify() ([]xdr.OfferEntry, []xdr.LiquidityPoolEntry, error)
 	Clear()
+	LastLedger() uint32
+	WriteSnapshot(w io.Writer) error
+	ReadSnapshot(r io.Reader) error
 }
 
 // OrderBookGraph is an in-memory graph representation of all the offers in the
@@ -166,6 +170,45 @@ func (graph *OrderBookGraph) Offers() []xdr.OfferEntry {
 	return offers
 }
 
+// LastLedger returns the ledger sequence the graph is accurate up to, or 0 if
+// no updates have been applied yet.
+func (graph *OrderBookGraph) LastLedger() uint32 {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+	return graph.lastLedger
+}
+
+// OrderBook returns the offers selling `selling` for `buying`, sorted from the
+// cheapest to the most expensive, and the liquidity pool between the two
+// assets if there is one.
+func (graph *OrderBookGraph) OrderBook(selling, buying xdr.Asset) ([]xdr.OfferEntry, *xdr.LiquidityPoolEntry) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	sellingID, ok := graph.assetStringToID[selling.String()]
+	if !ok {
+		return nil, nil
+	}
+	buyingID, ok := graph.assetStringToID[buying.String()]
+	if !ok {
+		return nil, nil
+	}
+
+	edges := graph.venuesForSellingAsset[sellingID]
+	i := edges.find(buyingID)
+	if i < 0 {
+		return nil, nil
+	}
+
+	venues := edges[i].value
+	offers := append([]xdr.OfferEntry(nil), venues.offers...)
+	if venues.pool.Body.ConstantProduct == nil {
+		return offers, nil
+	}
+	pool := venues.pool.LiquidityPoolEntry
+	return offers, &pool
+}
+
 // Verify checks the internal consistency of the OrderBookGraph data structures
 // and returns all the offers and pools contained in the graph.
 func (graph *OrderBookGraph) Verify() ([]xdr.OfferEntry, []xdr.LiquidityPoolEntry, error) {
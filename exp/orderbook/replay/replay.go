@@ -0,0 +1,233 @@
+// Package replay rebuilds the order book graph as it was at any ledger.
+//
+// The graph is first populated from a history archive checkpoint, or from a
+// snapshot written by an earlier replay, and the offer and liquidity pool
+// changes of the following ledgers are then applied one ledger at a time.
+package replay
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// Config configures a Replayer.
+type Config struct {
+	// HistoryArchive is used to populate the graph from checkpoints.
+	HistoryArchive historyarchive.ArchiveInterface
+	// LedgerBackend provides the ledgers applied after a checkpoint or
+	// snapshot. Ranges which are not prepared yet are prepared by Seek.
+	LedgerBackend     ledgerbackend.LedgerBackend
+	NetworkPassphrase string
+	// SnapshotDir is the directory snapshots are loaded from and saved to.
+	// Snapshots are not used if it is empty.
+	SnapshotDir string
+	// SnapshotInterval is the number of ledgers between saved snapshots.
+	// Snapshots are saved for every ledger which is a multiple of the
+	// interval. No snapshots are saved if it is 0.
+	SnapshotInterval uint32
+}
+
+type changeReaderFactory func(ctx context.Context, sequence uint32) (ingest.ChangeReader, error)
+
+// Replayer maintains an order book graph which can be moved to any ledger
+// covered by the history archive and the ledger backend.
+type Replayer struct {
+	config      Config
+	graph       *orderbook.OrderBookGraph
+	checkpoints historyarchive.CheckpointManager
+	snapshots   *snapshotStore
+
+	newCheckpointReader changeReaderFactory
+	newLedgerReader     changeReaderFactory
+}
+
+// NewReplayer constructs a Replayer. The graph is empty until Seek is called.
+func NewReplayer(config Config) (*Replayer, error) {
+	if config.HistoryArchive == nil {
+		return nil, errors.New("history archive is required")
+	}
+	if config.LedgerBackend == nil {
+		return nil, errors.New("ledger backend is required")
+	}
+	if config.NetworkPassphrase == "" {
+		return nil, errors.New("network passphrase is required")
+	}
+
+	r := &Replayer{
+		config:      config,
+		graph:       orderbook.NewOrderBookGraph(),
+		checkpoints: config.HistoryArchive.GetCheckpointManager(),
+	}
+	if config.SnapshotDir != "" {
+		r.snapshots = &snapshotStore{dir: config.SnapshotDir}
+	}
+	r.newCheckpointReader = func(ctx context.Context, sequence uint32) (ingest.ChangeReader, error) {
+		return ingest.NewCheckpointChangeReader(ctx, config.HistoryArchive, sequence)
+	}
+	r.newLedgerReader = func(ctx context.Context, sequence uint32) (ingest.ChangeReader, error) {
+		return ingest.NewLedgerChangeReader(ctx, config.LedgerBackend, config.NetworkPassphrase, sequence)
+	}
+	return r, nil
+}
+
+// Graph returns the order book graph. It reflects the ledger returned by
+// Ledger and must not be modified by the caller.
+func (r *Replayer) Graph() *orderbook.OrderBookGraph {
+	return r.graph
+}
+
+// Ledger returns the ledger the graph is currently at, or 0 if Seek has not
+// been called yet.
+func (r *Replayer) Ledger() uint32 {
+	return r.graph.LastLedger()
+}
+
+// LatestSnapshot returns the ledger of the most recent snapshot in the
+// snapshot directory, or 0 if there is none or snapshots are disabled.
+func (r *Replayer) LatestSnapshot() (uint32, error) {
+	if r.snapshots == nil {
+		return 0, nil
+	}
+	return r.snapshots.latest(math.MaxUint32)
+}
+
+// Seek moves the graph to the state at the end of the given ledger. Moving
+// forward applies the ledgers in between; otherwise the graph is rebuilt from
+// the most recent checkpoint or snapshot preceding the ledger, whichever is
+// closer.
+func (r *Replayer) Seek(ctx context.Context, ledger uint32) error {
+	checkpoint := r.checkpoints.PrevCheckpoint(ledger)
+	if checkpoint > ledger {
+		return errors.Errorf("ledger %d precedes the first checkpoint", ledger)
+	}
+
+	current := r.Ledger()
+	if current > ledger {
+		current = 0
+	}
+
+	var snapshot uint32
+	if r.snapshots != nil {
+		var err error
+		if snapshot, err = r.snapshots.latest(ledger); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case current >= checkpoint && current >= snapshot:
+	case snapshot >= checkpoint:
+		if err := r.snapshots.load(r.graph, snapshot); err != nil {
+			return errors.Wrapf(err, "could not load snapshot for ledger %d", snapshot)
+		}
+	default:
+		if err := r.loadCheckpoint(ctx, checkpoint); err != nil {
+			return errors.Wrapf(err, "could not load checkpoint %d", checkpoint)
+		}
+	}
+
+	from := r.Ledger() + 1
+	if from > ledger {
+		return nil
+	}
+	ledgerRange := ledgerbackend.BoundedRange(from, ledger)
+	prepared, err := r.config.LedgerBackend.IsPrepared(ctx, ledgerRange)
+	if err != nil {
+		return errors.Wrap(err, "could not check if ledger range is prepared")
+	}
+	if !prepared {
+		if err = r.config.LedgerBackend.PrepareRange(ctx, ledgerRange); err != nil {
+			return errors.Wrap(err, "could not prepare ledger range")
+		}
+	}
+
+	for sequence := from; sequence <= ledger; sequence++ {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = r.applyLedger(ctx, sequence); err != nil {
+			return errors.Wrapf(err, "could not apply ledger %d", sequence)
+		}
+		if r.snapshots != nil && r.config.SnapshotInterval > 0 &&
+			sequence%r.config.SnapshotInterval == 0 {
+			if err = r.snapshots.save(r.graph); err != nil {
+				return errors.Wrapf(err, "could not save snapshot for ledger %d", sequence)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Replayer) loadCheckpoint(ctx context.Context, checkpoint uint32) error {
+	reader, err := r.newCheckpointReader(ctx, checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "could not create checkpoint change reader")
+	}
+	defer reader.Close()
+
+	r.graph.Clear()
+	defer r.graph.Discard()
+
+	for {
+		change, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read change")
+		}
+
+		switch change.Type {
+		case xdr.LedgerEntryTypeOffer:
+			r.graph.AddOffers(change.Post.Data.MustOffer())
+		case xdr.LedgerEntryTypeLiquidityPool:
+			r.graph.AddLiquidityPools(change.Post.Data.MustLiquidityPool())
+		}
+	}
+
+	return r.graph.Apply(checkpoint)
+}
+
+func (r *Replayer) applyLedger(ctx context.Context, sequence uint32) error {
+	reader, err := r.newLedgerReader(ctx, sequence)
+	if err != nil {
+		return errors.Wrap(err, "could not create ledger change reader")
+	}
+	defer reader.Close()
+	defer r.graph.Discard()
+
+	for {
+		change, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read change")
+		}
+
+		switch change.Type {
+		case xdr.LedgerEntryTypeOffer:
+			if change.Post == nil {
+				r.graph.RemoveOffer(change.Pre.Data.MustOffer().OfferId)
+			} else {
+				r.graph.AddOffers(change.Post.Data.MustOffer())
+			}
+		case xdr.LedgerEntryTypeLiquidityPool:
+			if change.Post == nil {
+				r.graph.RemoveLiquidityPool(change.Pre.Data.MustLiquidityPool())
+			} else {
+				r.graph.AddLiquidityPools(change.Post.Data.MustLiquidityPool())
+			}
+		}
+	}
+
+	return r.graph.Apply(sequence)
+}
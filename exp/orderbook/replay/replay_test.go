@@ -0,0 +1,164 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/network"
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	seller   = xdr.MustAddress("GAXI33UCLQTCKM2NMRBS7XYBR535LLEVAHL5YBN4FTCB4HZHT7ZA5CVK")
+	usdAsset = xdr.MustNewCreditAsset("USD", seller.Address())
+)
+
+func offer(id xdr.Int64, amount xdr.Int64) xdr.OfferEntry {
+	return xdr.OfferEntry{
+		SellerId: seller,
+		OfferId:  id,
+		Buying:   usdAsset,
+		Selling:  xdr.MustNewNativeAsset(),
+		Price:    xdr.Price{N: xdr.Int32(id), D: 1},
+		Amount:   amount,
+	}
+}
+
+func offerEntry(o xdr.OfferEntry) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type:  xdr.LedgerEntryTypeOffer,
+			Offer: &o,
+		},
+	}
+}
+
+func mockReader(changes ...ingest.Change) *ingest.MockChangeReader {
+	reader := &ingest.MockChangeReader{}
+	for _, change := range changes {
+		reader.On("Read").Return(change, nil).Once()
+	}
+	reader.On("Read").Return(ingest.Change{}, io.EOF).Once()
+	reader.On("Close").Return(nil).Once()
+	return reader
+}
+
+type replayTestSuite struct {
+	replayer *Replayer
+	backend  *ledgerbackend.MockDatabaseBackend
+	// ledgers maps a ledger sequence to the changes in that ledger
+	ledgers     map[uint32][]ingest.Change
+	checkpoints []uint32
+}
+
+func newReplayTestSuite(t *testing.T, snapshotDir string) *replayTestSuite {
+	archive := &historyarchive.MockArchive{}
+	archive.On("GetCheckpointManager").
+		Return(historyarchive.NewCheckpointManager(historyarchive.DefaultCheckpointFrequency))
+	backend := &ledgerbackend.MockDatabaseBackend{}
+
+	replayer, err := NewReplayer(Config{
+		HistoryArchive:    archive,
+		LedgerBackend:     backend,
+		NetworkPassphrase: network.TestNetworkPassphrase,
+		SnapshotDir:       snapshotDir,
+		SnapshotInterval:  10,
+	})
+	require.NoError(t, err)
+
+	s := &replayTestSuite{
+		replayer: replayer,
+		backend:  backend,
+		ledgers: map[uint32][]ingest.Change{
+			64: {{
+				Type: xdr.LedgerEntryTypeOffer,
+				Post: offerEntry(offer(2, 200)),
+			}},
+			70: {{
+				Type: xdr.LedgerEntryTypeOffer,
+				Pre:  offerEntry(offer(1, 100)),
+				Post: offerEntry(offer(1, 50)),
+			}},
+			80: {{
+				Type: xdr.LedgerEntryTypeOffer,
+				Pre:  offerEntry(offer(2, 200)),
+			}},
+		},
+	}
+	replayer.newCheckpointReader = func(ctx context.Context, sequence uint32) (ingest.ChangeReader, error) {
+		assert.Equal(t, uint32(63), sequence)
+		s.checkpoints = append(s.checkpoints, sequence)
+		return mockReader(ingest.Change{
+			Type: xdr.LedgerEntryTypeOffer,
+			Post: offerEntry(offer(1, 100)),
+		}), nil
+	}
+	replayer.newLedgerReader = func(ctx context.Context, sequence uint32) (ingest.ChangeReader, error) {
+		return mockReader(s.ledgers[sequence]...), nil
+	}
+	return s
+}
+
+func TestSeek(t *testing.T) {
+	ctx := context.Background()
+	s := newReplayTestSuite(t, "")
+	s.backend.On("IsPrepared", ctx, mock.Anything).Return(false, nil)
+	s.backend.On("PrepareRange", ctx, mock.Anything).Return(nil)
+
+	assert.EqualError(t, s.replayer.Seek(ctx, 10), "ledger 10 precedes the first checkpoint")
+
+	require.NoError(t, s.replayer.Seek(ctx, 63))
+	assert.Equal(t, uint32(63), s.replayer.Ledger())
+	assert.Equal(t, []xdr.OfferEntry{offer(1, 100)}, s.replayer.Graph().Offers())
+
+	require.NoError(t, s.replayer.Seek(ctx, 75))
+	assert.Equal(t, uint32(75), s.replayer.Ledger())
+	assert.ElementsMatch(t, []xdr.OfferEntry{offer(1, 50), offer(2, 200)}, s.replayer.Graph().Offers())
+
+	require.NoError(t, s.replayer.Seek(ctx, 90))
+	assert.Equal(t, []xdr.OfferEntry{offer(1, 50)}, s.replayer.Graph().Offers())
+
+	// seeking backwards rebuilds the graph from the checkpoint
+	require.NoError(t, s.replayer.Seek(ctx, 65))
+	assert.ElementsMatch(t, []xdr.OfferEntry{offer(1, 100), offer(2, 200)}, s.replayer.Graph().Offers())
+	assert.Equal(t, []uint32{63, 63}, s.checkpoints)
+
+	s.backend.AssertCalled(t, "PrepareRange", ctx, ledgerbackend.BoundedRange(64, 75))
+	s.backend.AssertCalled(t, "PrepareRange", ctx, ledgerbackend.BoundedRange(76, 90))
+	s.backend.AssertCalled(t, "PrepareRange", ctx, ledgerbackend.BoundedRange(64, 65))
+}
+
+func TestSeekFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "orderbook-replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newReplayTestSuite(t, dir)
+	s.backend.On("IsPrepared", ctx, mock.Anything).Return(true, nil)
+
+	require.NoError(t, s.replayer.Seek(ctx, 85))
+	ledgers, err := s.replayer.snapshots.ledgers()
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{70, 80}, ledgers)
+	latest, err := s.replayer.LatestSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(80), latest)
+
+	// a new replayer starts from the closest snapshot instead of the checkpoint
+	other := newReplayTestSuite(t, dir)
+	other.backend.On("IsPrepared", ctx, mock.Anything).Return(true, nil)
+	require.NoError(t, other.replayer.Seek(ctx, 75))
+	assert.Empty(t, other.checkpoints)
+	assert.ElementsMatch(t, []xdr.OfferEntry{offer(1, 50), offer(2, 200)}, other.replayer.Graph().Offers())
+	other.backend.AssertCalled(t, "IsPrepared", ctx, ledgerbackend.BoundedRange(71, 75))
+}
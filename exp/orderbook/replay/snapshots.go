@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/support/errors"
+)
+
+const (
+	snapshotFilePrefix = "orderbook-"
+	snapshotFileSuffix = ".snapshot"
+)
+
+// snapshotStore keeps order book snapshots in a directory, one file per
+// ledger.
+type snapshotStore struct {
+	dir string
+}
+
+func (s snapshotStore) path(ledger uint32) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%010d%s", snapshotFilePrefix, ledger, snapshotFileSuffix))
+}
+
+// ledgers returns the ledgers of all the snapshots in the directory in
+// ascending order.
+func (s snapshotStore) ledgers() ([]uint32, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list snapshots")
+	}
+
+	var ledgers []uint32
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, snapshotFilePrefix) ||
+			!strings.HasSuffix(name, snapshotFileSuffix) {
+			continue
+		}
+		ledger, err := strconv.ParseUint(
+			strings.TrimSuffix(strings.TrimPrefix(name, snapshotFilePrefix), snapshotFileSuffix), 10, 32,
+		)
+		if err != nil {
+			continue
+		}
+		ledgers = append(ledgers, uint32(ledger))
+	}
+	sort.Slice(ledgers, func(i, j int) bool { return ledgers[i] < ledgers[j] })
+	return ledgers, nil
+}
+
+// latest returns the most recent snapshot ledger which is not after `ledger`,
+// or 0 if there is none.
+func (s snapshotStore) latest(ledger uint32) (uint32, error) {
+	ledgers, err := s.ledgers()
+	if err != nil {
+		return 0, err
+	}
+	var found uint32
+	for _, l := range ledgers {
+		if l > ledger {
+			break
+		}
+		found = l
+	}
+	return found, nil
+}
+
+func (s snapshotStore) load(graph *orderbook.OrderBookGraph, ledger uint32) error {
+	file, err := os.Open(s.path(ledger))
+	if err != nil {
+		return errors.Wrap(err, "could not open snapshot")
+	}
+	defer file.Close()
+	return graph.ReadSnapshot(file)
+}
+
+// save writes a snapshot of the graph. The snapshot is written to a temporary
+// file first so a partially written snapshot is never picked up by load.
+func (s snapshotStore) save(graph *orderbook.OrderBookGraph) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return errors.Wrap(err, "could not create snapshot directory")
+	}
+	file, err := ioutil.TempFile(s.dir, "orderbook-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create snapshot file")
+	}
+	defer os.Remove(file.Name())
+
+	if err = graph.WriteSnapshot(file); err != nil {
+		file.Close()
+		return errors.Wrap(err, "could not write snapshot")
+	}
+	if err = file.Close(); err != nil {
+		return errors.Wrap(err, "could not write snapshot")
+	}
+	return os.Rename(file.Name(), s.path(graph.LastLedger()))
+}
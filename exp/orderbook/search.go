@@ -428,48 +428,16 @@ func consumeOffersForSellingAsset(
 		return 0, errAssetAmountIsZero
 	}
 
-	totalConsumed := xdr.Int64(0)
-	for i := 0; i < len(offers); i++ {
-		if ignoreOffersFrom != nil && ignoreOffersFrom.Equals(offers[i].SellerId) {
-			continue
-		}
-
-		buyingUnitsFromOffer, sellingUnitsFromOffer, err := price.ConvertToBuyingUnits(
-			int64(offers[i].Amount),
-			int64(currentAssetAmount),
-			int64(offers[i].Price.N),
-			int64(offers[i].Price.D),
-		)
-		if err == price.ErrOverflow {
-			// skip paths which would result in overflow errors
-			// but still continue the path finding search
-			return -1, nil
-		} else if err != nil {
-			return -1, err
-		}
-
-		totalConsumed += xdr.Int64(buyingUnitsFromOffer)
-
-		// For sell-state, we are aiming to *minimize* the amount of the source
-		// assets we need to get to the destination, so if we exceed the best
-		// amount, it's time to bail.
-		//
-		// FIXME: Evaluate if this can work, and if it's actually performant.
-		// if totalConsumed >= currentBestAmount && currentBestAmount > 0 {
-		// 	return currentBestAmount, nil
-		// }
-
-		currentAssetAmount -= xdr.Int64(sellingUnitsFromOffer)
-
-		if currentAssetAmount == 0 {
-			return totalConsumed, nil
-		}
-		if currentAssetAmount < 0 {
-			return -1, errSoldTooMuch
-		}
-	}
-
-	return -1, nil
+	// For sell-state, we are aiming to *minimize* the amount of the source
+	// assets we need to get to the destination, so if we exceed the best
+	// amount, it's time to bail.
+	//
+	// FIXME: Evaluate if this can work, and if it's actually performant.
+	// if totalConsumed >= currentBestAmount && currentBestAmount > 0 {
+	// 	return currentBestAmount, nil
+	// }
+	totalConsumed, _, _, err := receiveFromOffers(offers, ignoreOffersFrom, currentAssetAmount)
+	return totalConsumed, err
 }
 
 func consumeOffersForBuyingAsset(
@@ -484,30 +452,46 @@ func consumeOffersForBuyingAsset(
 		return 0, errAssetAmountIsZero
 	}
 
-	totalConsumed := xdr.Int64(0)
+	totalConsumed, _, _, err := sendToOffers(offers, nil, currentAssetAmount)
+	return totalConsumed, err
+}
+
+// sendToOffers sells `amount` of the buying asset of the offers, which are
+// sorted by price, and returns the amount of the selling asset received. It
+// also returns the index of the last offer traded with and the amount left
+// in it. Offers preceding that index are consumed entirely. The amount
+// received is -1 if the offers cannot absorb `amount`.
+func sendToOffers(
+	offers []xdr.OfferEntry,
+	ignoreOffersFrom *xdr.AccountId,
+	amount xdr.Int64,
+) (xdr.Int64, int, xdr.Int64, error) {
+	received := xdr.Int64(0)
 	for i := 0; i < len(offers); i++ {
+		if ignoreOffersFrom != nil && ignoreOffersFrom.Equals(offers[i].SellerId) {
+			continue
+		}
 		n := int64(offers[i].Price.N)
 		d := int64(offers[i].Price.D)
 
-		// check if we can spend all of currentAssetAmount on the current offer
+		// check if we can spend the rest of amount on the current offer
 		// otherwise consume entire offer and move on to the next one
-		amountSold, err := price.MulFractionRoundDown(int64(currentAssetAmount), d, n)
+		amountSold, err := price.MulFractionRoundDown(int64(amount), d, n)
 		if err == nil {
 			if amountSold == 0 {
 				// not enough of the buying asset to consume the offer
-				return -1, nil
+				return -1, 0, 0, nil
 			}
 			if amountSold < 0 {
-				return -1, errSoldTooMuch
+				return -1, 0, 0, errSoldTooMuch
 			}
 
 			amountSoldXDR := xdr.Int64(amountSold)
 			if amountSoldXDR <= offers[i].Amount {
-				totalConsumed += amountSoldXDR
-				return totalConsumed, nil
+				return received + amountSoldXDR, i, offers[i].Amount - amountSoldXDR, nil
 			}
 		} else if err != price.ErrOverflow {
-			return -1, err
+			return -1, 0, 0, err
 		}
 
 		buyingUnitsFromOffer, sellingUnitsFromOffer, err := price.ConvertToBuyingUnits(
@@ -519,23 +503,65 @@ func consumeOffersForBuyingAsset(
 		if err == price.ErrOverflow {
 			// skip paths which would result in overflow errors
 			// but still continue the path finding search
-			return -1, nil
+			return -1, 0, 0, nil
+		} else if err != nil {
+			return -1, 0, 0, err
+		}
+
+		received += xdr.Int64(sellingUnitsFromOffer)
+		amount -= xdr.Int64(buyingUnitsFromOffer)
+
+		if amount == 0 {
+			return received, i, 0, nil
+		}
+		if amount < 0 {
+			return -1, 0, 0, errSoldTooMuch
+		}
+	}
+
+	return -1, 0, 0, nil
+}
+
+// receiveFromOffers buys `amount` of the selling asset of the offers, which
+// are sorted by price, and returns the amount of the buying asset spent. The
+// other results are the same as in sendToOffers.
+func receiveFromOffers(
+	offers []xdr.OfferEntry,
+	ignoreOffersFrom *xdr.AccountId,
+	amount xdr.Int64,
+) (xdr.Int64, int, xdr.Int64, error) {
+	spent := xdr.Int64(0)
+	for i := 0; i < len(offers); i++ {
+		if ignoreOffersFrom != nil && ignoreOffersFrom.Equals(offers[i].SellerId) {
+			continue
+		}
+
+		buyingUnitsFromOffer, sellingUnitsFromOffer, err := price.ConvertToBuyingUnits(
+			int64(offers[i].Amount),
+			int64(amount),
+			int64(offers[i].Price.N),
+			int64(offers[i].Price.D),
+		)
+		if err == price.ErrOverflow {
+			// skip paths which would result in overflow errors
+			// but still continue the path finding search
+			return -1, 0, 0, nil
 		} else if err != nil {
-			return -1, err
+			return -1, 0, 0, err
 		}
 
-		totalConsumed += xdr.Int64(sellingUnitsFromOffer)
-		currentAssetAmount -= xdr.Int64(buyingUnitsFromOffer)
+		spent += xdr.Int64(buyingUnitsFromOffer)
+		amount -= xdr.Int64(sellingUnitsFromOffer)
 
-		if currentAssetAmount == 0 {
-			return totalConsumed, nil
+		if amount == 0 {
+			return spent, i, offers[i].Amount - xdr.Int64(sellingUnitsFromOffer), nil
 		}
-		if currentAssetAmount < 0 {
-			return -1, errSoldTooMuch
+		if amount < 0 {
+			return -1, 0, 0, errSoldTooMuch
 		}
 	}
 
-	return -1, nil
+	return -1, 0, 0, nil
 }
 
 func processVenues(
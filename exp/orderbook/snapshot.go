@@ -0,0 +1,140 @@
+package orderbook
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// snapshotMagic identifies order book snapshots and their format version.
+var snapshotMagic = [4]byte{'O', 'B', 'S', '1'}
+
+// maxSnapshotEntrySize bounds the size of a snapshot entry so that a corrupt
+// frame length does not allocate gigabytes. Offers and pools are far smaller.
+const maxSnapshotEntrySize = 64 * 1024
+
+var (
+	errInvalidSnapshot = errors.New("invalid order book snapshot")
+	errEmptySnapshot   = errors.New("cannot snapshot an order book which has not been applied to a ledger")
+)
+
+// WriteSnapshot writes a gzip compressed snapshot of all the offers and
+// liquidity pools in the graph, along with the ledger the graph is accurate
+// up to. Updates which are queued but not applied are not included.
+//
+// The snapshot is made of the magic bytes "OBS1", the big endian ledger
+// sequence and one framed XDR LedgerEntryData per offer and pool.
+func (graph *OrderBookGraph) WriteSnapshot(w io.Writer) error {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	if graph.lastLedger == 0 {
+		return errEmptySnapshot
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	if _, err := bw.Write(snapshotMagic[:]); err != nil {
+		return errors.Wrap(err, "could not write snapshot header")
+	}
+	if err := binary.Write(bw, binary.BigEndian, graph.lastLedger); err != nil {
+		return errors.Wrap(err, "could not write snapshot header")
+	}
+
+	for _, edges := range graph.venuesForSellingAsset {
+		for _, edge := range edges {
+			for i := range edge.value.offers {
+				entry := xdr.LedgerEntryData{
+					Type:  xdr.LedgerEntryTypeOffer,
+					Offer: &edge.value.offers[i],
+				}
+				if err := xdr.MarshalFramed(bw, entry); err != nil {
+					return errors.Wrap(err, "could not write offer")
+				}
+			}
+		}
+	}
+	for _, pool := range graph.liquidityPools {
+		pool := pool
+		entry := xdr.LedgerEntryData{
+			Type:          xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &pool,
+		}
+		if err := xdr.MarshalFramed(bw, entry); err != nil {
+			return errors.Wrap(err, "could not write liquidity pool")
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "could not write snapshot")
+	}
+	return zw.Close()
+}
+
+// ReadSnapshot replaces the contents of the graph with a snapshot written by
+// WriteSnapshot. Any queued updates are discarded. Once the snapshot is read
+// the graph is accurate up to the ledger of the snapshot.
+func (graph *OrderBookGraph) ReadSnapshot(r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "could not open snapshot")
+	}
+	defer zr.Close()
+	br := bufio.NewReader(zr)
+
+	var magic [4]byte
+	if _, err = io.ReadFull(br, magic[:]); err != nil {
+		return errors.Wrap(err, "could not read snapshot header")
+	}
+	if magic != snapshotMagic {
+		return errInvalidSnapshot
+	}
+	var ledger uint32
+	if err = binary.Read(br, binary.BigEndian, &ledger); err != nil {
+		return errors.Wrap(err, "could not read snapshot header")
+	}
+	if ledger == 0 {
+		return errInvalidSnapshot
+	}
+
+	graph.Clear()
+	defer graph.Discard()
+
+	for {
+		var frameLen uint32
+		err = binary.Read(br, binary.BigEndian, &frameLen)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not read snapshot entry")
+		}
+		if frameLen&0x80000000 == 0 || frameLen&0x7fffffff > maxSnapshotEntrySize {
+			return errInvalidSnapshot
+		}
+
+		raw := make([]byte, frameLen&0x7fffffff)
+		if _, err = io.ReadFull(br, raw); err != nil {
+			return errors.Wrap(err, "could not read snapshot entry")
+		}
+		var entry xdr.LedgerEntryData
+		if err = xdr.SafeUnmarshal(raw, &entry); err != nil {
+			return errors.Wrap(err, "could not decode snapshot entry")
+		}
+
+		switch entry.Type {
+		case xdr.LedgerEntryTypeOffer:
+			graph.AddOffers(entry.MustOffer())
+		case xdr.LedgerEntryTypeLiquidityPool:
+			graph.AddLiquidityPools(entry.MustLiquidityPool())
+		default:
+			return errInvalidSnapshot
+		}
+	}
+
+	return graph.Apply(ledger)
+}
@@ -0,0 +1,95 @@
+package orderbook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	graph := NewOrderBookGraph()
+
+	var buf bytes.Buffer
+	assert.Equal(t, errEmptySnapshot, graph.WriteSnapshot(&buf))
+
+	graph.AddOffers(fiftyCentsOffer, quarterOffer, dollarOffer, eurOffer, twoEurOffer, threeEurOffer)
+	graph.AddLiquidityPools(eurUsdLiquidityPool, nativeEurPool)
+	if !assert.NoError(t, graph.Apply(42)) {
+		t.FailNow()
+	}
+
+	assert.NoError(t, graph.WriteSnapshot(&buf))
+
+	restored := NewOrderBookGraph()
+	// anything in the graph is replaced by the snapshot
+	restored.AddOffers(xdr.OfferEntry{
+		SellerId: issuer,
+		OfferId:  xdr.Int64(100),
+		Buying:   chfAsset,
+		Selling:  yenAsset,
+		Price:    xdr.Price{N: 1, D: 1},
+		Amount:   xdr.Int64(100),
+	})
+	assert.NoError(t, restored.Apply(1000))
+
+	assert.NoError(t, restored.ReadSnapshot(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, uint32(42), restored.LastLedger())
+	assertGraphEquals(t, graph, restored)
+
+	// the restored graph accepts the following ledgers
+	restored.RemoveOffer(dollarOffer.OfferId)
+	assert.NoError(t, restored.Apply(43))
+}
+
+func TestReadInvalidSnapshot(t *testing.T) {
+	graph := NewOrderBookGraph()
+	assert.Error(t, graph.ReadSnapshot(bytes.NewReader([]byte("not a snapshot"))))
+
+	var buf bytes.Buffer
+	source := NewOrderBookGraph()
+	source.AddOffers(eurOffer)
+	assert.NoError(t, source.Apply(1))
+	assert.NoError(t, source.WriteSnapshot(&buf))
+
+	truncated := buf.Bytes()[:buf.Len()-8]
+	assert.Error(t, graph.ReadSnapshot(bytes.NewReader(truncated)))
+
+	// a huge frame length is rejected before allocating the entry
+	buf.Reset()
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(append(snapshotMagic[:], 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	assert.Equal(t, errInvalidSnapshot, graph.ReadSnapshot(bytes.NewReader(buf.Bytes())))
+}
+
+func TestOrderBook(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddOffers(dollarOffer, fiftyCentsOffer, quarterOffer, eurOffer)
+	graph.AddLiquidityPools(nativeUsdPool)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+
+	offers, pool := graph.OrderBook(nativeAsset, usdAsset)
+	assert.Equal(t, []xdr.OfferEntry{quarterOffer, fiftyCentsOffer, dollarOffer}, offers)
+	if assert.NotNil(t, pool) {
+		assert.Equal(t, nativeUsdPool, *pool)
+	}
+
+	offers, pool = graph.OrderBook(nativeAsset, eurAsset)
+	assert.Equal(t, []xdr.OfferEntry{eurOffer}, offers)
+	assert.Nil(t, pool)
+
+	// the pool trades in both directions
+	offers, pool = graph.OrderBook(usdAsset, nativeAsset)
+	assert.Empty(t, offers)
+	assert.NotNil(t, pool)
+
+	offers, pool = graph.OrderBook(yenAsset, nativeAsset)
+	assert.Empty(t, offers)
+	assert.Nil(t, pool)
+}
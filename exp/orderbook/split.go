@@ -0,0 +1,549 @@
+package orderbook
+
+import (
+	"context"
+	"strings"
+
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// splitPlanChunks is the number of equal parts a payment amount is divided
+// into when it is allocated across several paths. Each part is assigned to
+// the path offering the best price for it once the parts before it have been
+// taken out of the order book.
+const splitPlanChunks = 20
+
+// SplitPlan is a payment divided across several payment paths. Each path is
+// meant to be submitted as a separate path payment operation, in order, in a
+// single transaction. The amounts of every path account for the offers and
+// pool reserves consumed by the paths which precede it.
+type SplitPlan struct {
+	Paths             []Path
+	SourceAmount      xdr.Int64
+	DestinationAmount xdr.Int64
+}
+
+// FindSplitStrictSendPaths plans how to spend exactly `amountToSpend` of
+// `sourceAsset` on `destinationAsset`, spreading the payment over at most
+// `maxPaths` paths so that the total amount delivered is maximized.
+//
+// An empty plan is returned if `amountToSpend` cannot be spent in full.
+func (graph *OrderBookGraph) FindSplitStrictSendPaths(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAsset xdr.Asset,
+	maxPaths int,
+	includePools bool,
+) (SplitPlan, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	sourceAssetString := sourceAsset.String()
+	sourceAssetID, ok := graph.assetStringToID[sourceAssetString]
+	if !ok {
+		return SplitPlan{}, graph.lastLedger, nil
+	}
+	destinationAssetID, ok := graph.assetStringToID[destinationAsset.String()]
+	if !ok {
+		return SplitPlan{}, graph.lastLedger, nil
+	}
+
+	book := newSplitBook(graph, nil, includePools, true)
+	plan, err := book.plan(amountToSpend, maxPaths, func(amount xdr.Int64) ([]Path, error) {
+		state := splitBuyingSearchState{
+			buyingGraphSearchState: &buyingGraphSearchState{
+				graph:             graph,
+				sourceAssetString: sourceAssetString,
+				sourceAssetAmount: amount,
+				targetAssets:      map[int32]bool{destinationAssetID: true},
+				paths:             []Path{},
+				includePools:      includePools,
+			},
+			book: book,
+		}
+		err := search(ctx, state, maxPathLength, sourceAssetID, amount)
+		return state.paths, err
+	})
+	if err != nil {
+		return SplitPlan{}, graph.lastLedger, errors.Wrap(err, "could not plan split payment")
+	}
+	return plan, graph.lastLedger, nil
+}
+
+// FindSplitStrictReceivePaths plans how to deliver exactly `destinationAmount`
+// of `destinationAsset` by spending `sourceAsset`, spreading the payment over
+// at most `maxPaths` paths so that the total amount spent is minimized.
+//
+// `sourceAccountID` is optional, but if it's provided, then no offers created
+// by `sourceAccountID` will be considered when evaluating payment paths.
+//
+// An empty plan is returned if `destinationAmount` cannot be delivered in
+// full.
+func (graph *OrderBookGraph) FindSplitStrictReceivePaths(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	sourceAccountID *xdr.AccountId,
+	destinationAsset xdr.Asset,
+	destinationAmount xdr.Int64,
+	maxPaths int,
+	includePools bool,
+) (SplitPlan, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	sourceAssetID, ok := graph.assetStringToID[sourceAsset.String()]
+	if !ok {
+		return SplitPlan{}, graph.lastLedger, nil
+	}
+	destinationAssetString := destinationAsset.String()
+	destinationAssetID, ok := graph.assetStringToID[destinationAssetString]
+	if !ok {
+		return SplitPlan{}, graph.lastLedger, nil
+	}
+
+	book := newSplitBook(graph, sourceAccountID, includePools, false)
+	plan, err := book.plan(destinationAmount, maxPaths, func(amount xdr.Int64) ([]Path, error) {
+		state := splitSellingSearchState{
+			sellingGraphSearchState: &sellingGraphSearchState{
+				graph:                  graph,
+				destinationAssetString: destinationAssetString,
+				destinationAssetAmount: amount,
+				ignoreOffersFrom:       sourceAccountID,
+				targetAssets:           map[int32]xdr.Int64{sourceAssetID: 0},
+				paths:                  []Path{},
+				includePools:           includePools,
+			},
+			book: book,
+		}
+		err := search(ctx, state, maxPathLength, destinationAssetID, amount)
+		return state.paths, err
+	})
+	if err != nil {
+		return SplitPlan{}, graph.lastLedger, errors.Wrap(err, "could not plan split payment")
+	}
+	return plan, graph.lastLedger, nil
+}
+
+// splitBuyingSearchState is a strict send search over the venues left in a
+// split book.
+type splitBuyingSearchState struct {
+	*buyingGraphSearchState
+	book *splitBook
+}
+
+func (state splitBuyingSearchState) venues(currentAsset int32) edgeSet {
+	return state.book.remainingVenues(state.graph.venuesForBuyingAsset[currentAsset], currentAsset, true)
+}
+
+// splitSellingSearchState is a strict receive search over the venues left in
+// a split book.
+type splitSellingSearchState struct {
+	*sellingGraphSearchState
+	book *splitBook
+}
+
+func (state splitSellingSearchState) venues(currentAsset int32) edgeSet {
+	return state.book.remainingVenues(state.graph.venuesForSellingAsset[currentAsset], currentAsset, false)
+}
+
+// splitBook is a copy-on-write view of the venues in the graph which tracks
+// the offers and pool reserves consumed while a split plan is built. The
+// graph itself is never modified.
+type splitBook struct {
+	graph            *OrderBookGraph
+	ignoreOffersFrom *xdr.AccountId
+	includePools     bool
+	strictSend       bool
+
+	// offers holds the remaining offers of each trading pair touched so far.
+	offers map[tradingPair][]xdr.OfferEntry
+	// pools holds the updated liquidity pools keyed in "asset order", like
+	// graph.liquidityPools.
+	pools map[tradingPair]liquidityPool
+	// touched is the set of assets with an updated venue.
+	touched map[int32]bool
+}
+
+func newSplitBook(
+	graph *OrderBookGraph,
+	ignoreOffersFrom *xdr.AccountId,
+	includePools bool,
+	strictSend bool,
+) *splitBook {
+	return &splitBook{
+		graph:            graph,
+		ignoreOffersFrom: ignoreOffersFrom,
+		includePools:     includePools,
+		strictSend:       strictSend,
+		offers:           map[tradingPair][]xdr.OfferEntry{},
+		pools:            map[tradingPair]liquidityPool{},
+		touched:          map[int32]bool{},
+	}
+}
+
+// plan allocates `total` chunk by chunk. For every chunk the search is run
+// again on the remaining venues and the chunk goes to the best of the routes
+// found so far. The chosen routes are then priced as consecutive path
+// payments. If the chunks cannot be allocated within `maxPaths` routes, the
+// best path carrying `total` on its own is planned instead.
+func (book *splitBook) plan(
+	total xdr.Int64,
+	maxPaths int,
+	find func(amount xdr.Int64) ([]Path, error),
+) (SplitPlan, error) {
+	if total <= 0 {
+		return SplitPlan{}, nil
+	}
+	if maxPaths < 1 {
+		maxPaths = 1
+	}
+
+	chunks := xdr.Int64(splitPlanChunks)
+	if total < chunks {
+		chunks = total
+	}
+	chunk := total / chunks
+
+	// routes holds the asset ids of every path found so far, from the source
+	// asset to the destination asset
+	var routes [][]int32
+	seen := map[string]bool{}
+	var allocated []xdr.Int64
+	// used lists the routes in the order in which they were first picked
+	var used []int
+	for i := xdr.Int64(0); i < chunks; i++ {
+		amount := chunk
+		if i == chunks-1 {
+			amount = total - chunk*(chunks-1)
+		}
+
+		paths, err := find(amount)
+		if err != nil {
+			return SplitPlan{}, err
+		}
+		for _, path := range paths {
+			assets := append([]string{path.SourceAsset}, path.InteriorNodes...)
+			if path.DestinationAsset != path.SourceAsset {
+				assets = append(assets, path.DestinationAsset)
+			}
+			key := strings.Join(assets, ",")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			route := make([]int32, len(assets))
+			for j, asset := range assets {
+				route[j] = book.graph.assetStringToID[asset]
+			}
+			routes = append(routes, route)
+			allocated = append(allocated, 0)
+		}
+
+		best, bestQuote := -1, xdr.Int64(0)
+		for j, route := range routes {
+			if allocated[j] == 0 && len(used) >= maxPaths {
+				continue
+			}
+			quote, err := book.quote(route, amount, false)
+			if err != nil {
+				return SplitPlan{}, err
+			}
+			if quote > 0 && (best < 0 || book.betterQuote(bestQuote, quote)) {
+				best, bestQuote = j, quote
+			}
+		}
+		if best < 0 {
+			// the remaining amount cannot be routed on the paths allowed, a
+			// single path may still carry the whole amount
+			return book.singlePathPlan(total, find)
+		}
+
+		if _, err := book.quote(routes[best], amount, true); err != nil {
+			return SplitPlan{}, err
+		}
+		if allocated[best] == 0 {
+			used = append(used, best)
+		}
+		allocated[best] += amount
+	}
+
+	// The chunks of a route are executed as a single path payment, so the
+	// plan is priced again on an untouched book, one route after the other.
+	final := newSplitBook(book.graph, book.ignoreOffersFrom, book.includePools, book.strictSend)
+	plan := SplitPlan{}
+	for _, j := range used {
+		quote, err := final.quote(routes[j], allocated[j], true)
+		if err != nil {
+			return SplitPlan{}, err
+		}
+		if quote <= 0 {
+			return book.singlePathPlan(total, find)
+		}
+
+		path := book.path(routes[j])
+		if book.strictSend {
+			path.SourceAmount, path.DestinationAmount = allocated[j], quote
+		} else {
+			path.SourceAmount, path.DestinationAmount = quote, allocated[j]
+		}
+		plan.Paths = append(plan.Paths, path)
+		plan.SourceAmount += path.SourceAmount
+		plan.DestinationAmount += path.DestinationAmount
+	}
+	return plan, nil
+}
+
+// singlePathPlan is the fallback of plan when the chunks cannot be allocated.
+// It returns a plan made of the best path carrying `total` on its own, or an
+// empty plan if there is none.
+func (book *splitBook) singlePathPlan(
+	total xdr.Int64,
+	find func(amount xdr.Int64) ([]Path, error),
+) (SplitPlan, error) {
+	// find searches the venues left in the book, so the trades recorded
+	// while allocating the chunks are discarded first.
+	book.offers = map[tradingPair][]xdr.OfferEntry{}
+	book.pools = map[tradingPair]liquidityPool{}
+	book.touched = map[int32]bool{}
+
+	paths, err := find(total)
+	if err != nil {
+		return SplitPlan{}, err
+	}
+
+	best := -1
+	for i, path := range paths {
+		if best < 0 ||
+			(book.strictSend && path.DestinationAmount > paths[best].DestinationAmount) ||
+			(!book.strictSend && path.SourceAmount < paths[best].SourceAmount) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return SplitPlan{}, nil
+	}
+	return SplitPlan{
+		Paths:             []Path{paths[best]},
+		SourceAmount:      paths[best].SourceAmount,
+		DestinationAmount: paths[best].DestinationAmount,
+	}, nil
+}
+
+func (book *splitBook) path(route []int32) Path {
+	interior := []int32{}
+	if len(route) > 2 {
+		interior = route[1 : len(route)-1]
+	}
+	return Path{
+		SourceAsset:      book.graph.idToAssetString[route[0]],
+		DestinationAsset: book.graph.idToAssetString[route[len(route)-1]],
+		InteriorNodes:    assetIDsToAssetStrings(book.graph, interior),
+	}
+}
+
+// betterQuote returns true if alternative is a better quote than current.
+// Strict send plans maximize the amount received, strict receive plans
+// minimize the amount spent.
+func (book *splitBook) betterQuote(current, alternative xdr.Int64) bool {
+	if book.strictSend {
+		return alternative > current
+	}
+	return alternative < current
+}
+
+// quote returns the amount received for sending `amount` along the route in
+// strict send plans, and the amount which has to be sent to receive `amount`
+// in strict receive plans. A non-positive quote means the route cannot carry
+// `amount`. If commit is true the trades are recorded in the book.
+//
+// A route never visits an asset twice, so each hop trades on a different
+// venue and the hops can be committed as they are evaluated.
+func (book *splitBook) quote(route []int32, amount xdr.Int64, commit bool) (xdr.Int64, error) {
+	if book.strictSend {
+		for i := 0; i+1 < len(route); i++ {
+			next, apply, err := book.sendHop(route[i], route[i+1], amount)
+			if err != nil || next <= 0 {
+				return -1, err
+			}
+			if commit {
+				apply()
+			}
+			amount = next
+		}
+		return amount, nil
+	}
+
+	for i := len(route) - 1; i > 0; i-- {
+		next, apply, err := book.receiveHop(route[i-1], route[i], amount)
+		if err != nil || next <= 0 {
+			return -1, err
+		}
+		if commit {
+			apply()
+		}
+		amount = next
+	}
+	return amount, nil
+}
+
+// sendHop trades `amount` of `from` for as much of `to` as possible, using
+// either the offers or the pool of the pair, whichever pays more.
+func (book *splitBook) sendHop(from, to int32, amount xdr.Int64) (xdr.Int64, func(), error) {
+	offers := book.offersFor(to, from)
+	offerAmount := xdr.Int64(-1)
+	var index int
+	var left xdr.Int64
+	if len(offers) > 0 {
+		var err error
+		offerAmount, index, left, err = sendToOffers(offers, book.ignoreOffersFrom, amount)
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+
+	if pool, ok := book.poolFor(from, to); ok {
+		poolAmount, err := makeTrade(pool, from, tradeTypeDeposit, amount)
+		if err == nil && poolAmount > 0 && poolAmount > offerAmount {
+			return poolAmount, func() {
+				book.setPool(pool.afterTrade(from, amount, poolAmount))
+			}, nil
+		}
+	}
+
+	return offerAmount, func() {
+		book.setOffers(to, from, remainingOffers(offers, index, left))
+	}, nil
+}
+
+// receiveHop returns the smallest amount of `from` which has to be traded to
+// receive `amount` of `to`, using either the offers or the pool of the pair.
+func (book *splitBook) receiveHop(from, to int32, amount xdr.Int64) (xdr.Int64, func(), error) {
+	offers := book.offersFor(to, from)
+	offerAmount := xdr.Int64(-1)
+	var index int
+	var left xdr.Int64
+	if len(offers) > 0 {
+		var err error
+		offerAmount, index, left, err = receiveFromOffers(offers, book.ignoreOffersFrom, amount)
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+
+	if pool, ok := book.poolFor(from, to); ok {
+		poolAmount, err := makeTrade(pool, from, tradeTypeExpectation, amount)
+		if err == nil && poolAmount > 0 && (offerAmount <= 0 || poolAmount < offerAmount) {
+			return poolAmount, func() {
+				book.setPool(pool.afterTrade(from, poolAmount, amount))
+			}, nil
+		}
+	}
+
+	return offerAmount, func() {
+		book.setOffers(to, from, remainingOffers(offers, index, left))
+	}, nil
+}
+
+// offersFor returns the remaining offers selling `selling` for `buying`.
+func (book *splitBook) offersFor(selling, buying int32) []xdr.OfferEntry {
+	if offers, ok := book.offers[tradingPair{buyingAsset: buying, sellingAsset: selling}]; ok {
+		return offers
+	}
+	edges := book.graph.venuesForSellingAsset[selling]
+	if i := edges.find(buying); i >= 0 {
+		return edges[i].value.offers
+	}
+	return nil
+}
+
+func (book *splitBook) setOffers(selling, buying int32, offers []xdr.OfferEntry) {
+	book.offers[tradingPair{buyingAsset: buying, sellingAsset: selling}] = offers
+	book.touched[selling], book.touched[buying] = true, true
+}
+
+// poolFor returns the current state of the liquidity pool between `a` and
+// `b`, if there is one and pools are considered.
+func (book *splitBook) poolFor(a, b int32) (liquidityPool, bool) {
+	if !book.includePools {
+		return liquidityPool{}, false
+	}
+	edges := book.graph.venuesForSellingAsset[a]
+	i := edges.find(b)
+	if i < 0 || edges[i].value.pool.Body.ConstantProduct == nil {
+		return liquidityPool{}, false
+	}
+
+	pool := edges[i].value.pool
+	if updated, ok := book.pools[pool.pair()]; ok {
+		return updated, true
+	}
+	return pool, true
+}
+
+func (book *splitBook) setPool(pool liquidityPool) {
+	book.pools[pool.pair()] = pool
+	book.touched[pool.assetA], book.touched[pool.assetB] = true, true
+}
+
+// remainingVenues returns the edges of `asset` with the venues updated by the
+// book. `buying` tells whether the edges come from graph.venuesForBuyingAsset
+// or from graph.venuesForSellingAsset.
+func (book *splitBook) remainingVenues(edges edgeSet, asset int32, buying bool) edgeSet {
+	if !book.touched[asset] {
+		return edges
+	}
+
+	result := make(edgeSet, len(edges))
+	for i, e := range edges {
+		pair := tradingPair{buyingAsset: e.key, sellingAsset: asset}
+		if buying {
+			pair = tradingPair{buyingAsset: asset, sellingAsset: e.key}
+		}
+		if offers, ok := book.offers[pair]; ok {
+			e.value.offers = offers
+		}
+		if e.value.pool.Body.ConstantProduct != nil {
+			if pool, ok := book.pools[e.value.pool.pair()]; ok {
+				e.value.pool = pool
+			}
+		}
+		result[i] = e
+	}
+	return result
+}
+
+// pair returns the key of the pool in "asset order".
+func (pool liquidityPool) pair() tradingPair {
+	return tradingPair{buyingAsset: pool.assetA, sellingAsset: pool.assetB}
+}
+
+// afterTrade returns a copy of the pool with `deposited` of `asset` added to
+// its reserves and `disbursed` of the other asset taken out of them.
+func (pool liquidityPool) afterTrade(asset int32, deposited, disbursed xdr.Int64) liquidityPool {
+	details := *pool.Body.ConstantProduct
+	if asset == pool.assetA {
+		details.ReserveA += deposited
+		details.ReserveB -= disbursed
+	} else {
+		details.ReserveB += deposited
+		details.ReserveA -= disbursed
+	}
+	pool.Body.ConstantProduct = &details
+	return pool
+}
+
+// remainingOffers returns the offers left after a trade which stopped at
+// offers[index], leaving `left` of it. The offers slice is not modified.
+func remainingOffers(offers []xdr.OfferEntry, index int, left xdr.Int64) []xdr.OfferEntry {
+	if left <= 0 {
+		return offers[index+1:]
+	}
+	result := append([]xdr.OfferEntry(nil), offers[index:]...)
+	result[0].Amount = left
+	return result
+}
@@ -0,0 +1,159 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSplitGraph(t *testing.T) *OrderBookGraph {
+	graph := NewOrderBookGraph()
+	// USD -> XLM directly through 1500 XLM of offers, or through the
+	// USD / EUR pool and a 1:1 EUR -> XLM offer.
+	graph.AddOffers(quarterOffer, fiftyCentsOffer, dollarOffer, eurOffer)
+	graph.AddLiquidityPools(eurUsdLiquidityPool)
+	if !assert.NoError(t, graph.Apply(1)) {
+		t.FailNow()
+	}
+	return graph
+}
+
+func TestFindSplitStrictSendPaths(t *testing.T) {
+	graph := setupSplitGraph(t)
+	offers := graph.Offers()
+
+	paths, _, err := graph.FindFixedPaths(
+		context.TODO(), 3, usdAsset, 1000, []xdr.Asset{nativeAsset}, 5, true,
+	)
+	assert.NoError(t, err)
+	// the offers alone cannot absorb 1000 USD
+	assertPathEquals(t, []Path{{
+		SourceAsset:       usdAsset.String(),
+		SourceAmount:      1000,
+		DestinationAsset:  nativeAsset.String(),
+		DestinationAmount: 499,
+		InteriorNodes:     []string{eurAsset.String()},
+	}}, paths)
+
+	plan, lastLedger, err := graph.FindSplitStrictSendPaths(
+		context.TODO(), 3, usdAsset, 1000, nativeAsset, 5, true,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+
+	// 850 USD buy the cheapest 1475 XLM from the offers and the other 150 USD
+	// go through the pool.
+	assert.Equal(t, xdr.Int64(1000), plan.SourceAmount)
+	assert.Equal(t, xdr.Int64(1605), plan.DestinationAmount)
+	assertPathEquals(t, []Path{
+		{
+			SourceAsset:       usdAsset.String(),
+			SourceAmount:      850,
+			DestinationAsset:  nativeAsset.String(),
+			DestinationAmount: 1475,
+			InteriorNodes:     []string{},
+		},
+		{
+			SourceAsset:       usdAsset.String(),
+			SourceAmount:      150,
+			DestinationAsset:  nativeAsset.String(),
+			DestinationAmount: 130,
+			InteriorNodes:     []string{eurAsset.String()},
+		},
+	}, plan.Paths)
+
+	// planning does not modify the graph
+	assert.ElementsMatch(t, offers, graph.Offers())
+
+	t.Run("single path", func(t *testing.T) {
+		// the offers cannot absorb every chunk, so the whole amount goes
+		// through the pool like in FindFixedPaths
+		plan, _, err := graph.FindSplitStrictSendPaths(
+			context.TODO(), 3, usdAsset, 1000, nativeAsset, 1, true,
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, xdr.Int64(1000), plan.SourceAmount)
+		assert.Equal(t, xdr.Int64(499), plan.DestinationAmount)
+		assertPathEquals(t, []Path{{
+			SourceAsset:       usdAsset.String(),
+			SourceAmount:      1000,
+			DestinationAsset:  nativeAsset.String(),
+			DestinationAmount: 499,
+			InteriorNodes:     []string{eurAsset.String()},
+		}}, plan.Paths)
+		assert.ElementsMatch(t, offers, graph.Offers())
+	})
+
+	t.Run("exclude pools", func(t *testing.T) {
+		plan, _, err := graph.FindSplitStrictSendPaths(
+			context.TODO(), 3, usdAsset, 1000, nativeAsset, 5, false,
+		)
+		assert.NoError(t, err)
+		assert.Empty(t, plan.Paths)
+	})
+
+	t.Run("unknown asset", func(t *testing.T) {
+		plan, _, err := graph.FindSplitStrictSendPaths(
+			context.TODO(), 3, chfAsset, 1000, nativeAsset, 5, true,
+		)
+		assert.NoError(t, err)
+		assert.Empty(t, plan.Paths)
+	})
+}
+
+func TestFindSplitStrictReceivePaths(t *testing.T) {
+	graph := setupSplitGraph(t)
+	offers := graph.Offers()
+
+	paths, _, err := graph.FindPaths(
+		context.TODO(), 3, nativeAsset, 1600, nil,
+		[]xdr.Asset{usdAsset}, []xdr.Int64{0}, false, 5, true,
+	)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	plan, _, err := graph.FindSplitStrictReceivePaths(
+		context.TODO(), 3, usdAsset, nil, nativeAsset, 1600, 5, true,
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, xdr.Int64(1007), plan.SourceAmount)
+	assert.Equal(t, xdr.Int64(1600), plan.DestinationAmount)
+	assertPathEquals(t, []Path{
+		{
+			SourceAsset:       usdAsset.String(),
+			SourceAmount:      815,
+			DestinationAsset:  nativeAsset.String(),
+			DestinationAmount: 1440,
+			InteriorNodes:     []string{},
+		},
+		{
+			SourceAsset:       usdAsset.String(),
+			SourceAmount:      192,
+			DestinationAsset:  nativeAsset.String(),
+			DestinationAmount: 160,
+			InteriorNodes:     []string{eurAsset.String()},
+		},
+	}, plan.Paths)
+
+	assert.ElementsMatch(t, offers, graph.Offers())
+
+	t.Run("ignore offers from source account", func(t *testing.T) {
+		plan, _, err := graph.FindSplitStrictReceivePaths(
+			context.TODO(), 3, usdAsset, &issuer, nativeAsset, 100, 5, true,
+		)
+		assert.NoError(t, err)
+		assert.Empty(t, plan.Paths)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, _, err := graph.FindSplitStrictReceivePaths(
+			ctx, 3, usdAsset, nil, nativeAsset, 1600, 5, true,
+		)
+		assert.Error(t, err)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/diamcircle/go/ingest/ledgerbackend"
 	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
 )
 
 var (
@@ -17,6 +18,9 @@ var (
 	// ErrMissingPrepareRange is returned when attempting an operation before PrepareRange has finished
 	// running
 	ErrPrepareRangeNotReady = errors.New("PrepareRange operation is not yet complete")
+	// ErrRangeRewindsCore is returned when preparing a range would restart captive core
+	// from a ledger it has already streamed while consumers are streaming ledgers
+	ErrRangeRewindsCore = errors.New("range would rewind captive core while consumers are streaming")
 )
 
 type rangeRequest struct {
@@ -30,17 +34,24 @@ type rangeRequest struct {
 
 // CaptiveCoreAPI manages a shared captive core subprocess and exposes an API for
 // executing commands remotely on the captive core instance.
+//
+// The captive core instance can be shared by many consumers at different
+// positions: the most recent ledgers are kept in a buffer of ledgerBufferSize
+// ledgers from which consumers behind the newest ledger are served.
 type CaptiveCoreAPI struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	core          ledgerbackend.LedgerBackend
 	activeRequest *rangeRequest
+	ledgers       *ledgerBuffer
+	consumers     *consumerSet
 	wg            *sync.WaitGroup
 	log           *log.Entry
 }
 
-// NewCaptiveCoreAPI constructs a new CaptiveCoreAPI instance.
-func NewCaptiveCoreAPI(core ledgerbackend.LedgerBackend, log *log.Entry) CaptiveCoreAPI {
+// NewCaptiveCoreAPI constructs a new CaptiveCoreAPI instance. If
+// ledgerBufferSize is not positive DefaultLedgerBufferSize is used.
+func NewCaptiveCoreAPI(core ledgerbackend.LedgerBackend, log *log.Entry, ledgerBufferSize int) CaptiveCoreAPI {
 	ctx, cancel := context.WithCancel(context.Background())
 	return CaptiveCoreAPI{
 		ctx:           ctx,
@@ -48,6 +59,8 @@ func NewCaptiveCoreAPI(core ledgerbackend.LedgerBackend, log *log.Entry) Captive
 		core:          core,
 		log:           log,
 		activeRequest: &rangeRequest{},
+		ledgers:       newLedgerBuffer(core, ledgerBufferSize),
+		consumers:     newConsumerSet(),
 		wg:            &sync.WaitGroup{},
 	}
 }
@@ -70,7 +83,7 @@ func (c *CaptiveCoreAPI) isShutdown() bool {
 func (c *CaptiveCoreAPI) startPrepareRange(ctx context.Context, ledgerRange ledgerbackend.Range) {
 	defer c.wg.Done()
 
-	err := c.core.PrepareRange(ctx, ledgerRange)
+	err := c.ledgers.prepare(ctx, ledgerRange)
 
 	c.activeRequest.Lock()
 	defer c.activeRequest.Unlock()
@@ -111,8 +124,11 @@ func (c *CaptiveCoreAPI) PrepareRange(ctx context.Context, ledgerRange ledgerbac
 		return ledgerbackend.PrepareRangeResponse{}, errors.New("Cannot prepare range when shut down")
 	}
 
-	if !c.activeRequest.valid || !c.activeRequest.ledgerRange.Contains(ledgerRange) {
+	if !c.activeRequest.valid || !c.isServable(ledgerRange) {
 		if c.activeRequest.valid {
+			if err := c.checkRewind(ledgerRange); err != nil {
+				return ledgerbackend.PrepareRangeResponse{}, err
+			}
 			c.log.WithFields(log.F{
 				"activeRange":    c.activeRequest.ledgerRange,
 				"requestedRange": ledgerRange,
@@ -123,6 +139,7 @@ func (c *CaptiveCoreAPI) PrepareRange(ctx context.Context, ledgerRange ledgerbac
 		c.activeRequest.startTime = time.Now()
 		c.activeRequest.ready = false
 		c.activeRequest.valid = true
+		c.ledgers.reset(ledgerRange.From())
 
 		c.wg.Add(1)
 		go c.startPrepareRange(c.ctx, ledgerRange)
@@ -143,6 +160,37 @@ func (c *CaptiveCoreAPI) PrepareRange(ctx context.Context, ledgerRange ledgerbac
 	}, nil
 }
 
+// isServable returns true if the ledgers of ledgerRange can be served without
+// preparing captive core again: the active range must contain ledgerRange and
+// its first ledger must not have been evicted from the ledger buffer. The
+// caller must hold the activeRequest lock.
+func (c *CaptiveCoreAPI) isServable(ledgerRange ledgerbackend.Range) bool {
+	if !c.activeRequest.ledgerRange.Contains(ledgerRange) {
+		return false
+	}
+	oldest, _, ok := c.ledgers.bounds()
+	return !ok || ledgerRange.From() >= oldest
+}
+
+// checkRewind returns an error if preparing ledgerRange would restart captive
+// core from a ledger it has already streamed while consumers are streaming
+// ledgers, which would stall them until captive core catches up again. The
+// caller must hold the activeRequest lock.
+func (c *CaptiveCoreAPI) checkRewind(ledgerRange ledgerbackend.Range) error {
+	streaming := c.consumers.count()
+	if streaming == 0 {
+		return nil
+	}
+	_, newest, ok := c.ledgers.bounds()
+	if ok && ledgerRange.From() <= newest {
+		return errors.Wrapf(
+			ErrRangeRewindsCore, "cannot prepare range %v (latest ledger is %d, %d consumers)",
+			ledgerRange, newest, streaming,
+		)
+	}
+	return nil
+}
+
 // GetLatestLedgerSequence determines the latest ledger sequence available on the captive core instance.
 func (c *CaptiveCoreAPI) GetLatestLedgerSequence(ctx context.Context) (ledgerbackend.LatestLedgerSequenceResponse, error) {
 	c.activeRequest.Lock()
@@ -163,24 +211,86 @@ func (c *CaptiveCoreAPI) GetLatestLedgerSequence(ctx context.Context) (ledgerbac
 }
 
 // GetLedger fetches the ledger with the given sequence number from the captive core instance.
+// Recently fetched ledgers are served from the ledger buffer.
 func (c *CaptiveCoreAPI) GetLedger(ctx context.Context, sequence uint32) (ledgerbackend.LedgerResponse, error) {
-	c.activeRequest.Lock()
-	defer c.activeRequest.Unlock()
+	ledger, err := c.getLedger(ctx, sequence)
+	// TODO: We are always true here now, so this changes the semantics of this
+	// call a bit. We need to change the client to long-poll this endpoint.
+	return ledgerbackend.LedgerResponse{
+		Ledger: ledgerbackend.Base64Ledger(ledger),
+	}, err
+}
 
+func (c *CaptiveCoreAPI) getLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	c.activeRequest.Lock()
 	if !c.activeRequest.valid {
-		return ledgerbackend.LedgerResponse{}, ErrMissingPrepareRange
+		c.activeRequest.Unlock()
+		return xdr.LedgerCloseMeta{}, ErrMissingPrepareRange
 	}
 	if !c.activeRequest.ready {
-		return ledgerbackend.LedgerResponse{}, ErrPrepareRangeNotReady
+		c.activeRequest.Unlock()
+		return xdr.LedgerCloseMeta{}, ErrPrepareRangeNotReady
 	}
+	ledgerRange, startTime := c.activeRequest.ledgerRange, c.activeRequest.startTime
+	c.activeRequest.Unlock()
 
-	ledger, err := c.core.GetLedger(ctx, sequence)
-	if err != nil {
-		c.activeRequest.valid = false
+	// The lock is not held while waiting for the ledger so that other
+	// consumers can be served from the ledger buffer in the meantime.
+	ledger, err := c.ledgers.get(ctx, sequence)
+	if err != nil && ctx.Err() == nil && errors.Cause(err) != ErrLedgerNotBuffered {
+		c.activeRequest.Lock()
+		// A range prepared since the ledger was requested is not affected.
+		if c.activeRequest.ledgerRange == ledgerRange && c.activeRequest.startTime == startTime {
+			c.activeRequest.valid = false
+		}
+		c.activeRequest.Unlock()
+	}
+	return ledger, err
+}
+
+// StreamLedgers calls send with consecutive ledgers starting from
+// consumer.NextLedger until ctx is done, the end of the active range is
+// reached or an error occurs. Consumers are tracked while streaming so their
+// lag can be reported by Consumers.
+func (c *CaptiveCoreAPI) StreamLedgers(ctx context.Context, consumer Consumer, send func(xdr.LedgerCloseMeta) error) error {
+	id := c.consumers.add(consumer)
+	defer c.consumers.remove(id)
+
+	for sequence := consumer.NextLedger; ; sequence++ {
+		if c.isShutdown() {
+			return errors.New("captive core server is shutting down")
+		}
+
+		c.activeRequest.Lock()
+		ledgerRange := c.activeRequest.ledgerRange
+		c.activeRequest.Unlock()
+		if ledgerRange.Bounded() && sequence > ledgerRange.To() {
+			if sequence == consumer.NextLedger {
+				return errors.Errorf("ledger %d is beyond the prepared range %v", sequence, ledgerRange)
+			}
+			// Requesting ledgers beyond a bounded range is an error, so the
+			// stream ends with the range.
+			return nil
+		}
+
+		ledger, err := c.getLedger(ctx, sequence)
+		if err != nil {
+			return err
+		}
+		if err := send(ledger); err != nil {
+			return err
+		}
+		c.consumers.sent(id, sequence)
+	}
+}
+
+// Consumers returns the clients streaming ledgers and their lag behind the
+// latest ledger streamed by captive core.
+func (c *CaptiveCoreAPI) Consumers() ConsumersResponse {
+	oldest, latest, _ := c.ledgers.bounds()
+	return ConsumersResponse{
+		OldestBufferedLedger: oldest,
+		LatestLedger:         latest,
+		Consumers:            c.consumers.list(latest),
 	}
-	// TODO: We are always true here now, so this changes the semantics of this
-	// call a bit. We need to change the client to long-poll this endpoint.
-	return ledgerbackend.LedgerResponse{
-		Ledger: ledgerbackend.Base64Ledger(ledger),
-	}, err
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/support/log"
 	"github.com/diamcircle/go/xdr"
 )
@@ -28,7 +29,7 @@ type APITestSuite struct {
 func (s *APITestSuite) SetupTest() {
 	s.ctx = context.Background()
 	s.ledgerBackend = &ledgerbackend.MockDatabaseBackend{}
-	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New())
+	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New(), DefaultLedgerBufferSize)
 }
 
 func (s *APITestSuite) TearDownTest() {
@@ -100,7 +101,7 @@ func (s *APITestSuite) TestLatestSeqError() {
 }
 
 func (s *APITestSuite) TestGetLedgerError() {
-	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+	s.waitUntilReady(ledgerbackend.UnboundedRange(64))
 
 	expectedErr := fmt.Errorf("test error")
 	s.ledgerBackend.On("GetLedger", s.ctx, uint32(64)).
@@ -121,7 +122,7 @@ func (s *APITestSuite) TestLatestSeqSucceeds() {
 }
 
 func (s *APITestSuite) TestGetLedgerSucceeds() {
-	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+	s.waitUntilReady(ledgerbackend.UnboundedRange(64))
 
 	expectedLedger := xdr.LedgerCloseMeta{
 		V0: &xdr.LedgerCloseMetaV0{
@@ -242,3 +243,148 @@ func (s *APITestSuite) TestNewPrepareRange() {
 	s.waitUntilReady(ledgerbackend.BoundedRange(45, 50))
 	s.waitUntilReady(ledgerbackend.UnboundedRange(46))
 }
+
+func testLedger(sequence uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq: xdr.Uint32(sequence),
+				},
+			},
+		},
+	}
+}
+
+func (s *APITestSuite) TestGetLedgerFromBuffer() {
+	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New(), 3)
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+
+	// The ledgers preceding the first requested one are buffered too.
+	for sequence := uint32(63); sequence <= 66; sequence++ {
+		s.ledgerBackend.On("GetLedger", s.ctx, sequence).
+			Return(testLedger(sequence), nil).Once()
+	}
+	response, err := s.api.GetLedger(s.ctx, 65)
+	s.Assert().NoError(err)
+	s.Assert().Equal(testLedger(65), xdr.LedgerCloseMeta(response.Ledger))
+
+	// Consumers behind are served from the buffer.
+	for _, sequence := range []uint32{63, 64, 65, 66, 64} {
+		response, err = s.api.GetLedger(s.ctx, sequence)
+		s.Assert().NoError(err)
+		s.Assert().Equal(testLedger(sequence), xdr.LedgerCloseMeta(response.Ledger))
+	}
+
+	consumers := s.api.Consumers()
+	s.Assert().Equal(uint32(64), consumers.OldestBufferedLedger)
+	s.Assert().Equal(uint32(66), consumers.LatestLedger)
+
+	_, err = s.api.GetLedger(s.ctx, 63)
+	s.Assert().EqualError(err, "cannot get ledger 63 (oldest buffered ledger is 64): ledger is older than the oldest buffered ledger")
+	s.Assert().True(s.api.activeRequest.valid)
+
+	// Ranges starting at a buffered ledger are already prepared.
+	prepared, err := s.api.PrepareRange(s.ctx, ledgerbackend.UnboundedRange(64))
+	s.Assert().NoError(err)
+	s.Assert().True(prepared.Ready)
+
+	// Ranges starting at an evicted ledger have to be prepared again.
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+	_, _, ok := s.api.ledgers.bounds()
+	s.Assert().False(ok)
+}
+
+func (s *APITestSuite) TestStreamLedgers() {
+	s.waitUntilReady(ledgerbackend.BoundedRange(63, 65))
+	for sequence := uint32(63); sequence <= 65; sequence++ {
+		s.ledgerBackend.On("GetLedger", mock.Anything, sequence).
+			Return(testLedger(sequence), nil).Once()
+	}
+
+	// The stream ends with the bounded range.
+	var streamed []xdr.LedgerCloseMeta
+	err := s.api.StreamLedgers(s.ctx, Consumer{Name: "test", NextLedger: 63}, func(ledger xdr.LedgerCloseMeta) error {
+		streamed = append(streamed, ledger)
+		consumers := s.api.Consumers().Consumers
+		s.Assert().Len(consumers, 1)
+		s.Assert().Equal("test", consumers[0].Name)
+		s.Assert().Equal(ledger.LedgerSequence(), consumers[0].NextLedger)
+		s.Assert().Equal(uint32(1), consumers[0].Lag)
+		return nil
+	})
+	s.Assert().NoError(err)
+	s.Assert().Equal([]xdr.LedgerCloseMeta{testLedger(63), testLedger(64), testLedger(65)}, streamed)
+	s.Assert().Empty(s.api.Consumers().Consumers)
+
+	err = s.api.StreamLedgers(s.ctx, Consumer{NextLedger: 66}, func(xdr.LedgerCloseMeta) error {
+		return nil
+	})
+	s.Assert().EqualError(err, "ledger 66 is beyond the prepared range [63,65]")
+}
+
+func (s *APITestSuite) TestPrepareRangeWaitsForFetchingConsumer() {
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+
+	waitChan := make(chan time.Time)
+	fetched := make(chan struct{})
+	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(63)).
+		WaitUntil(waitChan).
+		Run(func(mock.Arguments) { close(fetched) }).
+		Return(testLedger(63), nil).Once()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := s.api.GetLedger(s.ctx, 63)
+		s.Assert().NoError(err)
+	}()
+	for len(s.api.ledgers.fetching) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Captive core is prepared once the consumer fetching from it is done.
+	ledgerRange := ledgerbackend.UnboundedRange(100)
+	s.ledgerBackend.On("PrepareRange", mock.Anything, ledgerRange).
+		Run(func(mock.Arguments) {
+			select {
+			case <-fetched:
+			default:
+				s.Fail("captive core prepared while fetching a ledger")
+			}
+		}).
+		Return(nil).Once()
+	_, err := s.api.PrepareRange(s.ctx, ledgerRange)
+	s.Assert().NoError(err)
+
+	time.Sleep(10 * time.Millisecond)
+	close(waitChan)
+	<-done
+	s.api.wg.Wait()
+	s.Assert().True(s.api.activeRequest.ready)
+}
+
+func (s *APITestSuite) TestPrepareRangeRewindWhileStreaming() {
+	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New(), 1)
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+	for sequence := uint32(63); sequence <= 64; sequence++ {
+		s.ledgerBackend.On("GetLedger", mock.Anything, sequence).
+			Return(testLedger(sequence), nil).Once()
+	}
+
+	stop := errors.New("stop")
+	err := s.api.StreamLedgers(s.ctx, Consumer{NextLedger: 63}, func(ledger xdr.LedgerCloseMeta) error {
+		if ledger.LedgerSequence() < 64 {
+			return nil
+		}
+		// Ledger 63 has been evicted, preparing a range from it would
+		// restart captive core behind the streaming consumer.
+		_, err := s.api.PrepareRange(s.ctx, ledgerbackend.UnboundedRange(63))
+		s.Assert().EqualError(err, "cannot prepare range [63,latest) (latest ledger is 64, 1 consumers): range would rewind captive core while consumers are streaming")
+		s.Assert().Equal(ErrRangeRewindsCore, errors.Cause(err))
+		return stop
+	})
+	s.Assert().Equal(stop, err)
+
+	// Without consumers streaming the range can be prepared again.
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+}
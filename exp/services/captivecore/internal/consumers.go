@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Consumer describes a client streaming ledgers from the captive core server.
+type Consumer struct {
+	ID          uint64    `json:"id"`
+	Name        string    `json:"name"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	// NextLedger is the sequence of the next ledger sent to the consumer.
+	NextLedger uint32 `json:"nextLedger"`
+	// Lag is the number of ledgers streamed by captive core which have not
+	// been sent to the consumer yet.
+	Lag uint32 `json:"lag"`
+}
+
+// ConsumersResponse is the response for the Consumers command.
+type ConsumersResponse struct {
+	// OldestBufferedLedger and LatestLedger are the sequences of the oldest
+	// and the newest ledgers in the ledger buffer, 0 when it is empty.
+	OldestBufferedLedger uint32     `json:"oldestBufferedLedger"`
+	LatestLedger         uint32     `json:"latestLedger"`
+	Consumers            []Consumer `json:"consumers"`
+}
+
+// consumerSet keeps track of the clients streaming ledgers.
+type consumerSet struct {
+	lock      sync.Mutex
+	lastID    uint64
+	consumers map[uint64]*Consumer
+}
+
+func newConsumerSet() *consumerSet {
+	return &consumerSet{consumers: map[uint64]*Consumer{}}
+}
+
+func (s *consumerSet) add(consumer Consumer) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastID++
+	consumer.ID = s.lastID
+	consumer.ConnectedAt = time.Now()
+	s.consumers[consumer.ID] = &consumer
+	return consumer.ID
+}
+
+func (s *consumerSet) remove(id uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.consumers, id)
+}
+
+func (s *consumerSet) sent(id uint64, sequence uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if consumer, ok := s.consumers[id]; ok {
+		consumer.NextLedger = sequence + 1
+	}
+}
+
+func (s *consumerSet) count() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.consumers)
+}
+
+// list returns the consumers ordered by id, with their lag behind the given
+// latest ledger.
+func (s *consumerSet) list(latestLedger uint32) []Consumer {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	consumers := make([]Consumer, 0, len(s.consumers))
+	for _, consumer := range s.consumers {
+		c := *consumer
+		if latestLedger >= c.NextLedger {
+			c.Lag = latestLedger - c.NextLedger + 1
+		}
+		consumers = append(consumers, c)
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].ID < consumers[j].ID
+	})
+	return consumers
+}
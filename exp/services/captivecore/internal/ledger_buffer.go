@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"context"
+	"sync"
+
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// DefaultLedgerBufferSize is the default number of recent ledgers buffered
+// by CaptiveCoreAPI.
+const DefaultLedgerBufferSize = 256
+
+// ErrLedgerNotBuffered is returned when a consumer requests a ledger which
+// captive core has already streamed and which is no longer buffered.
+var ErrLedgerNotBuffered = errors.New("ledger is older than the oldest buffered ledger")
+
+// ledgerBuffer is a ring buffer of the most recent consecutive ledgers
+// streamed by captive core. Ledgers are fetched from captive core on demand by
+// the consumer furthest ahead, consumers behind it are served from the buffer.
+type ledgerBuffer struct {
+	core ledgerbackend.LedgerBackend
+	// fetching is held by the consumer fetching ledgers from captive core.
+	fetching chan struct{}
+
+	lock    sync.RWMutex
+	ledgers []xdr.LedgerCloseMeta
+	// first is the sequence of the oldest buffered ledger and next the
+	// sequence of the ledger following the newest one. The buffer is empty
+	// when they are equal.
+	first uint32
+	next  uint32
+	// rangeFrom is the first ledger of the range captive core is prepared
+	// for, 0 when unknown.
+	rangeFrom uint32
+	// generation is incremented on reset so ledgers fetched for a
+	// previous range are not buffered.
+	generation uint64
+}
+
+func newLedgerBuffer(core ledgerbackend.LedgerBackend, size int) *ledgerBuffer {
+	if size <= 0 {
+		size = DefaultLedgerBufferSize
+	}
+	return &ledgerBuffer{
+		core:     core,
+		fetching: make(chan struct{}, 1),
+		ledgers:  make([]xdr.LedgerCloseMeta, size),
+	}
+}
+
+// reset empties the buffer. It must be called when captive core is prepared
+// for a new range starting from rangeFrom.
+func (b *ledgerBuffer) reset(rangeFrom uint32) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for i := range b.ledgers {
+		b.ledgers[i] = xdr.LedgerCloseMeta{}
+	}
+	b.first, b.next = 0, 0
+	b.rangeFrom = rangeFrom
+	b.generation++
+}
+
+// bounds returns the sequences of the oldest and the newest buffered
+// ledgers. ok is false when the buffer is empty.
+func (b *ledgerBuffer) bounds() (oldest, newest uint32, ok bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.first == b.next {
+		return 0, 0, false
+	}
+	return b.first, b.next - 1, true
+}
+
+// evicted returns an error if the ledger with the given sequence is older than
+// the buffered ledgers.
+func (b *ledgerBuffer) evicted(sequence uint32) error {
+	if b.first != b.next && sequence < b.first {
+		return errors.Wrapf(
+			ErrLedgerNotBuffered, "cannot get ledger %d (oldest buffered ledger is %d)", sequence, b.first,
+		)
+	}
+	return nil
+}
+
+func (b *ledgerBuffer) lookup(sequence uint32) (xdr.LedgerCloseMeta, bool) {
+	if b.first <= sequence && sequence < b.next {
+		return b.ledgers[sequence%uint32(len(b.ledgers))], true
+	}
+	return xdr.LedgerCloseMeta{}, false
+}
+
+func (b *ledgerBuffer) add(sequence uint32, ledger xdr.LedgerCloseMeta) {
+	if b.first == b.next || sequence != b.next {
+		b.first = sequence
+	}
+	b.ledgers[sequence%uint32(len(b.ledgers))] = ledger
+	b.next = sequence + 1
+	if size := uint32(len(b.ledgers)); b.next-b.first > size {
+		b.first = b.next - size
+	}
+}
+
+// prepare prepares captive core for ledgerRange. It holds the fetching
+// semaphore so that captive core is not prepared while a consumer is fetching
+// ledgers from it.
+func (b *ledgerBuffer) prepare(ctx context.Context, ledgerRange ledgerbackend.Range) error {
+	select {
+	case b.fetching <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-b.fetching }()
+
+	return b.core.PrepareRange(ctx, ledgerRange)
+}
+
+// get returns the ledger with the given sequence, from the buffer when it is
+// buffered or from captive core otherwise.
+func (b *ledgerBuffer) get(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.lock.RLock()
+	ledger, ok := b.lookup(sequence)
+	// Evicted ledgers are reported without waiting for the consumer fetching
+	// the next ledger.
+	err := b.evicted(sequence)
+	b.lock.RUnlock()
+	if ok || err != nil {
+		return ledger, err
+	}
+
+	select {
+	case b.fetching <- struct{}{}:
+	case <-ctx.Done():
+		return xdr.LedgerCloseMeta{}, ctx.Err()
+	}
+	defer func() { <-b.fetching }()
+
+	return b.fetch(ctx, sequence)
+}
+
+// fetch gets the ledger with the given sequence from captive core, buffering
+// it and the ledgers between the newest buffered ledger and it. The caller
+// must hold the fetching semaphore.
+func (b *ledgerBuffer) fetch(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	b.lock.RLock()
+	// Another consumer may have fetched the ledger while we were waiting.
+	ledger, ok := b.lookup(sequence)
+	err := b.evicted(sequence)
+	empty := b.first == b.next
+	next, rangeFrom, generation := b.next, b.rangeFrom, b.generation
+	b.lock.RUnlock()
+	if ok || err != nil {
+		return ledger, err
+	}
+
+	from := sequence
+	if empty {
+		// Buffer the ledgers preceding the first requested one so that
+		// consumers behind it can be served too.
+		size := uint32(len(b.ledgers))
+		if rangeFrom != 0 && sequence >= rangeFrom {
+			from = rangeFrom
+			if sequence-rangeFrom >= size {
+				from = sequence - size + 1
+			}
+		}
+	} else {
+		from = next
+	}
+
+	for current := from; current <= sequence; current++ {
+		ledger, err = b.core.GetLedger(ctx, current)
+		if err != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+
+		b.lock.Lock()
+		if b.generation == generation {
+			b.add(current, ledger)
+		}
+		b.lock.Unlock()
+	}
+	return ledger, nil
+}
@@ -7,9 +7,11 @@ import (
 	"time"
 
 	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/support/errors"
 	supporthttp "github.com/diamcircle/go/support/http"
 	"github.com/diamcircle/go/support/http/httpdecode"
 	supportlog "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
 )
 
 func serializeResponse(
@@ -36,6 +38,63 @@ type GetLedgerRequest struct {
 	Sequence uint32 `path:"sequence"`
 }
 
+type StreamLedgersRequest struct {
+	From     uint32 `query:"from"`
+	Consumer string `query:"consumer"`
+}
+
+// streamLedgers streams framed XDR encoded ledgers over a long-lived
+// response. The response status is only sent with the first ledger so that
+// errors preventing the stream from starting are reported like in the other
+// endpoints. Once streaming the response is ended on error and the client
+// is expected to request the stream again from its next ledger.
+func streamLedgers(api CaptiveCoreAPI, w http.ResponseWriter, r *http.Request) {
+	req := StreamLedgersRequest{}
+	if err := httpdecode.Decode(r, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if req.From == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("from must be a ledger sequence"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		serializeResponse(api.log, w, r, nil, errors.New("streaming is not supported"))
+		return
+	}
+
+	consumer := Consumer{
+		Name:       req.Consumer,
+		RemoteAddr: r.RemoteAddr,
+		NextLedger: req.From,
+	}
+	streaming := false
+	err := api.StreamLedgers(r.Context(), consumer, func(ledger xdr.LedgerCloseMeta) error {
+		if !streaming {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.WriteHeader(http.StatusOK)
+			streaming = true
+		}
+		if err := xdr.MarshalFramed(w, ledger); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if !streaming {
+		serializeResponse(api.log, w, r, nil, err)
+		return
+	}
+	if err != nil && r.Context().Err() == nil {
+		api.log.WithContext(r.Context()).WithError(err).WithField("consumer", consumer.Name).
+			Warn("ledger stream ended")
+	}
+}
+
 // Handler returns an HTTP handler which exposes captive core operations via HTTP endpoints.
 func Handler(api CaptiveCoreAPI) http.Handler {
 	mux := supporthttp.NewMux(api.log)
@@ -73,6 +132,14 @@ func Handler(api CaptiveCoreAPI) http.Handler {
 		}
 	})
 
+	mux.Get("/ledgers", func(w http.ResponseWriter, r *http.Request) {
+		streamLedgers(api, w, r)
+	})
+
+	mux.Get("/consumers", func(w http.ResponseWriter, r *http.Request) {
+		serializeResponse(api.log, w, r, api.Consumers(), nil)
+	})
+
 	mux.Post("/prepare-range", func(w http.ResponseWriter, r *http.Request) {
 		ledgerRange := ledgerbackend.Range{}
 		if err := json.NewDecoder(r.Body).Decode(&ledgerRange); err != nil {
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -34,7 +35,7 @@ type ServerTestSuite struct {
 func (s *ServerTestSuite) SetupTest() {
 	s.ctx = context.Background()
 	s.ledgerBackend = &ledgerbackend.MockDatabaseBackend{}
-	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New())
+	s.api = NewCaptiveCoreAPI(s.ledgerBackend, log.New(), DefaultLedgerBufferSize)
 	s.handler = Handler(s.api)
 	s.server = httptest.NewServer(s.handler)
 	var err error
@@ -46,9 +47,19 @@ func (s *ServerTestSuite) SetupTest() {
 }
 
 func (s *ServerTestSuite) TearDownTest() {
-	s.ledgerBackend.AssertExpectations(s.T())
-	s.server.Close()
+	// The client is closed first to end its ledger stream, the server waits
+	// for outstanding requests when closed.
 	s.client.Close()
+	s.server.Close()
+	s.ledgerBackend.AssertExpectations(s.T())
+}
+
+// blockGetLedger makes the ledger backend block on GetLedger for the given
+// sequence until the request streaming it is closed.
+func (s *ServerTestSuite) blockGetLedger(sequence uint32) {
+	s.ledgerBackend.On("GetLedger", mock.Anything, sequence).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(xdr.LedgerCloseMeta{}, context.Canceled).Maybe()
 }
 
 func (s *ServerTestSuite) TestLatestSequence() {
@@ -151,6 +162,7 @@ func (s *ServerTestSuite) TestGetLedgerSucceeds() {
 	}
 	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(64)).
 		Return(expectedLedger, nil).Once()
+	s.blockGetLedger(65)
 
 	ledger, err := s.client.GetLedger(s.ctx, 64)
 	s.Assert().NoError(err)
@@ -170,13 +182,112 @@ func (s *ServerTestSuite) TestGetLedgerTakesAWhile() {
 			},
 		},
 	}
+	// The ledger stream is not bound by the timeout of ledger requests.
 	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(64)).
 		Run(func(mock.Arguments) { time.Sleep(6 * time.Second) }).
-		Return(xdr.LedgerCloseMeta{}, nil).Once()
-	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(64)).
 		Return(expectedLedger, nil).Once()
+	s.blockGetLedger(65)
 
 	ledger, err := s.client.GetLedger(s.ctx, 64)
 	s.Assert().NoError(err)
 	s.Assert().Equal(expectedLedger, ledger)
 }
+
+func (s *ServerTestSuite) TestGetLedgerRequest() {
+	s.api.activeRequest.valid = true
+	s.api.activeRequest.ready = true
+
+	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(64)).
+		Run(func(mock.Arguments) { time.Sleep(6 * time.Second) }).
+		Return(testLedger(64), nil).Once()
+
+	// The ledger fetched after the request timed out is buffered.
+	req := httptest.NewRequest("GET", "/ledger/64", nil)
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	s.Assert().Equal(http.StatusRequestTimeout, w.Result().StatusCode)
+
+	s.Assert().Eventually(func() bool {
+		_, latest, ok := s.api.ledgers.bounds()
+		return ok && latest == 64
+	}, 5*time.Second, 10*time.Millisecond)
+
+	w = httptest.NewRecorder()
+	s.handler.ServeHTTP(w, httptest.NewRequest("GET", "/ledger/64", nil))
+	s.Assert().Equal(http.StatusOK, w.Result().StatusCode)
+	var response ledgerbackend.LedgerResponse
+	s.Assert().NoError(json.NewDecoder(w.Result().Body).Decode(&response))
+	s.Assert().Equal(testLedger(64), xdr.LedgerCloseMeta(response.Ledger))
+}
+
+func (s *ServerTestSuite) TestStreamLedgersToManyConsumers() {
+	s.waitUntilReady(ledgerbackend.UnboundedRange(63))
+	for sequence := uint32(63); sequence <= 70; sequence++ {
+		s.ledgerBackend.On("GetLedger", mock.Anything, sequence).
+			Return(testLedger(sequence), nil).Once()
+	}
+	s.blockGetLedger(71)
+
+	ahead, err := ledgerbackend.NewRemoteCaptive(s.server.URL, ledgerbackend.ConsumerName("ahead"))
+	s.Assert().NoError(err)
+	defer ahead.Close()
+	for sequence := uint32(63); sequence <= 70; sequence++ {
+		ledger, err := ahead.GetLedger(s.ctx, sequence)
+		s.Assert().NoError(err)
+		s.Assert().Equal(testLedger(sequence), ledger)
+	}
+
+	// A consumer behind is served from the ledger buffer, without preparing
+	// captive core again.
+	s.Assert().NoError(s.client.PrepareRange(s.ctx, ledgerbackend.UnboundedRange(65)))
+	for sequence := uint32(65); sequence <= 67; sequence++ {
+		ledger, err := s.client.GetLedger(s.ctx, sequence)
+		s.Assert().NoError(err)
+		s.Assert().Equal(testLedger(sequence), ledger)
+	}
+
+	// The stream sent the ledgers read by the client, and possibly more.
+	var consumers ConsumersResponse
+	s.Assert().Eventually(func() bool {
+		consumers = s.api.Consumers()
+		return len(consumers.Consumers) == 2 &&
+			consumers.Consumers[0].NextLedger == 71 &&
+			consumers.Consumers[1].NextLedger >= 68
+	}, 5*time.Second, 10*time.Millisecond)
+	s.Assert().Equal(uint32(63), consumers.OldestBufferedLedger)
+	s.Assert().Equal(uint32(70), consumers.LatestLedger)
+	s.Assert().Equal("ahead", consumers.Consumers[0].Name)
+	s.Assert().Equal(uint32(71), consumers.Consumers[0].NextLedger)
+	s.Assert().Equal(uint32(0), consumers.Consumers[0].Lag)
+	s.Assert().Equal(71-consumers.Consumers[1].NextLedger, consumers.Consumers[1].Lag)
+
+	// Ledgers older than the buffer can not be streamed.
+	_, err = s.client.GetLedger(s.ctx, 62)
+	s.Assert().EqualError(err, "cannot get ledger 62 (oldest buffered ledger is 63): ledger is older than the oldest buffered ledger")
+	s.Assert().True(s.api.activeRequest.valid)
+}
+
+func (s *ServerTestSuite) TestConsumers() {
+	s.api.activeRequest.valid = true
+	s.api.activeRequest.ready = true
+	s.ledgerBackend.On("GetLedger", mock.Anything, uint32(64)).
+		Return(testLedger(64), nil).Once()
+	_, err := s.api.GetLedger(s.ctx, 64)
+	s.Assert().NoError(err)
+
+	req := httptest.NewRequest("GET", "/consumers", nil)
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	body, err := ioutil.ReadAll(resp.Body)
+	s.Assert().NoError(err)
+	s.Assert().Equal(http.StatusOK, resp.StatusCode)
+	s.Assert().JSONEq(`{"oldestBufferedLedger": 64, "latestLedger": 64, "consumers": []}`, string(body))
+}
+
+func (s *ServerTestSuite) waitUntilReady(ledgerRange ledgerbackend.Range) {
+	s.ledgerBackend.On("PrepareRange", mock.Anything, ledgerRange).
+		Return(nil).Once()
+	s.Assert().NoError(s.client.PrepareRange(s.ctx, ledgerRange))
+}
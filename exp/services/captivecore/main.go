@@ -24,6 +24,7 @@ func main() {
 	var captiveCoreTomlParams ledgerbackend.CaptiveCoreTomlParams
 	var historyArchiveURLs []string
 	var checkpointFrequency uint32
+	var ledgerBufferSize int
 	var logLevel logrus.Level
 	logger := supportlog.New()
 
@@ -115,6 +116,14 @@ func main() {
 			Required:    false,
 			Usage:       "establishes how many ledgers exist between checkpoints, do NOT change this unless you really know what you are doing",
 		},
+		&config.ConfigOption{
+			Name:        "ledger-buffer-size",
+			ConfigKey:   &ledgerBufferSize,
+			OptType:     types.Int,
+			FlagDefault: internal.DefaultLedgerBufferSize,
+			Required:    false,
+			Usage:       "number of recent ledgers kept in memory to serve consumers streaming behind the latest ledger",
+		},
 	}
 	cmd := &cobra.Command{
 		Use:   "captivecore",
@@ -154,7 +163,7 @@ func main() {
 			if err != nil {
 				logger.WithError(err).Fatal("Could not create captive core instance")
 			}
-			api := internal.NewCaptiveCoreAPI(core, logger.WithField("subservice", "api"))
+			api := internal.NewCaptiveCoreAPI(core, logger.WithField("subservice", "api"), ledgerBufferSize)
 
 			supporthttp.Run(supporthttp.Config{
 				ListenAddr: fmt.Sprintf(":%d", port),
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/exp/orderbook/replay"
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/network"
+	"github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+type level struct {
+	Price  string `json:"price"`
+	Amount int64  `json:"amount"`
+}
+
+type pool struct {
+	ReserveSelling int64 `json:"reserve_selling"`
+	ReserveBuying  int64 `json:"reserve_buying"`
+}
+
+type result struct {
+	Ledger uint32           `json:"ledger"`
+	Offers []level          `json:"offers,omitempty"`
+	Pool   *pool            `json:"pool,omitempty"`
+	Paths  []orderbook.Path `json:"paths,omitempty"`
+}
+
+// This program replays the order book over a range of ledgers. At every
+// -step ledgers it prints, as one line of JSON, the order book for the
+// -selling / -buying pair and the strict send paths from -source-asset to
+// -destination-asset. Snapshots saved in -snapshot-dir make later replays of
+// the same range start faster.
+func main() {
+	testnet := flag.Bool("testnet", false, "connect to the Diamcircle test network")
+	binaryPath := flag.String("captive-core-binary", "diamcircle-core", "path to the diamcircle-core binary")
+	start := flag.Uint("start", 0, "first ledger to query")
+	end := flag.Uint("end", 0, "last ledger to query")
+	step := flag.Uint("step", 1, "number of ledgers between queries")
+	snapshotDir := flag.String("snapshot-dir", "", "directory where order book snapshots are stored")
+	snapshotInterval := flag.Uint("snapshot-interval", 1000, "number of ledgers between order book snapshots")
+	selling := flag.String("selling", "", "selling asset of the order book to print (Code:Issuer or native)")
+	buying := flag.String("buying", "", "buying asset of the order book to print (Code:Issuer or native)")
+	sourceAsset := flag.String("source-asset", "", "source asset of the paths to print (Code:Issuer or native)")
+	destinationAsset := flag.String("destination-asset", "", "destination asset of the paths to print (Code:Issuer or native)")
+	sourceAmount := flag.Int64("source-amount", 0, "amount of the source asset to send, in stroops")
+	maxPathLength := flag.Int("max-path-length", 3, "maximum number of assets in a path")
+
+	flag.Parse()
+	log.SetLevel(log.InfoLevel)
+
+	if *start == 0 || *end < *start || *step == 0 {
+		log.Fatal("-start, -end and -step must describe a non empty range of ledgers")
+	}
+
+	var book []xdr.Asset
+	if *selling != "" || *buying != "" {
+		book = mustParseAssets(*selling + "," + *buying)
+	}
+	var paths []xdr.Asset
+	if *sourceAsset != "" || *destinationAsset != "" {
+		paths = mustParseAssets(*sourceAsset + "," + *destinationAsset)
+		if *sourceAmount <= 0 {
+			log.Fatal("-source-amount must be positive")
+		}
+	}
+
+	passphrase, archiveURLs := network.PublicNetworkPassphrase, []string{
+		"https://history.diamcircle.org/prd/core-live/core_live_001/",
+	}
+	if *testnet {
+		passphrase, archiveURLs = network.TestNetworkPassphrase, []string{
+			"https://history.diamcircle.org/prd/core-testnet/core_testnet_001",
+		}
+	}
+
+	archive, err := historyarchive.Connect(archiveURLs[0], historyarchive.ConnectOptions{})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not connect to history archive")
+	}
+	toml, err := ledgerbackend.NewCaptiveCoreToml(ledgerbackend.CaptiveCoreTomlParams{
+		NetworkPassphrase:  passphrase,
+		HistoryArchiveURLs: archiveURLs,
+	})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not create captive core config")
+	}
+	backend, err := ledgerbackend.NewCaptive(ledgerbackend.CaptiveCoreConfig{
+		BinaryPath:         *binaryPath,
+		NetworkPassphrase:  passphrase,
+		HistoryArchiveURLs: archiveURLs,
+		Toml:               toml,
+	})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not create captive core")
+	}
+	defer backend.Close()
+
+	replayer, err := replay.NewReplayer(replay.Config{
+		HistoryArchive:    archive,
+		LedgerBackend:     backend,
+		NetworkPassphrase: passphrase,
+		SnapshotDir:       *snapshotDir,
+		SnapshotInterval:  uint32(*snapshotInterval),
+	})
+	if err != nil {
+		log.WithField("err", err).Fatal("could not create replayer")
+	}
+
+	ctx := context.Background()
+	// Preparing the whole range upfront is much faster than letting every
+	// seek prepare its own range. Captive core needs to start from the
+	// checkpoint preceding the first ledger.
+	checkpoint := archive.GetCheckpointManager().PrevCheckpoint(uint32(*start))
+	if checkpoint < uint32(*start) {
+		err = backend.PrepareRange(ctx, ledgerbackend.BoundedRange(checkpoint+1, uint32(*end)))
+		if err != nil {
+			log.WithField("err", err).Fatal("could not prepare ledger range")
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for ledger := uint32(*start); ledger <= uint32(*end); ledger += uint32(*step) {
+		if err = replayer.Seek(ctx, ledger); err != nil {
+			log.WithField("err", err).WithField("ledger", ledger).Fatal("could not replay ledger")
+		}
+
+		out := result{Ledger: ledger}
+		graph := replayer.Graph()
+		if book != nil {
+			offers, liquidityPool := graph.OrderBook(book[0], book[1])
+			for _, offer := range offers {
+				out.Offers = append(out.Offers, level{
+					Price:  fmt.Sprintf("%d/%d", offer.Price.N, offer.Price.D),
+					Amount: int64(offer.Amount),
+				})
+			}
+			if liquidityPool != nil {
+				out.Pool = reserves(*liquidityPool, book[0])
+			}
+		}
+		if paths != nil {
+			out.Paths, _, err = graph.FindFixedPaths(
+				ctx, *maxPathLength, paths[0], xdr.Int64(*sourceAmount), paths[1:], 5, true,
+			)
+			if err != nil {
+				log.WithField("err", err).WithField("ledger", ledger).Fatal("could not find paths")
+			}
+		}
+
+		if err = encoder.Encode(out); err != nil {
+			log.WithField("err", err).Fatal("could not write output")
+		}
+	}
+}
+
+func mustParseAssets(s string) []xdr.Asset {
+	assets, err := xdr.BuildAssets(s)
+	if err != nil {
+		log.WithField("err", err).Fatal("could not parse assets")
+	}
+	if len(assets) != 2 {
+		log.Fatalf("expected two assets in %s", s)
+	}
+	return assets
+}
+
+func reserves(entry xdr.LiquidityPoolEntry, selling xdr.Asset) *pool {
+	params := entry.Body.MustConstantProduct()
+	if params.Params.AssetA.Equals(selling) {
+		return &pool{
+			ReserveSelling: int64(params.ReserveA),
+			ReserveBuying:  int64(params.ReserveB),
+		}
+	}
+	return &pool{
+		ReserveSelling: int64(params.ReserveB),
+		ReserveBuying:  int64(params.ReserveA),
+	}
+}
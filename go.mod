@@ -33,6 +33,7 @@ require (
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/lib/pq v1.10.9
 	github.com/manucorporat/sse v0.0.0-20160126180136-ee05b128a739
+	github.com/miekg/pkcs11 v1.1.1
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.31.1
@@ -57,6 +58,8 @@ require (
 	github.com/yudai/gojsondiff v1.0.0 // indirect
 	github.com/yudai/golcs v0.0.0-20170316035057-ecda9a501e82 // indirect
 	github.com/yudai/pp v2.0.1+incompatible // indirect
+	golang.org/x/crypto v0.18.0
+	golang.org/x/text v0.14.0
 	google.golang.org/api v0.157.0
 	gopkg.in/gavv/httpexpect.v1 v1.1.3
 	gopkg.in/square/go-jose.v2 v2.6.0
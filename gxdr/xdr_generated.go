@@ -2923,9 +2923,10 @@ type Int64 = int64
 type CryptoKeyType int32
 
 const (
-	KEY_TYPE_ED25519     CryptoKeyType = 0
-	KEY_TYPE_PRE_AUTH_TX CryptoKeyType = 1
-	KEY_TYPE_HASH_X      CryptoKeyType = 2
+	KEY_TYPE_ED25519                CryptoKeyType = 0
+	KEY_TYPE_PRE_AUTH_TX            CryptoKeyType = 1
+	KEY_TYPE_HASH_X                 CryptoKeyType = 2
+	KEY_TYPE_ED25519_SIGNED_PAYLOAD CryptoKeyType = 3
 	// MUXED enum values for supported type are derived from the enum values
 	// above by ORing them with 0x100
 	KEY_TYPE_MUXED_ED25519 CryptoKeyType = CryptoKeyType(0x100)
@@ -2940,9 +2941,10 @@ const (
 type SignerKeyType int32
 
 const (
-	SIGNER_KEY_TYPE_ED25519     SignerKeyType = SignerKeyType(KEY_TYPE_ED25519)
-	SIGNER_KEY_TYPE_PRE_AUTH_TX SignerKeyType = SignerKeyType(KEY_TYPE_PRE_AUTH_TX)
-	SIGNER_KEY_TYPE_HASH_X      SignerKeyType = SignerKeyType(KEY_TYPE_HASH_X)
+	SIGNER_KEY_TYPE_ED25519                SignerKeyType = SignerKeyType(KEY_TYPE_ED25519)
+	SIGNER_KEY_TYPE_PRE_AUTH_TX            SignerKeyType = SignerKeyType(KEY_TYPE_PRE_AUTH_TX)
+	SIGNER_KEY_TYPE_HASH_X                 SignerKeyType = SignerKeyType(KEY_TYPE_HASH_X)
+	SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD SignerKeyType = SignerKeyType(KEY_TYPE_ED25519_SIGNED_PAYLOAD)
 )
 
 type PublicKey struct {
@@ -2961,9 +2963,17 @@ type SignerKey struct {
 	//      PreAuthTx() *Uint256
 	//   SIGNER_KEY_TYPE_HASH_X:
 	//      HashX() *Uint256
+	//   SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+	//      Ed25519SignedPayload() *XdrAnon_SignerKey_Ed25519SignedPayload
 	Type SignerKeyType
 	_u   interface{}
 }
+type XdrAnon_SignerKey_Ed25519SignedPayload struct {
+	/* Public key that must sign the payload. */
+	Ed25519 Uint256
+	/* Payload to be raw signed by ed25519. */
+	Payload []byte // bound 64
+}
 
 // variable size as the size depends on the signature scheme used
 type Signature = []byte // bound 64
@@ -18389,16 +18399,18 @@ func (XdrType_Int64) XdrTypeName() string  { return "Int64" }
 func (v XdrType_Int64) XdrUnwrap() XdrType { return v.XdrType_int64 }
 
 var _XdrNames_CryptoKeyType = map[int32]string{
-	int32(KEY_TYPE_ED25519):       "KEY_TYPE_ED25519",
-	int32(KEY_TYPE_PRE_AUTH_TX):   "KEY_TYPE_PRE_AUTH_TX",
-	int32(KEY_TYPE_HASH_X):        "KEY_TYPE_HASH_X",
-	int32(KEY_TYPE_MUXED_ED25519): "KEY_TYPE_MUXED_ED25519",
+	int32(KEY_TYPE_ED25519):                "KEY_TYPE_ED25519",
+	int32(KEY_TYPE_PRE_AUTH_TX):            "KEY_TYPE_PRE_AUTH_TX",
+	int32(KEY_TYPE_HASH_X):                 "KEY_TYPE_HASH_X",
+	int32(KEY_TYPE_ED25519_SIGNED_PAYLOAD): "KEY_TYPE_ED25519_SIGNED_PAYLOAD",
+	int32(KEY_TYPE_MUXED_ED25519):          "KEY_TYPE_MUXED_ED25519",
 }
 var _XdrValues_CryptoKeyType = map[string]int32{
-	"KEY_TYPE_ED25519":       int32(KEY_TYPE_ED25519),
-	"KEY_TYPE_PRE_AUTH_TX":   int32(KEY_TYPE_PRE_AUTH_TX),
-	"KEY_TYPE_HASH_X":        int32(KEY_TYPE_HASH_X),
-	"KEY_TYPE_MUXED_ED25519": int32(KEY_TYPE_MUXED_ED25519),
+	"KEY_TYPE_ED25519":                int32(KEY_TYPE_ED25519),
+	"KEY_TYPE_PRE_AUTH_TX":            int32(KEY_TYPE_PRE_AUTH_TX),
+	"KEY_TYPE_HASH_X":                 int32(KEY_TYPE_HASH_X),
+	"KEY_TYPE_ED25519_SIGNED_PAYLOAD": int32(KEY_TYPE_ED25519_SIGNED_PAYLOAD),
+	"KEY_TYPE_MUXED_ED25519":          int32(KEY_TYPE_MUXED_ED25519),
 }
 
 func (CryptoKeyType) XdrEnumNames() map[int32]string {
@@ -18489,14 +18501,16 @@ type XdrType_PublicKeyType = *PublicKeyType
 func XDR_PublicKeyType(v *PublicKeyType) *PublicKeyType { return v }
 
 var _XdrNames_SignerKeyType = map[int32]string{
-	int32(SIGNER_KEY_TYPE_ED25519):     "SIGNER_KEY_TYPE_ED25519",
-	int32(SIGNER_KEY_TYPE_PRE_AUTH_TX): "SIGNER_KEY_TYPE_PRE_AUTH_TX",
-	int32(SIGNER_KEY_TYPE_HASH_X):      "SIGNER_KEY_TYPE_HASH_X",
+	int32(SIGNER_KEY_TYPE_ED25519):                "SIGNER_KEY_TYPE_ED25519",
+	int32(SIGNER_KEY_TYPE_PRE_AUTH_TX):            "SIGNER_KEY_TYPE_PRE_AUTH_TX",
+	int32(SIGNER_KEY_TYPE_HASH_X):                 "SIGNER_KEY_TYPE_HASH_X",
+	int32(SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD): "SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD",
 }
 var _XdrValues_SignerKeyType = map[string]int32{
-	"SIGNER_KEY_TYPE_ED25519":     int32(SIGNER_KEY_TYPE_ED25519),
-	"SIGNER_KEY_TYPE_PRE_AUTH_TX": int32(SIGNER_KEY_TYPE_PRE_AUTH_TX),
-	"SIGNER_KEY_TYPE_HASH_X":      int32(SIGNER_KEY_TYPE_HASH_X),
+	"SIGNER_KEY_TYPE_ED25519":                int32(SIGNER_KEY_TYPE_ED25519),
+	"SIGNER_KEY_TYPE_PRE_AUTH_TX":            int32(SIGNER_KEY_TYPE_PRE_AUTH_TX),
+	"SIGNER_KEY_TYPE_HASH_X":                 int32(SIGNER_KEY_TYPE_HASH_X),
+	"SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD": int32(SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD),
 }
 
 func (SignerKeyType) XdrEnumNames() map[int32]string {
@@ -18605,10 +18619,32 @@ func (u *PublicKey) XdrRecurse(x XDR, name string) {
 }
 func XDR_PublicKey(v *PublicKey) *PublicKey { return v }
 
+type XdrType_XdrAnon_SignerKey_Ed25519SignedPayload = *XdrAnon_SignerKey_Ed25519SignedPayload
+
+func (v *XdrAnon_SignerKey_Ed25519SignedPayload) XdrPointer() interface{} { return v }
+func (XdrAnon_SignerKey_Ed25519SignedPayload) XdrTypeName() string {
+	return "XdrAnon_SignerKey_Ed25519SignedPayload"
+}
+func (v XdrAnon_SignerKey_Ed25519SignedPayload) XdrValue() interface{} { return v }
+func (v *XdrAnon_SignerKey_Ed25519SignedPayload) XdrMarshal(x XDR, name string) {
+	x.Marshal(name, v)
+}
+func (v *XdrAnon_SignerKey_Ed25519SignedPayload) XdrRecurse(x XDR, name string) {
+	if name != "" {
+		name = x.Sprintf("%s.", name)
+	}
+	x.Marshal(x.Sprintf("%sed25519", name), XDR_Uint256(&v.Ed25519))
+	x.Marshal(x.Sprintf("%spayload", name), XdrVecOpaque{&v.Payload, 64})
+}
+func XDR_XdrAnon_SignerKey_Ed25519SignedPayload(v *XdrAnon_SignerKey_Ed25519SignedPayload) *XdrAnon_SignerKey_Ed25519SignedPayload {
+	return v
+}
+
 var _XdrTags_SignerKey = map[int32]bool{
-	XdrToI32(SIGNER_KEY_TYPE_ED25519):     true,
-	XdrToI32(SIGNER_KEY_TYPE_PRE_AUTH_TX): true,
-	XdrToI32(SIGNER_KEY_TYPE_HASH_X):      true,
+	XdrToI32(SIGNER_KEY_TYPE_ED25519):                true,
+	XdrToI32(SIGNER_KEY_TYPE_PRE_AUTH_TX):            true,
+	XdrToI32(SIGNER_KEY_TYPE_HASH_X):                 true,
+	XdrToI32(SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD): true,
 }
 
 func (_ SignerKey) XdrValidTags() map[int32]bool {
@@ -18663,9 +18699,24 @@ func (u *SignerKey) HashX() *Uint256 {
 		return nil
 	}
 }
+func (u *SignerKey) Ed25519SignedPayload() *XdrAnon_SignerKey_Ed25519SignedPayload {
+	switch u.Type {
+	case SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+		if v, ok := u._u.(*XdrAnon_SignerKey_Ed25519SignedPayload); ok {
+			return v
+		} else {
+			var zero XdrAnon_SignerKey_Ed25519SignedPayload
+			u._u = &zero
+			return &zero
+		}
+	default:
+		XdrPanic("SignerKey.Ed25519SignedPayload accessed when Type == %v", u.Type)
+		return nil
+	}
+}
 func (u SignerKey) XdrValid() bool {
 	switch u.Type {
-	case SIGNER_KEY_TYPE_ED25519, SIGNER_KEY_TYPE_PRE_AUTH_TX, SIGNER_KEY_TYPE_HASH_X:
+	case SIGNER_KEY_TYPE_ED25519, SIGNER_KEY_TYPE_PRE_AUTH_TX, SIGNER_KEY_TYPE_HASH_X, SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
 		return true
 	}
 	return false
@@ -18684,6 +18735,8 @@ func (u *SignerKey) XdrUnionBody() XdrType {
 		return XDR_Uint256(u.PreAuthTx())
 	case SIGNER_KEY_TYPE_HASH_X:
 		return XDR_Uint256(u.HashX())
+	case SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+		return XDR_XdrAnon_SignerKey_Ed25519SignedPayload(u.Ed25519SignedPayload())
 	}
 	return nil
 }
@@ -18695,6 +18748,8 @@ func (u *SignerKey) XdrUnionBodyName() string {
 		return "PreAuthTx"
 	case SIGNER_KEY_TYPE_HASH_X:
 		return "HashX"
+	case SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+		return "Ed25519SignedPayload"
 	}
 	return ""
 }
@@ -18720,6 +18775,9 @@ func (u *SignerKey) XdrRecurse(x XDR, name string) {
 	case SIGNER_KEY_TYPE_HASH_X:
 		x.Marshal(x.Sprintf("%shashX", name), XDR_Uint256(u.HashX()))
 		return
+	case SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+		x.Marshal(x.Sprintf("%sed25519SignedPayload", name), XDR_XdrAnon_SignerKey_Ed25519SignedPayload(u.Ed25519SignedPayload()))
+		return
 	}
 	XdrPanic("invalid Type (%v) in SignerKey", u.Type)
 }
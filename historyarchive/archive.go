@@ -393,37 +393,47 @@ func Connect(u string, opts ConnectOptions) (*Archive, error) {
 		arch.checkpointFiles[cat] = make(map[uint32]bool)
 	}
 
+	backend, err := ConnectBackend(u, opts)
+	arch.backend = backend
+	return &arch, err
+}
+
+// ConnectBackend returns the ArchiveBackend for the given URL without wrapping
+// it in an Archive. It supports the same schemes as Connect and can be used to
+// store arbitrary files in a local or object store.
+func ConnectBackend(u string, opts ConnectOptions) (ArchiveBackend, error) {
 	if u == "" {
-		return &arch, errors.New("URL is empty")
+		return nil, errors.New("URL is empty")
 	}
 
 	parsed, err := url.Parse(u)
 	if err != nil {
-		return &arch, err
+		return nil, err
 	}
 
 	if opts.Context == nil {
 		opts.Context = context.Background()
 	}
 
+	var backend ArchiveBackend
 	pth := parsed.Path
 	if parsed.Scheme == "s3" {
 		// Inside s3, all paths start _without_ the leading /
 		if len(pth) > 0 && pth[0] == '/' {
 			pth = pth[1:]
 		}
-		arch.backend, err = makeS3Backend(parsed.Host, pth, opts)
+		backend, err = makeS3Backend(parsed.Host, pth, opts)
 	} else if parsed.Scheme == "file" {
 		pth = path.Join(parsed.Host, pth)
-		arch.backend = makeFsBackend(pth, opts)
+		backend = makeFsBackend(pth, opts)
 	} else if parsed.Scheme == "http" || parsed.Scheme == "https" {
-		arch.backend = makeHttpBackend(parsed, opts)
+		backend = makeHttpBackend(parsed, opts)
 	} else if parsed.Scheme == "mock" {
-		arch.backend = makeMockBackend(opts)
+		backend = makeMockBackend(opts)
 	} else {
 		err = errors.New("unknown URL scheme: '" + parsed.Scheme + "'")
 	}
-	return &arch, err
+	return backend, err
 }
 
 func MustConnect(u string, opts ConnectOptions) *Archive {
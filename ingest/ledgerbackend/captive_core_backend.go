@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -17,6 +18,28 @@ import (
 // Ensure CaptiveDiamcircleCore implements LedgerBackend
 var _ LedgerBackend = (*CaptiveDiamcircleCore)(nil)
 
+const (
+	// maxRestartBackoff is the maximum delay before restarting Diamcircle-Core
+	// after it exited unexpectedly.
+	maxRestartBackoff = time.Minute
+	// restartResetLedgers is the number of ledgers Diamcircle-Core must stream
+	// after a restart for the restart not to count towards MaxRestarts anymore.
+	restartResetLedgers = 64
+)
+
+// diamcircleCoreExitedError is returned when the Diamcircle-Core process
+// exited unexpectedly.
+type diamcircleCoreExitedError struct {
+	exitErr error
+}
+
+func (e diamcircleCoreExitedError) Error() string {
+	if e.exitErr == nil {
+		return "diamcircle core exited unexpectedly"
+	}
+	return "diamcircle core exited unexpectedly: " + e.exitErr.Error()
+}
+
 func (c *CaptiveDiamcircleCore) roundDownToFirstReplayAfterCheckpointStart(ledger uint32) uint32 {
 	r := c.checkpointManager.GetCheckpointRange(ledger)
 	if r.Low <= 1 {
@@ -60,6 +83,13 @@ func (c *CaptiveDiamcircleCore) roundDownToFirstReplayAfterCheckpointStart(ledge
 //     has a very small internal buffer and Diamcircle-Core will not close the new
 //     ledger if it's not read.
 //
+// When MaxRestarts is set, a Diamcircle-Core process streaming an
+// UnboundedRange which exits unexpectedly is restarted from the last streamed
+// ledger, with exponential backoff, instead of making GetLedger fail. Its
+// storage is reused unless it was terminated by a signal. The status of
+// Diamcircle-Core and its recent log lines are returned by Status and
+// RecentLogLines.
+//
 // Except for the Close function, CaptiveDiamcircleCore is not thread-safe and should
 // not be accessed by multiple go routines. Close is thread-safe and can be called
 // from another go routine. Once Close is called it will interrupt and cancel any
@@ -70,11 +100,15 @@ type CaptiveDiamcircleCore struct {
 	archive           historyarchive.ArchiveInterface
 	checkpointManager historyarchive.CheckpointManager
 	ledgerHashStore   TrustedLedgerHashStore
+	log               *log.Entry
 
 	// cancel is the CancelFunc for context which controls the lifetime of a CaptiveDiamcircleCore instance.
 	// Once it is invoked CaptiveDiamcircleCore will not be able to stream ledgers from Diamcircle Core or
 	// spawn new instances of Diamcircle Core.
 	cancel context.CancelFunc
+	// ctx is the context cancelled by cancel. Restarts are abandoned once it
+	// is done.
+	ctx context.Context
 
 	diamcircleCoreRunner diamcircleCoreRunnerInterface
 	// diamcircleCoreLock protects access to diamcircleCoreRunner. When the read lock
@@ -85,6 +119,15 @@ type CaptiveDiamcircleCore struct {
 	// For testing
 	diamcircleCoreRunnerFactory func(mode diamcircleCoreRunnerMode) (diamcircleCoreRunnerInterface, error)
 
+	monitor *captiveCoreMonitor
+	// maxRestarts is the number of consecutive restarts of Diamcircle-Core
+	// after unexpected exits, 0 if it is not restarted.
+	maxRestarts         int
+	consecutiveRestarts int
+	// ledgersSinceRestart is the number of ledgers streamed since the last
+	// restart.
+	ledgersSinceRestart int
+
 	// cachedMeta keeps that ledger data of the last fetched ledger. Updated in GetLedger().
 	cachedMeta *xdr.LedgerCloseMeta
 
@@ -124,6 +167,16 @@ type CaptiveCoreConfig struct {
 	// stored. We always append /captive-core to this directory, since we clean
 	// it up entirely on shutdown.
 	StoragePath string
+	// MaxRestarts is the (optional) number of consecutive times a
+	// Diamcircle-Core process streaming an UnboundedRange is restarted after
+	// exiting unexpectedly, before GetLedger returns an error. Restarts are
+	// delayed with an exponential backoff, from 1 second up to 1 minute. A
+	// restart stops counting once Diamcircle-Core streamed 64 ledgers. If
+	// MaxRestarts is 0 Diamcircle-Core is not restarted.
+	MaxRestarts int
+	// LogLines is the (optional) number of recent Diamcircle-Core log lines
+	// returned by RecentLogLines. It defaults to 100.
+	LogLines int
 }
 
 // NewCaptive returns a new CaptiveDiamcircleCore instance.
@@ -159,15 +212,29 @@ func NewCaptive(config CaptiveCoreConfig) (*CaptiveDiamcircleCore, error) {
 		return nil, errors.Wrap(err, "Error connecting to ALL history archives.")
 	}
 
+	var httpPort uint
+	if config.Toml != nil {
+		httpPort = config.Toml.HTTPPort
+	}
+
 	c := &CaptiveDiamcircleCore{
 		archive:           &archivePool,
 		ledgerHashStore:   config.LedgerHashStore,
+		log:               config.Log,
 		cancel:            cancel,
+		ctx:               config.Context,
 		checkpointManager: historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+		monitor:           newCaptiveCoreMonitor(httpPort, config.LogLines),
+		maxRestarts:       config.MaxRestarts,
 	}
 
 	c.diamcircleCoreRunnerFactory = func(mode diamcircleCoreRunnerMode) (diamcircleCoreRunnerInterface, error) {
-		return newDiamcircleCoreRunner(config, mode)
+		runner, err := newDiamcircleCoreRunner(config, mode)
+		if err != nil {
+			return nil, err
+		}
+		runner.monitor = c.monitor
+		return runner, nil
 	}
 	return c, nil
 }
@@ -274,6 +341,7 @@ func (c *CaptiveDiamcircleCore) openOnlineReplaySubprocess(ctx context.Context,
 	c.prepared = &ran
 	c.lastLedger = nil
 	c.previousLedgerHash = nil
+	c.consecutiveRestarts = 0
 
 	return nil
 }
@@ -502,6 +570,12 @@ func (c *CaptiveDiamcircleCore) GetLedger(ctx context.Context, sequence uint32)
 			return xdr.LedgerCloseMeta{}, ctx.Err()
 		case result, ok := <-c.diamcircleCoreRunner.getMetaPipe():
 			found, ledger, err := c.handleMetaPipeResult(sequence, result, ok)
+			if err != nil && c.canRestart(err) {
+				if restartErr := c.restart(ctx, err); restartErr != nil {
+					return xdr.LedgerCloseMeta{}, errors.Wrapf(restartErr, "error restarting diamcircle-core (%v)", err)
+				}
+				continue
+			}
 			if found || err != nil {
 				return ledger, err
 			}
@@ -547,6 +621,10 @@ func (c *CaptiveDiamcircleCore) handleMetaPipeResult(sequence uint32, result met
 	}
 
 	c.nextLedger = result.LedgerSequence() + 1
+	c.ledgersSinceRestart++
+	if c.ledgersSinceRestart >= restartResetLedgers {
+		c.consecutiveRestarts = 0
+	}
 	currentLedgerHash := result.LedgerCloseMeta.LedgerHash().HexString()
 	c.previousLedgerHash = &currentLedgerHash
 
@@ -581,11 +659,7 @@ func (c *CaptiveDiamcircleCore) checkMetaPipeResult(result metaResult, ok bool)
 			return result.err
 		} else if exited, err := c.diamcircleCoreRunner.getProcessExitError(); exited {
 			// Case 2 - The diamcircle core process exited unexpectedly
-			if err == nil {
-				return errors.Errorf("diamcircle core exited unexpectedly")
-			} else {
-				return errors.Wrap(err, "diamcircle core exited unexpectedly")
-			}
+			return diamcircleCoreExitedError{exitErr: err}
 		} else if !ok {
 			// This case should never happen because the ledger buffer channel can only be closed
 			// if and only if the process exits or the context is cancelled.
@@ -596,6 +670,90 @@ func (c *CaptiveDiamcircleCore) checkMetaPipeResult(result metaResult, ok bool)
 	return nil
 }
 
+// canRestart returns true if Diamcircle-Core exited unexpectedly with err and
+// can be restarted from the last streamed ledger.
+func (c *CaptiveDiamcircleCore) canRestart(err error) bool {
+	if _, exited := err.(diamcircleCoreExitedError); !exited {
+		return false
+	}
+	return c.consecutiveRestarts < c.maxRestarts &&
+		!c.isClosed() &&
+		c.lastLedger == nil &&
+		c.nextLedger != 0 &&
+		c.previousLedgerHash != nil
+}
+
+// restart starts a new Diamcircle-Core process streaming ledgers from
+// nextLedger, after a backoff delay.
+func (c *CaptiveDiamcircleCore) restart(ctx context.Context, exitErr error) error {
+	backoff := maxRestartBackoff
+	if c.consecutiveRestarts < 6 {
+		backoff = time.Second << uint(c.consecutiveRestarts)
+	}
+	c.log.WithError(exitErr).Warnf(
+		"Restarting diamcircle-core from ledger %d in %s (restart %d of %d)",
+		c.nextLedger, backoff, c.consecutiveRestarts+1, c.maxRestarts,
+	)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return errors.New("diamcircle-core is no longer usable")
+	case <-time.After(backoff):
+	}
+
+	// restart is called by GetLedger which holds the read lock. It is upgraded
+	// to the write lock so that Close either sees the new runner or prevents
+	// it from being started.
+	exited := c.diamcircleCoreRunner
+	c.diamcircleCoreLock.RUnlock()
+	c.diamcircleCoreLock.Lock()
+	defer func() {
+		c.diamcircleCoreLock.Unlock()
+		c.diamcircleCoreLock.RLock()
+	}()
+
+	if c.isClosed() {
+		return errors.New("diamcircle-core is no longer usable")
+	}
+	if c.diamcircleCoreRunner != exited {
+		return errors.New("diamcircle-core was prepared again during the restart")
+	}
+
+	// The previous process is closed by handleMetaPipeResult. If the new one
+	// cannot be started, PrepareRange must be called again.
+	c.prepared = nil
+	runner, err := c.diamcircleCoreRunnerFactory(diamcircleCoreRunnerModeOnline)
+	if err != nil {
+		return errors.Wrap(err, "error creating diamcircle-core runner")
+	}
+	c.diamcircleCoreRunner = runner
+	if err = runner.runFrom(c.nextLedger-1, *c.previousLedgerHash); err != nil {
+		runner.close()
+		return errors.Wrap(err, "error running diamcircle-core")
+	}
+
+	ran := UnboundedRange(c.nextLedger)
+	c.prepared = &ran
+	c.consecutiveRestarts++
+	c.ledgersSinceRestart = 0
+	c.monitor.restarted()
+	return nil
+}
+
+// Status returns the status of the Diamcircle-Core process. The /info endpoint
+// of Diamcircle-Core is queried when its HTTP server is enabled.
+// Status is thread-safe.
+func (c *CaptiveDiamcircleCore) Status(ctx context.Context) CaptiveCoreStatus {
+	return c.monitor.getStatus(ctx)
+}
+
+// RecentLogLines returns the recent lines logged by Diamcircle-Core, oldest
+// first. RecentLogLines is thread-safe.
+func (c *CaptiveDiamcircleCore) RecentLogLines() []CaptiveCoreLogLine {
+	return c.monitor.recentLogLines()
+}
+
 // GetLatestLedgerSequence returns the sequence of the latest ledger available
 // in the backend. This method returns an error if not in a session (start with
 // PrepareRange).
@@ -12,6 +12,7 @@ import (
 	"github.com/diamcircle/go/historyarchive"
 	"github.com/diamcircle/go/network"
 	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/log"
 	"github.com/diamcircle/go/xdr"
 )
 
@@ -1421,3 +1422,132 @@ func TestCaptivePreviousLedgerCheck(t *testing.T) {
 	mockArchive.AssertExpectations(t)
 	mockLedgerHashStore.AssertExpectations(t)
 }
+
+func TestCaptiveRestartAfterUnexpectedExit(t *testing.T) {
+	ctx := context.Background()
+	hash := func(i int) string {
+		return fmt.Sprintf("%02x00000000000000000000000000000000000000000000000000000000000000", i)
+	}
+	meta300 := buildLedgerCloseMeta(testLedgerHeader{sequence: 300, hash: hash(3), previousLedgerHash: hash(2)})
+	meta301 := buildLedgerCloseMeta(testLedgerHeader{sequence: 301, hash: hash(4), previousLedgerHash: hash(3)})
+
+	metaChan := make(chan metaResult, 1)
+	metaChan <- metaResult{LedgerCloseMeta: &meta300}
+	close(metaChan)
+	mockRunner := &diamcircleCoreRunnerMock{}
+	mockRunner.On("runFrom", uint32(299), "0101010100000000000000000000000000000000000000000000000000000000").Return(nil).Once()
+	mockRunner.On("getMetaPipe").Return((<-chan metaResult)(metaChan))
+	mockRunner.On("context").Return(ctx)
+	mockRunner.On("getProcessExitError").Return(true, fmt.Errorf("signal kill"))
+	mockRunner.On("close").Return(nil).Once()
+
+	// The restarted runner streams ledgers from the last streamed ledger.
+	restartedMetaChan := make(chan metaResult, 1)
+	restartedMetaChan <- metaResult{LedgerCloseMeta: &meta301}
+	close(restartedMetaChan)
+	restartedRunner := &diamcircleCoreRunnerMock{}
+	restartedRunner.On("runFrom", uint32(300), hash(3)).Return(nil).Once()
+	restartedRunner.On("getMetaPipe").Return((<-chan metaResult)(restartedMetaChan))
+	restartedRunner.On("context").Return(ctx)
+	restartedRunner.On("getProcessExitError").Return(true, fmt.Errorf("signal kill"))
+	restartedRunner.On("close").Return(nil).Once()
+
+	mockArchive := &historyarchive.MockArchive{}
+	mockArchive.
+		On("GetRootHAS").
+		Return(historyarchive.HistoryArchiveState{
+			CurrentLedger: uint32(255),
+		}, nil)
+	mockArchive.
+		On("GetLedgerHeader", uint32(300)).
+		Return(xdr.LedgerHeaderHistoryEntry{
+			Header: xdr.LedgerHeader{
+				PreviousLedgerHash: xdr.Hash{1, 1, 1, 1},
+			},
+		}, nil).Once()
+
+	runners := []diamcircleCoreRunnerInterface{mockRunner, restartedRunner}
+	captiveBackend := CaptiveDiamcircleCore{
+		archive: mockArchive,
+		diamcircleCoreRunnerFactory: func(mode diamcircleCoreRunnerMode) (diamcircleCoreRunnerInterface, error) {
+			assert.Equal(t, diamcircleCoreRunnerModeOnline, mode)
+			runner := runners[0]
+			runners = runners[1:]
+			return runner, nil
+		},
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+		log:               log.New(),
+		monitor:           newCaptiveCoreMonitor(0, 0),
+		maxRestarts:       1,
+		ctx:               ctx,
+	}
+
+	assert.NoError(t, captiveBackend.PrepareRange(ctx, UnboundedRange(300)))
+
+	meta, err := captiveBackend.GetLedger(ctx, 301)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(301), meta.LedgerSequence())
+	assert.Equal(t, 1, captiveBackend.Status(ctx).Restarts)
+
+	// The number of consecutive restarts is exhausted.
+	_, err = captiveBackend.GetLedger(ctx, 302)
+	assert.EqualError(t, err, "diamcircle core exited unexpectedly: signal kill")
+
+	mockArchive.AssertExpectations(t)
+	mockRunner.AssertExpectations(t)
+	restartedRunner.AssertExpectations(t)
+}
+
+func TestCaptiveRestartAbandonedAfterCancel(t *testing.T) {
+	ctx := context.Background()
+	backendCtx, cancel := context.WithCancel(ctx)
+	meta300 := buildLedgerCloseMeta(testLedgerHeader{sequence: 300})
+
+	metaChan := make(chan metaResult, 1)
+	metaChan <- metaResult{LedgerCloseMeta: &meta300}
+	close(metaChan)
+	mockRunner := &diamcircleCoreRunnerMock{}
+	mockRunner.On("runFrom", uint32(299), "0101010100000000000000000000000000000000000000000000000000000000").Return(nil).Once()
+	mockRunner.On("getMetaPipe").Return((<-chan metaResult)(metaChan))
+	mockRunner.On("context").Return(ctx)
+	mockRunner.On("getProcessExitError").Return(true, fmt.Errorf("signal kill"))
+	mockRunner.On("close").Return(nil).Once()
+
+	mockArchive := &historyarchive.MockArchive{}
+	mockArchive.
+		On("GetRootHAS").
+		Return(historyarchive.HistoryArchiveState{
+			CurrentLedger: uint32(255),
+		}, nil)
+	mockArchive.
+		On("GetLedgerHeader", uint32(300)).
+		Return(xdr.LedgerHeaderHistoryEntry{
+			Header: xdr.LedgerHeader{
+				PreviousLedgerHash: xdr.Hash{1, 1, 1, 1},
+			},
+		}, nil).Once()
+
+	captiveBackend := CaptiveDiamcircleCore{
+		archive: mockArchive,
+		diamcircleCoreRunnerFactory: func(mode diamcircleCoreRunnerMode) (diamcircleCoreRunnerInterface, error) {
+			return mockRunner, nil
+		},
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+		log:               log.New(),
+		monitor:           newCaptiveCoreMonitor(0, 0),
+		maxRestarts:       1,
+		cancel:            cancel,
+		ctx:               backendCtx,
+	}
+
+	assert.NoError(t, captiveBackend.PrepareRange(ctx, UnboundedRange(300)))
+
+	// Once the backend context is cancelled no new process is started.
+	cancel()
+	_, err := captiveBackend.GetLedger(ctx, 301)
+	assert.EqualError(t, err, "error restarting diamcircle-core (diamcircle core exited unexpectedly: signal kill): diamcircle-core is no longer usable")
+	assert.Equal(t, 0, captiveBackend.Status(ctx).Restarts)
+
+	mockArchive.AssertExpectations(t)
+	mockRunner.AssertExpectations(t)
+}
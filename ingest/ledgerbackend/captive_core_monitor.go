@@ -0,0 +1,226 @@
+package ledgerbackend
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diamcircle/go/clients/diamcirclecore"
+	proto "github.com/diamcircle/go/protocols/diamcirclecore"
+)
+
+// defaultLogLines is the default number of recent Diamcircle-Core log lines
+// kept by CaptiveDiamcircleCore.
+const defaultLogLines = 100
+
+var (
+	stateChangeRx     = regexp.MustCompile(`Changing state \S+ -> (\S+)`)
+	catchupTargetRx   = regexp.MustCompile(`Catching up to ledger (\d+)`)
+	catchupProgressRx = regexp.MustCompile(`(\d+(?:\.\d+)?)%`)
+)
+
+// CaptiveCoreLogLine is a line logged by the Diamcircle-Core process.
+type CaptiveCoreLogLine struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// CaptiveCoreStatus describes the Diamcircle-Core process run by
+// CaptiveDiamcircleCore.
+type CaptiveCoreStatus struct {
+	// Running is true while a Diamcircle-Core process is running.
+	Running bool
+	// PID is the process id of the running Diamcircle-Core process.
+	PID int
+	// StartedAt is the time the last Diamcircle-Core process was started.
+	StartedAt time.Time
+	// Restarts is the number of times Diamcircle-Core was restarted after
+	// exiting unexpectedly.
+	Restarts int
+	// LastExitError is the error the last Diamcircle-Core process exited with.
+	LastExitError string
+	// State is the last state Diamcircle-Core logged it changed to, for
+	// example LM_SYNCED_STATE.
+	State string
+	// CatchupTargetLedger is the ledger Diamcircle-Core last logged it is
+	// catching up to.
+	CatchupTargetLedger uint32
+	// CatchupProgress is the catchup progress last logged by Diamcircle-Core,
+	// between 0 and 1, or -1 when unknown.
+	CatchupProgress float64
+	// ResidentMemoryBytes is the resident memory of the running Diamcircle-Core
+	// process, 0 when unknown.
+	ResidentMemoryBytes uint64
+	// Info is the response of the Diamcircle-Core /info endpoint, nil when
+	// the HTTP server of Diamcircle-Core is disabled or unreachable.
+	Info *proto.InfoResponse
+}
+
+// captiveCoreMonitor keeps track of the Diamcircle-Core processes run by
+// CaptiveDiamcircleCore: their lifecycle, the state parsed from their log and
+// their recent log lines. It is safe for concurrent use and all its methods can
+// be called on a nil monitor, which does nothing.
+type captiveCoreMonitor struct {
+	httpPort uint
+
+	lock     sync.Mutex
+	status   CaptiveCoreStatus
+	logLines []CaptiveCoreLogLine
+	// logStart is the index of the oldest line of logLines once it is full.
+	logStart    int
+	maxLogLines int
+}
+
+func newCaptiveCoreMonitor(httpPort uint, maxLogLines int) *captiveCoreMonitor {
+	if maxLogLines <= 0 {
+		maxLogLines = defaultLogLines
+	}
+	return &captiveCoreMonitor{
+		httpPort:    httpPort,
+		maxLogLines: maxLogLines,
+		status:      CaptiveCoreStatus{CatchupProgress: -1},
+	}
+}
+
+// processStarted records that a Diamcircle-Core process was started.
+func (m *captiveCoreMonitor) processStarted(pid int) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status.Running = true
+	m.status.PID = pid
+	m.status.StartedAt = time.Now()
+	m.status.State = ""
+	m.status.CatchupTargetLedger = 0
+	m.status.CatchupProgress = -1
+}
+
+// processExited records that the Diamcircle-Core process exited.
+func (m *captiveCoreMonitor) processExited(err error) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status.Running = false
+	m.status.PID = 0
+	m.status.LastExitError = ""
+	if err != nil {
+		m.status.LastExitError = err.Error()
+	}
+}
+
+// restarted records that Diamcircle-Core was restarted after exiting
+// unexpectedly.
+func (m *captiveCoreMonitor) restarted() {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status.Restarts++
+}
+
+// observeLine records a line logged by Diamcircle-Core and updates the state
+// parsed from the log.
+func (m *captiveCoreMonitor) observeLine(line string) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	entry := CaptiveCoreLogLine{Time: time.Now(), Line: line}
+	if len(m.logLines) < m.maxLogLines {
+		m.logLines = append(m.logLines, entry)
+	} else {
+		m.logLines[m.logStart] = entry
+		m.logStart = (m.logStart + 1) % m.maxLogLines
+	}
+
+	if matches := stateChangeRx.FindStringSubmatch(line); matches != nil {
+		m.status.State = matches[1]
+		if m.status.State == "LM_SYNCED_STATE" {
+			m.status.CatchupProgress = -1
+		}
+	}
+	if matches := catchupTargetRx.FindStringSubmatch(line); matches != nil {
+		if target, err := strconv.ParseUint(matches[1], 10, 32); err == nil {
+			m.status.CatchupTargetLedger = uint32(target)
+		}
+		if progress := catchupProgressRx.FindStringSubmatch(line); progress != nil {
+			if percent, err := strconv.ParseFloat(progress[1], 64); err == nil {
+				m.status.CatchupProgress = percent / 100
+			}
+		}
+	}
+}
+
+// recentLogLines returns the recent log lines, oldest first.
+func (m *captiveCoreMonitor) recentLogLines() []CaptiveCoreLogLine {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	lines := make([]CaptiveCoreLogLine, 0, len(m.logLines))
+	lines = append(lines, m.logLines[m.logStart:]...)
+	return append(lines, m.logLines[:m.logStart]...)
+}
+
+// getStatus returns the status of Diamcircle-Core. The /info endpoint of
+// Diamcircle-Core is queried when its HTTP server is enabled.
+func (m *captiveCoreMonitor) getStatus(ctx context.Context) CaptiveCoreStatus {
+	if m == nil {
+		return CaptiveCoreStatus{CatchupProgress: -1}
+	}
+	m.lock.Lock()
+	status := m.status
+	m.lock.Unlock()
+
+	if !status.Running {
+		return status
+	}
+	if memory, err := residentMemory(status.PID); err == nil {
+		status.ResidentMemoryBytes = memory
+	}
+	if m.httpPort != 0 {
+		client := diamcirclecore.Client{
+			HTTP: &http.Client{
+				Timeout: 2 * time.Second,
+			},
+			URL: fmt.Sprintf("http://localhost:%d", m.httpPort),
+		}
+		if info, err := client.Info(ctx); err == nil {
+			status.Info = info
+		}
+	}
+	return status
+}
+
+// residentMemory returns the resident memory of a process. It is only
+// supported on Linux.
+func residentMemory(pid int) (uint64, error) {
+	statm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm content: %s", statm)
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
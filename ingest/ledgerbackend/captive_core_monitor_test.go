@@ -0,0 +1,67 @@
+package ledgerbackend
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaptiveCoreMonitorLog(t *testing.T) {
+	monitor := newCaptiveCoreMonitor(0, 2)
+	monitor.processStarted(123)
+
+	monitor.observeLine("[Ledger INFO] Changing state LM_BOOTING_STATE -> LM_CATCHING_UP_STATE")
+	monitor.observeLine("[History INFO] Catching up to ledger 1234: Applying buckets 45%. Currently on level 3")
+	status := monitor.getStatus(context.Background())
+	assert.True(t, status.Running)
+	assert.Equal(t, 123, status.PID)
+	assert.Equal(t, "LM_CATCHING_UP_STATE", status.State)
+	assert.Equal(t, uint32(1234), status.CatchupTargetLedger)
+	assert.Equal(t, 0.45, status.CatchupProgress)
+
+	monitor.observeLine("[Ledger INFO] Changing state LM_CATCHING_UP_STATE -> LM_SYNCED_STATE")
+	status = monitor.getStatus(context.Background())
+	assert.Equal(t, "LM_SYNCED_STATE", status.State)
+	assert.Equal(t, float64(-1), status.CatchupProgress)
+
+	// Only the last 2 lines are kept.
+	var lines []string
+	for _, line := range monitor.recentLogLines() {
+		lines = append(lines, line.Line)
+	}
+	assert.Equal(t, []string{
+		"[History INFO] Catching up to ledger 1234: Applying buckets 45%. Currently on level 3",
+		"[Ledger INFO] Changing state LM_CATCHING_UP_STATE -> LM_SYNCED_STATE",
+	}, lines)
+
+	monitor.processExited(errors.New("signal: killed"))
+	status = monitor.getStatus(context.Background())
+	assert.False(t, status.Running)
+	assert.Equal(t, "signal: killed", status.LastExitError)
+
+	// A nil monitor does nothing.
+	var nilMonitor *captiveCoreMonitor
+	nilMonitor.observeLine("line")
+	assert.Empty(t, nilMonitor.recentLogLines())
+	assert.False(t, nilMonitor.getStatus(context.Background()).Running)
+}
+
+func TestStorageReusableAfterExit(t *testing.T) {
+	assert.True(t, storageReusableAfterExit(nil))
+	assert.True(t, storageReusableAfterExit(context.Canceled))
+	assert.False(t, storageReusableAfterExit(errors.New("some error")))
+
+	if runtime.GOOS == "windows" {
+		t.Skip("processes cannot be terminated by a signal on windows")
+	}
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	assert.True(t, storageReusableAfterExit(err))
+
+	err = exec.Command("sh", "-c", "kill -9 $$").Run()
+	assert.False(t, storageReusableAfterExit(err))
+}
@@ -46,6 +46,21 @@ func (r Range) String() string {
 	return fmt.Sprintf("[%d,latest)", r.from)
 }
 
+// From returns the first ledger of the range.
+func (r Range) From() uint32 {
+	return r.from
+}
+
+// To returns the last ledger of a bounded range.
+func (r Range) To() uint32 {
+	return r.to
+}
+
+// Bounded returns true if the range has a last ledger.
+func (r Range) Bounded() bool {
+	return r.bounded
+}
+
 func (r Range) Contains(other Range) bool {
 	if r.bounded && !other.bounded {
 		return false
@@ -1,9 +1,11 @@
 package ledgerbackend
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -12,10 +14,16 @@ import (
 	"sync"
 	"time"
 
+	xdr3 "github.com/diamcircle/go-xdr/xdr3"
+
 	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/xdr"
 )
 
+// errStreamingNotSupported is returned when the captive core server does not
+// support streaming ledgers.
+var errStreamingNotSupported = errors.New("captive core server does not support streaming ledgers")
+
 // PrepareRangeResponse describes the status of the pending PrepareRange operation.
 type PrepareRangeResponse struct {
 	LedgerRange   Range     `json:"ledgerRange"`
@@ -61,11 +69,43 @@ func (r Base64Ledger) MarshalJSON() ([]byte, error) {
 }
 
 // RemoteCaptiveDiamcircleCore is an http client for interacting with a remote captive core server.
+//
+// Ledgers are streamed from the server over a long-lived request, falling back
+// to requesting each ledger when the server does not support streaming.
 type RemoteCaptiveDiamcircleCore struct {
 	url                      *url.URL
 	client                   *http.Client
+	streamClient             *http.Client
 	lock                     *sync.Mutex
+	stream                   *ledgerStream
+	ctx                      context.Context
+	cancel                   context.CancelFunc
 	prepareRangePollInterval time.Duration
+	consumerName             string
+}
+
+// ledgerStream is a long-lived request streaming consecutive ledgers from the
+// captive core server.
+type ledgerStream struct {
+	cancel  context.CancelFunc
+	body    io.ReadCloser
+	decoder *xdr3.Decoder
+	// next is the sequence of the next ledger in the stream.
+	next uint32
+	// unsupported is true when the server does not support streaming.
+	unsupported bool
+}
+
+func (s *ledgerStream) close() {
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+	s.decoder = nil
 }
 
 // RemoteCaptiveOption values can be passed into NewRemoteCaptive to customize a RemoteCaptiveDiamcircleCore instance.
@@ -79,6 +119,14 @@ func PrepareRangePollInterval(d time.Duration) RemoteCaptiveOption {
 	}
 }
 
+// ConsumerName configures the name the captive core server reports for this
+// client when listing the consumers streaming ledgers.
+func ConsumerName(name string) RemoteCaptiveOption {
+	return func(c *RemoteCaptiveDiamcircleCore) {
+		c.consumerName = name
+	}
+}
+
 // NewRemoteCaptive returns a new RemoteCaptiveDiamcircleCore instance.
 //
 // Only the captiveCoreURL parameter is required.
@@ -88,11 +136,18 @@ func NewRemoteCaptive(captiveCoreURL string, options ...RemoteCaptiveOption) (Re
 		return RemoteCaptiveDiamcircleCore{}, errors.Wrap(err, "unparseable url")
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	client := RemoteCaptiveDiamcircleCore{
 		prepareRangePollInterval: time.Second,
 		url:                      u,
 		client:                   &http.Client{Timeout: 10 * time.Second},
-		lock:                     &sync.Mutex{},
+		// Streaming requests last as long as ledgers are read so they
+		// are only bounded by the client context.
+		streamClient: &http.Client{},
+		lock:         &sync.Mutex{},
+		stream:       &ledgerStream{},
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 	for _, option := range options {
 		option(&client)
@@ -148,8 +203,13 @@ func (c RemoteCaptiveDiamcircleCore) GetLatestLedgerSequence(ctx context.Context
 	return parsed.Sequence, nil
 }
 
-// Close cancels any pending PrepareRange requests.
+// Close closes the ledger stream.
 func (c RemoteCaptiveDiamcircleCore) Close() error {
+	// Canceling first interrupts a GetLedger call holding the lock.
+	c.cancel()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stream.close()
 	return nil
 }
 
@@ -167,6 +227,12 @@ func (c RemoteCaptiveDiamcircleCore) PrepareRange(ctx context.Context, ledgerRan
 	// multiple prepareRange requests happening at the same time. Do we still
 	// need to enforce that?
 
+	// The server rejects ranges rewinding captive core while consumers are
+	// streaming, so the ledger stream of this client is closed first.
+	c.lock.Lock()
+	c.stream.close()
+	c.lock.Unlock()
+
 	timer := time.NewTimer(c.prepareRangePollInterval)
 	defer timer.Stop()
 
@@ -219,9 +285,10 @@ func (c RemoteCaptiveDiamcircleCore) IsPrepared(ctx context.Context, ledgerRange
 	return parsed.Ready, nil
 }
 
-// GetLedger long-polls a remote diamcircle core backend, until the requested
-// ledger is ready.
-
+// GetLedger returns the ledger with the given sequence from the remote captive
+// core server. Consecutive ledgers are read from a single stream which is
+// requested again when a ledger other than the next one is requested.
+//
 // Call PrepareRange first to instruct the backend which ledgers to fetch.
 //
 // Requesting a ledger on non-prepared backend will return an error.
@@ -230,6 +297,141 @@ func (c RemoteCaptiveDiamcircleCore) IsPrepared(ctx context.Context, ledgerRange
 // request sequences in a non-decreasing order. If the requested sequence number
 // is less than the last requested sequence number, an error will be returned.
 func (c RemoteCaptiveDiamcircleCore) GetLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.stream.unsupported {
+		ledger, err := c.streamLedger(ctx, sequence)
+		if err != errStreamingNotSupported {
+			return ledger, err
+		}
+		c.stream.unsupported = true
+	}
+	return c.pollLedger(ctx, sequence)
+}
+
+// streamLedger reads the ledger with the given sequence from the ledger
+// stream. The caller must hold the lock.
+func (c RemoteCaptiveDiamcircleCore) streamLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if c.stream.body != nil && c.stream.next != sequence {
+		c.stream.close()
+	}
+
+	// The server ends the stream when it cannot send the next ledger, the
+	// reason is reported when requesting the stream again.
+	reopened := c.stream.body == nil
+	for {
+		ledger, err := c.readStream(ctx, sequence)
+		if err == nil {
+			c.stream.next = sequence + 1
+			return ledger, nil
+		}
+		c.stream.close()
+		if ctx.Err() != nil {
+			return xdr.LedgerCloseMeta{}, errors.Wrap(ctx.Err(), "shutting down")
+		}
+		if reopened || err == errStreamingNotSupported {
+			return xdr.LedgerCloseMeta{}, err
+		}
+		reopened = true
+	}
+}
+
+// readStream reads the next ledger of the stream, requesting the stream from
+// the given sequence if it is closed. The stream is closed when ctx is done
+// as requests and reads of the stream cannot be interrupted otherwise.
+func (c RemoteCaptiveDiamcircleCore) readStream(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
+	if c.stream.body == nil {
+		var streamCtx context.Context
+		streamCtx, c.stream.cancel = context.WithCancel(c.ctx)
+		if err := c.openStream(ctx, streamCtx, sequence); err != nil {
+			return xdr.LedgerCloseMeta{}, err
+		}
+	}
+
+	var ledger xdr.LedgerCloseMeta
+	err := c.interruptible(ctx, func() error {
+		if _, err := xdr.ReadFrameLength(c.stream.decoder); err != nil {
+			return errors.Wrap(err, "error reading frame length")
+		}
+		if _, err := ledger.DecodeFrom(c.stream.decoder); err != nil {
+			return errors.Wrap(err, "unmarshalling framed LedgerCloseMeta")
+		}
+		return nil
+	})
+	if err != nil {
+		return xdr.LedgerCloseMeta{}, err
+	}
+	if streamed := ledger.LedgerSequence(); streamed != sequence {
+		return xdr.LedgerCloseMeta{}, errors.Errorf("unexpected ledger %d in stream, expected %d", streamed, sequence)
+	}
+	return ledger, nil
+}
+
+// interruptible runs f, canceling the stream if ctx is done before f
+// returns.
+func (c RemoteCaptiveDiamcircleCore) interruptible(ctx context.Context, f func() error) error {
+	cancel := c.stream.cancel
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+	return f()
+}
+
+// openStream requests the stream of ledgers starting from the given sequence.
+func (c RemoteCaptiveDiamcircleCore) openStream(ctx, streamCtx context.Context, sequence uint32) error {
+	u := *c.url
+	u.Path = path.Join(u.Path, "ledgers")
+	query := url.Values{}
+	query.Set("from", strconv.FormatUint(uint64(sequence), 10))
+	if c.consumerName != "" {
+		query.Set("consumer", c.consumerName)
+	}
+	u.RawQuery = query.Encode()
+	request, err := http.NewRequestWithContext(streamCtx, "GET", u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "cannot construct http request")
+	}
+
+	var response *http.Response
+	err = c.interruptible(ctx, func() error {
+		var doErr error
+		response, doErr = c.streamClient.Do(request)
+		return doErr
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to execute request")
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		response.Body.Close()
+		return errStreamingNotSupported
+	default:
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return errors.Wrap(err, "failed to read response body")
+		}
+		return errors.New(string(body))
+	}
+
+	c.stream.body = response.Body
+	c.stream.decoder = xdr3.NewDecoder(bufio.NewReader(response.Body))
+	c.stream.next = sequence
+	return nil
+}
+
+// pollLedger long-polls a remote diamcircle core backend, until the requested
+// ledger is ready.
+func (c RemoteCaptiveDiamcircleCore) pollLedger(ctx context.Context, sequence uint32) (xdr.LedgerCloseMeta, error) {
 	for {
 		// TODO: Have some way to cancel all outstanding requests, not just
 		// PrepareRange.
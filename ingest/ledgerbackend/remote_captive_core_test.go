@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -24,6 +25,11 @@ func TestGetLedgerSucceeds(t *testing.T) {
 	}
 	called := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ledgers" {
+			// Servers without streaming support.
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		called++
 		json.NewEncoder(w).Encode(LedgerResponse{
 			Ledger: Base64Ledger(expectedLedger),
@@ -52,6 +58,11 @@ func TestGetLedgerTakesAWhile(t *testing.T) {
 	}
 	called := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ledgers" {
+			// Servers without streaming support.
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
 		called++
 		if called == 1 {
 			// TODO: Check this is what the server really does.
@@ -72,3 +83,59 @@ func TestGetLedgerTakesAWhile(t *testing.T) {
 	require.Equal(t, 2, called)
 	require.Equal(t, expectedLedger, ledger)
 }
+
+func TestGetLedgerStreams(t *testing.T) {
+	ledger := func(sequence uint32) xdr.LedgerCloseMeta {
+		return xdr.LedgerCloseMeta{
+			V0: &xdr.LedgerCloseMetaV0{
+				LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+					Header: xdr.LedgerHeader{
+						LedgerSeq: xdr.Uint32(sequence),
+					},
+				},
+			},
+		}
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ledgers", r.URL.Path)
+		requests = append(requests, r.URL.RawQuery)
+		from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 32)
+		require.NoError(t, err)
+		if from > 100 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("ledger is beyond the prepared range"))
+			return
+		}
+		// The stream ends after 3 ledgers.
+		for sequence := uint32(from); sequence < uint32(from)+3; sequence++ {
+			require.NoError(t, xdr.MarshalFramed(w, ledger(sequence)))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewRemoteCaptive(server.URL, ConsumerName("test"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	for sequence := uint32(64); sequence <= 70; sequence++ {
+		streamed, err := client.GetLedger(context.Background(), sequence)
+		require.NoError(t, err)
+		require.Equal(t, ledger(sequence), streamed)
+	}
+	// Requesting a ledger other than the next one requests the stream again.
+	streamed, err := client.GetLedger(context.Background(), 90)
+	require.NoError(t, err)
+	require.Equal(t, ledger(90), streamed)
+
+	_, err = client.GetLedger(context.Background(), 101)
+	require.EqualError(t, err, "ledger is beyond the prepared range")
+
+	require.Equal(t, []string{
+		"consumer=test&from=64",
+		"consumer=test&from=67",
+		"consumer=test&from=70",
+		"consumer=test&from=90",
+		"consumer=test&from=101",
+	}, requests)
+}
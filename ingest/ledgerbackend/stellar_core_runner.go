@@ -68,7 +68,8 @@ type diamcircleCoreRunner struct {
 	storagePath string
 	nonce       string
 
-	log *log.Entry
+	log     *log.Entry
+	monitor *captiveCoreMonitor
 }
 
 func createRandomHexString(n int) string {
@@ -204,6 +205,7 @@ func (r *diamcircleCoreRunner) getLogLineWriter() io.Writer {
 			if line == "" {
 				continue
 			}
+			r.monitor.observeLine(line)
 
 			matches := levelRx.FindStringSubmatch(line)
 			if len(matches) >= 4 {
@@ -275,6 +277,7 @@ func (r *diamcircleCoreRunner) catchup(from, to uint32) error {
 	}
 
 	r.started = true
+	r.monitor.processStarted(r.cmd.Process.Pid)
 	r.ledgerBuffer = newBufferedLedgerMetaReader(r.pipe.Reader)
 	go r.ledgerBuffer.start()
 
@@ -320,6 +323,7 @@ func (r *diamcircleCoreRunner) runFrom(from uint32, hash string) error {
 	}
 
 	r.started = true
+	r.monitor.processStarted(r.cmd.Process.Pid)
 	r.ledgerBuffer = newBufferedLedgerMetaReader(r.pipe.Reader)
 	go r.ledgerBuffer.start()
 
@@ -404,6 +408,7 @@ func (r *diamcircleCoreRunner) handleExit() {
 	} else {
 		r.processExitError = waitErr
 	}
+	r.monitor.processExited(r.processExitError)
 }
 
 // closeLogLineWriters closes the go routines created by getLogLineWriter()
@@ -468,15 +473,30 @@ func (r *diamcircleCoreRunner) close() error {
 	}
 
 	if runtime.GOOS == "windows" ||
-		(r.processExitError != nil && r.processExitError != context.Canceled) ||
+		!storageReusableAfterExit(r.processExitError) ||
 		r.mode == diamcircleCoreRunnerModeOffline {
 		// It's impossible to send SIGINT on Windows so buckets can become
 		// corrupted. If we can't reuse it, then remove it.
-		// We also remove the storage path if there was an error terminating the
-		// process (files can be corrupted).
+		// We also remove the storage path if the process was terminated
+		// abruptly (files can be corrupted).
 		// We remove all files when reingesting to save disk space.
 		return os.RemoveAll(storagePath)
 	}
 
 	return nil
 }
+
+// storageReusableAfterExit returns true if the storage of a Diamcircle-Core
+// process which exited with the given error can be reused. It can when the
+// process was interrupted by the runner or exited by itself, even with a non
+// zero status, but not when it was terminated by a signal (killed, crashed or
+// aborted) in the middle of writing its files.
+func storageReusableAfterExit(exitErr error) bool {
+	if exitErr == nil || exitErr == context.Canceled {
+		return true
+	}
+	if err, ok := exitErr.(*exec.ExitError); ok {
+		return err.ProcessState.Exited()
+	}
+	return false
+}
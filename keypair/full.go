@@ -79,6 +79,11 @@ func (kp *Full) Seed() string {
 	return kp.seed
 }
 
+func (kp *Full) rawSeed() (seed [32]byte) {
+	copy(seed[:], strkey.MustDecode(strkey.VersionByteSeed, kp.seed))
+	return
+}
+
 func (kp *Full) Verify(input []byte, sig []byte) error {
 	if len(sig) != 64 {
 		return ErrInvalidSignature
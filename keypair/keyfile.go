@@ -0,0 +1,307 @@
+package keypair
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/diamcircle/go/strkey"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyFileVersion is the version of the key file format written by this
+// package.
+const KeyFileVersion = 1
+
+const (
+	// KDFScrypt derives the encryption key with scrypt.
+	KDFScrypt = "scrypt"
+	// KDFArgon2id derives the encryption key with Argon2id.
+	KDFArgon2id = "argon2id"
+
+	cipherAES256GCM = "aes-256-gcm"
+	keyFileKeyLen   = 32
+	keyFileSaltLen  = 32
+
+	// Key files are untrusted input, so the KDF parameters read from them are
+	// capped to keep Decrypt from allocating gigabytes or running for hours.
+	maxScryptN       = 1 << 20
+	maxScryptR       = 32
+	maxScryptP       = 16
+	maxScryptMemory  = 1 << 30 // bytes, scrypt uses 128*N*r
+	maxArgon2Time    = 16
+	maxArgon2Memory  = 1 << 20 // KiB
+	maxArgon2Threads = 64
+)
+
+var (
+	// ErrWrongPassword is returned when a key file cannot be decrypted with
+	// the given password, or when its contents have been tampered with.
+	ErrWrongPassword = errors.New("wrong password or corrupted key file")
+
+	// ErrUnsupportedKeyFile is returned when a key file uses a version, KDF or
+	// cipher this package does not understand.
+	ErrUnsupportedKeyFile = errors.New("unsupported key file")
+)
+
+// KDFParams configures the key derivation function used to turn a password
+// into an encryption key. Only the parameters relevant to Name are used.
+type KDFParams struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+
+	// scrypt parameters
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id parameters
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// DefaultScryptParams returns the scrypt parameters used by NewKeyFile.
+func DefaultScryptParams() KDFParams {
+	return KDFParams{Name: KDFScrypt, N: 1 << 15, R: 8, P: 1}
+}
+
+// DefaultArgon2idParams returns the recommended Argon2id parameters.
+func DefaultArgon2idParams() KDFParams {
+	return KDFParams{Name: KDFArgon2id, Time: 3, Memory: 64 * 1024, Threads: 4}
+}
+
+func (p KDFParams) deriveKey(password []byte) ([]byte, error) {
+	switch p.Name {
+	case KDFScrypt:
+		return scrypt.Key(password, p.Salt, p.N, p.R, p.P, keyFileKeyLen)
+	case KDFArgon2id:
+		if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+			return nil, ErrUnsupportedKeyFile
+		}
+		return argon2.IDKey(password, p.Salt, p.Time, p.Memory, p.Threads, keyFileKeyLen), nil
+	default:
+		return nil, ErrUnsupportedKeyFile
+	}
+}
+
+// checkLimits returns ErrUnsupportedKeyFile if the parameters would make
+// deriving the key too expensive, or if the scrypt N is not a power of two.
+func (p KDFParams) checkLimits() error {
+	switch p.Name {
+	case KDFScrypt:
+		if p.N <= 1 || p.N > maxScryptN || p.N&(p.N-1) != 0 ||
+			p.R <= 0 || p.R > maxScryptR || p.P <= 0 || p.P > maxScryptP ||
+			128*p.N*p.R > maxScryptMemory {
+			return ErrUnsupportedKeyFile
+		}
+	case KDFArgon2id:
+		if p.Time > maxArgon2Time || p.Memory > maxArgon2Memory || p.Threads > maxArgon2Threads {
+			return ErrUnsupportedKeyFile
+		}
+	}
+	return nil
+}
+
+// KeyFileCrypto holds the encrypted seed and everything, except the password,
+// needed to decrypt it.
+type KeyFileCrypto struct {
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+	KDF        KDFParams `json:"kdf"`
+}
+
+// KeyFile is an encrypted-at-rest representation of a Full keypair. The
+// address, label and creation time are stored in the clear so that a key file
+// can be identified without its password; they are authenticated together
+// with the encrypted seed so any modification is detected on Decrypt.
+type KeyFile struct {
+	Version   int           `json:"version"`
+	Address   string        `json:"address"`
+	Label     string        `json:"label,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Crypto    KeyFileCrypto `json:"crypto"`
+}
+
+// NewKeyFile encrypts kp with password using the default scrypt parameters.
+func NewKeyFile(kp *Full, password []byte, label string) (*KeyFile, error) {
+	return NewKeyFileWithKDF(kp, password, label, DefaultScryptParams())
+}
+
+// NewKeyFileWithKDF encrypts kp with password using the given key derivation
+// parameters. A random salt is generated if params.Salt is empty.
+func NewKeyFileWithKDF(kp *Full, password []byte, label string, params KDFParams) (*KeyFile, error) {
+	f := &KeyFile{
+		Version:   KeyFileVersion,
+		Address:   kp.Address(),
+		Label:     label,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := f.encrypt(kp, password, params); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// LoadKeyFile reads a key file from path. The seed stays encrypted until
+// Decrypt is called.
+func LoadKeyFile(path string) (*KeyFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f KeyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("decoding key file: %w", err)
+	}
+	if f.Version != KeyFileVersion {
+		return nil, ErrUnsupportedKeyFile
+	}
+	if !strkey.IsValidEd25519PublicKey(f.Address) {
+		return nil, ErrInvalidKey
+	}
+	return &f, nil
+}
+
+// Save writes the key file to path, readable only by the current user. The
+// file is written to a temporary file first and renamed into place so an
+// existing key file is never left half written.
+func (f *KeyFile) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Decrypt returns the keypair stored in the key file. It returns
+// ErrWrongPassword if the password is wrong or the file has been modified.
+func (f *KeyFile) Decrypt(password []byte) (*Full, error) {
+	if f.Crypto.Cipher != cipherAES256GCM {
+		return nil, ErrUnsupportedKeyFile
+	}
+	if err := f.Crypto.KDF.checkLimits(); err != nil {
+		return nil, err
+	}
+
+	key, err := f.Crypto.KDF.deriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newKeyFileAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Crypto.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassword
+	}
+
+	rawSeed, err := aead.Open(nil, f.Crypto.Nonce, f.Crypto.Ciphertext, f.additionalData())
+	if err != nil || len(rawSeed) != 32 {
+		return nil, ErrWrongPassword
+	}
+
+	var seed [32]byte
+	copy(seed[:], rawSeed)
+	kp, err := FromRawSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	if kp.Address() != f.Address {
+		return nil, ErrWrongPassword
+	}
+	return kp, nil
+}
+
+// ChangePassword re-encrypts the key file under newPassword, keeping the
+// same KDF but with a fresh salt and nonce.
+func (f *KeyFile) ChangePassword(oldPassword, newPassword []byte) error {
+	kp, err := f.Decrypt(oldPassword)
+	if err != nil {
+		return err
+	}
+
+	params := f.Crypto.KDF
+	params.Salt = nil
+	return f.encrypt(kp, newPassword, params)
+}
+
+func (f *KeyFile) encrypt(kp *Full, password []byte, params KDFParams) error {
+	if len(params.Salt) == 0 {
+		params.Salt = make([]byte, keyFileSaltLen)
+		if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+			return err
+		}
+	}
+
+	key, err := params.deriveKey(password)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newKeyFileAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	rawSeed := kp.rawSeed()
+	f.Crypto = KeyFileCrypto{
+		Cipher:     cipherAES256GCM,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, rawSeed[:], f.additionalData()),
+		KDF:        params,
+	}
+	return nil
+}
+
+// additionalData binds the unencrypted metadata to the ciphertext.
+func (f *KeyFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d\x00%s\x00%s\x00%s", f.Version, f.Address, f.Label, f.CreatedAt.UTC().Format(time.RFC3339)))
+}
+
+func newKeyFileAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,131 @@
+package keypair
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastScryptParams keeps the tests quick; never use parameters this weak for
+// real key files.
+var fastScryptParams = KDFParams{Name: KDFScrypt, N: 1 << 10, R: 8, P: 1}
+
+func TestKeyFile_SaveLoad(t *testing.T) {
+	kp := MustParseFull("SBFGFF27Y64ZUGFAIG5AMJGQODZZKV2YQKAVUUN4HNE24XZXD2OEUVUP")
+
+	f, err := NewKeyFileWithKDF(kp, []byte("hunter2"), "hot wallet", fastScryptParams)
+	require.NoError(t, err)
+	assert.Equal(t, kp.Address(), f.Address)
+	assert.Equal(t, "hot wallet", f.Label)
+	assert.Len(t, f.Crypto.KDF.Salt, keyFileSaltLen)
+
+	dir, err := ioutil.TempDir("", "keyfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "key.json")
+	require.NoError(t, f.Save(path))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(contents), kp.Seed())
+
+	loaded, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, f.Address, loaded.Address)
+	assert.Equal(t, f.Label, loaded.Label)
+	assert.True(t, f.CreatedAt.Equal(loaded.CreatedAt))
+
+	decrypted, err := loaded.Decrypt([]byte("hunter2"))
+	require.NoError(t, err)
+	assert.True(t, kp.Equal(decrypted))
+
+	_, err = loaded.Decrypt([]byte("wrong"))
+	assert.Equal(t, ErrWrongPassword, err)
+}
+
+func TestKeyFile_Argon2id(t *testing.T) {
+	kp := MustRandom()
+
+	params := KDFParams{Name: KDFArgon2id, Time: 1, Memory: 1024, Threads: 1}
+	f, err := NewKeyFileWithKDF(kp, []byte("pass"), "", params)
+	require.NoError(t, err)
+
+	decrypted, err := f.Decrypt([]byte("pass"))
+	require.NoError(t, err)
+	assert.True(t, kp.Equal(decrypted))
+}
+
+func TestKeyFile_ChangePassword(t *testing.T) {
+	kp := MustRandom()
+
+	f, err := NewKeyFileWithKDF(kp, []byte("old"), "", fastScryptParams)
+	require.NoError(t, err)
+	oldSalt := f.Crypto.KDF.Salt
+
+	assert.Equal(t, ErrWrongPassword, f.ChangePassword([]byte("wrong"), []byte("new")))
+
+	require.NoError(t, f.ChangePassword([]byte("old"), []byte("new")))
+	assert.NotEqual(t, oldSalt, f.Crypto.KDF.Salt)
+
+	_, err = f.Decrypt([]byte("old"))
+	assert.Equal(t, ErrWrongPassword, err)
+
+	decrypted, err := f.Decrypt([]byte("new"))
+	require.NoError(t, err)
+	assert.True(t, kp.Equal(decrypted))
+}
+
+func TestKeyFile_Tampered(t *testing.T) {
+	kp := MustRandom()
+
+	f, err := NewKeyFileWithKDF(kp, []byte("pass"), "label", fastScryptParams)
+	require.NoError(t, err)
+
+	f.Label = "other label"
+	_, err = f.Decrypt([]byte("pass"))
+	assert.Equal(t, ErrWrongPassword, err)
+
+	f.Label = "label"
+	f.Address = MustRandom().Address()
+	_, err = f.Decrypt([]byte("pass"))
+	assert.Equal(t, ErrWrongPassword, err)
+}
+
+func TestKeyFile_Unsupported(t *testing.T) {
+	_, err := NewKeyFileWithKDF(MustRandom(), []byte("pass"), "", KDFParams{Name: "pbkdf2"})
+	assert.Equal(t, ErrUnsupportedKeyFile, err)
+}
+
+func TestKeyFile_KDFLimits(t *testing.T) {
+	kp := MustRandom()
+	f, err := NewKeyFileWithKDF(kp, []byte("pass"), "", fastScryptParams)
+	require.NoError(t, err)
+
+	for _, params := range []KDFParams{
+		{Name: KDFScrypt, N: 1000, R: 8, P: 1},
+		{Name: KDFScrypt, N: 1 << 30, R: 8, P: 1},
+		{Name: KDFScrypt, N: 1 << 20, R: 32, P: 1},
+		{Name: KDFScrypt, N: 1 << 10, R: 8, P: 1 << 20},
+		{Name: KDFArgon2id, Time: 1, Memory: 1 << 30, Threads: 1},
+		{Name: KDFArgon2id, Time: 1 << 30, Memory: 64, Threads: 1},
+	} {
+		tampered := *f
+		params.Salt = f.Crypto.KDF.Salt
+		tampered.Crypto.KDF = params
+		_, err = tampered.Decrypt([]byte("pass"))
+		assert.Equal(t, ErrUnsupportedKeyFile, err, "%+v", params)
+	}
+
+	decrypted, err := f.Decrypt([]byte("pass"))
+	require.NoError(t, err)
+	assert.Equal(t, kp.Address(), decrypted.Address())
+}
@@ -0,0 +1,58 @@
+package keypair
+
+import (
+	"github.com/diamcircle/go/strkey"
+	"github.com/diamcircle/go/xdr"
+)
+
+// SignPayloadDecorated signs payload for a signed payload signer ("P..."
+// address) of this keypair. The hint of the returned signature is the
+// keypair's hint XORed with the last four bytes of the payload, which is how
+// the network matches the signature against the signer.
+func (kp *Full) SignPayloadDecorated(payload []byte) (xdr.DecoratedSignature, error) {
+	sig, err := kp.Sign(payload)
+	if err != nil {
+		return xdr.DecoratedSignature{}, err
+	}
+
+	return xdr.DecoratedSignature{
+		Hint:      xdr.SignatureHint(signedPayloadHint(kp.Hint(), payload)),
+		Signature: xdr.Signature(sig),
+	}, nil
+}
+
+// VerifySignedPayload checks that sig is a valid signature for the signed
+// payload signer encoded in address.
+func VerifySignedPayload(address string, sig xdr.DecoratedSignature) error {
+	sp, err := strkey.DecodeSignedPayload(address)
+	if err != nil {
+		return err
+	}
+
+	kp, err := ParseAddress(sp.Signer())
+	if err != nil {
+		return err
+	}
+
+	payload := sp.Payload()
+	if signedPayloadHint(kp.Hint(), payload) != sig.Hint {
+		return ErrInvalidSignature
+	}
+	return kp.Verify(payload, sig.Signature)
+}
+
+// signedPayloadHint XORs the signer's hint with the last four bytes of the
+// payload. Payloads shorter than four bytes are zero-padded at the end.
+func signedPayloadHint(signerHint [4]byte, payload []byte) (hint [4]byte) {
+	var tail [4]byte
+	if len(payload) > 4 {
+		copy(tail[:], payload[len(payload)-4:])
+	} else {
+		copy(tail[:], payload)
+	}
+
+	for i := range hint {
+		hint[i] = signerHint[i] ^ tail[i]
+	}
+	return
+}
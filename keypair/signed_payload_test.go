@@ -0,0 +1,45 @@
+package keypair
+
+import (
+	"testing"
+
+	"github.com/diamcircle/go/strkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFull_SignPayloadDecorated(t *testing.T) {
+	kp := MustParseFull("SBFGFF27Y64ZUGFAIG5AMJGQODZZKV2YQKAVUUN4HNE24XZXD2OEUVUP")
+
+	payload := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+		0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	}
+	sig, err := kp.SignPayloadDecorated(payload)
+	require.NoError(t, err)
+	assert.Equal(t, [4]byte{0x8b, 0x59, 0x9d, 0xb6}, [4]byte(sig.Hint))
+	assert.NoError(t, kp.Verify(payload, sig.Signature))
+
+	sp, err := strkey.NewSignedPayload(kp.Address(), payload)
+	require.NoError(t, err)
+	address, err := sp.Encode()
+	require.NoError(t, err)
+	assert.NoError(t, VerifySignedPayload(address, sig))
+
+	// a signature over a different payload does not verify
+	other, err := strkey.NewSignedPayload(kp.Address(), payload[:31])
+	require.NoError(t, err)
+	otherAddress, err := other.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, ErrInvalidSignature, VerifySignedPayload(otherAddress, sig))
+}
+
+func TestFull_SignPayloadDecorated_ShortPayload(t *testing.T) {
+	kp := MustParseFull("SBFGFF27Y64ZUGFAIG5AMJGQODZZKV2YQKAVUUN4HNE24XZXD2OEUVUP")
+
+	sig, err := kp.SignPayloadDecorated([]byte{0x01, 0x02})
+	require.NoError(t, err)
+	assert.Equal(t, [4]byte{0x97, 0x45, 0x82, 0x96}, [4]byte(sig.Hint))
+}
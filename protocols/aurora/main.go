@@ -21,10 +21,11 @@ import (
 // KeyTypeNames maps from strkey version bytes into json string values to use in
 // aurora responses.
 var KeyTypeNames = map[strkey.VersionByte]string{
-	strkey.VersionByteAccountID: "ed25519_public_key",
-	strkey.VersionByteSeed:      "ed25519_secret_seed",
-	strkey.VersionByteHashX:     "sha256_hash",
-	strkey.VersionByteHashTx:    "preauth_tx",
+	strkey.VersionByteAccountID:     "ed25519_public_key",
+	strkey.VersionByteSeed:          "ed25519_secret_seed",
+	strkey.VersionByteHashX:         "sha256_hash",
+	strkey.VersionByteHashTx:        "preauth_tx",
+	strkey.VersionByteSignedPayload: "ed25519_signed_payload",
 }
 
 // Account is the summary of an account
@@ -276,6 +277,27 @@ func (o Offer) PagingToken() string {
 	return o.PT
 }
 
+// AssetPrice represents a reference price of the base asset in units of the
+// counter asset, computed with the given method. The other fields are
+// confidence signals: the trades and volume the price is based on, the best
+// bid and ask and their relative spread for mid-prices, and the path the
+// price was quoted on if there is no direct market between the assets.
+type AssetPrice struct {
+	Base          Asset      `json:"base"`
+	Counter       Asset      `json:"counter"`
+	Method        string     `json:"method"`
+	Price         string     `json:"price"`
+	Ledger        int32      `json:"ledger"`
+	TradeCount    int64      `json:"trade_count,string"`
+	BaseVolume    string     `json:"base_volume"`
+	CounterVolume string     `json:"counter_volume"`
+	LastTradeAt   *time.Time `json:"last_trade_at,omitempty"`
+	Bid           string     `json:"bid,omitempty"`
+	Ask           string     `json:"ask,omitempty"`
+	Spread        string     `json:"spread,omitempty"`
+	Route         *Path      `json:"route,omitempty"`
+}
+
 // OrderBookSummary represents a snapshot summary of a given order book
 type OrderBookSummary struct {
 	Bids    []PriceLevel `json:"bids"`
@@ -302,6 +324,25 @@ func (p Path) PagingToken() string {
 	return ""
 }
 
+// SplitPaths is a payment divided across several payment paths. Each path is
+// a separate path payment operation, to be submitted in order in a single
+// transaction. The source and destination amounts are the totals of all the
+// paths.
+type SplitPaths struct {
+	SourceAssetType        string `json:"source_asset_type"`
+	SourceAssetCode        string `json:"source_asset_code,omitempty"`
+	SourceAssetIssuer      string `json:"source_asset_issuer,omitempty"`
+	SourceAmount           string `json:"source_amount"`
+	DestinationAssetType   string `json:"destination_asset_type"`
+	DestinationAssetCode   string `json:"destination_asset_code,omitempty"`
+	DestinationAssetIssuer string `json:"destination_asset_issuer,omitempty"`
+	DestinationAmount      string `json:"destination_amount"`
+	Paths                  []Path `json:"paths"`
+	// Operations holds the base64 XDR path payment operations of the plan.
+	// They are only included if a destination account was given.
+	Operations []string `json:"operations,omitempty"`
+}
+
 // Price represents a price for an offer
 type Price base.Price
 
@@ -477,6 +518,16 @@ type TradeAggregation struct {
 	OpenR         TradePrice `json:"open_r"`
 	Close         string     `json:"close"`
 	CloseR        TradePrice `json:"close_r"`
+	// VWAP is the volume weighted average price of the trades, the counter
+	// volume divided by the base volume, computed without rounding errors.
+	VWAP string `json:"vwap"`
+	// The liquidity pool volumes are the parts of the base and counter
+	// volumes traded with liquidity pools, the order book volumes the parts
+	// traded with offers.
+	LiquidityPoolBaseVolume    string `json:"liquidity_pool_base_volume"`
+	LiquidityPoolCounterVolume string `json:"liquidity_pool_counter_volume"`
+	OrderbookBaseVolume        string `json:"orderbook_base_volume"`
+	OrderbookCounterVolume     string `json:"orderbook_counter_volume"`
 }
 
 // PagingToken implementation for hal.Pageable. Not actually used
@@ -817,3 +868,55 @@ type LiquidityPoolReserve struct {
 	Asset  string `json:"asset"`
 	Amount string `json:"amount"`
 }
+
+// LiquidityPoolStats represents the state of a liquidity pool at the end of a
+// time bucket, along with the volume traded against the pool and the fees it
+// earned during the bucket.
+type LiquidityPoolStats struct {
+	Timestamp       int64                  `json:"timestamp,string"`
+	TradeCount      int64                  `json:"trade_count,string"`
+	TotalTrustlines uint64                 `json:"total_trustlines,string"`
+	TotalShares     string                 `json:"total_shares"`
+	Reserves        []LiquidityPoolReserve `json:"reserves"`
+	Volume          []LiquidityPoolReserve `json:"volume"`
+	FeesEarned      []LiquidityPoolReserve `json:"fees_earned"`
+	// SharePrice is the amount of each reserve asset redeemable for one pool
+	// share.
+	SharePrice []LiquidityPoolReserve `json:"share_price"`
+	// APR is the annualized fee yield of the bucket, the fees earned relative
+	// to the reserves averaged over both assets.
+	APR string `json:"apr"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res LiquidityPoolStats) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
+// LiquidityPoolPosition represents the position of an account in a liquidity
+// pool. The cost basis is the part of the deposited reserves which has not
+// been withdrawn yet, withdrawals reducing it in proportion to the shares
+// redeemed.
+type LiquidityPoolPosition struct {
+	Links struct {
+		LiquidityPool hal.Link `json:"liquidity_pool"`
+	} `json:"_links"`
+
+	LiquidityPoolID string                 `json:"liquidity_pool_id"`
+	PT              string                 `json:"paging_token"`
+	Shares          string                 `json:"shares"`
+	Deposited       []LiquidityPoolReserve `json:"deposited"`
+	Withdrawn       []LiquidityPoolReserve `json:"withdrawn"`
+	CostBasis       []LiquidityPoolReserve `json:"cost_basis"`
+	CurrentValue    []LiquidityPoolReserve `json:"current_value"`
+	// ImpermanentLoss is the relative difference between the current value
+	// of the position and the value the cost basis would have if it had been
+	// held, both valued at the current pool price. It includes the fees
+	// earned by the position.
+	ImpermanentLoss string `json:"impermanent_loss"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res LiquidityPoolPosition) PagingToken() string {
+	return res.PT
+}
@@ -9,6 +9,7 @@ type InfoResponse struct {
 		ProtocolVersion int        `json:"protocol_version"`
 		State           string     `json:"state"`
 		Ledger          LedgerInfo `json:"ledger"`
+		Peers           PeersInfo  `json:"peers"`
 
 		// TODO: all the other fields
 	}
@@ -27,6 +28,13 @@ type LedgerInfo struct {
 	Version      int    `json:"version"`
 }
 
+// PeersInfo is the part of the diamcircle-core's info json response.
+// It's returned under `peers` key
+type PeersInfo struct {
+	AuthenticatedCount int `json:"authenticated_count"`
+	PendingCount       int `json:"pending_count"`
+}
+
 // IsSynced returns a boolean indicating whether diamcirclecore is synced with the
 // network.
 func (resp *InfoResponse) IsSynced() bool {
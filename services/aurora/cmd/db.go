@@ -35,6 +35,9 @@ var dbMigrateCmd = &cobra.Command{
 func requireAndSetFlag(name string) error {
 	for _, flag := range flags {
 		if flag.Name == name {
+			// The flag may be optional when serving requests, like the
+			// database URL with the embedded storage driver.
+			flag.Required = true
 			flag.Require()
 			flag.SetValue()
 			return nil
@@ -472,6 +475,67 @@ var dbDetectGapsCmd = &cobra.Command{
 	},
 }
 
+var dbExportStateCmd = &cobra.Command{
+	Use:   "export-state DIR",
+	Short: "exports the state at the last ingested ledger",
+	Long: "Writes the state tables (accounts, trust lines, offers, liquidity pools, claimable balances " +
+		"and account data) at the last ingested ledger to DIR as a checksummed snapshot, which can be " +
+		"imported with import-state to bootstrap another Aurora database.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return ErrUsage{cmd}
+		}
+		for _, name := range []string{aurora.DatabaseURLFlagName, aurora.NetworkPassphraseFlagName} {
+			if err := requireAndSetFlag(name); err != nil {
+				return err
+			}
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		defer auroraSession.Close()
+
+		snapshot, err := ingest.ExportState(context.Background(), auroraSession, config.NetworkPassphrase, args[0])
+		if err != nil {
+			return err
+		}
+		hlog.WithField("ledger", snapshot.Ledger).Info("State exported successfully!")
+		return nil
+	},
+}
+
+var dbImportStateCmd = &cobra.Command{
+	Use:   "import-state DIR",
+	Short: "imports a state snapshot into an empty database",
+	Long: "Imports the state snapshot written by export-state to DIR into an empty, migrated Aurora " +
+		"database. Ingestion resumes from the ledger following the snapshot ledger.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return ErrUsage{cmd}
+		}
+		for _, name := range []string{aurora.DatabaseURLFlagName, aurora.NetworkPassphraseFlagName} {
+			if err := requireAndSetFlag(name); err != nil {
+				return err
+			}
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		defer auroraSession.Close()
+
+		snapshot, err := ingest.ImportState(context.Background(), auroraSession, config.NetworkPassphrase, args[0])
+		if err != nil {
+			return err
+		}
+		hlog.WithField("ledger", snapshot.Ledger).Info("State imported successfully!")
+		return nil
+	},
+}
+
 func runDBDetectGaps(config aurora.Config) ([]history.LedgerRange, error) {
 	auroraSession, err := db.Open("postgres", config.DatabaseURL)
 	if err != nil {
@@ -509,6 +573,8 @@ func init() {
 		dbReingestCmd,
 		dbDetectGapsCmd,
 		dbFillGapsCmd,
+		dbExportStateCmd,
+		dbImportStateCmd,
 	)
 	dbMigrateCmd.AddCommand(
 		dbMigrateDownCmd,
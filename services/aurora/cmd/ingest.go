@@ -6,6 +6,7 @@ import (
 	"go/types"
 	"net/http"
 	_ "net/http/pprof"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -16,6 +17,7 @@ import (
 	support "github.com/diamcircle/go/support/config"
 	"github.com/diamcircle/go/support/db"
 	"github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
 )
 
 var ingestCmd = &cobra.Command{
@@ -238,7 +240,7 @@ var ingestStressTestCmd = &cobra.Command{
 
 var ingestTriggerStateRebuildCmd = &cobra.Command{
 	Use:   "trigger-state-rebuild",
-	Short: "updates a database to trigger state rebuild, state will be rebuilt by a running Aurora instance, DO NOT RUN production DB, some endpoints will be unavailable until state is rebuilt",
+	Short: "updates a database to trigger state rebuild, state will be rebuilt in shadow tables by a running Aurora instance and swapped with the live state once it caught up",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
 		if err := aurora.ApplyFlags(config, flags, aurora.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true}); err != nil {
@@ -251,7 +253,7 @@ var ingestTriggerStateRebuildCmd = &cobra.Command{
 		}
 
 		historyQ := &history.Q{auroraSession}
-		if err := historyQ.UpdateIngestVersion(ctx, 0); err != nil {
+		if err := historyQ.UpdateStateRebuildRequested(ctx, true); err != nil {
 			return fmt.Errorf("cannot trigger state rebuild: %v", err)
 		}
 
@@ -285,43 +287,151 @@ var ingestInitGenesisStateCmd = &cobra.Command{
 			return fmt.Errorf("cannot run on non-empty DB")
 		}
 
-		ingestConfig := ingest.Config{
-			NetworkPassphrase:   config.NetworkPassphrase,
-			HistorySession:      auroraSession,
-			HistoryArchiveURL:   config.HistoryArchiveURLs[0],
-			EnableCaptiveCore:   config.EnableCaptiveCoreIngestion,
-			CheckpointFrequency: config.CheckpointFrequency,
+		system, err := newIngestSystem(auroraSession)
+		if err != nil {
+			return err
 		}
 
-		if config.EnableCaptiveCoreIngestion {
-			ingestConfig.CaptiveCoreBinaryPath = config.CaptiveCoreBinaryPath
-		} else {
-			if config.DiamcircleCoreDatabaseURL == "" {
-				return fmt.Errorf("flag --%s cannot be empty", aurora.DiamcircleCoreDBURLFlagName)
+		err = system.BuildGenesisState()
+		if err != nil {
+			return err
+		}
+
+		log.Info("Genesis ledger stat successfully ingested!")
+		return nil
+	},
+}
+
+var ingestRepairCheckpoint uint32
+var ingestRepairLedgerKeys string
+var ingestRepairReportID uint
+
+var ingestRepairStateCmdOpts = []*support.ConfigOption{
+	{
+		Name:        "checkpoint",
+		ConfigKey:   &ingestRepairCheckpoint,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(0),
+		Usage:       "[optional] checkpoint ledger of the history archive state to repair entries from, defaults to the ledger of the report or the latest checkpoint",
+	},
+	{
+		Name:        "ledger-keys",
+		ConfigKey:   &ingestRepairLedgerKeys,
+		OptType:     types.String,
+		Required:    false,
+		FlagDefault: "",
+		Usage:       "[optional] comma separated list of base64 encoded ledger keys of the entries to repair",
+	},
+	{
+		Name:        "report-id",
+		ConfigKey:   &ingestRepairReportID,
+		OptType:     types.Uint,
+		Required:    false,
+		FlagDefault: uint(0),
+		Usage:       "[optional] id of the state verification report whose mismatching entries are repaired",
+	},
+}
+
+var ingestRepairStateCmd = &cobra.Command{
+	Use:   "repair-state",
+	Short: "repairs individual state entries from the history archive state",
+	Long: "Replaces the given entries in the state tables with their version in the history archive state " +
+		"at a checkpoint ledger, without rebuilding the whole state. The entries are either given with " +
+		"--ledger-keys or are the mismatching entries of a state verification report.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		if err := aurora.ApplyFlags(config, flags, aurora.ApplyOptions{RequireCaptiveCoreConfig: false, AlwaysIngest: true}); err != nil {
+			return err
+		}
+
+		if (ingestRepairLedgerKeys == "") == (ingestRepairReportID == 0) {
+			return fmt.Errorf("exactly one of --ledger-keys or --report-id must be set")
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return fmt.Errorf("cannot open Aurora DB: %v", err)
+		}
+		historyQ := &history.Q{auroraSession}
+
+		var encodedKeys []string
+		checkpoint := ingestRepairCheckpoint
+		if ingestRepairReportID != 0 {
+			report, err := historyQ.StateVerificationReportByID(ctx, int64(ingestRepairReportID))
+			if err != nil {
+				return fmt.Errorf("cannot load state verification report: %v", err)
 			}
+			mismatches, err := historyQ.StateVerificationMismatches(ctx, report.ID)
+			if err != nil {
+				return fmt.Errorf("cannot load state verification mismatches: %v", err)
+			}
+			for _, mismatch := range mismatches {
+				encodedKeys = append(encodedKeys, mismatch.LedgerKey)
+			}
+			if checkpoint == 0 {
+				checkpoint = report.LedgerSequence
+			}
+		} else {
+			encodedKeys = strings.Split(ingestRepairLedgerKeys, ",")
+		}
 
-			coreSession, dbErr := db.Open("postgres", config.DiamcircleCoreDatabaseURL)
-			if dbErr != nil {
-				return fmt.Errorf("cannot open Core DB: %v", dbErr)
+		keys := make([]xdr.LedgerKey, 0, len(encodedKeys))
+		for _, encodedKey := range encodedKeys {
+			var key xdr.LedgerKey
+			if err = xdr.SafeUnmarshalBase64(strings.TrimSpace(encodedKey), &key); err != nil {
+				return fmt.Errorf("invalid ledger key %s: %v", encodedKey, err)
 			}
-			ingestConfig.CoreSession = coreSession
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			log.Info("No entries to repair")
+			return nil
 		}
 
-		system, err := ingest.NewSystem(ingestConfig)
+		system, err := newIngestSystem(auroraSession)
 		if err != nil {
 			return err
 		}
 
-		err = system.BuildGenesisState()
+		repaired, err := system.RepairState(checkpoint, keys)
 		if err != nil {
 			return err
 		}
 
-		log.Info("Genesis ledger stat successfully ingested!")
+		log.WithField("repaired", repaired).Info("State entries repaired")
 		return nil
 	},
 }
 
+// newIngestSystem returns an ingestion system, for commands which do not run
+// the ingestion loop, using the history database of the given session.
+func newIngestSystem(auroraSession *db.Session) (ingest.System, error) {
+	ingestConfig := ingest.Config{
+		NetworkPassphrase:   config.NetworkPassphrase,
+		HistorySession:      auroraSession,
+		HistoryArchiveURL:   config.HistoryArchiveURLs[0],
+		EnableCaptiveCore:   config.EnableCaptiveCoreIngestion,
+		CheckpointFrequency: config.CheckpointFrequency,
+	}
+
+	if config.EnableCaptiveCoreIngestion {
+		ingestConfig.CaptiveCoreBinaryPath = config.CaptiveCoreBinaryPath
+	} else {
+		if config.DiamcircleCoreDatabaseURL == "" {
+			return nil, fmt.Errorf("flag --%s cannot be empty", aurora.DiamcircleCoreDBURLFlagName)
+		}
+
+		coreSession, dbErr := db.Open("postgres", config.DiamcircleCoreDatabaseURL)
+		if dbErr != nil {
+			return nil, fmt.Errorf("cannot open Core DB: %v", dbErr)
+		}
+		ingestConfig.CoreSession = coreSession
+	}
+
+	return ingest.NewSystem(ingestConfig)
+}
+
 func init() {
 	for _, co := range ingestVerifyRangeCmdOpts {
 		err := co.Init(ingestVerifyRangeCmd)
@@ -337,6 +447,13 @@ func init() {
 		}
 	}
 
+	for _, co := range ingestRepairStateCmdOpts {
+		err := co.Init(ingestRepairStateCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+
 	viper.BindPFlags(ingestVerifyRangeCmd.PersistentFlags())
 
 	RootCmd.AddCommand(ingestCmd)
@@ -345,5 +462,6 @@ func init() {
 		ingestStressTestCmd,
 		ingestTriggerStateRebuildCmd,
 		ingestInitGenesisStateCmd,
+		ingestRepairStateCmd,
 	)
 }
@@ -17,7 +17,7 @@ import (
 )
 
 // AccountInfo returns the information about an account identified by addr.
-func AccountInfo(ctx context.Context, hq *history.Q, addr string) (*protocol.Account, error) {
+func AccountInfo(ctx context.Context, hq history.QInterface, addr string) (*protocol.Account, error) {
 	var (
 		record     history.AccountEntry
 		data       []history.Data
@@ -270,7 +270,7 @@ func (handler GetAccountsHandler) loadSigners(ctx context.Context, historyQ *his
 	return signers, nil
 }
 
-func getLedgerBySequence(ctx context.Context, hq *history.Q, sequence int32) (*history.Ledger, error) {
+func getLedgerBySequence(ctx context.Context, hq history.QInterface, sequence int32) (*history.Ledger, error) {
 	ledger := &history.Ledger{}
 	err := hq.LedgerBySequence(ctx, ledger, sequence)
 	switch {
@@ -306,7 +306,7 @@ func (handler GetAccountByIDHandler) GetResource(
 	r *http.Request,
 ) (StreamableObjectResponse, error) {
 
-	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	historyQ, err := auroraContext.QFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
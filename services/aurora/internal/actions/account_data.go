@@ -56,7 +56,7 @@ func loadAccountData(r *http.Request) (history.Data, error) {
 	if err != nil {
 		return history.Data{}, err
 	}
-	historyQ, err := context.HistoryQFromRequest(r)
+	historyQ, err := context.QFromRequest(r)
 	if err != nil {
 		return history.Data{}, err
 	}
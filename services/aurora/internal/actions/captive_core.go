@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/aurora/internal/ingest"
+)
+
+// CaptiveCoreStatus is the admin representation of the status of the captive
+// core process.
+type CaptiveCoreStatus struct {
+	Running             bool       `json:"running"`
+	PID                 int        `json:"pid,omitempty"`
+	StartedAt           *time.Time `json:"started_at,omitempty"`
+	Restarts            int        `json:"restarts"`
+	LastExitError       string     `json:"last_exit_error,omitempty"`
+	State               string     `json:"state,omitempty"`
+	CatchupTargetLedger uint32     `json:"catchup_target_ledger,omitempty"`
+	// CatchupProgress is between 0 and 1, it is omitted when unknown.
+	CatchupProgress     *float64 `json:"catchup_progress,omitempty"`
+	ResidentMemoryBytes uint64   `json:"resident_memory_bytes,omitempty"`
+	// Info is the response of the captive core /info endpoint, omitted when
+	// its HTTP server is disabled or unreachable.
+	Info interface{} `json:"info,omitempty"`
+}
+
+// CaptiveCoreLogs is the admin representation of the recent captive core log
+// lines.
+type CaptiveCoreLogs struct {
+	Lines []ledgerbackend.CaptiveCoreLogLine `json:"lines"`
+}
+
+// GetCaptiveCoreStatusHandler is the action handler for the admin
+// captive_core end-point
+type GetCaptiveCoreStatusHandler struct {
+	CaptiveCore ingest.CaptiveCoreMonitor
+}
+
+// GetResource returns the status of the captive core process.
+func (handler GetCaptiveCoreStatusHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	status := handler.CaptiveCore.Status(r.Context())
+
+	response := CaptiveCoreStatus{
+		Running:             status.Running,
+		PID:                 status.PID,
+		Restarts:            status.Restarts,
+		LastExitError:       status.LastExitError,
+		State:               status.State,
+		CatchupTargetLedger: status.CatchupTargetLedger,
+		ResidentMemoryBytes: status.ResidentMemoryBytes,
+	}
+	if !status.StartedAt.IsZero() {
+		response.StartedAt = &status.StartedAt
+	}
+	if status.CatchupProgress >= 0 {
+		response.CatchupProgress = &status.CatchupProgress
+	}
+	if status.Info != nil {
+		response.Info = status.Info.Info
+	}
+	return response, nil
+}
+
+// GetCaptiveCoreLogsHandler is the action handler for the admin
+// captive_core/logs end-point
+type GetCaptiveCoreLogsHandler struct {
+	CaptiveCore ingest.CaptiveCoreMonitor
+}
+
+// GetResource returns the recent captive core log lines, oldest first.
+func (handler GetCaptiveCoreLogsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	lines := handler.CaptiveCore.RecentLogLines()
+	if lines == nil {
+		lines = []ledgerbackend.CaptiveCoreLogLine{}
+	}
+	return CaptiveCoreLogs{Lines: lines}, nil
+}
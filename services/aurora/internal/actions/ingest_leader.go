@@ -0,0 +1,79 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/diamcircle/go/services/aurora/internal/ingest"
+)
+
+// IngestLeaderStatus is the admin representation of the ingestion leader
+// election status.
+type IngestLeaderStatus struct {
+	Backend           string     `json:"backend"`
+	NodeID            string     `json:"node_id"`
+	Leader            bool       `json:"leader"`
+	LeaderID          string     `json:"leader_id,omitempty"`
+	LeaderDatabasePID int        `json:"leader_database_pid,omitempty"`
+	FencingToken      int64      `json:"fencing_token,omitempty"`
+	AcquiredAt        *time.Time `json:"acquired_at,omitempty"`
+	RenewedAt         *time.Time `json:"renewed_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	// LeaseAgeSeconds is the time since the leadership was acquired.
+	LeaseAgeSeconds *float64 `json:"lease_age_seconds,omitempty"`
+}
+
+// GetIngestLeaderHandler is the action handler for the admin
+// ingestion/leader end-point
+type GetIngestLeaderHandler struct {
+	Leader ingest.LeaderElector
+}
+
+// GetResource returns the ingestion leader election status.
+func (handler GetIngestLeaderHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	return ingestLeaderStatus(r, handler.Leader)
+}
+
+// IngestLeaderHandoffHandler is the action handler for the admin
+// ingestion/leader/handoff end-point. It deposes the current leader, so that
+// another instance takes over the ingestion.
+type IngestLeaderHandoffHandler struct {
+	Leader ingest.LeaderElector
+}
+
+// GetResource forces a leadership handoff and returns the ingestion leader
+// election status.
+func (handler IngestLeaderHandoffHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := handler.Leader.ForceHandoff(r.Context()); err != nil {
+		return nil, err
+	}
+	return ingestLeaderStatus(r, handler.Leader)
+}
+
+func ingestLeaderStatus(r *http.Request, leader ingest.LeaderElector) (IngestLeaderStatus, error) {
+	status, err := leader.Status(r.Context())
+	if err != nil {
+		return IngestLeaderStatus{}, err
+	}
+
+	response := IngestLeaderStatus{
+		Backend:           status.Backend,
+		NodeID:            status.NodeID,
+		Leader:            status.Leader,
+		LeaderID:          status.LeaderID,
+		LeaderDatabasePID: status.LeaderDatabasePID,
+		FencingToken:      status.FencingToken,
+	}
+	if !status.AcquiredAt.IsZero() {
+		response.AcquiredAt = &status.AcquiredAt
+		age := time.Since(status.AcquiredAt).Seconds()
+		response.LeaseAgeSeconds = &age
+	}
+	if !status.RenewedAt.IsZero() {
+		response.RenewedAt = &status.RenewedAt
+	}
+	if !status.ExpiresAt.IsZero() {
+		response.ExpiresAt = &status.ExpiresAt
+	}
+	return response, nil
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
 	"github.com/diamcircle/go/services/aurora/internal/context"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
@@ -57,6 +58,9 @@ type LedgerByIDQuery struct {
 
 type GetLedgerByIDHandler struct {
 	LedgerState *ledger.State
+	// ColdStorage, if set, is used to load ledgers older than the history
+	// elder.
+	ColdStorage *coldstorage.Store
 }
 
 func (handler GetLedgerByIDHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
@@ -65,17 +69,24 @@ func (handler GetLedgerByIDHandler) GetResource(w HeaderWriter, r *http.Request)
 	if err != nil {
 		return nil, err
 	}
-	if int32(qp.LedgerID) < handler.LedgerState.CurrentStatus().HistoryElder {
-		return nil, problem.BeforeHistory
-	}
-	historyQ, err := context.HistoryQFromRequest(r)
-	if err != nil {
-		return nil, err
-	}
 	var ledger history.Ledger
-	err = historyQ.LedgerBySequence(r.Context(), &ledger, int32(qp.LedgerID))
-	if err != nil {
-		return nil, err
+	if int32(qp.LedgerID) < handler.LedgerState.CurrentStatus().HistoryElder {
+		if handler.ColdStorage == nil {
+			return nil, problem.BeforeHistory
+		}
+		ledger, err = handler.ColdStorage.LedgerBySequence(r.Context(), int32(qp.LedgerID))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		historyQ, err := context.HistoryQFromRequest(r)
+		if err != nil {
+			return nil, err
+		}
+		err = historyQ.LedgerBySequence(r.Context(), &ledger, int32(qp.LedgerID))
+		if err != nil {
+			return nil, err
+		}
 	}
 	var result aurora.Ledger
 	resourceadapter.PopulateLedger(r.Context(), &result, ledger)
@@ -0,0 +1,350 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/diamcircle/go/amount"
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/protocols/aurora/effects"
+	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ledger"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/render/hal"
+)
+
+// LiquidityPoolPositionsQuery query struct for the
+// accounts/{account_id}/liquidity_pool_positions end-point
+type LiquidityPoolPositionsQuery struct {
+	AccountID string `schema:"account_id" valid:"accountID"`
+}
+
+// GetLiquidityPoolPositionsHandler is the action handler for the
+// accounts/{account_id}/liquidity_pool_positions end-point. It returns the
+// open liquidity pool positions of an account, built from the account
+// deposit, withdrawal and revocation effects, ordered by liquidity pool id.
+type GetLiquidityPoolPositionsHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResourcePage returns a page of liquidity pool positions.
+func (handler GetLiquidityPoolPositionsHandler) GetResourcePage(w HeaderWriter, r *http.Request) ([]hal.Pageable, error) {
+	ctx := r.Context()
+	qp := LiquidityPoolPositionsQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the effects of the pools in the page are loaded.
+	poolIDs, err := historyQ.LiquidityPoolIDsForAccount(ctx, qp.AccountID, pq)
+	if err != nil {
+		return nil, err
+	}
+	response := []hal.Pageable{}
+	if len(poolIDs) == 0 {
+		return response, nil
+	}
+
+	records, err := historyQ.LiquidityPoolEffectsForAccount(ctx, qp.AccountID, poolIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := computeLiquidityPoolPositions(records)
+	if err != nil {
+		return nil, err
+	}
+	positions = pageLiquidityPoolPositions(positions, pq)
+
+	pools, err := historyQ.GetLiquidityPoolsByID(ctx, poolIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load liquidity pools")
+	}
+	poolsByID := map[string]history.LiquidityPool{}
+	for _, pool := range pools {
+		poolsByID[pool.PoolID] = pool
+	}
+
+	for _, position := range positions {
+		var res aurora.LiquidityPoolPosition
+		populateLiquidityPoolPosition(ctx, &res, position, poolsByID[position.poolID])
+		response = append(response, res)
+	}
+	return response, nil
+}
+
+// liquidityPoolPosition holds the position of an account in a liquidity
+// pool. Amounts are in stroops and indexed like the pool reserves.
+type liquidityPoolPosition struct {
+	poolID    string
+	assets    [2]string
+	shares    *big.Int
+	deposited [2]*big.Int
+	withdrawn [2]*big.Int
+	costBasis [2]*big.Int
+}
+
+func newLiquidityPoolPosition(pool effects.LiquidityPool) (*liquidityPoolPosition, error) {
+	if len(pool.Reserves) != 2 {
+		return nil, errors.Errorf("unexpected number of reserves in liquidity pool %s", pool.ID)
+	}
+	position := &liquidityPoolPosition{poolID: pool.ID, shares: new(big.Int)}
+	for i := range position.assets {
+		position.assets[i] = pool.Reserves[i].Asset
+		position.deposited[i] = new(big.Int)
+		position.withdrawn[i] = new(big.Int)
+		position.costBasis[i] = new(big.Int)
+	}
+	return position, nil
+}
+
+// deposit adds deposited reserves to the position, and to its cost basis.
+func (p *liquidityPoolPosition) deposit(reserves []string, shares string) error {
+	if len(reserves) != len(p.assets) {
+		return errors.Errorf("unexpected number of reserves: %d", len(reserves))
+	}
+	amounts, err := parseLiquidityPoolAmounts(reserves...)
+	if err != nil {
+		return err
+	}
+	received, err := parseLiquidityPoolAmounts(shares)
+	if err != nil {
+		return err
+	}
+
+	for i := range p.deposited {
+		p.deposited[i].Add(p.deposited[i], amounts[i])
+		p.costBasis[i].Add(p.costBasis[i], amounts[i])
+	}
+	p.shares.Add(p.shares, received[0])
+	return nil
+}
+
+// withdraw removes redeemed shares from the position. The cost basis is
+// reduced in proportion to the shares redeemed.
+func (p *liquidityPoolPosition) withdraw(reserves []string, shares string) error {
+	if len(reserves) != len(p.assets) {
+		return errors.Errorf("unexpected number of reserves: %d", len(reserves))
+	}
+	amounts, err := parseLiquidityPoolAmounts(reserves...)
+	if err != nil {
+		return err
+	}
+	redeemed, err := parseLiquidityPoolAmounts(shares)
+	if err != nil {
+		return err
+	}
+
+	for i := range p.withdrawn {
+		p.withdrawn[i].Add(p.withdrawn[i], amounts[i])
+		if p.shares.Sign() > 0 {
+			reduction := new(big.Int).Mul(p.costBasis[i], redeemed[0])
+			reduction.Quo(reduction, p.shares)
+			p.costBasis[i].Sub(p.costBasis[i], reduction)
+		}
+	}
+	p.shares.Sub(p.shares, redeemed[0])
+	if p.shares.Sign() <= 0 {
+		p.shares.SetInt64(0)
+		for i := range p.costBasis {
+			p.costBasis[i].SetInt64(0)
+		}
+	}
+	return nil
+}
+
+// computeLiquidityPoolPositions builds the open liquidity pool positions of
+// an account from its liquidity pool effects, in ascending order.
+func computeLiquidityPoolPositions(records []history.Effect) ([]*liquidityPoolPosition, error) {
+	positions := map[string]*liquidityPoolPosition{}
+	position := func(pool effects.LiquidityPool) (*liquidityPoolPosition, error) {
+		if p, ok := positions[pool.ID]; ok {
+			return p, nil
+		}
+		p, err := newLiquidityPoolPosition(pool)
+		if err != nil {
+			return nil, err
+		}
+		positions[pool.ID] = p
+		return p, nil
+	}
+
+	for _, record := range records {
+		var err error
+		switch record.Type {
+		case history.EffectLiquidityPoolDeposited:
+			var details effects.LiquidityPoolDeposited
+			if err = record.UnmarshalDetails(&details); err != nil {
+				return nil, err
+			}
+			var p *liquidityPoolPosition
+			if p, err = position(details.LiquidityPool); err != nil {
+				return nil, err
+			}
+			reserves := make([]string, 0, len(details.ReservesDeposited))
+			for _, reserve := range details.ReservesDeposited {
+				reserves = append(reserves, reserve.Amount)
+			}
+			err = p.deposit(reserves, details.SharesReceived)
+		case history.EffectLiquidityPoolWithdrew:
+			var details effects.LiquidityPoolWithdrew
+			if err = record.UnmarshalDetails(&details); err != nil {
+				return nil, err
+			}
+			var p *liquidityPoolPosition
+			if p, err = position(details.LiquidityPool); err != nil {
+				return nil, err
+			}
+			reserves := make([]string, 0, len(details.ReservesReceived))
+			for _, reserve := range details.ReservesReceived {
+				reserves = append(reserves, reserve.Amount)
+			}
+			err = p.withdraw(reserves, details.SharesRedeemed)
+		case history.EffectLiquidityPoolRevoked:
+			var details effects.LiquidityPoolRevoked
+			if err = record.UnmarshalDetails(&details); err != nil {
+				return nil, err
+			}
+			var p *liquidityPoolPosition
+			if p, err = position(details.LiquidityPool); err != nil {
+				return nil, err
+			}
+			// revoked reserves are in the same order as the pool reserves
+			// but omit the reserves which were not revoked
+			reserves := []string{"0", "0"}
+			for _, reserve := range details.ReservesRevoked {
+				for i, asset := range p.assets {
+					if reserve.Asset == asset {
+						reserves[i] = reserve.Amount
+					}
+				}
+			}
+			err = p.withdraw(reserves, details.SharesRevoked)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not apply effect %d-%d", record.HistoryOperationID, record.Order)
+		}
+	}
+
+	var open []*liquidityPoolPosition
+	for _, p := range positions {
+		if p.shares.Sign() > 0 {
+			open = append(open, p)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].poolID < open[j].poolID })
+	return open, nil
+}
+
+// pageLiquidityPoolPositions returns the page of positions matching the page
+// query, using the liquidity pool id as cursor.
+func pageLiquidityPoolPositions(positions []*liquidityPoolPosition, pq db2.PageQuery) []*liquidityPoolPosition {
+	var page []*liquidityPoolPosition
+	if pq.Order == db2.OrderDescending {
+		for i := len(positions) - 1; i >= 0; i-- {
+			if pq.Cursor == "" || positions[i].poolID < pq.Cursor {
+				page = append(page, positions[i])
+			}
+		}
+	} else {
+		for _, p := range positions {
+			if p.poolID > pq.Cursor {
+				page = append(page, p)
+			}
+		}
+	}
+	if uint64(len(page)) > pq.Limit {
+		page = page[:pq.Limit]
+	}
+	return page
+}
+
+// populateLiquidityPoolPosition fills out the details of a position. The
+// current value and the impermanent loss are zero if the pool no longer
+// exists.
+func populateLiquidityPoolPosition(
+	ctx context.Context,
+	dest *aurora.LiquidityPoolPosition,
+	position *liquidityPoolPosition,
+	pool history.LiquidityPool,
+) {
+	dest.LiquidityPoolID = position.poolID
+	dest.PT = position.poolID
+	dest.Shares = amount.StringFromInt64(position.shares.Int64())
+
+	var value [2]*big.Int
+	for i, asset := range position.assets {
+		value[i] = new(big.Int)
+		if len(pool.AssetReserves) == 2 && pool.ShareCount > 0 {
+			value[i].Mul(position.shares, new(big.Int).SetUint64(pool.AssetReserves[i].Reserve))
+			value[i].Quo(value[i], new(big.Int).SetUint64(pool.ShareCount))
+		}
+
+		dest.Deposited = append(dest.Deposited, aurora.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: amount.StringFromInt64(position.deposited[i].Int64()),
+		})
+		dest.Withdrawn = append(dest.Withdrawn, aurora.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: amount.StringFromInt64(position.withdrawn[i].Int64()),
+		})
+		dest.CostBasis = append(dest.CostBasis, aurora.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: amount.StringFromInt64(position.costBasis[i].Int64()),
+		})
+		dest.CurrentValue = append(dest.CurrentValue, aurora.LiquidityPoolReserve{
+			Asset:  asset,
+			Amount: amount.StringFromInt64(value[i].Int64()),
+		})
+	}
+
+	// value both the position and the cost basis in the second reserve asset
+	// at the current pool price
+	impermanentLoss := new(big.Rat)
+	if len(pool.AssetReserves) == 2 && pool.AssetReserves[0].Reserve > 0 {
+		price := new(big.Rat).SetFrac(
+			new(big.Int).SetUint64(pool.AssetReserves[1].Reserve),
+			new(big.Int).SetUint64(pool.AssetReserves[0].Reserve),
+		)
+		held := new(big.Rat).Mul(new(big.Rat).SetInt(position.costBasis[0]), price)
+		held.Add(held, new(big.Rat).SetInt(position.costBasis[1]))
+		current := new(big.Rat).Mul(new(big.Rat).SetInt(value[0]), price)
+		current.Add(current, new(big.Rat).SetInt(value[1]))
+		if held.Sign() > 0 {
+			impermanentLoss.Quo(current, held)
+			impermanentLoss.Sub(impermanentLoss, big.NewRat(1, 1))
+		}
+	}
+	dest.ImpermanentLoss = impermanentLoss.FloatString(7)
+
+	lb := hal.LinkBuilder{Base: auroraContext.BaseURL(ctx)}
+	dest.Links.LiquidityPool = lb.Link(fmt.Sprintf("/liquidity_pools/%s", position.poolID))
+}
+
+// parseLiquidityPoolAmounts parses amount strings into stroops
+func parseLiquidityPoolAmounts(amounts ...string) ([]*big.Int, error) {
+	parsed := make([]*big.Int, len(amounts))
+	for i, a := range amounts {
+		v, err := amount.ParseInt64(a)
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = big.NewInt(v)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/protocols/aurora/base"
+	"github.com/diamcircle/go/protocols/aurora/effects"
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/xdr"
+)
+
+func liquidityPoolEffect(t *testing.T, typ history.EffectType, details interface{}) history.Effect {
+	encoded, err := json.Marshal(details)
+	require.NoError(t, err)
+	return history.Effect{Type: typ, DetailsString: null.StringFrom(string(encoded))}
+}
+
+func TestLiquidityPoolPositions(t *testing.T) {
+	eur := xdr.MustNewCreditAsset("EUR", "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB")
+	pool := effects.LiquidityPool{
+		ID: "cafebabe",
+		Reserves: []base.AssetAmount{
+			{Asset: eur.StringCanonical()},
+			{Asset: "native"},
+		},
+	}
+	closedPool := pool
+	closedPool.ID = "0000beef"
+
+	records := []history.Effect{
+		liquidityPoolEffect(t, history.EffectLiquidityPoolDeposited, effects.LiquidityPoolDeposited{
+			LiquidityPool: pool,
+			ReservesDeposited: []base.AssetAmount{
+				{Asset: eur.StringCanonical(), Amount: "100.0000000"},
+				{Asset: "native", Amount: "400.0000000"},
+			},
+			SharesReceived: "200.0000000",
+		}),
+		liquidityPoolEffect(t, history.EffectLiquidityPoolDeposited, effects.LiquidityPoolDeposited{
+			LiquidityPool: closedPool,
+			ReservesDeposited: []base.AssetAmount{
+				{Asset: eur.StringCanonical(), Amount: "1.0000000"},
+				{Asset: "native", Amount: "1.0000000"},
+			},
+			SharesReceived: "1.0000000",
+		}),
+		liquidityPoolEffect(t, history.EffectLiquidityPoolWithdrew, effects.LiquidityPoolWithdrew{
+			LiquidityPool: pool,
+			ReservesReceived: []base.AssetAmount{
+				{Asset: eur.StringCanonical(), Amount: "30.0000000"},
+				{Asset: "native", Amount: "90.0000000"},
+			},
+			SharesRedeemed: "50.0000000",
+		}),
+		liquidityPoolEffect(t, history.EffectLiquidityPoolRevoked, effects.LiquidityPoolRevoked{
+			LiquidityPool: closedPool,
+			ReservesRevoked: []effects.LiquidityPoolClaimableAssetAmount{
+				{Asset: "native", Amount: "2.0000000"},
+			},
+			SharesRevoked: "1.0000000",
+		}),
+	}
+
+	positions, err := computeLiquidityPoolPositions(records)
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+
+	var dest aurora.LiquidityPoolPosition
+	populateLiquidityPoolPosition(context.Background(), &dest, positions[0], history.LiquidityPool{
+		PoolID:     "cafebabe",
+		ShareCount: 15000000000,
+		AssetReserves: history.LiquidityPoolAssetReserves{
+			{Asset: eur, Reserve: 10000000000},
+			{Asset: xdr.MustNewNativeAsset(), Reserve: 27000000000},
+		},
+	})
+	assert.Equal(t, "cafebabe", dest.LiquidityPoolID)
+	assert.Equal(t, "cafebabe", dest.PagingToken())
+	assert.Equal(t, "150.0000000", dest.Shares)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "100.0000000"},
+		{Asset: "native", Amount: "400.0000000"},
+	}, dest.Deposited)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "30.0000000"},
+		{Asset: "native", Amount: "90.0000000"},
+	}, dest.Withdrawn)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "75.0000000"},
+		{Asset: "native", Amount: "300.0000000"},
+	}, dest.CostBasis)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "100.0000000"},
+		{Asset: "native", Amount: "270.0000000"},
+	}, dest.CurrentValue)
+	// 540 XLM worth of reserves against 502.5 XLM worth of held assets
+	assert.Equal(t, "0.0746269", dest.ImpermanentLoss)
+
+	// the pool no longer exists
+	dest = aurora.LiquidityPoolPosition{}
+	populateLiquidityPoolPosition(context.Background(), &dest, positions[0], history.LiquidityPool{})
+	assert.Equal(t, "0.0000000", dest.CurrentValue[0].Amount)
+	assert.Equal(t, "0.0000000", dest.ImpermanentLoss)
+}
+
+func TestPageLiquidityPoolPositions(t *testing.T) {
+	positions := []*liquidityPoolPosition{{poolID: "aa"}, {poolID: "bb"}, {poolID: "cc"}}
+	ids := func(page []*liquidityPoolPosition) []string {
+		var ids []string
+		for _, p := range page {
+			ids = append(ids, p.poolID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"aa", "bb"}, ids(pageLiquidityPoolPositions(positions, db2.PageQuery{Order: "asc", Limit: 2})))
+	assert.Equal(t, []string{"cc"}, ids(pageLiquidityPoolPositions(positions, db2.PageQuery{Cursor: "bb", Order: "asc", Limit: 2})))
+	assert.Equal(t, []string{"cc", "bb"}, ids(pageLiquidityPoolPositions(positions, db2.PageQuery{Order: "desc", Limit: 2})))
+	assert.Equal(t, []string{"aa"}, ids(pageLiquidityPoolPositions(positions, db2.PageQuery{Cursor: "bb", Order: "desc", Limit: 2})))
+}
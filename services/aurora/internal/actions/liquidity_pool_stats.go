@@ -0,0 +1,162 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+	gTime "time"
+
+	"github.com/diamcircle/go/protocols/aurora"
+	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ledger"
+	"github.com/diamcircle/go/services/aurora/internal/resourceadapter"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/render/hal"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/diamcircle/go/support/time"
+	"github.com/diamcircle/go/xdr"
+)
+
+// LiquidityPoolStatsQuery query struct for liquidity_pools/{id}/stats end-point
+type LiquidityPoolStatsQuery struct {
+	ID               string      `schema:"liquidity_pool_id" valid:"sha256"`
+	OffsetFilter     uint64      `schema:"offset" valid:"-"`
+	StartTimeFilter  time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter    time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs validations on LiquidityPoolStatsQuery
+func (q LiquidityPoolStatsQuery) Validate() error {
+	resolutionDuration := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
+	if !history.ValidResolution(resolutionDuration) {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"the resolution must be a multiple of 1 minute (60000), 5 minutes (300000), 15 minutes (900000), "+
+				"1 hour (3600000), 1 day (86400000) or 1 week (604800000)"),
+		)
+	}
+	offsetDuration := gTime.Duration(q.OffsetFilter) * gTime.Millisecond
+	if !history.ValidOffset(offsetDuration, resolutionDuration) {
+		return problem.MakeInvalidFieldProblem(
+			"offset",
+			errors.New("illegal or missing offset. offset must be a multiple of a"+
+				" minute, less than or equal to the resolution, and less than 24 hours"),
+		)
+	}
+	if !q.EndTimeFilter.IsNil() && q.EndTimeFilter < q.StartTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("illegal end time. end time must not be less than the start time"),
+		)
+	}
+
+	return nil
+}
+
+// GetLiquidityPoolStatsHandler is the action handler for the
+// liquidity_pools/{id}/stats end-point
+type GetLiquidityPoolStatsHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of liquidity pool stats
+func (handler GetLiquidityPoolStatsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := LiquidityPoolStatsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	lp, err := historyQ.FindLiquidityPoolByID(ctx, qp.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lp.AssetReserves) != 2 {
+		return nil, errors.Errorf("unexpected number of reserves in liquidity pool %s", qp.ID)
+	}
+	assets := [2]xdr.Asset{lp.AssetReserves[0].Asset, lp.AssetReserves[1].Asset}
+
+	historyLP, err := historyQ.LiquidityPoolByID(ctx, qp.ID)
+	if err != nil {
+		return nil, err
+	}
+	// the first reserve asset is missing from history_assets if it was never
+	// traded, in which case no trade matches the 0 id
+	assetAID, err := historyQ.GetAssetID(ctx, assets[0])
+	if err != nil && !historyQ.NoRows(err) {
+		return nil, err
+	}
+
+	query := history.LiquidityPoolStatsQuery{
+		LiquidityPoolID: historyLP.InternalID,
+		AssetAID:        assetAID,
+		Resolution:      int64(qp.ResolutionFilter),
+		Offset:          int64(qp.OffsetFilter),
+		StartTime:       qp.StartTimeFilter,
+		Order:           pq.Order,
+		Limit:           pq.Limit,
+	}
+	if !qp.EndTimeFilter.IsNil() {
+		// round the end time down, to not deliver a partial bucket
+		offset := time.MillisFromInt64(query.Offset)
+		if qp.EndTimeFilter < offset {
+			return nil, problem.MakeInvalidFieldProblem(
+				"end_time",
+				errors.New("illegal end time. end time must be greater than the offset"),
+			)
+		}
+		query.EndTime = (qp.EndTimeFilter - offset).RoundDown(query.Resolution) + offset
+	}
+
+	records, err := historyQ.GetLiquidityPoolStats(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+
+	for _, record := range records {
+		var res aurora.LiquidityPoolStats
+		err = resourceadapter.PopulateLiquidityPoolStats(ctx, &res, record, assets, query.Resolution)
+		if err != nil {
+			return nil, err
+		}
+		page.Add(res)
+	}
+
+	newURL := FullURL(ctx)
+	page.Links.Self = hal.NewLink(newURL.String())
+
+	// adjust the time range for the next page
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+	} else {
+		timestamp := records[len(records)-1].Timestamp
+		q := newURL.Query()
+		if page.Order == "asc" {
+			q.Set("start_time", strconv.FormatInt(timestamp+query.Resolution, 10))
+		} else {
+			q.Set("end_time", strconv.FormatInt(timestamp, 10))
+		}
+		newURL.RawQuery = q.Encode()
+		page.Links.Next = hal.NewLink(newURL.String())
+	}
+
+	return page, nil
+}
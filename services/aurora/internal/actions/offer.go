@@ -30,7 +30,7 @@ func (handler GetOfferByID) GetResource(w HeaderWriter, r *http.Request) (interf
 		return nil, err
 	}
 
-	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	historyQ, err := auroraContext.QFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +114,7 @@ func (handler GetOffersHandler) GetResourcePage(
 		Buying:    buying,
 	}
 
-	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	historyQ, err := auroraContext.QFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +168,7 @@ func (handler GetAccountOffersHandler) GetResourcePage(
 		return nil, err
 	}
 
-	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	historyQ, err := auroraContext.QFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -181,7 +181,7 @@ func (handler GetAccountOffersHandler) GetResourcePage(
 	return offers, nil
 }
 
-func getOffersPage(ctx context.Context, historyQ *history.Q, query history.OffersQuery) ([]hal.Pageable, error) {
+func getOffersPage(ctx context.Context, historyQ history.QInterface, query history.OffersQuery) ([]hal.Pageable, error) {
 	records, err := historyQ.GetOffers(ctx, query)
 	if err != nil {
 		return nil, err
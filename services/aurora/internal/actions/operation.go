@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
 	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
+	"github.com/diamcircle/go/services/aurora/internal/db2"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
 	"github.com/diamcircle/go/services/aurora/internal/render/problem"
@@ -65,6 +67,9 @@ func (qp OperationsQuery) Validate() error {
 type GetOperationsHandler struct {
 	LedgerState  *ledger.State
 	OnlyPayments bool
+	// ColdStorage, if set, is used to page the operations of an account past
+	// the history elder.
+	ColdStorage *coldstorage.Store
 }
 
 // GetResourcePage returns a page of operations.
@@ -76,17 +81,20 @@ func (handler GetOperationsHandler) GetResourcePage(w HeaderWriter, r *http.Requ
 		return nil, err
 	}
 
-	err = validateCursorWithinHistory(handler.LedgerState, pq)
-	if err != nil {
-		return nil, err
-	}
-
 	qp := OperationsQuery{}
 	err = getParams(&qp, r)
 	if err != nil {
 		return nil, err
 	}
 
+	withColdStorage := handler.ColdStorage != nil && qp.AccountID != ""
+	if !withColdStorage {
+		err = validateCursorWithinHistory(handler.LedgerState, pq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	historyQ, err := auroraContext.HistoryQFromRequest(r)
 	if err != nil {
 		return nil, err
@@ -121,12 +129,82 @@ func (handler GetOperationsHandler) GetResourcePage(w HeaderWriter, r *http.Requ
 		query.OnlyPayments()
 	}
 
+	if withColdStorage {
+		return handler.pageWithColdStorage(ctx, historyQ, query, qp, pq)
+	}
+
 	ops, txs, err := query.Page(pq).Fetch(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return buildOperationsPage(ctx, historyQ, ops, txs, qp.IncludeTransactions())
+	return buildOperationsPage(ctx, historyQ, ops, txs, qp.IncludeTransactions(), nil)
+}
+
+// pageWithColdStorage merges the operations of an account loaded from the
+// database with the ones reaped to cold storage. Reaped operations are older
+// than the ones in the database so they come first in ascending order and
+// last in descending order.
+func (handler GetOperationsHandler) pageWithColdStorage(
+	ctx context.Context,
+	historyQ *history.Q,
+	query *history.OperationsQ,
+	qp OperationsQuery,
+	pq db2.PageQuery,
+) ([]hal.Pageable, error) {
+	cursor, err := pq.CursorInt64()
+	if err != nil {
+		return nil, supportProblem.MakeInvalidFieldProblem("cursor", errors.New("invalid value"))
+	}
+	elder := toid.New(handler.LedgerState.CurrentStatus().HistoryElder, 0, 0).ToInt64()
+	coldQuery := coldstorage.OperationsQuery{
+		Account:             qp.AccountID,
+		IncludeFailed:       qp.IncludeFailedTransactions,
+		IncludeTransactions: qp.IncludeTransactions(),
+		OnlyPayments:        handler.OnlyPayments,
+		Page:                pq,
+	}
+
+	var (
+		ops  []history.Operation
+		txs  []history.Transaction
+		cold coldstorage.Operations
+	)
+	if pq.Order == db2.OrderDescending {
+		ops, txs, err = query.Page(pq).Fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(ops)) < pq.Limit {
+			coldQuery.Page.Limit = pq.Limit - uint64(len(ops))
+			cold, err = handler.ColdStorage.AccountOperations(ctx, historyQ, coldQuery)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, cold.Operations...)
+			txs = append(txs, cold.Transactions...)
+		}
+	} else {
+		if cursor < elder {
+			cold, err = handler.ColdStorage.AccountOperations(ctx, historyQ, coldQuery)
+			if err != nil {
+				return nil, err
+			}
+			ops, txs = cold.Operations, cold.Transactions
+		}
+		if uint64(len(ops)) < pq.Limit {
+			hotPage := pq
+			hotPage.Limit = pq.Limit - uint64(len(ops))
+			hotOps, hotTxs, err := query.Page(hotPage).Fetch(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ops = append(ops, hotOps...)
+			txs = append(txs, hotTxs...)
+		}
+	}
+
+	return buildOperationsPage(ctx, historyQ, ops, txs, qp.IncludeTransactions(), cold.Ledgers)
 }
 
 // GetOperationByIDHandler is the action handler for all end-points returning a list of operations.
@@ -185,10 +263,21 @@ func (handler GetOperationByIDHandler) GetResource(w HeaderWriter, r *http.Reque
 	)
 }
 
-func buildOperationsPage(ctx context.Context, historyQ *history.Q, operations []history.Operation, transactions []history.Transaction, includeTransactions bool) ([]hal.Pageable, error) {
+// buildOperationsPage renders operations. The ledgers of the operations are
+// loaded from the database unless they are found in coldLedgers.
+func buildOperationsPage(
+	ctx context.Context,
+	historyQ *history.Q,
+	operations []history.Operation,
+	transactions []history.Transaction,
+	includeTransactions bool,
+	coldLedgers map[int32]history.Ledger,
+) ([]hal.Pageable, error) {
 	ledgerCache := history.LedgerCache{}
 	for _, record := range operations {
-		ledgerCache.Queue(record.LedgerSequence())
+		if _, ok := coldLedgers[record.LedgerSequence()]; !ok {
+			ledgerCache.Queue(record.LedgerSequence())
+		}
 	}
 
 	if err := ledgerCache.Load(ctx, historyQ); err != nil {
@@ -198,6 +287,9 @@ func buildOperationsPage(ctx context.Context, historyQ *history.Q, operations []
 	var response []hal.Pageable
 	for i, operationRecord := range operations {
 		ledger, found := ledgerCache.Records[operationRecord.LedgerSequence()]
+		if !found {
+			ledger, found = coldLedgers[operationRecord.LedgerSequence()]
+		}
 		if !found {
 			msg := fmt.Sprintf("could not find ledger data for sequence %d", operationRecord.LedgerSequence())
 			return nil, errors.New(msg)
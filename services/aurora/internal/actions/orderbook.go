@@ -1,15 +1,29 @@
 package actions
 
 import (
+	"context"
+	"math"
+	"math/big"
 	"net/http"
+	"sort"
+	"strconv"
 
+	"github.com/diamcircle/go/amount"
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/price"
 	protocol "github.com/diamcircle/go/protocols/aurora"
-	"github.com/diamcircle/go/services/aurora/internal/context"
+	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/resourceadapter"
+	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/support/render/problem"
+	"github.com/diamcircle/go/xdr"
 )
 
+// poolPriceStepBips is the increase of the marginal price of a liquidity
+// pool between two of the price levels synthesized from its reserves.
+const poolPriceStepBips = 50
+
 // StreamableObjectResponse is an interface for objects returned by streamable object endpoints
 // A streamable object endpoint is an SSE endpoint which returns a single JSON object response
 // instead of a page of items.
@@ -95,7 +109,16 @@ func (handler GetOrderbookHandler) GetResource(w HeaderWriter, r *http.Request)
 		return nil, invalidOrderBook
 	}
 
-	historyQ, err := context.HistoryQFromRequest(r)
+	includePools, err := getIncludePools(r)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := getPriceBucket(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
@@ -112,8 +135,205 @@ func (handler GetOrderbookHandler) GetResource(w HeaderWriter, r *http.Request)
 	if err := resourceadapter.PopulateAsset(r.Context(), &response.Buying, buying); err != nil {
 		return nil, err
 	}
-	response.Bids = convertPriceLevels(summary.Bids)
-	response.Asks = convertPriceLevels(summary.Asks)
+	if !includePools && bucket == nil {
+		response.Bids = convertPriceLevels(summary.Bids)
+		response.Asks = convertPriceLevels(summary.Asks)
+		return response, nil
+	}
+
+	asks, err := depthLevelsFromSummary(summary.Asks)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := depthLevelsFromSummary(summary.Bids)
+	if err != nil {
+		return nil, err
+	}
+	if includePools {
+		var poolAsks, poolBids []depthLevel
+		poolAsks, poolBids, err = poolDepthLevels(r.Context(), historyQ, selling, buying, int(limit))
+		if err != nil {
+			return nil, err
+		}
+		asks = append(asks, poolAsks...)
+		bids = append(bids, poolBids...)
+	}
 
+	if response.Asks, err = aggregateDepthLevels(asks, bucket, true, int(limit)); err != nil {
+		return nil, err
+	}
+	if response.Bids, err = aggregateDepthLevels(bids, bucket, false, int(limit)); err != nil {
+		return nil, err
+	}
 	return response, nil
 }
+
+func getIncludePools(r *http.Request) (bool, error) {
+	value, err := getString(r, "include_pools")
+	if err != nil || value == "" {
+		return false, err
+	}
+	includePools, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, problem.MakeInvalidFieldProblem(
+			"include_pools", errors.New("must be true or false"),
+		)
+	}
+	return includePools, nil
+}
+
+// getPriceBucket returns the width of the price buckets requested with the
+// price_bucket parameter, or nil if price levels should not be aggregated.
+func getPriceBucket(r *http.Request) (*big.Rat, error) {
+	value, err := getString(r, "price_bucket")
+	if err != nil || value == "" {
+		return nil, err
+	}
+	width, err := amount.ParseInt64(value)
+	if err != nil || width <= 0 {
+		return nil, problem.MakeInvalidFieldProblem(
+			"price_bucket", errors.New("must be a positive number with at most 7 decimal places"),
+		)
+	}
+	return big.NewRat(width, amount.One), nil
+}
+
+// depthLevel is a price level which may combine offers and liquidity pools.
+// Prices are in units of the buying asset per unit of the selling asset and
+// amounts are in units of the asset being sold at that level.
+type depthLevel struct {
+	price  *big.Rat
+	amount *big.Rat
+}
+
+func depthLevelsFromSummary(src []history.PriceLevel) ([]depthLevel, error) {
+	levels := make([]depthLevel, 0, len(src))
+	for _, l := range src {
+		levelAmount, ok := new(big.Rat).SetString(l.Amount)
+		if !ok {
+			return nil, errors.Errorf("invalid price level amount %s", l.Amount)
+		}
+		levels = append(levels, depthLevel{
+			price:  big.NewRat(int64(l.Pricen), int64(l.Priced)),
+			amount: levelAmount,
+		})
+	}
+	return levels, nil
+}
+
+// poolDepthLevels synthesizes the asks and bids of the liquidity pool
+// trading selling for buying, if there is one.
+func poolDepthLevels(
+	ctx context.Context, historyQ *history.Q, selling, buying xdr.Asset, limit int,
+) ([]depthLevel, []depthLevel, error) {
+	assetA, assetB := selling, buying
+	if assetB.LessThan(assetA) {
+		assetA, assetB = assetB, assetA
+	}
+	poolID, err := xdr.NewPoolId(assetA, assetB, xdr.LiquidityPoolFeeV18)
+	if err != nil {
+		return nil, nil, nil
+	}
+	hexID, err := xdr.MarshalHex(poolID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not encode liquidity pool id")
+	}
+
+	pool, err := historyQ.FindLiquidityPoolByID(ctx, hexID)
+	if historyQ.NoRows(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, errors.Wrap(err, "could not load liquidity pool")
+	}
+
+	var sellingReserve, buyingReserve xdr.Int64
+	for _, reserve := range pool.AssetReserves {
+		if reserve.Asset.Equals(selling) {
+			sellingReserve = xdr.Int64(reserve.Reserve)
+		} else if reserve.Asset.Equals(buying) {
+			buyingReserve = xdr.Int64(reserve.Reserve)
+		}
+	}
+	fee := xdr.Int32(pool.Fee)
+
+	var asks, bids []depthLevel
+	// asks pay out the selling asset in exchange for the buying asset
+	for _, level := range orderbook.PoolPriceLevels(buyingReserve, sellingReserve, fee, poolPriceStepBips, limit) {
+		asks = append(asks, depthLevel{
+			price:  big.NewRat(int64(level.Cost), int64(level.Amount)),
+			amount: big.NewRat(int64(level.Amount), amount.One),
+		})
+	}
+	// bids pay out the buying asset in exchange for the selling asset
+	for _, level := range orderbook.PoolPriceLevels(sellingReserve, buyingReserve, fee, poolPriceStepBips, limit) {
+		bids = append(bids, depthLevel{
+			price:  big.NewRat(int64(level.Amount), int64(level.Cost)),
+			amount: big.NewRat(int64(level.Amount), amount.One),
+		})
+	}
+	return asks, bids, nil
+}
+
+// aggregateDepthLevels sorts the levels from the best to the worst price and
+// merges the levels sharing a price. If bucket is not nil prices are first
+// rounded to a multiple of bucket: up for asks and down for bids, so that
+// the price of a bucket is never better than the price of the levels in it.
+// At most limit levels are returned.
+func aggregateDepthLevels(levels []depthLevel, bucket *big.Rat, asks bool, limit int) ([]protocol.PriceLevel, error) {
+	if bucket != nil {
+		for i, level := range levels {
+			multiple := new(big.Rat).Quo(level.price, bucket)
+			quo, rem := new(big.Int).QuoRem(multiple.Num(), multiple.Denom(), new(big.Int))
+			if asks && rem.Sign() > 0 {
+				quo.Add(quo, big.NewInt(1))
+			}
+			levels[i].price = new(big.Rat).Mul(new(big.Rat).SetInt(quo), bucket)
+		}
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool {
+		cmp := levels[i].price.Cmp(levels[j].price)
+		if asks {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+
+	result := make([]protocol.PriceLevel, 0, len(levels))
+	for i := 0; i < len(levels) && len(result) < limit; {
+		sum := new(big.Rat).Set(levels[i].amount)
+		j := i + 1
+		for ; j < len(levels) && levels[j].price.Cmp(levels[i].price) == 0; j++ {
+			sum.Add(sum, levels[j].amount)
+		}
+
+		priceR, err := ratToPrice(levels[i].price)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, protocol.PriceLevel{
+			PriceR: priceR,
+			Price:  levels[i].price.FloatString(7),
+			Amount: sum.FloatString(7),
+		})
+		i = j
+	}
+	return result, nil
+}
+
+// ratToPrice returns the price as a fraction, approximated if the numerator
+// or denominator do not fit in an int32.
+func ratToPrice(r *big.Rat) (protocol.Price, error) {
+	if r.Num().IsInt64() && r.Num().Int64() <= math.MaxInt32 &&
+		r.Denom().IsInt64() && r.Denom().Int64() <= math.MaxInt32 {
+		return protocol.Price{
+			N: int32(r.Num().Int64()),
+			D: int32(r.Denom().Int64()),
+		}, nil
+	}
+	approximation, err := price.Parse(r.FloatString(7))
+	if err != nil {
+		return protocol.Price{}, errors.Wrap(err, "could not approximate price")
+	}
+	return protocol.Price{N: int32(approximation.N), D: int32(approximation.D)}, nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/test"
+	"github.com/diamcircle/go/support/render/problem"
 	"github.com/stretchr/testify/assert"
 
 	protocol "github.com/diamcircle/go/protocols/aurora"
@@ -667,3 +668,175 @@ func TestOrderbookGetResource(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderbookGetResourceWithPools(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	var eurAssetType, eurAssetCode, eurAssetIssuer string
+	if err := eurAsset.Extract(&eurAssetType, &eurAssetCode, &eurAssetIssuer); err != nil {
+		t.Fatalf("cound not extract eur asset: %v", err)
+	}
+
+	offers := []history.Offer{
+		{
+			SellerID:           seller.Address(),
+			OfferID:            int64(3),
+			BuyingAsset:        eurAsset,
+			SellingAsset:       nativeAsset,
+			Amount:             int64(500),
+			Pricen:             int32(2),
+			Priced:             int32(1),
+			Price:              float64(2),
+			LastModifiedLedger: uint32(4),
+		},
+		{
+			SellerID:           seller.Address(),
+			OfferID:            int64(20),
+			BuyingAsset:        eurAsset,
+			SellingAsset:       nativeAsset,
+			Amount:             int64(500),
+			Pricen:             int32(3),
+			Priced:             int32(1),
+			Price:              float64(3),
+			LastModifiedLedger: uint32(4),
+		},
+		{
+			SellerID:           seller.Address(),
+			OfferID:            int64(17),
+			BuyingAsset:        nativeAsset,
+			SellingAsset:       eurAsset,
+			Amount:             int64(500),
+			Pricen:             int32(5),
+			Priced:             int32(9),
+			Price:              float64(5) / float64(9),
+			LastModifiedLedger: uint32(4),
+		},
+	}
+	pool := history.MakeTestPool(nativeAsset, 1000000000, eurAsset, 2000000000)
+
+	assert.NoError(t, q.TruncateTables(tt.Ctx, []string{"offers", "liquidity_pools"}))
+	assert.NoError(t, q.UpsertOffers(tt.Ctx, offers))
+	assert.NoError(t, q.UpsertLiquidityPools(tt.Ctx, []history.LiquidityPool{pool}))
+
+	assert.NoError(t, q.BeginTx(&sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	}))
+	defer q.Rollback()
+
+	empty := OrderBookResponse{
+		OrderBookSummary: protocol.OrderBookSummary{
+			Selling: protocol.Asset{
+				Type: "native",
+			},
+			Buying: protocol.Asset{
+				Type:   eurAssetType,
+				Code:   eurAssetCode,
+				Issuer: eurAssetIssuer,
+			},
+		},
+	}
+
+	poolLevels := empty
+	poolLevels.Asks = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 2, D: 1}, Price: "2.0000000", Amount: "0.0000500"},
+		{PriceR: protocol.Price{N: 1669597, D: 830221}, Price: "2.0110272", Amount: "0.2490663"},
+		{PriceR: protocol.Price{N: 5021299, D: 2484461}, Price: "2.0210818", Amount: "0.2484461"},
+	}
+	poolLevels.Bids = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 4981327, D: 2504396}, Price: "1.9890333", Amount: "0.4981327"},
+		{PriceR: protocol.Price{N: 1656307, D: 836883}, Price: "1.9791381", Amount: "0.4968921"},
+		{PriceR: protocol.Price{N: 4956545, D: 2516918}, Price: "1.9692914", Amount: "0.4956545"},
+	}
+
+	bucketed := empty
+	bucketed.Asks = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 2, D: 1}, Price: "2.0000000", Amount: "0.0000500"},
+		{PriceR: protocol.Price{N: 21, D: 10}, Price: "2.1000000", Amount: "0.4975124"},
+	}
+	bucketed.Bids = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 19, D: 10}, Price: "1.9000000", Amount: "0.9950248"},
+		{PriceR: protocol.Price{N: 9, D: 5}, Price: "1.8000000", Amount: "0.0000500"},
+	}
+
+	bucketedOffers := empty
+	bucketedOffers.Asks = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 2, D: 1}, Price: "2.0000000", Amount: "0.0000500"},
+		{PriceR: protocol.Price{N: 3, D: 1}, Price: "3.0000000", Amount: "0.0000500"},
+	}
+	bucketedOffers.Bids = []protocol.PriceLevel{
+		{PriceR: protocol.Price{N: 1, D: 1}, Price: "1.0000000", Amount: "0.0000500"},
+	}
+
+	for _, testCase := range []struct {
+		name        string
+		queryParams map[string]string
+		expected    OrderBookResponse
+	}{
+		{
+			"include pools",
+			map[string]string{"include_pools": "true", "limit": "3"},
+			poolLevels,
+		},
+		{
+			"include pools with price buckets",
+			map[string]string{"include_pools": "true", "price_bucket": "0.1", "limit": "2"},
+			bucketed,
+		},
+		{
+			"price buckets without pools",
+			map[string]string{"price_bucket": "1"},
+			bucketedOffers,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			params := map[string]string{
+				"buying_asset_type":   eurAssetType,
+				"buying_asset_code":   eurAssetCode,
+				"buying_asset_issuer": eurAssetIssuer,
+				"selling_asset_type":  "native",
+			}
+			for key, value := range testCase.queryParams {
+				params[key] = value
+			}
+			r := makeRequest(t, params, map[string]string{}, q)
+			w := httptest.NewRecorder()
+			response, err := GetOrderbookHandler{}.GetResource(w, r)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !response.Equals(testCase.expected) {
+				t.Fatalf("expected %v but got %v", testCase.expected, response)
+			}
+		})
+	}
+}
+
+func TestOrderbookGetResourceInvalidDepthParams(t *testing.T) {
+	for _, testCase := range []struct {
+		name        string
+		queryParams map[string]string
+	}{
+		{"invalid include_pools", map[string]string{"include_pools": "maybe"}},
+		{"zero price_bucket", map[string]string{"price_bucket": "0"}},
+		{"negative price_bucket", map[string]string{"price_bucket": "-1"}},
+		{"too precise price_bucket", map[string]string{"price_bucket": "0.00000001"}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			params := map[string]string{
+				"buying_asset_type":  "native",
+				"selling_asset_type": "native",
+			}
+			for key, value := range testCase.queryParams {
+				params[key] = value
+			}
+			r := makeRequest(t, params, map[string]string{}, nil)
+			w := httptest.NewRecorder()
+			_, err := GetOrderbookHandler{}.GetResource(w, r)
+			assert.IsType(t, &problem.P{}, err)
+		})
+	}
+}
@@ -332,6 +332,126 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 	return renderPaths(ctx, records)
 }
 
+// FindSplitPathsHandler is the http handler for the split payment paths
+// endpoint. It plans a strict send or strict receive payment divided across
+// several payment paths.
+type FindSplitPathsHandler struct {
+	MaxPathLength       uint
+	SetLastLedgerHeader bool
+	PathFinder          paths.Finder
+}
+
+// SplitPathsQuery query struct for paths/split end-point
+type SplitPathsQuery struct {
+	SourceAccount          string `schema:"source_account" valid:"accountID,optional"`
+	SourceAssetType        string `schema:"source_asset_type" valid:"assetType"`
+	SourceAssetIssuer      string `schema:"source_asset_issuer" valid:"accountID,optional"`
+	SourceAssetCode        string `schema:"source_asset_code" valid:"-"`
+	SourceAmount           string `schema:"source_amount" valid:"amount,optional"`
+	DestinationAccount     string `schema:"destination_account" valid:"accountID,optional"`
+	DestinationAssetType   string `schema:"destination_asset_type" valid:"assetType"`
+	DestinationAssetIssuer string `schema:"destination_asset_issuer" valid:"accountID,optional"`
+	DestinationAssetCode   string `schema:"destination_asset_code" valid:"-"`
+	DestinationAmount      string `schema:"destination_amount" valid:"amount,optional"`
+}
+
+// SourceAmountOrDestinationAmountProblem custom error where source amount or destination amount is required
+var SourceAmountOrDestinationAmountProblem = problem.P{
+	Type:   "bad_request",
+	Title:  "Bad Request",
+	Status: http.StatusBadRequest,
+	Detail: "The request requires either a source amount, for strict send payments, " +
+		"or a destination amount, for strict receive payments. Both fields cannot be present.",
+}
+
+// URITemplate returns a rfc6570 URI template for the query struct
+func (q SplitPathsQuery) URITemplate() string {
+	return getURITemplate(&q, "paths/split", false)
+}
+
+// Validate runs custom validations.
+func (q SplitPathsQuery) Validate() error {
+	if (len(q.SourceAmount) > 0) == (len(q.DestinationAmount) > 0) {
+		return SourceAmountOrDestinationAmountProblem
+	}
+
+	err := validateAssetParams(
+		q.SourceAssetType,
+		q.SourceAssetCode,
+		q.SourceAssetIssuer,
+		"source_",
+	)
+	if err != nil {
+		return err
+	}
+
+	return validateAssetParams(
+		q.DestinationAssetType,
+		q.DestinationAssetCode,
+		q.DestinationAssetIssuer,
+		"destination_",
+	)
+}
+
+// Query returns the paths.SplitQuery described by the query parameters
+func (q SplitPathsQuery) Query() paths.SplitQuery {
+	query := paths.SplitQuery{}
+
+	var err error
+	query.SourceAsset, err = xdr.BuildAsset(q.SourceAssetType, q.SourceAssetIssuer, q.SourceAssetCode)
+	if err != nil {
+		panic(err)
+	}
+	query.DestinationAsset, err = xdr.BuildAsset(q.DestinationAssetType, q.DestinationAssetIssuer, q.DestinationAssetCode)
+	if err != nil {
+		panic(err)
+	}
+
+	if q.SourceAmount != "" {
+		query.SourceAmount = amount.MustParse(q.SourceAmount)
+	} else {
+		query.DestinationAmount = amount.MustParse(q.DestinationAmount)
+	}
+
+	if q.SourceAccount != "" {
+		sourceAccount := xdr.MustAddress(q.SourceAccount)
+		query.SourceAccount = &sourceAccount
+	}
+	return query
+}
+
+// GetResource returns a payment split across several paths
+func (handler FindSplitPathsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := SplitPathsQuery{}
+
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	query := qp.Query()
+	plan, lastIngestedLedger, err := handler.PathFinder.FindSplitPaths(ctx, query, handler.MaxPathLength)
+	if err == simplepath.ErrEmptyInMemoryOrderBook {
+		err = auroraProblem.StillIngesting
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if handler.SetLastLedgerHeader {
+		// To make the Last-Ledger header consistent with the response content,
+		// we need to extract it from the ledger and not the DB.
+		// Thus, we overwrite the header if it was previously set.
+		SetLastLedgerHeader(w, lastIngestedLedger)
+	}
+
+	var res aurora.SplitPaths
+	if err := resourceadapter.PopulateSplitPaths(ctx, &res, query, plan, qp.DestinationAccount); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func assetsForAddress(r *http.Request, addy string) ([]xdr.Asset, []xdr.Int64, error) {
 	historyQ, err := auroraContext.HistoryQFromRequest(r)
 	if err != nil {
@@ -0,0 +1,395 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/diamcircle/go/amount"
+	protocol "github.com/diamcircle/go/protocols/aurora"
+	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ledger"
+	"github.com/diamcircle/go/services/aurora/internal/paths"
+	auroraProblem "github.com/diamcircle/go/services/aurora/internal/render/problem"
+	"github.com/diamcircle/go/services/aurora/internal/resourceadapter"
+	"github.com/diamcircle/go/services/aurora/internal/simplepath"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/diamcircle/go/xdr"
+)
+
+// Price methods supported by the /prices endpoint
+const (
+	// PriceMethodTWAP averages the last trade price at the end of each of
+	// the last ledgers.
+	PriceMethodTWAP = "twap"
+	// PriceMethodMid is the middle of the best bid and ask of the order book,
+	// including the liquidity pool of the pair.
+	PriceMethodMid = "mid"
+	// PriceMethodLast is the price of the last trade.
+	PriceMethodLast = "last"
+)
+
+const (
+	defaultPriceLedgers = 720
+	maxPriceLedgers     = 17280
+)
+
+// AssetPriceQuery query struct for the /prices end-point
+type AssetPriceQuery struct {
+	Method                 string `schema:"method" valid:"-"`
+	Ledgers                uint32 `schema:"ledgers" valid:"-"`
+	AllowRouting           bool   `schema:"allow_routing" valid:"-"`
+	RoutingAmount          string `schema:"routing_amount" valid:"amount,optional"`
+	TradeAssetsQueryParams `valid:"optional"`
+}
+
+// Validate runs validations on AssetPriceQuery
+func (q AssetPriceQuery) Validate() error {
+	base, err := q.Base()
+	if err != nil {
+		return err
+	}
+	if base == nil {
+		return problem.MakeInvalidFieldProblem(
+			"base_asset_type",
+			errors.New("Missing required field"),
+		)
+	}
+	counter, err := q.Counter()
+	if err != nil {
+		return err
+	}
+	if counter == nil {
+		return problem.MakeInvalidFieldProblem(
+			"counter_asset_type",
+			errors.New("Missing required field"),
+		)
+	}
+	if base.Equals(*counter) {
+		return problem.MakeInvalidFieldProblem(
+			"counter_asset_type",
+			errors.New("the counter asset must be different from the base asset"),
+		)
+	}
+
+	switch q.Method {
+	case "", PriceMethodTWAP, PriceMethodMid, PriceMethodLast:
+	default:
+		return problem.MakeInvalidFieldProblem(
+			"method",
+			fmt.Errorf("method must be one of %s, %s or %s", PriceMethodTWAP, PriceMethodMid, PriceMethodLast),
+		)
+	}
+	if q.Ledgers > maxPriceLedgers {
+		return problem.MakeInvalidFieldProblem(
+			"ledgers",
+			fmt.Errorf("ledgers must be at most %d", maxPriceLedgers),
+		)
+	}
+
+	return nil
+}
+
+// GetAssetPriceHandler is the action handler for the /prices end-point. It
+// computes a reference price of the base asset in units of the counter
+// asset. If there is no direct market between the assets and routing is
+// allowed, the price is quoted on the best payment path instead.
+type GetAssetPriceHandler struct {
+	LedgerState   *ledger.State
+	PathFinder    paths.Finder
+	MaxPathLength uint
+}
+
+// GetResource returns the price of an asset pair
+func (handler GetAssetPriceHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := AssetPriceQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	base, _ := qp.Base()
+	counter, _ := qp.Counter()
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := protocol.AssetPrice{
+		Method:        qp.Method,
+		Ledger:        handler.LedgerState.CurrentStatus().HistoryLatest,
+		BaseVolume:    amount.StringFromInt64(0),
+		CounterVolume: amount.StringFromInt64(0),
+	}
+	if response.Method == "" {
+		response.Method = PriceMethodTWAP
+	}
+	if err = resourceadapter.PopulateAsset(ctx, &response.Base, *base); err != nil {
+		return nil, err
+	}
+	if err = resourceadapter.PopulateAsset(ctx, &response.Counter, *counter); err != nil {
+		return nil, err
+	}
+
+	var price *big.Rat
+	switch response.Method {
+	case PriceMethodMid:
+		price, err = midPrice(ctx, historyQ, *base, *counter, &response)
+	default:
+		price, err = tradePrice(ctx, historyQ, *base, *counter, qp.Ledgers, &response)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if price == nil && qp.AllowRouting && handler.PathFinder != nil {
+		price, err = handler.routedPrice(ctx, *base, *counter, qp.RoutingAmount, &response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if price == nil {
+		return nil, problem.NotFound
+	}
+
+	response.Price = price.FloatString(7)
+	return response, nil
+}
+
+// tradePrice computes the last trade price or the time weighted average
+// price of the trades between base and counter, or returns nil if they were
+// never traded. The average is computed from the last trade of each ledger,
+// so at most one trade per ledger is loaded.
+func tradePrice(
+	ctx context.Context,
+	historyQ *history.Q,
+	base, counter xdr.Asset,
+	ledgers uint32,
+	response *protocol.AssetPrice,
+) (*big.Rat, error) {
+	baseID, err := historyQ.GetAssetID(ctx, base)
+	if historyQ.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	counterID, err := historyQ.GetAssetID(ctx, counter)
+	if historyQ.NoRows(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if response.Method == PriceMethodLast {
+		trade, err := historyQ.GetLastPriceTrade(ctx, baseID, counterID, response.Ledger+1)
+		if historyQ.NoRows(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		response.TradeCount = 1
+		response.BaseVolume = amount.StringFromInt64(trade.BaseAmount)
+		response.CounterVolume = amount.StringFromInt64(trade.CounterAmount)
+		response.LastTradeAt = &trade.LedgerCloseTime
+		return tradeRatio(trade), nil
+	}
+
+	if ledgers == 0 {
+		ledgers = defaultPriceLedgers
+	}
+	from := response.Ledger - int32(ledgers) + 1
+	if from < 1 {
+		from = 1
+	}
+
+	var previous *history.PriceTrade
+	trade, err := historyQ.GetLastPriceTrade(ctx, baseID, counterID, from)
+	if err == nil {
+		previous = &trade
+	} else if !historyQ.NoRows(err) {
+		return nil, err
+	}
+	// Only the last trade of each ledger affects the average, the volume of
+	// the other trades is aggregated by the database.
+	trades, err := historyQ.GetLedgerPriceTrades(ctx, baseID, counterID, from)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := historyQ.GetPriceTradeSummary(ctx, baseID, counterID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setPriceTradeSummary(response, summary); err != nil {
+		return nil, err
+	}
+	if summary.LastTradeAt == nil && previous != nil {
+		response.LastTradeAt = &previous.LedgerCloseTime
+	}
+	return timeWeightedPrice(trades, previous, from, response.Ledger), nil
+}
+
+// timeWeightedPrice returns the average of the last trade price at the end of
+// each ledger in [from, to], or nil if there is no trade in or before the
+// range. previous is the last trade before the range, if any, and trades
+// are the trades of the range in ascending order.
+func timeWeightedPrice(trades []history.PriceTrade, previous *history.PriceTrade, from, to int32) *big.Rat {
+	var current *big.Rat
+	if previous != nil {
+		current = tradeRatio(*previous)
+	}
+
+	sum := new(big.Rat)
+	var count int64
+	i := 0
+	for seq := from; seq <= to; seq++ {
+		for ; i < len(trades) && trades[i].LedgerSequence() <= seq; i++ {
+			if price := tradeRatio(trades[i]); price != nil {
+				current = price
+			}
+		}
+		if current != nil {
+			sum.Add(sum, current)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+	return sum.Quo(sum, big.NewRat(count, 1))
+}
+
+// tradeRatio returns the price of a trade in units of counter per unit of
+// base, or nil if no base asset was exchanged.
+func tradeRatio(trade history.PriceTrade) *big.Rat {
+	if trade.BaseAmount == 0 {
+		return nil
+	}
+	return big.NewRat(trade.CounterAmount, trade.BaseAmount)
+}
+
+func setPriceTradeSummary(response *protocol.AssetPrice, summary history.PriceTradeSummary) error {
+	var err error
+	response.TradeCount = summary.TradeCount
+	if response.BaseVolume, err = amount.IntStringToAmount(summary.BaseVolume); err != nil {
+		return errors.Wrap(err, "invalid base volume")
+	}
+	if response.CounterVolume, err = amount.IntStringToAmount(summary.CounterVolume); err != nil {
+		return errors.Wrap(err, "invalid counter volume")
+	}
+	response.LastTradeAt = summary.LastTradeAt
+	return nil
+}
+
+// midPrice returns the middle of the best bid and ask of the order book of
+// base and counter, including the liquidity pool of the pair, or nil if
+// either side is empty.
+func midPrice(
+	ctx context.Context,
+	historyQ *history.Q,
+	base, counter xdr.Asset,
+	response *protocol.AssetPrice,
+) (*big.Rat, error) {
+	summary, err := historyQ.GetOrderBookSummary(ctx, base, counter, 1)
+	if err != nil {
+		return nil, err
+	}
+	asks, err := depthLevelsFromSummary(summary.Asks)
+	if err != nil {
+		return nil, err
+	}
+	bids, err := depthLevelsFromSummary(summary.Bids)
+	if err != nil {
+		return nil, err
+	}
+	poolAsks, poolBids, err := poolDepthLevels(ctx, historyQ, base, counter, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	ask := bestDepthPrice(append(asks, poolAsks...), true)
+	bid := bestDepthPrice(append(bids, poolBids...), false)
+	if ask != nil {
+		response.Ask = ask.FloatString(7)
+	}
+	if bid != nil {
+		response.Bid = bid.FloatString(7)
+	}
+	if ask == nil || bid == nil {
+		return nil, nil
+	}
+
+	mid := new(big.Rat).Add(ask, bid)
+	mid.Quo(mid, big.NewRat(2, 1))
+	if mid.Sign() > 0 {
+		spread := new(big.Rat).Sub(ask, bid)
+		response.Spread = spread.Quo(spread, mid).FloatString(7)
+	}
+	return mid, nil
+}
+
+// bestDepthPrice returns the lowest ask or the highest bid price of the
+// levels, or nil if there is none.
+func bestDepthPrice(levels []depthLevel, asks bool) *big.Rat {
+	var best *big.Rat
+	for _, level := range levels {
+		if best == nil ||
+			(asks && level.price.Cmp(best) < 0) ||
+			(!asks && level.price.Cmp(best) > 0) {
+			best = level.price
+		}
+	}
+	return best
+}
+
+// routedPrice quotes the price on the payment path delivering the most
+// counter for routingAmount of base, 1 unit by default.
+func (handler GetAssetPriceHandler) routedPrice(
+	ctx context.Context,
+	base, counter xdr.Asset,
+	routingAmount string,
+	response *protocol.AssetPrice,
+) (*big.Rat, error) {
+	amountToSpend := xdr.Int64(amount.One)
+	if routingAmount != "" {
+		parsed, err := amount.Parse(routingAmount)
+		if err != nil {
+			return nil, problem.MakeInvalidFieldProblem("routing_amount", err)
+		}
+		amountToSpend = parsed
+	}
+
+	records, _, err := handler.PathFinder.FindFixedPaths(
+		ctx,
+		base,
+		amountToSpend,
+		[]xdr.Asset{counter},
+		handler.MaxPathLength,
+	)
+	if err == simplepath.ErrEmptyInMemoryOrderBook {
+		err = auroraProblem.StillIngesting
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var best *paths.Path
+	for i := range records {
+		if best == nil || records[i].DestinationAmount > best.DestinationAmount {
+			best = &records[i]
+		}
+	}
+	if best == nil || best.SourceAmount == 0 {
+		return nil, nil
+	}
+
+	response.Route = &protocol.Path{}
+	if err = resourceadapter.PopulatePath(ctx, response.Route, *best); err != nil {
+		return nil, err
+	}
+	return big.NewRat(int64(best.DestinationAmount), int64(best.SourceAmount)), nil
+}
@@ -0,0 +1,132 @@
+package actions
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	protocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/paths"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/xdr"
+)
+
+func priceTrade(ledger int32, baseAmount, counterAmount int64) history.PriceTrade {
+	return history.PriceTrade{
+		HistoryOperationID: toid.New(ledger, 1, 1).ToInt64(),
+		BaseAmount:         baseAmount,
+		CounterAmount:      counterAmount,
+	}
+}
+
+func TestAssetPriceQueryValidate(t *testing.T) {
+	valid := AssetPriceQuery{
+		TradeAssetsQueryParams: TradeAssetsQueryParams{
+			BaseAssetType:      "native",
+			CounterAssetType:   "credit_alphanum4",
+			CounterAssetCode:   "USD",
+			CounterAssetIssuer: "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+		},
+	}
+	assert.NoError(t, valid.Validate())
+
+	q := valid
+	q.Method = PriceMethodMid
+	assert.NoError(t, q.Validate())
+
+	q.Method = "median"
+	assert.Error(t, q.Validate())
+
+	q = valid
+	q.Ledgers = maxPriceLedgers + 1
+	assert.Error(t, q.Validate())
+
+	q = valid
+	q.CounterAssetType, q.CounterAssetCode, q.CounterAssetIssuer = "native", "", ""
+	assert.Error(t, q.Validate())
+
+	q = valid
+	q.BaseAssetType = ""
+	assert.Error(t, q.Validate())
+}
+
+func TestTimeWeightedPrice(t *testing.T) {
+	// no trade in or before the range
+	assert.Nil(t, timeWeightedPrice(nil, nil, 10, 20))
+
+	// the price before the range is carried over until the first trade
+	previous := priceTrade(5, 10, 10)
+	trades := []history.PriceTrade{
+		priceTrade(12, 10, 20),
+		priceTrade(12, 10, 30),
+		priceTrade(14, 0, 0),
+	}
+	// ledgers 10 and 11 at 1, 12 to 14 at 3
+	price := timeWeightedPrice(trades, &previous, 10, 14)
+	require.NotNil(t, price)
+	assert.Equal(t, big.NewRat(11, 5).String(), price.String())
+
+	// ledgers without a known price are ignored
+	price = timeWeightedPrice(trades, nil, 10, 14)
+	require.NotNil(t, price)
+	assert.Equal(t, big.NewRat(3, 1).String(), price.String())
+}
+
+func TestBestDepthPrice(t *testing.T) {
+	levels := []depthLevel{
+		{price: big.NewRat(2, 1)},
+		{price: big.NewRat(1, 2)},
+		{price: big.NewRat(3, 1)},
+	}
+	assert.Equal(t, big.NewRat(1, 2).String(), bestDepthPrice(levels, true).String())
+	assert.Equal(t, big.NewRat(3, 1).String(), bestDepthPrice(levels, false).String())
+	assert.Nil(t, bestDepthPrice(nil, true))
+}
+
+func TestRoutedPrice(t *testing.T) {
+	ctx := context.Background()
+	usd := xdr.MustNewCreditAsset("USD", "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB")
+	eur := xdr.MustNewCreditAsset("EUR", "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB")
+	native := xdr.MustNewNativeAsset()
+
+	finder := &paths.MockFinder{}
+	handler := GetAssetPriceHandler{PathFinder: finder, MaxPathLength: 3}
+	finder.On("FindFixedPaths", ctx, native, xdr.Int64(100000000), []xdr.Asset{usd}, uint(3)).
+		Return([]paths.Path{
+			{
+				Source:            "native",
+				SourceAmount:      100000000,
+				Destination:       usd.StringCanonical(),
+				DestinationAmount: 1000000,
+			},
+			{
+				Path:              []string{"credit_alphanum4/EUR/" + eur.GetIssuer()},
+				Source:            "native",
+				SourceAmount:      100000000,
+				Destination:       usd.StringCanonical(),
+				DestinationAmount: 1200000,
+			},
+		}, uint32(10), nil).Once()
+
+	var response protocol.AssetPrice
+	price, err := handler.routedPrice(ctx, native, usd, "10", &response)
+	require.NoError(t, err)
+	assert.Equal(t, "0.0120000", price.FloatString(7))
+	require.NotNil(t, response.Route)
+	assert.Equal(t, "0.1200000", response.Route.DestinationAmount)
+	assert.Equal(t, []protocol.Asset{{Type: "credit_alphanum4", Code: "EUR", Issuer: eur.GetIssuer()}}, response.Route.Path)
+
+	finder.On("FindFixedPaths", ctx, native, xdr.Int64(10000000), []xdr.Asset{usd}, uint(3)).
+		Return([]paths.Path{}, uint32(10), nil).Once()
+	response = protocol.AssetPrice{}
+	price, err = handler.routedPrice(ctx, native, usd, "", &response)
+	require.NoError(t, err)
+	assert.Nil(t, price)
+	assert.Nil(t, response.Route)
+
+	finder.AssertExpectations(t)
+}
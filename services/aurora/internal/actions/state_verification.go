@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ledger"
+	"github.com/diamcircle/go/support/render/hal"
+	"github.com/diamcircle/go/support/render/problem"
+)
+
+// StateVerificationReport is the admin representation of the result of a
+// state verification.
+type StateVerificationReport struct {
+	ID             int64                       `json:"id,string"`
+	PT             string                      `json:"paging_token"`
+	LedgerSequence uint32                      `json:"ledger_sequence"`
+	CreatedAt      time.Time                   `json:"created_at"`
+	Valid          bool                        `json:"valid"`
+	Error          string                      `json:"error,omitempty"`
+	Entries        int64                       `json:"entries"`
+	MismatchCount  int32                       `json:"mismatch_count"`
+	Mismatches     []StateVerificationMismatch `json:"mismatches,omitempty"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (r StateVerificationReport) PagingToken() string {
+	return r.PT
+}
+
+// StateVerificationMismatch is an entry which does not match its version in
+// the history archive state. The key and entries are base64 encoded XDR.
+type StateVerificationMismatch struct {
+	LedgerKey          string `json:"ledger_key"`
+	EntryType          string `json:"entry_type"`
+	Processor          string `json:"processor"`
+	LastModifiedLedger int64  `json:"last_modified_ledger,omitempty"`
+	ExpectedEntry      string `json:"expected_entry_xdr,omitempty"`
+	ActualEntry        string `json:"actual_entry_xdr,omitempty"`
+}
+
+// StateVerificationReportQuery query struct for the
+// state_verification/reports/{id} end-point
+type StateVerificationReportQuery struct {
+	ID uint64 `schema:"id" valid:"-"`
+}
+
+// GetStateVerificationReportsHandler is the action handler for the admin
+// state_verification/reports end-point
+type GetStateVerificationReportsHandler struct {
+	HistoryQ    *history.Q
+	LedgerState *ledger.State
+}
+
+// GetResourcePage returns a page of state verification reports, without
+// their mismatches.
+func (handler GetStateVerificationReportsHandler) GetResourcePage(
+	w HeaderWriter, r *http.Request,
+) ([]hal.Pageable, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := handler.HistoryQ.StateVerificationReports(r.Context(), pq)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []hal.Pageable
+	for _, record := range records {
+		response = append(response, newStateVerificationReport(record))
+	}
+	return response, nil
+}
+
+// GetStateVerificationReportHandler is the action handler for the admin
+// state_verification/reports/{id} end-point
+type GetStateVerificationReportHandler struct {
+	HistoryQ *history.Q
+}
+
+// GetResource returns a state verification report with its mismatches.
+func (handler GetStateVerificationReportHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	qp := StateVerificationReportQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	record, err := handler.HistoryQ.StateVerificationReportByID(r.Context(), int64(qp.ID))
+	if handler.HistoryQ.NoRows(err) {
+		return nil, problem.NotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	mismatches, err := handler.HistoryQ.StateVerificationMismatches(r.Context(), record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newStateVerificationReport(record)
+	for _, mismatch := range mismatches {
+		report.Mismatches = append(report.Mismatches, StateVerificationMismatch{
+			LedgerKey:          mismatch.LedgerKey,
+			EntryType:          mismatch.EntryType.String(),
+			Processor:          mismatch.Processor,
+			LastModifiedLedger: mismatch.LastModifiedLedger.Int64,
+			ExpectedEntry:      mismatch.ExpectedEntry.String,
+			ActualEntry:        mismatch.ActualEntry.String,
+		})
+	}
+	return report, nil
+}
+
+func newStateVerificationReport(record history.StateVerificationReport) StateVerificationReport {
+	return StateVerificationReport{
+		ID:             record.ID,
+		PT:             record.PagingToken(),
+		LedgerSequence: record.LedgerSequence,
+		CreatedAt:      record.CreatedAt,
+		Valid:          record.Valid,
+		Error:          record.Error.String,
+		Entries:        record.Entries,
+		MismatchCount:  record.Mismatches,
+	}
+}
@@ -318,23 +318,21 @@ func (q TradeAggregationsQuery) Validate() error {
 
 	//check if resolution is legal
 	resolutionDuration := gTime.Duration(q.ResolutionFilter) * gTime.Millisecond
-	if history.StrictResolutionFiltering {
-		if _, ok := history.AllowedResolutions[resolutionDuration]; !ok {
-			return problem.MakeInvalidFieldProblem(
-				"resolution",
-				errors.New("illegal or missing resolution. "+
-					"allowed resolutions are: 1 minute (60000), 5 minutes (300000), 15 minutes (900000), 1 hour (3600000), "+
-					"1 day (86400000) and 1 week (604800000)"),
-			)
-		}
+	if !history.ValidResolution(resolutionDuration) {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"the resolution must be a multiple of 1 minute (60000), 5 minutes (300000), 15 minutes (900000), "+
+				"1 hour (3600000), 1 day (86400000) or 1 week (604800000)"),
+		)
 	}
 	// check if offset is legal
 	offsetDuration := gTime.Duration(q.OffsetFilter) * gTime.Millisecond
-	if offsetDuration%gTime.Hour != 0 || offsetDuration >= gTime.Hour*24 || offsetDuration > resolutionDuration {
+	if !history.ValidOffset(offsetDuration, resolutionDuration) {
 		return problem.MakeInvalidFieldProblem(
 			"offset",
-			errors.New("illegal or missing offset. offset must be a multiple of an"+
-				" hour, less than or equal to the resolution, and less than 24 hours"),
+			errors.New("illegal or missing offset. offset must be a multiple of a"+
+				" minute, less than or equal to the resolution, and less than 24 hours"),
 		)
 	}
 
@@ -394,6 +392,47 @@ func (handler GetTradeAggregationsHandler) GetResource(w HeaderWriter, r *http.R
 	return handler.buildPage(r, aggregations)
 }
 
+// GetStreamedResources returns the trade aggregations of the request starting
+// at the bucket with the since timestamp, in ascending order. Streams poll it
+// every ledger so that both new buckets and updates of the latest bucket are
+// sent to the client.
+func (handler GetTradeAggregationsHandler) GetStreamedResources(r *http.Request, since int64) ([]aurora.TradeAggregation, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	pq.Order = db2.OrderAscending
+
+	qp := TradeAggregationsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	if since > qp.StartTimeFilter.ToInt64() {
+		qp.StartTimeFilter = time.MillisFromInt64(since)
+	}
+	if !qp.EndTimeFilter.IsNil() && qp.StartTimeFilter >= qp.EndTimeFilter {
+		return nil, nil
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := handler.fetchRecords(ctx, historyQ, qp, pq)
+	if err != nil {
+		return nil, err
+	}
+	aggregations := make([]aurora.TradeAggregation, len(records))
+	for i, record := range records {
+		if err = resourceadapter.PopulateTradeAggregation(ctx, &aggregations[i], record); err != nil {
+			return nil, err
+		}
+	}
+	return aggregations, nil
+}
+
 func (handler GetTradeAggregationsHandler) fetchRecords(ctx context.Context, historyQ *history.Q, qp TradeAggregationsQuery, pq db2.PageQuery) ([]history.TradeAggregation, error) {
 	baseAsset, err := qp.Base()
 	if err != nil {
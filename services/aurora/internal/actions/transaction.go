@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
 	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
 	"github.com/diamcircle/go/services/aurora/internal/db2"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
@@ -23,6 +24,9 @@ type TransactionQuery struct {
 
 // GetTransactionByHashHandler is the action handler for the end-point returning a transaction.
 type GetTransactionByHashHandler struct {
+	// ColdStorage, if set, is used to load transactions which were reaped
+	// from the database.
+	ColdStorage *coldstorage.Store
 }
 
 // GetResource returns a transaction page.
@@ -45,6 +49,9 @@ func (handler GetTransactionByHashHandler) GetResource(w HeaderWriter, r *http.R
 	)
 
 	err = historyQ.TransactionByHash(ctx, &record, qp.TransactionHash)
+	if historyQ.NoRows(err) && handler.ColdStorage != nil {
+		record, err = handler.coldTransactionByHash(ctx, historyQ, qp.TransactionHash)
+	}
 	if err != nil {
 		return resource, errors.Wrap(err, "loading transaction record")
 	}
@@ -55,6 +62,16 @@ func (handler GetTransactionByHashHandler) GetResource(w HeaderWriter, r *http.R
 	return resource, nil
 }
 
+// coldTransactionByHash loads a reaped transaction from cold storage. It
+// returns sql.ErrNoRows if the transaction was never exported.
+func (handler GetTransactionByHashHandler) coldTransactionByHash(ctx context.Context, historyQ *history.Q, hash string) (history.Transaction, error) {
+	seq, err := historyQ.ColdTransactionLedger(ctx, hash)
+	if err != nil {
+		return history.Transaction{}, err
+	}
+	return handler.ColdStorage.TransactionByHash(ctx, seq, hash)
+}
+
 // TransactionsQuery query struct for transactions end-points
 type TransactionsQuery struct {
 	AccountID                 string `schema:"account_id" valid:"accountID,optional"`
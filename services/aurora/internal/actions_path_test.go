@@ -3,6 +3,7 @@ package aurora
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -48,6 +49,11 @@ func mockPathFindingClient(
 		MaxPathLength:        3,
 		SetLastLedgerHeader:  true,
 	}}
+	findSplitPaths := httpx.ObjectActionHandler{actions.FindSplitPathsHandler{
+		PathFinder:          finder,
+		MaxPathLength:       3,
+		SetLastLedgerHeader: true,
+	}}
 
 	router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -71,6 +77,7 @@ func mockPathFindingClient(
 		router.Method("GET", "/paths", findPaths)
 		router.Method("GET", "/paths/strict-receive", findPaths)
 		router.Method("GET", "/paths/strict-send", findFixedPaths)
+		router.Method("GET", "/paths/split", findSplitPaths)
 	})
 
 	return test.NewRequestHelper(router)
@@ -673,3 +680,90 @@ func TestStrictReceivePathsQueryURLTemplate(t *testing.T) {
 	qp := actions.StrictReceivePathsQuery{}
 	tt.Equal(expected, qp.URITemplate())
 }
+
+func TestSplitPathActions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	usd := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	eur := xdr.MustNewCreditAsset("EUR", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	query := paths.SplitQuery{
+		SourceAsset:      usd,
+		SourceAmount:     xdr.Int64(100000000),
+		DestinationAsset: xdr.MustNewNativeAsset(),
+	}
+	plan := paths.SplitPlan{
+		Paths: []paths.Path{
+			{
+				Path:              []string{},
+				Source:            usd.String(),
+				SourceAmount:      xdr.Int64(70000000),
+				Destination:       "native",
+				DestinationAmount: xdr.Int64(140000000),
+			},
+			{
+				Path:              []string{eur.String()},
+				Source:            usd.String(),
+				SourceAmount:      xdr.Int64(30000000),
+				Destination:       "native",
+				DestinationAmount: xdr.Int64(50000000),
+			},
+		},
+		SourceAmount:      xdr.Int64(100000000),
+		DestinationAmount: xdr.Int64(190000000),
+	}
+
+	finder := paths.MockFinder{}
+	finder.On("FindSplitPaths", mock.Anything, query, uint(3)).
+		Return(plan, uint32(1234), nil).Once()
+	finder.On("FindSplitPaths", mock.Anything, mock.Anything, uint(3)).
+		Return(paths.SplitPlan{}, uint32(0), simplepath.ErrEmptyInMemoryOrderBook).Once()
+
+	rh := mockPathFindingClient(
+		tt,
+		&finder,
+		2,
+		tt.AuroraSession(),
+	)
+
+	q := make(url.Values)
+	q.Add("source_asset_type", "credit_alphanum4")
+	q.Add("source_asset_code", "USD")
+	q.Add("source_asset_issuer", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	q.Add("destination_asset_type", "native")
+	q.Add("destination_account", "GAEDTJ4PPEFVW5XV2S7LUXBEHNQMX5Q2GM562RJGOQG7GVCE5H3HIB4V")
+	q.Add("source_amount", "10")
+
+	w := rh.Get("/paths/split?" + q.Encode())
+	tt.Assert.Equal(http.StatusOK, w.Code)
+	tt.Assert.Equal("1234", w.Header().Get(actions.LastLedgerHeaderName))
+
+	var response aurora.SplitPaths
+	tt.Assert.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+	tt.Assert.Equal("10.0000000", response.SourceAmount)
+	tt.Assert.Equal("19.0000000", response.DestinationAmount)
+	tt.Assert.Equal("native", response.DestinationAssetType)
+	tt.Assert.Len(response.Paths, 2)
+	tt.Assert.Equal("7.0000000", response.Paths[0].SourceAmount)
+	tt.Assert.Equal("EUR", response.Paths[1].Path[0].Code)
+	tt.Assert.Len(response.Operations, 2)
+
+	var op xdr.Operation
+	tt.Assert.NoError(xdr.SafeUnmarshalBase64(response.Operations[1], &op))
+	pathPayment := op.Body.MustPathPaymentStrictSendOp()
+	tt.Assert.Equal(xdr.Int64(30000000), pathPayment.SendAmount)
+	tt.Assert.Equal(xdr.Int64(50000000), pathPayment.DestMin)
+	tt.Assert.Equal([]xdr.Asset{eur}, pathPayment.Path)
+	tt.Assert.Equal("GAEDTJ4PPEFVW5XV2S7LUXBEHNQMX5Q2GM562RJGOQG7GVCE5H3HIB4V", pathPayment.Destination.Address())
+
+	w = rh.Get("/paths/split?" + q.Encode())
+	tt.Assert.Equal(auroraProblem.StillIngesting.Status, w.Code)
+
+	// both amounts
+	q.Add("destination_amount", "10")
+	w = rh.Get("/paths/split?" + q.Encode())
+	tt.Assert.Equal(http.StatusBadRequest, w.Code)
+
+	finder.AssertExpectations(t)
+}
@@ -398,7 +398,7 @@ func TestTradeActions_Aggregation(t *testing.T) {
 
 	//test illegal resolution
 	if history.StrictResolutionFiltering {
-		q.Add("resolution", strconv.FormatInt(hour/2, 10))
+		q.Add("resolution", strconv.FormatInt(minute/2, 10))
 		w = ht.GetWithParams(aggregationPath, q)
 		ht.Assert.Equal(400, w.Code)
 	}
@@ -677,7 +677,7 @@ func TestTradeActions_AggregationInvalidOffset(t *testing.T) {
 		startTime  int64
 		endTime    int64
 	}{
-		{offset: minute, resolution: hour},                                            // Test invalid offset value that's not hour aligned
+		{offset: minute / 2, resolution: hour},                                        // Test invalid offset value that's not minute aligned
 		{offset: 25 * hour, resolution: week},                                         // Test invalid offset value that's greater than 24 hours
 		{offset: 3 * hour, resolution: hour},                                          // Test invalid offset value that's greater than the resolution
 		{offset: 3 * hour, startTime: 28 * hour, endTime: 26 * hour, resolution: day}, // Test invalid end time that's less than the start time
@@ -757,6 +757,60 @@ func TestTradeActions_AggregationOffset(t *testing.T) {
 	}
 }
 
+func TestTradeActions_AggregationMultipleResolutions(t *testing.T) {
+	ht := StartHTTPTestWithoutScenario(t)
+	defer ht.Finish()
+
+	dbQ := &history.Q{ht.AuroraSession()}
+	// One trade every 10 minutes
+	ass1, ass2, err := PopulateTestTrades(dbQ, day, 100, 10*minute, 1)
+	ht.Require.NoError(err)
+
+	q := make(url.Values)
+	setAssetQuery(&q, "base_", ass1)
+	setAssetQuery(&q, "counter_", ass2)
+	q.Add("order", "asc")
+
+	// 2 hours is not one of the allowed resolutions but a multiple of them
+	q.Set("resolution", strconv.FormatInt(2*hour, 10))
+	w := ht.GetWithParams(aggregationPath, q)
+	if ht.Assert.Equal(200, w.Code) {
+		ht.Assert.PageOf(9, w.Body)
+		var records []aurora.TradeAggregation
+		ht.UnmarshalPage(w.Body, &records)
+		ht.Assert.Equal(int64(12), records[0].TradeCount)
+		ht.Assert.Equal(day+8*2*hour, records[8].Timestamp)
+		ht.Assert.Equal(int64(4), records[8].TradeCount)
+	}
+
+	// offsets no longer need to be whole hours
+	q.Set("offset", strconv.FormatInt(30*minute, 10))
+	w = ht.GetWithParams(aggregationPath, q)
+	if ht.Assert.Equal(200, w.Code) {
+		ht.Assert.PageOf(10, w.Body)
+		var records []aurora.TradeAggregation
+		ht.UnmarshalPage(w.Body, &records)
+		ht.Assert.Equal(day-90*minute, records[0].Timestamp)
+		ht.Assert.Equal(int64(3), records[0].TradeCount)
+	}
+
+	// a resolution of 2 days is aggregated from the daily rollup table
+	q.Del("offset")
+	q.Set("resolution", strconv.FormatInt(2*day, 10))
+	w = ht.GetWithParams(aggregationPath, q)
+	if ht.Assert.Equal(200, w.Code) {
+		ht.Assert.PageOf(1, w.Body)
+		var records []aurora.TradeAggregation
+		ht.UnmarshalPage(w.Body, &records)
+		ht.Assert.Equal(int64(100), records[0].TradeCount)
+		ht.Assert.Equal("0.0505000", records[0].BaseVolume)
+		ht.Assert.Equal("0.0505000", records[0].OrderbookBaseVolume)
+		ht.Assert.Equal("0.0000000", records[0].LiquidityPoolBaseVolume)
+		ht.Assert.Equal("1.0000000", records[0].Open)
+		ht.Assert.Equal("100.0000000", records[0].Close)
+	}
+}
+
 //GetTestAsset generates an issuer on the fly and creates a CreditAlphanum4 Asset with given code
 func GetTestAsset(code string) xdr.Asset {
 	var codeBytes [4]byte
@@ -14,8 +14,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/diamcircle/go/clients/diamcirclecore"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
 	"github.com/diamcircle/go/services/aurora/internal/corestate"
+	"github.com/diamcircle/go/services/aurora/internal/db2/embedded"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/db2/replicas"
 	"github.com/diamcircle/go/services/aurora/internal/httpx"
 	"github.com/diamcircle/go/services/aurora/internal/ingest"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
@@ -38,6 +41,7 @@ type App struct {
 	webServer       *httpx.Server
 	historyQ        *history.Q
 	primaryHistoryQ *history.Q
+	replicas        *replicas.Pool
 	ctx             context.Context
 	cancel          func()
 	auroraVersion  string
@@ -47,9 +51,15 @@ type App struct {
 	paths           paths.Finder
 	ingester        ingest.System
 	reaper          *reap.System
+	coldStorage     *coldstorage.Store
 	ticks           *time.Ticker
 	ledgerState     *ledger.State
 
+	// embeddedStore and embeddedIngester replace the database and the
+	// ingestion system when the embedded storage driver is enabled.
+	embeddedStore    *embedded.Store
+	embeddedIngester *ingest.EmbeddedSystem
+
 	// metrics
 	prometheusRegistry *prometheus.Registry
 	buildInfoGauge     *prometheus.GaugeVec
@@ -90,7 +100,9 @@ func (a *App) Serve() error {
 	}
 
 	go a.run()
-	go a.orderBookStream.Run(a.ctx)
+	if a.orderBookStream != nil {
+		go a.orderBookStream.Run(a.ctx)
+	}
 
 	// WaitGroup for all go routines. Makes sure that DB is closed when
 	// all services gracefully shutdown.
@@ -104,6 +116,14 @@ func (a *App) Serve() error {
 		}()
 	}
 
+	if a.embeddedIngester != nil {
+		wg.Add(1)
+		go func() {
+			a.embeddedIngester.Run()
+			wg.Done()
+		}()
+	}
+
 	if a.reaper != nil {
 		wg.Add(1)
 		go func() {
@@ -175,6 +195,9 @@ func (a *App) waitForDone() {
 	if a.ingester != nil {
 		a.ingester.Shutdown()
 	}
+	if a.embeddedIngester != nil {
+		a.embeddedIngester.Shutdown()
+	}
 	if a.reaper != nil {
 		a.reaper.Shutdown()
 	}
@@ -185,6 +208,9 @@ func (a *App) waitForDone() {
 // sure all requests are first properly finished to avoid "sql: database is
 // closed" errors.
 func (a *App) CloseDB() {
+	if a.historyQ == nil {
+		return
+	}
 	a.historyQ.SessionInterface.Close()
 }
 
@@ -243,21 +269,37 @@ func (a *App) UpdateAuroraLedgerState(ctx context.Context) {
 		log.WithStack(err).WithField("err", err.Error()).Error(msg)
 	}
 
+	if a.embeddedStore != nil {
+		if err := a.loadEmbeddedLedgerState(ctx, &next); err != nil {
+			logErr(err, "failed to load the latest known ledger state from the embedded store")
+			return
+		}
+		a.ledgerState.SetAuroraStatus(next)
+		return
+	}
+
+	// Requests fall back to the primary database when the read replicas
+	// lag behind, so the ledger state describes the primary database.
+	q := a.HistoryQ()
+	if a.primaryHistoryQ != nil {
+		q = a.primaryHistoryQ
+	}
+
 	var err error
 	next.HistoryLatest, next.HistoryLatestClosedAt, err =
-		a.HistoryQ().LatestLedgerSequenceClosedAt(ctx)
+		q.LatestLedgerSequenceClosedAt(ctx)
 	if err != nil {
 		logErr(err, "failed to load the latest known ledger state from history DB")
 		return
 	}
 
-	err = a.HistoryQ().ElderLedger(ctx, &next.HistoryElder)
+	err = q.ElderLedger(ctx, &next.HistoryElder)
 	if err != nil {
 		logErr(err, "failed to load the oldest known ledger state from history DB")
 		return
 	}
 
-	next.ExpHistoryLatest, err = a.HistoryQ().GetLastLedgerIngestNonBlocking(ctx)
+	next.ExpHistoryLatest, err = q.GetLastLedgerIngestNonBlocking(ctx)
 	if err != nil {
 		logErr(err, "failed to load the oldest known exp ledger state from history DB")
 		return
@@ -266,6 +308,29 @@ func (a *App) UpdateAuroraLedgerState(ctx context.Context) {
 	a.ledgerState.SetAuroraStatus(next)
 }
 
+// loadEmbeddedLedgerState loads the ledger state of the embedded store, which
+// keeps the headers of the last embedded.LedgerRetentionCount ledgers.
+func (a *App) loadEmbeddedLedgerState(ctx context.Context, next *ledger.AuroraStatus) error {
+	lastLedger, err := a.embeddedStore.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil || lastLedger == 0 {
+		return err
+	}
+
+	var header history.Ledger
+	if err = a.embeddedStore.LedgerBySequence(ctx, &header, int32(lastLedger)); err != nil {
+		return err
+	}
+
+	next.HistoryLatest = int32(lastLedger)
+	next.HistoryLatestClosedAt = header.ClosedAt
+	next.HistoryElder = 1
+	if lastLedger > embedded.LedgerRetentionCount {
+		next.HistoryElder = int32(lastLedger) - embedded.LedgerRetentionCount + 1
+	}
+	next.ExpHistoryLatest = lastLedger
+	return nil
+}
+
 // UpdateFeeStatsState triggers a refresh of several operation fee metrics.
 func (a *App) UpdateFeeStatsState(ctx context.Context) {
 	var (
@@ -433,21 +498,33 @@ func (a *App) Tick(ctx context.Context) error {
 	var wg sync.WaitGroup
 	log.Debug("ticking app")
 
-	// update ledger state, operation fee state, and diamcircle-core info in parallel
-	wg.Add(4)
+	// update ledger state, operation fee state, diamcircle-core info and read
+	// replica state in parallel
+	wg.Add(3)
 	var err error
+	if a.replicas != nil {
+		wg.Add(1)
+		go func() { a.replicas.Update(ctx); wg.Done() }()
+	}
+	// fee stats are computed from the history tables, which the embedded
+	// store does not have
+	if a.embeddedStore == nil {
+		wg.Add(1)
+		go func() { a.UpdateFeeStatsState(ctx); wg.Done() }()
+	}
 	go func() { a.UpdateCoreLedgerState(ctx); wg.Done() }()
 	go func() { a.UpdateAuroraLedgerState(ctx); wg.Done() }()
-	go func() { a.UpdateFeeStatsState(ctx); wg.Done() }()
 	go func() { err = a.UpdateDiamcircleCoreInfo(ctx); wg.Done() }()
 	wg.Wait()
 	if err != nil {
 		return err
 	}
 
-	wg.Add(1)
-	go func() { a.submitter.Tick(ctx); wg.Done() }()
-	wg.Wait()
+	if a.submitter != nil {
+		wg.Add(1)
+		go func() { a.submitter.Tick(ctx); wg.Done() }()
+		wg.Wait()
+	}
 
 	log.Debug("finished ticking app")
 	return ctx.Err()
@@ -478,6 +555,10 @@ func (a *App) init() error {
 	// diamcircleCoreInfo
 	a.UpdateDiamcircleCoreInfo(a.ctx)
 
+	if a.config.EmbeddedDataDir != "" {
+		return a.initEmbedded()
+	}
+
 	// aurora-db and core-db
 	mustInitAuroraDB(a)
 
@@ -490,8 +571,12 @@ func (a *App) init() error {
 	// txsub
 	initSubmissionSystem(a)
 
+	// history cold storage
+	initColdStorage(a)
+
 	// reaper
 	a.reaper = reap.New(a.config.HistoryRetentionCount, a.AuroraSession(), a.ledgerState)
+	a.reaper.ColdStorage = a.coldStorage
 
 	// go metrics
 	initGoMetrics(a)
@@ -525,6 +610,7 @@ func (a *App) init() error {
 		CoreGetter:              a,
 		AuroraVersion:          a.auroraVersion,
 		FriendbotURL:            a.config.FriendbotURL,
+		ColdStorage:             a.coldStorage,
 		HealthCheck: healthCheck{
 			session: a.historyQ.SessionInterface,
 			ctx:     a.ctx,
@@ -538,6 +624,73 @@ func (a *App) init() error {
 
 	if a.primaryHistoryQ != nil {
 		routerConfig.PrimaryDBSession = a.primaryHistoryQ.SessionInterface
+		routerConfig.Replicas = a.replicas
+	}
+
+	if a.ingester != nil {
+		routerConfig.IngestLeader = a.ingester.Leader()
+		routerConfig.CaptiveCore = a.ingester.CaptiveCore()
+	}
+
+	var err error
+	config := httpx.ServerConfig{
+		Port:      uint16(a.config.Port),
+		AdminPort: uint16(a.config.AdminPort),
+	}
+	if a.config.TLSCert != "" && a.config.TLSKey != "" {
+		config.TLSConfig = &httpx.TLSConfig{
+			CertPath: a.config.TLSCert,
+			KeyPath:  a.config.TLSKey,
+		}
+	}
+	a.webServer, err = httpx.NewServer(config, routerConfig, a.ledgerState)
+	if err != nil {
+		return err
+	}
+
+	// web.metrics
+	initWebMetrics(a)
+
+	return nil
+}
+
+// initEmbedded initializes a single-node app using the embedded storage
+// driver. Only the state endpoints are served: there is no database, reaper,
+// path finder or transaction submission.
+func (a *App) initEmbedded() error {
+	// embedded store and ingester
+	initEmbeddedIngester(a)
+
+	// go metrics
+	initGoMetrics(a)
+
+	// process metrics
+	initProcessMetrics(a)
+
+	// build and ledger state metrics
+	initDbMetrics(a)
+	a.ingestingGauge.Inc()
+
+	routerConfig := httpx.RouterConfig{
+		EmbeddedStore:         a.embeddedStore,
+		RateQuota:             a.config.RateQuota,
+		BehindCloudflare:      a.config.BehindCloudflare,
+		BehindAWSLoadBalancer: a.config.BehindAWSLoadBalancer,
+		SSEUpdateFrequency:    a.config.SSEUpdateFrequency,
+		ConnectionTimeout:     a.config.ConnectionTimeout,
+		NetworkPassphrase:     a.config.NetworkPassphrase,
+		PrometheusRegistry:    a.prometheusRegistry,
+		CoreGetter:            a,
+		AuroraVersion:         a.auroraVersion,
+		FriendbotURL:          a.config.FriendbotURL,
+		HealthCheck: healthCheck{
+			ctx: a.ctx,
+			core: &diamcirclecore.Client{
+				HTTP: &http.Client{Timeout: infoRequestTimeout},
+				URL:  a.config.DiamcircleCoreURL,
+			},
+			cache: newHealthCache(healthCacheTTL),
+		},
 	}
 
 	var err error
@@ -0,0 +1,289 @@
+// Package coldstorage moves reaped aurora history to compressed, ledger
+// partitioned files on a local or object store and serves it back through a
+// read-only path.
+//
+// Each partition holds the ledgers, transactions, operations and operation
+// participants of PartitionSize consecutive ledgers in a single gzipped JSON
+// file. The hot database keeps two small indexes, from transaction hashes to
+// ledgers and from accounts to the partitions they participated in, so that a
+// cold read fetches only the partitions it needs. Reading a partition means
+// downloading and decoding the whole file, so cold reads are expected to be
+// one to two orders of magnitude slower than reads from the database.
+package coldstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+)
+
+// DefaultPartitionSize is the default number of ledgers stored in a
+// partition. It is aligned with history archive checkpoints.
+const DefaultPartitionSize = 64
+
+// indexBatchSize is the batch size used to insert the cold storage indexes.
+const indexBatchSize = 1000
+
+// Partition is the history of a range of ledgers stored in a cold storage
+// file. Ledgers, transactions and operations are sorted in ascending order.
+type Partition struct {
+	Ledgers      []history.Ledger      `json:"ledgers"`
+	Transactions []history.Transaction `json:"transactions"`
+	Operations   []history.Operation   `json:"operations"`
+	// Participants maps operation ids to the addresses of the accounts
+	// participating in the operation.
+	Participants map[int64][]string `json:"participants"`
+}
+
+// Store reads and writes history partitions on an archive backend.
+type Store struct {
+	backend       historyarchive.ArchiveBackend
+	partitionSize int32
+}
+
+// NewStore returns a Store writing partitions of partitionSize ledgers to
+// backend. A partitionSize of 0 selects DefaultPartitionSize.
+func NewStore(backend historyarchive.ArchiveBackend, partitionSize uint32) *Store {
+	if partitionSize == 0 {
+		partitionSize = DefaultPartitionSize
+	}
+	return &Store{
+		backend:       backend,
+		partitionSize: int32(partitionSize),
+	}
+}
+
+// Connect returns a Store on the local or object store at the given URL. It
+// supports the URL schemes of historyarchive.ConnectBackend.
+func Connect(u string, opts historyarchive.ConnectOptions, partitionSize uint32) (*Store, error) {
+	backend, err := historyarchive.ConnectBackend(u, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to cold storage")
+	}
+	return NewStore(backend, partitionSize), nil
+}
+
+// PartitionOf returns the partition the ledger with the given sequence is
+// stored in.
+func (s *Store) PartitionOf(seq int32) int32 {
+	return seq / s.partitionSize
+}
+
+// partitionRange returns the first and last ledger of a partition.
+func (s *Store) partitionRange(partition int32) (int32, int32) {
+	start := partition * s.partitionSize
+	return start, start + s.partitionSize - 1
+}
+
+func partitionPath(partition int32) string {
+	return fmt.Sprintf("history/%06d/%010d.json.gz", partition/1000, partition)
+}
+
+// GetPartition downloads and decodes a partition. It returns sql.ErrNoRows if
+// the partition does not exist.
+func (s *Store) GetPartition(partition int32) (*Partition, error) {
+	pth := partitionPath(partition)
+	exists, err := s.backend.Exists(pth)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not check %s", pth)
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+
+	rdr, err := s.backend.GetFile(pth)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get %s", pth)
+	}
+	defer rdr.Close()
+	gz, err := gzip.NewReader(rdr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decompress %s", pth)
+	}
+	defer gz.Close()
+
+	var p Partition
+	if err = json.NewDecoder(gz).Decode(&p); err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", pth)
+	}
+	return &p, nil
+}
+
+// PutPartition encodes and uploads a partition, replacing the existing file.
+func (s *Store) PutPartition(partition int32, p *Partition) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(p); err != nil {
+		return errors.Wrap(err, "could not encode partition")
+	}
+	if err := gz.Close(); err != nil {
+		return errors.Wrap(err, "could not compress partition")
+	}
+
+	pth := partitionPath(partition)
+	if err := s.backend.PutFile(pth, ioutil.NopCloser(&buf)); err != nil {
+		return errors.Wrapf(err, "could not put %s", pth)
+	}
+	return nil
+}
+
+// Export copies the history of the ledgers between startSeq and endSeq
+// inclusive from the database to cold storage and indexes it in the
+// database. Partitions which were already partially exported are merged, so
+// exporting the same range twice is harmless. Export must run in the same
+// transaction which deletes the exported range from the database.
+func (s *Store) Export(ctx context.Context, q *history.Q, startSeq, endSeq int32) error {
+	for partition := s.PartitionOf(startSeq); partition <= s.PartitionOf(endSeq); partition++ {
+		from, to := s.partitionRange(partition)
+		if from < startSeq {
+			from = startSeq
+		}
+		if to > endSeq {
+			to = endSeq
+		}
+
+		loaded, err := loadPartition(ctx, q, from, to)
+		if err != nil {
+			return errors.Wrapf(err, "could not load ledgers %d to %d", from, to)
+		}
+		if len(loaded.Ledgers) == 0 {
+			continue
+		}
+
+		existing, err := s.GetPartition(partition)
+		if err == sql.ErrNoRows {
+			existing = &Partition{}
+		} else if err != nil {
+			return err
+		}
+		if err = s.PutPartition(partition, mergePartition(existing, loaded)); err != nil {
+			return err
+		}
+
+		if err = s.index(ctx, q, partition, loaded); err != nil {
+			return errors.Wrapf(err, "could not index partition %d", partition)
+		}
+	}
+	return nil
+}
+
+func loadPartition(ctx context.Context, q *history.Q, from, to int32) (*Partition, error) {
+	start, end, err := toid.LedgerRangeInclusive(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Partition{Participants: map[int64][]string{}}
+	if p.Ledgers, err = q.LedgersInRange(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if p.Transactions, err = q.TransactionsInRange(ctx, start, end); err != nil {
+		return nil, err
+	}
+	if p.Operations, err = q.OperationsInRange(ctx, start, end); err != nil {
+		return nil, err
+	}
+	participants, err := q.OperationParticipantsInRange(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, participant := range participants {
+		p.Participants[participant.OperationID] = append(p.Participants[participant.OperationID], participant.Address)
+	}
+	return p, nil
+}
+
+// mergePartition replaces the ledgers of existing which were loaded again
+// with the ones of loaded. Ledgers missing from loaded, for instance because
+// they were already deleted from the database, are kept.
+func mergePartition(existing, loaded *Partition) *Partition {
+	reloaded := map[int32]bool{}
+	for _, ledger := range loaded.Ledgers {
+		reloaded[ledger.Sequence] = true
+	}
+
+	merged := &Partition{Participants: map[int64][]string{}}
+	for _, ledger := range existing.Ledgers {
+		if !reloaded[ledger.Sequence] {
+			merged.Ledgers = append(merged.Ledgers, ledger)
+		}
+	}
+	for _, transaction := range existing.Transactions {
+		if !reloaded[transaction.LedgerSequence] {
+			merged.Transactions = append(merged.Transactions, transaction)
+		}
+	}
+	for _, operation := range existing.Operations {
+		if !reloaded[operation.LedgerSequence()] {
+			merged.Operations = append(merged.Operations, operation)
+		}
+	}
+	for id, addresses := range existing.Participants {
+		if !reloaded[toid.Parse(id).LedgerSequence] {
+			merged.Participants[id] = addresses
+		}
+	}
+
+	merged.Ledgers = append(merged.Ledgers, loaded.Ledgers...)
+	merged.Transactions = append(merged.Transactions, loaded.Transactions...)
+	merged.Operations = append(merged.Operations, loaded.Operations...)
+	for id, addresses := range loaded.Participants {
+		merged.Participants[id] = addresses
+	}
+
+	sort.Slice(merged.Ledgers, func(i, j int) bool {
+		return merged.Ledgers[i].Sequence < merged.Ledgers[j].Sequence
+	})
+	sort.Slice(merged.Transactions, func(i, j int) bool {
+		return merged.Transactions[i].ID < merged.Transactions[j].ID
+	})
+	sort.Slice(merged.Operations, func(i, j int) bool {
+		return merged.Operations[i].ID < merged.Operations[j].ID
+	})
+	return merged
+}
+
+func (s *Store) index(ctx context.Context, q *history.Q, partition int32, p *Partition) error {
+	var transactions []history.ColdTransaction
+	for _, transaction := range p.Transactions {
+		transactions = append(transactions, history.ColdTransaction{
+			TransactionHash: transaction.TransactionHash,
+			LedgerSequence:  transaction.LedgerSequence,
+		})
+		if transaction.InnerTransactionHash.Valid {
+			transactions = append(transactions, history.ColdTransaction{
+				TransactionHash: transaction.InnerTransactionHash.String,
+				LedgerSequence:  transaction.LedgerSequence,
+			})
+		}
+	}
+	if err := q.InsertColdTransactions(ctx, transactions, indexBatchSize); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	var accounts []history.ColdAccountPartition
+	for _, addresses := range p.Participants {
+		for _, address := range addresses {
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+			accounts = append(accounts, history.ColdAccountPartition{
+				Address:   address,
+				Partition: partition,
+			})
+		}
+	}
+	return q.InsertColdAccountPartitions(ctx, accounts, indexBatchSize)
+}
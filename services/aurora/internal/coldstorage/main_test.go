@@ -0,0 +1,148 @@
+package coldstorage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	alice = "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"
+	bob   = "GBXGQJWVLWOYHFLVTKWV5FGHA3LNYY2JQKM7OAJAUEQFU6LPCSEFVXON"
+)
+
+func newTestStore(t *testing.T) *Store {
+	backend, err := historyarchive.ConnectBackend("mock://test", historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+	return NewStore(backend, 0)
+}
+
+func testPartition(seqs ...int32) *Partition {
+	p := &Partition{Participants: map[int64][]string{}}
+	for _, seq := range seqs {
+		txID := toid.New(seq, 1, 0).ToInt64()
+		p.Ledgers = append(p.Ledgers, history.Ledger{Sequence: seq})
+		p.Transactions = append(p.Transactions, history.Transaction{
+			TransactionWithoutLedger: history.TransactionWithoutLedger{
+				TotalOrderID:   history.TotalOrderID{ID: txID},
+				LedgerSequence: seq,
+			},
+		})
+		payment := history.Operation{
+			TotalOrderID:          history.TotalOrderID{ID: toid.New(seq, 1, 1).ToInt64()},
+			TransactionID:         txID,
+			Type:                  xdr.OperationTypePayment,
+			TransactionSuccessful: true,
+		}
+		offer := history.Operation{
+			TotalOrderID:          history.TotalOrderID{ID: toid.New(seq, 1, 2).ToInt64()},
+			TransactionID:         txID,
+			Type:                  xdr.OperationTypeManageSellOffer,
+			TransactionSuccessful: true,
+		}
+		p.Operations = append(p.Operations, payment, offer)
+		p.Participants[payment.ID] = []string{alice, bob}
+		p.Participants[offer.ID] = []string{alice}
+	}
+	return p
+}
+
+func TestPartitionRoundTrip(t *testing.T) {
+	store := newTestStore(t)
+	assert.Equal(t, int32(1), store.PartitionOf(64))
+	assert.Equal(t, int32(0), store.PartitionOf(63))
+
+	_, err := store.GetPartition(1)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	p := testPartition(64, 65)
+	p.Transactions[1].TransactionHash = "outer"
+	p.Transactions[1].InnerTransactionHash = null.StringFrom("inner")
+	require.NoError(t, store.PutPartition(1, p))
+
+	ctx := context.Background()
+	ledger, err := store.LedgerBySequence(ctx, 65)
+	require.NoError(t, err)
+	assert.Equal(t, int32(65), ledger.Sequence)
+	_, err = store.LedgerBySequence(ctx, 66)
+	assert.Equal(t, sql.ErrNoRows, err)
+	_, err = store.LedgerBySequence(ctx, 200)
+	assert.Equal(t, sql.ErrNoRows, err)
+
+	transaction, err := store.TransactionByHash(ctx, 65, "inner")
+	require.NoError(t, err)
+	assert.Equal(t, "outer", transaction.TransactionHash)
+	_, err = store.TransactionByHash(ctx, 65, "other")
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestMergePartition(t *testing.T) {
+	existing := testPartition(64, 65, 66)
+	existing.Ledgers[1].LedgerHash = "old"
+	loaded := testPartition(65, 67)
+	loaded.Ledgers[0].LedgerHash = "new"
+
+	merged := mergePartition(existing, loaded)
+	var seqs []int32
+	for _, ledger := range merged.Ledgers {
+		seqs = append(seqs, ledger.Sequence)
+	}
+	assert.Equal(t, []int32{64, 65, 66, 67}, seqs)
+	assert.Equal(t, "new", merged.Ledgers[1].LedgerHash)
+	assert.Len(t, merged.Transactions, 4)
+	assert.Len(t, merged.Operations, 8)
+	assert.Len(t, merged.Participants, 8)
+	for i := 1; i < len(merged.Operations); i++ {
+		assert.True(t, merged.Operations[i-1].ID < merged.Operations[i].ID)
+	}
+}
+
+func TestAppendAccountOperations(t *testing.T) {
+	p := testPartition(64, 65)
+	p.Operations[3].TransactionSuccessful = false
+
+	result := Operations{Ledgers: map[int32]history.Ledger{}}
+	query := OperationsQuery{
+		Account:             alice,
+		IncludeTransactions: true,
+		Page:                db2.PageQuery{Order: db2.OrderAscending, Limit: 10},
+	}
+	require.NoError(t, p.appendAccountOperations(&result, query, 0))
+	assert.Equal(t, []int64{p.Operations[0].ID, p.Operations[1].ID, p.Operations[2].ID}, operationIDs(result))
+	assert.Len(t, result.Transactions, 3)
+	assert.Len(t, result.Ledgers, 2)
+
+	result = Operations{Ledgers: map[int32]history.Ledger{}}
+	query.IncludeFailed = true
+	query.Page = db2.PageQuery{Order: db2.OrderDescending, Limit: 2}
+	require.NoError(t, p.appendAccountOperations(&result, query, p.Operations[3].ID))
+	assert.Equal(t, []int64{p.Operations[2].ID, p.Operations[1].ID}, operationIDs(result))
+
+	result = Operations{Ledgers: map[int32]history.Ledger{}}
+	query = OperationsQuery{
+		Account:      bob,
+		OnlyPayments: true,
+		Page:         db2.PageQuery{Order: db2.OrderAscending, Limit: 10},
+	}
+	require.NoError(t, p.appendAccountOperations(&result, query, 0))
+	assert.Equal(t, []int64{p.Operations[0].ID, p.Operations[2].ID}, operationIDs(result))
+	assert.Empty(t, result.Transactions)
+}
+
+func operationIDs(result Operations) []int64 {
+	var ids []int64
+	for _, operation := range result.Operations {
+		ids = append(ids, operation.ID)
+	}
+	return ids
+}
@@ -0,0 +1,181 @@
+package coldstorage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+)
+
+// LedgerBySequence loads a ledger from cold storage. It returns
+// sql.ErrNoRows if the ledger was not exported.
+func (s *Store) LedgerBySequence(ctx context.Context, seq int32) (history.Ledger, error) {
+	p, err := s.GetPartition(s.PartitionOf(seq))
+	if err != nil {
+		return history.Ledger{}, err
+	}
+	for _, ledger := range p.Ledgers {
+		if ledger.Sequence == seq {
+			return ledger, nil
+		}
+	}
+	return history.Ledger{}, sql.ErrNoRows
+}
+
+// TransactionByHash loads a transaction included in the ledger with the
+// given sequence from cold storage, matching either its hash or the hash of
+// its inner transaction. It returns sql.ErrNoRows if the transaction was not
+// exported.
+func (s *Store) TransactionByHash(ctx context.Context, seq int32, hash string) (history.Transaction, error) {
+	p, err := s.GetPartition(s.PartitionOf(seq))
+	if err != nil {
+		return history.Transaction{}, err
+	}
+	for _, transaction := range p.Transactions {
+		if transaction.TransactionHash == hash ||
+			(transaction.InnerTransactionHash.Valid && transaction.InnerTransactionHash.String == hash) {
+			return transaction, nil
+		}
+	}
+	return history.Transaction{}, sql.ErrNoRows
+}
+
+// OperationsQuery selects the operations of an account read from cold
+// storage.
+type OperationsQuery struct {
+	Account             string
+	IncludeFailed       bool
+	IncludeTransactions bool
+	OnlyPayments        bool
+	Page                db2.PageQuery
+}
+
+// Operations is a page of operations read from cold storage together with
+// the ledgers they belong to and, if requested, their transactions. As in
+// history.OperationsQ, Transactions[i] is the transaction of Operations[i].
+type Operations struct {
+	Operations   []history.Operation
+	Transactions []history.Transaction
+	Ledgers      map[int32]history.Ledger
+}
+
+// AccountOperations loads a page of the operations of an account from cold
+// storage. Only the partitions the account participated in are read. The page
+// is shorter than the limit only if cold storage has no more operations
+// matching the query.
+func (s *Store) AccountOperations(ctx context.Context, q *history.Q, query OperationsQuery) (Operations, error) {
+	result := Operations{Ledgers: map[int32]history.Ledger{}}
+	cursor, err := query.Page.CursorInt64()
+	if err != nil {
+		return result, err
+	}
+	desc := query.Page.Order == db2.OrderDescending
+
+	from := s.PartitionOf(toid.Parse(cursor).LedgerSequence)
+	for uint64(len(result.Operations)) < query.Page.Limit {
+		partitions, err := q.ColdAccountPartitions(ctx, query.Account, from, query.Page.Order, query.Page.Limit)
+		if err != nil {
+			return result, err
+		}
+		for _, partition := range partitions {
+			if uint64(len(result.Operations)) == query.Page.Limit {
+				return result, nil
+			}
+			p, err := s.GetPartition(partition)
+			if err == sql.ErrNoRows {
+				continue
+			} else if err != nil {
+				return result, err
+			}
+			if err = p.appendAccountOperations(&result, query, cursor); err != nil {
+				return result, errors.Wrapf(err, "could not read partition %d", partition)
+			}
+		}
+		if uint64(len(partitions)) < query.Page.Limit {
+			return result, nil
+		}
+
+		last := partitions[len(partitions)-1]
+		if desc {
+			from = last - 1
+		} else {
+			from = last + 1
+		}
+	}
+	return result, nil
+}
+
+// appendAccountOperations appends the operations of the partition matching
+// query past cursor to result, until the page is full.
+func (p *Partition) appendAccountOperations(result *Operations, query OperationsQuery, cursor int64) error {
+	desc := query.Page.Order == db2.OrderDescending
+	transactions := map[int64]history.Transaction{}
+	if query.IncludeTransactions {
+		for _, transaction := range p.Transactions {
+			transactions[transaction.ID] = transaction
+		}
+	}
+	ledgers := map[int32]history.Ledger{}
+	for _, ledger := range p.Ledgers {
+		ledgers[ledger.Sequence] = ledger
+	}
+
+	for i := range p.Operations {
+		if uint64(len(result.Operations)) == query.Page.Limit {
+			return nil
+		}
+		operation := p.Operations[i]
+		if desc {
+			operation = p.Operations[len(p.Operations)-1-i]
+		}
+
+		if (desc && operation.ID >= cursor) || (!desc && operation.ID <= cursor) {
+			continue
+		}
+		if !query.IncludeFailed && !operation.TransactionSuccessful {
+			continue
+		}
+		if query.OnlyPayments && !isPayment(operation) {
+			continue
+		}
+		if !contains(p.Participants[operation.ID], query.Account) {
+			continue
+		}
+
+		ledger, ok := ledgers[operation.LedgerSequence()]
+		if !ok {
+			return errors.Errorf("ledger %d could not be found", operation.LedgerSequence())
+		}
+		result.Ledgers[ledger.Sequence] = ledger
+		if query.IncludeTransactions {
+			transaction, ok := transactions[operation.TransactionID]
+			if !ok {
+				return errors.Errorf("transaction with id %v could not be found", operation.TransactionID)
+			}
+			result.Transactions = append(result.Transactions, transaction)
+		}
+		result.Operations = append(result.Operations, operation)
+	}
+	return nil
+}
+
+func isPayment(operation history.Operation) bool {
+	for _, typ := range history.PaymentOperationTypes {
+		if operation.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(addresses []string, address string) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
@@ -14,7 +14,7 @@ import (
 // app's main function and is provided to NewApp.
 type Config struct {
 	DatabaseURL        string
-	RoDatabaseURL      string
+	RoDatabaseURLs     []string
 	HistoryArchiveURLs []string
 	Port               uint
 	AdminPort          uint
@@ -28,6 +28,8 @@ type Config struct {
 	CaptiveCoreToml             *ledgerbackend.CaptiveCoreToml
 	CaptiveCoreStoragePath      string
 	CaptiveCoreReuseStoragePath bool
+	CaptiveCoreMaxRestarts      uint
+	CaptiveCoreLogLines         uint
 
 	DiamcircleCoreDatabaseURL string
 	DiamcircleCoreURL         string
@@ -49,6 +51,15 @@ type Config struct {
 	// MaxAssetsPerPathRequest is the maximum number of assets considered for `/paths/strict-send` and `/paths/strict-recieve`
 	MaxAssetsPerPathRequest int
 	DisablePoolPathFinding  bool
+	// OrderBookSnapshotPath is the file the in-memory order book graph is
+	// saved to and restored from at startup. Snapshots are disabled if empty.
+	OrderBookSnapshotPath string
+	// OrderBookSnapshotInterval is the number of ledgers between order book
+	// snapshots.
+	OrderBookSnapshotInterval uint32
+	// PathFinderURL is the path finding service used instead of the
+	// in-memory order book when set.
+	PathFinderURL *url.URL
 
 	NetworkPassphrase string
 	SentryDSN         string
@@ -70,6 +81,11 @@ type Config struct {
 	// determining a "retention duration", each ledger roughly corresponds to 10
 	// seconds of real time.
 	HistoryRetentionCount uint
+	// HistoryColdStorageURL, if set, is the URL of a local (file://) or object
+	// (s3://) store where the reaper exports history before deleting it from
+	// the database. Reaped ledgers, transactions and account operations are
+	// then served from there.
+	HistoryColdStorageURL string
 	// StaleThreshold represents the number of ledgers a history database may be
 	// out-of-date by before aurora begins to respond with an error to history
 	// requests.
@@ -83,6 +99,15 @@ type Config struct {
 	// IngestEnableExtendedLogLedgerStats enables extended ledger stats in
 	// logging.
 	IngestEnableExtendedLogLedgerStats bool
+	// IngestLeaderElection is the leader election backend of the ingesting
+	// instances, `advisory-lock` or `lease`. Empty disables leader election.
+	IngestLeaderElection string
+	// IngestLeaderNodeID identifies this instance in the leader election.
+	IngestLeaderNodeID string
+	// EmbeddedDataDir, if set, is the directory where a single-node aurora
+	// stores the state it ingests instead of a database. Only the state
+	// endpoints are served in that mode.
+	EmbeddedDataDir string
 	// ApplyMigrations will apply pending migrations to the aurora database
 	// before starting the aurora service
 	ApplyMigrations bool
@@ -14,6 +14,7 @@ type CtxKey string
 
 var RequestContextKey = CtxKey("request")
 var SessionContextKey = CtxKey("session")
+var EmbeddedStoreContextKey = CtxKey("embedded_store")
 
 func RequestFromContext(ctx context.Context) *http.Request {
 	found, _ := ctx.Value(&RequestContextKey).(*http.Request)
@@ -61,3 +62,16 @@ func HistoryQFromRequest(request *http.Request) (*history.Q, error) {
 	}
 	return &history.Q{session}, nil
 }
+
+// QFromRequest returns the queries used to serve the request: the embedded
+// store if there is one in the request context, the history database otherwise.
+func QFromRequest(request *http.Request) (history.QInterface, error) {
+	if store, ok := request.Context().Value(&EmbeddedStoreContextKey).(history.QInterface); ok {
+		return store, nil
+	}
+	q, err := HistoryQFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
@@ -0,0 +1,328 @@
+// Package embedded implements an embedded store for single-node aurora
+// deployments. The state (accounts, signers, trust lines, account data and
+// offers) and the headers of the most recent ledgers are kept in memory and
+// persisted to a file in a local data directory, so that aurora can serve the
+// account and offer endpoints without an external database. The other
+// endpoints, including the history ones, are not served from the store.
+//
+// The store implements history.QInterface for the request handlers and the
+// state queries of the ingestion processors (history.QAccounts,
+// history.QData, history.QOffers, history.QSigners, history.QTrustLines and
+// history.QLedgers) on transactions started with Store.Update.
+package embedded
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/gob"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/errors"
+)
+
+const (
+	stateFileName = "state.gob"
+	// LedgerRetentionCount is the number of most recent ledgers whose header
+	// is kept in the store, roughly a day of ledgers.
+	LedgerRetentionCount = 17280
+)
+
+// Store is the embedded store. It is safe for concurrent use.
+type Store struct {
+	dir string
+
+	mutex sync.RWMutex
+	state *state
+	// lastLedgerIngest is the last ledger ingested into the store, readable
+	// while a transaction holds the lock.
+	lastLedgerIngest uint32
+}
+
+var _ history.QInterface = (*Store)(nil)
+
+// Open opens the store persisted in the given data directory, creating the
+// directory if it does not exist.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "could not create data directory")
+	}
+
+	st := newState()
+	file, err := os.Open(filepath.Join(dir, stateFileName))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "could not open state file")
+	default:
+		defer file.Close()
+		if err = gob.NewDecoder(bufio.NewReader(file)).Decode(st); err != nil {
+			return nil, errors.Wrap(err, "could not decode state file")
+		}
+	}
+	st.index()
+
+	return &Store{
+		dir:              dir,
+		state:            st,
+		lastLedgerIngest: st.LastLedgerIngest,
+	}, nil
+}
+
+// Save persists the store to its data directory. The previous version of the
+// state file is replaced atomically.
+func (s *Store) Save() error {
+	// The state is copied so that writers are not blocked while it is
+	// encoded and written.
+	s.mutex.RLock()
+	st := s.state.snapshot()
+	s.mutex.RUnlock()
+
+	file, err := ioutil.TempFile(s.dir, stateFileName+".*")
+	if err != nil {
+		return errors.Wrap(err, "could not create state file")
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err = gob.NewEncoder(writer).Encode(st); err != nil {
+		return errors.Wrap(err, "could not encode state")
+	}
+	if err = writer.Flush(); err != nil {
+		return errors.Wrap(err, "could not write state file")
+	}
+	if err = file.Sync(); err != nil {
+		return errors.Wrap(err, "could not sync state file")
+	}
+	if err = file.Close(); err != nil {
+		return errors.Wrap(err, "could not close state file")
+	}
+	return errors.Wrap(
+		os.Rename(file.Name(), filepath.Join(s.dir, stateFileName)),
+		"could not replace state file",
+	)
+}
+
+// Update runs fn in a transaction. Readers do not see the changes made by fn
+// until it returns, and the changes are rolled back if it returns an error.
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tx := &Tx{state: s.state}
+	if err := fn(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+	atomic.StoreUint32(&s.lastLedgerIngest, s.state.LastLedgerIngest)
+	return nil
+}
+
+// Reset replaces the content of the store with what fn writes to an empty
+// store. The store is unchanged if fn returns an error. Unlike Update, readers
+// are not blocked while fn runs, which makes it suitable to ingest the state
+// of a checkpoint.
+func (s *Store) Reset(fn func(tx *Tx) error) error {
+	st := newState()
+	if err := fn(&Tx{state: st, noRollback: true}); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.state = st
+	atomic.StoreUint32(&s.lastLedgerIngest, st.LastLedgerIngest)
+	return nil
+}
+
+// GetLastLedgerIngestNonBlocking returns the last ledger ingested into the
+// store.
+func (s *Store) GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error) {
+	return atomic.LoadUint32(&s.lastLedgerIngest), nil
+}
+
+// LedgerBySequence loads the header of the ledger at `seq` into `dest`, a
+// *history.Ledger.
+func (s *Store) LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ledger, ok := s.state.Ledgers[seq]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	switch dest := dest.(type) {
+	case *history.Ledger:
+		*dest = ledger
+		return nil
+	default:
+		return errors.Errorf("unsupported destination type %T", dest)
+	}
+}
+
+// LedgersBySequence loads the headers of the ledgers identified by `seqs`
+// into `dest`, a *[]history.Ledger. Unknown ledgers are skipped.
+func (s *Store) LedgersBySequence(ctx context.Context, dest interface{}, seqs ...int32) error {
+	if len(seqs) == 0 {
+		return errors.New("no sequence arguments provided")
+	}
+	ledgers, ok := dest.(*[]history.Ledger)
+	if !ok {
+		return errors.Errorf("unsupported destination type %T", dest)
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, seq := range seqs {
+		if ledger, ok := s.state.Ledgers[seq]; ok {
+			*ledgers = append(*ledgers, ledger)
+		}
+	}
+	return nil
+}
+
+// GetAccountByID loads an account.
+func (s *Store) GetAccountByID(ctx context.Context, id string) (history.AccountEntry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	account, ok := s.state.Accounts[id]
+	if !ok {
+		return history.AccountEntry{}, sql.ErrNoRows
+	}
+	return account, nil
+}
+
+// GetAccountDataByAccountID loads the data entries of an account, ordered by
+// name.
+func (s *Store) GetAccountDataByAccountID(ctx context.Context, id string) ([]history.Data, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var data []history.Data
+	for _, entry := range s.state.Data[id] {
+		data = append(data, entry)
+	}
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Name < data[j].Name
+	})
+	return data, nil
+}
+
+// GetAccountDataByName loads a data entry of an account.
+func (s *Store) GetAccountDataByName(ctx context.Context, id, name string) (history.Data, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	data, ok := s.state.Data[id][name]
+	if !ok {
+		return history.Data{}, sql.ErrNoRows
+	}
+	return data, nil
+}
+
+// GetAccountSignersByAccountID loads the signers of an account, ordered by
+// signer.
+func (s *Store) GetAccountSignersByAccountID(ctx context.Context, id string) ([]history.AccountSigner, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.state.signersForAccount(id), nil
+}
+
+// GetSortedTrustLinesByAccountID loads the trust lines of an account, ordered
+// by asset code, asset issuer and liquidity pool id.
+func (s *Store) GetSortedTrustLinesByAccountID(ctx context.Context, id string) ([]history.TrustLine, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var trustLines []history.TrustLine
+	for key := range s.state.trustLinesByAccount[id] {
+		trustLines = append(trustLines, s.state.TrustLines[key])
+	}
+	sort.Slice(trustLines, func(i, j int) bool {
+		a, b := trustLines[i], trustLines[j]
+		if a.AssetCode != b.AssetCode {
+			return a.AssetCode < b.AssetCode
+		}
+		if a.AssetIssuer != b.AssetIssuer {
+			return a.AssetIssuer < b.AssetIssuer
+		}
+		return a.LiquidityPoolID < b.LiquidityPoolID
+	})
+	return trustLines, nil
+}
+
+// GetOfferByID loads an offer which has not been deleted.
+func (s *Store) GetOfferByID(ctx context.Context, id int64) (history.Offer, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	offer, ok := s.state.Offers[id]
+	if !ok || offer.Deleted {
+		return history.Offer{}, sql.ErrNoRows
+	}
+	return offer, nil
+}
+
+// GetOffers loads a page of offers matching the query. Offers are indexed by
+// seller, the other filters scan all the offers.
+func (s *Store) GetOffers(ctx context.Context, query history.OffersQuery) ([]history.Offer, error) {
+	cursor, err := query.PageQuery.CursorInt64()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not apply query to page")
+	}
+	ascending := query.PageQuery.Order == db2.OrderAscending
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var offers []history.Offer
+	match := func(offer history.Offer) {
+		switch {
+		case offer.Deleted,
+			ascending && offer.OfferID <= cursor,
+			!ascending && offer.OfferID >= cursor,
+			query.SellerID != "" && offer.SellerID != query.SellerID,
+			query.Selling != nil && !offer.SellingAsset.Equals(*query.Selling),
+			query.Buying != nil && !offer.BuyingAsset.Equals(*query.Buying),
+			query.Sponsor != "" && offer.Sponsor.String != query.Sponsor:
+			return
+		}
+		offers = append(offers, offer)
+	}
+	if query.SellerID != "" {
+		for id := range s.state.offersBySeller[query.SellerID] {
+			match(s.state.Offers[id])
+		}
+	} else {
+		for _, offer := range s.state.Offers {
+			match(offer)
+		}
+	}
+
+	sort.Slice(offers, func(i, j int) bool {
+		if ascending {
+			return offers[i].OfferID < offers[j].OfferID
+		}
+		return offers[i].OfferID > offers[j].OfferID
+	})
+	if uint64(len(offers)) > query.PageQuery.Limit {
+		offers = offers[:query.PageQuery.Limit]
+	}
+	return offers, nil
+}
+
+// NoRows returns true if err reports a missing row.
+func (s *Store) NoRows(err error) bool {
+	return err == sql.ErrNoRows
+}
@@ -0,0 +1,271 @@
+package embedded
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	account1 = "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"
+	account2 = "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+	issuer   = "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+)
+
+var usd = xdr.MustNewCreditAsset("USD", issuer)
+
+func openTestStore(t *testing.T) (*Store, string) {
+	dir, err := ioutil.TempDir("", "aurora-embedded")
+	require.NoError(t, err)
+	store, err := Open(dir)
+	require.NoError(t, err)
+	return store, dir
+}
+
+func TestStoreUpdateAndReload(t *testing.T) {
+	ctx := context.Background()
+	store, dir := openTestStore(t)
+	defer os.RemoveAll(dir)
+
+	err := store.Update(func(tx *Tx) error {
+		if err := tx.UpsertAccounts(ctx, []history.AccountEntry{
+			{AccountID: account1, Balance: 100, LastModifiedLedger: 10},
+			{AccountID: account2, Balance: 200, LastModifiedLedger: 10},
+		}); err != nil {
+			return err
+		}
+		if _, err := tx.CreateAccountSigner(ctx, account1, account2, 1, nil); err != nil {
+			return err
+		}
+		if err := tx.UpsertAccountData(ctx, []history.Data{
+			{AccountID: account1, Name: "b", Value: []byte("2")},
+			{AccountID: account1, Name: "a", Value: []byte("1")},
+		}); err != nil {
+			return err
+		}
+		if err := tx.UpsertTrustLines(ctx, []history.TrustLine{
+			{AccountID: account1, AssetCode: "USD", AssetIssuer: issuer, LedgerKey: "usd", Balance: 5},
+		}); err != nil {
+			return err
+		}
+		return tx.UpdateLastLedgerIngest(ctx, 10)
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.Save())
+
+	// The store is persisted in the data directory.
+	store, err = Open(dir)
+	require.NoError(t, err)
+
+	lastLedger, err := store.GetLastLedgerIngestNonBlocking(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), lastLedger)
+
+	account, err := store.GetAccountByID(ctx, account1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), account.Balance)
+
+	_, err = store.GetAccountByID(ctx, issuer)
+	assert.True(t, store.NoRows(err))
+
+	data, err := store.GetAccountDataByAccountID(ctx, account1)
+	assert.NoError(t, err)
+	assert.Len(t, data, 2)
+	assert.Equal(t, "a", data[0].Name)
+
+	signers, err := store.GetAccountSignersByAccountID(ctx, account1)
+	assert.NoError(t, err)
+	assert.Equal(t, []history.AccountSigner{{Account: account1, Signer: account2, Weight: 1}}, signers)
+
+	trustLines, err := store.GetSortedTrustLinesByAccountID(ctx, account1)
+	assert.NoError(t, err)
+	assert.Len(t, trustLines, 1)
+	assert.Equal(t, int64(5), trustLines[0].Balance)
+
+	// The signer index is rebuilt when the store is loaded.
+	err = store.Update(func(tx *Tx) error {
+		accounts, err := tx.AccountsForSigner(ctx, account2, db2.PageQuery{Order: db2.OrderAscending, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, accounts, 1)
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestStoreUpdateRollback(t *testing.T) {
+	ctx := context.Background()
+	store, dir := openTestStore(t)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, store.Update(func(tx *Tx) error {
+		return tx.UpsertTrustLines(ctx, []history.TrustLine{
+			{AccountID: account1, AssetCode: "USD", AssetIssuer: issuer, LedgerKey: "usd", Balance: 5},
+		})
+	}))
+
+	err := store.Update(func(tx *Tx) error {
+		if _, err := tx.RemoveTrustLines(ctx, []string{"usd"}); err != nil {
+			return err
+		}
+		if err := tx.UpsertAccounts(ctx, []history.AccountEntry{{AccountID: account2}}); err != nil {
+			return err
+		}
+		if err := tx.UpdateLastLedgerIngest(ctx, 11); err != nil {
+			return err
+		}
+		return errors.New("processor failed")
+	})
+	assert.EqualError(t, err, "processor failed")
+
+	trustLines, err := store.GetSortedTrustLinesByAccountID(ctx, account1)
+	assert.NoError(t, err)
+	assert.Len(t, trustLines, 1)
+	_, err = store.GetAccountByID(ctx, account2)
+	assert.True(t, store.NoRows(err))
+	lastLedger, err := store.GetLastLedgerIngestNonBlocking(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), lastLedger)
+}
+
+func TestStoreGetOffers(t *testing.T) {
+	ctx := context.Background()
+	store, dir := openTestStore(t)
+	defer os.RemoveAll(dir)
+
+	offers := []history.Offer{
+		{OfferID: 1, SellerID: account1, SellingAsset: xdr.MustNewNativeAsset(), BuyingAsset: usd},
+		{OfferID: 2, SellerID: account2, SellingAsset: usd, BuyingAsset: xdr.MustNewNativeAsset()},
+		{OfferID: 3, SellerID: account1, SellingAsset: usd, BuyingAsset: xdr.MustNewNativeAsset(), Sponsor: null.StringFrom(issuer)},
+		{OfferID: 4, SellerID: account1, SellingAsset: usd, BuyingAsset: xdr.MustNewNativeAsset(), Deleted: true, LastModifiedLedger: 5},
+	}
+	require.NoError(t, store.Update(func(tx *Tx) error {
+		return tx.UpsertOffers(ctx, offers)
+	}))
+
+	_, err := store.GetOfferByID(ctx, 4)
+	assert.True(t, store.NoRows(err))
+
+	for _, testCase := range []struct {
+		name     string
+		query    history.OffersQuery
+		expected []int64
+	}{
+		{
+			name:     "all",
+			query:    history.OffersQuery{PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 10}},
+			expected: []int64{1, 2, 3},
+		},
+		{
+			name:     "descending with cursor",
+			query:    history.OffersQuery{PageQuery: db2.PageQuery{Order: db2.OrderDescending, Cursor: "3", Limit: 10}},
+			expected: []int64{2, 1},
+		},
+		{
+			name:     "seller",
+			query:    history.OffersQuery{PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 1}, SellerID: account1},
+			expected: []int64{1},
+		},
+		{
+			name:     "selling",
+			query:    history.OffersQuery{PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 10}, Selling: &usd},
+			expected: []int64{2, 3},
+		},
+		{
+			name:     "sponsor",
+			query:    history.OffersQuery{PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 10}, Sponsor: issuer},
+			expected: []int64{3},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			records, err := store.GetOffers(ctx, testCase.query)
+			assert.NoError(t, err)
+			var ids []int64
+			for _, record := range records {
+				ids = append(ids, record.OfferID)
+			}
+			assert.Equal(t, testCase.expected, ids)
+		})
+	}
+
+	// Deleted offers are compacted.
+	require.NoError(t, store.Update(func(tx *Tx) error {
+		removed, err := tx.CompactOffers(ctx, 5)
+		assert.Equal(t, int64(1), removed)
+		return err
+	}))
+	records, err := store.GetOffers(ctx, history.OffersQuery{
+		PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 10},
+		SellerID:  account1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestStoreLedgers(t *testing.T) {
+	ctx := context.Background()
+	store, dir := openTestStore(t)
+	defer os.RemoveAll(dir)
+
+	header := func(seq uint32) xdr.LedgerHeaderHistoryEntry {
+		return xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(seq)}}
+	}
+	require.NoError(t, store.Update(func(tx *Tx) error {
+		for _, seq := range []uint32{1, 2, LedgerRetentionCount + 1} {
+			if _, err := tx.InsertLedger(ctx, header(seq), 1, 0, 2, 2, 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	var ledger history.Ledger
+	assert.NoError(t, store.LedgerBySequence(ctx, &ledger, 2))
+	assert.Equal(t, int32(2), ledger.Sequence)
+	assert.Equal(t, int32(2), ledger.OperationCount)
+
+	// The header of ledger 1 has expired.
+	assert.True(t, store.NoRows(store.LedgerBySequence(ctx, &ledger, 1)))
+
+	var ledgers []history.Ledger
+	assert.NoError(t, store.LedgersBySequence(ctx, &ledgers, 1, 2, LedgerRetentionCount+1))
+	assert.Len(t, ledgers, 2)
+}
+
+func TestStateSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store, dir := openTestStore(t)
+	defer os.RemoveAll(dir)
+
+	update := func(value string, weight int32) {
+		require.NoError(t, store.Update(func(tx *Tx) error {
+			if err := tx.UpsertAccountData(ctx, []history.Data{
+				{AccountID: account1, Name: "a", Value: []byte(value)},
+			}); err != nil {
+				return err
+			}
+			if _, err := tx.RemoveAccountSigner(ctx, account1, account2); err != nil {
+				return err
+			}
+			_, err := tx.CreateAccountSigner(ctx, account1, account2, weight, nil)
+			return err
+		}))
+	}
+	update("1", 1)
+	snapshot := store.state.snapshot()
+
+	// The snapshot does not see the writes made after it was taken.
+	update("2", 2)
+	assert.Equal(t, []byte("1"), []byte(snapshot.Data[account1]["a"].Value))
+	assert.Equal(t, int32(1), snapshot.Signers[account1][account2].Weight)
+	assert.Equal(t, []byte("2"), []byte(store.state.Data[account1]["a"].Value))
+}
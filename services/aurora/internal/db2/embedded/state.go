@@ -0,0 +1,202 @@
+package embedded
+
+import (
+	"sort"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+)
+
+// state is the content of the store, persisted with encoding/gob. The
+// unexported indexes are rebuilt when the state is loaded.
+type state struct {
+	LastLedgerIngest uint32
+	Accounts         map[string]history.AccountEntry
+	// Data maps account ids to the data entries of the account by name.
+	Data map[string]map[string]history.Data
+	// Signers maps account ids to the signers of the account by signer.
+	Signers map[string]map[string]history.AccountSigner
+	// TrustLines maps ledger keys to trust lines.
+	TrustLines map[string]history.TrustLine
+	Offers     map[int64]history.Offer
+	Ledgers    map[int32]history.Ledger
+
+	trustLinesByAccount map[string]map[string]struct{}
+	offersBySeller      map[string]map[int64]struct{}
+	accountsBySigner    map[string]map[string]struct{}
+}
+
+func newState() *state {
+	st := &state{
+		Accounts:   map[string]history.AccountEntry{},
+		Data:       map[string]map[string]history.Data{},
+		Signers:    map[string]map[string]history.AccountSigner{},
+		TrustLines: map[string]history.TrustLine{},
+		Offers:     map[int64]history.Offer{},
+		Ledgers:    map[int32]history.Ledger{},
+	}
+	st.index()
+	return st
+}
+
+// snapshot returns a copy of the persisted content of the state, which is not
+// changed by later writes to the state.
+func (st *state) snapshot() *state {
+	cp := &state{
+		LastLedgerIngest: st.LastLedgerIngest,
+		Accounts:         make(map[string]history.AccountEntry, len(st.Accounts)),
+		Data:             make(map[string]map[string]history.Data, len(st.Data)),
+		Signers:          make(map[string]map[string]history.AccountSigner, len(st.Signers)),
+		TrustLines:       make(map[string]history.TrustLine, len(st.TrustLines)),
+		Offers:           make(map[int64]history.Offer, len(st.Offers)),
+		Ledgers:          make(map[int32]history.Ledger, len(st.Ledgers)),
+	}
+	for id, account := range st.Accounts {
+		cp.Accounts[id] = account
+	}
+	for account, data := range st.Data {
+		cp.Data[account] = make(map[string]history.Data, len(data))
+		for name, entry := range data {
+			cp.Data[account][name] = entry
+		}
+	}
+	for account, signers := range st.Signers {
+		cp.Signers[account] = make(map[string]history.AccountSigner, len(signers))
+		for signer, entry := range signers {
+			cp.Signers[account][signer] = entry
+		}
+	}
+	for key, trustLine := range st.TrustLines {
+		cp.TrustLines[key] = trustLine
+	}
+	for id, offer := range st.Offers {
+		cp.Offers[id] = offer
+	}
+	for sequence, ledger := range st.Ledgers {
+		cp.Ledgers[sequence] = ledger
+	}
+	return cp
+}
+
+func (st *state) index() {
+	st.trustLinesByAccount = map[string]map[string]struct{}{}
+	for key, trustLine := range st.TrustLines {
+		addToIndex(st.trustLinesByAccount, trustLine.AccountID, key)
+	}
+	st.offersBySeller = map[string]map[int64]struct{}{}
+	for id, offer := range st.Offers {
+		if st.offersBySeller[offer.SellerID] == nil {
+			st.offersBySeller[offer.SellerID] = map[int64]struct{}{}
+		}
+		st.offersBySeller[offer.SellerID][id] = struct{}{}
+	}
+	st.accountsBySigner = map[string]map[string]struct{}{}
+	for account, signers := range st.Signers {
+		for signer := range signers {
+			addToIndex(st.accountsBySigner, signer, account)
+		}
+	}
+}
+
+func addToIndex(index map[string]map[string]struct{}, key, value string) {
+	if index[key] == nil {
+		index[key] = map[string]struct{}{}
+	}
+	index[key][value] = struct{}{}
+}
+
+func removeFromIndex(index map[string]map[string]struct{}, key, value string) {
+	delete(index[key], value)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+func (st *state) signersForAccount(account string) []history.AccountSigner {
+	var signers []history.AccountSigner
+	for _, signer := range st.Signers[account] {
+		signers = append(signers, signer)
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return signers[i].Signer < signers[j].Signer
+	})
+	return signers
+}
+
+// The set methods below replace (exists is true) or delete (exists is false)
+// an entry and maintain the indexes.
+
+func (st *state) setAccount(id string, account history.AccountEntry, exists bool) {
+	if exists {
+		st.Accounts[id] = account
+	} else {
+		delete(st.Accounts, id)
+	}
+}
+
+func (st *state) setData(accountID, name string, data history.Data, exists bool) {
+	if exists {
+		if st.Data[accountID] == nil {
+			st.Data[accountID] = map[string]history.Data{}
+		}
+		st.Data[accountID][name] = data
+		return
+	}
+	delete(st.Data[accountID], name)
+	if len(st.Data[accountID]) == 0 {
+		delete(st.Data, accountID)
+	}
+}
+
+func (st *state) setSigner(account, signer string, row history.AccountSigner, exists bool) {
+	if exists {
+		if st.Signers[account] == nil {
+			st.Signers[account] = map[string]history.AccountSigner{}
+		}
+		st.Signers[account][signer] = row
+		addToIndex(st.accountsBySigner, signer, account)
+		return
+	}
+	delete(st.Signers[account], signer)
+	if len(st.Signers[account]) == 0 {
+		delete(st.Signers, account)
+	}
+	removeFromIndex(st.accountsBySigner, signer, account)
+}
+
+func (st *state) setTrustLine(key string, trustLine history.TrustLine, exists bool) {
+	if previous, ok := st.TrustLines[key]; ok {
+		removeFromIndex(st.trustLinesByAccount, previous.AccountID, key)
+	}
+	if exists {
+		st.TrustLines[key] = trustLine
+		addToIndex(st.trustLinesByAccount, trustLine.AccountID, key)
+	} else {
+		delete(st.TrustLines, key)
+	}
+}
+
+func (st *state) setOffer(id int64, offer history.Offer, exists bool) {
+	if previous, ok := st.Offers[id]; ok {
+		delete(st.offersBySeller[previous.SellerID], id)
+		if len(st.offersBySeller[previous.SellerID]) == 0 {
+			delete(st.offersBySeller, previous.SellerID)
+		}
+	}
+	if !exists {
+		delete(st.Offers, id)
+		return
+	}
+	st.Offers[id] = offer
+	if st.offersBySeller[offer.SellerID] == nil {
+		st.offersBySeller[offer.SellerID] = map[int64]struct{}{}
+	}
+	st.offersBySeller[offer.SellerID][id] = struct{}{}
+}
+
+func (st *state) setLedger(seq int32, ledger history.Ledger, exists bool) {
+	if exists {
+		st.Ledgers[seq] = ledger
+	} else {
+		delete(st.Ledgers, seq)
+	}
+}
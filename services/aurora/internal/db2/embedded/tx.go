@@ -0,0 +1,424 @@
+package embedded
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/guregu/null"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// Tx is a transaction on the store, see Store.Update. It implements the
+// queries of the state ingestion processors and must not be used once the
+// function it was passed to returns.
+type Tx struct {
+	state *state
+	// undo holds the functions restoring the entries changed by the
+	// transaction, in the order of the changes.
+	undo       []func()
+	noRollback bool
+}
+
+var (
+	_ history.QAccounts   = (*Tx)(nil)
+	_ history.QData       = (*Tx)(nil)
+	_ history.QLedgers    = (*Tx)(nil)
+	_ history.QOffers     = (*Tx)(nil)
+	_ history.QSigners    = (*Tx)(nil)
+	_ history.QTrustLines = (*Tx)(nil)
+)
+
+func (tx *Tx) journal(undo func()) {
+	if !tx.noRollback {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+func (tx *Tx) rollback() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	tx.undo = nil
+}
+
+func (tx *Tx) setAccount(id string, account history.AccountEntry, exists bool) {
+	previous, existed := tx.state.Accounts[id]
+	tx.journal(func() { tx.state.setAccount(id, previous, existed) })
+	tx.state.setAccount(id, account, exists)
+}
+
+func (tx *Tx) setData(accountID, name string, data history.Data, exists bool) {
+	previous, existed := tx.state.Data[accountID][name]
+	tx.journal(func() { tx.state.setData(accountID, name, previous, existed) })
+	tx.state.setData(accountID, name, data, exists)
+}
+
+func (tx *Tx) setSigner(account, signer string, row history.AccountSigner, exists bool) {
+	previous, existed := tx.state.Signers[account][signer]
+	tx.journal(func() { tx.state.setSigner(account, signer, previous, existed) })
+	tx.state.setSigner(account, signer, row, exists)
+}
+
+func (tx *Tx) setTrustLine(key string, trustLine history.TrustLine, exists bool) {
+	previous, existed := tx.state.TrustLines[key]
+	tx.journal(func() { tx.state.setTrustLine(key, previous, existed) })
+	tx.state.setTrustLine(key, trustLine, exists)
+}
+
+func (tx *Tx) setOffer(id int64, offer history.Offer, exists bool) {
+	previous, existed := tx.state.Offers[id]
+	tx.journal(func() { tx.state.setOffer(id, previous, existed) })
+	tx.state.setOffer(id, offer, exists)
+}
+
+func (tx *Tx) setLedger(seq int32, ledger history.Ledger, exists bool) {
+	previous, existed := tx.state.Ledgers[seq]
+	tx.journal(func() { tx.state.setLedger(seq, previous, existed) })
+	tx.state.setLedger(seq, ledger, exists)
+}
+
+// GetLastLedgerIngestNonBlocking returns the last ledger ingested into the
+// store.
+func (tx *Tx) GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error) {
+	return tx.state.LastLedgerIngest, nil
+}
+
+// GetLastLedgerIngest returns the last ledger ingested into the store. The
+// store is already locked by the transaction.
+func (tx *Tx) GetLastLedgerIngest(ctx context.Context) (uint32, error) {
+	return tx.state.LastLedgerIngest, nil
+}
+
+// UpdateLastLedgerIngest updates the last ledger ingested into the store.
+func (tx *Tx) UpdateLastLedgerIngest(ctx context.Context, ledgerSequence uint32) error {
+	previous := tx.state.LastLedgerIngest
+	tx.journal(func() { tx.state.LastLedgerIngest = previous })
+	tx.state.LastLedgerIngest = ledgerSequence
+	return nil
+}
+
+// GetAccountsByIDs loads the accounts with the given ids.
+func (tx *Tx) GetAccountsByIDs(ctx context.Context, ids []string) ([]history.AccountEntry, error) {
+	var accounts []history.AccountEntry
+	for _, id := range ids {
+		if account, ok := tx.state.Accounts[id]; ok {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+// UpsertAccounts creates or updates accounts.
+func (tx *Tx) UpsertAccounts(ctx context.Context, accounts []history.AccountEntry) error {
+	for _, account := range accounts {
+		tx.setAccount(account.AccountID, account, true)
+	}
+	return nil
+}
+
+// RemoveAccounts deletes accounts and returns the number of deleted accounts.
+func (tx *Tx) RemoveAccounts(ctx context.Context, accountIDs []string) (int64, error) {
+	var removed int64
+	for _, id := range accountIDs {
+		if _, ok := tx.state.Accounts[id]; ok {
+			tx.setAccount(id, history.AccountEntry{}, false)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// CountAccounts returns the number of accounts.
+func (tx *Tx) CountAccounts(ctx context.Context) (int, error) {
+	return len(tx.state.Accounts), nil
+}
+
+// CountAccountsData returns the number of account data entries.
+func (tx *Tx) CountAccountsData(ctx context.Context) (int, error) {
+	count := 0
+	for _, data := range tx.state.Data {
+		count += len(data)
+	}
+	return count, nil
+}
+
+// GetAccountDataByKeys loads the account data entries with the given keys.
+func (tx *Tx) GetAccountDataByKeys(ctx context.Context, keys []history.AccountDataKey) ([]history.Data, error) {
+	var data []history.Data
+	for _, key := range keys {
+		if entry, ok := tx.state.Data[key.AccountID][key.DataName]; ok {
+			data = append(data, entry)
+		}
+	}
+	return data, nil
+}
+
+// UpsertAccountData creates or updates account data entries.
+func (tx *Tx) UpsertAccountData(ctx context.Context, data []history.Data) error {
+	for _, entry := range data {
+		tx.setData(entry.AccountID, entry.Name, entry, true)
+	}
+	return nil
+}
+
+// RemoveAccountData deletes account data entries and returns the number of
+// deleted entries.
+func (tx *Tx) RemoveAccountData(ctx context.Context, keys []history.AccountDataKey) (int64, error) {
+	var removed int64
+	for _, key := range keys {
+		if _, ok := tx.state.Data[key.AccountID][key.DataName]; ok {
+			tx.setData(key.AccountID, key.DataName, history.Data{}, false)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// AccountsForSigner loads a page of the accounts which have the given signer,
+// by account id.
+func (tx *Tx) AccountsForSigner(ctx context.Context, signer string, page db2.PageQuery) ([]history.AccountSigner, error) {
+	var accounts []string
+	for account := range tx.state.accountsBySigner[signer] {
+		switch page.Order {
+		case db2.OrderAscending:
+			if page.Cursor == "" || account > page.Cursor {
+				accounts = append(accounts, account)
+			}
+		case db2.OrderDescending:
+			if page.Cursor == "" || account < page.Cursor {
+				accounts = append(accounts, account)
+			}
+		default:
+			return nil, errors.Errorf("invalid order: %s", page.Order)
+		}
+	}
+
+	sort.Strings(accounts)
+	if page.Order == db2.OrderDescending {
+		sort.Sort(sort.Reverse(sort.StringSlice(accounts)))
+	}
+	if uint64(len(accounts)) > page.Limit {
+		accounts = accounts[:page.Limit]
+	}
+
+	results := make([]history.AccountSigner, 0, len(accounts))
+	for _, account := range accounts {
+		results = append(results, tx.state.Signers[account][signer])
+	}
+	return results, nil
+}
+
+// NewAccountSignersBatchInsertBuilder returns a batch insert builder adding
+// signers to the store.
+func (tx *Tx) NewAccountSignersBatchInsertBuilder(maxBatchSize int) history.AccountSignersBatchInsertBuilder {
+	return signersBatchInsertBuilder{tx: tx}
+}
+
+type signersBatchInsertBuilder struct {
+	tx *Tx
+}
+
+func (b signersBatchInsertBuilder) Add(ctx context.Context, signer history.AccountSigner) error {
+	_, err := b.tx.CreateAccountSigner(ctx, signer.Account, signer.Signer, signer.Weight, signer.Sponsor.Ptr())
+	return err
+}
+
+func (b signersBatchInsertBuilder) Exec(ctx context.Context) error {
+	return nil
+}
+
+// CreateAccountSigner adds a signer to an account.
+func (tx *Tx) CreateAccountSigner(ctx context.Context, account, signer string, weight int32, sponsor *string) (int64, error) {
+	if _, ok := tx.state.Signers[account][signer]; ok {
+		return 0, errors.Errorf("signer %s of account %s already exists", signer, account)
+	}
+	tx.setSigner(account, signer, history.AccountSigner{
+		Account: account,
+		Signer:  signer,
+		Weight:  weight,
+		Sponsor: null.StringFromPtr(sponsor),
+	}, true)
+	return 1, nil
+}
+
+// RemoveAccountSigner removes a signer from an account and returns the number
+// of removed signers.
+func (tx *Tx) RemoveAccountSigner(ctx context.Context, account, signer string) (int64, error) {
+	if _, ok := tx.state.Signers[account][signer]; !ok {
+		return 0, nil
+	}
+	tx.setSigner(account, signer, history.AccountSigner{}, false)
+	return 1, nil
+}
+
+// SignersForAccounts loads the signers of the given accounts.
+func (tx *Tx) SignersForAccounts(ctx context.Context, accounts []string) ([]history.AccountSigner, error) {
+	var signers []history.AccountSigner
+	for _, account := range accounts {
+		signers = append(signers, tx.state.signersForAccount(account)...)
+	}
+	return signers, nil
+}
+
+// GetTrustLinesByKeys loads the trust lines with the given ledger keys.
+func (tx *Tx) GetTrustLinesByKeys(ctx context.Context, ledgerKeys []string) ([]history.TrustLine, error) {
+	var trustLines []history.TrustLine
+	for _, key := range ledgerKeys {
+		if trustLine, ok := tx.state.TrustLines[key]; ok {
+			trustLines = append(trustLines, trustLine)
+		}
+	}
+	return trustLines, nil
+}
+
+// UpsertTrustLines creates or updates trust lines.
+func (tx *Tx) UpsertTrustLines(ctx context.Context, trustLines []history.TrustLine) error {
+	for _, trustLine := range trustLines {
+		tx.setTrustLine(trustLine.LedgerKey, trustLine, true)
+	}
+	return nil
+}
+
+// RemoveTrustLines deletes trust lines and returns the number of deleted
+// trust lines.
+func (tx *Tx) RemoveTrustLines(ctx context.Context, ledgerKeys []string) (int64, error) {
+	var removed int64
+	for _, key := range ledgerKeys {
+		if _, ok := tx.state.TrustLines[key]; ok {
+			tx.setTrustLine(key, history.TrustLine{}, false)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// GetAllOffers loads the offers which have not been deleted.
+func (tx *Tx) GetAllOffers(ctx context.Context) ([]history.Offer, error) {
+	var offers []history.Offer
+	for _, offer := range tx.state.Offers {
+		if !offer.Deleted {
+			offers = append(offers, offer)
+		}
+	}
+	return offers, nil
+}
+
+// GetOffersByIDs loads the offers with the given ids which have not been
+// deleted.
+func (tx *Tx) GetOffersByIDs(ctx context.Context, ids []int64) ([]history.Offer, error) {
+	var offers []history.Offer
+	for _, id := range ids {
+		if offer, ok := tx.state.Offers[id]; ok && !offer.Deleted {
+			offers = append(offers, offer)
+		}
+	}
+	return offers, nil
+}
+
+// CountOffers returns the number of offers which have not been deleted.
+func (tx *Tx) CountOffers(ctx context.Context) (int, error) {
+	count := 0
+	for _, offer := range tx.state.Offers {
+		if !offer.Deleted {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetUpdatedOffers loads the offers, including the deleted ones, modified
+// after the given ledger.
+func (tx *Tx) GetUpdatedOffers(ctx context.Context, newerThanSequence uint32) ([]history.Offer, error) {
+	var offers []history.Offer
+	for _, offer := range tx.state.Offers {
+		if offer.LastModifiedLedger > newerThanSequence {
+			offers = append(offers, offer)
+		}
+	}
+	return offers, nil
+}
+
+// UpsertOffers creates or updates offers. Removed offers are kept with their
+// Deleted flag set until they are compacted.
+func (tx *Tx) UpsertOffers(ctx context.Context, offers []history.Offer) error {
+	for _, offer := range offers {
+		tx.setOffer(offer.OfferID, offer, true)
+	}
+	return nil
+}
+
+// CompactOffers deletes the offers removed at or before the given ledger and
+// returns the number of deleted offers.
+func (tx *Tx) CompactOffers(ctx context.Context, cutOffSequence uint32) (int64, error) {
+	var removed int64
+	for id, offer := range tx.state.Offers {
+		if offer.Deleted && offer.LastModifiedLedger <= cutOffSequence {
+			tx.setOffer(id, history.Offer{}, false)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// InsertLedger stores the header of a ledger. The header of the ledger
+// LedgerRetentionCount ledgers before it is deleted.
+func (tx *Tx) InsertLedger(ctx context.Context,
+	ledger xdr.LedgerHeaderHistoryEntry,
+	successTxsCount int,
+	failedTxsCount int,
+	opCount int,
+	txSetOpCount int,
+	ingestVersion int,
+) (int64, error) {
+	seq := int32(ledger.Header.LedgerSeq)
+	if _, ok := tx.state.Ledgers[seq]; ok {
+		return 0, errors.Errorf("ledger %d already exists", seq)
+	}
+
+	headerXDR, err := xdr.MarshalBase64(ledger.Header)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		successful = int32(successTxsCount)
+		failed     = int32(failedTxsCount)
+		txSetOps   = int32(txSetOpCount)
+		now        = time.Now().UTC()
+	)
+	tx.setLedger(seq, history.Ledger{
+		TotalOrderID:               history.TotalOrderID{ID: toid.New(seq, 0, 0).ToInt64()},
+		Sequence:                   seq,
+		ImporterVersion:            int32(ingestVersion),
+		LedgerHash:                 hex.EncodeToString(ledger.Hash[:]),
+		PreviousLedgerHash:         null.NewString(hex.EncodeToString(ledger.Header.PreviousLedgerHash[:]), seq > 1),
+		TransactionCount:           successful,
+		SuccessfulTransactionCount: &successful,
+		FailedTransactionCount:     &failed,
+		OperationCount:             int32(opCount),
+		TxSetOperationCount:        &txSetOps,
+		ClosedAt:                   time.Unix(int64(ledger.Header.ScpValue.CloseTime), 0).UTC(),
+		CreatedAt:                  now,
+		UpdatedAt:                  now,
+		TotalCoins:                 int64(ledger.Header.TotalCoins),
+		FeePool:                    int64(ledger.Header.FeePool),
+		BaseFee:                    int32(ledger.Header.BaseFee),
+		BaseReserve:                int32(ledger.Header.BaseReserve),
+		MaxTxSetSize:               int32(ledger.Header.MaxTxSetSize),
+		ProtocolVersion:            int32(ledger.Header.LedgerVersion),
+		LedgerHeaderXDR:            null.StringFrom(headerXDR),
+	}, true)
+
+	if expired := seq - LedgerRetentionCount; expired > 0 {
+		if _, ok := tx.state.Ledgers[expired]; ok {
+			tx.setLedger(expired, history.Ledger{}, false)
+		}
+	}
+	return 1, nil
+}
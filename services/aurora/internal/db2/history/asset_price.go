@@ -0,0 +1,119 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+)
+
+// PriceTrade is a trade between two assets, oriented by the assets of a
+// price query: BaseAmount is the amount of the base asset and CounterAmount
+// the amount of the counter asset exchanged.
+type PriceTrade struct {
+	HistoryOperationID int64     `db:"history_operation_id"`
+	LedgerCloseTime    time.Time `db:"ledger_closed_at"`
+	BaseAmount         int64     `db:"base_amount"`
+	CounterAmount      int64     `db:"counter_amount"`
+}
+
+// LedgerSequence returns the sequence of the ledger the trade happened in
+func (t PriceTrade) LedgerSequence() int32 {
+	return toid.Parse(t.HistoryOperationID).LedgerSequence
+}
+
+// PriceTradeSummary aggregates the trades between two assets, oriented like
+// PriceTrade.
+type PriceTradeSummary struct {
+	TradeCount int64 `db:"trade_count"`
+	// BaseVolume and CounterVolume are integer amounts, they are strings as
+	// the sums may not fit in an int64.
+	BaseVolume    string     `db:"base_volume"`
+	CounterVolume string     `db:"counter_volume"`
+	LastTradeAt   *time.Time `db:"last_trade_at"`
+}
+
+// priceTradeColumns returns the columns holding the amounts of the base and
+// the counter asset of the trades between two assets, and the ids of the
+// assets in canonical order.
+func priceTradeColumns(baseAssetID, counterAssetID int64) (string, string, int64, int64) {
+	orderPreserved, baseAssetID, counterAssetID := getCanonicalAssetOrder(baseAssetID, counterAssetID)
+	if !orderPreserved {
+		return "counter_amount", "base_amount", baseAssetID, counterAssetID
+	}
+	return "base_amount", "counter_amount", baseAssetID, counterAssetID
+}
+
+// selectPriceTrades selects the trades between two assets, oriented by
+// baseAssetID and counterAssetID.
+func selectPriceTrades(baseAssetID, counterAssetID int64) sq.SelectBuilder {
+	baseColumn, counterColumn, baseAssetID, counterAssetID := priceTradeColumns(baseAssetID, counterAssetID)
+
+	return sq.Select(
+		"history_operation_id",
+		"ledger_closed_at",
+		baseColumn+" AS base_amount",
+		counterColumn+" AS counter_amount",
+	).
+		From("history_trades").
+		Where(sq.Eq{"base_asset_id": baseAssetID, "counter_asset_id": counterAssetID})
+}
+
+// GetLedgerPriceTrades returns, for each ledger from the ledger with the given
+// sequence onwards, the last trade between two assets in which some of the
+// base asset was exchanged, in ascending order. At most one trade per ledger
+// is loaded, however many trades the ledgers contain.
+func (q *Q) GetLedgerPriceTrades(ctx context.Context, baseAssetID, counterAssetID int64, fromLedger int32) ([]PriceTrade, error) {
+	baseColumn, _, _, _ := priceTradeColumns(baseAssetID, counterAssetID)
+	sql := selectPriceTrades(baseAssetID, counterAssetID).
+		Options("DISTINCT ON (history_operation_id >> 32)").
+		Where(sq.GtOrEq{"history_operation_id": toid.New(fromLedger, 0, 0).ToInt64()}).
+		Where(baseColumn+" <> 0").
+		OrderBy("history_operation_id >> 32 asc", "history_operation_id desc", `"order" desc`)
+
+	var trades []PriceTrade
+	if err := q.Select(ctx, &trades, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select trades")
+	}
+	return trades, nil
+}
+
+// GetPriceTradeSummary aggregates the trades between two assets from the
+// ledger with the given sequence onwards.
+func (q *Q) GetPriceTradeSummary(ctx context.Context, baseAssetID, counterAssetID int64, fromLedger int32) (PriceTradeSummary, error) {
+	baseColumn, counterColumn, baseAssetID, counterAssetID := priceTradeColumns(baseAssetID, counterAssetID)
+	sql := sq.Select(
+		"count(*) AS trade_count",
+		"COALESCE(sum("+baseColumn+"), 0)::text AS base_volume",
+		"COALESCE(sum("+counterColumn+"), 0)::text AS counter_volume",
+		"max(ledger_closed_at) AS last_trade_at",
+	).
+		From("history_trades").
+		Where(sq.Eq{"base_asset_id": baseAssetID, "counter_asset_id": counterAssetID}).
+		Where(sq.GtOrEq{"history_operation_id": toid.New(fromLedger, 0, 0).ToInt64()})
+
+	var summary PriceTradeSummary
+	if err := q.Get(ctx, &summary, sql); err != nil {
+		return summary, errors.Wrap(err, "could not aggregate trades")
+	}
+	return summary, nil
+}
+
+// GetLastPriceTrade returns the last trade between two assets before the
+// ledger with the given sequence in which some of the base asset was
+// exchanged. It returns sql.ErrNoRows if there is none.
+func (q *Q) GetLastPriceTrade(ctx context.Context, baseAssetID, counterAssetID int64, beforeLedger int32) (PriceTrade, error) {
+	baseColumn, _, _, _ := priceTradeColumns(baseAssetID, counterAssetID)
+	sql := selectPriceTrades(baseAssetID, counterAssetID).
+		Where(sq.Lt{"history_operation_id": toid.New(beforeLedger, 0, 0).ToInt64()}).
+		Where(baseColumn+" <> 0").
+		OrderBy("history_operation_id desc", `"order" desc`).
+		Limit(1)
+
+	var trade PriceTrade
+	err := q.Get(ctx, &trade, sql)
+	return trade, err
+}
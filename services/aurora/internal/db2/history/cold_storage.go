@@ -0,0 +1,158 @@
+package history
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+)
+
+// ColdTransaction maps the hash of a transaction exported to cold storage to
+// the ledger it was included in. Fee bump transactions have a row for both
+// their outer and inner hash.
+type ColdTransaction struct {
+	TransactionHash string `db:"transaction_hash"`
+	LedgerSequence  int32  `db:"ledger_sequence"`
+}
+
+// ColdAccountPartition records that an account participated in an operation
+// of a cold storage partition.
+type ColdAccountPartition struct {
+	Address   string `db:"address"`
+	Partition int32  `db:"partition"`
+}
+
+// OperationParticipant is an account participating in an operation.
+type OperationParticipant struct {
+	OperationID int64  `db:"history_operation_id"`
+	Address     string `db:"address"`
+}
+
+// LedgersInRange loads the ledgers between startSeq and endSeq inclusive, in
+// ascending order.
+func (q *Q) LedgersInRange(ctx context.Context, startSeq, endSeq int32) ([]Ledger, error) {
+	sql := selectLedger.
+		Where(sq.GtOrEq{"hl.sequence": startSeq}).
+		Where(sq.LtOrEq{"hl.sequence": endSeq}).
+		OrderBy("hl.sequence asc")
+
+	var ledgers []Ledger
+	if err := q.Select(ctx, &ledgers, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select ledgers")
+	}
+	return ledgers, nil
+}
+
+// TransactionsInRange loads the transactions, including failed ones, with a
+// total order id in [start, end), in ascending order.
+func (q *Q) TransactionsInRange(ctx context.Context, start, end int64) ([]Transaction, error) {
+	sql := selectTransaction.
+		Where(sq.GtOrEq{"ht.id": start}).
+		Where(sq.Lt{"ht.id": end}).
+		OrderBy("ht.id asc")
+
+	var transactions []Transaction
+	if err := q.Select(ctx, &transactions, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select transactions")
+	}
+	return transactions, nil
+}
+
+// OperationsInRange loads the operations, including the ones of failed
+// transactions, with a total order id in [start, end), in ascending order.
+func (q *Q) OperationsInRange(ctx context.Context, start, end int64) ([]Operation, error) {
+	sql := selectOperation.
+		Where(sq.GtOrEq{"hop.id": start}).
+		Where(sq.Lt{"hop.id": end}).
+		OrderBy("hop.id asc")
+
+	var operations []Operation
+	if err := q.Select(ctx, &operations, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select operations")
+	}
+	return operations, nil
+}
+
+// OperationParticipantsInRange loads the participants of the operations with a
+// total order id in [start, end).
+func (q *Q) OperationParticipantsInRange(ctx context.Context, start, end int64) ([]OperationParticipant, error) {
+	sql := sq.Select("hopp.history_operation_id", "ha.address").
+		From("history_operation_participants hopp").
+		Join("history_accounts ha ON ha.id = hopp.history_account_id").
+		Where(sq.GtOrEq{"hopp.history_operation_id": start}).
+		Where(sq.Lt{"hopp.history_operation_id": end})
+
+	var participants []OperationParticipant
+	if err := q.Select(ctx, &participants, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select operation participants")
+	}
+	return participants, nil
+}
+
+// InsertColdTransactions indexes transactions exported to cold storage.
+func (q *Q) InsertColdTransactions(ctx context.Context, rows []ColdTransaction, batchSize int) error {
+	builder := &db.BatchInsertBuilder{
+		Table:        q.GetTable("history_cold_transactions"),
+		MaxBatchSize: batchSize,
+		Suffix:       "ON CONFLICT (transaction_hash) DO NOTHING",
+	}
+	for _, row := range rows {
+		if err := builder.RowStruct(ctx, row); err != nil {
+			return errors.Wrap(err, "could not insert history_cold_transactions row")
+		}
+	}
+	return errors.Wrap(builder.Exec(ctx), "could not exec history_cold_transactions insert builder")
+}
+
+// InsertColdAccountPartitions indexes the cold storage partitions accounts
+// participated in.
+func (q *Q) InsertColdAccountPartitions(ctx context.Context, rows []ColdAccountPartition, batchSize int) error {
+	builder := &db.BatchInsertBuilder{
+		Table:        q.GetTable("history_cold_account_partitions"),
+		MaxBatchSize: batchSize,
+		Suffix:       "ON CONFLICT (address, partition) DO NOTHING",
+	}
+	for _, row := range rows {
+		if err := builder.RowStruct(ctx, row); err != nil {
+			return errors.Wrap(err, "could not insert history_cold_account_partitions row")
+		}
+	}
+	return errors.Wrap(builder.Exec(ctx), "could not exec history_cold_account_partitions insert builder")
+}
+
+// ColdTransactionLedger returns the sequence of the ledger a transaction
+// exported to cold storage was included in. It returns sql.ErrNoRows if the
+// transaction was never exported.
+func (q *Q) ColdTransactionLedger(ctx context.Context, hash string) (int32, error) {
+	sql := sq.Select("ledger_sequence").
+		From("history_cold_transactions").
+		Where(sq.Eq{"transaction_hash": hash})
+
+	var seq int32
+	err := q.Get(ctx, &seq, sql)
+	return seq, err
+}
+
+// ColdAccountPartitions returns up to limit cold storage partitions an account
+// participated in, starting from the given partition inclusive and in the
+// given order.
+func (q *Q) ColdAccountPartitions(ctx context.Context, address string, from int32, order string, limit uint64) ([]int32, error) {
+	sql := sq.Select("partition").
+		From("history_cold_account_partitions").
+		Where(sq.Eq{"address": address}).
+		Limit(limit)
+	if order == db2.OrderDescending {
+		sql = sql.Where(sq.LtOrEq{"partition": from}).OrderBy("partition desc")
+	} else {
+		sql = sql.Where(sq.GtOrEq{"partition": from}).OrderBy("partition asc")
+	}
+
+	var partitions []int32
+	if err := q.Select(ctx, &partitions, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select cold account partitions")
+	}
+	return partitions, nil
+}
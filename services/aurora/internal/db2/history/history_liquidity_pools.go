@@ -14,6 +14,7 @@ type QHistoryLiquidityPools interface {
 	CreateHistoryLiquidityPools(ctx context.Context, poolIDs []string, batchSize int) (map[string]int64, error)
 	NewOperationLiquidityPoolBatchInsertBuilder(maxBatchSize int) OperationLiquidityPoolBatchInsertBuilder
 	NewTransactionLiquidityPoolBatchInsertBuilder(maxBatchSize int) TransactionLiquidityPoolBatchInsertBuilder
+	NewLiquidityPoolSnapshotBatchInsertBuilder(maxBatchSize int) LiquidityPoolSnapshotBatchInsertBuilder
 }
 
 // CreateHistoryLiquidityPools creates rows in the history_liquidity_pools table for a given list of ids.
@@ -0,0 +1,210 @@
+package history
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+)
+
+const (
+	// HistoryPartitionSize is the number of ledgers of the history table
+	// partitions created by aurora. Partitions are aligned on multiples of
+	// HistoryPartitionSize.
+	HistoryPartitionSize = 100_000
+	// historyPartitionLockID is the key of the transaction advisory lock
+	// serializing the changes to the history table partitions.
+	historyPartitionLockID = 2023
+)
+
+// partitionedHistoryTables maps the history tables partitioned by ledger range
+// to their partition key, a TOID column.
+var partitionedHistoryTables = map[string]string{
+	"history_effects":      "history_operation_id",
+	"history_operations":   "id",
+	"history_trades":       "history_operation_id",
+	"history_transactions": "id",
+}
+
+// HistoryPartition is a row of data from the `history_partitions` table. It
+// is the range of ledgers [StartLedger, EndLedger) of a partition of every
+// partitioned history table.
+type HistoryPartition struct {
+	StartLedger uint32 `db:"start_ledger"`
+	EndLedger   uint32 `db:"end_ledger"`
+}
+
+// Legacy returns true for the partition holding the history ingested before
+// the history tables were partitioned.
+func (p HistoryPartition) Legacy() bool {
+	return p.StartLedger == 0
+}
+
+// Contains returns true if the partition holds the rows of the given ledger.
+func (p HistoryPartition) Contains(ledger uint32) bool {
+	return p.StartLedger <= ledger && ledger < p.EndLedger
+}
+
+func historyPartitionName(table string, startLedger uint32) string {
+	return fmt.Sprintf("%s_p%d", table, startLedger)
+}
+
+func ledgerTOID(ledger uint32) int64 {
+	return toid.New(int32(ledger), 0, 0).ToInt64()
+}
+
+// GetHistoryPartitions returns the partitions of the history tables ordered
+// by ledger. It returns no partitions when the history tables are not
+// partitioned, including in databases loaded from dumps predating the
+// history_partitions table.
+func (q *Q) GetHistoryPartitions(ctx context.Context) ([]HistoryPartition, error) {
+	var exists bool
+	err := q.GetRaw(ctx, &exists, "SELECT to_regclass('history_partitions') IS NOT NULL")
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	var partitions []HistoryPartition
+	err = q.Select(ctx, &partitions, sq.Select("start_ledger, end_ledger").
+		From("history_partitions").
+		OrderBy("start_ledger"))
+	return partitions, err
+}
+
+func (q *Q) lockHistoryPartitions(ctx context.Context) error {
+	_, err := q.ExecRaw(ctx, "SELECT pg_advisory_xact_lock(?)", historyPartitionLockID)
+	return errors.Wrap(err, "could not lock history partitions")
+}
+
+// CreateHistoryPartitions creates the missing partitions of the history tables
+// holding the ledgers between fromLedger and toLedger (inclusive) and returns
+// them. The rows of these ledgers are moved out of the default partitions.
+// It must be called in a transaction, the partitions cannot be changed
+// concurrently until the transaction ends. Nothing is created when the history
+// tables are not partitioned.
+func (q *Q) CreateHistoryPartitions(ctx context.Context, fromLedger, toLedger uint32) ([]HistoryPartition, error) {
+	if err := q.lockHistoryPartitions(ctx); err != nil {
+		return nil, err
+	}
+
+	existing, err := q.GetHistoryPartitions(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load history partitions")
+	}
+	if len(existing) == 0 {
+		return nil, nil
+	}
+
+	missing := missingHistoryPartitions(existing, fromLedger, toLedger, HistoryPartitionSize)
+	for _, partition := range missing {
+		if err = q.createHistoryPartition(ctx, partition); err != nil {
+			return nil, errors.Wrapf(
+				err, "could not create history partition [%d, %d)",
+				partition.StartLedger, partition.EndLedger,
+			)
+		}
+	}
+	return missing, nil
+}
+
+func (q *Q) createHistoryPartition(ctx context.Context, partition HistoryPartition) error {
+	start, end := ledgerTOID(partition.StartLedger), ledgerTOID(partition.EndLedger)
+	for table, column := range partitionedHistoryTables {
+		name := historyPartitionName(table, partition.StartLedger)
+		// The default partition cannot hold rows of the new partition when
+		// it is attached.
+		for _, sql := range []string{
+			fmt.Sprintf(
+				"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE)",
+				name, table,
+			),
+			fmt.Sprintf(
+				"WITH moved AS (DELETE FROM %s_default WHERE %s >= %d AND %s < %d RETURNING *) "+
+					"INSERT INTO %s SELECT * FROM moved",
+				table, column, start, column, end, name,
+			),
+			fmt.Sprintf(
+				"ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%d) TO (%d)",
+				table, name, start, end,
+			),
+		} {
+			if _, err := q.ExecRaw(ctx, sql); err != nil {
+				return errors.Wrapf(err, "could not create %s", name)
+			}
+		}
+	}
+
+	_, err := q.Exec(ctx, sq.Insert("history_partitions").
+		Columns("start_ledger", "end_ledger").
+		Values(partition.StartLedger, partition.EndLedger))
+	return err
+}
+
+// DropHistoryPartition drops the given partition of the history tables. The
+// legacy partition cannot be dropped, its rows are deleted instead. It must be
+// called in a transaction.
+func (q *Q) DropHistoryPartition(ctx context.Context, partition HistoryPartition) error {
+	if partition.Legacy() {
+		return errors.New("the legacy history partition cannot be dropped")
+	}
+	if err := q.lockHistoryPartitions(ctx); err != nil {
+		return err
+	}
+
+	for table := range partitionedHistoryTables {
+		name := historyPartitionName(table, partition.StartLedger)
+		if _, err := q.ExecRaw(ctx, "DROP TABLE IF EXISTS "+name); err != nil {
+			return errors.Wrapf(err, "could not drop %s", name)
+		}
+	}
+
+	_, err := q.Exec(ctx, sq.Delete("history_partitions").
+		Where(sq.Eq{"start_ledger": partition.StartLedger}))
+	return errors.Wrap(err, "could not delete history partition")
+}
+
+// missingHistoryPartitions returns the partitions to create so that the
+// ledgers between fromLedger and toLedger (inclusive) belong to a partition,
+// given the existing partitions ordered by ledger. New partitions are aligned
+// on multiples of size and are clipped by the existing partitions.
+func missingHistoryPartitions(existing []HistoryPartition, fromLedger, toLedger, size uint32) []HistoryPartition {
+	var missing []HistoryPartition
+	for ledger := fromLedger; ledger <= toLedger; {
+		var (
+			previousEnd uint32
+			nextStart   uint32
+			covered     bool
+		)
+		for _, partition := range existing {
+			if partition.Contains(ledger) {
+				ledger = partition.EndLedger
+				covered = true
+				break
+			}
+			if partition.EndLedger <= ledger && partition.EndLedger > previousEnd {
+				previousEnd = partition.EndLedger
+			}
+			if partition.StartLedger > ledger && nextStart == 0 {
+				nextStart = partition.StartLedger
+			}
+		}
+		if covered {
+			continue
+		}
+
+		start := ledger - ledger%size
+		if start < previousEnd {
+			start = previousEnd
+		}
+		end := start - start%size + size
+		if nextStart > 0 && nextStart < end {
+			end = nextStart
+		}
+		missing = append(missing, HistoryPartition{StartLedger: start, EndLedger: end})
+		ledger = end
+	}
+	return missing
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/diamcircle/go/services/aurora/internal/test"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/xdr"
+	"github.com/guregu/null"
+)
+
+func TestMissingHistoryPartitions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+
+	legacy := HistoryPartition{StartLedger: 0, EndLedger: 250}
+	for _, testCase := range []struct {
+		name     string
+		existing []HistoryPartition
+		from, to uint32
+		expected []HistoryPartition
+	}{
+		{
+			name:     "covered by the legacy partition",
+			existing: []HistoryPartition{legacy},
+			from:     10,
+			to:       249,
+		},
+		{
+			name:     "first partition is clipped by the legacy partition",
+			existing: []HistoryPartition{legacy},
+			from:     200,
+			to:       420,
+			expected: []HistoryPartition{{250, 300}, {300, 400}, {400, 500}},
+		},
+		{
+			name:     "aligned partition after a gap",
+			existing: []HistoryPartition{legacy},
+			from:     1234,
+			to:       1234,
+			expected: []HistoryPartition{{1200, 1300}},
+		},
+		{
+			name:     "gap between existing partitions",
+			existing: []HistoryPartition{legacy, {250, 300}, {500, 600}},
+			from:     250,
+			to:       650,
+			expected: []HistoryPartition{{300, 400}, {400, 500}, {600, 700}},
+		},
+		{
+			name:     "last partition is clipped by the next partition",
+			existing: []HistoryPartition{legacy, {350, 400}},
+			from:     300,
+			to:       300,
+			expected: []HistoryPartition{{300, 350}},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			tt.Assert.Equal(
+				testCase.expected,
+				missingHistoryPartitions(testCase.existing, testCase.from, testCase.to, 100),
+			)
+		})
+	}
+}
+
+func TestHistoryPartitions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	partitions, err := q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	if len(partitions) == 0 {
+		t.Skip("history tables are not partitioned")
+	}
+	tt.Assert.Equal([]HistoryPartition{{StartLedger: 0, EndLedger: 1}}, partitions)
+
+	// The operation is stored in the default partition until its partition
+	// is created.
+	opID := toid.New(HistoryPartitionSize+10, 1, 1).ToInt64()
+	builder := q.NewOperationBatchInsertBuilder(1)
+	tt.Assert.NoError(builder.Add(
+		tt.Ctx,
+		opID,
+		toid.New(HistoryPartitionSize+10, 1, 0).ToInt64(),
+		1,
+		xdr.OperationTypeBumpSequence,
+		[]byte("{}"),
+		"GAUJETIZVEP2NRYLUESJ3LS66NVCEGMON4UDCBCSBEVPIID773P2W6AY",
+		null.String{},
+	))
+	tt.Assert.NoError(builder.Exec(tt.Ctx))
+
+	tt.Assert.NoError(q.Begin())
+	created, err := q.CreateHistoryPartitions(tt.Ctx, HistoryPartitionSize-5, HistoryPartitionSize+10)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.Equal([]HistoryPartition{
+		{StartLedger: 1, EndLedger: HistoryPartitionSize},
+		{StartLedger: HistoryPartitionSize, EndLedger: 2 * HistoryPartitionSize},
+	}, created)
+
+	var count int
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &count, "SELECT COUNT(*) FROM history_operations_default"))
+	tt.Assert.Equal(0, count)
+	tt.Assert.NoError(q.GetRaw(
+		tt.Ctx, &count, "SELECT COUNT(*) FROM history_operations_p100000 WHERE id = ?", opID,
+	))
+	tt.Assert.Equal(1, count)
+
+	// Creating the partitions again is a no-op.
+	tt.Assert.NoError(q.Begin())
+	created, err = q.CreateHistoryPartitions(tt.Ctx, 1, 2*HistoryPartitionSize-1)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.Empty(created)
+
+	tt.Assert.NoError(q.Begin())
+	tt.Assert.Error(q.DropHistoryPartition(tt.Ctx, partitions[0]))
+	tt.Assert.NoError(q.DropHistoryPartition(tt.Ctx, HistoryPartition{StartLedger: HistoryPartitionSize, EndLedger: 2 * HistoryPartitionSize}))
+	tt.Assert.NoError(q.Commit())
+
+	partitions, err = q.GetHistoryPartitions(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{
+		{StartLedger: 0, EndLedger: 1},
+		{StartLedger: 1, EndLedger: HistoryPartitionSize},
+	}, partitions)
+}
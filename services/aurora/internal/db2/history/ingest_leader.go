@@ -0,0 +1,193 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/support/errors"
+)
+
+const (
+	// IngestLeaderLockID is the key of the session advisory lock held by the
+	// leading ingesting instance when leaders are elected with advisory locks.
+	IngestLeaderLockID = 2022
+	// ingestLeaderLeaseName is the name of the ingestion leader lease row.
+	ingestLeaderLeaseName = "ingest"
+	// dbNow is the current time of the database, in UTC. Leases are timed
+	// with the database clock, so that the clocks of the aurora instances do
+	// not need to be in sync.
+	dbNow = "(now() AT TIME ZONE 'UTC')"
+	// dbClockNow is the current time of the database clock, in UTC. Unlike
+	// dbNow, it is not the start time of the current transaction.
+	dbClockNow = "(clock_timestamp() AT TIME ZONE 'UTC')"
+)
+
+// LeaderLease is a row of data from the `ingest_leader_lease` table. The
+// fencing token is incremented every time the lease changes hands.
+type LeaderLease struct {
+	Holder       string    `db:"holder"`
+	FencingToken int64     `db:"fencing_token"`
+	AcquiredAt   time.Time `db:"acquired_at"`
+	RenewedAt    time.Time `db:"renewed_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// AdvisoryLockHolder describes the database session holding an advisory
+// lock.
+type AdvisoryLockHolder struct {
+	PID             int            `db:"pid"`
+	ApplicationName string         `db:"application_name"`
+	ClientAddr      sql.NullString `db:"client_addr"`
+	BackendStart    time.Time      `db:"backend_start"`
+}
+
+const leaderLeaseColumns = "holder, fencing_token, acquired_at, renewed_at, expires_at"
+
+func leaseExpiry(ttl time.Duration) string {
+	return fmt.Sprintf("%s + interval '%d milliseconds'", dbNow, ttl.Milliseconds())
+}
+
+// AcquireLeaderLease acquires the ingestion leader lease for holder if it is
+// free or expired. It returns false if another holder has a valid lease.
+func (q *Q) AcquireLeaderLease(ctx context.Context, holder string, ttl time.Duration) (LeaderLease, bool, error) {
+	sql := fmt.Sprintf(`
+		INSERT INTO ingest_leader_lease (name, holder, fencing_token, acquired_at, renewed_at, expires_at)
+		VALUES (?, ?, 1, %[1]s, %[1]s, %[2]s)
+		ON CONFLICT (name) DO UPDATE SET
+			holder = excluded.holder,
+			fencing_token = ingest_leader_lease.fencing_token + 1,
+			acquired_at = excluded.acquired_at,
+			renewed_at = excluded.renewed_at,
+			expires_at = excluded.expires_at
+		WHERE ingest_leader_lease.expires_at <= %[1]s
+		RETURNING %[3]s`,
+		dbNow, leaseExpiry(ttl), leaderLeaseColumns,
+	)
+
+	var lease LeaderLease
+	err := q.GetRaw(ctx, &lease, sql, ingestLeaderLeaseName, holder)
+	if q.NoRows(err) {
+		return lease, false, nil
+	} else if err != nil {
+		return lease, false, errors.Wrap(err, "could not acquire leader lease")
+	}
+	return lease, true, nil
+}
+
+// RenewLeaderLease extends the ingestion leader lease. It returns false if
+// the lease has expired or changed hands in the meantime.
+func (q *Q) RenewLeaderLease(ctx context.Context, lease LeaderLease, ttl time.Duration) (LeaderLease, bool, error) {
+	sql := fmt.Sprintf(`
+		UPDATE ingest_leader_lease SET renewed_at = %[1]s, expires_at = %[2]s
+		WHERE name = ? AND holder = ? AND fencing_token = ? AND expires_at > %[1]s
+		RETURNING %[3]s`,
+		dbNow, leaseExpiry(ttl), leaderLeaseColumns,
+	)
+
+	var renewed LeaderLease
+	err := q.GetRaw(ctx, &renewed, sql, ingestLeaderLeaseName, lease.Holder, lease.FencingToken)
+	if q.NoRows(err) {
+		return renewed, false, nil
+	} else if err != nil {
+		return renewed, false, errors.Wrap(err, "could not renew leader lease")
+	}
+	return renewed, true, nil
+}
+
+// ReleaseLeaderLease expires the given ingestion leader lease, so that
+// another instance can acquire it without waiting for it to expire.
+func (q *Q) ReleaseLeaderLease(ctx context.Context, lease LeaderLease) error {
+	_, err := q.Exec(ctx, sq.Update("ingest_leader_lease").
+		Set("expires_at", sq.Expr(dbNow)).
+		Where(sq.Eq{
+			"name":          ingestLeaderLeaseName,
+			"holder":        lease.Holder,
+			"fencing_token": lease.FencingToken,
+		}))
+	return errors.Wrap(err, "could not release leader lease")
+}
+
+// ExpireLeaderLease expires the ingestion leader lease, whoever holds it.
+func (q *Q) ExpireLeaderLease(ctx context.Context) error {
+	_, err := q.Exec(ctx, sq.Update("ingest_leader_lease").
+		Set("expires_at", sq.Expr(dbNow)).
+		Where(sq.Eq{"name": ingestLeaderLeaseName}))
+	return errors.Wrap(err, "could not expire leader lease")
+}
+
+// GetLeaderLease returns the ingestion leader lease, which may be expired.
+func (q *Q) GetLeaderLease(ctx context.Context) (LeaderLease, error) {
+	var lease LeaderLease
+	err := q.Get(ctx, &lease, sq.Select(leaderLeaseColumns).
+		From("ingest_leader_lease").
+		Where(sq.Eq{"name": ingestLeaderLeaseName}))
+	return lease, err
+}
+
+// CheckLeaderLease returns true if the ingestion leader lease with the given
+// fencing token is valid. The lease row is locked until the end of the
+// current transaction, so the lease cannot change hands before the
+// transaction commits. The expiry is checked against the database clock, as
+// the transaction may have started long before the lease expired.
+func (q *Q) CheckLeaderLease(ctx context.Context, fencingToken int64) (bool, error) {
+	var valid bool
+	err := q.GetRaw(ctx, &valid, fmt.Sprintf(`
+		SELECT fencing_token = ? AND expires_at > %s FROM ingest_leader_lease
+		WHERE name = ? FOR SHARE`, dbClockNow),
+		fencingToken, ingestLeaderLeaseName,
+	)
+	if q.NoRows(err) {
+		return false, nil
+	}
+	return valid, errors.Wrap(err, "could not check leader lease")
+}
+
+// HoldsAdvisoryLock returns true if the database session with the given
+// process id holds the advisory lock with the given key.
+func (q *Q) HoldsAdvisoryLock(ctx context.Context, lockID int64, pid int) (bool, error) {
+	var held bool
+	err := q.GetRaw(ctx, &held, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_locks
+			WHERE locktype = 'advisory' AND classid = 0 AND objid = ? AND objsubid = 1
+			AND pid = ? AND granted
+		)`,
+		lockID, pid,
+	)
+	return held, errors.Wrap(err, "could not check advisory lock")
+}
+
+// GetAdvisoryLockHolder returns the database session holding the advisory
+// lock with the given key.
+func (q *Q) GetAdvisoryLockHolder(ctx context.Context, lockID int64) (AdvisoryLockHolder, error) {
+	var holder AdvisoryLockHolder
+	err := q.GetRaw(ctx, &holder, `
+		SELECT a.pid, a.application_name, host(a.client_addr) AS client_addr, a.backend_start
+		FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid
+		WHERE l.locktype = 'advisory' AND l.classid = 0 AND l.objid = ? AND l.objsubid = 1
+		AND l.granted`,
+		lockID,
+	)
+	return holder, err
+}
+
+// TerminateAdvisoryLockHolder terminates the database session holding the
+// advisory lock with the given key, releasing the lock. It returns false if
+// the lock is not held.
+func (q *Q) TerminateAdvisoryLockHolder(ctx context.Context, lockID int64) (bool, error) {
+	var terminated bool
+	err := q.GetRaw(ctx, &terminated, `
+		SELECT pg_terminate_backend(pid) FROM pg_locks
+		WHERE locktype = 'advisory' AND classid = 0 AND objid = ? AND objsubid = 1
+		AND granted`,
+		lockID,
+	)
+	if q.NoRows(err) {
+		return false, nil
+	}
+	return terminated, errors.Wrap(err, "could not terminate advisory lock holder")
+}
@@ -0,0 +1,115 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diamcircle/go/services/aurora/internal/test"
+)
+
+func TestLeaderLease(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+	ttl := time.Minute
+
+	lease, acquired, err := q.AcquireLeaderLease(tt.Ctx, "node-a", ttl)
+	tt.Assert.NoError(err)
+	tt.Assert.True(acquired)
+	tt.Assert.Equal("node-a", lease.Holder)
+	tt.Assert.Equal(int64(1), lease.FencingToken)
+	tt.Assert.True(lease.ExpiresAt.After(lease.AcquiredAt))
+
+	// The lease is held by node-a.
+	_, acquired, err = q.AcquireLeaderLease(tt.Ctx, "node-b", ttl)
+	tt.Assert.NoError(err)
+	tt.Assert.False(acquired)
+
+	renewed, ok, err := q.RenewLeaderLease(tt.Ctx, lease, ttl)
+	tt.Assert.NoError(err)
+	tt.Assert.True(ok)
+	tt.Assert.Equal(lease.FencingToken, renewed.FencingToken)
+	tt.Assert.True(renewed.AcquiredAt.Equal(lease.AcquiredAt))
+
+	valid, err := q.CheckLeaderLease(tt.Ctx, lease.FencingToken)
+	tt.Assert.NoError(err)
+	tt.Assert.True(valid)
+
+	// Forced handoff.
+	tt.Assert.NoError(q.ExpireLeaderLease(tt.Ctx))
+	_, ok, err = q.RenewLeaderLease(tt.Ctx, lease, ttl)
+	tt.Assert.NoError(err)
+	tt.Assert.False(ok)
+
+	lease, acquired, err = q.AcquireLeaderLease(tt.Ctx, "node-b", ttl)
+	tt.Assert.NoError(err)
+	tt.Assert.True(acquired)
+	tt.Assert.Equal("node-b", lease.Holder)
+	tt.Assert.Equal(int64(2), lease.FencingToken)
+
+	// node-a is fenced off.
+	valid, err = q.CheckLeaderLease(tt.Ctx, 1)
+	tt.Assert.NoError(err)
+	tt.Assert.False(valid)
+
+	tt.Assert.NoError(q.ReleaseLeaderLease(tt.Ctx, lease))
+	current, err := q.GetLeaderLease(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal("node-b", current.Holder)
+	valid, err = q.CheckLeaderLease(tt.Ctx, lease.FencingToken)
+	tt.Assert.NoError(err)
+	tt.Assert.False(valid)
+}
+
+func TestCheckLeaderLeaseInLongTransaction(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	lease, acquired, err := q.AcquireLeaderLease(tt.Ctx, "node-a", 500*time.Millisecond)
+	tt.Assert.NoError(err)
+	tt.Assert.True(acquired)
+
+	tt.Assert.NoError(q.Begin())
+	defer q.Rollback()
+	valid, err := q.CheckLeaderLease(tt.Ctx, lease.FencingToken)
+	tt.Assert.NoError(err)
+	tt.Assert.True(valid)
+
+	// The lease expires while the transaction is running.
+	time.Sleep(time.Second)
+	valid, err = q.CheckLeaderLease(tt.Ctx, lease.FencingToken)
+	tt.Assert.NoError(err)
+	tt.Assert.False(valid)
+}
+
+func TestAdvisoryLockHolder(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	tt.Assert.NoError(q.Begin())
+	defer q.Rollback()
+
+	_, err := q.GetAdvisoryLockHolder(tt.Ctx, IngestLeaderLockID)
+	tt.Assert.True(q.NoRows(err))
+
+	_, err = q.ExecRaw(tt.Ctx, "SELECT pg_advisory_xact_lock(?)", IngestLeaderLockID)
+	tt.Assert.NoError(err)
+	var pid int
+	tt.Assert.NoError(q.GetRaw(tt.Ctx, &pid, "SELECT pg_backend_pid()"))
+
+	holder, err := q.GetAdvisoryLockHolder(tt.Ctx, IngestLeaderLockID)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(pid, holder.PID)
+
+	held, err := q.HoldsAdvisoryLock(tt.Ctx, IngestLeaderLockID, pid)
+	tt.Assert.NoError(err)
+	tt.Assert.True(held)
+	held, err = q.HoldsAdvisoryLock(tt.Ctx, IngestLeaderLockID, pid+1)
+	tt.Assert.NoError(err)
+	tt.Assert.False(held)
+}
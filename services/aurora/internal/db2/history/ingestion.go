@@ -2,22 +2,236 @@ package history
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/support/errors"
 )
 
+const (
+	// ShadowStateSchema is the schema the state tables are rebuilt in before
+	// being swapped with the live ones.
+	ShadowStateSchema = "aurora_shadow"
+	// retiredStateSchema holds the live state tables while they are swapped
+	// with the shadow ones.
+	retiredStateSchema = "aurora_retired"
+	// liveStateSchema is the schema of the state tables served by the API.
+	liveStateSchema = "public"
+	// shadowStateLockID is the key of the advisory lock held while the shadow
+	// state tables are written to, so that only one aurora instance rebuilds
+	// the state at a time.
+	shadowStateLockID = 2021
+)
+
+// stateTables are the aurora database tables populated by the ingestion
+// system using history archive snapshots.
+var stateTables = []string{
+	"accounts",
+	"accounts_data",
+	"accounts_signers",
+	"claimable_balances",
+	"exp_asset_stats",
+	"liquidity_pools",
+	"offers",
+	"trust_lines",
+}
+
+// shadowKeyValueKeys are the keys of the key value store written by the
+// ingestion of the state tables. The shadow state tables have their own copy
+// of them, which replaces the live one when the tables are swapped.
+var shadowKeyValueKeys = []interface{}{
+	offerCompactionSequence,
+	liquidityPoolCompactionSequence,
+}
+
+// stateTableKeys maps the state tables holding ledger entries to the column
+// identifying the entries.
+var stateTableKeys = map[string]string{
+	"accounts":           "account_id",
+	"accounts_data":      "ledger_key",
+	"claimable_balances": "id",
+	"liquidity_pools":    "id",
+	"offers":             "offerid",
+	"trust_lines":        "ledger_key",
+}
+
+// GetStateTableKeys returns up to limit keys of the ledger entries stored in
+// table, in ascending order, starting after cursor. Deleted offers and
+// liquidity pools are skipped.
+func (q *Q) GetStateTableKeys(ctx context.Context, table, cursor string, limit uint64) ([]string, error) {
+	column, ok := stateTableKeys[table]
+	if !ok {
+		return nil, errors.Errorf("%s is not a state table with ledger entries", table)
+	}
+
+	sql := sq.Select(column + "::text").
+		From(table).
+		OrderBy(column + " ASC").
+		Limit(limit)
+	if cursor != "" {
+		sql = sql.Where(sq.Gt{column: cursor})
+	}
+	if table == "offers" || table == "liquidity_pools" {
+		sql = sql.Where("deleted = ?", false)
+	}
+
+	var keys []string
+	if err := q.Select(ctx, &keys, sql); err != nil {
+		return nil, errors.Wrapf(err, "could not select keys of %s", table)
+	}
+	return keys, nil
+}
+
 // TruncateIngestStateTables clears out ingestion state tables.
 // Ingestion state tables are aurora database tables populated by
 // the ingestion system using history archive snapshots.
 // Any aurora database tables which cannot be populated using
 // history archive snapshots will not be truncated.
 func (q *Q) TruncateIngestStateTables(ctx context.Context) error {
-	return q.TruncateTables(ctx, []string{
-		"accounts",
-		"accounts_data",
-		"accounts_signers",
-		"claimable_balances",
-		"exp_asset_stats",
-		"liquidity_pools",
-		"offers",
-		"trust_lines",
-	})
+	return q.TruncateTables(ctx, stateTables)
+}
+
+// TryLockShadowStateTables acquires the shadow state lock for the current
+// transaction. It returns false if another session holds the lock.
+func (q *Q) TryLockShadowStateTables(ctx context.Context) (bool, error) {
+	var locked bool
+	err := q.GetRaw(ctx, &locked, "SELECT pg_try_advisory_xact_lock(?)", shadowStateLockID)
+	return locked, errors.Wrap(err, "could not acquire shadow state lock")
+}
+
+// CreateShadowStateTables creates empty copies of the state tables, with the
+// same columns, constraints and indexes, in ShadowStateSchema. Shadow tables
+// left over by a previous rebuild are dropped. A key value store holding a
+// copy of the compaction sequences is created too, so that compacting the
+// shadow tables does not change the compaction sequences of the live ones.
+func (q *Q) CreateShadowStateTables(ctx context.Context) error {
+	if err := q.DropShadowStateTables(ctx); err != nil {
+		return err
+	}
+	if _, err := q.ExecRaw(ctx, "CREATE SCHEMA "+ShadowStateSchema); err != nil {
+		return errors.Wrap(err, "could not create shadow schema")
+	}
+	for _, table := range stateTables {
+		_, err := q.ExecRaw(ctx, fmt.Sprintf(
+			"CREATE TABLE %s.%s (LIKE %s.%s INCLUDING ALL)",
+			ShadowStateSchema, table, liveStateSchema, table,
+		))
+		if err != nil {
+			return errors.Wrapf(err, "could not create shadow table %s", table)
+		}
+	}
+
+	_, err := q.ExecRaw(ctx, fmt.Sprintf(
+		"CREATE TABLE %s.key_value_store (LIKE %s.key_value_store INCLUDING ALL)",
+		ShadowStateSchema, liveStateSchema,
+	))
+	if err != nil {
+		return errors.Wrap(err, "could not create shadow key value store")
+	}
+	_, err = q.ExecRaw(ctx, fmt.Sprintf(
+		"INSERT INTO %s.key_value_store SELECT * FROM %s.key_value_store WHERE key IN (?, ?)",
+		ShadowStateSchema, liveStateSchema,
+	), shadowKeyValueKeys...)
+	return errors.Wrap(err, "could not copy compaction sequences to shadow key value store")
+}
+
+// UseShadowStateTables makes the queries of the current transaction read and
+// write the shadow state tables instead of the live ones.
+func (q *Q) UseShadowStateTables(ctx context.Context) error {
+	_, err := q.ExecRaw(ctx, fmt.Sprintf("SET LOCAL search_path TO %s, %s", ShadowStateSchema, liveStateSchema))
+	return errors.Wrap(err, "could not set search path")
+}
+
+// SwapShadowStateTables replaces the live state tables with the shadow ones
+// and drops the previous live tables. The compaction sequences of the shadow
+// tables replace the live ones. The indexes of the shadow tables are
+// renamed after the ones they replace, so migrations can keep referring to
+// them. It must run in a transaction, the swap is visible to other sessions
+// only once the transaction commits.
+func (q *Q) SwapShadowStateTables(ctx context.Context) error {
+	if _, err := q.ExecRaw(ctx, "SET LOCAL search_path TO DEFAULT"); err != nil {
+		return errors.Wrap(err, "could not reset search path")
+	}
+	if _, err := q.ExecRaw(ctx, "CREATE SCHEMA "+retiredStateSchema); err != nil {
+		return errors.Wrap(err, "could not create retired schema")
+	}
+
+	for _, table := range stateTables {
+		live, err := q.stateTableIndexes(ctx, liveStateSchema, table)
+		if err != nil {
+			return err
+		}
+		shadow, err := q.stateTableIndexes(ctx, ShadowStateSchema, table)
+		if err != nil {
+			return err
+		}
+
+		for _, cmd := range []string{
+			fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA %s", liveStateSchema, table, retiredStateSchema),
+			fmt.Sprintf("ALTER TABLE %s.%s SET SCHEMA %s", ShadowStateSchema, table, liveStateSchema),
+		} {
+			if _, err = q.ExecRaw(ctx, cmd); err != nil {
+				return errors.Wrapf(err, "could not swap table %s", table)
+			}
+		}
+
+		for definition, name := range shadow {
+			original, ok := live[definition]
+			if !ok || original == name {
+				continue
+			}
+			_, err = q.ExecRaw(ctx, fmt.Sprintf("ALTER INDEX %s.%s RENAME TO %s", liveStateSchema, name, original))
+			if err != nil {
+				return errors.Wrapf(err, "could not rename index %s", name)
+			}
+		}
+	}
+
+	_, err := q.ExecRaw(ctx, fmt.Sprintf(
+		"INSERT INTO %s.key_value_store SELECT * FROM %s.key_value_store "+
+			"ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value",
+		liveStateSchema, ShadowStateSchema,
+	))
+	if err != nil {
+		return errors.Wrap(err, "could not copy shadow compaction sequences")
+	}
+
+	if _, err := q.ExecRaw(ctx, fmt.Sprintf("DROP SCHEMA %s CASCADE", retiredStateSchema)); err != nil {
+		return errors.Wrap(err, "could not drop retired schema")
+	}
+	return q.DropShadowStateTables(ctx)
+}
+
+// DropShadowStateTables drops the shadow state tables, if any.
+func (q *Q) DropShadowStateTables(ctx context.Context) error {
+	_, err := q.ExecRaw(ctx, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", ShadowStateSchema))
+	return errors.Wrap(err, "could not drop shadow schema")
+}
+
+// stateTableIndexes maps the definitions of the indexes of a table, stripped
+// of their name and schema, to their names.
+func (q *Q) stateTableIndexes(ctx context.Context, schema, table string) (map[string]string, error) {
+	var indexes []struct {
+		Name       string `db:"indexname"`
+		Definition string `db:"indexdef"`
+	}
+	sql := sq.Select("indexname", "indexdef").
+		From("pg_indexes").
+		Where(sq.Eq{"schemaname": schema, "tablename": table})
+	if err := q.Select(ctx, &indexes, sql); err != nil {
+		return nil, errors.Wrapf(err, "could not select indexes of %s.%s", schema, table)
+	}
+
+	byDefinition := map[string]string{}
+	for _, index := range indexes {
+		// CREATE [UNIQUE] INDEX name ON schema.table USING method (columns)
+		definition := index.Definition
+		if i := strings.Index(definition, " USING "); i >= 0 {
+			definition = strings.SplitN(definition, " INDEX ", 2)[0] + definition[i:]
+		}
+		byDefinition[definition] = index.Name
+	}
+	return byDefinition, nil
 }
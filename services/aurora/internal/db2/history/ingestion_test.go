@@ -0,0 +1,97 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/services/aurora/internal/test"
+)
+
+func TestSwapShadowStateTables(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	liveIndexes, err := q.stateTableIndexes(tt.Ctx, liveStateSchema, "accounts")
+	require.NoError(t, err)
+	require.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{account1}))
+	require.NoError(t, q.UpdateOfferCompactionSequence(tt.Ctx, 10))
+
+	require.NoError(t, q.Begin())
+	locked, err := q.TryLockShadowStateTables(tt.Ctx)
+	require.NoError(t, err)
+	assert.True(t, locked)
+	require.NoError(t, q.CreateShadowStateTables(tt.Ctx))
+	require.NoError(t, q.UseShadowStateTables(tt.Ctx))
+	require.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{account2}))
+	seq, err := q.GetOfferCompactionSequence(tt.Ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), seq)
+	_, err = q.CompactOffers(tt.Ctx, 20)
+	require.NoError(t, err)
+	require.NoError(t, q.Commit())
+
+	// The live tables and compaction sequences are left untouched until the
+	// swap.
+	accounts, err := q.GetAccountsByIDs(tt.Ctx, []string{account1.AccountID, account2.AccountID})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, account1.AccountID, accounts[0].AccountID)
+	seq, err = q.GetOfferCompactionSequence(tt.Ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(10), seq)
+
+	require.NoError(t, q.Begin())
+	require.NoError(t, q.SwapShadowStateTables(tt.Ctx))
+	require.NoError(t, q.Commit())
+
+	accounts, err = q.GetAccountsByIDs(tt.Ctx, []string{account1.AccountID, account2.AccountID})
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, account2.AccountID, accounts[0].AccountID)
+	seq, err = q.GetOfferCompactionSequence(tt.Ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(20), seq)
+
+	swappedIndexes, err := q.stateTableIndexes(tt.Ctx, liveStateSchema, "accounts")
+	require.NoError(t, err)
+	assert.Equal(t, liveIndexes, swappedIndexes)
+
+	var schemas int
+	require.NoError(t, q.GetRaw(tt.Ctx, &schemas,
+		"SELECT COUNT(*) FROM information_schema.schemata WHERE schema_name IN (?, ?)",
+		ShadowStateSchema, retiredStateSchema,
+	))
+	assert.Equal(t, 0, schemas)
+}
+
+func TestGetStateTableKeys(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	require.NoError(t, q.UpsertAccounts(tt.Ctx, []AccountEntry{account1, account2}))
+	deletedOffer := threeEurOffer
+	deletedOffer.OfferID = 6
+	deletedOffer.Deleted = true
+	require.NoError(t, q.UpsertOffers(tt.Ctx, []Offer{threeEurOffer, eurOffer, deletedOffer, twoEurOffer}))
+
+	keys, err := q.GetStateTableKeys(tt.Ctx, "offers", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "5"}, keys)
+
+	keys, err = q.GetStateTableKeys(tt.Ctx, "offers", "5", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"50"}, keys)
+
+	keys, err = q.GetStateTableKeys(tt.Ctx, "accounts", "", 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{account1.AccountID, account2.AccountID}, keys)
+
+	_, err = q.GetStateTableKeys(tt.Ctx, "accounts_signers", "", 10)
+	assert.EqualError(t, err, "accounts_signers is not a state table with ledger entries")
+}
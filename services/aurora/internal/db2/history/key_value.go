@@ -16,6 +16,7 @@ const (
 	// to upgrade it in migration files too!
 	lastLedgerKey                   = "exp_ingest_last_ledger"
 	stateInvalid                    = "exp_state_invalid"
+	stateRebuildRequested           = "exp_state_rebuild_requested"
 	offerCompactionSequence         = "offer_compaction_sequence"
 	liquidityPoolCompactionSequence = "liquidity_pool_compaction_sequence"
 )
@@ -126,6 +127,33 @@ func (q *Q) UpdateExpStateInvalid(ctx context.Context, val bool) error {
 	)
 }
 
+// GetStateRebuildRequested returns true if a rebuild of the state in shadow
+// tables was requested. Returns false otherwise.
+func (q *Q) GetStateRebuildRequested(ctx context.Context) (bool, error) {
+	requested, err := q.getValueFromStore(ctx, stateRebuildRequested, false)
+	if err != nil {
+		return false, err
+	}
+
+	if requested == "" {
+		return false, nil
+	}
+	val, err := strconv.ParseBool(requested)
+	if err != nil {
+		return false, errors.Wrap(err, "Error converting rebuild requested value")
+	}
+	return val, nil
+}
+
+// UpdateStateRebuildRequested updates the state rebuild requested value.
+func (q *Q) UpdateStateRebuildRequested(ctx context.Context, val bool) error {
+	return q.updateValueInStore(
+		ctx,
+		stateRebuildRequested,
+		strconv.FormatBool(val),
+	)
+}
+
 // GetOfferCompactionSequence returns the sequence number corresponding to the
 // last time the offers table was compacted.
 func (q *Q) GetOfferCompactionSequence(ctx context.Context) (uint32, error) {
@@ -20,7 +20,7 @@ func (lc *LedgerCache) Queue(seq int32) {
 
 // Load loads a batch of ledgers identified by `sequences`, using `q`,
 // and populates the cache with the results
-func (lc *LedgerCache) Load(ctx context.Context, q *Q) error {
+func (lc *LedgerCache) Load(ctx context.Context, q QInterface) error {
 	lc.lock.Lock()
 	defer lc.lock.Unlock()
 
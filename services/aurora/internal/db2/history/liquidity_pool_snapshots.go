@@ -0,0 +1,239 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+	strtime "github.com/diamcircle/go/support/time"
+	"github.com/diamcircle/go/xdr"
+)
+
+// LiquidityPoolSnapshot is a row of data from the
+// `history_liquidity_pool_snapshots` table. It holds the state of a liquidity
+// pool at the end of a ledger in which the pool was modified.
+type LiquidityPoolSnapshot struct {
+	HistoryLiquidityPoolID int64     `db:"history_liquidity_pool_id"`
+	HistoryLedgerID        int64     `db:"history_ledger_id"`
+	ClosedAt               time.Time `db:"closed_at"`
+	TrustlineCount         uint64    `db:"trustline_count"`
+	ShareCount             uint64    `db:"share_count"`
+	ReserveA               uint64    `db:"reserve_a"`
+	ReserveB               uint64    `db:"reserve_b"`
+}
+
+// LiquidityPoolSnapshotBatchInsertBuilder is used to insert liquidity pool
+// snapshots into the history_liquidity_pool_snapshots table
+type LiquidityPoolSnapshotBatchInsertBuilder interface {
+	Add(ctx context.Context, snapshot LiquidityPoolSnapshot) error
+	Exec(ctx context.Context) error
+}
+
+type liquidityPoolSnapshotBatchInsertBuilder struct {
+	builder db.BatchInsertBuilder
+}
+
+func (q *Q) NewLiquidityPoolSnapshotBatchInsertBuilder(maxBatchSize int) LiquidityPoolSnapshotBatchInsertBuilder {
+	return &liquidityPoolSnapshotBatchInsertBuilder{
+		builder: db.BatchInsertBuilder{
+			Table:        q.GetTable("history_liquidity_pool_snapshots"),
+			MaxBatchSize: maxBatchSize,
+		},
+	}
+}
+
+// Add adds a new liquidity pool snapshot to the batch
+func (i *liquidityPoolSnapshotBatchInsertBuilder) Add(ctx context.Context, snapshot LiquidityPoolSnapshot) error {
+	return i.builder.RowStruct(ctx, snapshot)
+}
+
+// Exec flushes all pending liquidity pool snapshots to the db
+func (i *liquidityPoolSnapshotBatchInsertBuilder) Exec(ctx context.Context) error {
+	return i.builder.Exec(ctx)
+}
+
+// LiquidityPoolStats represents the state of a liquidity pool at the end of a
+// time bucket, along with the volume and the fees of the trades executed
+// against the pool during the bucket. Volumes and fees are split by reserve
+// asset.
+type LiquidityPoolStats struct {
+	Timestamp      int64  `db:"timestamp"`
+	TradeCount     int64  `db:"trade_count"`
+	TrustlineCount uint64 `db:"trustline_count"`
+	ShareCount     uint64 `db:"share_count"`
+	ReserveA       uint64 `db:"reserve_a"`
+	ReserveB       uint64 `db:"reserve_b"`
+	VolumeA        string `db:"volume_a"`
+	VolumeB        string `db:"volume_b"`
+	FeeA           string `db:"fee_a"`
+	FeeB           string `db:"fee_b"`
+}
+
+// LiquidityPoolStatsQuery holds the parameters of a liquidity pool stats
+// query. AssetAID is the history_assets id of the first reserve asset of the
+// pool. Buckets start at ((t - Offset) / Resolution) * Resolution + Offset,
+// in milliseconds, and only buckets within [StartTime, EndTime) are returned.
+type LiquidityPoolStatsQuery struct {
+	LiquidityPoolID int64
+	AssetAID        int64
+	Resolution      int64
+	Offset          int64
+	StartTime       strtime.Millis
+	EndTime         strtime.Millis
+	Order           string
+	Limit           uint64
+}
+
+// GetLiquidityPoolStats returns the stats of a liquidity pool bucketed by
+// time. Buckets in which the pool was not modified are omitted.
+func (q *Q) GetLiquidityPoolStats(ctx context.Context, query LiquidityPoolStatsQuery) ([]LiquidityPoolStats, error) {
+	if query.Resolution <= 0 {
+		return nil, errors.New("resolution is not allowed")
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		return nil, errors.New("invalid order")
+	}
+
+	snapshotBucket := formatMillisBucket("closed_at", query.Resolution, query.Offset)
+	tradeBucket := formatMillisBucket("ledger_closed_at", query.Resolution, query.Offset)
+
+	snapshots := sq.Select(
+		"DISTINCT ON (bucket) "+snapshotBucket+" AS bucket",
+		"trustline_count",
+		"share_count",
+		"reserve_a",
+		"reserve_b",
+	).
+		From("history_liquidity_pool_snapshots").
+		Where(sq.Eq{"history_liquidity_pool_id": query.LiquidityPoolID}).
+		Where(sq.GtOrEq{snapshotBucket: query.StartTime}).
+		OrderBy("bucket", "history_ledger_id DESC")
+
+	// The pool sells the base asset when it is on the base side of the trade
+	// and receives the counter asset, and conversely. Fees are charged on
+	// the amount received by the pool.
+	receivedIsA := fmt.Sprintf(
+		"(CASE WHEN base_liquidity_pool_id = %d THEN counter_asset_id ELSE base_asset_id END) = %d",
+		query.LiquidityPoolID, query.AssetAID,
+	)
+	received := fmt.Sprintf(
+		"(CASE WHEN base_liquidity_pool_id = %d THEN counter_amount ELSE base_amount END)",
+		query.LiquidityPoolID,
+	)
+	trades := sq.Select(
+		tradeBucket+" AS bucket",
+		"count(*) AS trade_count",
+		fmt.Sprintf(
+			"sum(CASE WHEN base_asset_id = %d THEN base_amount ELSE counter_amount END) AS volume_a",
+			query.AssetAID,
+		),
+		fmt.Sprintf(
+			"sum(CASE WHEN base_asset_id = %d THEN counter_amount ELSE base_amount END) AS volume_b",
+			query.AssetAID,
+		),
+		fmt.Sprintf(
+			"sum(CASE WHEN %s THEN %s * liquidity_pool_fee / 10000.0 ELSE 0 END) AS fee_a",
+			receivedIsA, received,
+		),
+		fmt.Sprintf(
+			"sum(CASE WHEN %s THEN 0 ELSE %s * liquidity_pool_fee / 10000.0 END) AS fee_b",
+			receivedIsA, received,
+		),
+	).
+		From("history_trades").
+		Where(sq.Or{
+			sq.Eq{"base_liquidity_pool_id": query.LiquidityPoolID},
+			sq.Eq{"counter_liquidity_pool_id": query.LiquidityPoolID},
+		}).
+		Where(sq.GtOrEq{tradeBucket: query.StartTime}).
+		GroupBy("bucket")
+
+	if !query.EndTime.IsNil() {
+		snapshots = snapshots.Where(sq.Lt{snapshotBucket: query.EndTime})
+		trades = trades.Where(sq.Lt{tradeBucket: query.EndTime})
+	}
+
+	sql := sq.Select(
+		"s.bucket AS timestamp",
+		"COALESCE(t.trade_count, 0) AS trade_count",
+		"s.trustline_count",
+		"s.share_count",
+		"s.reserve_a",
+		"s.reserve_b",
+		"COALESCE(t.volume_a, 0)::text AS volume_a",
+		"COALESCE(t.volume_b, 0)::text AS volume_b",
+		"COALESCE(trunc(t.fee_a), 0)::text AS fee_a",
+		"COALESCE(trunc(t.fee_b), 0)::text AS fee_b",
+	).
+		FromSelect(snapshots, "s").
+		JoinClause(trades.Prefix("LEFT JOIN (").Suffix(") t ON t.bucket = s.bucket")).
+		OrderBy("s.bucket " + query.Order).
+		Limit(query.Limit)
+
+	var stats []LiquidityPoolStats
+	if err := q.Select(ctx, &stats, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select liquidity pool stats")
+	}
+	return stats, nil
+}
+
+// formatMillisBucket formats a sql expression which rounds the given
+// timestamp column, in milliseconds, down to its bucket. Given a time t, it
+// gives it a timestamp defined by
+// f(t) = ((t - offset)/resolution)*resolution + offset.
+func formatMillisBucket(column string, resolution, offset int64) string {
+	return fmt.Sprintf("((to_millis(%s) - %d) / %d) * %d + %d", column, offset, resolution, resolution, offset)
+}
+
+// LiquidityPoolIDsForAccount returns a page of the ids of the liquidity pools
+// in which an account holds pool shares, using the pool id as cursor.
+func (q *Q) LiquidityPoolIDsForAccount(ctx context.Context, accountID string, page db2.PageQuery) ([]string, error) {
+	sql := sq.Select("liquidity_pool_id").
+		From("trust_lines").
+		Where(sq.Eq{
+			"account_id": accountID,
+			"asset_type": xdr.AssetTypeAssetTypePoolShare,
+		}).
+		Where("balance > 0")
+	sql, err := page.ApplyToUsingCursor(sql, "liquidity_pool_id", page.Cursor)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not apply query to page")
+	}
+
+	var ids []string
+	if err := q.Select(ctx, &ids, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select liquidity pool ids")
+	}
+	return ids, nil
+}
+
+// LiquidityPoolEffectsForAccount returns the liquidity pool deposit,
+// withdrawal and revocation effects of an account in the given pools, in
+// ascending order.
+func (q *Q) LiquidityPoolEffectsForAccount(ctx context.Context, accountID string, poolIDs []string) ([]Effect, error) {
+	var account Account
+	if err := q.AccountByAddress(ctx, &account, accountID); err != nil {
+		return nil, err
+	}
+
+	sql := selectEffect.
+		Where("heff.history_account_id = ?", account.ID).
+		Where(sq.Eq{"heff.type": []EffectType{
+			EffectLiquidityPoolDeposited,
+			EffectLiquidityPoolWithdrew,
+			EffectLiquidityPoolRevoked,
+		}}).
+		Where(sq.Eq{"heff.details->'liquidity_pool'->>'id'": poolIDs}).
+		OrderBy("heff.history_operation_id asc, heff.order asc")
+
+	var effects []Effect
+	if err := q.Select(ctx, &effects, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select liquidity pool effects")
+	}
+	return effects, nil
+}
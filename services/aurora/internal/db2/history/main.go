@@ -274,8 +274,25 @@ type IngestionQ interface {
 	GetLatestHistoryLedger(context.Context) (uint32, error)
 	GetOfferCompactionSequence(context.Context) (uint32, error)
 	GetLiquidityPoolCompactionSequence(context.Context) (uint32, error)
+	GetStateRebuildRequested(context.Context) (bool, error)
+	GetStateTableKeys(ctx context.Context, table, cursor string, limit uint64) ([]string, error)
+	UpdateStateRebuildRequested(context.Context, bool) error
 	TruncateIngestStateTables(context.Context) error
+	TryLockShadowStateTables(context.Context) (bool, error)
+	CreateShadowStateTables(context.Context) error
+	UseShadowStateTables(context.Context) error
+	SwapShadowStateTables(context.Context) error
+	DropShadowStateTables(context.Context) error
 	DeleteRangeAll(ctx context.Context, start, end int64) error
+	InsertStateVerificationReport(
+		ctx context.Context,
+		report StateVerificationReport,
+		mismatches []StateVerificationMismatch,
+		batchSize int,
+	) (int64, error)
+	DeleteStateVerificationReportsBefore(ctx context.Context, id int64) error
+	CheckLeaderLease(ctx context.Context, fencingToken int64) (bool, error)
+	HoldsAdvisoryLock(ctx context.Context, lockID int64, pid int) (bool, error)
 }
 
 // QAccounts defines account related queries.
@@ -652,6 +669,25 @@ type Q struct {
 	db.SessionInterface
 }
 
+// QInterface defines the state and ledger queries aurora can serve from
+// storage drivers other than the PostgreSQL database, like the embedded store
+// of package db2/embedded. Missing rows are reported with sql.ErrNoRows.
+type QInterface interface {
+	GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error)
+	LedgerBySequence(ctx context.Context, dest interface{}, seq int32) error
+	LedgersBySequence(ctx context.Context, dest interface{}, seqs ...int32) error
+	GetAccountByID(ctx context.Context, id string) (AccountEntry, error)
+	GetAccountDataByAccountID(ctx context.Context, id string) ([]Data, error)
+	GetAccountDataByName(ctx context.Context, id, name string) (Data, error)
+	GetAccountSignersByAccountID(ctx context.Context, id string) ([]AccountSigner, error)
+	GetSortedTrustLinesByAccountID(ctx context.Context, id string) ([]TrustLine, error)
+	GetOfferByID(ctx context.Context, id int64) (Offer, error)
+	GetOffers(ctx context.Context, query OffersQuery) ([]Offer, error)
+	NoRows(err error) bool
+}
+
+var _ QInterface = (*Q)(nil)
+
 // QSigners defines signer related queries.
 type QSigners interface {
 	GetLastLedgerIngestNonBlocking(ctx context.Context) (uint32, error)
@@ -811,10 +847,13 @@ func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
 		"history_ledgers":                        "id",
 		"history_operation_claimable_balances":   "history_operation_id",
 		"history_operation_participants":         "history_operation_id",
+		"history_liquidity_pool_snapshots":       "history_ledger_id",
 		"history_operation_liquidity_pools":      "history_operation_id",
 		"history_operations":                     "id",
 		"history_trades":                         "history_operation_id",
 		"history_trades_60000":                   "open_ledger_toid",
+		"history_trades_3600000":                 "open_ledger_toid",
+		"history_trades_86400000":                "open_ledger_toid",
 		"history_transaction_claimable_balances": "history_transaction_id",
 		"history_transaction_participants":       "history_transaction_id",
 		"history_transaction_liquidity_pools":    "history_transaction_id",
@@ -58,3 +58,25 @@ func (m *MockOperationLiquidityPoolBatchInsertBuilder) Exec(ctx context.Context)
 	a := m.Called(ctx)
 	return a.Error(0)
 }
+
+// NewLiquidityPoolSnapshotBatchInsertBuilder mock
+func (m *MockQHistoryLiquidityPools) NewLiquidityPoolSnapshotBatchInsertBuilder(maxBatchSize int) LiquidityPoolSnapshotBatchInsertBuilder {
+	a := m.Called(maxBatchSize)
+	return a.Get(0).(LiquidityPoolSnapshotBatchInsertBuilder)
+}
+
+// MockLiquidityPoolSnapshotBatchInsertBuilder is a mock implementation of the
+// LiquidityPoolSnapshotBatchInsertBuilder interface
+type MockLiquidityPoolSnapshotBatchInsertBuilder struct {
+	mock.Mock
+}
+
+func (m *MockLiquidityPoolSnapshotBatchInsertBuilder) Add(ctx context.Context, snapshot LiquidityPoolSnapshot) error {
+	a := m.Called(ctx, snapshot)
+	return a.Error(0)
+}
+
+func (m *MockLiquidityPoolSnapshotBatchInsertBuilder) Exec(ctx context.Context) error {
+	a := m.Called(ctx)
+	return a.Error(0)
+}
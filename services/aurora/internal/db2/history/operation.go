@@ -238,13 +238,7 @@ func (q *OperationsQ) ForTransaction(ctx context.Context, hash string) *Operatio
 // are in the "payment" class of operations:  CreateAccountOps, Payments, and
 // PathPayments.
 func (q *OperationsQ) OnlyPayments() *OperationsQ {
-	q.sql = q.sql.Where(sq.Eq{"hop.type": []xdr.OperationType{
-		xdr.OperationTypeCreateAccount,
-		xdr.OperationTypePayment,
-		xdr.OperationTypePathPaymentStrictReceive,
-		xdr.OperationTypePathPaymentStrictSend,
-		xdr.OperationTypeAccountMerge,
-	}})
+	q.sql = q.sql.Where(sq.Eq{"hop.type": PaymentOperationTypes})
 	return q
 }
 
@@ -377,6 +371,16 @@ func validateTransactionForOperation(transaction Transaction, operation Operatio
 	return nil
 }
 
+// PaymentOperationTypes are the types of the operations returned by the
+// payments end-points.
+var PaymentOperationTypes = []xdr.OperationType{
+	xdr.OperationTypeCreateAccount,
+	xdr.OperationTypePayment,
+	xdr.OperationTypePathPaymentStrictReceive,
+	xdr.OperationTypePathPaymentStrictSend,
+	xdr.OperationTypeAccountMerge,
+}
+
 // QOperations defines history_operation related queries.
 type QOperations interface {
 	NewOperationBatchInsertBuilder(maxBatchSize int) OperationBatchInsertBuilder
@@ -0,0 +1,148 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// StateVerificationReport is a row of data from the
+// `state_verification_reports` table. It is the result of a verification of
+// the state tables against the history archive state at LedgerSequence.
+type StateVerificationReport struct {
+	ID             int64       `db:"id"`
+	LedgerSequence uint32      `db:"ledger_sequence"`
+	CreatedAt      time.Time   `db:"created_at"`
+	Valid          bool        `db:"valid"`
+	Error          null.String `db:"error"`
+	Entries        int64       `db:"entries"`
+	Mismatches     int32       `db:"mismatches"`
+}
+
+// PagingToken returns a cursor for this report.
+func (r StateVerificationReport) PagingToken() string {
+	return fmt.Sprintf("%d", r.ID)
+}
+
+// StateVerificationMismatch is a row of data from the
+// `state_verification_mismatches` table. The ledger key and entries are
+// base64 encoded XDR. ExpectedEntry is null if the entry is missing from the
+// history archive state, ActualEntry if it is missing from the database.
+type StateVerificationMismatch struct {
+	ReportID           int64               `db:"report_id"`
+	LedgerKey          string              `db:"ledger_key"`
+	EntryType          xdr.LedgerEntryType `db:"entry_type"`
+	Processor          string              `db:"processor"`
+	LastModifiedLedger null.Int            `db:"last_modified_ledger"`
+	ExpectedEntry      null.String         `db:"expected_entry"`
+	ActualEntry        null.String         `db:"actual_entry"`
+}
+
+var selectStateVerificationReport = sq.Select(
+	"id",
+	"ledger_sequence",
+	"created_at",
+	"valid",
+	"error",
+	"entries",
+	"mismatches",
+).From("state_verification_reports")
+
+// InsertStateVerificationReport inserts a state verification report and its
+// mismatches and returns the id of the report.
+func (q *Q) InsertStateVerificationReport(
+	ctx context.Context,
+	report StateVerificationReport,
+	mismatches []StateVerificationMismatch,
+	batchSize int,
+) (int64, error) {
+	sql := sq.Insert("state_verification_reports").
+		SetMap(map[string]interface{}{
+			"ledger_sequence": report.LedgerSequence,
+			"created_at":      report.CreatedAt,
+			"valid":           report.Valid,
+			"error":           report.Error,
+			"entries":         report.Entries,
+			"mismatches":      report.Mismatches,
+		}).
+		Suffix("RETURNING id")
+
+	var id int64
+	if err := q.Get(ctx, &id, sql); err != nil {
+		return 0, errors.Wrap(err, "could not insert state verification report")
+	}
+
+	builder := &db.BatchInsertBuilder{
+		Table:        q.GetTable("state_verification_mismatches"),
+		MaxBatchSize: batchSize,
+	}
+	for _, mismatch := range mismatches {
+		mismatch.ReportID = id
+		if err := builder.RowStruct(ctx, mismatch); err != nil {
+			return 0, errors.Wrap(err, "could not insert state_verification_mismatches row")
+		}
+	}
+	if err := builder.Exec(ctx); err != nil {
+		return 0, errors.Wrap(err, "could not exec state_verification_mismatches insert builder")
+	}
+	return id, nil
+}
+
+// DeleteStateVerificationReportsBefore deletes the state verification
+// reports, and their mismatches, with an id lower than the given one.
+func (q *Q) DeleteStateVerificationReportsBefore(ctx context.Context, id int64) error {
+	_, err := q.Exec(ctx, sq.Delete("state_verification_reports").Where(sq.Lt{"id": id}))
+	return errors.Wrap(err, "could not delete state verification reports")
+}
+
+// StateVerificationReports loads a page of state verification reports.
+func (q *Q) StateVerificationReports(ctx context.Context, page db2.PageQuery) ([]StateVerificationReport, error) {
+	sql, err := page.ApplyTo(selectStateVerificationReport, "id")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not apply query to page")
+	}
+
+	var reports []StateVerificationReport
+	if err := q.Select(ctx, &reports, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select state verification reports")
+	}
+	return reports, nil
+}
+
+// StateVerificationReportByID loads a state verification report.
+func (q *Q) StateVerificationReportByID(ctx context.Context, id int64) (StateVerificationReport, error) {
+	var report StateVerificationReport
+	err := q.Get(ctx, &report, selectStateVerificationReport.Where(sq.Eq{"id": id}))
+	return report, err
+}
+
+// StateVerificationMismatches loads the mismatches of a state verification
+// report.
+func (q *Q) StateVerificationMismatches(ctx context.Context, reportID int64) ([]StateVerificationMismatch, error) {
+	sql := sq.Select(
+		"report_id",
+		"ledger_key",
+		"entry_type",
+		"processor",
+		"last_modified_ledger",
+		"expected_entry",
+		"actual_entry",
+	).
+		From("state_verification_mismatches").
+		Where(sq.Eq{"report_id": reportID}).
+		OrderBy("entry_type asc", "ledger_key asc")
+
+	var mismatches []StateVerificationMismatch
+	if err := q.Select(ctx, &mismatches, sql); err != nil {
+		return nil, errors.Wrap(err, "could not select state verification mismatches")
+	}
+	return mismatches, nil
+}
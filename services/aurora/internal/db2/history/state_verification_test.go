@@ -0,0 +1,83 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2"
+	"github.com/diamcircle/go/services/aurora/internal/test"
+	"github.com/diamcircle/go/xdr"
+)
+
+func TestStateVerificationReports(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	valid := StateVerificationReport{
+		LedgerSequence: 63,
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+		Valid:          true,
+		Entries:        10,
+	}
+	validID, err := q.InsertStateVerificationReport(tt.Ctx, valid, nil, 10)
+	require.NoError(t, err)
+
+	invalid := StateVerificationReport{
+		LedgerSequence: 127,
+		CreatedAt:      time.Now().UTC().Truncate(time.Second),
+		Error:          null.StringFrom("2 entries do not match"),
+		Entries:        10,
+		Mismatches:     2,
+	}
+	mismatches := []StateVerificationMismatch{
+		{
+			LedgerKey:          "b",
+			EntryType:          xdr.LedgerEntryTypeOffer,
+			Processor:          "OffersProcessor",
+			LastModifiedLedger: null.IntFrom(100),
+			ActualEntry:        null.StringFrom("actual"),
+		},
+		{
+			LedgerKey:     "a",
+			EntryType:     xdr.LedgerEntryTypeAccount,
+			Processor:     "AccountsProcessor",
+			ExpectedEntry: null.StringFrom("expected"),
+		},
+	}
+	invalidID, err := q.InsertStateVerificationReport(tt.Ctx, invalid, mismatches, 10)
+	require.NoError(t, err)
+
+	reports, err := q.StateVerificationReports(tt.Ctx, db2.PageQuery{Order: db2.OrderDescending, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	invalid.ID = invalidID
+	assert.True(t, invalid.CreatedAt.Equal(reports[0].CreatedAt))
+	reports[0].CreatedAt = invalid.CreatedAt
+	assert.Equal(t, invalid, reports[0])
+	assert.Equal(t, validID, reports[1].ID)
+
+	report, err := q.StateVerificationReportByID(tt.Ctx, invalidID)
+	require.NoError(t, err)
+	assert.Equal(t, invalidID, report.ID)
+	assert.Equal(t, invalid.Error, report.Error)
+
+	loaded, err := q.StateVerificationMismatches(tt.Ctx, invalidID)
+	require.NoError(t, err)
+	mismatches[0].ReportID = invalidID
+	mismatches[1].ReportID = invalidID
+	assert.Equal(t, []StateVerificationMismatch{mismatches[1], mismatches[0]}, loaded)
+
+	require.NoError(t, q.DeleteStateVerificationReportsBefore(tt.Ctx, invalidID+1))
+	reports, err = q.StateVerificationReports(tt.Ctx, db2.PageQuery{Order: db2.OrderAscending, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, reports)
+	loaded, err = q.StateVerificationMismatches(tt.Ctx, invalidID)
+	require.NoError(t, err)
+	assert.Empty(t, loaded)
+}
@@ -25,9 +25,58 @@ var AllowedResolutions = map[time.Duration]struct{}{
 }
 
 // StrictResolutionFiltering represents a simple feature flag to determine whether only
-// predetermined resolutions of trade aggregations are allowed.
+// predetermined resolutions of trade aggregations, and their multiples, are allowed.
 var StrictResolutionFiltering = true
 
+// RollupResolutions are the resolutions, in milliseconds, of the trade
+// aggregation tables maintained at ingestion time, in ascending order. Each
+// table is named history_trades_<resolution>. The 1 minute table is built
+// from history_trades and every other table from the previous one. Buckets
+// of all the tables start at a multiple of their resolution.
+var RollupResolutions = []int64{60000, 3600000, 86400000}
+
+// ValidResolution returns true if trade aggregations are allowed for the
+// given resolution. Unless StrictResolutionFiltering is disabled the
+// resolution must be a multiple of one of the AllowedResolutions.
+func ValidResolution(resolution time.Duration) bool {
+	if resolution <= 0 {
+		return false
+	}
+	if !StrictResolutionFiltering {
+		return true
+	}
+	for allowed := range AllowedResolutions {
+		if resolution%allowed == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidOffset returns true if trade aggregations are allowed with the given
+// offset and resolution. The offset must be a multiple of a minute, less than
+// or equal to the resolution and less than 24 hours.
+func ValidOffset(offset, resolution time.Duration) bool {
+	return offset%time.Minute == 0 && offset < time.Hour*24 && offset <= resolution
+}
+
+// rollupTable returns the coarsest trade aggregation table whose buckets can
+// be combined into buckets of the given resolution and offset, and its
+// resolution.
+func rollupTable(resolution, offset int64) (string, int64) {
+	table, tableResolution := rollupTableName(RollupResolutions[0]), RollupResolutions[0]
+	for _, r := range RollupResolutions[1:] {
+		if resolution%r == 0 && offset%r == 0 {
+			table, tableResolution = rollupTableName(r), r
+		}
+	}
+	return table, tableResolution
+}
+
+func rollupTableName(resolution int64) string {
+	return fmt.Sprintf("history_trades_%d", resolution)
+}
+
 // TradeAggregation represents an aggregation of trades from the trades table
 type TradeAggregation struct {
 	Timestamp     int64   `db:"timestamp"`
@@ -43,6 +92,10 @@ type TradeAggregation struct {
 	OpenD         int64   `db:"open_d"`
 	CloseN        int64   `db:"close_n"`
 	CloseD        int64   `db:"close_d"`
+	// LiquidityPoolBaseVolume and LiquidityPoolCounterVolume are the parts
+	// of BaseVolume and CounterVolume traded with liquidity pools.
+	LiquidityPoolBaseVolume    string `db:"liquidity_pool_base_volume"`
+	LiquidityPoolCounterVolume string `db:"liquidity_pool_counter_volume"`
 }
 
 // TradeAggregationsQ is a helper struct to aid in configuring queries to
@@ -66,14 +119,12 @@ func (q Q) GetTradeAggregationsQ(baseAssetID int64, counterAssetID int64, resolu
 	offsetDuration := time.Duration(offset) * time.Millisecond
 
 	//check if resolution allowed
-	if StrictResolutionFiltering {
-		if _, ok := AllowedResolutions[resolutionDuration]; !ok {
-			return &TradeAggregationsQ{}, errors.New("resolution is not allowed")
-		}
+	if !ValidResolution(resolutionDuration) {
+		return &TradeAggregationsQ{}, errors.New("resolution is not allowed")
 	}
-	// check if offset is allowed. Offset must be 1) a multiple of an hour 2) less than the resolution and 3)
+	// check if offset is allowed. Offset must be 1) a multiple of a minute 2) less than the resolution and 3)
 	// less than 24 hours
-	if offsetDuration%time.Hour != 0 || offsetDuration >= time.Hour*24 || offsetDuration > resolutionDuration {
+	if !ValidOffset(offsetDuration, resolutionDuration) {
 		return &TradeAggregationsQ{}, errors.New("offset is not allowed.")
 	}
 
@@ -135,7 +186,8 @@ func (q *TradeAggregationsQ) GetSql() sq.SelectBuilder {
 		bucketSQL = reverseBucketTrades(q.resolution, q.offset)
 	}
 
-	bucketSQL = bucketSQL.From("history_trades_60000").
+	table, tableResolution := rollupTable(q.resolution, q.offset)
+	bucketSQL = bucketSQL.From(table).
 		Where(sq.Eq{"base_asset_id": q.baseAssetID, "counter_asset_id": q.counterAssetID})
 
 	//adjust time range and apply time filters
@@ -144,7 +196,7 @@ func (q *TradeAggregationsQ) GetSql() sq.SelectBuilder {
 		bucketSQL = bucketSQL.Where(sq.Lt{"timestamp": q.endTime})
 	}
 
-	if q.resolution != 60000 {
+	if q.resolution != tableResolution {
 		//ensure open/close order for cases when multiple trades occur in the same ledger
 		bucketSQL = bucketSQL.OrderBy("timestamp ASC", "open_ledger_toid ASC")
 		// Do on-the-fly aggregation for higher resolutions.
@@ -180,6 +232,8 @@ func bucketTrades(resolution int64, offset int64) sq.SelectBuilder {
 		"open_d",
 		"close_n",
 		"close_d",
+		"liquidity_pool_base_volume",
+		"liquidity_pool_counter_volume",
 	)
 }
 
@@ -200,6 +254,8 @@ func reverseBucketTrades(resolution int64, offset int64) sq.SelectBuilder {
 		"open_d as open_n",
 		"close_n as close_d",
 		"close_d as close_n",
+		"liquidity_pool_base_volume as liquidity_pool_counter_volume",
+		"liquidity_pool_counter_volume as liquidity_pool_base_volume",
 	)
 }
 
@@ -218,12 +274,39 @@ func aggregate(query sq.SelectBuilder) sq.SelectBuilder {
 		"(first(ARRAY[open_n, open_d]))[2] as open_d",
 		"(last(ARRAY[close_n, close_d]))[1] as close_n",
 		"(last(ARRAY[close_n, close_d]))[2] as close_d",
+		"sum(liquidity_pool_base_volume) as liquidity_pool_base_volume",
+		"sum(liquidity_pool_counter_volume) as liquidity_pool_counter_volume",
 	).FromSelect(query, "htrd").GroupBy("timestamp")
 }
 
+// tradeAggregationColumns are the columns of the trade aggregation tables, in
+// the order they are inserted by the rebuild queries.
+var tradeAggregationColumns = []string{
+	"timestamp",
+	"base_asset_id",
+	"counter_asset_id",
+	"count",
+	"base_volume",
+	"counter_volume",
+	"avg",
+	"high_n",
+	"high_d",
+	"low_n",
+	"low_d",
+	"open_ledger_toid",
+	"open_n",
+	"open_d",
+	"close_ledger_toid",
+	"close_n",
+	"close_d",
+	"liquidity_pool_base_volume",
+	"liquidity_pool_counter_volume",
+}
+
 // RebuildTradeAggregationTimes rebuilds a specific set of trade aggregation
 // buckets, (specified by start and end times) to ensure complete data in case
-// of partial reingestion.
+// of partial reingestion. The buckets of the rollup tables containing the
+// times are rebuilt as well.
 func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Millis) error {
 	from = from.RoundDown(60_000)
 	to = to.RoundDown(60_000)
@@ -247,6 +330,7 @@ func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Mi
 		"counter_asset_id",
 		"counter_amount",
 		"ARRAY[price_n, price_d] as price",
+		"trade_type",
 	).From("history_trades").Where(
 		sq.GtOrEq{"to_millis(ledger_closed_at, 60000)": from},
 	).Where(
@@ -272,13 +356,101 @@ func (q Q) RebuildTradeAggregationTimes(ctx context.Context, from, to strtime.Mi
 		"last(history_operation_id) as close_ledger_toid",
 		"(last(price))[1] as close_n",
 		"(last(price))[2] as close_d",
+		fmt.Sprintf(
+			"coalesce(sum(base_amount) FILTER (WHERE trade_type = %d), 0) as liquidity_pool_base_volume",
+			LiquidityPoolTradeType,
+		),
+		fmt.Sprintf(
+			"coalesce(sum(counter_amount) FILTER (WHERE trade_type = %d), 0) as liquidity_pool_counter_volume",
+			LiquidityPoolTradeType,
+		),
 	).FromSelect(trades, "trades").GroupBy("base_asset_id", "counter_asset_id", "timestamp")
 
 	// Insert the new bucket values.
-	_, err = q.Exec(ctx, sq.Insert("history_trades_60000").Select(rebuilt))
+	_, err = q.Exec(ctx, sq.Insert("history_trades_60000").Columns(tradeAggregationColumns...).Select(rebuilt))
 	if err != nil {
 		return errors.Wrap(err, "could not rebuild trade aggregation bucket")
 	}
+
+	for i := 1; i < len(RollupResolutions); i++ {
+		err = q.rebuildRollupTimes(ctx, RollupResolutions[i-1], RollupResolutions[i], from, to)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildRollupTimes rebuilds the buckets of the `resolution` trade
+// aggregation table containing the times between from and to (inclusive)
+// from the buckets of the finer `source` table.
+func (q Q) rebuildRollupTimes(ctx context.Context, source, resolution int64, from, to strtime.Millis) error {
+	from = from.RoundDown(resolution)
+	to = to.RoundDown(resolution)
+	table := rollupTableName(resolution)
+
+	_, err := q.Exec(ctx, sq.Delete(table).Where(
+		sq.GtOrEq{"timestamp": from},
+	).Where(
+		sq.LtOrEq{"timestamp": to},
+	))
+	if err != nil {
+		return errors.Wrapf(err, "could not rebuild %s bucket", table)
+	}
+
+	// open_ledger_toid keeps the buckets of the source table in
+	// chronological order within a rolled up bucket
+	buckets := sq.Select(
+		formatBucketTimestampSelect(resolution, 0),
+		"base_asset_id",
+		"counter_asset_id",
+		"count",
+		"base_volume",
+		"counter_volume",
+		"high_n",
+		"high_d",
+		"low_n",
+		"low_d",
+		"open_ledger_toid",
+		"open_n",
+		"open_d",
+		"close_ledger_toid",
+		"close_n",
+		"close_d",
+		"liquidity_pool_base_volume",
+		"liquidity_pool_counter_volume",
+	).From(rollupTableName(source)).Where(
+		sq.GtOrEq{"timestamp": from},
+	).Where(
+		sq.Lt{"timestamp": to + strtime.MillisFromInt64(resolution)},
+	).OrderBy("base_asset_id", "counter_asset_id", "open_ledger_toid")
+
+	rebuilt := sq.Select(
+		"timestamp",
+		"base_asset_id",
+		"counter_asset_id",
+		"sum(\"count\") as count",
+		"sum(base_volume) as base_volume",
+		"sum(counter_volume) as counter_volume",
+		"sum(counter_volume::numeric)/sum(base_volume::numeric) as avg",
+		"(max_price(ARRAY[high_n, high_d]))[1] as high_n",
+		"(max_price(ARRAY[high_n, high_d]))[2] as high_d",
+		"(min_price(ARRAY[low_n, low_d]))[1] as low_n",
+		"(min_price(ARRAY[low_n, low_d]))[2] as low_d",
+		"first(open_ledger_toid) as open_ledger_toid",
+		"(first(ARRAY[open_n, open_d]))[1] as open_n",
+		"(first(ARRAY[open_n, open_d]))[2] as open_d",
+		"last(close_ledger_toid) as close_ledger_toid",
+		"(last(ARRAY[close_n, close_d]))[1] as close_n",
+		"(last(ARRAY[close_n, close_d]))[2] as close_d",
+		"sum(liquidity_pool_base_volume) as liquidity_pool_base_volume",
+		"sum(liquidity_pool_counter_volume) as liquidity_pool_counter_volume",
+	).FromSelect(buckets, "buckets").GroupBy("base_asset_id", "counter_asset_id", "timestamp")
+
+	_, err = q.Exec(ctx, sq.Insert(table).Columns(tradeAggregationColumns...).Select(rebuilt))
+	if err != nil {
+		return errors.Wrapf(err, "could not rebuild %s bucket", table)
+	}
 	return nil
 }
 
@@ -2,10 +2,12 @@ package history
 
 import (
 	"github.com/diamcircle/go/xdr"
+	"strconv"
 	"testing"
 
 	"github.com/diamcircle/go/services/aurora/internal/db2"
 	"github.com/diamcircle/go/services/aurora/internal/test"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
 )
 
 var (
@@ -240,3 +242,83 @@ func TestTradesForReverseAssetPair(t *testing.T) {
 		}
 	}
 }
+
+func TestPriceTrades(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+	fixtures := TradeScenario(tt, q)
+	eurAsset := xdr.MustNewCreditAsset("EUR", issuer.Address())
+	chfAsset := xdr.MustNewCreditAsset("CHF", "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU")
+	expected := fixtures.TradesByAssetPair(eurAsset, chfAsset)
+	tt.Assert.NotEmpty(expected)
+
+	eurID, err := q.GetAssetID(tt.Ctx, eurAsset)
+	tt.Assert.NoError(err)
+	chfID, err := q.GetAssetID(tt.Ctx, chfAsset)
+	tt.Assert.NoError(err)
+
+	// the last trade of each ledger
+	var lastOfLedger []Trade
+	for _, trade := range expected {
+		if trade.BaseAmount == 0 {
+			continue
+		}
+		n := len(lastOfLedger)
+		if n > 0 && toid.Parse(lastOfLedger[n-1].HistoryOperationID).LedgerSequence ==
+			toid.Parse(trade.HistoryOperationID).LedgerSequence {
+			lastOfLedger[n-1] = trade
+		} else {
+			lastOfLedger = append(lastOfLedger, trade)
+		}
+	}
+
+	trades, err := q.GetLedgerPriceTrades(tt.Ctx, chfID, eurID, 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(trades, len(lastOfLedger))
+	for i, trade := range trades {
+		tt.Assert.Equal(lastOfLedger[i].HistoryOperationID, trade.HistoryOperationID)
+		tt.Assert.Equal(lastOfLedger[i].BaseAmount, trade.BaseAmount)
+		tt.Assert.Equal(lastOfLedger[i].CounterAmount, trade.CounterAmount)
+	}
+
+	reversed, err := q.GetLedgerPriceTrades(tt.Ctx, eurID, chfID, 0)
+	tt.Assert.NoError(err)
+	for _, trade := range reversed {
+		tt.Assert.NotZero(trade.BaseAmount)
+	}
+
+	var baseVolume, counterVolume int64
+	for _, trade := range expected {
+		baseVolume += trade.BaseAmount
+		counterVolume += trade.CounterAmount
+	}
+	summary, err := q.GetPriceTradeSummary(tt.Ctx, chfID, eurID, 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(len(expected)), summary.TradeCount)
+	tt.Assert.Equal(strconv.FormatInt(baseVolume, 10), summary.BaseVolume)
+	tt.Assert.Equal(strconv.FormatInt(counterVolume, 10), summary.CounterVolume)
+	tt.Assert.NotNil(summary.LastTradeAt)
+
+	summary, err = q.GetPriceTradeSummary(tt.Ctx, eurID, chfID, 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(strconv.FormatInt(counterVolume, 10), summary.BaseVolume)
+	tt.Assert.Equal(strconv.FormatInt(baseVolume, 10), summary.CounterVolume)
+
+	last := trades[len(trades)-1]
+	trade, err := q.GetLastPriceTrade(tt.Ctx, chfID, eurID, last.LedgerSequence()+1)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(last.HistoryOperationID, trade.HistoryOperationID)
+
+	_, err = q.GetLastPriceTrade(tt.Ctx, chfID, eurID, trades[0].LedgerSequence())
+	tt.Assert.True(q.NoRows(err))
+
+	// trades in which none of the base asset was exchanged are skipped
+	if len(reversed) > 0 {
+		trade, err = q.GetLastPriceTrade(tt.Ctx, eurID, chfID, reversed[len(reversed)-1].LedgerSequence()+1)
+		tt.Assert.NoError(err)
+		tt.Assert.Equal(reversed[len(reversed)-1].HistoryOperationID, trade.HistoryOperationID)
+		tt.Assert.NotZero(trade.BaseAmount)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/guregu/null"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/diamcircle/go/services/aurora/internal/db2"
 	"github.com/diamcircle/go/services/aurora/internal/test"
 	"github.com/diamcircle/go/xdr"
 )
@@ -273,3 +274,29 @@ func TestAssetsForAddress(t *testing.T) {
 	tt.Assert.NoError(err)
 	tt.Assert.Equal(eurTrustLine, records[0])
 }
+
+func TestLiquidityPoolIDsForAccount(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{tt.AuroraSession()}
+
+	otherPool := poolShareTrustLine
+	otherPool.LedgerKey = "mlmn909"
+	otherPool.LiquidityPoolID = "apolnbv"
+	emptyPool := poolShareTrustLine
+	emptyPool.LedgerKey = "mlmn910"
+	emptyPool.LiquidityPoolID = "zpolnbv"
+	emptyPool.Balance = 0
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []TrustLine{eurTrustLine, poolShareTrustLine, otherPool, emptyPool}))
+
+	page := db2.PageQuery{Order: db2.OrderAscending, Limit: 10}
+	ids, err := q.LiquidityPoolIDsForAccount(tt.Ctx, poolShareTrustLine.AccountID, page)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]string{"apolnbv", "mpolnbv"}, ids)
+
+	page = db2.PageQuery{Order: db2.OrderDescending, Limit: 1, Cursor: "mpolnbv"}
+	ids, err = q.LiquidityPoolIDsForAccount(tt.Ctx, poolShareTrustLine.AccountID, page)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]string{"apolnbv"}, ids)
+}
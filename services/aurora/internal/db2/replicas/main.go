@@ -0,0 +1,201 @@
+// Package replicas routes the reads of aurora requests to the read replicas
+// of the aurora database. The last ledger ingested into every replica is
+// tracked, so that requests which need a ledger-consistent view of the
+// database are only sent to replicas which have caught up with the primary
+// database.
+package replicas
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/log"
+)
+
+// PrimaryName is the name of the primary database in the pool.
+const PrimaryName = "primary"
+
+// Replica is a database requests can be routed to.
+type Replica struct {
+	Name    string
+	Session db.SessionInterface
+}
+
+type replicaState struct {
+	Replica
+	lastIngestedLedger uint32
+	healthy            bool
+}
+
+// Pool routes requests between the primary database and its read replicas.
+// Requests are spread across the replicas which have ingested the last ledger
+// ingested into the primary database and fall back to the primary database
+// when no replica has caught up.
+type Pool struct {
+	primary  Replica
+	replicas []*replicaState
+	next     uint32
+
+	mutex                     sync.RWMutex
+	primaryLastIngestedLedger uint32
+
+	lastIngestedLedgerGauge *prometheus.GaugeVec
+	healthyGauge            *prometheus.GaugeVec
+	selectedCounter         *prometheus.CounterVec
+}
+
+// NewPool returns a pool routing requests between the given primary database
+// and read replicas. Replicas are not used until their last ingested ledger
+// has been loaded by Update.
+func NewPool(primary db.SessionInterface, replicas []Replica) *Pool {
+	p := &Pool{
+		primary: Replica{Name: PrimaryName, Session: primary},
+		lastIngestedLedgerGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "aurora", Subsystem: "db", Name: "replica_last_ingested_ledger",
+				Help: "last ledger ingested into the database, as seen by the replica router",
+			},
+			[]string{"replica"},
+		),
+		healthyGauge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "aurora", Subsystem: "db", Name: "replica_healthy",
+				Help: "1 if the read replica could be queried during the last update, 0 otherwise",
+			},
+			[]string{"replica"},
+		),
+		selectedCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "db", Name: "replica_requests_total",
+				Help: "number of requests routed to the database",
+			},
+			[]string{"replica"},
+		),
+	}
+	for _, replica := range replicas {
+		p.replicas = append(p.replicas, &replicaState{Replica: replica})
+	}
+	return p
+}
+
+// RegisterMetrics registers the prometheus metrics of the pool.
+func (p *Pool) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(p.lastIngestedLedgerGauge)
+	registry.MustRegister(p.healthyGauge)
+	registry.MustRegister(p.selectedCounter)
+}
+
+// Primary returns the primary database.
+func (p *Pool) Primary() Replica {
+	return p.primary
+}
+
+// PrimaryLastIngestedLedger returns the last ledger ingested into the primary
+// database, as of the last update.
+func (p *Pool) PrimaryLastIngestedLedger() uint32 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.primaryLastIngestedLedger
+}
+
+// Update loads the last ledger ingested into the primary database and into
+// every replica. Replicas which cannot be queried are not used until the next
+// successful update.
+func (p *Pool) Update(ctx context.Context) {
+	var wg sync.WaitGroup
+	results := make([]uint32, len(p.replicas))
+	errs := make([]error, len(p.replicas))
+	for i, replica := range p.replicas {
+		wg.Add(1)
+		go func(i int, replica *replicaState) {
+			defer wg.Done()
+			results[i], errs[i] = lastIngestedLedger(ctx, replica.Session)
+		}(i, replica)
+	}
+	primaryLedger, primaryErr := lastIngestedLedger(ctx, p.primary.Session)
+	wg.Wait()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if primaryErr != nil {
+		log.WithField("err", primaryErr.Error()).
+			Error("failed to load the last ingested ledger of the primary database")
+	} else {
+		p.primaryLastIngestedLedger = primaryLedger
+		p.lastIngestedLedgerGauge.WithLabelValues(p.primary.Name).Set(float64(primaryLedger))
+	}
+
+	for i, replica := range p.replicas {
+		replica.healthy = errs[i] == nil
+		if !replica.healthy {
+			log.WithField("replica", replica.Name).WithField("err", errs[i].Error()).
+				Warn("failed to load the last ingested ledger of the read replica")
+			p.healthyGauge.WithLabelValues(replica.Name).Set(0)
+			continue
+		}
+		replica.lastIngestedLedger = results[i]
+		p.healthyGauge.WithLabelValues(replica.Name).Set(1)
+		p.lastIngestedLedgerGauge.WithLabelValues(replica.Name).Set(float64(results[i]))
+	}
+}
+
+func lastIngestedLedger(ctx context.Context, session db.SessionInterface) (uint32, error) {
+	q := &history.Q{session.Clone()}
+	return q.GetLastLedgerIngestNonBlocking(ctx)
+}
+
+// Select returns a healthy replica which has ingested the last ledger ingested
+// into the primary database, or the primary database if there is none.
+// Replicas are selected in a round-robin fashion.
+func (p *Pool) Select() Replica {
+	selected := p.selectReplica()
+	p.selectedCounter.WithLabelValues(selected.Name).Inc()
+	return selected
+}
+
+func (p *Pool) selectReplica() Replica {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	n := len(p.replicas)
+	if n == 0 {
+		return p.primary
+	}
+	start := int(atomic.AddUint32(&p.next, 1) % uint32(n))
+	for i := 0; i < n; i++ {
+		replica := p.replicas[(start+i)%n]
+		if replica.healthy && replica.lastIngestedLedger >= p.primaryLastIngestedLedger {
+			return replica.Replica
+		}
+	}
+	return p.primary
+}
+
+// CaughtUp returns true if a database whose last ingested ledger is
+// lastIngestedLedger has caught up with the primary database. When it has
+// not, the replica with the given name is not selected until the next update
+// reports it has caught up.
+func (p *Pool) CaughtUp(name string, lastIngestedLedger uint32) bool {
+	if name == PrimaryName {
+		return true
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if lastIngestedLedger >= p.primaryLastIngestedLedger {
+		return true
+	}
+	for _, replica := range p.replicas {
+		if replica.Name == name && replica.lastIngestedLedger > lastIngestedLedger {
+			replica.lastIngestedLedger = lastIngestedLedger
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package replicas
+
+import (
+	"testing"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/test"
+)
+
+func TestPoolSelect(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+	tt.Assert.NoError(q.UpdateLastLedgerIngest(tt.Ctx, 100))
+
+	pool := NewPool(tt.AuroraSession(), []Replica{
+		{Name: "replica_0", Session: tt.AuroraSession()},
+		{Name: "replica_1", Session: tt.AuroraSession()},
+	})
+
+	// Replicas are not used before their state is loaded.
+	tt.Assert.Equal(PrimaryName, pool.Select().Name)
+
+	pool.Update(tt.Ctx)
+	tt.Assert.Equal(uint32(100), pool.PrimaryLastIngestedLedger())
+	selected := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		selected[pool.Select().Name] = true
+	}
+	tt.Assert.Equal(map[string]bool{"replica_0": true, "replica_1": true}, selected)
+
+	// replica_0 turns out to be behind the primary database.
+	tt.Assert.False(pool.CaughtUp("replica_0", 99))
+	tt.Assert.True(pool.CaughtUp("replica_1", 100))
+	tt.Assert.True(pool.CaughtUp(PrimaryName, 0))
+	for i := 0; i < 4; i++ {
+		tt.Assert.Equal("replica_1", pool.Select().Name)
+	}
+
+	tt.Assert.False(pool.CaughtUp("replica_1", 99))
+	tt.Assert.Equal(PrimaryName, pool.Select().Name)
+
+	// Both replicas have caught up by the next update.
+	pool.Update(tt.Ctx)
+	selected = map[string]bool{}
+	for i := 0; i < 4; i++ {
+		selected[pool.Select().Name] = true
+	}
+	tt.Assert.Equal(map[string]bool{"replica_0": true, "replica_1": true}, selected)
+}
+
+func TestPoolWithoutReplicas(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	pool := NewPool(tt.AuroraSession(), nil)
+	pool.Update(tt.Ctx)
+	tt.Assert.Equal(PrimaryName, pool.Select().Name)
+}
@@ -48,6 +48,12 @@
 // migrations/50_liquidity_pools.sql (3.876kB)
 // migrations/51_remove_ht_unused_indexes.sql (321B)
 // migrations/52_add_trade_type_index.sql (424B)
+// migrations/53_trade_aggregation_rollups.sql (4.299kB)
+// migrations/54_liquidity_pool_snapshots.sql (705B)
+// migrations/55_cold_storage_indexes.sql (467B)
+// migrations/56_state_verification_reports.sql (768B)
+// migrations/57_ingest_leader_lease.sql (416B)
+// migrations/58_partition_history_tables.sql (4.701kB)
 // migrations/5_create_trades_table.sql (1.1kB)
 // migrations/6_create_assets_table.sql (366B)
 // migrations/7_modify_trades_table.sql (2.303kB)
@@ -1082,6 +1088,126 @@ func migrations52_add_trade_type_indexSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations53_trade_aggregation_rollupsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xed\x58\x59\x6f\x9b\x40\x10\x7e\xe7\x57\xcc\x23\xb4\x76\x0e\xb7\x8a\xaa\x58\x7d\x20\x81\x24\x56\x89\x1d\x61\xac\x28\x8a\x22\x44\x0c\xb6\x57\x01\x96\xc2\x3a\x89\xff\x7d\x97\x5d\x8e\x05\xaf\x8f\xf4\x78\xaa\xf3\xe0\xe0\xd9\xf9\xe6\xd8\xf9\x66\xc6\x76\xb7\x0b\x9f\x23\x34\x4f\x3d\x12\xc0\x24\x81\x18\x93\xd4\x8b\x33\x6f\x4a\x10\x8e\x15\x45\xb7\x1c\xd3\x06\x47\xbf\xb0\x4c\x58\xa0\x8c\xe0\x74\xe5\x52\x05\x3f\xc8\xdc\xb3\x13\xfa\xa7\x00\xe8\x86\x01\x21\xfa\xb9\x44\x3e\x22\x2b\x37\xc1\x38\x74\x9f\xbd\x2c\x70\x5f\x71\xb8\x8c\x02\x88\xe9\x4b\x8a\xa6\x30\x1c\x39\x30\x9c\x58\x16\x18\xe6\x95\x3e\xb1\x1c\x38\xe9\xc8\xc1\x53\xbc\x8c\x49\x90\xee\xc6\xf7\x15\xa5\xdb\x85\x0b\x6f\xfa\x32\x43\x61\x08\x64\x11\xd4\xa6\x20\x37\x05\xdc\x44\x06\x78\xc6\x4e\x83\x77\x9a\x01\x8a\xe7\xf0\xbc\x9c\xbe\x04\x24\x3b\x52\x26\x77\x86\xee\xc8\x33\x03\x7d\x0c\x0b\x92\xfa\x30\x36\x1d\x1a\xe8\x96\x0c\xbf\x43\x98\x1c\x09\x82\xce\xba\x7a\x2b\x27\x86\x68\xca\x94\x2b\x7b\x74\x0b\x2a\x85\x8e\x4d\xcb\xbc\xcc\x5d\x02\x10\xec\x46\x34\x35\x94\xa9\x61\xe0\xcf\xa9\xee\x34\xc4\x59\xe0\xbb\x1e\xe9\x00\x0b\x52\x03\x2f\x03\x82\x68\x8e\xc4\x8b\x92\x0e\x03\xb1\x48\xbc\x2c\x0b\x88\x8b\x7c\x2e\x2a\x5d\x35\xa5\xd9\x32\x52\xb9\x72\x94\x9f\x33\x5b\xad\x34\xb8\x52\x05\xaf\xf5\x5a\xc1\x03\xb0\xf0\x9b\xf7\x48\xa5\xf7\x37\xa6\x6d\x02\x7b\xeb\x92\x55\x92\x67\xde\xa3\xe2\x6b\x7b\x34\xb9\x83\x8b\x87\x56\xac\xeb\x71\xd6\xb9\x29\x5a\x5e\x90\x30\x51\xb8\xc9\xbc\x32\x47\xd5\x21\xbf\xd0\x5a\x97\xf2\x6a\x68\x70\x9d\x86\x07\xa1\x54\xa5\x48\xd4\x6d\xbb\x6f\xd6\xa9\x94\x52\xda\x5d\xda\x66\x4e\x1b\x69\x5b\x7c\x61\x85\x39\x01\xd5\x1a\xfc\xd8\xc0\xac\xc1\xf0\xd2\x9a\x18\x83\xe1\x35\xe8\x96\xa5\xf5\xb7\x9a\xfb\x76\xf6\xf5\xc3\xf6\xd6\xda\x62\x81\x97\x69\xb8\x2a\x69\x0f\xb3\x14\x47\x4c\x7e\x0a\x11\x8a\x97\xb4\xf1\xab\x86\xb8\x1f\x38\x37\x95\x1e\xbd\xf0\x16\x21\xd5\xfa\xca\x8f\xa1\xc8\x54\x83\x4f\xe5\xe3\x9f\xb0\x91\x49\x05\x8c\x48\xc2\x26\xdb\xb8\x6c\x81\xe6\x0b\x37\x16\x9e\x0b\x3b\x21\x7e\x2b\xc5\xf9\x63\x21\xc5\x49\x10\xbb\x45\x13\x11\x8c\x44\x69\x2c\x3c\x97\xb1\xe4\x6d\xb6\xae\xce\xc5\xb1\xf8\xa6\x74\xba\x71\x3a\x48\xcf\xf7\xe9\x9e\x6a\xbe\x8e\x6c\x83\x8e\xe0\x7d\x9a\xa5\x9d\xa4\xa2\x51\xf8\x60\x38\x36\x6d\x87\xfe\x73\x46\x1b\xa9\xca\x62\x14\xca\x0c\xed\x2a\x4a\xeb\xb8\xa9\x92\xc2\xd0\xa8\x67\x85\x78\x24\x5c\x8f\x7c\xe8\x34\xc7\x8e\xa0\x29\x23\x82\x4c\xf9\xfc\xbc\x58\x1b\xda\x71\xcb\x61\x7d\x92\xdb\xf3\x5e\xe7\xa5\x11\x35\xf2\xde\xdd\x84\x1e\x04\xaa\x6e\xdb\xfa\xc3\x63\x41\xb0\x82\x5c\x4f\x9a\xf6\x78\xfa\x94\x63\x44\xe2\xed\x05\xeb\xd5\x30\xbf\x86\xa1\xb8\x01\xe3\xb4\xe5\x94\xad\x7d\x09\x64\xde\x03\xd3\xab\x30\x95\x9f\x19\x4a\x33\xa2\xb6\x89\xc1\x72\x97\xb7\x04\x75\xc3\x31\xdc\x45\xd1\x21\x45\x77\xd4\x81\x89\x9d\xb3\x13\xd2\xab\x21\x95\x97\xd0\xa3\x80\xb5\x3e\xe3\x45\x96\x77\x1f\x75\xc3\x40\xdc\x4b\xd9\x8b\x65\x1f\xd6\xa1\x35\xba\x74\x37\xa8\x27\x80\x1a\x04\xde\xdc\xd3\x2c\xca\x5d\x2d\x2f\x35\x22\xa1\xf4\xae\xd1\x50\x0c\x87\x62\x26\x33\xc1\xef\xac\x4f\x00\xd9\x62\xf0\x3d\x24\xdb\x0b\xcd\x7d\xf1\xb1\xad\x50\x2e\xac\x7c\x2d\x54\xcb\xeb\xb0\x17\xfe\xde\x5e\x28\xa6\xf6\x3f\xdd\x0c\xf5\xa7\x8e\xc3\x6a\x38\xac\x86\xc3\x6a\xf8\x3f\x56\x43\xf5\x3b\x80\x81\xdf\xe8\x77\x7f\xc3\x1e\xdd\x6d\xff\x52\xd2\xdf\xa2\x53\x8c\xa9\xfe\x3e\x3f\x21\x30\x2b\xdb\x6f\x4d\xa6\xd2\xbc\x90\xbe\xf2\x0b\x5d\x07\x04\xe1\xcb\x10\x00\x00")
+
+func migrations53_trade_aggregation_rollupsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations53_trade_aggregation_rollupsSql,
+		"migrations/53_trade_aggregation_rollups.sql",
+	)
+}
+
+func migrations53_trade_aggregation_rollupsSql() (*asset, error) {
+	bytes, err := migrations53_trade_aggregation_rollupsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/53_trade_aggregation_rollups.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x71, 0xc3, 0x99, 0xd1, 0x73, 0xdd, 0x62, 0x45, 0x74, 0x72, 0x6d, 0x0a, 0x65, 0xcb, 0x29, 0x0e, 0xc4, 0xb9, 0x77, 0x44, 0x36, 0x4a, 0x24, 0x6f, 0x8c, 0x44, 0xf0, 0xa7, 0x1d, 0x7c, 0xfe, 0xb2}}
+	return a, nil
+}
+
+var _migrations54_liquidity_pool_snapshotsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\x92\xc1\x4e\xc3\x30\x0c\x86\xef\x79\x0a\x1f\x37\xb1\x3e\xc1\x4e\x83\x56\xa8\x52\x95\xc2\x68\x25\x6e\x51\xda\x58\xad\xa5\x36\xe9\x12\x97\x31\x9e\x9e\x4a\x20\x18\x83\x6e\x80\x8f\xf6\x1f\xeb\xd3\x17\x47\x11\x5c\xf5\xd4\x78\xcd\x08\xe5\x20\xc4\xcd\x36\xd9\x14\x09\x14\x9b\xeb\x2c\x81\x96\x02\x3b\x7f\x50\x1d\xed\x46\x32\xc4\x07\x35\x38\xd7\xa9\x60\xf5\x10\x5a\xc7\x01\x16\x02\xa6\x9a\x89\x91\x81\x8a\x1a\xb2\x0c\x32\x2f\x40\x96\x59\xb6\xfa\x1a\x47\xd3\xa0\x9f\x8d\xd5\x9d\x0b\x68\x94\x66\x60\xea\x31\xb0\xee\x07\xd8\x13\xb7\x6e\x7c\xeb\xc0\x8b\xb3\x78\xf2\x86\xfd\x18\xb8\x23\x8b\xaa\x76\xe3\xb4\xf1\xc7\xc5\xa1\xd5\xfe\x6c\xc0\x63\x40\xff\x84\x4a\x9f\x1f\x57\xa7\x63\xb1\x5c\x7f\x08\x2c\x65\x7a\x5f\x26\x90\xca\x38\x79\x04\xb2\x06\x9f\xd5\x25\x9b\xca\xd9\x49\x46\x80\x5c\x5e\x16\x5f\x3e\xa4\xf2\x16\x2a\xf6\x88\xb0\x98\xf5\xbf\xfa\xee\x7a\x42\x7c\x27\xfc\x2b\xda\xe7\x77\xfd\x1f\xf0\x88\x42\x44\x47\x97\x17\xbb\xbd\x15\x22\xde\xe6\x77\xbf\xbd\xbc\x5a\x87\x5a\x1b\x5c\x8b\x57\xf0\x53\xf1\xe4\xc1\x02\x00\x00")
+
+func migrations54_liquidity_pool_snapshotsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations54_liquidity_pool_snapshotsSql,
+		"migrations/54_liquidity_pool_snapshots.sql",
+	)
+}
+
+func migrations54_liquidity_pool_snapshotsSql() (*asset, error) {
+	bytes, err := migrations54_liquidity_pool_snapshotsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/54_liquidity_pool_snapshots.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x97, 0x5, 0x2c, 0x9b, 0xa9, 0xc4, 0xe7, 0xbd, 0x55, 0x52, 0xd2, 0x92, 0xf2, 0x36, 0xe, 0xcb, 0x15, 0x1d, 0x8a, 0x51, 0x16, 0xa, 0x0, 0x25, 0xb4, 0x2f, 0xd9, 0x42, 0x6b, 0x4e, 0xcb, 0x3d}}
+	return a, nil
+}
+
+var _migrations55_cold_storage_indexesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x90\xbb\x0e\xc2\x30\x0c\x45\xf7\x7c\x85\xc7\x56\xd0\x0d\xb1\x30\x15\xe8\x80\x28\x0f\x55\x65\x60\x8a\xac\xc4\x6a\x23\x41\x02\x4e\x00\xf1\xf7\x54\x80\xa0\x42\xbc\xbc\xd9\xba\xd7\x3e\xbe\x49\x02\x9d\xad\xa9\x18\x03\xc1\x6a\x27\xc4\xa8\xc8\xd2\x32\x83\x32\x1d\xe6\x19\xd4\xc6\x07\xc7\x67\xa9\xdc\x46\xcb\xc0\x68\x3d\xaa\x60\x9c\xf5\x10\x09\x68\xaa\x35\x92\x35\xfa\x1a\x54\x8d\xdc\xf4\xc4\x70\x44\x3e\x1b\x5b\x45\xfd\x5e\x0c\xf3\x45\x09\xf3\x55\x9e\x77\xaf\xa6\x0d\xe9\x8a\x58\x7a\xda\x1f\xc8\x2a\x02\x63\x03\x35\x83\x17\xd5\xb2\x98\xcc\xd2\x62\x0d\xd3\x6c\x0d\xd1\xeb\x9d\x58\xc4\x83\x6f\xa8\xa8\x94\x3b\xd8\x20\x77\xc8\xc1\xb4\x81\x51\x6b\x26\xef\xff\xe2\x7c\x98\xff\x21\xbc\x2f\xee\x3e\x5d\x37\xc6\xa4\x15\xef\xd8\x9d\xac\x10\xe3\x62\xb1\xfc\x19\xaf\x42\xaf\x50\xd3\xe0\xa3\xfa\xcd\x87\x0f\xcf\x05\x13\x16\xbb\x34\xd3\x01\x00\x00")
+
+func migrations55_cold_storage_indexesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations55_cold_storage_indexesSql,
+		"migrations/55_cold_storage_indexes.sql",
+	)
+}
+
+func migrations55_cold_storage_indexesSql() (*asset, error) {
+	bytes, err := migrations55_cold_storage_indexesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/55_cold_storage_indexes.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd, 0xbe, 0xd, 0x1e, 0x9c, 0xda, 0x8, 0xc1, 0x88, 0xf7, 0x53, 0x3a, 0x8f, 0xac, 0xe, 0x51, 0xd4, 0x5d, 0xd5, 0xe, 0x9, 0x36, 0xf5, 0x3d, 0x5e, 0xae, 0x90, 0xc9, 0x2, 0xb6, 0x7a, 0x3e}}
+	return a, nil
+}
+
+var _migrations56_state_verification_reportsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x92\x4d\x6e\xc2\x30\x10\x85\xf7\x39\xc5\x2c\x41\x85\x13\xb0\x4a\x13\x57\xaa\x9a\x06\x14\x60\xc1\xca\x72\x9d\x29\x58\x4d\xec\xd4\x1e\xfe\x7a\xfa\x1a\x0c\x28\xa4\xb4\x34\x3b\xbf\x19\xcf\x7b\xf3\x39\xc3\x21\x3c\xd4\x6a\x69\x05\x21\xcc\x9b\x28\x4a\x0a\x16\xcf\x18\xcc\xe2\xc7\x8c\x81\x23\x2f\xf3\x0d\x5a\xf5\xae\xa4\x20\x65\x34\xb7\xd8\x18\x4b\x0e\x7a\x11\xf8\x4f\x95\xf0\xa6\x96\xce\x37\x88\x6a\x70\x54\x2a\x2c\x97\x68\xb9\xc3\xcf\x35\x6a\x89\xa0\x34\xa1\x17\x20\x1f\xcf\x20\x9f\x67\x59\xe8\x92\x16\xfd\xe4\x92\x0b\x02\x52\x35\x7a\x9f\xba\x81\xad\xa2\x95\x59\x07\x05\xbe\x8c\xc6\xce\xa5\x8d\xa8\x0e\x7e\xc6\x54\x28\x74\xa7\x86\xd6\x1a\x0b\x84\x3b\x3a\x9d\x35\x59\x85\xee\x90\xce\x27\xe8\x34\xd7\xca\xd5\x82\xe4\xca\xd7\x6f\xc7\x9b\x14\xcf\xaf\x71\xb1\x80\x17\xb6\x80\x9e\x2a\xfb\x51\x7f\x74\x9f\x4c\x6b\x6a\x80\x13\x50\xf1\xc0\xa8\x9d\x02\x0a\xf6\xc4\x0a\x96\x27\x6c\xfa\x27\x62\xef\x0c\xe3\x1c\x52\x96\x31\x6f\x9c\xc4\xd3\x24\x4e\xd9\x15\xe6\x0f\xdc\x1f\x97\xee\xd2\xf0\xdb\xef\x39\xed\x9b\xdf\xf8\x37\xd6\x48\x74\xee\x84\xac\x53\xac\x84\x23\x5e\x9b\xd2\x27\xf2\x4f\x14\x9c\xce\x73\x4e\xf3\x77\x0d\xca\xc3\xfb\x1d\x8d\x5a\xd8\x85\xa4\xb5\xa8\x7e\xc8\x57\x3c\x2f\x58\x06\xad\x2d\x02\xe2\x61\xeb\x67\x4c\xcd\x56\x47\x51\x5a\x8c\x27\xff\x42\x2e\x85\x93\xa2\xc4\xd1\x9d\x1b\x67\xb6\x97\xf6\x6f\x2a\x6d\x2b\xc3\x00\x03\x00\x00")
+
+func migrations56_state_verification_reportsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations56_state_verification_reportsSql,
+		"migrations/56_state_verification_reports.sql",
+	)
+}
+
+func migrations56_state_verification_reportsSql() (*asset, error) {
+	bytes, err := migrations56_state_verification_reportsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/56_state_verification_reports.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x55, 0x72, 0x93, 0x59, 0x7e, 0x4e, 0x7a, 0x2d, 0x1c, 0x44, 0x2f, 0xa7, 0xcd, 0x89, 0x61, 0xfd, 0x1e, 0x85, 0x65, 0x87, 0x39, 0x11, 0xce, 0x89, 0x3e, 0xe1, 0xdf, 0xaf, 0xcb, 0xd2, 0x6f, 0x48}}
+	return a, nil
+}
+
+var _migrations57_ingest_leader_leaseSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x95\x90\xbd\xae\xc2\x30\x0c\x46\x77\x3f\x85\xc7\x56\xd0\xe5\x0a\x58\x98\x0a\x74\x40\x94\x1f\x55\x65\x60\xaa\x4c\xf0\x6d\x23\x68\x52\xd2\x40\x81\xa7\x27\xc0\x82\x2a\x84\x84\x17\xcb\xc9\x39\xb2\xf5\x05\x01\x76\x4a\x99\x1b\xb2\x8c\xeb\x0a\x60\x9c\x44\x61\x1a\x61\x1a\x8e\xe2\x08\xa5\xca\xb9\xb6\xd9\x81\x69\xc7\xe6\xd1\x6a\x46\x0f\xd0\x95\xa2\x92\x51\x14\x64\x48\x58\x36\x78\x26\x73\x75\xb0\x37\xe8\xf9\xb8\x58\xa6\xb8\x58\xc7\x71\xf7\x09\x16\xfa\xe0\xdc\x0f\xe8\x5f\x7f\xd0\x66\xff\x59\x09\xf7\x95\x59\xbd\x67\x85\x5b\x99\x4b\x65\x5b\x08\x89\xe3\x49\x1a\xde\x65\x64\xd1\xca\xd2\x5d\x47\x65\x85\x8d\xb4\x85\x3e\xbd\x5e\xf0\xa6\x15\xb7\x2c\xc3\x8a\x9b\x5f\x25\xbe\x54\x6e\x53\xfd\x9b\xb4\x4a\xa6\xf3\x30\xd9\xe0\x2c\xda\xa0\xf7\x08\xc9\x07\x7f\x08\x10\xbc\xa5\x3c\xd1\x8d\x02\x98\x24\xcb\xd5\x97\x94\x05\xd5\xc2\x8d\x43\xb8\x03\x42\x21\x54\x91\xa0\x01\x00\x00")
+
+func migrations57_ingest_leader_leaseSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations57_ingest_leader_leaseSql,
+		"migrations/57_ingest_leader_lease.sql",
+	)
+}
+
+func migrations57_ingest_leader_leaseSql() (*asset, error) {
+	bytes, err := migrations57_ingest_leader_leaseSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/57_ingest_leader_lease.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x60, 0x3, 0xc4, 0x62, 0xd7, 0x80, 0x20, 0x65, 0x97, 0xed, 0xf1, 0xdf, 0x81, 0x2b, 0x91, 0xdc, 0xa0, 0xa7, 0x60, 0x4c, 0xcd, 0x69, 0x49, 0xde, 0x49, 0xfe, 0x15, 0x76, 0x12, 0x47, 0xfa, 0x46}}
+	return a, nil
+}
+
+var _migrations58_partition_history_tablesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xed\x57\x5b\x4f\xe3\x46\x14\x7e\xcf\xaf\x38\x0f\x20\xdb\xdd\x10\x41\xf7\x0d\x58\x24\x93\x0c\xe0\xae\xb1\xa9\xed\xb4\xa0\xae\x14\x19\x7b\x1c\x2c\x1c\x3b\x1d\x4f\x0a\x48\xfb\xe3\x7b\x66\xc6\x57\x70\xb8\xac\x56\x7d\x6a\x1e\x12\xcf\xf1\x99\x6f\xce\x7c\xe7\x9a\xbd\x3d\xf8\xb4\x4a\x97\x2c\xe4\x14\xe6\xeb\xd1\x68\x6f\x0f\xee\xd2\x92\x17\xec\x69\xb1\x0e\x19\x4f\x79\x5a\xe4\x25\x64\x28\x2a\x81\xdf\x51\xc8\x68\xbc\xa4\x0c\x58\x98\x2f\x69\x09\x7f\x95\x1c\x95\x16\x4a\x38\x06\x9a\xc7\xd5\xb3\x01\x45\x22\xf4\x05\x5e\x07\x47\x09\xeb\x13\x80\x87\xb7\x19\xa2\x34\x0a\x34\x86\xdb\xa7\xde\x11\x13\x08\x50\xbf\x51\x10\x70\x15\xc6\xb1\xdc\x7c\xd2\xc7\x02\x69\x4f\x9a\x2f\x21\xe4\x35\x8e\x0d\x69\x09\x79\xb8\x42\xf0\x6a\xcf\x62\x7d\x6c\x9f\x4c\x04\x96\x00\x57\x1b\x51\x87\xae\xd6\xfc\x09\x1e\xee\x68\x3e\x64\x64\xc8\x28\xe4\x05\xef\x1a\x3b\x19\x4d\x3d\x62\x06\x04\x02\xf3\xd4\x26\x43\xbc\xe9\x23\xc0\x4f\x97\x24\x48\x73\x4e\xc5\xaf\xe3\x06\xe0\xcc\x6d\x1b\xae\x3c\xeb\xd2\xf4\x6e\xe0\x2b\xb9\x19\x4b\xf5\x96\xc5\x17\xca\x4a\x61\x7a\x41\xa6\x5f\x41\xef\xc1\x1e\x77\xc9\x1f\x19\x47\xd2\x93\x57\xb5\x25\x82\x91\xea\x1e\x0f\x29\xbf\x43\xdc\x98\x3e\xe2\x82\xd1\xbf\x37\x29\xc3\x87\xab\xa2\xe4\x4b\x46\xfd\xdf\x6d\x38\x38\x18\x0f\xdc\x5f\xc0\x0d\x50\x00\x45\x0e\x45\x16\xa3\x01\xff\x50\x56\x8a\x3b\x0b\x5e\x6b\x6a\x59\xf1\x50\xe2\x59\x18\x29\x1c\x55\xcb\x02\x92\x90\x49\x94\x7b\xba\xe6\xf8\x02\x42\x74\xd2\x32\x8c\x9e\x5a\xc8\xd6\x47\xfb\x63\xf4\x45\x1a\xa1\xb1\xf2\x70\x61\x52\xc1\xd2\x65\x9a\x87\x99\xb2\x69\x0c\x51\x81\xa7\x4a\x6f\x67\x59\x27\x3a\x4b\xd8\xac\x81\x17\x4a\x82\x81\x5d\xf2\xc6\x08\x81\xa4\x94\x54\x64\xd5\x44\x54\x41\xf5\xc2\x1a\x61\x2c\xa3\x2a\x7a\x24\x73\x21\xa0\x65\x50\x6e\x92\x24\x7d\x1c\xd7\x76\x3d\x43\xe9\xf2\xa3\xa2\xeb\x9e\xd2\x75\xff\x06\x02\xb2\x9c\x80\x27\x18\x2a\x92\xd6\x2c\xe5\xa0\x62\xc3\xf1\xa4\xbe\x19\xc2\x1b\x08\x98\xaa\xe8\x8c\x69\x12\x6e\x32\xde\x4f\x8d\x9a\xbb\xfa\xe5\x26\xe7\x69\x06\xe1\x86\x15\x2c\x84\x88\x51\xc1\x85\xd8\x9d\xb2\x76\x9f\xcc\x83\xa6\x04\xf8\x1c\xbf\x57\x34\xe7\xa7\x14\xed\x1c\xcd\x5c\xd8\xd9\x19\xcd\xc8\xd4\x36\x3d\x22\x63\x8f\x23\x1d\x51\xc1\xe2\x23\xb9\x4a\xe3\xc7\xde\x3a\xb9\xef\x2d\x57\xe1\xe3\x22\xc5\x9c\x16\x77\xe6\x4a\x74\x5b\x6c\xf2\x56\x72\x4a\xce\x2d\x47\xca\xad\x33\x88\x36\x0c\xa9\xe6\x8b\x92\x72\x91\xc2\xba\x56\x52\x86\xfe\x5d\x54\x91\xb5\xc8\x37\x2b\xcd\x38\x3c\xac\x73\xe2\x18\x23\x75\x1f\x3f\x10\x5c\x10\x85\x21\x3e\x1e\x09\xe6\x9e\xa3\xce\x22\xce\x0c\x71\x31\x15\xc4\xc2\x27\x36\x99\x06\x30\x75\x4d\x9b\xf8\x53\xa2\x5f\x9a\xd7\x7a\x89\xe1\x4f\xf3\x88\x1a\x63\xd8\x37\xe0\x13\x1c\x80\xe5\x04\x6e\x65\xe3\x99\xe7\x5e\x36\x39\x5d\x39\x47\xe1\x9e\xb9\x1e\xf2\x60\x39\x35\xe6\x2f\x4a\x57\xff\xc3\xb4\xe7\xc4\x6f\x4c\xd1\xb5\x7a\x37\x4d\x12\x1a\xf1\x52\x1b\x43\x23\x2a\xd6\x14\x09\x17\xd7\x4a\x63\xcd\x18\x0f\x6c\x6a\x34\xe4\xbe\x6d\x5a\x9c\x85\x31\xfd\x20\x32\xee\xc9\xcb\x30\xea\x61\x4b\x35\x03\x4c\xbf\xca\x77\x5d\x04\xe8\x18\x03\xf7\xc9\x00\xdb\x75\xaf\x1a\x18\x72\x4d\xa6\x73\xac\x79\x49\xc1\x56\x21\xd7\xb5\x21\x5e\x77\x2d\xc5\xa8\xa4\x65\xd7\xc2\x33\xf8\x04\x91\xc4\x8f\x80\x35\x14\xcd\x2a\x3a\x8e\x1a\x64\x45\xfb\xe1\x17\x38\x97\x55\xd5\x0f\x74\x29\x19\x83\x5e\xc5\xd1\xc9\x09\x7c\xfe\x55\xfa\xc9\x68\x1d\x2c\x8c\xab\x5c\xfc\xbf\x5f\x3a\x7e\x31\xed\x80\x78\x55\x67\xda\xb5\x30\x2f\x1c\xf3\x12\x3b\x95\x5b\xfb\x43\xe1\xa8\x5f\xf8\xfe\x1d\x34\xac\x6b\x9a\x71\xb4\x0d\xaf\x91\x8b\x8f\xd6\xeb\x7b\x88\xae\xdb\xd6\x57\xf9\x60\x39\x53\x7b\x3e\xb3\x9c\x73\x98\x91\x33\x73\x6e\x07\x7e\x47\x34\x75\x1d\x3f\xf0\x4c\x74\x7e\x57\xea\x07\xae\x67\x9e\x13\x03\xae\x4c\x2f\xb0\x02\xcb\x75\xe0\xf4\x06\x3c\xd3\x39\x27\x20\x22\x49\x1b\xf7\xce\xde\x62\x79\x15\x62\x8d\xaa\x51\xc5\x44\x1d\x17\xa2\x52\xb5\x91\x11\x4d\x18\xcd\xe4\x7e\x24\x56\xe1\xad\x97\x8b\x25\xe5\x0b\x59\xca\xb1\x7e\xea\xe9\x44\x3e\xa2\x5e\x1a\x4b\xfe\x51\xd8\x33\x44\xc6\x16\xee\x92\x6a\x90\xc2\x6f\x2e\xe2\xe3\x3a\xca\xc2\xb2\x84\x08\xf0\x1e\xd1\xa4\xc0\xb0\xfd\x02\x5d\xac\x1e\xc6\x9f\x17\xc4\x23\xea\xb5\x7c\x89\xba\xfa\x73\x97\x1c\x1e\x32\xba\x94\xa0\xcd\xd6\x9e\xe3\xb7\x3b\xdf\x72\x66\xe4\x7a\xc0\xf9\xc8\x45\x45\x62\xfd\x34\x14\x00\x5d\x5c\xb4\x80\x3e\xae\x17\x8c\xae\xb3\x30\xa2\xba\xd8\x86\x74\x60\x88\x8a\x5b\x7e\xf3\x3f\xc1\xdc\x17\xae\x44\xec\xda\x02\xf1\x02\x4f\x6e\xe4\x55\xe6\x77\x03\xac\x9f\xbc\xb5\xa3\xb0\x85\x74\xfc\x54\xe4\x83\x5e\x42\x79\x89\xd9\x82\xad\x40\xb8\xaa\x78\xc3\x43\xad\xf6\x00\xf9\xf8\xf2\x1d\xd4\x83\x89\xd6\xa2\x2a\x7f\x5a\x53\xd4\xd4\x12\xed\xa3\xce\x68\x72\xc5\x9c\xcd\x3a\x99\x20\x24\xbb\x65\x27\x23\x93\xfb\xf6\x01\x2f\xf4\x3a\x61\xd5\x9c\x55\xcf\x1f\x21\xd6\x4f\x3c\x95\xa6\xcb\x5c\x54\x88\xd7\xc7\x9a\x90\xf3\x30\xba\xa3\x71\x17\xac\x1a\x9a\x70\x72\xd9\x3e\xcc\x4c\xde\x57\x22\x9e\xdf\x3a\x08\xcc\xe9\x45\x27\xc7\x51\x26\xdc\xad\x6a\x73\x55\xa8\x2f\x2d\x47\xae\x0d\x11\xaa\xfa\x6e\xf9\xee\xdc\x57\xad\xe3\xf8\x18\xfb\x43\xb7\x02\x6c\xf3\xc8\xf3\xf2\xd5\x5a\xe5\x9e\x89\x75\x55\xbb\xb4\xfe\x49\xd5\x54\xd5\x06\xf3\x60\x13\xb2\x1c\x9f\x78\x81\xea\x70\x43\xff\x08\xb6\xff\x63\xaa\xa8\xd0\xf7\xab\xfb\x20\xbe\xc0\xde\xd9\x39\x1a\x1e\xd1\x48\x1e\x8f\x7a\x6f\x66\xc5\x43\x3e\xea\xcd\xde\xe8\xc3\x7a\x34\x2e\xf0\x8b\x75\xff\x8b\x89\x18\x58\xe1\x00\x8d\xd3\x58\x18\xdd\x37\xf3\xb2\x0c\x95\xde\xdf\xb6\x7a\x0e\xbf\xc5\xc9\x6e\xa5\x06\x48\x9c\x2c\x5f\x0e\xb9\xe1\x12\x53\xec\xa7\x8d\x93\xbd\xd1\x50\xfc\xff\x21\xd7\x96\x8f\x8d\x43\xaf\x4a\xc3\x41\x7f\x40\x6b\x2f\x66\xbc\x73\x20\xfc\xe1\x69\xe1\xad\xa9\xe0\xb5\x79\xe0\xed\xbe\x3f\xdc\xf1\x3f\xd6\xec\x67\xe4\x45\xb2\xfd\x48\xcf\xd7\xba\xb1\x8c\xb0\x7d\xa2\x3a\x98\xbd\x2e\xdc\x49\x8d\x21\xcc\x99\xe7\x5e\x35\x96\xbe\x67\xc3\x7b\x06\x99\x21\x03\x3e\xd2\xfe\xff\x8b\xc6\xae\x0c\x7b\xde\x52\x1a\x4b\xe4\x04\xa5\xed\x7e\x13\xb7\xf8\xf9\x8d\x3e\xa3\x09\xd7\xdb\xe5\xde\xe7\xe1\x4e\xdc\x5f\xbd\x5d\x7d\x3a\xce\x7c\x99\x88\x47\xa3\x7f\x01\xd5\xd2\xd2\x17\x5d\x12\x00\x00")
+
+func migrations58_partition_history_tablesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations58_partition_history_tablesSql,
+		"migrations/58_partition_history_tables.sql",
+	)
+}
+
+func migrations58_partition_history_tablesSql() (*asset, error) {
+	bytes, err := migrations58_partition_history_tablesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/58_partition_history_tables.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xdd, 0xf2, 0x67, 0x87, 0xe4, 0xb1, 0xe4, 0x3d, 0xf5, 0x4d, 0xfa, 0x4d, 0xb, 0x9b, 0x78, 0xe1, 0xb6, 0xb2, 0x53, 0x87, 0x57, 0xda, 0xd3, 0xdc, 0x20, 0xdc, 0x5c, 0x1b, 0x57, 0x7f, 0x89, 0xb6}}
+	return a, nil
+}
+
 var _migrations5_create_trades_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x94\x51\x6f\xaa\x40\x10\x85\xdf\xf9\x15\x13\x9f\x30\x17\x93\x7b\x6f\x5a\x5f\x4c\x9a\x58\x25\xad\xa9\xc1\xd6\x4a\xd2\x37\xb2\xb0\x23\x6c\xa2\x2c\x99\x1d\xda\xf0\xef\x1b\x68\x69\x10\x57\xad\xaf\x9c\x39\x67\x38\xbb\x5f\x76\x34\x82\x3f\x7b\x95\x92\x60\x84\xb0\x70\x66\x6b\x7f\xba\xf1\x61\x33\xbd\x5f\xfa\x90\x29\xc3\x9a\xaa\x88\x49\x48\x34\xe0\x3a\x00\xf0\xf3\x51\x17\x48\x82\x95\xce\x23\x25\x21\x56\xa9\xca\x19\x82\xd5\x06\x82\x70\xb9\xf4\x9a\xc9\x81\x26\x89\x34\x00\x95\x33\xa6\x48\x1d\xb5\x91\xf5\x76\x8b\x64\x35\x37\xb2\xc1\xdd\xee\x84\x5e\xcb\x71\x59\x9d\x75\xeb\x9d\x8c\x84\x31\xc8\x11\x57\x05\x42\x92\x09\x12\x09\x23\xc1\xbb\xa0\x4a\xe5\xa9\x3b\xbe\x19\xf6\x22\x3b\x1e\x65\x4c\x89\x64\x71\xdd\x8e\xcf\xb8\x12\x2d\x6d\x9b\xfe\xfd\xb7\x7b\xf6\xba\xcc\xb9\xff\xff\x30\x7b\xf4\x67\x4f\xe0\x76\x47\xee\xe0\xef\xf0\xbb\x57\xac\xcb\x34\xe3\x6b\x9b\x1d\xb8\xae\xe8\x76\xe0\xfb\x75\xbb\xd6\x75\xb6\xdf\xe1\x50\xdd\xd0\x19\x4e\x9c\x96\xbf\x30\x58\xbc\x84\x3e\x2c\x82\xb9\xff\x06\x19\x93\x8c\x0a\x25\x61\x15\xf4\x91\x0c\x5f\x17\xc1\x03\xc4\x4c\x88\xe0\xda\xc8\xf4\x5a\x0a\x3b\xe1\x9d\xd4\xb8\x8a\x1a\x0c\x2f\x45\xb7\xac\xda\x52\xea\x90\xfa\xb6\x2e\x65\xf4\x90\xf4\xfa\xe4\x78\xc7\x00\x9e\x5a\xf7\x75\x78\x97\x16\x1e\xb1\xe2\x1d\x5f\xa8\x67\x63\xa3\x5e\xdb\x7d\x17\xe6\xfa\x23\x77\xe6\xeb\xd5\xb3\xfd\x5d\x48\x84\x49\x84\xc4\x89\xf3\x19\x00\x00\xff\xff\x79\x87\x24\x6b\x4c\x04\x00\x00")
 
 func migrations5_create_trades_tableSqlBytes() ([]byte, error) {
@@ -1341,6 +1467,12 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/50_liquidity_pools.sql":                                  migrations50_liquidity_poolsSql,
 	"migrations/51_remove_ht_unused_indexes.sql":                         migrations51_remove_ht_unused_indexesSql,
 	"migrations/52_add_trade_type_index.sql":                             migrations52_add_trade_type_indexSql,
+	"migrations/53_trade_aggregation_rollups.sql":                        migrations53_trade_aggregation_rollupsSql,
+	"migrations/54_liquidity_pool_snapshots.sql":                         migrations54_liquidity_pool_snapshotsSql,
+	"migrations/55_cold_storage_indexes.sql":                             migrations55_cold_storage_indexesSql,
+	"migrations/56_state_verification_reports.sql":                       migrations56_state_verification_reportsSql,
+	"migrations/57_ingest_leader_lease.sql":                              migrations57_ingest_leader_leaseSql,
+	"migrations/58_partition_history_tables.sql":                         migrations58_partition_history_tablesSql,
 	"migrations/5_create_trades_table.sql":                               migrations5_create_trades_tableSql,
 	"migrations/6_create_assets_table.sql":                               migrations6_create_assets_tableSql,
 	"migrations/7_modify_trades_table.sql":                               migrations7_modify_trades_tableSql,
@@ -1439,6 +1571,12 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"50_liquidity_pools.sql":                                  &bintree{migrations50_liquidity_poolsSql, map[string]*bintree{}},
 		"51_remove_ht_unused_indexes.sql":                         &bintree{migrations51_remove_ht_unused_indexesSql, map[string]*bintree{}},
 		"52_add_trade_type_index.sql":                             &bintree{migrations52_add_trade_type_indexSql, map[string]*bintree{}},
+		"53_trade_aggregation_rollups.sql":                        &bintree{migrations53_trade_aggregation_rollupsSql, map[string]*bintree{}},
+		"54_liquidity_pool_snapshots.sql":                         &bintree{migrations54_liquidity_pool_snapshotsSql, map[string]*bintree{}},
+		"55_cold_storage_indexes.sql":                             &bintree{migrations55_cold_storage_indexesSql, map[string]*bintree{}},
+		"56_state_verification_reports.sql":                       &bintree{migrations56_state_verification_reportsSql, map[string]*bintree{}},
+		"57_ingest_leader_lease.sql":                              &bintree{migrations57_ingest_leader_leaseSql, map[string]*bintree{}},
+		"58_partition_history_tables.sql":                         &bintree{migrations58_partition_history_tablesSql, map[string]*bintree{}},
 		"5_create_trades_table.sql":                               &bintree{migrations5_create_trades_tableSql, map[string]*bintree{}},
 		"6_create_assets_table.sql":                               &bintree{migrations6_create_assets_tableSql, map[string]*bintree{}},
 		"7_modify_trades_table.sql":                               &bintree{migrations7_modify_trades_tableSql, map[string]*bintree{}},
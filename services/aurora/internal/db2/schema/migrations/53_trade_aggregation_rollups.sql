@@ -0,0 +1,133 @@
+-- +migrate Up notransaction
+
+ALTER TABLE history_trades_60000
+  ADD liquidity_pool_base_volume numeric NOT NULL DEFAULT 0,
+  ADD liquidity_pool_counter_volume numeric NOT NULL DEFAULT 0;
+
+-- Backfill the liquidity pool volumes of the existing buckets.
+UPDATE history_trades_60000 AS htrd SET
+  liquidity_pool_base_volume = lp.base_volume,
+  liquidity_pool_counter_volume = lp.counter_volume
+FROM (
+  SELECT
+    to_millis(ledger_closed_at, 60000) as timestamp,
+    base_asset_id,
+    counter_asset_id,
+    sum(base_amount) as base_volume,
+    sum(counter_amount) as counter_volume
+  FROM history_trades
+  WHERE trade_type = 2
+  GROUP BY base_asset_id, counter_asset_id, timestamp
+) AS lp
+WHERE htrd.timestamp = lp.timestamp
+  AND htrd.base_asset_id = lp.base_asset_id
+  AND htrd.counter_asset_id = lp.counter_asset_id;
+
+CREATE TABLE history_trades_3600000 (LIKE history_trades_60000 INCLUDING ALL);
+CREATE TABLE history_trades_86400000 (LIKE history_trades_60000 INCLUDING ALL);
+
+-- Backfill the hourly buckets from the 1 minute buckets.
+WITH buckets AS (
+  SELECT
+    (timestamp / 3600000) * 3600000 as timestamp,
+    base_asset_id,
+    counter_asset_id,
+    count,
+    base_volume,
+    counter_volume,
+    high_n,
+    high_d,
+    low_n,
+    low_d,
+    open_ledger_toid,
+    open_n,
+    open_d,
+    close_ledger_toid,
+    close_n,
+    close_d,
+    liquidity_pool_base_volume,
+    liquidity_pool_counter_volume
+  FROM history_trades_60000
+  ORDER BY base_asset_id, counter_asset_id, open_ledger_toid
+)
+  INSERT INTO history_trades_3600000 (
+    SELECT
+      timestamp,
+      base_asset_id,
+      counter_asset_id,
+      sum(count) as count,
+      sum(base_volume) as base_volume,
+      sum(counter_volume) as counter_volume,
+      sum(counter_volume::numeric)/sum(base_volume::numeric) as avg,
+      (max_price(ARRAY[high_n, high_d]))[1] as high_n,
+      (max_price(ARRAY[high_n, high_d]))[2] as high_d,
+      (min_price(ARRAY[low_n, low_d]))[1] as low_n,
+      (min_price(ARRAY[low_n, low_d]))[2] as low_d,
+      first(open_ledger_toid) as open_ledger_toid,
+      (first(ARRAY[open_n, open_d]))[1] as open_n,
+      (first(ARRAY[open_n, open_d]))[2] as open_d,
+      last(close_ledger_toid) as close_ledger_toid,
+      (last(ARRAY[close_n, close_d]))[1] as close_n,
+      (last(ARRAY[close_n, close_d]))[2] as close_d,
+      sum(liquidity_pool_base_volume) as liquidity_pool_base_volume,
+      sum(liquidity_pool_counter_volume) as liquidity_pool_counter_volume
+    FROM buckets
+    GROUP BY base_asset_id, counter_asset_id, timestamp
+  );
+
+-- Backfill the daily buckets from the hourly buckets.
+WITH buckets AS (
+  SELECT
+    (timestamp / 86400000) * 86400000 as timestamp,
+    base_asset_id,
+    counter_asset_id,
+    count,
+    base_volume,
+    counter_volume,
+    high_n,
+    high_d,
+    low_n,
+    low_d,
+    open_ledger_toid,
+    open_n,
+    open_d,
+    close_ledger_toid,
+    close_n,
+    close_d,
+    liquidity_pool_base_volume,
+    liquidity_pool_counter_volume
+  FROM history_trades_3600000
+  ORDER BY base_asset_id, counter_asset_id, open_ledger_toid
+)
+  INSERT INTO history_trades_86400000 (
+    SELECT
+      timestamp,
+      base_asset_id,
+      counter_asset_id,
+      sum(count) as count,
+      sum(base_volume) as base_volume,
+      sum(counter_volume) as counter_volume,
+      sum(counter_volume::numeric)/sum(base_volume::numeric) as avg,
+      (max_price(ARRAY[high_n, high_d]))[1] as high_n,
+      (max_price(ARRAY[high_n, high_d]))[2] as high_d,
+      (min_price(ARRAY[low_n, low_d]))[1] as low_n,
+      (min_price(ARRAY[low_n, low_d]))[2] as low_d,
+      first(open_ledger_toid) as open_ledger_toid,
+      (first(ARRAY[open_n, open_d]))[1] as open_n,
+      (first(ARRAY[open_n, open_d]))[2] as open_d,
+      last(close_ledger_toid) as close_ledger_toid,
+      (last(ARRAY[close_n, close_d]))[1] as close_n,
+      (last(ARRAY[close_n, close_d]))[2] as close_d,
+      sum(liquidity_pool_base_volume) as liquidity_pool_base_volume,
+      sum(liquidity_pool_counter_volume) as liquidity_pool_counter_volume
+    FROM buckets
+    GROUP BY base_asset_id, counter_asset_id, timestamp
+  );
+
+-- +migrate Down
+
+DROP TABLE history_trades_86400000;
+DROP TABLE history_trades_3600000;
+ALTER TABLE history_trades_60000
+  DROP liquidity_pool_base_volume,
+  DROP liquidity_pool_counter_volume;
@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE history_liquidity_pool_snapshots (
+    history_liquidity_pool_id bigint NOT NULL,
+    history_ledger_id bigint NOT NULL,
+    closed_at timestamp without time zone NOT NULL,
+    trustline_count bigint NOT NULL,
+    share_count bigint NOT NULL,
+    reserve_a bigint NOT NULL,
+    reserve_b bigint NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_liquidity_pool_snapshots_on_ids ON history_liquidity_pool_snapshots USING btree (history_liquidity_pool_id, history_ledger_id);
+CREATE INDEX index_history_liquidity_pool_snapshots_on_ledger_id ON history_liquidity_pool_snapshots USING btree (history_ledger_id);
+
+-- +migrate Down
+
+DROP TABLE history_liquidity_pool_snapshots cascade;
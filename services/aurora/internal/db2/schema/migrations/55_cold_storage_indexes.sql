@@ -0,0 +1,18 @@
+-- +migrate Up
+
+CREATE TABLE history_cold_transactions (
+    transaction_hash character varying(64) NOT NULL,
+    ledger_sequence integer NOT NULL,
+    PRIMARY KEY (transaction_hash)
+);
+
+CREATE TABLE history_cold_account_partitions (
+    address character varying(64) NOT NULL,
+    partition integer NOT NULL,
+    PRIMARY KEY (address, partition)
+);
+
+-- +migrate Down
+
+DROP TABLE history_cold_transactions cascade;
+DROP TABLE history_cold_account_partitions cascade;
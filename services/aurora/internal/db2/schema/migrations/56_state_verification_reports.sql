@@ -0,0 +1,28 @@
+-- +migrate Up
+
+CREATE TABLE state_verification_reports (
+    id bigserial,
+    ledger_sequence integer NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    valid boolean NOT NULL,
+    error text,
+    entries bigint NOT NULL,
+    mismatches integer NOT NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE state_verification_mismatches (
+    report_id bigint NOT NULL REFERENCES state_verification_reports (id) ON DELETE CASCADE,
+    ledger_key text NOT NULL,
+    entry_type integer NOT NULL,
+    processor text NOT NULL,
+    last_modified_ledger integer,
+    expected_entry text,
+    actual_entry text,
+    PRIMARY KEY (report_id, ledger_key)
+);
+
+-- +migrate Down
+
+DROP TABLE state_verification_mismatches cascade;
+DROP TABLE state_verification_reports cascade;
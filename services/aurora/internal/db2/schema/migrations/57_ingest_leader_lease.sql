@@ -0,0 +1,15 @@
+-- +migrate Up
+
+CREATE TABLE ingest_leader_lease (
+    name character varying(64) NOT NULL,
+    holder character varying(256) NOT NULL,
+    fencing_token bigint NOT NULL,
+    acquired_at timestamp without time zone NOT NULL,
+    renewed_at timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone NOT NULL,
+    PRIMARY KEY (name)
+);
+
+-- +migrate Down
+
+DROP TABLE ingest_leader_lease cascade;
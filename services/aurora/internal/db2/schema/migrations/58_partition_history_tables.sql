@@ -0,0 +1,121 @@
+-- +migrate Up
+
+-- history_partitions lists the ledger ranges [start_ledger, end_ledger) of the
+-- partitions of the history tables partitioned by ledger range. The partition
+-- of the <table> history table starting at ledger L is named <table>_p<L>.
+-- The table is empty when the history tables are not partitioned.
+CREATE TABLE history_partitions (
+    start_ledger integer NOT NULL PRIMARY KEY,
+    end_ledger integer NOT NULL,
+    CHECK (start_ledger < end_ledger)
+);
+
+-- Partitioning tables with indexes requires PostgreSQL 11, the history tables
+-- are not partitioned on older versions.
+--
+-- The rows ingested so far are kept in a legacy partition <table>_p0, which is
+-- the original table, covering all the ledgers up to the latest ingested
+-- ledger. The indexes of the legacy partition are renamed with a _p0 suffix,
+-- the indexes of the partitioned table keep the original names. Rows of
+-- ledgers without a partition are stored in the default partition
+-- <table>_default until aurora creates their partition.
+-- +migrate StatementBegin
+DO $$
+DECLARE
+    t record;
+    idx record;
+    fk record;
+    max_id bigint;
+    bound bigint;
+BEGIN
+    IF current_setting('server_version_num')::integer < 110000 THEN
+        RETURN;
+    END IF;
+
+    SELECT COALESCE(MAX(sequence), 0) + 1 INTO bound FROM history_ledgers;
+    FOR t IN SELECT * FROM (VALUES
+        ('history_effects', 'history_operation_id'),
+        ('history_operations', 'id'),
+        ('history_trades', 'history_operation_id'),
+        ('history_transactions', 'id')
+    ) AS tables(name, key) LOOP
+        EXECUTE format('SELECT COALESCE(MAX(%I), 0) FROM %I', t.key, t.name) INTO max_id;
+        bound := GREATEST(bound, (max_id >> 32) + 1);
+    END LOOP;
+
+    FOR t IN SELECT * FROM (VALUES
+        ('history_effects', 'history_operation_id'),
+        ('history_operations', 'id'),
+        ('history_trades', 'history_operation_id'),
+        ('history_transactions', 'id')
+    ) AS tables(name, key) LOOP
+        EXECUTE format('ALTER TABLE %I RENAME TO %I', t.name, t.name || '_p0');
+        EXECUTE format(
+            'CREATE TABLE %I (LIKE %I INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE) PARTITION BY RANGE (%I)',
+            t.name, t.name || '_p0', t.key
+        );
+
+        FOR idx IN SELECT c.relname AS name, pg_get_indexdef(i.indexrelid) AS def
+            FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+            WHERE i.indrelid = (t.name || '_p0')::regclass
+        LOOP
+            EXECUTE format('ALTER INDEX %I RENAME TO %I', idx.name, idx.name || '_p0');
+            EXECUTE regexp_replace(idx.def, ' ON \S+ USING ', format(' ON %I USING ', t.name));
+        END LOOP;
+
+        FOR fk IN SELECT conname AS name, pg_get_constraintdef(oid) AS def
+            FROM pg_constraint
+            WHERE conrelid = (t.name || '_p0')::regclass AND contype = 'f'
+        LOOP
+            EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I %s', t.name, fk.name, fk.def);
+        END LOOP;
+
+        -- The indexes and foreign keys of the legacy partition are attached
+        -- to the ones of the partitioned table.
+        EXECUTE format(
+            'ALTER TABLE %I ATTACH PARTITION %I FOR VALUES FROM (MINVALUE) TO (%s)',
+            t.name, t.name || '_p0', bound << 32
+        );
+        EXECUTE format('CREATE TABLE %I PARTITION OF %I DEFAULT', t.name || '_default', t.name);
+    END LOOP;
+
+    INSERT INTO history_partitions (start_ledger, end_ledger) VALUES (0, bound);
+END $$;
+-- +migrate StatementEnd
+
+-- +migrate Down
+
+-- The rows of all the other partitions are moved back to the legacy
+-- partition, which becomes the unpartitioned table again.
+-- +migrate StatementBegin
+DO $$
+DECLARE
+    t record;
+    idx record;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM history_partitions) THEN
+        RETURN;
+    END IF;
+
+    FOR t IN SELECT * FROM (VALUES
+        ('history_effects'),
+        ('history_operations'),
+        ('history_trades'),
+        ('history_transactions')
+    ) AS tables(name) LOOP
+        EXECUTE format('ALTER TABLE %I DETACH PARTITION %I', t.name, t.name || '_p0');
+        EXECUTE format('INSERT INTO %I SELECT * FROM %I', t.name || '_p0', t.name);
+        EXECUTE format('DROP TABLE %I', t.name);
+        EXECUTE format('ALTER TABLE %I RENAME TO %I', t.name || '_p0', t.name);
+
+        FOR idx IN SELECT c.relname AS name
+            FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+            WHERE i.indrelid = t.name::regclass AND c.relname LIKE '%\_p0'
+        LOOP
+            EXECUTE format('ALTER INDEX %I RENAME TO %I', idx.name, left(idx.name, -3));
+        END LOOP;
+    END LOOP;
+END $$;
+-- +migrate StatementEnd
+
+DROP TABLE history_partitions;
@@ -15,6 +15,7 @@ import (
 	"github.com/diamcircle/go/ingest/ledgerbackend"
 	"github.com/diamcircle/go/network"
 	"github.com/diamcircle/go/services/aurora/internal/db2/schema"
+	"github.com/diamcircle/go/services/aurora/internal/ingest"
 	apkg "github.com/diamcircle/go/support/app"
 	support "github.com/diamcircle/go/support/config"
 	"github.com/diamcircle/go/support/db"
@@ -25,10 +26,16 @@ import (
 const (
 	// DatabaseURLFlagName is the command line flag for configuring the Aurora postgres URL
 	DatabaseURLFlagName = "db-url"
+	// EmbeddedDataDirFlagName is the command line flag for configuring the
+	// data directory of the embedded store serving the account and offer
+	// endpoints
+	EmbeddedDataDirFlagName = "embedded-data-dir"
 	// DiamcircleCoreDBURLFlagName is the command line flag for configuring the postgres Diamcircle Core URL
 	DiamcircleCoreDBURLFlagName = "diamcircle-core-db-url"
 	// DiamcircleCoreURLFlagName is the command line flag for configuring the URL fore Diamcircle Core HTTP endpoint
 	DiamcircleCoreURLFlagName = "diamcircle-core-url"
+	// NetworkPassphraseFlagName is the command line flag for configuring the network passphrase
+	NetworkPassphraseFlagName = "network-passphrase"
 	// DiamcircleCoreBinaryPathName is the command line flag for configuring the path to the diamcircle core binary
 	DiamcircleCoreBinaryPathName = "diamcircle-core-binary-path"
 	// captiveCoreConfigAppendPathName is the command line flag for configuring the path to the captive core additional configuration
@@ -107,15 +114,25 @@ func Flags() (*Config, support.ConfigOptions) {
 			EnvVar:    "DATABASE_URL",
 			ConfigKey: &config.DatabaseURL,
 			OptType:   types.String,
-			Required:  true,
-			Usage:     "aurora postgres database to connect with",
+			Required:  false,
+			Usage:     "aurora postgres database to connect with, required unless --" + EmbeddedDataDirFlagName + " is set",
 		},
 		&support.ConfigOption{
 			Name:      "ro-database-url",
-			ConfigKey: &config.RoDatabaseURL,
+			ConfigKey: &config.RoDatabaseURLs,
 			OptType:   types.String,
 			Required:  false,
-			Usage:     "aurora postgres read-replica to connect with, when set it will return stale history error when replica is behind primary",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				var urls []string
+				for _, databaseURL := range strings.Split(viper.GetString(co.Name), ",") {
+					if databaseURL = strings.TrimSpace(databaseURL); databaseURL != "" {
+						urls = append(urls, databaseURL)
+					}
+				}
+				*(co.ConfigKey.(*[]string)) = urls
+				return nil
+			},
+			Usage: "comma-separated list of aurora postgres read-replicas to connect with, when set requests are routed to the replicas which have caught up with the primary database and fall back to the primary database otherwise",
 		},
 		&support.ConfigOption{
 			Name:        DiamcircleCoreBinaryPathName,
@@ -206,6 +223,20 @@ func Flags() (*Config, support.ConfigOptions) {
 			Usage:     "Storage location for Captive Core bucket data",
 			ConfigKey: &config.CaptiveCoreStoragePath,
 		},
+		&support.ConfigOption{
+			Name:        "captive-core-max-restarts",
+			ConfigKey:   &config.CaptiveCoreMaxRestarts,
+			OptType:     types.Uint,
+			FlagDefault: uint(5),
+			Usage:       "number of consecutive times Captive Core is restarted, with exponential backoff, after exiting unexpectedly while ingesting (0 disables restarts)",
+		},
+		&support.ConfigOption{
+			Name:        "captive-core-log-lines",
+			ConfigKey:   &config.CaptiveCoreLogLines,
+			OptType:     types.Uint,
+			FlagDefault: uint(100),
+			Usage:       "number of recent Captive Core log lines served on the admin port at /captive_core/logs",
+		},
 		&support.ConfigOption{
 			Name:           "captive-core-peer-port",
 			OptType:        types.Uint,
@@ -372,7 +403,31 @@ func Flags() (*Config, support.ConfigOptions) {
 			Usage:       "excludes liquidity pools from consideration in the `/paths` endpoint",
 		},
 		&support.ConfigOption{
-			Name:      "network-passphrase",
+			Name:        "orderbook-snapshot-path",
+			ConfigKey:   &config.OrderBookSnapshotPath,
+			OptType:     types.String,
+			FlagDefault: "",
+			Required:    false,
+			Usage:       "file where the in-memory order book used for path finding is saved periodically and restored from at startup, snapshots are disabled if empty",
+		},
+		&support.ConfigOption{
+			Name:        "orderbook-snapshot-interval",
+			ConfigKey:   &config.OrderBookSnapshotInterval,
+			OptType:     types.Uint32,
+			FlagDefault: uint32(100),
+			Required:    false,
+			Usage:       "number of ledgers between snapshots of the in-memory order book",
+		},
+		&support.ConfigOption{
+			Name:           "path-finder-url",
+			ConfigKey:      &config.PathFinderURL,
+			OptType:        types.String,
+			CustomSetValue: support.SetURL,
+			Required:       false,
+			Usage:          "path finding service queried by the `/paths` endpoints instead of the in-memory order book, the order book is not maintained by aurora if set",
+		},
+		&support.ConfigOption{
+			Name:      NetworkPassphraseFlagName,
 			ConfigKey: &config.NetworkPassphrase,
 			OptType:   types.String,
 			Required:  true,
@@ -431,6 +486,13 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: uint(0),
 			Usage:       "the minimum number of ledgers to maintain within aurora's history tables.  0 signifies an unlimited number of ledgers will be retained",
 		},
+		&support.ConfigOption{
+			Name:        "history-cold-storage-url",
+			ConfigKey:   &config.HistoryColdStorageURL,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "file:// or s3:// URL where the reaper exports history before deleting it. reaped ledgers, transactions and account operations are then served from it, with a higher latency than from the database",
+		},
 		&support.ConfigOption{
 			Name:        "history-stale-threshold",
 			ConfigKey:   &config.StaleThreshold,
@@ -459,6 +521,38 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: false,
 			Usage:       "enables extended ledger stats in the log (ledger entry changes and operations stats)",
 		},
+		&support.ConfigOption{
+			Name:        "ingest-leader-election",
+			ConfigKey:   &config.IngestLeaderElection,
+			OptType:     types.String,
+			FlagDefault: "",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				backend := viper.GetString(co.Name)
+				switch backend {
+				case "", ingest.LeaderElectionAdvisoryLock, ingest.LeaderElectionLease:
+					*(co.ConfigKey.(*string)) = backend
+					return nil
+				default:
+					return fmt.Errorf("Invalid config: --%s must be %s or %s", co.Name,
+						ingest.LeaderElectionAdvisoryLock, ingest.LeaderElectionLease)
+				}
+			},
+			Usage: "[optional] elects the ingesting instance with a session advisory lock (advisory-lock) or a lease renewed with heartbeats (lease), by default all ingesting instances compete for every ledger",
+		},
+		&support.ConfigOption{
+			Name:        "ingest-leader-node-id",
+			ConfigKey:   &config.IngestLeaderNodeID,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "[optional] identifies this instance in the ingestion leader election, defaults to the hostname and process id",
+		},
+		&support.ConfigOption{
+			Name:        EmbeddedDataDirFlagName,
+			ConfigKey:   &config.EmbeddedDataDir,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "[experimental] runs a single-node aurora without a database, storing the ingested state in this directory. only the account and offer endpoints are served, transaction submission and history endpoints are disabled. requires --ingest and captive core",
+		},
 		&support.ConfigOption{
 			Name:        "apply-migrations",
 			ConfigKey:   &config.ApplyMigrations,
@@ -540,17 +634,26 @@ func ApplyFlags(config *Config, flags support.ConfigOptions, options ApplyOption
 		config.Ingest = true
 	}
 
+	if config.EmbeddedDataDir == "" && config.DatabaseURL == "" {
+		return fmt.Errorf("flag --%s cannot be empty", DatabaseURLFlagName)
+	}
+	if config.EmbeddedDataDir != "" && (!config.Ingest || !config.EnableCaptiveCoreIngestion) {
+		return fmt.Errorf("Invalid config: --%s requires --ingest and captive core ingestion", EmbeddedDataDirFlagName)
+	}
+
 	if config.Ingest {
 		// Migrations should be checked as early as possible. Apply and check
 		// only on ingesting instances which are required to have write-access
 		// to the DB.
-		if config.ApplyMigrations {
+		if config.ApplyMigrations && config.EmbeddedDataDir == "" {
 			if err := applyMigrations(*config); err != nil {
 				return err
 			}
 		}
-		if err := checkMigrations(*config); err != nil {
-			return err
+		if config.EmbeddedDataDir == "" {
+			if err := checkMigrations(*config); err != nil {
+				return err
+			}
 		}
 
 		// config.HistoryArchiveURLs contains a single empty value when empty so using
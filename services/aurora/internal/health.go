@@ -68,9 +68,12 @@ func (h healthCheck) runCheck() healthResponse {
 		CoreUp:            true,
 		CoreSynced:        true,
 	}
-	if err := h.session.Ping(h.ctx, dbPingTimeout); err != nil {
-		healthLogger.Warnf("could not ping db: %s", err)
-		response.DatabaseConnected = false
+	// There is no database to ping with the embedded storage driver.
+	if h.session != nil {
+		if err := h.session.Ping(h.ctx, dbPingTimeout); err != nil {
+			healthLogger.Warnf("could not ping db: %s", err)
+			response.DatabaseConnected = false
+		}
 	}
 	if resp, err := h.core.Info(h.ctx); err != nil {
 		healthLogger.Warnf("request to diamcircle core failed: %s", err)
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/diamcircle/go/protocols/aurora"
 	"github.com/diamcircle/go/services/aurora/internal/actions"
 	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
@@ -328,3 +330,70 @@ func WrapRaw(next http.Handler, action rawAction) http.Handler {
 		}
 	})
 }
+
+// tradeAggregationsHandler renders pages of trade aggregations and streams
+// the buckets which are created or updated by every ingested ledger.
+type tradeAggregationsHandler struct {
+	action        actions.GetTradeAggregationsHandler
+	streamHandler sse.StreamHandler
+}
+
+func (handler tradeAggregationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch render.Negotiate(r) {
+	case render.MimeHal, render.MimeJSON:
+		ObjectActionHandler{handler.action}.ServeHTTP(w, r)
+		return
+	case render.MimeEventStream:
+		handler.renderStream(w, r)
+		return
+	}
+
+	problem.Render(r.Context(), w, hProblem.NotAcceptable)
+}
+
+func (handler tradeAggregationsHandler) renderStream(w http.ResponseWriter, r *http.Request) {
+	// Use pq to Get SSE limit.
+	pq, err := actions.GetPageQuery(handler.action.LedgerState, r, actions.DisableCursorValidation)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	// Resume from the last bucket sent to the client if it reconnects.
+	var since int64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		since, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			problem.Render(r.Context(), w, problem.MakeInvalidFieldProblem("Last-Event-ID", err))
+			return
+		}
+	}
+
+	// The latest bucket is polled until a newer one exists and only sent
+	// again if it has changed.
+	var latest *aurora.TradeAggregation
+	handler.streamHandler.ServeStream(
+		w,
+		r,
+		int(pq.Limit),
+		repeatableReadStream(r, func() ([]sse.Event, error) {
+			records, err := handler.action.GetStreamedResources(r, since)
+			if err != nil {
+				return nil, err
+			}
+
+			events := make([]sse.Event, 0, len(records))
+			for i := range records {
+				if latest != nil && *latest == records[i] {
+					continue
+				}
+				events = append(events, sse.Event{ID: records[i].PagingToken(), Data: records[i]})
+			}
+			if len(records) > 0 {
+				latest = &records[len(records)-1]
+				since = latest.Timestamp
+			}
+			return events, nil
+		}),
+	)
+}
@@ -15,7 +15,9 @@ import (
 
 	"github.com/diamcircle/go/services/aurora/internal/actions"
 	auroraContext "github.com/diamcircle/go/services/aurora/internal/context"
+	"github.com/diamcircle/go/services/aurora/internal/db2/embedded"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/db2/replicas"
 	"github.com/diamcircle/go/services/aurora/internal/errors"
 	"github.com/diamcircle/go/services/aurora/internal/hchi"
 	"github.com/diamcircle/go/services/aurora/internal/ingest"
@@ -231,8 +233,11 @@ func recoverMiddleware(h http.Handler) http.Handler {
 
 // NewHistoryMiddleware adds session to the request context and ensures Aurora
 // is not in a stale state, which is when the difference between latest core
-// ledger and latest history ledger is higher than the given threshold
-func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, session db.SessionInterface) func(http.Handler) http.Handler {
+// ledger and latest history ledger is higher than the given threshold.
+// When replicaPool is set, the session reads from a read replica which had
+// caught up with the primary database at the last update of the pool, or from
+// the primary database.
+func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, session db.SessionInterface, replicaPool *replicas.Pool) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -255,7 +260,7 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 				}
 			}
 
-			requestSession := session.Clone()
+			requestSession := selectReplica(replicaPool, session).Session.Clone()
 			h.ServeHTTP(w, r.WithContext(
 				context.WithValue(
 					ctx,
@@ -273,10 +278,23 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 // has been verified and is correct (Otherwise returns `500 Internal Server Error` to prevent
 // returning invalid data to the user)
 type StateMiddleware struct {
-	AuroraSession      db.SessionInterface
+	AuroraSession db.SessionInterface
+	// Replicas, when set, routes the request to a read replica which has
+	// ingested the last ledger ingested into the primary database. The
+	// request falls back to the primary database if there is none.
+	Replicas            *replicas.Pool
 	NoStateVerification bool
 }
 
+// selectReplica returns the database serving a request which needs a
+// ledger-consistent view of the database.
+func selectReplica(replicaPool *replicas.Pool, session db.SessionInterface) replicas.Replica {
+	if replicaPool == nil {
+		return replicas.Replica{Name: replicas.PrimaryName, Session: session}
+	}
+	return replicaPool.Select()
+}
+
 func ingestionStatus(ctx context.Context, q *history.Q) (uint32, bool, error) {
 	version, err := q.GetIngestVersion(ctx)
 	if err != nil {
@@ -305,6 +323,48 @@ func ingestionStatus(ctx context.Context, q *history.Q) (uint32, bool, error) {
 	return lastIngestedLedger, ready, nil
 }
 
+type stateStatus struct {
+	stateInvalid       bool
+	lastIngestedLedger uint32
+	ready              bool
+}
+
+// beginTx starts a repeatable read transaction on the given database and
+// loads the ingestion status within the transaction.
+func (m *StateMiddleware) beginTx(ctx context.Context, replica replicas.Replica) (db.SessionInterface, stateStatus, error) {
+	var status stateStatus
+	session := replica.Session.Clone()
+	q := &history.Q{session}
+
+	// We want to start a repeatable read session to ensure that the data we
+	// fetch from the db belong to the same ledger.
+	// Otherwise, because the ingestion system is running concurrently with this request,
+	// it is possible to have one read fetch data from ledger N and another read
+	// fetch data from ledger N+1 .
+	err := session.BeginTx(&sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return nil, status, supportErrors.Wrap(err, "Error starting ingestion read transaction")
+	}
+
+	if !m.NoStateVerification {
+		status.stateInvalid, err = q.GetExpStateInvalid(ctx)
+		if err != nil {
+			session.Rollback()
+			return nil, status, supportErrors.Wrap(err, "Error running GetExpStateInvalid")
+		}
+	}
+
+	status.lastIngestedLedger, status.ready, err = ingestionStatus(ctx, q)
+	if err != nil {
+		session.Rollback()
+		return nil, status, err
+	}
+	return session, status, nil
+}
+
 // WrapFunc executes the middleware on a given HTTP handler function
 func (m *StateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -313,45 +373,29 @@ func (m *StateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 		if chiRoute != nil {
 			ctx = context.WithValue(ctx, &db.RouteContextKey, sanitizeMetricRoute(chiRoute.RoutePattern()))
 		}
-		session := m.AuroraSession.Clone()
-		q := &history.Q{session}
 		sseRequest := render.Negotiate(r) == render.MimeEventStream
 
-		// We want to start a repeatable read session to ensure that the data we
-		// fetch from the db belong to the same ledger.
-		// Otherwise, because the ingestion system is running concurrently with this request,
-		// it is possible to have one read fetch data from ledger N and another read
-		// fetch data from ledger N+1 .
-		err := session.BeginTx(&sql.TxOptions{
-			Isolation: sql.LevelRepeatableRead,
-			ReadOnly:  true,
-		})
+		replica := selectReplica(m.Replicas, m.AuroraSession)
+		session, status, err := m.beginTx(ctx, replica)
+		if err == nil && m.Replicas != nil && !m.Replicas.CaughtUp(replica.Name, status.lastIngestedLedger) {
+			// The replica has fallen behind the primary database since its
+			// last ingested ledger was loaded.
+			session.Rollback()
+			session, status, err = m.beginTx(ctx, m.Replicas.Primary())
+		}
 		if err != nil {
-			err = supportErrors.Wrap(err, "Error starting ingestion read transaction")
 			problem.Render(ctx, w, err)
 			return
 		}
 		defer session.Rollback()
 
-		if !m.NoStateVerification {
-			stateInvalid, invalidErr := q.GetExpStateInvalid(ctx)
-			if invalidErr != nil {
-				invalidErr = supportErrors.Wrap(invalidErr, "Error running GetExpStateInvalid")
-				problem.Render(ctx, w, invalidErr)
-				return
-			}
-			if stateInvalid {
-				problem.Render(ctx, w, problem.ServerError)
-				return
-			}
-		}
-
-		lastIngestedLedger, ready, err := ingestionStatus(ctx, q)
-		if err != nil {
-			problem.Render(ctx, w, err)
+		if !m.NoStateVerification && status.stateInvalid {
+			problem.Render(ctx, w, problem.ServerError)
 			return
 		}
-		if !m.NoStateVerification && !ready {
+
+		lastIngestedLedger := status.lastIngestedLedger
+		if !m.NoStateVerification && !status.ready {
 			problem.Render(ctx, w, hProblem.StillIngesting)
 			return
 		}
@@ -386,50 +430,39 @@ func (m *StateMiddleware) Wrap(h http.Handler) http.Handler {
 	return m.WrapFunc(h.ServeHTTP)
 }
 
-type ReplicaSyncCheckMiddleware struct {
-	PrimaryHistoryQ *history.Q
-	ReplicaHistoryQ *history.Q
-	ServerMetrics   *ServerMetrics
+// EmbeddedStateMiddleware is a middleware which enables a state handler backed
+// by the embedded store once the store has ingested the state. Unlike
+// StateMiddleware, the reads of a request are not guaranteed to see the same
+// ledger.
+type EmbeddedStateMiddleware struct {
+	Store *embedded.Store
 }
 
 // WrapFunc executes the middleware on a given HTTP handler function
-func (m *ReplicaSyncCheckMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
+func (m *EmbeddedStateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		for attempt := 1; attempt <= 4; attempt++ {
-			primaryIngestLedger, err := m.PrimaryHistoryQ.GetLastLedgerIngestNonBlocking(r.Context())
-			if err != nil {
-				problem.Render(r.Context(), w, err)
-				return
-			}
-
-			replicaIngestLedger, err := m.ReplicaHistoryQ.GetLastLedgerIngestNonBlocking(r.Context())
-			if err != nil {
-				problem.Render(r.Context(), w, err)
-				return
-			}
-
-			if replicaIngestLedger >= primaryIngestLedger {
-				break
-			}
+		ctx := r.Context()
+		lastIngestedLedger, err := m.Store.GetLastLedgerIngestNonBlocking(ctx)
+		if err != nil {
+			problem.Render(ctx, w, err)
+			return
+		}
+		if lastIngestedLedger == 0 {
+			problem.Render(ctx, w, hProblem.StillIngesting)
+			return
+		}
 
-			switch attempt {
-			case 1:
-				time.Sleep(20 * time.Millisecond)
-			case 2:
-				time.Sleep(40 * time.Millisecond)
-			case 3:
-				time.Sleep(80 * time.Millisecond)
-			case 4:
-				problem.Render(r.Context(), w, hProblem.StaleHistory)
-				m.ServerMetrics.ReplicaLagErrorsCounter.Inc()
-				return
-			}
+		if render.Negotiate(r) != render.MimeEventStream {
+			actions.SetLastLedgerHeader(w, lastIngestedLedger)
 		}
 
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(w, r.WithContext(
+			context.WithValue(ctx, &auroraContext.EmbeddedStoreContextKey, history.QInterface(m.Store)),
+		))
 	}
 }
 
-func (m *ReplicaSyncCheckMiddleware) Wrap(h http.Handler) http.Handler {
+// Wrap executes the middleware on a given HTTP handler
+func (m *EmbeddedStateMiddleware) Wrap(h http.Handler) http.Handler {
 	return m.WrapFunc(h.ServeHTTP)
 }
@@ -16,7 +16,11 @@ import (
 	"github.com/diamcircle/throttled"
 
 	"github.com/diamcircle/go/services/aurora/internal/actions"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
+	"github.com/diamcircle/go/services/aurora/internal/db2/embedded"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/db2/replicas"
+	"github.com/diamcircle/go/services/aurora/internal/ingest"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
 	"github.com/diamcircle/go/services/aurora/internal/paths"
 	"github.com/diamcircle/go/services/aurora/internal/render/sse"
@@ -29,6 +33,7 @@ import (
 type RouterConfig struct {
 	DBSession        db.SessionInterface
 	PrimaryDBSession db.SessionInterface
+	Replicas         *replicas.Pool
 	TxSubmitter      *txsub.System
 	RateQuota        *throttled.RateQuota
 
@@ -46,6 +51,12 @@ type RouterConfig struct {
 	AuroraVersion          string
 	FriendbotURL            *url.URL
 	HealthCheck             http.Handler
+	ColdStorage             *coldstorage.Store
+	IngestLeader            ingest.LeaderElector
+	CaptiveCore             ingest.CaptiveCoreMonitor
+	// EmbeddedStore, when set, serves the state endpoints from the embedded
+	// store. The other endpoints are not mounted.
+	EmbeddedStore *embedded.Store
 }
 
 type Router struct {
@@ -67,7 +78,11 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 		}
 	}
 	result.addMiddleware(config, rateLimiter, serverMetrics)
-	result.addRoutes(config, rateLimiter, ledgerState)
+	if config.EmbeddedStore != nil {
+		result.addEmbeddedRoutes(config, rateLimiter, ledgerState)
+	} else {
+		result.addRoutes(config, rateLimiter, ledgerState)
+	}
 	return &result, nil
 }
 
@@ -100,15 +115,6 @@ func (r *Router) addMiddleware(config *RouterConfig,
 		r.Use(rateLimitter.RateLimit)
 	}
 
-	if config.PrimaryDBSession != nil {
-		replicaSyncMiddleware := ReplicaSyncCheckMiddleware{
-			PrimaryHistoryQ: &history.Q{config.PrimaryDBSession},
-			ReplicaHistoryQ: &history.Q{config.DBSession},
-			ServerMetrics:   serverMetrics,
-		}
-		r.Use(replicaSyncMiddleware.Wrap)
-	}
-
 	// Internal middlewares
 	r.Internal.Use(chimiddleware.StripSlashes)
 	r.Internal.Use(chimiddleware.RequestID)
@@ -118,6 +124,7 @@ func (r *Router) addMiddleware(config *RouterConfig,
 func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
 	stateMiddleware := StateMiddleware{
 		AuroraSession: config.DBSession,
+		Replicas:      config.Replicas,
 	}
 
 	r.Method(http.MethodGet, "/health", config.HealthCheck)
@@ -135,7 +142,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
 	}
 
-	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession)
+	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession, config.Replicas)
 	// State endpoints behind stateMiddleware
 	r.Group(func(r chi.Router) {
 		r.Route("/accounts", func(r chi.Router) {
@@ -174,6 +181,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
+				r.With(historyMiddleware).Method(http.MethodGet, "/stats", ObjectActionHandler{actions.GetLiquidityPoolStatsHandler{LedgerState: ledgerState}})
 			})
 		})
 
@@ -200,6 +208,16 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths", findPaths)
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-receive", findPaths)
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/strict-send", findFixedPaths)
+		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/paths/split", ObjectActionHandler{actions.FindSplitPathsHandler{
+			MaxPathLength:       config.MaxPathLength,
+			SetLastLedgerHeader: true,
+			PathFinder:          config.PathFinder,
+		}})
+		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/prices", ObjectActionHandler{actions.GetAssetPriceHandler{
+			LedgerState:   ledgerState,
+			PathFinder:    config.PathFinder,
+			MaxPathLength: config.MaxPathLength,
+		}})
 		r.With(stateMiddleware.Wrap).Method(
 			http.MethodGet,
 			"/order_book",
@@ -218,19 +236,25 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/operations", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 			LedgerState:  ledgerState,
 			OnlyPayments: false,
+			ColdStorage:  config.ColdStorage,
 		}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/payments", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 			LedgerState:  ledgerState,
 			OnlyPayments: true,
+			ColdStorage:  config.ColdStorage,
 		}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
+		r.With(historyMiddleware).Method(http.MethodGet, "/accounts/{account_id:\\w+}/liquidity_pool_positions", restPageHandler(ledgerState, actions.GetLiquidityPoolPositionsHandler{LedgerState: ledgerState}))
 	})
 	// ledger actions
 	r.Route("/ledgers", func(r chi.Router) {
 		r.With(historyMiddleware).Method(http.MethodGet, "/", streamableHistoryPageHandler(ledgerState, actions.GetLedgersHandler{LedgerState: ledgerState}, streamHandler))
 		r.Route("/{ledger_id}", func(r chi.Router) {
-			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetLedgerByIDHandler{LedgerState: ledgerState}})
+			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetLedgerByIDHandler{
+				LedgerState: ledgerState,
+				ColdStorage: config.ColdStorage,
+			}})
 			r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 			r.Group(func(r chi.Router) {
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
@@ -259,7 +283,9 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Route("/transactions", func(r chi.Router) {
 		r.With(historyMiddleware).Method(http.MethodGet, "/", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 		r.Route("/{tx_id}", func(r chi.Router) {
-			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetTransactionByHashHandler{}})
+			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetTransactionByHashHandler{
+				ColdStorage: config.ColdStorage,
+			}})
 			r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 			r.With(historyMiddleware).Method(http.MethodGet, "/operations", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 				LedgerState:  ledgerState,
@@ -294,7 +320,10 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 
 		// trading related endpoints
 		r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
-		r.With(historyMiddleware).Method(http.MethodGet, "/trade_aggregations", ObjectActionHandler{actions.GetTradeAggregationsHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}})
+		r.With(historyMiddleware).Method(http.MethodGet, "/trade_aggregations", tradeAggregationsHandler{
+			action:        actions.GetTradeAggregationsHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter},
+			streamHandler: streamHandler,
+		})
 		// /offers/{offer_id} has been created above so we need to use absolute
 		// routes here.
 		r.With(historyMiddleware).Method(http.MethodGet, "/offers/{offer_id}/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
@@ -328,4 +357,100 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Internal.Get("/metrics", promhttp.HandlerFor(config.PrometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP)
 	r.Internal.Get("/debug/pprof/heap", pprof.Index)
 	r.Internal.Get("/debug/pprof/profile", pprof.Profile)
+
+	// state verification reports are read from the primary database, since
+	// they are written by the ingesting instance
+	reportsHistoryQ := &history.Q{config.DBSession}
+	if config.PrimaryDBSession != nil {
+		reportsHistoryQ = &history.Q{config.PrimaryDBSession}
+	}
+	r.Internal.Route("/state_verification/reports", func(r chi.Router) {
+		r.Use(contextMiddleware)
+		r.Method(http.MethodGet, "/", restPageHandler(ledgerState, actions.GetStateVerificationReportsHandler{
+			HistoryQ:    reportsHistoryQ,
+			LedgerState: ledgerState,
+		}))
+		r.Method(http.MethodGet, "/{id}", ObjectActionHandler{actions.GetStateVerificationReportHandler{
+			HistoryQ: reportsHistoryQ,
+		}})
+	})
+
+	if config.IngestLeader != nil {
+		r.Internal.Route("/ingestion/leader", func(r chi.Router) {
+			r.Use(contextMiddleware)
+			r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetIngestLeaderHandler{
+				Leader: config.IngestLeader,
+			}})
+			r.Method(http.MethodPost, "/handoff", ObjectActionHandler{actions.IngestLeaderHandoffHandler{
+				Leader: config.IngestLeader,
+			}})
+		})
+	}
+
+	if config.CaptiveCore != nil {
+		r.Internal.Route("/captive_core", func(r chi.Router) {
+			r.Use(contextMiddleware)
+			r.Method(http.MethodGet, "/", ObjectActionHandler{actions.GetCaptiveCoreStatusHandler{
+				CaptiveCore: config.CaptiveCore,
+			}})
+			r.Method(http.MethodGet, "/logs", ObjectActionHandler{actions.GetCaptiveCoreLogsHandler{
+				CaptiveCore: config.CaptiveCore,
+			}})
+		})
+	}
+}
+
+// addEmbeddedRoutes mounts the endpoints which can be served from the embedded
+// store.
+func (r *Router) addEmbeddedRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
+	stateMiddleware := EmbeddedStateMiddleware{Store: config.EmbeddedStore}
+
+	r.Method(http.MethodGet, "/health", config.HealthCheck)
+
+	r.Method(http.MethodGet, "/", ObjectActionHandler{Action: actions.GetRootHandler{
+		LedgerState:       ledgerState,
+		CoreStateGetter:   config.CoreGetter,
+		NetworkPassphrase: config.NetworkPassphrase,
+		FriendbotURL:      config.FriendbotURL,
+		AuroraVersion:    config.AuroraVersion,
+	}})
+
+	streamHandler := sse.StreamHandler{
+		RateLimiter:         rateLimiter,
+		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(stateMiddleware.Wrap)
+		r.Route("/accounts/{account_id}", func(r chi.Router) {
+			r.Method(
+				http.MethodGet,
+				"/",
+				streamableObjectActionHandler{
+					streamHandler: streamHandler,
+					action:        actions.GetAccountByIDHandler{},
+				},
+			)
+			accountData := actions.GetAccountDataHandler{}
+			r.Method(http.MethodGet, "/data/{key}", WrapRaw(
+				streamableObjectActionHandler{streamHandler: streamHandler, action: accountData},
+				accountData,
+			))
+			r.Method(http.MethodGet, "/offers", streamableStatePageHandler(ledgerState, actions.GetAccountOffersHandler{LedgerState: ledgerState}, streamHandler))
+		})
+
+		r.Route("/offers", func(r chi.Router) {
+			r.Method(http.MethodGet, "/", restPageHandler(ledgerState, actions.GetOffersHandler{LedgerState: ledgerState}))
+			r.Method(http.MethodGet, "/{offer_id}", ObjectActionHandler{actions.GetOfferByID{}})
+		})
+	})
+
+	r.NotFound(func(w http.ResponseWriter, request *http.Request) {
+		problem.Render(request.Context(), w, problem.NotFound)
+	})
+
+	// internal
+	r.Internal.Get("/metrics", promhttp.HandlerFor(config.PrometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP)
+	r.Internal.Get("/debug/pprof/heap", pprof.Index)
+	r.Internal.Get("/debug/pprof/profile", pprof.Profile)
 }
@@ -22,8 +22,7 @@ import (
 )
 
 type ServerMetrics struct {
-	RequestDurationSummary  *prometheus.SummaryVec
-	ReplicaLagErrorsCounter prometheus.Counter
+	RequestDurationSummary *prometheus.SummaryVec
 }
 
 type TLSConfig struct {
@@ -71,12 +70,6 @@ func NewServer(serverConfig ServerConfig, routerConfig RouterConfig, ledgerState
 			},
 			[]string{"status", "route", "streaming", "method"},
 		),
-		ReplicaLagErrorsCounter: prometheus.NewCounter(
-			prometheus.CounterOpts{
-				Namespace: "aurora", Subsystem: "http", Name: "replica_lag_errors_count",
-				Help: "Count of HTTP errors returned due to replica lag",
-			},
-		),
 	}
 	router, err := NewRouter(&routerConfig, sm, ledgerState)
 	if err != nil {
@@ -108,7 +101,6 @@ func NewServer(serverConfig ServerConfig, routerConfig RouterConfig, ledgerState
 // RegisterMetrics registers the prometheus metrics
 func (s *Server) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.Metrics.RequestDurationSummary)
-	registry.MustRegister(s.Metrics.ReplicaLagErrorsCounter)
 }
 
 func (s *Server) Serve() error {
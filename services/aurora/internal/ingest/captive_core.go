@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+)
+
+// CaptiveCoreMonitor reports the status of the captive core process.
+type CaptiveCoreMonitor interface {
+	Status(ctx context.Context) ledgerbackend.CaptiveCoreStatus
+	RecentLogLines() []ledgerbackend.CaptiveCoreLogLine
+}
+
+var (
+	captiveCoreRunningDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_running",
+		"1 if the captive core process is running, 0 otherwise.",
+		nil, nil,
+	)
+	captiveCoreRestartsDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_restarts_total",
+		"number of restarts of captive core after it exited unexpectedly.",
+		nil, nil,
+	)
+	captiveCoreStateDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_state",
+		"1 for the state captive core last logged it changed to.",
+		[]string{"state"}, nil,
+	)
+	captiveCoreCatchupTargetDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_catchup_target_ledger",
+		"ledger captive core last logged it is catching up to.",
+		nil, nil,
+	)
+	captiveCoreCatchupProgressDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_catchup_progress",
+		"catchup progress last logged by captive core, between 0 and 1.",
+		nil, nil,
+	)
+	captiveCoreMemoryDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_resident_memory_bytes",
+		"resident memory of the captive core process.",
+		nil, nil,
+	)
+	captiveCoreUptimeDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_uptime_seconds",
+		"time since the captive core process was started.",
+		nil, nil,
+	)
+	captiveCoreLedgerDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_ledger",
+		"last ledger closed by captive core, from its /info endpoint.",
+		nil, nil,
+	)
+	captiveCorePeersDesc = prometheus.NewDesc(
+		"aurora_ingest_captive_core_peers",
+		"number of peers of captive core, from its /info endpoint.",
+		[]string{"type"}, nil,
+	)
+)
+
+// captiveCoreCollector is a prometheus collector of the status of the captive
+// core process. The status is loaded once per scrape.
+type captiveCoreCollector struct {
+	ctx         context.Context
+	captiveCore CaptiveCoreMonitor
+}
+
+func (c captiveCoreCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- captiveCoreRunningDesc
+	ch <- captiveCoreRestartsDesc
+	ch <- captiveCoreStateDesc
+	ch <- captiveCoreCatchupTargetDesc
+	ch <- captiveCoreCatchupProgressDesc
+	ch <- captiveCoreMemoryDesc
+	ch <- captiveCoreUptimeDesc
+	ch <- captiveCoreLedgerDesc
+	ch <- captiveCorePeersDesc
+}
+
+func (c captiveCoreCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(c.ctx, 3*time.Second)
+	defer cancel()
+	status := c.captiveCore.Status(ctx)
+
+	running := float64(0)
+	if status.Running {
+		running = 1
+	}
+	ch <- prometheus.MustNewConstMetric(captiveCoreRunningDesc, prometheus.GaugeValue, running)
+	ch <- prometheus.MustNewConstMetric(captiveCoreRestartsDesc, prometheus.CounterValue, float64(status.Restarts))
+	if !status.Running {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(captiveCoreUptimeDesc, prometheus.GaugeValue, time.Since(status.StartedAt).Seconds())
+	if status.State != "" {
+		ch <- prometheus.MustNewConstMetric(captiveCoreStateDesc, prometheus.GaugeValue, 1, status.State)
+	}
+	if status.CatchupTargetLedger != 0 {
+		ch <- prometheus.MustNewConstMetric(captiveCoreCatchupTargetDesc, prometheus.GaugeValue, float64(status.CatchupTargetLedger))
+	}
+	if status.CatchupProgress >= 0 {
+		ch <- prometheus.MustNewConstMetric(captiveCoreCatchupProgressDesc, prometheus.GaugeValue, status.CatchupProgress)
+	}
+	if status.ResidentMemoryBytes != 0 {
+		ch <- prometheus.MustNewConstMetric(captiveCoreMemoryDesc, prometheus.GaugeValue, float64(status.ResidentMemoryBytes))
+	}
+	if status.Info != nil {
+		ch <- prometheus.MustNewConstMetric(captiveCoreLedgerDesc, prometheus.GaugeValue, float64(status.Info.Info.Ledger.Num))
+		ch <- prometheus.MustNewConstMetric(captiveCorePeersDesc, prometheus.GaugeValue, float64(status.Info.Info.Peers.AuthenticatedCount), "authenticated")
+		ch <- prometheus.MustNewConstMetric(captiveCorePeersDesc, prometheus.GaugeValue, float64(status.Info.Info.Peers.PendingCount), "pending")
+	}
+}
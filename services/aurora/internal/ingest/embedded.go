@@ -0,0 +1,277 @@
+package ingest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/aurora/internal/db2/embedded"
+	"github.com/diamcircle/go/services/aurora/internal/ingest/processors"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+// EmbeddedSystem ingests the network into an embedded store, for single-node
+// deployments without a database. Only the state (accounts, signers, trust
+// lines, account data and offers) and the ledger headers are ingested.
+type EmbeddedSystem struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	config            Config
+	store             *embedded.Store
+	historyAdapter    historyArchiveAdapterInterface
+	ledgerBackend     ledgerbackend.LedgerBackend
+	runner            *ProcessorRunner
+	checkpointManager historyarchive.CheckpointManager
+}
+
+// NewEmbeddedSystem creates an ingestion system writing to store.
+func NewEmbeddedSystem(config Config, store *embedded.Store) (*EmbeddedSystem, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	archive, err := historyarchive.Connect(
+		config.HistoryArchiveURL,
+		historyarchive.ConnectOptions{
+			Context:             ctx,
+			NetworkPassphrase:   config.NetworkPassphrase,
+			CheckpointFrequency: config.CheckpointFrequency,
+		},
+	)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "error creating history archive")
+	}
+
+	ledgerBackend, err := newLedgerBackend(ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	historyAdapter := newHistoryArchiveAdapter(archive)
+	system := &EmbeddedSystem{
+		ctx:            ctx,
+		cancel:         cancel,
+		config:         config,
+		store:          store,
+		historyAdapter: historyAdapter,
+		ledgerBackend:  ledgerBackend,
+		runner: &ProcessorRunner{
+			ctx:            ctx,
+			config:         config,
+			historyAdapter: historyAdapter,
+		},
+		checkpointManager: historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+	}
+	// Added here rather than in Run, which is started in its own goroutine,
+	// so that Shutdown always waits for Run to return.
+	system.wg.Add(1)
+	return system, nil
+}
+
+// Run ingests ledgers until Shutdown is called. The state is built from the
+// latest checkpoint when the store is empty. Run must be called exactly once.
+func (s *EmbeddedSystem) Run() {
+	defer s.wg.Done()
+
+	for {
+		err := s.run()
+		if s.ctx.Err() != nil {
+			return
+		}
+		log.WithError(err).Error("Error in embedded ingestion")
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (s *EmbeddedSystem) run() error {
+	lastLedger, err := s.store.GetLastLedgerIngestNonBlocking(s.ctx)
+	if err != nil {
+		return errors.Wrap(err, getLastIngestedErrMsg)
+	}
+
+	if lastLedger == 0 {
+		if lastLedger, err = s.buildState(); err != nil {
+			return err
+		}
+	}
+
+	if err = s.prepareRange(lastLedger + 1); err != nil {
+		return err
+	}
+
+	for sequence := lastLedger + 1; ; sequence++ {
+		ledger, err := s.ledgerBackend.GetLedger(s.ctx, sequence)
+		if err != nil {
+			return errors.Wrap(err, "error getting ledger from the backend")
+		}
+
+		startTime := time.Now()
+		err = s.store.Update(func(tx *embedded.Tx) error {
+			return s.ingestLedger(tx, ledger)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error ingesting ledger %d", sequence)
+		}
+		log.WithFields(logpkg.F{
+			"sequence": sequence,
+			"duration": time.Since(startTime).Seconds(),
+		}).Info("Processed ledger")
+
+		if s.checkpointManager.IsCheckpoint(sequence) {
+			if err = s.store.Save(); err != nil {
+				return errors.Wrap(err, "error saving embedded store")
+			}
+		}
+	}
+}
+
+// buildState ingests the state of the latest checkpoint into the store and
+// returns the checkpoint ledger.
+func (s *EmbeddedSystem) buildState() (uint32, error) {
+	checkpointLedger, err := s.historyAdapter.GetLatestLedgerSequence()
+	if err != nil {
+		return 0, errors.Wrap(err, "error getting last checkpoint")
+	}
+
+	if err = s.prepareRange(checkpointLedger); err != nil {
+		return 0, err
+	}
+	ledger, err := s.ledgerBackend.GetLedger(s.ctx, checkpointLedger)
+	if err != nil {
+		return 0, errors.Wrap(err, "error getting ledger from the backend")
+	}
+	if err = s.runner.checkIfProtocolVersionSupported(ledger.ProtocolVersion()); err != nil {
+		return 0, errors.Wrap(err, "Error while checking for supported protocol version")
+	}
+	if err = s.runner.validateBucketList(checkpointLedger, ledger.BucketListHash()); err != nil {
+		return 0, errors.Wrap(err, "Error validating bucket list from HAS")
+	}
+
+	log.WithField("sequence", checkpointLedger).Info("Processing state")
+	startTime := time.Now()
+
+	err = s.store.Reset(func(tx *embedded.Tx) error {
+		changeReader, err := s.historyAdapter.GetState(s.ctx, checkpointLedger)
+		if err != nil {
+			return errors.Wrap(err, "Error creating HAS reader")
+		}
+		defer changeReader.Close()
+
+		changeProcessor := newEmbeddedChangeProcessor(tx, checkpointLedger, false)
+		if err = processors.StreamChanges(s.ctx, changeProcessor, changeReader); err != nil {
+			return errors.Wrap(err, "Error streaming changes from HAS")
+		}
+		if err = changeProcessor.Commit(s.ctx); err != nil {
+			return errors.Wrap(err, "Error commiting changes from processor")
+		}
+
+		// The state of the checkpoint includes the changes of the checkpoint
+		// ledger so only its header is left to ingest.
+		if err = s.ingestLedgerHeader(tx, ledger); err != nil {
+			return err
+		}
+		return tx.UpdateLastLedgerIngest(s.ctx, checkpointLedger)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "Error ingesting history archive")
+	}
+	log.WithFields(logpkg.F{
+		"sequence": checkpointLedger,
+		"duration": time.Since(startTime).Seconds(),
+	}).Info("Processed state")
+
+	if err = s.store.Save(); err != nil {
+		return 0, errors.Wrap(err, "error saving embedded store")
+	}
+	return checkpointLedger, nil
+}
+
+func (s *EmbeddedSystem) ingestLedger(tx *embedded.Tx, ledger xdr.LedgerCloseMeta) error {
+	changeReader, err := ingest.NewLedgerChangeReaderFromLedgerCloseMeta(s.config.NetworkPassphrase, ledger)
+	if err != nil {
+		return errors.Wrap(err, "Error creating ledger change reader")
+	}
+	changeProcessor := newEmbeddedChangeProcessor(tx, ledger.LedgerSequence(), true)
+	if err = processors.StreamChanges(s.ctx, changeProcessor, changeReader); err != nil {
+		return errors.Wrap(err, "Error streaming changes from ledger")
+	}
+	if err = changeProcessor.Commit(s.ctx); err != nil {
+		return errors.Wrap(err, "Error commiting changes from processor")
+	}
+
+	if err = s.ingestLedgerHeader(tx, ledger); err != nil {
+		return err
+	}
+	return tx.UpdateLastLedgerIngest(s.ctx, ledger.LedgerSequence())
+}
+
+func (s *EmbeddedSystem) ingestLedgerHeader(tx *embedded.Tx, ledger xdr.LedgerCloseMeta) error {
+	transactionReader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(s.config.NetworkPassphrase, ledger)
+	if err != nil {
+		return errors.Wrap(err, "Error creating ledger reader")
+	}
+	if err = s.runner.checkIfProtocolVersionSupported(ledger.ProtocolVersion()); err != nil {
+		return errors.Wrap(err, "Error while checking for supported protocol version")
+	}
+
+	ledgerProcessor := processors.NewLedgerProcessor(tx, transactionReader.GetHeader(), CurrentVersion)
+	if err = processors.StreamLedgerTransactions(s.ctx, ledgerProcessor, transactionReader); err != nil {
+		return errors.Wrap(err, "Error streaming changes from ledger")
+	}
+	if err = ledgerProcessor.Commit(s.ctx); err != nil {
+		return errors.Wrap(err, "Error committing changes from processor")
+	}
+	return nil
+}
+
+func (s *EmbeddedSystem) prepareRange(from uint32) error {
+	ledgerRange := ledgerbackend.UnboundedRange(from)
+	prepared, err := s.ledgerBackend.IsPrepared(s.ctx, ledgerRange)
+	if err != nil {
+		return errors.Wrap(err, "error checking prepared range")
+	}
+	if prepared {
+		return nil
+	}
+
+	log.WithField("from", from).Info("Preparing range")
+	if err = s.ledgerBackend.PrepareRange(s.ctx, ledgerRange); err != nil {
+		return errors.Wrap(err, "error preparing range")
+	}
+	return nil
+}
+
+// Shutdown stops ingestion and saves the store.
+func (s *EmbeddedSystem) Shutdown() {
+	log.Info("Shutting down embedded ingestion system...")
+	s.cancel()
+	s.wg.Wait()
+	if err := s.ledgerBackend.Close(); err != nil {
+		log.WithError(err).Info("could not close ledger backend")
+	}
+	if err := s.store.Save(); err != nil {
+		log.WithError(err).Error("could not save embedded store")
+	}
+}
+
+func newEmbeddedChangeProcessor(tx *embedded.Tx, sequence uint32, useLedgerCache bool) *groupChangeProcessors {
+	return newGroupChangeProcessors([]auroraChangeProcessor{
+		processors.NewAccountDataProcessor(tx),
+		processors.NewAccountsProcessor(tx),
+		processors.NewOffersProcessor(tx, sequence),
+		processors.NewSignersProcessor(tx, useLedgerCache),
+		processors.NewTrustLinesProcessor(tx),
+	})
+}
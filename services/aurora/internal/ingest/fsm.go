@@ -118,6 +118,10 @@ func (startState) String() string {
 }
 
 func (state startState) run(s *system) (transition, error) {
+	if !s.isLeader() {
+		return s.followLeader()
+	}
+
 	if err := s.historyQ.Begin(); err != nil {
 		return start(), errors.Wrap(err, "Error starting a transaction")
 	}
@@ -267,6 +271,10 @@ func (b buildState) run(s *system) (transition, error) {
 		}).Info("Ledger returned from the backend")
 	}
 
+	if err := s.ensureHistoryPartitions(b.checkpointLedger, b.checkpointLedger); err != nil {
+		return nextFailState, err
+	}
+
 	if err := s.historyQ.Begin(); err != nil {
 		return nextFailState, errors.Wrap(err, "Error starting a transaction")
 	}
@@ -312,6 +320,13 @@ func (b buildState) run(s *system) (transition, error) {
 		return nextFailState, errors.Wrap(err, updateExpStateInvalidErrMsg)
 	}
 
+	// The state is rebuilt in place so a pending shadow rebuild is not needed
+	// anymore.
+	err = s.historyQ.UpdateStateRebuildRequested(s.ctx, false)
+	if err != nil {
+		return nextFailState, errors.Wrap(err, "Error clearing state rebuild request")
+	}
+
 	// State tables should be empty.
 	err = s.historyQ.TruncateIngestStateTables(s.ctx)
 	if err != nil {
@@ -397,6 +412,24 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	s.Metrics().LedgerFetchDurationSummary.Observe(float64(duration))
 
+	if !s.isLeader() {
+		lastIngestedLedger, err := s.historyQ.GetLastLedgerIngestNonBlocking(s.ctx)
+		if err != nil {
+			return retryResume(r), errors.Wrap(err, getLastIngestedErrMsg)
+		}
+		if ingestLedger <= lastIngestedLedger {
+			return retryResume(resumeState{
+				latestSuccessfullyProcessedLedger: lastIngestedLedger,
+			}), nil
+		}
+		// Wait for the leader to ingest the ledger.
+		return retryResume(r), nil
+	}
+
+	if err = s.ensureHistoryPartitions(ingestLedger, ingestLedger); err != nil {
+		return retryResume(r), err
+	}
+
 	if err = s.historyQ.Begin(); err != nil {
 		return retryResume(r),
 			errors.Wrap(err, "Error starting a transaction")
@@ -513,6 +546,7 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	localLog.Info("Processed ledger")
 
+	s.maybeRebuildShadowState(ledgerCloseMeta)
 	s.maybeVerifyState(ingestLedger)
 
 	return resumeImmediately(ingestLedger), nil
@@ -562,6 +596,10 @@ func (h historyRangeState) run(s *system) (transition, error) {
 		return start(), err
 	}
 
+	if err = s.ensureHistoryPartitions(h.fromLedger, h.toLedger); err != nil {
+		return start(), err
+	}
+
 	if err = s.historyQ.Begin(); err != nil {
 		return start(), errors.Wrap(err, "Error starting a transaction")
 	}
@@ -594,7 +632,10 @@ func (h historyRangeState) run(s *system) (transition, error) {
 		ledgerCloseMeta, err = s.ledgerBackend.GetLedger(s.ctx, cur)
 		if err != nil {
 			// Commit finished work in case of ledger backend error.
-			commitErr := s.historyQ.Commit()
+			commitErr := s.checkLeadership(s.ctx)
+			if commitErr == nil {
+				commitErr = s.historyQ.Commit()
+			}
 			if commitErr != nil {
 				log.WithError(commitErr).Error("Error commiting partial range results")
 			} else {
@@ -611,8 +652,12 @@ func (h historyRangeState) run(s *system) (transition, error) {
 		if err = runTransactionProcessorsOnLedger(s, ledgerCloseMeta); err != nil {
 			return start(), err
 		}
+		s.progress.mark()
 	}
 
+	if err = s.checkLeadership(s.ctx); err != nil {
+		return start(), err
+	}
 	if err = s.historyQ.Commit(); err != nil {
 		return start(), errors.Wrap(err, commitErrMsg)
 	}
@@ -732,6 +777,10 @@ func (h reingestHistoryRangeState) run(s *system) (transition, error) {
 
 	var startTime time.Time
 
+	if err := s.ensureHistoryPartitions(h.fromLedger, h.toLedger); err != nil {
+		return stop(), err
+	}
+
 	if h.force {
 		if t, err := h.prepareRange(s); err != nil {
 			return t, err
@@ -844,6 +893,10 @@ func (v verifyRangeState) run(s *system) (transition, error) {
 		return stop(), errors.Errorf("invalid range: [%d, %d]", v.fromLedger, v.toLedger)
 	}
 
+	if err := s.ensureHistoryPartitions(v.fromLedger, v.toLedger); err != nil {
+		return stop(), err
+	}
+
 	if err := s.historyQ.Begin(); err != nil {
 		err = errors.Wrap(err, "Error starting a transaction")
 		return stop(), err
@@ -1038,6 +1091,10 @@ func (s *system) completeIngestion(ctx context.Context, ledger uint32) error {
 		return errors.New("ledger must be positive")
 	}
 
+	if err := s.checkLeadership(ctx); err != nil {
+		return err
+	}
+
 	if err := s.historyQ.UpdateLastLedgerIngest(ctx, ledger); err != nil {
 		err = errors.Wrap(err, updateLastLedgerIngestErrMsg)
 		return err
@@ -1050,6 +1107,36 @@ func (s *system) completeIngestion(ctx context.Context, ledger uint32) error {
 	return nil
 }
 
+// followLeader is run by the instances which do not lead the ingestion
+// instead of startState. They wait for the leader to ingest the state and
+// then resume from the last ingested ledger, without writing to the database.
+func (s *system) followLeader() (transition, error) {
+	lastIngestedLedger, err := s.historyQ.GetLastLedgerIngestNonBlocking(s.ctx)
+	if err != nil {
+		return start(), errors.Wrap(err, getLastIngestedErrMsg)
+	}
+
+	ingestVersion, err := s.historyQ.GetIngestVersion(s.ctx)
+	if err != nil {
+		return start(), errors.Wrap(err, getIngestVersionErrMsg)
+	}
+
+	if ingestVersion > CurrentVersion {
+		log.WithFields(logpkg.F{
+			"ingestVersion":  ingestVersion,
+			"currentVersion": CurrentVersion,
+		}).Info("ingestion version in db is greater than current version, going to terminate")
+		return stop(), nil
+	}
+
+	if ingestVersion != CurrentVersion || lastIngestedLedger == 0 {
+		// The state is being built by the leader.
+		return start(), nil
+	}
+
+	return resume(lastIngestedLedger), nil
+}
+
 // maybePrepareRange checks if the range is prepared and, if not, prepares it.
 func (s *system) maybePrepareRange(ctx context.Context, from uint32) error {
 	ledgerRange := ledgerbackend.UnboundedRange(from)
@@ -1063,7 +1150,9 @@ func (s *system) maybePrepareRange(ctx context.Context, from uint32) error {
 		log.WithFields(logpkg.F{"from": from}).Info("Preparing range")
 		startTime := time.Now()
 
+		donePreparing := s.progress.startPreparing()
 		err = s.ledgerBackend.PrepareRange(ctx, ledgerRange)
+		donePreparing()
 		if err != nil {
 			return errors.Wrap(err, "error preparing range")
 		}
@@ -0,0 +1,85 @@
+package ingest
+
+import (
+	"context"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+)
+
+// historyPartitions creates the partitions of the history tables ahead of
+// ingestion. It does nothing when the history tables are not partitioned.
+type historyPartitions struct {
+	historyQ *history.Q
+
+	checked bool
+	enabled bool
+	// [createdFrom, createdTo] is a range of ledgers known to have a
+	// partition.
+	createdFrom uint32
+	createdTo   uint32
+}
+
+func newHistoryPartitions(session db.SessionInterface) *historyPartitions {
+	return &historyPartitions{historyQ: &history.Q{session.Clone()}}
+}
+
+func (p *historyPartitions) isEnabled(ctx context.Context) (bool, error) {
+	if !p.checked {
+		partitions, err := p.historyQ.GetHistoryPartitions(ctx)
+		if err != nil {
+			return false, errors.Wrap(err, "could not load history partitions")
+		}
+		p.enabled = len(partitions) > 0
+		p.checked = true
+	}
+	return p.enabled, nil
+}
+
+// ensure creates the missing partitions of the ledgers between fromLedger and
+// toLedger (inclusive). The partitions of the next HistoryPartitionSize
+// ledgers are created as well, so that partitions are usually created before
+// ingestion reaches them.
+func (p *historyPartitions) ensure(ctx context.Context, fromLedger, toLedger uint32) error {
+	if enabled, err := p.isEnabled(ctx); err != nil || !enabled {
+		return err
+	}
+	if p.createdTo > 0 && p.createdFrom <= fromLedger && toLedger <= p.createdTo {
+		return nil
+	}
+
+	toLedger += history.HistoryPartitionSize
+	if err := p.historyQ.Begin(); err != nil {
+		return errors.Wrap(err, "error starting a transaction")
+	}
+	defer p.historyQ.Rollback()
+
+	created, err := p.historyQ.CreateHistoryPartitions(ctx, fromLedger, toLedger)
+	if err != nil {
+		return err
+	}
+	if err = p.historyQ.Commit(); err != nil {
+		return errors.Wrap(err, "error committing history partitions")
+	}
+
+	for _, partition := range created {
+		log.WithField("start_ledger", partition.StartLedger).
+			WithField("end_ledger", partition.EndLedger).
+			Info("Created history partition")
+	}
+	p.createdFrom, p.createdTo = fromLedger, toLedger
+	return nil
+}
+
+// ensureHistoryPartitions creates the missing partitions of the ledgers
+// between fromLedger and toLedger, if the history tables are partitioned.
+func (s *system) ensureHistoryPartitions(fromLedger, toLedger uint32) error {
+	if s.historyPartitions == nil {
+		return nil
+	}
+	return errors.Wrap(
+		s.historyPartitions.ensure(s.ctx, fromLedger, toLedger),
+		"error creating history partitions",
+	)
+}
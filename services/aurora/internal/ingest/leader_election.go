@@ -0,0 +1,497 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+)
+
+const (
+	// LeaderElectionAdvisoryLock elects the instance holding a session
+	// advisory lock on a dedicated database connection.
+	LeaderElectionAdvisoryLock = "advisory-lock"
+	// LeaderElectionLease elects the instance holding a lease, stored in the
+	// database, which it renews with heartbeats.
+	LeaderElectionLease = "lease"
+
+	// leaderLeaseTTL is the time after which the lease of a leader which
+	// stopped sending heartbeats expires.
+	leaderLeaseTTL = 30 * time.Second
+	// leaderHeartbeatInterval is the interval at which instances campaign
+	// for, or renew, the leadership.
+	leaderHeartbeatInterval = leaderLeaseTTL / 3
+	// leaderHandoffCooldown is the time an instance which gave up the
+	// leadership waits before campaigning again, so that another instance
+	// takes over.
+	leaderHandoffCooldown = 2 * leaderLeaseTTL
+	// leaderReleaseTimeout is the timeout of releasing the leadership on
+	// shutdown.
+	leaderReleaseTimeout = 5 * time.Second
+	// leaderProgressTimeout is the time after which a leader whose ingestion
+	// makes no progress gives up the leadership, so that an instance which
+	// is stuck, for example waiting for a wedged diamcircle-core, does not
+	// keep renewing it.
+	leaderProgressTimeout = 10 * time.Minute
+)
+
+var errNotLeader = errors.New("this instance is not the ingestion leader")
+
+// LeaderStatus is the leadership status seen by an aurora instance.
+type LeaderStatus struct {
+	// Backend is the leader election implementation.
+	Backend string
+	// NodeID identifies this instance.
+	NodeID string
+	// Leader is true if this instance is the leader.
+	Leader bool
+	// LeaderID identifies the leading instance, empty if there is none.
+	LeaderID string
+	// LeaderDatabasePID is the process id of the database session holding
+	// the advisory lock.
+	LeaderDatabasePID int
+	// FencingToken is incremented every time the lease changes hands.
+	FencingToken int64
+	// AcquiredAt is the time the leadership was acquired, zero if unknown.
+	AcquiredAt time.Time
+	// RenewedAt is the time of the last heartbeat of the leader.
+	RenewedAt time.Time
+	// ExpiresAt is the time the lease expires if it is not renewed.
+	ExpiresAt time.Time
+}
+
+// LeaderElector elects the aurora instance leading the ingestion. The other
+// instances follow the ledgers ingested by the leader. Implementations are
+// safe for concurrent use.
+type LeaderElector interface {
+	// Campaign acquires the leadership if it is free, or renews it if this
+	// instance is the leader. It returns true if this instance is the
+	// leader.
+	Campaign(ctx context.Context) (bool, error)
+	// IsLeader returns true if this instance was the leader at the last
+	// Campaign.
+	IsLeader() bool
+	// Fence returns an error if this instance is no longer the leader. It
+	// runs in the transaction of q so that the transaction of a deposed
+	// leader is not committed.
+	Fence(ctx context.Context, q history.IngestionQ) error
+	// Resign gives up the leadership. This instance does not campaign again
+	// before leaderHandoffCooldown.
+	Resign(ctx context.Context) error
+	// ForceHandoff deposes the current leader, whichever instance it is.
+	ForceHandoff(ctx context.Context) error
+	// Status returns the leadership status.
+	Status(ctx context.Context) (LeaderStatus, error)
+}
+
+// NewLeaderElector returns the leader elector of the given backend, nil if
+// backend is empty. nodeID identifies this instance, it defaults to the
+// hostname and process id.
+func NewLeaderElector(backend, nodeID string, historySession db.SessionInterface) (LeaderElector, error) {
+	if backend == "" {
+		return nil, nil
+	}
+	if nodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get hostname")
+		}
+		nodeID = fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	}
+
+	switch backend {
+	case LeaderElectionAdvisoryLock:
+		session, ok := historySession.(*db.Session)
+		if !ok {
+			return nil, errors.New("advisory lock leader election requires a database session")
+		}
+		return &advisoryLockElector{
+			db:     session.DB,
+			q:      &history.Q{session.Clone()},
+			nodeID: nodeID,
+		}, nil
+	case LeaderElectionLease:
+		return &leaseElector{
+			q:      &history.Q{historySession.Clone()},
+			nodeID: nodeID,
+			ttl:    leaderLeaseTTL,
+		}, nil
+	default:
+		return nil, errors.Errorf("unknown leader election backend: %s", backend)
+	}
+}
+
+// leaseElector elects the instance holding the lease in the
+// `ingest_leader_lease` table. The leader renews the lease with heartbeats.
+// The lease of a leader which stops sending heartbeats expires after ttl, and
+// its fencing token prevents its ingestion transactions from being committed.
+type leaseElector struct {
+	q      *history.Q
+	nodeID string
+	ttl    time.Duration
+
+	mutex         sync.Mutex
+	leader        bool
+	lease         history.LeaderLease
+	cooldownUntil time.Time
+}
+
+func (e *leaseElector) Campaign(ctx context.Context) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.leader {
+		lease, renewed, err := e.q.RenewLeaderLease(ctx, e.lease, e.ttl)
+		if err != nil {
+			return true, err
+		}
+		if renewed {
+			e.lease = lease
+		} else {
+			// The lease expired, another instance may have taken over.
+			e.leader = false
+			e.cooldownUntil = time.Now().Add(leaderHandoffCooldown)
+		}
+		return e.leader, nil
+	}
+
+	if time.Now().Before(e.cooldownUntil) {
+		return false, nil
+	}
+	lease, acquired, err := e.q.AcquireLeaderLease(ctx, e.nodeID, e.ttl)
+	if err != nil {
+		return false, err
+	}
+	if acquired {
+		e.leader = true
+		e.lease = lease
+	}
+	return e.leader, nil
+}
+
+func (e *leaseElector) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.leader
+}
+
+func (e *leaseElector) Fence(ctx context.Context, q history.IngestionQ) error {
+	e.mutex.Lock()
+	leader, fencingToken := e.leader, e.lease.FencingToken
+	e.mutex.Unlock()
+	if !leader {
+		return errNotLeader
+	}
+
+	valid, err := q.CheckLeaderLease(ctx, fencingToken)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		e.mutex.Lock()
+		if e.lease.FencingToken == fencingToken {
+			e.leader = false
+			e.cooldownUntil = time.Now().Add(leaderHandoffCooldown)
+		}
+		e.mutex.Unlock()
+		return errNotLeader
+	}
+	return nil
+}
+
+func (e *leaseElector) Resign(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cooldownUntil = time.Now().Add(leaderHandoffCooldown)
+	if !e.leader {
+		return nil
+	}
+	e.leader = false
+	return e.q.ReleaseLeaderLease(ctx, e.lease)
+}
+
+func (e *leaseElector) ForceHandoff(ctx context.Context) error {
+	if e.IsLeader() {
+		return e.Resign(ctx)
+	}
+	return e.q.ExpireLeaderLease(ctx)
+}
+
+func (e *leaseElector) Status(ctx context.Context) (LeaderStatus, error) {
+	status := LeaderStatus{
+		Backend: LeaderElectionLease,
+		NodeID:  e.nodeID,
+		Leader:  e.IsLeader(),
+	}
+
+	lease, err := e.q.GetLeaderLease(ctx)
+	if e.q.NoRows(err) {
+		return status, nil
+	} else if err != nil {
+		return status, errors.Wrap(err, "could not get leader lease")
+	}
+	status.FencingToken = lease.FencingToken
+	status.AcquiredAt = lease.AcquiredAt
+	status.RenewedAt = lease.RenewedAt
+	status.ExpiresAt = lease.ExpiresAt
+	if lease.ExpiresAt.After(time.Now().UTC()) {
+		status.LeaderID = lease.Holder
+	}
+	return status, nil
+}
+
+// advisoryLockElector elects the instance holding the
+// history.IngestLeaderLockID session advisory lock. The lock is held on a
+// dedicated database connection, whose application_name is set to the node
+// id so that the leader can be identified. The lock is released when the
+// connection is closed.
+type advisoryLockElector struct {
+	db     *sqlx.DB
+	q      *history.Q
+	nodeID string
+
+	mutex         sync.Mutex
+	conn          *sqlx.Conn
+	pid           int
+	acquiredAt    time.Time
+	cooldownUntil time.Time
+}
+
+func (e *advisoryLockElector) Campaign(ctx context.Context) (bool, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.conn != nil {
+		// The lock is held as long as the connection is alive.
+		if err := e.conn.PingContext(ctx); err != nil {
+			e.closeConn()
+			e.cooldownUntil = time.Now().Add(leaderHandoffCooldown)
+			return false, errors.Wrap(err, "lost connection holding the leader lock")
+		}
+		return true, nil
+	}
+
+	if time.Now().Before(e.cooldownUntil) {
+		return false, nil
+	}
+	conn, err := e.db.Connx(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "could not open connection")
+	}
+	var locked bool
+	err = conn.GetContext(ctx, &locked, "SELECT pg_try_advisory_lock($1)", history.IngestLeaderLockID)
+	if err != nil || !locked {
+		conn.Close()
+		return false, errors.Wrap(err, "could not acquire leader lock")
+	}
+	if err = conn.GetContext(ctx, &e.pid, "SELECT pg_backend_pid()"); err != nil {
+		conn.Close()
+		return false, errors.Wrap(err, "could not get backend pid")
+	}
+	if _, err = conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", e.nodeID); err != nil {
+		conn.Close()
+		return false, errors.Wrap(err, "could not set application name")
+	}
+	e.conn = conn
+	e.acquiredAt = time.Now().UTC()
+	return true, nil
+}
+
+func (e *advisoryLockElector) closeConn() {
+	if err := e.conn.Close(); err != nil {
+		log.WithError(err).Warn("Error closing leader lock connection")
+	}
+	e.conn = nil
+	e.pid = 0
+	e.acquiredAt = time.Time{}
+}
+
+func (e *advisoryLockElector) IsLeader() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.conn != nil
+}
+
+func (e *advisoryLockElector) Fence(ctx context.Context, q history.IngestionQ) error {
+	e.mutex.Lock()
+	pid := e.pid
+	e.mutex.Unlock()
+	if pid == 0 {
+		return errNotLeader
+	}
+
+	held, err := q.HoldsAdvisoryLock(ctx, history.IngestLeaderLockID, pid)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return errNotLeader
+	}
+	return nil
+}
+
+func (e *advisoryLockElector) Resign(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cooldownUntil = time.Now().Add(leaderHandoffCooldown)
+	if e.conn == nil {
+		return nil
+	}
+	// Closing the connection releases the lock.
+	e.closeConn()
+	return nil
+}
+
+func (e *advisoryLockElector) ForceHandoff(ctx context.Context) error {
+	if e.IsLeader() {
+		return e.Resign(ctx)
+	}
+	// The session of a stuck leader is terminated, which releases the lock.
+	_, err := e.q.TerminateAdvisoryLockHolder(ctx, history.IngestLeaderLockID)
+	return err
+}
+
+func (e *advisoryLockElector) Status(ctx context.Context) (LeaderStatus, error) {
+	e.mutex.Lock()
+	status := LeaderStatus{
+		Backend:    LeaderElectionAdvisoryLock,
+		NodeID:     e.nodeID,
+		Leader:     e.conn != nil,
+		AcquiredAt: e.acquiredAt,
+	}
+	e.mutex.Unlock()
+
+	holder, err := e.q.GetAdvisoryLockHolder(ctx, history.IngestLeaderLockID)
+	if e.q.NoRows(err) {
+		return status, nil
+	} else if err != nil {
+		return status, errors.Wrap(err, "could not get leader lock holder")
+	}
+	status.LeaderID = holder.ApplicationName
+	status.LeaderDatabasePID = holder.PID
+	return status, nil
+}
+
+// runLeaderElection campaigns for the leadership every
+// leaderHeartbeatInterval until the system is shut down, when the leadership
+// is released.
+func (s *system) runLeaderElection() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(leaderHeartbeatInterval)
+	defer ticker.Stop()
+	wasLeader := false
+	for {
+		wasLeader = s.campaignForLeadership(wasLeader)
+
+		select {
+		case <-s.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), leaderReleaseTimeout)
+			defer cancel()
+			if err := s.leader.Resign(ctx); err != nil {
+				log.WithError(err).Warn("Error releasing ingestion leadership")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaignForLeadership campaigns for the leadership, after giving it up if
+// this instance was the leader and its ingestion stalled. It returns true if
+// this instance is the leader.
+func (s *system) campaignForLeadership(wasLeader bool) bool {
+	if stalled := s.progress.since(); wasLeader && stalled > leaderProgressTimeout {
+		log.WithField("stalled", stalled.String()).
+			Warn("Ingestion made no progress, giving up the ingestion leadership")
+		if err := s.leader.Resign(s.ctx); err != nil && !isCancelledError(err) {
+			log.WithError(err).Warn("Error releasing ingestion leadership")
+		}
+	}
+
+	leader, err := s.leader.Campaign(s.ctx)
+	if err != nil && !isCancelledError(err) {
+		log.WithError(err).Warn("Error campaigning for ingestion leadership")
+	}
+	if leader != wasLeader {
+		log.WithFields(logpkg.F{"leader": leader}).Info("Ingestion leadership changed")
+		if leader {
+			// The new leader is given leaderProgressTimeout to make
+			// progress.
+			s.progress.mark()
+		}
+	}
+	return leader
+}
+
+// isLeader returns true if this instance leads the ingestion. Without leader
+// election all instances compete for the last ingested ledger row lock.
+func (s *system) isLeader() bool {
+	return s.leader == nil || s.leader.IsLeader()
+}
+
+// checkLeadership returns an error if this instance was deposed. It must be
+// called in the ingestion transaction before it is committed.
+func (s *system) checkLeadership(ctx context.Context) error {
+	if s.leader == nil {
+		return nil
+	}
+	return s.leader.Fence(ctx, s.historyQ)
+}
+
+// ingestProgress records the time the ingestion last made progress. The
+// leader renews the leadership only while its ingestion makes progress. Its
+// methods can be called on a nil ingestProgress, which never stalls.
+type ingestProgress struct {
+	// at is the time of the last progress, in unix nanoseconds.
+	at int64
+	// preparing is the number of ledger ranges being prepared.
+	preparing int32
+}
+
+// startPreparing records that a ledger range is being prepared until the
+// returned function is called. Preparing a range, which includes the catchup
+// of captive core, routinely takes longer than leaderProgressTimeout so the
+// ingestion is not considered stalled in the meantime.
+func (p *ingestProgress) startPreparing() func() {
+	if p == nil {
+		return func() {}
+	}
+	atomic.AddInt32(&p.preparing, 1)
+	return func() {
+		p.mark()
+		atomic.AddInt32(&p.preparing, -1)
+	}
+}
+
+// mark records that the ingestion made progress.
+func (p *ingestProgress) mark() {
+	if p == nil {
+		return
+	}
+	atomic.StoreInt64(&p.at, time.Now().UnixNano())
+}
+
+// since returns the time elapsed since the ingestion last made progress, 0
+// while a ledger range is being prepared.
+func (p *ingestProgress) since() time.Duration {
+	if p == nil || atomic.LoadInt32(&p.preparing) > 0 {
+		return 0
+	}
+	at := atomic.LoadInt64(&p.at)
+	if at == 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, at))
+}
@@ -0,0 +1,211 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/test"
+)
+
+type mockLeaderElector struct {
+	mock.Mock
+}
+
+func (m *mockLeaderElector) Campaign(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockLeaderElector) IsLeader() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *mockLeaderElector) Fence(ctx context.Context, q history.IngestionQ) error {
+	args := m.Called(ctx, q)
+	return args.Error(0)
+}
+
+func (m *mockLeaderElector) Resign(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockLeaderElector) ForceHandoff(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockLeaderElector) Status(ctx context.Context) (LeaderStatus, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(LeaderStatus), args.Error(1)
+}
+
+var _ LeaderElector = (*mockLeaderElector)(nil)
+
+func newFollowerTestSystem(historyQ *mockDBQ, leader *mockLeaderElector) *system {
+	s := &system{
+		ctx:               context.Background(),
+		historyQ:          historyQ,
+		leader:            leader,
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+	s.initMetrics()
+	return s
+}
+
+func TestFollowerWaitsForState(t *testing.T) {
+	historyQ := &mockDBQ{}
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(historyQ, leader)
+
+	leader.On("IsLeader").Return(false).Once()
+	historyQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(0), nil).Once()
+	historyQ.On("GetIngestVersion", s.ctx).Return(CurrentVersion, nil).Once()
+
+	next, err := startState{}.run(s)
+	assert.NoError(t, err)
+	assert.Equal(t, start(), next)
+	historyQ.AssertExpectations(t)
+	leader.AssertExpectations(t)
+}
+
+func TestFollowerResumesFromLastIngestedLedger(t *testing.T) {
+	historyQ := &mockDBQ{}
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(historyQ, leader)
+
+	leader.On("IsLeader").Return(false).Once()
+	historyQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(100), nil).Once()
+	historyQ.On("GetIngestVersion", s.ctx).Return(CurrentVersion, nil).Once()
+
+	next, err := startState{}.run(s)
+	assert.NoError(t, err)
+	assert.Equal(t, resume(100), next)
+	historyQ.AssertExpectations(t)
+	leader.AssertExpectations(t)
+}
+
+func TestFollowerDoesNotIngestLedger(t *testing.T) {
+	historyQ := &mockDBQ{}
+	leader := &mockLeaderElector{}
+	ledgerBackend := &ledgerbackend.MockDatabaseBackend{}
+	s := newFollowerTestSystem(historyQ, leader)
+	s.ledgerBackend = ledgerBackend
+
+	ledgerBackend.On("IsPrepared", s.ctx, ledgerbackend.UnboundedRange(101)).Return(true, nil).Twice()
+	ledgerBackend.On("GetLedger", s.ctx, uint32(101)).Return(shadowTestLedger(101), nil).Twice()
+	leader.On("IsLeader").Return(false).Twice()
+
+	// The leader has not ingested the ledger yet.
+	historyQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(100), nil).Once()
+	r := resumeState{latestSuccessfullyProcessedLedger: 100}
+	next, err := r.run(s)
+	assert.NoError(t, err)
+	assert.Equal(t, retryResume(r), next)
+
+	historyQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(101), nil).Once()
+	next, err = r.run(s)
+	assert.NoError(t, err)
+	assert.Equal(t, retryResume(resumeState{latestSuccessfullyProcessedLedger: 101}), next)
+
+	historyQ.AssertExpectations(t)
+	leader.AssertExpectations(t)
+	ledgerBackend.AssertExpectations(t)
+}
+
+func TestDeposedLeaderDoesNotCommit(t *testing.T) {
+	historyQ := &mockDBQ{}
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(historyQ, leader)
+
+	leader.On("Fence", s.ctx, historyQ).Return(errNotLeader).Once()
+
+	err := s.completeIngestion(s.ctx, 101)
+	assert.Equal(t, errNotLeader, err)
+	historyQ.AssertNotCalled(t, "UpdateLastLedgerIngest", s.ctx, uint32(101))
+	historyQ.AssertNotCalled(t, "Commit")
+	leader.AssertExpectations(t)
+}
+
+func TestNewLeaderElector(t *testing.T) {
+	leader, err := NewLeaderElector("", "", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, leader)
+
+	_, err = NewLeaderElector("zookeeper", "node-a", nil)
+	assert.EqualError(t, err, "unknown leader election backend: zookeeper")
+}
+
+func TestStalledLeaderResigns(t *testing.T) {
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(&mockDBQ{}, leader)
+	s.progress = &ingestProgress{}
+	s.progress.at = time.Now().Add(-leaderProgressTimeout - time.Minute).UnixNano()
+
+	leader.On("Resign", s.ctx).Return(nil).Once()
+	leader.On("Campaign", s.ctx).Return(false, nil).Once()
+	assert.False(t, s.campaignForLeadership(true))
+	leader.AssertExpectations(t)
+}
+
+func TestPreparingLeaderRenews(t *testing.T) {
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(&mockDBQ{}, leader)
+	s.progress = &ingestProgress{}
+	s.progress.at = time.Now().Add(-leaderProgressTimeout - time.Minute).UnixNano()
+
+	// Captive core catchup can outlast leaderProgressTimeout.
+	donePreparing := s.progress.startPreparing()
+	leader.On("Campaign", s.ctx).Return(true, nil).Once()
+	assert.True(t, s.campaignForLeadership(true))
+	leader.AssertNotCalled(t, "Resign", s.ctx)
+
+	donePreparing()
+	assert.True(t, s.progress.since() < leaderProgressTimeout)
+	leader.AssertExpectations(t)
+}
+
+func TestProgressingLeaderRenews(t *testing.T) {
+	leader := &mockLeaderElector{}
+	s := newFollowerTestSystem(&mockDBQ{}, leader)
+	s.progress = &ingestProgress{}
+	s.progress.mark()
+
+	leader.On("Campaign", s.ctx).Return(true, nil).Once()
+	assert.True(t, s.campaignForLeadership(true))
+	leader.AssertExpectations(t)
+	leader.AssertNotCalled(t, "Resign", s.ctx)
+}
+
+func TestDeposedLeaseLeaderCoolsDown(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	elector := &leaseElector{q: q, nodeID: "node-a", ttl: leaderLeaseTTL}
+	leader, err := elector.Campaign(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.True(leader)
+
+	// The lease expires before it is renewed.
+	tt.Assert.NoError(q.ExpireLeaderLease(tt.Ctx))
+	leader, err = elector.Campaign(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.False(leader)
+
+	// The lease is free but the deposed leader does not campaign again
+	// before the cooldown.
+	leader, err = elector.Campaign(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.False(leader)
+	tt.Assert.True(elector.cooldownUntil.After(time.Now()))
+}
@@ -26,6 +26,8 @@ type loggingChangeReader struct {
 	frequency int
 	source    string
 	sequence  uint32
+	// progress is marked every time the logger reports, it may be nil.
+	progress *ingestProgress
 }
 
 func newloggingChangeReader(
@@ -52,6 +54,7 @@ func (lcr *loggingChangeReader) Read() (ingest.Change, error) {
 		lcr.entryCount++
 
 		if lcr.entryCount%lcr.frequency == 0 {
+			lcr.progress.mark()
 			logger := log.WithField("processed_entries", lcr.entryCount).
 				WithField("source", lcr.source).
 				WithField("sequence", lcr.sequence)
@@ -20,6 +20,7 @@ import (
 	"github.com/diamcircle/go/support/db"
 	"github.com/diamcircle/go/support/errors"
 	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
 )
 
 const (
@@ -57,8 +58,9 @@ const (
 	// MaxDBConnections is the size of the postgres connection pool dedicated to Aurora ingestion:
 	//  * Ledger ingestion,
 	//  * State verifications,
-	//  * Metrics updates.
-	MaxDBConnections = 3
+	//  * Metrics updates,
+	//  * Leader election.
+	MaxDBConnections = 4
 
 	defaultCoreCursorName           = "HORIZON"
 	stateVerificationErrorThreshold = 3
@@ -88,6 +90,20 @@ type Config struct {
 
 	// The checkpoint frequency will be 64 unless you are using an exotic test setup.
 	CheckpointFrequency uint32
+
+	// LeaderElection is the leader election backend, LeaderElectionAdvisoryLock
+	// or LeaderElectionLease. When empty, all instances compete for the lock
+	// on the last ingested ledger value.
+	LeaderElection string
+	// LeaderNodeID identifies this instance in the leader election.
+	LeaderNodeID string
+
+	// CaptiveCoreMaxRestarts is the number of consecutive times captive core
+	// is restarted after exiting unexpectedly.
+	CaptiveCoreMaxRestarts int
+	// CaptiveCoreLogLines is the number of recent captive core log lines
+	// kept for diagnosis.
+	CaptiveCoreLogLines int
 }
 
 const (
@@ -161,6 +177,9 @@ type System interface {
 	VerifyRange(fromLedger, toLedger uint32, verifyState bool) error
 	ReingestRange(ledgerRanges []history.LedgerRange, force bool) error
 	BuildGenesisState() error
+	RepairState(checkpointLedger uint32, keys []xdr.LedgerKey) (int, error)
+	Leader() LeaderElector
+	CaptiveCore() CaptiveCoreMonitor
 	Shutdown()
 }
 
@@ -192,6 +211,25 @@ type system struct {
 	disableStateVerification bool
 
 	checkpointManager historyarchive.CheckpointManager
+
+	// shadowRebuild is the rebuild of the state in shadow tables in
+	// progress, if any. It is only accessed by the ingestion state machine.
+	shadowRebuild *shadowRebuild
+
+	// leader elects the instance leading the ingestion, nil if leader
+	// election is disabled.
+	leader LeaderElector
+	// progress records when the ingestion last made progress, the leader
+	// gives up the leadership if it stalls. It may be nil.
+	progress *ingestProgress
+
+	// historyPartitions creates the partitions of the history tables ahead
+	// of ingestion, nil in tests.
+	historyPartitions *historyPartitions
+
+	// captiveCore is the captive core ledger backend, nil when ledgers are
+	// not streamed from a local captive core process.
+	captiveCore CaptiveCoreMonitor
 }
 
 func NewSystem(config Config) (System, error) {
@@ -210,47 +248,23 @@ func NewSystem(config Config) (System, error) {
 		return nil, errors.Wrap(err, "error creating history archive")
 	}
 
-	var ledgerBackend ledgerbackend.LedgerBackend
-	if config.EnableCaptiveCore {
-		if len(config.RemoteCaptiveCoreURL) > 0 {
-			ledgerBackend, err = ledgerbackend.NewRemoteCaptive(config.RemoteCaptiveCoreURL)
-			if err != nil {
-				cancel()
-				return nil, errors.Wrap(err, "error creating captive core backend")
-			}
-		} else {
-			logger := log.WithField("subservice", "diamcircle-core")
-			ledgerBackend, err = ledgerbackend.NewCaptive(
-				ledgerbackend.CaptiveCoreConfig{
-					BinaryPath:          config.CaptiveCoreBinaryPath,
-					StoragePath:         config.CaptiveCoreStoragePath,
-					Toml:                config.CaptiveCoreToml,
-					NetworkPassphrase:   config.NetworkPassphrase,
-					HistoryArchiveURLs:  []string{config.HistoryArchiveURL},
-					CheckpointFrequency: config.CheckpointFrequency,
-					LedgerHashStore:     ledgerbackend.NewAuroraDBLedgerHashStore(config.HistorySession),
-					Log:                 logger,
-					Context:             ctx,
-				},
-			)
-			if err != nil {
-				cancel()
-				return nil, errors.Wrap(err, "error creating captive core backend")
-			}
-		}
-	} else {
-		coreSession := config.CoreSession.Clone()
-		ledgerBackend, err = ledgerbackend.NewDatabaseBackendFromSession(coreSession, config.NetworkPassphrase)
-		if err != nil {
-			cancel()
-			return nil, errors.Wrap(err, "error creating ledger backend")
-		}
+	ledgerBackend, err := newLedgerBackend(ctx, config)
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
 	historyQ := &history.Q{config.HistorySession.Clone()}
 
 	historyAdapter := newHistoryArchiveAdapter(archive)
 
+	leader, err := NewLeaderElector(config.LeaderElection, config.LeaderNodeID, config.HistorySession)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "error creating leader elector")
+	}
+
+	progress := &ingestProgress{}
 	system := &system{
 		cancel:                      cancel,
 		config:                      config,
@@ -269,14 +283,69 @@ func NewSystem(config Config) (System, error) {
 			config:         config,
 			historyQ:       historyQ,
 			historyAdapter: historyAdapter,
+			progress:       progress,
 		},
 		checkpointManager: historyarchive.NewCheckpointManager(config.CheckpointFrequency),
+		leader:            leader,
+		progress:          progress,
+		historyPartitions: newHistoryPartitions(config.HistorySession),
+	}
+
+	if captiveCore, ok := ledgerBackend.(*ledgerbackend.CaptiveDiamcircleCore); ok {
+		system.captiveCore = captiveCore
 	}
 
 	system.initMetrics()
 	return system, nil
 }
 
+// newLedgerBackend creates the ledger backend configured in config.
+func newLedgerBackend(ctx context.Context, config Config) (ledgerbackend.LedgerBackend, error) {
+	if !config.EnableCaptiveCore {
+		coreSession := config.CoreSession.Clone()
+		ledgerBackend, err := ledgerbackend.NewDatabaseBackendFromSession(coreSession, config.NetworkPassphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating ledger backend")
+		}
+		return ledgerBackend, nil
+	}
+
+	if len(config.RemoteCaptiveCoreURL) > 0 {
+		ledgerBackend, err := ledgerbackend.NewRemoteCaptive(
+			config.RemoteCaptiveCoreURL,
+			ledgerbackend.ConsumerName("aurora"),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating captive core backend")
+		}
+		return ledgerBackend, nil
+	}
+
+	var ledgerHashStore ledgerbackend.TrustedLedgerHashStore
+	if config.HistorySession != nil {
+		ledgerHashStore = ledgerbackend.NewAuroraDBLedgerHashStore(config.HistorySession)
+	}
+	ledgerBackend, err := ledgerbackend.NewCaptive(
+		ledgerbackend.CaptiveCoreConfig{
+			BinaryPath:          config.CaptiveCoreBinaryPath,
+			StoragePath:         config.CaptiveCoreStoragePath,
+			Toml:                config.CaptiveCoreToml,
+			NetworkPassphrase:   config.NetworkPassphrase,
+			HistoryArchiveURLs:  []string{config.HistoryArchiveURL},
+			CheckpointFrequency: config.CheckpointFrequency,
+			LedgerHashStore:     ledgerHashStore,
+			Log:                 log.WithField("subservice", "diamcircle-core"),
+			Context:             ctx,
+			MaxRestarts:         config.CaptiveCoreMaxRestarts,
+			LogLines:            config.CaptiveCoreLogLines,
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating captive core backend")
+	}
+	return ledgerBackend, nil
+}
+
 func (s *system) initMetrics() {
 	s.metrics.MaxSupportedProtocolVersion = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: "aurora", Subsystem: "ingest", Name: "max_supported_protocol_version",
@@ -441,6 +510,9 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.metrics.CaptiveCoreSupportedProtocolVersion)
 	registry.MustRegister(s.metrics.LedgerFetchDurationSummary)
 	registry.MustRegister(s.metrics.StateVerifyLedgerEntriesCount)
+	if s.captiveCore != nil {
+		registry.MustRegister(captiveCoreCollector{ctx: s.ctx, captiveCore: s.captiveCore})
+	}
 }
 
 // Run starts ingestion system. Ingestion system supports distributed ingestion
@@ -459,6 +531,8 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 // try to acquire a lock on `LastLedgerIngest value in key value store and only
 // one instance will be able to acquire it. This happens in both initial processing
 // and ledger processing. So this solves 3a and 3b in both 1a and 1b.
+// When leader election is enabled, only the elected instance tries to
+// acquire the lock, the other instances wait for it to ingest the ledgers.
 //
 // Finally, 1a and 1b are tricky because we need to keep the latest version
 // of order book graph in memory of each Aurora instance. To solve this:
@@ -473,9 +547,22 @@ func (s *system) RegisterMetrics(registry *prometheus.Registry) {
 //   * If instances is a NOT leader, it runs ledger pipeline without updating a
 //     a database so order book graph is updated but database is not overwritten.
 func (s *system) Run() {
+	if s.leader != nil {
+		s.wg.Add(1)
+		go s.runLeaderElection()
+	}
 	s.runStateMachine(startState{})
 }
 
+// Leader returns the leader elector, nil if leader election is disabled.
+func (s *system) Leader() LeaderElector {
+	return s.leader
+}
+
+func (s *system) CaptiveCore() CaptiveCoreMonitor {
+	return s.captiveCore
+}
+
 func (s *system) StressTest(numTransactions, changesPerTransaction int) error {
 	if numTransactions <= 0 {
 		return errors.New("transactions must be positive")
@@ -589,6 +676,8 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 			} else {
 				logger.Error("Error in ingestion state machine")
 			}
+		} else {
+			s.progress.mark()
 		}
 
 		// Exit after processing shutdownState
@@ -693,6 +782,9 @@ func (s *system) Shutdown() {
 	s.cancel()
 	// wait for ingestion state machine to terminate
 	s.wg.Wait()
+	if s.shadowRebuild != nil {
+		s.shadowRebuild.close()
+	}
 	if err := s.ledgerBackend.Close(); err != nil {
 		log.WithError(err).Info("could not close ledger backend")
 	}
@@ -704,6 +796,11 @@ func markStateInvalid(ctx context.Context, historyQ history.IngestionQ, err erro
 	if err := q.UpdateExpStateInvalid(ctx, true); err != nil {
 		log.WithField("err", err).Error(updateExpStateInvalidErrMsg)
 	}
+	// Rebuild the state in shadow tables so that it can be fixed without
+	// making the state endpoints unavailable.
+	if err := q.UpdateStateRebuildRequested(ctx, true); err != nil {
+		log.WithField("err", err).Error("Error requesting state rebuild")
+	}
 }
 
 func isCancelledError(err error) bool {
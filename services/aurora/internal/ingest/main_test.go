@@ -283,6 +283,11 @@ func (m *mockDBQ) GetLastLedgerIngest(ctx context.Context) (uint32, error) {
 	return args.Get(0).(uint32), args.Error(1)
 }
 
+func (m *mockDBQ) GetStateTableKeys(ctx context.Context, table, cursor string, limit uint64) ([]string, error) {
+	args := m.Called(ctx, table, cursor, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *mockDBQ) GetOfferCompactionSequence(ctx context.Context) (uint32, error) {
 	args := m.Called(ctx)
 	return args.Get(0).(uint32), args.Error(1)
@@ -338,6 +343,66 @@ func (m *mockDBQ) TruncateIngestStateTables(ctx context.Context) error {
 	return args.Error(0)
 }
 
+func (m *mockDBQ) GetStateRebuildRequested(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *mockDBQ) UpdateStateRebuildRequested(ctx context.Context, requested bool) error {
+	args := m.Called(ctx, requested)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) TryLockShadowStateTables(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *mockDBQ) CreateShadowStateTables(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) UseShadowStateTables(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) SwapShadowStateTables(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) DropShadowStateTables(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) InsertStateVerificationReport(
+	ctx context.Context,
+	report history.StateVerificationReport,
+	mismatches []history.StateVerificationMismatch,
+	batchSize int,
+) (int64, error) {
+	args := m.Called(ctx, report, mismatches, batchSize)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockDBQ) DeleteStateVerificationReportsBefore(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockDBQ) CheckLeaderLease(ctx context.Context, fencingToken int64) (bool, error) {
+	args := m.Called(ctx, fencingToken)
+	return args.Get(0).(bool), args.Error(1)
+}
+
+func (m *mockDBQ) HoldsAdvisoryLock(ctx context.Context, lockID int64, pid int) (bool, error) {
+	args := m.Called(ctx, lockID, pid)
+	return args.Get(0).(bool), args.Error(1)
+}
+
 func (m *mockDBQ) DeleteRangeAll(ctx context.Context, start, end int64) error {
 	args := m.Called(ctx, start, end)
 	return args.Error(0)
@@ -434,6 +499,11 @@ func (m *mockProcessorsRunner) RunHistoryArchiveIngestion(
 	return args.Get(0).(ingest.StatsChangeProcessorResults), args.Error(1)
 }
 
+func (m *mockProcessorsRunner) RunChangeProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (ingest.StatsChangeProcessorResults, error) {
+	args := m.Called(ledger)
+	return args.Get(0).(ingest.StatsChangeProcessorResults), args.Error(1)
+}
+
 func (m *mockProcessorsRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	ledgerStats,
 	error,
@@ -506,6 +576,23 @@ func (m *mockSystem) BuildGenesisState() error {
 	return args.Error(0)
 }
 
+func (m *mockSystem) RepairState(checkpointLedger uint32, keys []xdr.LedgerKey) (int, error) {
+	args := m.Called(checkpointLedger, keys)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockSystem) Leader() LeaderElector {
+	args := m.Called()
+	leader, _ := args.Get(0).(LeaderElector)
+	return leader
+}
+
+func (m *mockSystem) CaptiveCore() CaptiveCoreMonitor {
+	args := m.Called()
+	captiveCore, _ := args.Get(0).(CaptiveCoreMonitor)
+	return captiveCore
+}
+
 func (m *mockSystem) Shutdown() {
 	m.Called()
 }
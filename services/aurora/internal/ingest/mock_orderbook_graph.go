@@ -1,6 +1,8 @@
 package ingest
 
 import (
+	"io"
+
 	"github.com/diamcircle/go/exp/orderbook"
 	"github.com/diamcircle/go/xdr"
 	"github.com/stretchr/testify/mock"
@@ -58,3 +60,18 @@ func (m *mockOrderBookGraph) Verify() ([]xdr.OfferEntry, []xdr.LiquidityPoolEntr
 	args := m.Called()
 	return args.Get(0).([]xdr.OfferEntry), args.Get(1).([]xdr.LiquidityPoolEntry), args.Error(2)
 }
+
+func (m *mockOrderBookGraph) LastLedger() uint32 {
+	args := m.Called()
+	return args.Get(0).(uint32)
+}
+
+func (m *mockOrderBookGraph) WriteSnapshot(w io.Writer) error {
+	args := m.Called(w)
+	return args.Error(0)
+}
+
+func (m *mockOrderBookGraph) ReadSnapshot(r io.Reader) error {
+	args := m.Called(r)
+	return args.Error(0)
+}
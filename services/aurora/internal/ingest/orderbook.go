@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"math/rand"
+	"os"
 	"sort"
 	"time"
 
@@ -36,6 +37,14 @@ type OrderBookStream struct {
 	lastLedger        uint32
 	lastVerification  time.Time
 	encodingBuffer    *xdr.EncodingBuffer
+
+	// snapshotPath is the file the graph is saved to every snapshotInterval
+	// ledgers and restored from at startup. Snapshots are disabled if it is
+	// empty.
+	snapshotPath       string
+	snapshotInterval   uint32
+	lastSnapshotLedger uint32
+	snapshotRestored   bool
 }
 
 // NewOrderBookStream constructs and initializes an OrderBookStream instance
@@ -51,6 +60,15 @@ func NewOrderBookStream(historyQ history.IngestionQ, graph orderbook.OBGraph) *O
 	}
 }
 
+// EnableSnapshots makes the OrderBookStream save the order book graph to the
+// file at `path` every `interval` ledgers. On the first update the graph is
+// restored from that file, if it exists, and caught up with the changes
+// ingested since the snapshot instead of being rebuilt from the DB.
+func (o *OrderBookStream) EnableSnapshots(path string, interval uint32) {
+	o.snapshotPath = path
+	o.snapshotInterval = interval
+}
+
 type ingestionStatus struct {
 	HistoryConsistentWithState        bool
 	StateInvalid                      bool
@@ -94,8 +112,83 @@ func (o *OrderBookStream) getIngestionStatus(ctx context.Context) (ingestionStat
 	return status, nil
 }
 
+// restoreSnapshot loads the graph from the snapshot file. If the snapshot
+// can be caught up with the DB, lastLedger is set to the snapshot ledger so
+// that update() only applies the changes ingested since the snapshot.
+func (o *OrderBookStream) restoreSnapshot(status ingestionStatus) {
+	o.snapshotRestored = true
+
+	file, err := os.Open(o.snapshotPath)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.WithError(err).Warn("could not open order book snapshot")
+		return
+	}
+	defer file.Close()
+
+	if err = o.graph.ReadSnapshot(file); err != nil {
+		log.WithError(err).Warn("could not read order book snapshot")
+		o.graph.Clear()
+		return
+	}
+
+	ledger := o.graph.LastLedger()
+	if ledger > status.LastIngestedLedger || ledger < status.LastOfferCompactionLedger {
+		log.WithField("status", status).
+			WithField("snapshot_ledger", ledger).
+			Info("order book snapshot cannot be caught up with ingestion")
+		o.graph.Clear()
+		return
+	}
+
+	log.WithField("snapshot_ledger", ledger).Info("restored order book from snapshot")
+	o.lastLedger = ledger
+	o.lastSnapshotLedger = ledger
+	// verify the restored graph against the DB as soon as it has caught up
+	o.lastVerification = time.Time{}
+}
+
+// saveSnapshot saves the graph to the snapshot file if at least
+// snapshotInterval ledgers were applied since the last snapshot. The
+// snapshot is written to a temporary file first so that a crash never
+// leaves a truncated snapshot behind.
+func (o *OrderBookStream) saveSnapshot() {
+	if o.snapshotPath == "" || o.snapshotInterval == 0 || o.lastLedger == 0 ||
+		o.lastLedger < o.lastSnapshotLedger+o.snapshotInterval {
+		return
+	}
+
+	tmp := o.snapshotPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		log.WithError(err).Warn("could not create order book snapshot")
+		return
+	}
+	err = o.graph.WriteSnapshot(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, o.snapshotPath)
+	}
+	if err != nil {
+		log.WithError(err).
+			WithField("path", o.snapshotPath).
+			Warn("could not write order book snapshot")
+		os.Remove(tmp)
+		return
+	}
+	o.lastSnapshotLedger = o.lastLedger
+}
+
 // update returns true if the order book graph was reset
 func (o *OrderBookStream) update(ctx context.Context, status ingestionStatus) (bool, error) {
+	if o.lastLedger == 0 && o.snapshotPath != "" && !o.snapshotRestored &&
+		!status.StateInvalid && status.HistoryConsistentWithState {
+		o.restoreSnapshot(status)
+	}
+
 	reset := o.lastLedger == 0
 	if status.StateInvalid {
 		log.WithField("status", status).Warn("ingestion state is invalid")
@@ -318,6 +411,7 @@ func (o *OrderBookStream) Update(ctx context.Context) error {
 	if reset, err := o.update(ctx, status); err != nil {
 		return errors.Wrap(err, "Error updating")
 	} else if reset {
+		o.saveSnapshot()
 		return nil
 	}
 
@@ -359,6 +453,8 @@ func (o *OrderBookStream) Update(ctx context.Context) error {
 			o.lastLedger = 0
 		}
 	}
+
+	o.saveSnapshot()
 	return nil
 }
 
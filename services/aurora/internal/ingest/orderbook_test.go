@@ -5,12 +5,16 @@ package ingest
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/ingest/processors"
 	"github.com/diamcircle/go/xdr"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -581,6 +585,109 @@ func (t *UpdateOrderBookStreamTestSuite) TestApplyUpdatesSucceeds() {
 	t.Assert().False(reset)
 }
 
+func (t *UpdateOrderBookStreamTestSuite) snapshotPath() string {
+	dir, err := ioutil.TempDir("", "orderbook-snapshot")
+	t.Require().NoError(err)
+	t.T().Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "orderbook.snapshot")
+	t.Require().NoError(ioutil.WriteFile(path, []byte("snapshot"), 0644))
+	return path
+}
+
+func (t *UpdateOrderBookStreamTestSuite) TestRestoreSnapshot() {
+	status := ingestionStatus{
+		HistoryConsistentWithState:        true,
+		StateInvalid:                      false,
+		LastIngestedLedger:                201,
+		LastOfferCompactionLedger:         100,
+		LastLiquidityPoolCompactionLedger: 100,
+	}
+	t.stream.EnableSnapshots(t.snapshotPath(), 100)
+
+	// the changes since the snapshot ledger are applied to the snapshot
+	t.mockUpdate()
+	t.stream.lastLedger = 0
+	t.graph.On("ReadSnapshot", mock.Anything).Return(nil).Once()
+	t.graph.On("LastLedger").Return(uint32(100)).Once()
+	t.graph.On("Apply", status.LastIngestedLedger).
+		Return(nil).
+		Once()
+
+	reset, err := t.stream.update(t.ctx, status)
+	t.Assert().NoError(err)
+	t.Assert().False(reset)
+	t.Assert().Equal(status.LastIngestedLedger, t.stream.lastLedger)
+	t.Assert().Equal(uint32(100), t.stream.lastSnapshotLedger)
+	t.Assert().True(t.stream.lastVerification.IsZero())
+}
+
+func (t *UpdateOrderBookStreamTestSuite) TestRestoreSnapshotBehindCompaction() {
+	status := ingestionStatus{
+		HistoryConsistentWithState:        true,
+		StateInvalid:                      false,
+		LastIngestedLedger:                201,
+		LastOfferCompactionLedger:         100,
+		LastLiquidityPoolCompactionLedger: 100,
+	}
+	t.stream.EnableSnapshots(t.snapshotPath(), 100)
+
+	// the offers removed before the compaction ledger cannot be applied to
+	// the snapshot so the graph is rebuilt from the DB
+	t.graph.On("ReadSnapshot", mock.Anything).Return(nil).Once()
+	t.graph.On("LastLedger").Return(uint32(50)).Once()
+	t.graph.On("Clear").Return().Once()
+	t.mockReset(status)
+
+	reset, err := t.stream.update(t.ctx, status)
+	t.Assert().NoError(err)
+	t.Assert().True(reset)
+	t.Assert().Equal(status.LastIngestedLedger, t.stream.lastLedger)
+}
+
+func (t *UpdateOrderBookStreamTestSuite) TestRestoreInvalidSnapshot() {
+	status := ingestionStatus{
+		HistoryConsistentWithState:        true,
+		StateInvalid:                      false,
+		LastIngestedLedger:                201,
+		LastOfferCompactionLedger:         100,
+		LastLiquidityPoolCompactionLedger: 100,
+	}
+	t.stream.EnableSnapshots(t.snapshotPath(), 100)
+
+	t.graph.On("ReadSnapshot", mock.Anything).Return(fmt.Errorf("invalid snapshot")).Once()
+	t.graph.On("Clear").Return().Once()
+	t.mockReset(status)
+
+	reset, err := t.stream.update(t.ctx, status)
+	t.Assert().NoError(err)
+	t.Assert().True(reset)
+
+	// the snapshot is only restored at startup
+	t.stream.lastLedger = 0
+	t.mockReset(status)
+	_, err = t.stream.update(t.ctx, status)
+	t.Assert().NoError(err)
+}
+
+func (t *UpdateOrderBookStreamTestSuite) TestSaveSnapshot() {
+	path := t.snapshotPath()
+	t.stream.EnableSnapshots(path, 100)
+	t.stream.lastLedger = 99
+
+	// nothing is saved until the interval has passed
+	t.stream.saveSnapshot()
+
+	t.graph.On("WriteSnapshot", mock.Anything).Return(nil).Once()
+	t.stream.lastLedger = 201
+	t.stream.saveSnapshot()
+	t.Assert().Equal(uint32(201), t.stream.lastSnapshotLedger)
+	t.stream.lastLedger = 250
+	t.stream.saveSnapshot()
+
+	_, err := os.Stat(path + ".tmp")
+	t.Assert().True(os.IsNotExist(err))
+}
+
 type VerifyOffersStreamTestSuite struct {
 	suite.Suite
 	ctx      context.Context
@@ -1,6 +1,7 @@
 package ingest
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -27,11 +28,19 @@ type ParallelSystems struct {
 	config        Config
 	workerCount   uint
 	systemFactory func(Config) (System, error)
+	// historyPartitions creates the partitions of the history tables before
+	// the workers start, nil in tests.
+	historyPartitions *historyPartitions
 }
 
 func NewParallelSystems(config Config, workerCount uint) (*ParallelSystems, error) {
 	// Leaving this because used in tests, will update after a code review.
-	return newParallelSystems(config, workerCount, NewSystem)
+	ps, err := newParallelSystems(config, workerCount, NewSystem)
+	if err != nil {
+		return nil, err
+	}
+	ps.historyPartitions = newHistoryPartitions(config.HistorySession)
+	return ps, nil
 }
 
 // private version of NewParallel systems, allowing to inject a mock system
@@ -132,6 +141,17 @@ func (ps *ParallelSystems) ReingestRange(ledgerRanges []history.LedgerRange, bat
 		return err
 	}
 
+	// Partitions are created up front so that the workers do not compete
+	// to create them.
+	if ps.historyPartitions != nil {
+		for _, ledgerRange := range ledgerRanges {
+			err := ps.historyPartitions.ensure(context.Background(), ledgerRange.StartSequence, ledgerRange.EndSequence)
+			if err != nil {
+				return errors.Wrap(err, "error creating history partitions")
+			}
+		}
+	}
+
 	for i := uint(0); i < ps.workerCount; i++ {
 		wg.Add(1)
 		s, err := ps.systemFactory(ps.config)
@@ -71,6 +71,7 @@ type ProcessorRunnerInterface interface {
 		tradeStats processors.TradeStats,
 		err error,
 	)
+	RunChangeProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (ingest.StatsChangeProcessorResults, error)
 	RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 		stats ledgerStats,
 		err error,
@@ -86,6 +87,9 @@ type ProcessorRunner struct {
 	historyQ       history.IngestionQ
 	historyAdapter historyArchiveAdapterInterface
 	logMemoryStats bool
+	// progress is marked while the state is ingested from a history archive
+	// snapshot, it may be nil.
+	progress *ingestProgress
 }
 
 func (s *ProcessorRunner) SetHistoryAdapter(historyAdapter historyArchiveAdapterInterface) {
@@ -144,6 +148,7 @@ func (s *ProcessorRunner) buildTransactionProcessor(
 		processors.NewTransactionProcessor(s.historyQ, sequence),
 		processors.NewClaimableBalancesTransactionProcessor(s.historyQ, sequence),
 		processors.NewLiquidityPoolsTransactionProcessor(s.historyQ, sequence),
+		processors.NewLiquidityPoolSnapshotsProcessor(s.historyQ, ledger),
 	})
 }
 
@@ -221,13 +226,15 @@ func (s *ProcessorRunner) RunHistoryArchiveIngestion(
 		log.WithField("sequence", checkpointLedger).
 			Info("Processing entries from History Archive Snapshot")
 
-		err = processors.StreamChanges(s.ctx, changeProcessor, newloggingChangeReader(
+		loggingReader := newloggingChangeReader(
 			changeReader,
 			"historyArchive",
 			checkpointLedger,
 			logFrequency,
 			s.logMemoryStats,
-		))
+		)
+		loggingReader.progress = s.progress
+		err = processors.StreamChanges(s.ctx, changeProcessor, loggingReader)
 		if err != nil {
 			return changeStats.GetResults(), errors.Wrap(err, "Error streaming changes from HAS")
 		}
@@ -268,6 +275,20 @@ func (s *ProcessorRunner) runChangeProcessorOnLedger(
 	return nil
 }
 
+// RunChangeProcessorsOnLedger applies the ledger entry changes of a ledger to
+// the state tables without ingesting its history.
+func (s *ProcessorRunner) RunChangeProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (ingest.StatsChangeProcessorResults, error) {
+	changeStatsProcessor := ingest.StatsChangeProcessor{}
+
+	if err := s.checkIfProtocolVersionSupported(ledger.ProtocolVersion()); err != nil {
+		return changeStatsProcessor.GetResults(), errors.Wrap(err, "Error while checking for supported protocol version")
+	}
+
+	groupChangeProcessors := buildChangeProcessor(s.historyQ, &changeStatsProcessor, ledgerSource, ledger.LedgerSequence())
+	err := s.runChangeProcessorOnLedger(groupChangeProcessors, ledger)
+	return changeStatsProcessor.GetResults(), err
+}
+
 func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	transactionStats processors.StatsLedgerTransactionProcessorResults,
 	transactionDurations processorsRunDurations,
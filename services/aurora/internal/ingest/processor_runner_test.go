@@ -240,6 +240,7 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 	assert.IsType(t, &processors.TradeProcessor{}, processor.processors[4])
 	assert.IsType(t, &processors.ParticipantsProcessor{}, processor.processors[5])
 	assert.IsType(t, &processors.TransactionProcessor{}, processor.processors[6])
+	assert.IsType(t, &processors.LiquidityPoolSnapshotsProcessor{}, processor.processors[9])
 }
 
 func TestProcessorRunnerRunAllProcessorsOnLedger(t *testing.T) {
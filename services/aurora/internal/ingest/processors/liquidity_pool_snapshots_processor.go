@@ -0,0 +1,111 @@
+package processors
+
+import (
+	"context"
+	"time"
+
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// LiquidityPoolSnapshotsProcessor records the state of every liquidity pool
+// modified in a ledger at the end of the ledger. The snapshots are used to
+// build the liquidity pool stats time series.
+type LiquidityPoolSnapshotsProcessor struct {
+	qLiquidityPools history.QHistoryLiquidityPools
+	ledger          xdr.LedgerHeaderHistoryEntry
+	// snapshots holds the latest state of the modified pools, keyed by pool
+	// id. A removed pool has a zero snapshot.
+	snapshots map[string]history.LiquidityPoolSnapshot
+}
+
+func NewLiquidityPoolSnapshotsProcessor(
+	Q history.QHistoryLiquidityPools,
+	ledger xdr.LedgerHeaderHistoryEntry,
+) *LiquidityPoolSnapshotsProcessor {
+	return &LiquidityPoolSnapshotsProcessor{
+		qLiquidityPools: Q,
+		ledger:          ledger,
+		snapshots:       map[string]history.LiquidityPoolSnapshot{},
+	}
+}
+
+// ProcessTransaction updates the snapshots of the liquidity pools modified by
+// the transaction. Transactions are processed in application order, so the
+// last change of a pool is its state at the end of the ledger.
+func (p *LiquidityPoolSnapshotsProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return errors.Wrapf(err, "reading transaction %v changes", transaction.Index)
+	}
+
+	for _, change := range changes {
+		if change.Type != xdr.LedgerEntryTypeLiquidityPool {
+			continue
+		}
+
+		switch {
+		case change.Post != nil:
+			lp := change.Post.Data.MustLiquidityPool()
+			cp := lp.Body.MustConstantProduct()
+			p.snapshots[PoolIDToString(lp.LiquidityPoolId)] = history.LiquidityPoolSnapshot{
+				TrustlineCount: uint64(cp.PoolSharesTrustLineCount),
+				ShareCount:     uint64(cp.TotalPoolShares),
+				ReserveA:       uint64(cp.ReserveA),
+				ReserveB:       uint64(cp.ReserveB),
+			}
+		case change.Pre != nil:
+			lp := change.Pre.Data.MustLiquidityPool()
+			p.snapshots[PoolIDToString(lp.LiquidityPoolId)] = history.LiquidityPoolSnapshot{}
+		default:
+			return errors.New("Invalid io.Change: change.Pre == nil && change.Post == nil")
+		}
+	}
+
+	return nil
+}
+
+func (p *LiquidityPoolSnapshotsProcessor) Commit(ctx context.Context) error {
+	if len(p.snapshots) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(p.snapshots))
+	for id := range p.snapshots {
+		ids = append(ids, id)
+	}
+
+	toInternalID, err := p.qLiquidityPools.CreateHistoryLiquidityPools(ctx, ids, maxBatchSize)
+	if err != nil {
+		return errors.Wrap(err, "Could not create liquidity pool ids")
+	}
+
+	sequence := int32(p.ledger.Header.LedgerSeq)
+	ledgerID := toid.New(sequence, 0, 0).ToInt64()
+	closeTime := time.Unix(int64(p.ledger.Header.ScpValue.CloseTime), 0).UTC()
+
+	batch := p.qLiquidityPools.NewLiquidityPoolSnapshotBatchInsertBuilder(maxBatchSize)
+	for _, id := range ids {
+		internalID, ok := toInternalID[id]
+		if !ok {
+			return errors.Errorf("no internal id found for liquidity pool %s", id)
+		}
+
+		snapshot := p.snapshots[id]
+		snapshot.HistoryLiquidityPoolID = internalID
+		snapshot.HistoryLedgerID = ledgerID
+		snapshot.ClosedAt = closeTime
+		if err := batch.Add(ctx, snapshot); err != nil {
+			return errors.Wrap(err, "could not insert liquidity pool snapshot in db")
+		}
+	}
+
+	if err := batch.Exec(ctx); err != nil {
+		return errors.Wrap(err, "could not flush liquidity pool snapshots to db")
+	}
+
+	return nil
+}
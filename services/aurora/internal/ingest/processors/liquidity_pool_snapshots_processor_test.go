@@ -0,0 +1,156 @@
+//lint:file-ignore U1001 Ignore all unused code, staticcheck doesn't understand testify/suite
+
+package processors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/toid"
+	"github.com/diamcircle/go/xdr"
+)
+
+type LiquidityPoolSnapshotsProcessorTestSuiteLedger struct {
+	suite.Suite
+	ctx                    context.Context
+	processor              *LiquidityPoolSnapshotsProcessor
+	mockQ                  *history.MockQHistoryLiquidityPools
+	mockBatchInsertBuilder *history.MockLiquidityPoolSnapshotBatchInsertBuilder
+
+	ledger xdr.LedgerHeaderHistoryEntry
+}
+
+func TestLiquidityPoolSnapshotsProcessorTestSuiteLedger(t *testing.T) {
+	suite.Run(t, new(LiquidityPoolSnapshotsProcessorTestSuiteLedger))
+}
+
+func (s *LiquidityPoolSnapshotsProcessorTestSuiteLedger) SetupTest() {
+	s.ctx = context.Background()
+	s.mockQ = &history.MockQHistoryLiquidityPools{}
+	s.mockBatchInsertBuilder = &history.MockLiquidityPoolSnapshotBatchInsertBuilder{}
+	s.ledger = xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			LedgerSeq: 20,
+			ScpValue:  xdr.DiamcircleValue{CloseTime: 1000},
+		},
+	}
+
+	s.processor = NewLiquidityPoolSnapshotsProcessor(s.mockQ, s.ledger)
+}
+
+func (s *LiquidityPoolSnapshotsProcessorTestSuiteLedger) TearDownTest() {
+	s.mockQ.AssertExpectations(s.T())
+	s.mockBatchInsertBuilder.AssertExpectations(s.T())
+}
+
+func liquidityPoolEntry(poolID xdr.PoolId, reserveA, reserveB, shares, trustlines xdr.Int64) *xdr.LedgerEntry {
+	return &xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			LiquidityPool: &xdr.LiquidityPoolEntry{
+				LiquidityPoolId: poolID,
+				Body: xdr.LiquidityPoolEntryBody{
+					Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+					ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+						Params: xdr.LiquidityPoolConstantProductParameters{
+							AssetA: xdr.MustNewCreditAsset("EUR", "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+							AssetB: xdr.MustNewNativeAsset(),
+							Fee:    30,
+						},
+						ReserveA:                 reserveA,
+						ReserveB:                 reserveB,
+						TotalPoolShares:          shares,
+						PoolSharesTrustLineCount: trustlines,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (s *LiquidityPoolSnapshotsProcessorTestSuiteLedger) TestNoLiquidityPools() {
+	s.Assert().NoError(s.processor.ProcessTransaction(s.ctx, createTransaction(true, 1)))
+	s.Assert().NoError(s.processor.Commit(s.ctx))
+}
+
+func (s *LiquidityPoolSnapshotsProcessorTestSuiteLedger) TestSnapshotsLastStateOfLedger() {
+	updatedPool := xdr.PoolId{0xca, 0xfe}
+	removedPool := xdr.PoolId{0xba, 0xbe}
+
+	first := createTransaction(true, 1)
+	first.UnsafeMeta.V = 2
+	first.UnsafeMeta.V2.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{
+				Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
+				State: liquidityPoolEntry(updatedPool, 100, 200, 141, 2),
+			},
+			{
+				Type:    xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+				Updated: liquidityPoolEntry(updatedPool, 110, 190, 141, 2),
+			},
+			{
+				Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
+				State: liquidityPoolEntry(removedPool, 1, 1, 1, 1),
+			},
+			{
+				Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved,
+				Removed: &xdr.LedgerKey{
+					Type:          xdr.LedgerEntryTypeLiquidityPool,
+					LiquidityPool: &xdr.LedgerKeyLiquidityPool{LiquidityPoolId: removedPool},
+				},
+			},
+		}},
+	}
+
+	second := createTransaction(true, 1)
+	second.Index = 2
+	second.UnsafeMeta.V = 2
+	second.UnsafeMeta.V2.Operations = []xdr.OperationMeta{
+		{Changes: xdr.LedgerEntryChanges{
+			{
+				Type:  xdr.LedgerEntryChangeTypeLedgerEntryState,
+				State: liquidityPoolEntry(updatedPool, 110, 190, 141, 2),
+			},
+			{
+				Type:    xdr.LedgerEntryChangeTypeLedgerEntryUpdated,
+				Updated: liquidityPoolEntry(updatedPool, 120, 180, 145, 3),
+			},
+		}},
+	}
+
+	updatedID, removedID := PoolIDToString(updatedPool), PoolIDToString(removedPool)
+	s.mockQ.On("CreateHistoryLiquidityPools", s.ctx, mock.AnythingOfType("[]string"), maxBatchSize).
+		Run(func(args mock.Arguments) {
+			s.Assert().ElementsMatch([]string{updatedID, removedID}, args.Get(1).([]string))
+		}).Return(map[string]int64{updatedID: 1, removedID: 2}, nil).Once()
+	s.mockQ.On("NewLiquidityPoolSnapshotBatchInsertBuilder", maxBatchSize).
+		Return(s.mockBatchInsertBuilder).Once()
+
+	ledgerID := toid.New(20, 0, 0).ToInt64()
+	closedAt := time.Unix(1000, 0).UTC()
+	s.mockBatchInsertBuilder.On("Add", s.ctx, history.LiquidityPoolSnapshot{
+		HistoryLiquidityPoolID: 1,
+		HistoryLedgerID:        ledgerID,
+		ClosedAt:               closedAt,
+		TrustlineCount:         3,
+		ShareCount:             145,
+		ReserveA:               120,
+		ReserveB:               180,
+	}).Return(nil).Once()
+	s.mockBatchInsertBuilder.On("Add", s.ctx, history.LiquidityPoolSnapshot{
+		HistoryLiquidityPoolID: 2,
+		HistoryLedgerID:        ledgerID,
+		ClosedAt:               closedAt,
+	}).Return(nil).Once()
+	s.mockBatchInsertBuilder.On("Exec", s.ctx).Return(nil).Once()
+
+	s.Assert().NoError(s.processor.ProcessTransaction(s.ctx, first))
+	s.Assert().NoError(s.processor.ProcessTransaction(s.ctx, second))
+	s.Assert().NoError(s.processor.Commit(s.ctx))
+}
@@ -0,0 +1,297 @@
+package ingest
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/aurora/internal/ingest/processors"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+// ledgerEntrySet is a stateEntryWriter collecting ledger entries by their
+// base64 encoded ledger key.
+type ledgerEntrySet map[string]xdr.LedgerEntry
+
+func (set ledgerEntrySet) Write(entry xdr.LedgerEntry) error {
+	key, err := xdr.MarshalBase64(entry.LedgerKey())
+	if err != nil {
+		return errors.Wrap(err, "could not marshal ledger key")
+	}
+	set[key] = *entry.Normalize()
+	return nil
+}
+
+// RepairState replaces the entries with the given ledger keys in the state
+// tables with their version in the history archive state at the given
+// checkpoint ledger, or at the latest checkpoint if checkpointLedger is 0.
+// Entries missing from the history archive state are removed. Entries
+// created, updated or removed by a ledger ingested after the checkpoint
+// ledger are skipped, since their history archive version is stale; the
+// ledgers are read from the ledger backend while ingestion is blocked. The
+// changes are applied by the change processors, as if they were part of the
+// last ingested ledger, so derived data like signers and asset stats is
+// repaired too. The state is marked valid again only if every entry was
+// repaired and matches its history archive version when read back. It
+// returns the number of repaired entries.
+func (s *system) RepairState(checkpointLedger uint32, keys []xdr.LedgerKey) (int, error) {
+	if checkpointLedger == 0 {
+		var err error
+		checkpointLedger, err = s.historyAdapter.GetLatestLedgerSequence()
+		if err != nil {
+			return 0, errors.Wrap(err, "Error getting the latest ledger sequence")
+		}
+	}
+	if !s.checkpointManager.IsCheckpoint(checkpointLedger) {
+		return 0, errors.Errorf("ledger %d is not a checkpoint ledger", checkpointLedger)
+	}
+
+	wanted := map[string]bool{}
+	for _, key := range keys {
+		encodedKey, err := xdr.MarshalBase64(key)
+		if err != nil {
+			return 0, errors.Wrap(err, "could not marshal ledger key")
+		}
+		wanted[encodedKey] = true
+	}
+
+	localLog := log.WithFields(logpkg.F{
+		"subservice": "repair_state",
+		"checkpoint": checkpointLedger,
+	})
+	localLog.WithField("entries", len(keys)).Info("Loading entries from history archive state")
+	expected, err := s.loadCheckpointEntries(checkpointLedger, wanted)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.historyQ.Begin(); err != nil {
+		return 0, errors.Wrap(err, "Error starting a transaction")
+	}
+	defer s.historyQ.Rollback()
+
+	// This will get the value `FOR UPDATE`, blocking it for other nodes.
+	lastIngestedLedger, err := s.historyQ.GetLastLedgerIngest(s.ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, getLastIngestedErrMsg)
+	}
+	if lastIngestedLedger < checkpointLedger {
+		return 0, errors.Errorf(
+			"last ingested ledger %d is older than checkpoint ledger %d",
+			lastIngestedLedger, checkpointLedger,
+		)
+	}
+
+	// The version of entries changed after the checkpoint, including the
+	// ones removed since then, cannot be checked against the history archive.
+	changed, err := s.changedEntries(checkpointLedger+1, lastIngestedLedger, wanted)
+	if err != nil {
+		return 0, err
+	}
+
+	actual := ledgerEntrySet{}
+	err = addLedgerKeysToStateVerifier(s.ctx, actual, processors.AssetStatSet{}, s.historyQ, keys, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	changeStats := ingest.StatsChangeProcessor{}
+	changeProcessor := buildChangeProcessor(s.historyQ, &changeStats, ledgerSource, lastIngestedLedger)
+	repaired, skipped := 0, 0
+	for _, key := range keys {
+		encodedKey, err := xdr.MarshalBase64(key)
+		if err != nil {
+			return 0, errors.Wrap(err, "could not marshal ledger key")
+		}
+		if changed[encodedKey] {
+			localLog.WithField("key", encodedKey).Warn("Entry was changed after the checkpoint ledger, skipping")
+			skipped++
+			continue
+		}
+		change := ingest.Change{Type: key.Type}
+		if entry, ok := actual[encodedKey]; ok {
+			if uint32(entry.LastModifiedLedgerSeq) > checkpointLedger {
+				localLog.WithField("key", encodedKey).Warn("Entry was modified after the checkpoint ledger, skipping")
+				skipped++
+				continue
+			}
+			change.Pre = &entry
+		}
+		if entry, ok := expected[encodedKey]; ok {
+			change.Post = &entry
+		}
+
+		equal, err := entriesEqual(change.Pre, change.Post)
+		if err != nil {
+			return 0, err
+		}
+		if equal {
+			continue
+		}
+
+		if err = changeProcessor.ProcessChange(s.ctx, change); err != nil {
+			return 0, errors.Wrapf(err, "Error repairing entry %s", encodedKey)
+		}
+		localLog.WithField("key", encodedKey).Info("Repairing entry")
+		repaired++
+	}
+
+	if err = changeProcessor.Commit(s.ctx); err != nil {
+		return 0, errors.Wrap(err, "Error commiting changes from processor")
+	}
+
+	if repaired > 0 && skipped == 0 {
+		// Verify the entries again and let the state be verified at the next
+		// checkpoint instead of rebuilding it only if they all match.
+		mismatches, err := s.verifyRepairedEntries(keys, expected)
+		if err != nil {
+			return 0, err
+		}
+		if mismatches == 0 {
+			if err = s.historyQ.UpdateExpStateInvalid(s.ctx, false); err != nil {
+				return 0, errors.Wrap(err, updateExpStateInvalidErrMsg)
+			}
+			if err = s.historyQ.UpdateStateRebuildRequested(s.ctx, false); err != nil {
+				return 0, errors.Wrap(err, "Error clearing state rebuild request")
+			}
+		} else {
+			localLog.WithField("mismatches", mismatches).
+				Warn("Repaired entries do not match the history archive state, state is still invalid")
+		}
+	} else if skipped > 0 {
+		localLog.WithField("skipped", skipped).Warn("Not all entries were repaired, state is still invalid")
+	}
+	if err = s.historyQ.Commit(); err != nil {
+		return 0, errors.Wrap(err, commitErrMsg)
+	}
+	return repaired, nil
+}
+
+// changedEntries returns the keys, among the wanted ones, of the entries
+// created, updated or removed by the ledgers from fromLedger to toLedger.
+func (s *system) changedEntries(fromLedger, toLedger uint32, wanted map[string]bool) (map[string]bool, error) {
+	changed := map[string]bool{}
+	if fromLedger > toLedger {
+		return changed, nil
+	}
+
+	err := s.ledgerBackend.PrepareRange(s.ctx, ledgerbackend.BoundedRange(fromLedger, toLedger))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error preparing range")
+	}
+	for sequence := fromLedger; sequence <= toLedger; sequence++ {
+		ledger, err := s.ledgerBackend.GetLedger(s.ctx, sequence)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error getting ledger %d", sequence)
+		}
+		reader, err := ingest.NewLedgerChangeReaderFromLedgerCloseMeta(s.config.NetworkPassphrase, ledger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error creating change reader for ledger %d", sequence)
+		}
+		for {
+			change, err := reader.Read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				reader.Close()
+				return nil, errors.Wrapf(err, "Error reading changes of ledger %d", sequence)
+			}
+
+			entry := change.Post
+			if entry == nil {
+				entry = change.Pre
+			}
+			encodedKey, err := xdr.MarshalBase64(entry.LedgerKey())
+			if err != nil {
+				reader.Close()
+				return nil, errors.Wrap(err, "could not marshal ledger key")
+			}
+			if wanted[encodedKey] {
+				changed[encodedKey] = true
+			}
+		}
+		reader.Close()
+	}
+	return changed, nil
+}
+
+// verifyRepairedEntries reads the entries with the given ledger keys back
+// from the state tables and returns how many differ from their history
+// archive version.
+func (s *system) verifyRepairedEntries(keys []xdr.LedgerKey, expected ledgerEntrySet) (int, error) {
+	repaired := ledgerEntrySet{}
+	err := addLedgerKeysToStateVerifier(s.ctx, repaired, processors.AssetStatSet{}, s.historyQ, keys, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	mismatches := 0
+	for _, key := range keys {
+		encodedKey, err := xdr.MarshalBase64(key)
+		if err != nil {
+			return 0, errors.Wrap(err, "could not marshal ledger key")
+		}
+		var actual, want *xdr.LedgerEntry
+		if entry, ok := repaired[encodedKey]; ok {
+			actual = &entry
+		}
+		if entry, ok := expected[encodedKey]; ok {
+			want = &entry
+		}
+		equal, err := entriesEqual(actual, want)
+		if err != nil {
+			return 0, err
+		}
+		if !equal {
+			mismatches++
+		}
+	}
+	return mismatches, nil
+}
+
+// loadCheckpointEntries returns the entries with the given base64 encoded
+// ledger keys in the history archive state at the given checkpoint ledger.
+func (s *system) loadCheckpointEntries(checkpointLedger uint32, wanted map[string]bool) (ledgerEntrySet, error) {
+	stateReader, err := s.historyAdapter.GetState(s.ctx, checkpointLedger)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error running GetState")
+	}
+	defer stateReader.Close()
+
+	entries := ledgerEntrySet{}
+	for len(entries) < len(wanted) {
+		change, err := stateReader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "Error reading history archive state")
+		}
+
+		encodedKey, err := xdr.MarshalBase64(change.Post.LedgerKey())
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal ledger key")
+		}
+		if wanted[encodedKey] {
+			entries[encodedKey] = *change.Post.Normalize()
+		}
+	}
+	return entries, nil
+}
+
+func entriesEqual(a, b *xdr.LedgerEntry) (bool, error) {
+	if a == nil || b == nil {
+		return a == b, nil
+	}
+	aBytes, err := a.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	bBytes, err := b.MarshalBinary()
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(aBytes, bBytes), nil
+}
@@ -0,0 +1,207 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/xdr"
+)
+
+func repairTestOffer(amount int64, lastModifiedLedger uint32) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: xdr.Uint32(lastModifiedLedger),
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeOffer,
+			Offer: &xdr.OfferEntry{
+				SellerId: xdr.MustAddress("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"),
+				OfferId:  xdr.Int64(10),
+				Selling:  xdr.MustNewNativeAsset(),
+				Buying:   xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+				Amount:   xdr.Int64(amount),
+				Price:    xdr.Price{N: 1, D: 2},
+			},
+		},
+	}
+}
+
+func repairTestOfferRow(entry xdr.LedgerEntry) history.Offer {
+	offer := entry.Data.MustOffer()
+	return history.Offer{
+		SellerID:           offer.SellerId.Address(),
+		OfferID:            int64(offer.OfferId),
+		SellingAsset:       offer.Selling,
+		BuyingAsset:        offer.Buying,
+		Amount:             int64(offer.Amount),
+		Pricen:             int32(offer.Price.N),
+		Priced:             int32(offer.Price.D),
+		Price:              0.5,
+		LastModifiedLedger: uint32(entry.LastModifiedLedgerSeq),
+	}
+}
+
+func newRepairTestSystem(
+	historyQ *mockDBQ,
+	historyAdapter *mockHistoryArchiveAdapter,
+	ledgerBackend *mockLedgerBackend,
+	archiveEntries ...xdr.LedgerEntry,
+) *system {
+	s := &system{
+		ctx:               context.Background(),
+		historyQ:          historyQ,
+		historyAdapter:    historyAdapter,
+		ledgerBackend:     ledgerBackend,
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+
+	reader := &ingest.MockChangeReader{}
+	for i := range archiveEntries {
+		reader.On("Read").Return(ingest.Change{
+			Type: archiveEntries[i].Data.Type,
+			Post: &archiveEntries[i],
+		}, nil).Once()
+	}
+	reader.On("Read").Return(ingest.Change{}, io.EOF).Maybe()
+	reader.On("Close").Return(nil).Once()
+	historyAdapter.On("GetState", s.ctx, uint32(63)).Return(reader, nil).Once()
+	return s
+}
+
+// expectRepairTestLedgers makes the ledger backend return the ledgers from 64
+// to lastLedger, with the changes of upgrades for ledger 64.
+func expectRepairTestLedgers(s *system, ledgerBackend *mockLedgerBackend, lastLedger uint32, changes ...xdr.LedgerEntryChange) {
+	ledgerBackend.On("PrepareRange", s.ctx, ledgerbackend.BoundedRange(64, lastLedger)).Return(nil).Once()
+	for sequence := uint32(64); sequence <= lastLedger; sequence++ {
+		ledger := xdr.LedgerCloseMeta{V0: &xdr.LedgerCloseMetaV0{}}
+		ledger.V0.LedgerHeader.Header.LedgerSeq = xdr.Uint32(sequence)
+		if sequence == 64 && len(changes) > 0 {
+			ledger.V0.UpgradesProcessing = []xdr.UpgradeEntryMeta{{Changes: changes}}
+		}
+		ledgerBackend.On("GetLedger", s.ctx, sequence).Return(ledger, nil).Once()
+	}
+}
+
+func TestRepairStateNotCheckpoint(t *testing.T) {
+	s := &system{
+		ctx:               context.Background(),
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+	_, err := s.RepairState(62, nil)
+	assert.EqualError(t, err, "ledger 62 is not a checkpoint ledger")
+}
+
+func TestRepairStateUpdatesEntry(t *testing.T) {
+	historyQ := &mockDBQ{}
+	historyAdapter := &mockHistoryArchiveAdapter{}
+	ledgerBackend := &mockLedgerBackend{}
+	expected := repairTestOffer(100, 60)
+	s := newRepairTestSystem(historyQ, historyAdapter, ledgerBackend, expected)
+	actual := repairTestOffer(50, 60)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(65), nil).Once()
+	expectRepairTestLedgers(s, ledgerBackend, 65)
+	historyQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{10}).
+		Return([]history.Offer{repairTestOfferRow(actual)}, nil).Once()
+	historyQ.MockQOffers.On("UpsertOffers", s.ctx, []history.Offer{repairTestOfferRow(expected)}).Return(nil).Once()
+	// the repaired entry is verified again
+	historyQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{10}).
+		Return([]history.Offer{repairTestOfferRow(expected)}, nil).Once()
+	historyQ.On("UpdateExpStateInvalid", s.ctx, false).Return(nil).Once()
+	historyQ.On("UpdateStateRebuildRequested", s.ctx, false).Return(nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	repaired, err := s.RepairState(63, []xdr.LedgerKey{expected.LedgerKey()})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	historyQ.AssertExpectations(t)
+	historyQ.MockQOffers.AssertExpectations(t)
+	historyAdapter.AssertExpectations(t)
+	ledgerBackend.AssertExpectations(t)
+}
+
+func TestRepairStateKeepsStateInvalidOnMismatch(t *testing.T) {
+	historyQ := &mockDBQ{}
+	historyAdapter := &mockHistoryArchiveAdapter{}
+	ledgerBackend := &mockLedgerBackend{}
+	expected := repairTestOffer(100, 60)
+	s := newRepairTestSystem(historyQ, historyAdapter, ledgerBackend, expected)
+	actual := repairTestOffer(50, 60)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(63), nil).Once()
+	historyQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{10}).
+		Return([]history.Offer{repairTestOfferRow(actual)}, nil).Twice()
+	historyQ.MockQOffers.On("UpsertOffers", s.ctx, []history.Offer{repairTestOfferRow(expected)}).Return(nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	repaired, err := s.RepairState(63, []xdr.LedgerKey{expected.LedgerKey()})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, repaired)
+	historyQ.AssertExpectations(t)
+	historyQ.MockQOffers.AssertExpectations(t)
+	historyQ.AssertNotCalled(t, "UpdateExpStateInvalid", mock.Anything, mock.Anything)
+	historyQ.AssertNotCalled(t, "UpdateStateRebuildRequested", mock.Anything, mock.Anything)
+}
+
+func TestRepairStateSkipsEntriesModifiedAfterCheckpoint(t *testing.T) {
+	historyQ := &mockDBQ{}
+	historyAdapter := &mockHistoryArchiveAdapter{}
+	ledgerBackend := &mockLedgerBackend{}
+	expected := repairTestOffer(100, 60)
+	s := newRepairTestSystem(historyQ, historyAdapter, ledgerBackend, expected)
+	actual := repairTestOffer(50, 65)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(65), nil).Once()
+	expectRepairTestLedgers(s, ledgerBackend, 65)
+	historyQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{10}).
+		Return([]history.Offer{repairTestOfferRow(actual)}, nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	repaired, err := s.RepairState(63, []xdr.LedgerKey{expected.LedgerKey()})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	historyQ.AssertExpectations(t)
+	historyQ.MockQOffers.AssertExpectations(t)
+	historyQ.MockQOffers.AssertNotCalled(t, "UpsertOffers", mock.Anything, mock.Anything)
+}
+
+func TestRepairStateSkipsEntriesRemovedAfterCheckpoint(t *testing.T) {
+	historyQ := &mockDBQ{}
+	historyAdapter := &mockHistoryArchiveAdapter{}
+	ledgerBackend := &mockLedgerBackend{}
+	expected := repairTestOffer(100, 60)
+	s := newRepairTestSystem(historyQ, historyAdapter, ledgerBackend, expected)
+	key := expected.LedgerKey()
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(64), nil).Once()
+	// the offer is removed in ledger 64, so it is not in the state tables
+	expectRepairTestLedgers(s, ledgerBackend, 64,
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &expected},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+	)
+	historyQ.MockQOffers.On("GetOffersByIDs", s.ctx, []int64{10}).
+		Return([]history.Offer{}, nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	repaired, err := s.RepairState(63, []xdr.LedgerKey{key})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, repaired)
+	historyQ.AssertExpectations(t)
+	historyQ.MockQOffers.AssertExpectations(t)
+	historyQ.MockQOffers.AssertNotCalled(t, "UpsertOffers", mock.Anything, mock.Anything)
+	ledgerBackend.AssertExpectations(t)
+}
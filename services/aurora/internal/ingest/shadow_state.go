@@ -0,0 +1,353 @@
+package ingest
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	// shadowCatchupBatchSize is the maximum number of spooled ledgers applied
+	// to the shadow state tables after each ingested ledger. Once fewer
+	// ledgers are left they are applied in the transaction swapping the
+	// shadow tables with the live ones.
+	shadowCatchupBatchSize = 20
+	// shadowCheckpointPollInterval is the interval at which the history
+	// archive is checked for the checkpoint the shadow state is built from.
+	shadowCheckpointPollInterval = 10 * time.Second
+)
+
+// shadowRebuild is a rebuild of the state in the shadow state tables. The
+// state at checkpointLedger is ingested from the history archive by a
+// separate goroutine while the ledgers ingested in the meantime are spooled
+// to a temporary file. Once the history archive ingestion is done, the
+// spooled ledgers are applied to the shadow tables, which are then swapped
+// with the live ones. The live state tables are served during the whole
+// rebuild.
+type shadowRebuild struct {
+	checkpointLedger uint32
+	// lastSpooledLedger is the last ledger written to the spool.
+	lastSpooledLedger uint32
+	// lastAppliedLedger is the last ledger applied to the shadow tables.
+	lastAppliedLedger uint32
+
+	spool  *os.File
+	stream *historyarchive.XdrStream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// done is closed when the history archive ingestion is over, err is its
+	// result.
+	done chan struct{}
+	err  error
+}
+
+func newShadowRebuild(ctx context.Context, checkpointLedger uint32) (*shadowRebuild, error) {
+	spool, err := ioutil.TempFile("", "aurora-shadow-state")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create spool file")
+	}
+	rdr, err := os.Open(spool.Name())
+	if err != nil {
+		spool.Close()
+		os.Remove(spool.Name())
+		return nil, errors.Wrap(err, "could not open spool file")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &shadowRebuild{
+		checkpointLedger:  checkpointLedger,
+		lastSpooledLedger: checkpointLedger,
+		lastAppliedLedger: checkpointLedger,
+		spool:             spool,
+		stream:            historyarchive.NewXdrStream(rdr),
+		ctx:               ctx,
+		cancel:            cancel,
+		done:              make(chan struct{}),
+	}, nil
+}
+
+// built returns true if the history archive ingestion is over.
+func (r *shadowRebuild) built() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *shadowRebuild) write(ledger xdr.LedgerCloseMeta) error {
+	if ledger.LedgerSequence() != r.lastSpooledLedger+1 {
+		return errors.Errorf(
+			"ledger %d is not contiguous with last spooled ledger %d",
+			ledger.LedgerSequence(), r.lastSpooledLedger,
+		)
+	}
+	if err := xdr.MarshalFramed(r.spool, ledger); err != nil {
+		return errors.Wrap(err, "could not spool ledger")
+	}
+	r.lastSpooledLedger = ledger.LedgerSequence()
+	return nil
+}
+
+func (r *shadowRebuild) next() (xdr.LedgerCloseMeta, error) {
+	var ledger xdr.LedgerCloseMeta
+	if err := r.stream.ReadOne(&ledger); err != nil {
+		return ledger, errors.Wrap(err, "could not read spooled ledger")
+	}
+	if ledger.LedgerSequence() != r.lastAppliedLedger+1 {
+		return ledger, errors.Errorf(
+			"unexpected spooled ledger %d after %d",
+			ledger.LedgerSequence(), r.lastAppliedLedger,
+		)
+	}
+	return ledger, nil
+}
+
+func (r *shadowRebuild) close() {
+	r.cancel()
+	r.stream.Close()
+	r.spool.Close()
+	os.Remove(r.spool.Name())
+}
+
+// maybeRebuildShadowState drives the rebuild of the state in the shadow
+// state tables. It must be called after a ledger was ingested. A rebuild
+// starts at the first checkpoint ledger ingested after it was requested. If
+// the rebuild fails, the shadow tables are dropped and the rebuild starts
+// again at the next checkpoint.
+func (s *system) maybeRebuildShadowState(ledger xdr.LedgerCloseMeta) {
+	if s.shadowRebuild == nil {
+		if !s.checkpointManager.IsCheckpoint(ledger.LedgerSequence()) {
+			return
+		}
+		requested, err := s.historyQ.GetStateRebuildRequested(s.ctx)
+		if err != nil {
+			if !isCancelledError(err) {
+				log.WithError(err).Error("Error getting state rebuild requested value")
+			}
+			return
+		}
+		if requested {
+			s.startShadowRebuild(ledger)
+		}
+		return
+	}
+
+	err := s.shadowRebuild.write(ledger)
+	if err == nil && s.shadowRebuild.built() {
+		err = s.shadowRebuild.err
+		if err == nil {
+			err = s.catchUpShadowState()
+		}
+	}
+	if err != nil {
+		if !isCancelledError(err) {
+			log.WithError(err).WithField("checkpoint", s.shadowRebuild.checkpointLedger).
+				Error("Error rebuilding state in shadow tables, retrying at next checkpoint")
+		}
+		s.abortShadowRebuild()
+	}
+}
+
+func (s *system) startShadowRebuild(ledger xdr.LedgerCloseMeta) {
+	r, err := newShadowRebuild(s.ctx, ledger.LedgerSequence())
+	if err != nil {
+		log.WithError(err).Error("Error starting state rebuild in shadow tables")
+		return
+	}
+	s.shadowRebuild = r
+
+	log.WithField("checkpoint", r.checkpointLedger).Info("Rebuilding state in shadow tables")
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(r.done)
+		r.err = s.buildShadowState(r.ctx, r.checkpointLedger, ledger.ProtocolVersion(), ledger.BucketListHash())
+	}()
+}
+
+// buildShadowState ingests the state at checkpointLedger from the history
+// archive into new shadow state tables.
+func (s *system) buildShadowState(
+	ctx context.Context, checkpointLedger, protocolVersion uint32, bucketListHash xdr.Hash,
+) error {
+	for {
+		latest, err := s.historyAdapter.GetLatestLedgerSequence()
+		if err != nil {
+			return errors.Wrap(err, "Error getting last ledger in history archive")
+		}
+		if latest >= checkpointLedger {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(shadowCheckpointPollInterval):
+		}
+	}
+
+	historyQ := s.historyQ.CloneIngestionQ()
+	if err := historyQ.Begin(); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	defer historyQ.Rollback()
+
+	if err := lockShadowStateTables(ctx, historyQ); err != nil {
+		return err
+	}
+	if err := historyQ.CreateShadowStateTables(ctx); err != nil {
+		return err
+	}
+	if err := historyQ.UseShadowStateTables(ctx); err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	runner := &ProcessorRunner{
+		ctx:            ctx,
+		config:         s.config,
+		historyQ:       historyQ,
+		historyAdapter: s.historyAdapter,
+	}
+	stats, err := runner.RunHistoryArchiveIngestion(checkpointLedger, protocolVersion, bucketListHash)
+	if err != nil {
+		return errors.Wrap(err, "Error ingesting history archive")
+	}
+	if err = historyQ.Commit(); err != nil {
+		return errors.Wrap(err, commitErrMsg)
+	}
+
+	log.
+		WithFields(stats.Map()).
+		WithFields(logpkg.F{
+			"checkpoint": checkpointLedger,
+			"duration":   time.Since(startTime).Seconds(),
+		}).
+		Info("Ingested state in shadow tables")
+	return nil
+}
+
+// catchUpShadowState applies spooled ledgers to the shadow state tables. When
+// few enough ledgers are left, it applies them and swaps the shadow tables
+// with the live ones in the same transaction.
+func (s *system) catchUpShadowState() error {
+	r := s.shadowRebuild
+	if err := s.historyQ.Begin(); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	defer s.historyQ.Rollback()
+
+	swap := r.lastSpooledLedger-r.lastAppliedLedger <= shadowCatchupBatchSize
+	if swap {
+		// This will get the value `FOR UPDATE`, blocking it for other nodes.
+		lastIngestedLedger, err := s.historyQ.GetLastLedgerIngest(s.ctx)
+		if err != nil {
+			return errors.Wrap(err, getLastIngestedErrMsg)
+		}
+		if lastIngestedLedger != r.lastSpooledLedger {
+			return errors.Errorf(
+				"last ingested ledger %d does not match last spooled ledger %d",
+				lastIngestedLedger, r.lastSpooledLedger,
+			)
+		}
+	}
+
+	if err := lockShadowStateTables(s.ctx, s.historyQ); err != nil {
+		return err
+	}
+	if err := s.historyQ.UseShadowStateTables(s.ctx); err != nil {
+		return err
+	}
+	for i := 0; r.lastAppliedLedger < r.lastSpooledLedger && (swap || i < shadowCatchupBatchSize); i++ {
+		ledger, err := r.next()
+		if err != nil {
+			return err
+		}
+		if _, err = s.runner.RunChangeProcessorsOnLedger(ledger); err != nil {
+			return errors.Wrapf(err, "Error applying ledger %d to shadow tables", ledger.LedgerSequence())
+		}
+		r.lastAppliedLedger = ledger.LedgerSequence()
+	}
+
+	if swap {
+		if err := s.historyQ.SwapShadowStateTables(s.ctx); err != nil {
+			return err
+		}
+		if err := s.historyQ.UpdateStateRebuildRequested(s.ctx, false); err != nil {
+			return errors.Wrap(err, "Error clearing state rebuild request")
+		}
+		if err := s.historyQ.UpdateExpStateInvalid(s.ctx, false); err != nil {
+			return errors.Wrap(err, updateExpStateInvalidErrMsg)
+		}
+	}
+
+	if err := s.historyQ.Commit(); err != nil {
+		return errors.Wrap(err, commitErrMsg)
+	}
+
+	if swap {
+		log.WithFields(logpkg.F{
+			"checkpoint": r.checkpointLedger,
+			"ledger":     r.lastAppliedLedger,
+		}).Info("Swapped shadow state tables with live state tables")
+		r.close()
+		s.shadowRebuild = nil
+	}
+	return nil
+}
+
+// lockShadowStateTables acquires the shadow state lock in the transaction of
+// historyQ, so that aurora instances do not rebuild the state concurrently.
+func lockShadowStateTables(ctx context.Context, historyQ history.IngestionQ) error {
+	locked, err := historyQ.TryLockShadowStateTables(ctx)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return errors.New("shadow state tables are used by another instance")
+	}
+	return nil
+}
+
+// abortShadowRebuild stops the current rebuild and drops the shadow state
+// tables, unless another instance is using them. The rebuild request is kept
+// so that the rebuild starts again at the next checkpoint.
+func (s *system) abortShadowRebuild() {
+	r := s.shadowRebuild
+	s.shadowRebuild = nil
+	r.cancel()
+	go func() {
+		// Wait for the history archive ingestion to stop before cleaning up.
+		<-r.done
+		r.close()
+	}()
+
+	if err := s.dropShadowStateTables(); err != nil && !isCancelledError(err) {
+		log.WithError(err).Warn("Error dropping shadow state tables")
+	}
+}
+
+func (s *system) dropShadowStateTables() error {
+	if err := s.historyQ.Begin(); err != nil {
+		return errors.Wrap(err, "Error starting a transaction")
+	}
+	defer s.historyQ.Rollback()
+
+	locked, err := s.historyQ.TryLockShadowStateTables(s.ctx)
+	if err != nil || !locked {
+		return err
+	}
+	if err = s.historyQ.DropShadowStateTables(s.ctx); err != nil {
+		return err
+	}
+	return errors.Wrap(s.historyQ.Commit(), commitErrMsg)
+}
@@ -0,0 +1,138 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/xdr"
+)
+
+func shadowTestLedger(seq uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V0: &xdr.LedgerCloseMetaV0{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq:     xdr.Uint32(seq),
+					LedgerVersion: xdr.Uint32(MaxSupportedProtocolVersion),
+				},
+			},
+		},
+	}
+}
+
+func newShadowTestSystem(historyQ *mockDBQ, runner *mockProcessorsRunner) *system {
+	return &system{
+		ctx:               context.Background(),
+		historyQ:          historyQ,
+		runner:            runner,
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+}
+
+func builtShadowRebuild(t *testing.T, s *system, checkpointLedger uint32) *shadowRebuild {
+	r, err := newShadowRebuild(s.ctx, checkpointLedger)
+	require.NoError(t, err)
+	close(r.done)
+	s.shadowRebuild = r
+	return r
+}
+
+func TestMaybeRebuildShadowStateNotRequested(t *testing.T) {
+	historyQ := &mockDBQ{}
+	s := newShadowTestSystem(historyQ, &mockProcessorsRunner{})
+
+	// Rebuild requests are only checked at checkpoints.
+	s.maybeRebuildShadowState(shadowTestLedger(100))
+
+	historyQ.On("GetStateRebuildRequested", s.ctx).Return(false, nil).Once()
+	s.maybeRebuildShadowState(shadowTestLedger(127))
+
+	assert.Nil(t, s.shadowRebuild)
+	historyQ.AssertExpectations(t)
+}
+
+func TestMaybeRebuildShadowStateCatchUpAndSwap(t *testing.T) {
+	historyQ := &mockDBQ{}
+	runner := &mockProcessorsRunner{}
+	s := newShadowTestSystem(historyQ, runner)
+	builtShadowRebuild(t, s, 127)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", s.ctx).Return(uint32(128), nil).Once()
+	historyQ.On("TryLockShadowStateTables", s.ctx).Return(true, nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx).Return(nil).Once()
+	runner.On("RunChangeProcessorsOnLedger", mock.MatchedBy(func(ledger xdr.LedgerCloseMeta) bool {
+		return ledger.LedgerSequence() == 128
+	})).
+		Return(ingest.StatsChangeProcessorResults{}, nil).Once()
+	historyQ.On("SwapShadowStateTables", s.ctx).Return(nil).Once()
+	historyQ.On("UpdateStateRebuildRequested", s.ctx, false).Return(nil).Once()
+	historyQ.On("UpdateExpStateInvalid", s.ctx, false).Return(nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	s.maybeRebuildShadowState(shadowTestLedger(128))
+
+	assert.Nil(t, s.shadowRebuild)
+	historyQ.AssertExpectations(t)
+	runner.AssertExpectations(t)
+}
+
+func TestMaybeRebuildShadowStateCatchUpInBatches(t *testing.T) {
+	historyQ := &mockDBQ{}
+	runner := &mockProcessorsRunner{}
+	s := newShadowTestSystem(historyQ, runner)
+	r, err := newShadowRebuild(s.ctx, 127)
+	require.NoError(t, err)
+	s.shadowRebuild = r
+	defer r.close()
+
+	// Ledgers are spooled while the history archive is being ingested.
+	last := uint32(127 + shadowCatchupBatchSize + 5)
+	for seq := uint32(128); seq < last; seq++ {
+		s.maybeRebuildShadowState(shadowTestLedger(seq))
+	}
+	close(r.done)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("TryLockShadowStateTables", s.ctx).Return(true, nil).Once()
+	historyQ.On("UseShadowStateTables", s.ctx).Return(nil).Once()
+	runner.On("RunChangeProcessorsOnLedger", mock.MatchedBy(func(ledger xdr.LedgerCloseMeta) bool {
+		return ledger.LedgerSequence() < 128+shadowCatchupBatchSize
+	})).
+		Return(ingest.StatsChangeProcessorResults{}, nil).Times(shadowCatchupBatchSize)
+	historyQ.On("Commit").Return(nil).Once()
+
+	s.maybeRebuildShadowState(shadowTestLedger(last))
+
+	assert.Equal(t, r, s.shadowRebuild)
+	assert.Equal(t, last, r.lastSpooledLedger)
+	assert.Equal(t, uint32(127+shadowCatchupBatchSize), r.lastAppliedLedger)
+	historyQ.AssertExpectations(t)
+	runner.AssertExpectations(t)
+}
+
+func TestMaybeRebuildShadowStateAbortsOnGap(t *testing.T) {
+	historyQ := &mockDBQ{}
+	s := newShadowTestSystem(historyQ, &mockProcessorsRunner{})
+	builtShadowRebuild(t, s, 127)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	historyQ.On("TryLockShadowStateTables", s.ctx).Return(true, nil).Once()
+	historyQ.On("DropShadowStateTables", s.ctx).Return(nil).Once()
+	historyQ.On("Commit").Return(nil).Once()
+
+	// Ledger 128 was ingested by another instance.
+	s.maybeRebuildShadowState(shadowTestLedger(129))
+
+	assert.Nil(t, s.shadowRebuild)
+	historyQ.AssertExpectations(t)
+}
@@ -0,0 +1,469 @@
+package ingest
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ingest/processors"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	// StateSnapshotVersion is the version of the state snapshot format written
+	// by ExportState.
+	StateSnapshotVersion = 1
+
+	stateSnapshotManifestFile = "state.json"
+	stateSnapshotEntriesFile  = "entries.xdr.gz"
+)
+
+// stateSnapshotTables are the state tables holding the ledger entries of a
+// state snapshot. Signers and asset stats are derived from the entries.
+var stateSnapshotTables = []string{
+	"accounts",
+	"accounts_data",
+	"claimable_balances",
+	"liquidity_pools",
+	"offers",
+	"trust_lines",
+}
+
+// StateSnapshot is the manifest of a state snapshot. A state snapshot is a
+// directory with the manifest and the ledger entries of the state at Ledger,
+// stored as gzipped framed XDR.
+type StateSnapshot struct {
+	Version                    int              `json:"version"`
+	NetworkPassphrase          string           `json:"network_passphrase"`
+	Ledger                     uint32           `json:"ledger"`
+	LedgerHash                 string           `json:"ledger_hash"`
+	LedgerHeader               string           `json:"ledger_header"`
+	SuccessfulTransactionCount int32            `json:"successful_transaction_count"`
+	FailedTransactionCount     int32            `json:"failed_transaction_count"`
+	OperationCount             int32            `json:"operation_count"`
+	TxSetOperationCount        int32            `json:"tx_set_operation_count"`
+	Entries                    map[string]int64 `json:"entries"`
+	// EntriesHash is the hex encoded SHA256 hash of the uncompressed entries.
+	EntriesHash string `json:"entries_hash"`
+}
+
+// totalEntries returns the number of ledger entries in the snapshot.
+func (s StateSnapshot) totalEntries() int64 {
+	var total int64
+	for _, count := range s.Entries {
+		total += count
+	}
+	return total
+}
+
+// stateSnapshotWriter writes ledger entries as framed XDR and keeps track of
+// their hash and number.
+type stateSnapshotWriter struct {
+	w       io.Writer
+	hash    hash.Hash
+	entries map[string]int64
+}
+
+func newStateSnapshotWriter(w io.Writer) *stateSnapshotWriter {
+	h := sha256.New()
+	return &stateSnapshotWriter{
+		w:       io.MultiWriter(w, h),
+		hash:    h,
+		entries: map[string]int64{},
+	}
+}
+
+func (w *stateSnapshotWriter) Write(entry xdr.LedgerEntry) error {
+	if err := xdr.MarshalFramed(w.w, entry); err != nil {
+		return errors.Wrap(err, "error writing ledger entry")
+	}
+	w.entries[entry.Data.Type.String()]++
+	return nil
+}
+
+// ExportState writes a snapshot of the state at the last ingested ledger to
+// dir, which is created if it does not exist.
+func ExportState(ctx context.Context, session db.SessionInterface, networkPassphrase, dir string) (StateSnapshot, error) {
+	q := &history.Q{session.Clone()}
+	defer q.Rollback()
+	err := q.BeginTx(&sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "Error starting transaction")
+	}
+
+	ingestVersion, err := q.GetIngestVersion(ctx)
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, getIngestVersionErrMsg)
+	}
+	if ingestVersion != CurrentVersion {
+		return StateSnapshot{}, errors.Errorf(
+			"ingestion version in db (%d) is not the current version (%d), reingest the state first",
+			ingestVersion, CurrentVersion,
+		)
+	}
+	stateInvalid, err := q.GetExpStateInvalid(ctx)
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "Error getting state invalid value")
+	}
+	if stateInvalid {
+		return StateSnapshot{}, errors.New("state is invalid, repair it before exporting it")
+	}
+	sequence, err := q.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, getLastIngestedErrMsg)
+	}
+	if sequence == 0 {
+		return StateSnapshot{}, errors.New("no state has been ingested")
+	}
+
+	var ledger history.Ledger
+	if err = q.LedgerBySequence(ctx, &ledger, int32(sequence)); err != nil {
+		return StateSnapshot{}, errors.Wrapf(err, "could not load ledger %d", sequence)
+	}
+	if !ledger.LedgerHeaderXDR.Valid {
+		return StateSnapshot{}, errors.Errorf("ledger %d has no header", sequence)
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not create snapshot directory")
+	}
+	file, err := os.Create(filepath.Join(dir, stateSnapshotEntriesFile))
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not create entries file")
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	writer := newStateSnapshotWriter(gzipWriter)
+	assetStats := processors.AssetStatSet{}
+	for _, table := range stateSnapshotTables {
+		if err = writeStateTable(ctx, writer, assetStats, q, table); err != nil {
+			return StateSnapshot{}, err
+		}
+	}
+	if err = checkAssetStats(ctx, assetStats, q); err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "asset stats do not match the exported entries")
+	}
+
+	if err = gzipWriter.Close(); err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not write entries file")
+	}
+	if err = file.Close(); err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not write entries file")
+	}
+
+	snapshot := StateSnapshot{
+		Version:           StateSnapshotVersion,
+		NetworkPassphrase: networkPassphrase,
+		Ledger:            sequence,
+		LedgerHash:        ledger.LedgerHash,
+		LedgerHeader:      ledger.LedgerHeaderXDR.String,
+		OperationCount:    ledger.OperationCount,
+		Entries:           writer.entries,
+		EntriesHash:       hex.EncodeToString(writer.hash.Sum(nil)),
+	}
+	if ledger.SuccessfulTransactionCount != nil {
+		snapshot.SuccessfulTransactionCount = *ledger.SuccessfulTransactionCount
+	}
+	if ledger.FailedTransactionCount != nil {
+		snapshot.FailedTransactionCount = *ledger.FailedTransactionCount
+	}
+	if ledger.TxSetOperationCount != nil {
+		snapshot.TxSetOperationCount = *ledger.TxSetOperationCount
+	}
+
+	manifest, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not encode manifest")
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, stateSnapshotManifestFile), manifest, 0644)
+	if err != nil {
+		return StateSnapshot{}, errors.Wrap(err, "could not write manifest")
+	}
+
+	log.WithFields(logpkg.F{
+		"sequence": sequence,
+		"entries":  snapshot.totalEntries(),
+	}).Info("Exported state")
+	return snapshot, nil
+}
+
+// writeStateTable writes the ledger entries of a state table to writer, in
+// batches of verifyBatchSize entries.
+func writeStateTable(
+	ctx context.Context,
+	writer stateEntryWriter,
+	assetStats processors.AssetStatSet,
+	q history.IngestionQ,
+	table string,
+) error {
+	cursor := ""
+	for {
+		keys, err := q.GetStateTableKeys(ctx, table, cursor, verifyBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		cursor = keys[len(keys)-1]
+
+		switch table {
+		case "accounts":
+			err = addAccountsToStateVerifier(ctx, writer, q, keys)
+		case "accounts_data":
+			var data []xdr.LedgerKeyData
+			for _, key := range keys {
+				var ledgerKey xdr.LedgerKey
+				if err = xdr.SafeUnmarshalBase64(key, &ledgerKey); err != nil {
+					return errors.Wrapf(err, "invalid account data key %s", key)
+				}
+				data = append(data, *ledgerKey.Data)
+			}
+			err = addDataToStateVerifier(ctx, writer, q, data)
+		case "claimable_balances":
+			var ids []xdr.ClaimableBalanceId
+			for _, key := range keys {
+				var id xdr.ClaimableBalanceId
+				if err = xdr.SafeUnmarshalHex(key, &id); err != nil {
+					return errors.Wrapf(err, "invalid claimable balance id %s", key)
+				}
+				ids = append(ids, id)
+			}
+			err = addClaimableBalanceToStateVerifier(ctx, writer, assetStats, q, ids)
+		case "liquidity_pools":
+			var ids []xdr.PoolId
+			for _, key := range keys {
+				var id xdr.PoolId
+				raw, decodeErr := hex.DecodeString(key)
+				if decodeErr != nil || len(raw) != len(id) {
+					return errors.Errorf("invalid liquidity pool id %s", key)
+				}
+				copy(id[:], raw)
+				ids = append(ids, id)
+			}
+			err = addLiquidityPoolsToStateVerifier(ctx, writer, assetStats, q, ids)
+		case "offers":
+			var ids []int64
+			for _, key := range keys {
+				id, parseErr := strconv.ParseInt(key, 10, 64)
+				if parseErr != nil {
+					return errors.Wrapf(parseErr, "invalid offer id %s", key)
+				}
+				ids = append(ids, id)
+			}
+			err = addOffersToStateVerifier(ctx, writer, q, ids)
+		case "trust_lines":
+			var trustLines []xdr.LedgerKeyTrustLine
+			for _, key := range keys {
+				var ledgerKey xdr.LedgerKey
+				if err = xdr.SafeUnmarshalBase64(key, &ledgerKey); err != nil {
+					return errors.Wrapf(err, "invalid trust line key %s", key)
+				}
+				trustLines = append(trustLines, *ledgerKey.TrustLine)
+			}
+			err = addTrustLinesToStateVerifier(ctx, writer, assetStats, q, trustLines)
+		default:
+			return errors.Errorf("unexpected state table %s", table)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "could not write %s", table)
+		}
+	}
+}
+
+// stateSnapshotReader is an ingest.ChangeReader returning the ledger entries
+// of a state snapshot as created entries.
+type stateSnapshotReader struct {
+	stream *historyarchive.XdrStream
+	read   int64
+}
+
+func (r *stateSnapshotReader) Read() (ingest.Change, error) {
+	var entry xdr.LedgerEntry
+	if err := r.stream.ReadOne(&entry); err != nil {
+		if err == io.EOF {
+			return ingest.Change{}, io.EOF
+		}
+		return ingest.Change{}, errors.Wrap(err, "error reading ledger entry")
+	}
+	r.read++
+	return ingest.Change{
+		Type: entry.Data.Type,
+		Post: &entry,
+	}, nil
+}
+
+// Close checks the hash of the entries.
+func (r *stateSnapshotReader) Close() error {
+	return r.stream.Close()
+}
+
+// ReadStateSnapshot reads the manifest of the state snapshot in dir.
+func ReadStateSnapshot(dir string) (StateSnapshot, error) {
+	var snapshot StateSnapshot
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, stateSnapshotManifestFile))
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not read manifest")
+	}
+	if err = json.Unmarshal(manifest, &snapshot); err != nil {
+		return snapshot, errors.Wrap(err, "could not decode manifest")
+	}
+	if snapshot.Version != StateSnapshotVersion {
+		return snapshot, errors.Errorf("unsupported state snapshot version %d", snapshot.Version)
+	}
+	return snapshot, nil
+}
+
+// ImportState ingests the state snapshot in dir into an empty database. The
+// ingestion cursor is set to the ledger of the snapshot, so ingestion resumes
+// from the next ledger.
+func ImportState(ctx context.Context, session db.SessionInterface, networkPassphrase, dir string) (StateSnapshot, error) {
+	snapshot, err := ReadStateSnapshot(dir)
+	if err != nil {
+		return snapshot, err
+	}
+	if snapshot.NetworkPassphrase != networkPassphrase {
+		return snapshot, errors.Errorf(
+			"state snapshot network passphrase %q does not match %q",
+			snapshot.NetworkPassphrase, networkPassphrase,
+		)
+	}
+
+	var expectedHash [sha256.Size]byte
+	rawHash, err := hex.DecodeString(snapshot.EntriesHash)
+	if err != nil || len(rawHash) != len(expectedHash) {
+		return snapshot, errors.Errorf("invalid entries hash %s", snapshot.EntriesHash)
+	}
+	copy(expectedHash[:], rawHash)
+
+	var header xdr.LedgerHeader
+	if err = xdr.SafeUnmarshalBase64(snapshot.LedgerHeader, &header); err != nil {
+		return snapshot, errors.Wrap(err, "invalid ledger header")
+	}
+	var ledgerHash xdr.Hash
+	rawLedgerHash, err := hex.DecodeString(snapshot.LedgerHash)
+	if err != nil || len(rawLedgerHash) != len(ledgerHash) {
+		return snapshot, errors.Errorf("invalid ledger hash %s", snapshot.LedgerHash)
+	}
+	copy(ledgerHash[:], rawLedgerHash)
+	rawHeader, err := header.MarshalBinary()
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not marshal ledger header")
+	}
+	if xdr.Hash(sha256.Sum256(rawHeader)) != ledgerHash {
+		return snapshot, errors.Errorf("ledger hash %s does not match the ledger header", snapshot.LedgerHash)
+	}
+	if uint32(header.LedgerSeq) != snapshot.Ledger {
+		return snapshot, errors.Errorf(
+			"ledger header sequence %d does not match the snapshot ledger %d",
+			header.LedgerSeq, snapshot.Ledger,
+		)
+	}
+
+	err = newHistoryPartitions(session).ensure(ctx, snapshot.Ledger, snapshot.Ledger)
+	if err != nil {
+		return snapshot, errors.Wrap(err, "error creating history partitions")
+	}
+
+	q := &history.Q{session.Clone()}
+	defer q.Rollback()
+	if err = q.Begin(); err != nil {
+		return snapshot, errors.Wrap(err, "Error starting a transaction")
+	}
+
+	lastIngestedLedger, err := q.GetLastLedgerIngest(ctx)
+	if err != nil {
+		return snapshot, errors.Wrap(err, getLastIngestedErrMsg)
+	}
+	lastHistoryLedger, err := q.GetLatestHistoryLedger(ctx)
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not get latest history ledger")
+	}
+	accounts, err := q.CountAccounts(ctx)
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not count accounts")
+	}
+	if lastIngestedLedger != 0 || lastHistoryLedger != 0 || accounts != 0 {
+		return snapshot, errors.New("the database is not empty")
+	}
+
+	file, err := os.Open(filepath.Join(dir, stateSnapshotEntriesFile))
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not open entries file")
+	}
+	stream, err := historyarchive.NewXdrGzStream(file)
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not read entries file")
+	}
+	stream.SetExpectedHash(expectedHash)
+	reader := &stateSnapshotReader{stream: stream}
+
+	changeStats := ingest.StatsChangeProcessor{}
+	changeProcessor := buildChangeProcessor(q, &changeStats, historyArchiveSource, snapshot.Ledger)
+	if err = processors.StreamChanges(ctx, changeProcessor, reader); err != nil {
+		reader.Close()
+		return snapshot, errors.Wrap(err, "Error streaming changes from state snapshot")
+	}
+	if err = reader.Close(); err != nil {
+		return snapshot, errors.Wrap(err, "could not verify entries file")
+	}
+	if reader.read != snapshot.totalEntries() {
+		return snapshot, errors.Errorf(
+			"state snapshot has %d entries, expected %d",
+			reader.read, snapshot.totalEntries(),
+		)
+	}
+	if err = changeProcessor.Commit(ctx); err != nil {
+		return snapshot, errors.Wrap(err, "Error commiting changes from processor")
+	}
+
+	_, err = q.InsertLedger(
+		ctx,
+		xdr.LedgerHeaderHistoryEntry{Hash: ledgerHash, Header: header},
+		int(snapshot.SuccessfulTransactionCount),
+		int(snapshot.FailedTransactionCount),
+		int(snapshot.OperationCount),
+		int(snapshot.TxSetOperationCount),
+		CurrentVersion,
+	)
+	if err != nil {
+		return snapshot, errors.Wrap(err, "could not insert ledger")
+	}
+	if err = q.UpdateIngestVersion(ctx, CurrentVersion); err != nil {
+		return snapshot, errors.Wrap(err, "Error updating ingestion version")
+	}
+	if err = q.UpdateLastLedgerIngest(ctx, snapshot.Ledger); err != nil {
+		return snapshot, errors.Wrap(err, updateLastLedgerIngestErrMsg)
+	}
+	if err = q.UpdateExpStateInvalid(ctx, false); err != nil {
+		return snapshot, errors.Wrap(err, updateExpStateInvalidErrMsg)
+	}
+	if err = q.Commit(); err != nil {
+		return snapshot, errors.Wrap(err, commitErrMsg)
+	}
+
+	log.WithFields(logpkg.F{
+		"sequence": snapshot.Ledger,
+		"entries":  reader.read,
+	}).Info("Imported state")
+	return snapshot, nil
+}
@@ -0,0 +1,108 @@
+package ingest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/diamcircle/go/ingest"
+	"github.com/diamcircle/go/randxdr"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/test"
+	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/xdr"
+)
+
+func TestExportImportState(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	session := &db.Session{DB: tt.AuroraDB}
+	q := &history.Q{session}
+	passphrase := "test network"
+
+	checkpointLedger := uint32(63)
+	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, historyArchiveSource, checkpointLedger)
+	gen := randxdr.NewGenerator()
+	var changes []xdr.LedgerEntryChange
+	for i := 0; i < 20; i++ {
+		changes = append(changes,
+			genLiquidityPool(tt, gen),
+			genClaimableBalance(tt, gen),
+			genOffer(tt, gen),
+			genTrustLine(tt, gen),
+			genAccount(tt, gen),
+			genAccountData(tt, gen),
+		)
+	}
+	for _, change := range ingest.GetChangesFromLedgerEntryChanges(changes) {
+		tt.Assert.NoError(changeProcessor.ProcessChange(tt.Ctx, change))
+	}
+	tt.Assert.NoError(changeProcessor.Commit(tt.Ctx))
+
+	header := xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(checkpointLedger)},
+	}
+	rawHeader, err := header.Header.MarshalBinary()
+	tt.Assert.NoError(err)
+	header.Hash = sha256.Sum256(rawHeader)
+	_, err = q.InsertLedger(tt.Ctx, header, 2, 1, 5, 6, CurrentVersion)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.UpdateIngestVersion(tt.Ctx, CurrentVersion))
+	tt.Assert.NoError(q.UpdateLastLedgerIngest(tt.Ctx, checkpointLedger))
+
+	dir, err := ioutil.TempDir("", "aurora-state")
+	tt.Assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	exported, err := ExportState(tt.Ctx, session, passphrase, dir)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(checkpointLedger, exported.Ledger)
+	tt.Assert.Equal(int64(20), exported.Entries[xdr.LedgerEntryTypeAccount.String()])
+	tt.Assert.Equal(int64(120), exported.totalEntries())
+
+	// The snapshot can only be imported into an empty database.
+	_, err = ImportState(tt.Ctx, session, passphrase, dir)
+	tt.Assert.EqualError(err, "the database is not empty")
+
+	_, err = ImportState(tt.Ctx, session, "other network", dir)
+	tt.Assert.EqualError(err, `state snapshot network passphrase "test network" does not match "other network"`)
+
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	// The ledger hash must be the hash of the ledger header.
+	manifestFile := filepath.Join(dir, stateSnapshotManifestFile)
+	manifest, err := ioutil.ReadFile(manifestFile)
+	tt.Assert.NoError(err)
+	tampered := exported
+	tampered.LedgerHash = hex.EncodeToString(make([]byte, sha256.Size))
+	tamperedManifest, err := json.Marshal(tampered)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(ioutil.WriteFile(manifestFile, tamperedManifest, 0644))
+	_, err = ImportState(tt.Ctx, session, passphrase, dir)
+	tt.Assert.EqualError(err, "ledger hash "+tampered.LedgerHash+" does not match the ledger header")
+	tt.Assert.NoError(ioutil.WriteFile(manifestFile, manifest, 0644))
+
+	imported, err := ImportState(tt.Ctx, session, passphrase, dir)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(exported, imported)
+
+	lastLedger, err := q.GetLastLedgerIngestNonBlocking(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(checkpointLedger, lastLedger)
+	lastHistoryLedger, err := q.GetLatestHistoryLedger(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(checkpointLedger, lastHistoryLedger)
+
+	// Exporting the imported state yields the same entries.
+	reexportDir, err := ioutil.TempDir("", "aurora-state")
+	tt.Assert.NoError(err)
+	defer os.RemoveAll(reexportDir)
+	reexported, err := ExportState(tt.Ctx, session, passphrase, reexportDir)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(exported, reexported)
+}
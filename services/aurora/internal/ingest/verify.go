@@ -34,7 +34,7 @@ const stateVerifierExpectedIngestionVersion = 15
 // verifyState is called as a go routine from pipeline post hook every 64
 // ledgers. It checks if the state is correct. If another go routine is already
 // running it exits.
-func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
+func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) (err error) {
 	s.stateVerificationMutex.Lock()
 	if s.stateVerificationRunning {
 		log.Warn("State verification is already running...")
@@ -62,7 +62,7 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 
 	historyQ := s.historyQ.CloneIngestionQ()
 	defer historyQ.Rollback()
-	err := historyQ.BeginTx(&sql.TxOptions{
+	err = historyQ.BeginTx(&sql.TxOptions{
 		Isolation: sql.LevelRepeatableRead,
 		ReadOnly:  true,
 	})
@@ -153,9 +153,18 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 	defer stateReader.Close()
 
 	verifier := verify.NewStateVerifier(stateReader, nil)
+	verifier.CollectMismatches(maxReportedMismatches)
 
-	assetStats := processors.AssetStatSet{}
 	total := int64(0)
+	defer func() {
+		// Valid and invalid states are reported, errors preventing the
+		// verification are not.
+		if _, ok := errors.Cause(err).(ingest.StateError); err == nil || ok {
+			s.storeStateVerificationReport(ledgerSequence, total, verifier, err)
+		}
+	}()
+
+	assetStats := processors.AssetStatSet{}
 	for {
 		var keys []xdr.LedgerKey
 		keys, err = verifier.GetLedgerKeys(verifyBatchSize)
@@ -167,65 +176,9 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 			break
 		}
 
-		accounts := make([]string, 0, verifyBatchSize)
-		data := make([]xdr.LedgerKeyData, 0, verifyBatchSize)
-		offers := make([]int64, 0, verifyBatchSize)
-		trustLines := make([]xdr.LedgerKeyTrustLine, 0, verifyBatchSize)
-		cBalances := make([]xdr.ClaimableBalanceId, 0, verifyBatchSize)
-		lPools := make([]xdr.PoolId, 0, verifyBatchSize)
-		for _, key := range keys {
-			switch key.Type {
-			case xdr.LedgerEntryTypeAccount:
-				accounts = append(accounts, key.Account.AccountId.Address())
-				totalByType["accounts"]++
-			case xdr.LedgerEntryTypeData:
-				data = append(data, *key.Data)
-				totalByType["data"]++
-			case xdr.LedgerEntryTypeOffer:
-				offers = append(offers, int64(key.Offer.OfferId))
-				totalByType["offers"]++
-			case xdr.LedgerEntryTypeTrustline:
-				trustLines = append(trustLines, *key.TrustLine)
-				totalByType["trust_lines"]++
-			case xdr.LedgerEntryTypeClaimableBalance:
-				cBalances = append(cBalances, key.ClaimableBalance.BalanceId)
-				totalByType["claimable_balances"]++
-			case xdr.LedgerEntryTypeLiquidityPool:
-				lPools = append(lPools, key.LiquidityPool.LiquidityPoolId)
-				totalByType["liquidity_pools"]++
-			default:
-				return errors.New("GetLedgerKeys return unexpected type")
-			}
-		}
-
-		err = addAccountsToStateVerifier(s.ctx, verifier, historyQ, accounts)
-		if err != nil {
-			return errors.Wrap(err, "addAccountsToStateVerifier failed")
-		}
-
-		err = addDataToStateVerifier(s.ctx, verifier, historyQ, data)
-		if err != nil {
-			return errors.Wrap(err, "addDataToStateVerifier failed")
-		}
-
-		err = addOffersToStateVerifier(s.ctx, verifier, historyQ, offers)
-		if err != nil {
-			return errors.Wrap(err, "addOffersToStateVerifier failed")
-		}
-
-		err = addTrustLinesToStateVerifier(s.ctx, verifier, assetStats, historyQ, trustLines)
-		if err != nil {
-			return errors.Wrap(err, "addTrustLinesToStateVerifier failed")
-		}
-
-		err = addClaimableBalanceToStateVerifier(s.ctx, verifier, assetStats, historyQ, cBalances)
-		if err != nil {
-			return errors.Wrap(err, "addClaimableBalanceToStateVerifier failed")
-		}
-
-		err = addLiquidityPoolsToStateVerifier(s.ctx, verifier, assetStats, historyQ, lPools)
+		err = addLedgerKeysToStateVerifier(s.ctx, verifier, assetStats, historyQ, keys, totalByType)
 		if err != nil {
-			return errors.Wrap(err, "addLiquidityPoolsToStateVerifier failed")
+			return err
 		}
 
 		total += int64(len(keys))
@@ -264,6 +217,25 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 		return errors.Wrap(err, "Error running historyQ.CountLiquidityPools")
 	}
 
+	// The entries of the state tables missing from the checkpoint state are
+	// reported as mismatches, for the types with more entries in the state
+	// tables than entries found in the checkpoint state.
+	extraTypes := verifier.ExtraEntryTypes(map[xdr.LedgerEntryType]int{
+		xdr.LedgerEntryTypeAccount:          countAccounts,
+		xdr.LedgerEntryTypeData:             countData,
+		xdr.LedgerEntryTypeOffer:            countOffers,
+		xdr.LedgerEntryTypeTrustline:        countTrustLines,
+		xdr.LedgerEntryTypeClaimableBalance: countClaimableBalances,
+		xdr.LedgerEntryTypeLiquidityPool:    countLiquidityPools,
+	})
+	for _, typ := range extraTypes {
+		localLog.WithField("type", typ.String()).Info("Looking for extra entries")
+		err = writeStateTable(s.ctx, extraEntryWriter{verifier}, processors.AssetStatSet{}, historyQ, ledgerEntryStateTables[typ])
+		if err != nil {
+			return errors.Wrap(err, "could not look for extra entries")
+		}
+	}
+
 	err = verifier.Verify(countAccounts + countData + countOffers + countTrustLines + countClaimableBalances + countLiquidityPools)
 	if err != nil {
 		return errors.Wrap(err, "verifier.Verify failed")
@@ -279,6 +251,113 @@ func (s *system) verifyState(verifyAgainstLatestCheckpoint bool) error {
 	return nil
 }
 
+// stateEntryWriter receives the ledger entries loaded from the state tables.
+// It is implemented by verify.StateVerifier.
+type stateEntryWriter interface {
+	Write(entry xdr.LedgerEntry) error
+}
+
+// extraEntryWriter passes the ledger entries loaded from the state tables to
+// the WriteExtra method of a verifier.
+type extraEntryWriter struct {
+	verifier *verify.StateVerifier
+}
+
+func (w extraEntryWriter) Write(entry xdr.LedgerEntry) error {
+	return w.verifier.WriteExtra(entry)
+}
+
+// ledgerEntryStateTables maps the ledger entry types to the state tables
+// storing them.
+var ledgerEntryStateTables = map[xdr.LedgerEntryType]string{
+	xdr.LedgerEntryTypeAccount:          "accounts",
+	xdr.LedgerEntryTypeData:             "accounts_data",
+	xdr.LedgerEntryTypeOffer:            "offers",
+	xdr.LedgerEntryTypeTrustline:        "trust_lines",
+	xdr.LedgerEntryTypeClaimableBalance: "claimable_balances",
+	xdr.LedgerEntryTypeLiquidityPool:    "liquidity_pools",
+}
+
+// addLedgerKeysToStateVerifier loads the entries with the given ledger keys
+// from the state tables and writes them to verifier. If totalByType is not
+// nil, it is incremented with the number of keys of each type.
+func addLedgerKeysToStateVerifier(
+	ctx context.Context,
+	verifier stateEntryWriter,
+	assetStats processors.AssetStatSet,
+	q history.IngestionQ,
+	keys []xdr.LedgerKey,
+	totalByType map[string]int64,
+) error {
+	accounts := make([]string, 0, len(keys))
+	data := make([]xdr.LedgerKeyData, 0, len(keys))
+	offers := make([]int64, 0, len(keys))
+	trustLines := make([]xdr.LedgerKeyTrustLine, 0, len(keys))
+	cBalances := make([]xdr.ClaimableBalanceId, 0, len(keys))
+	lPools := make([]xdr.PoolId, 0, len(keys))
+	counts := map[string]int64{}
+	for _, key := range keys {
+		switch key.Type {
+		case xdr.LedgerEntryTypeAccount:
+			accounts = append(accounts, key.Account.AccountId.Address())
+			counts["accounts"]++
+		case xdr.LedgerEntryTypeData:
+			data = append(data, *key.Data)
+			counts["data"]++
+		case xdr.LedgerEntryTypeOffer:
+			offers = append(offers, int64(key.Offer.OfferId))
+			counts["offers"]++
+		case xdr.LedgerEntryTypeTrustline:
+			trustLines = append(trustLines, *key.TrustLine)
+			counts["trust_lines"]++
+		case xdr.LedgerEntryTypeClaimableBalance:
+			cBalances = append(cBalances, key.ClaimableBalance.BalanceId)
+			counts["claimable_balances"]++
+		case xdr.LedgerEntryTypeLiquidityPool:
+			lPools = append(lPools, key.LiquidityPool.LiquidityPoolId)
+			counts["liquidity_pools"]++
+		default:
+			return errors.New("GetLedgerKeys return unexpected type")
+		}
+	}
+	if totalByType != nil {
+		for typ, count := range counts {
+			totalByType[typ] += count
+		}
+	}
+
+	err := addAccountsToStateVerifier(ctx, verifier, q, accounts)
+	if err != nil {
+		return errors.Wrap(err, "addAccountsToStateVerifier failed")
+	}
+
+	err = addDataToStateVerifier(ctx, verifier, q, data)
+	if err != nil {
+		return errors.Wrap(err, "addDataToStateVerifier failed")
+	}
+
+	err = addOffersToStateVerifier(ctx, verifier, q, offers)
+	if err != nil {
+		return errors.Wrap(err, "addOffersToStateVerifier failed")
+	}
+
+	err = addTrustLinesToStateVerifier(ctx, verifier, assetStats, q, trustLines)
+	if err != nil {
+		return errors.Wrap(err, "addTrustLinesToStateVerifier failed")
+	}
+
+	err = addClaimableBalanceToStateVerifier(ctx, verifier, assetStats, q, cBalances)
+	if err != nil {
+		return errors.Wrap(err, "addClaimableBalanceToStateVerifier failed")
+	}
+
+	err = addLiquidityPoolsToStateVerifier(ctx, verifier, assetStats, q, lPools)
+	if err != nil {
+		return errors.Wrap(err, "addLiquidityPoolsToStateVerifier failed")
+	}
+	return nil
+}
+
 func checkAssetStats(ctx context.Context, set processors.AssetStatSet, q history.IngestionQ) error {
 	page := db2.PageQuery{
 		Order: "asc",
@@ -329,7 +408,7 @@ func checkAssetStats(ctx context.Context, set processors.AssetStatSet, q history
 	return nil
 }
 
-func addAccountsToStateVerifier(ctx context.Context, verifier *verify.StateVerifier, q history.IngestionQ, ids []string) error {
+func addAccountsToStateVerifier(ctx context.Context, verifier stateEntryWriter, q history.IngestionQ, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -445,7 +524,7 @@ func addAccountsToStateVerifier(ctx context.Context, verifier *verify.StateVerif
 	return nil
 }
 
-func addDataToStateVerifier(ctx context.Context, verifier *verify.StateVerifier, q history.IngestionQ, lkeys []xdr.LedgerKeyData) error {
+func addDataToStateVerifier(ctx context.Context, verifier stateEntryWriter, q history.IngestionQ, lkeys []xdr.LedgerKeyData) error {
 	if len(lkeys) == 0 {
 		return nil
 	}
@@ -485,7 +564,7 @@ func addDataToStateVerifier(ctx context.Context, verifier *verify.StateVerifier,
 
 func addOffersToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier stateEntryWriter,
 	q history.IngestionQ,
 	ids []int64,
 ) error {
@@ -534,7 +613,7 @@ func offerToXDR(row history.Offer) xdr.OfferEntry {
 
 func addTrustLinesToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier stateEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	keys []xdr.LedgerKeyTrustLine,
@@ -636,7 +715,7 @@ func trustLineToXDR(row history.TrustLine) (xdr.LedgerEntry, error) {
 
 func addClaimableBalanceToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier stateEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	ids []xdr.ClaimableBalanceId,
@@ -717,7 +796,7 @@ func addClaimableBalanceToStateVerifier(
 
 func addLiquidityPoolsToStateVerifier(
 	ctx context.Context,
-	verifier *verify.StateVerifier,
+	verifier stateEntryWriter,
 	assetStats processors.AssetStatSet,
 	q history.IngestionQ,
 	ids []xdr.PoolId,
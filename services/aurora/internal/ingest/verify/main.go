@@ -5,7 +5,9 @@ package verify
 import (
 	"bytes"
 	"encoding/base64"
+	"hash/fnv"
 	"io"
+	"sort"
 
 	"github.com/diamcircle/go/ingest"
 	"github.com/diamcircle/go/support/errors"
@@ -20,6 +22,19 @@ import (
 // that will be used for equality check.
 type TransformLedgerEntryFunction func(xdr.LedgerEntry) (ignore bool, newEntry xdr.LedgerEntry)
 
+// Mismatch is a ledger entry whose version in the application storage does
+// not match the one in the checkpoint state.
+type Mismatch struct {
+	Key xdr.LedgerKey
+	// Expected is the entry in the checkpoint state, transformed by the
+	// transform function. It is nil if the entry is missing from the
+	// checkpoint state.
+	Expected *xdr.LedgerEntry
+	// Actual is the entry in the application storage. It is nil if the entry
+	// is missing from the application storage.
+	Actual *xdr.LedgerEntry
+}
+
 // StateVerifier verifies if ledger entries provided by Add method are the same
 // as in the checkpoint ledger entries provided by CheckpointChangeReader.
 // The algorithm works in the following way:
@@ -46,6 +61,22 @@ type StateVerifier struct {
 
 	currentEntries map[string]xdr.LedgerEntry
 	encodingBuffer *xdr.EncodingBuffer
+
+	// collectMismatches is true if mismatches are recorded instead of being
+	// returned as errors. See CollectMismatches.
+	collectMismatches bool
+	maxMismatches     int
+	mismatches        []Mismatch
+	mismatchCount     int
+	missingEntries    int
+	// storedByType counts, by type, the entries read from the checkpoint
+	// state which were not recorded as missing from the storage.
+	storedByType map[xdr.LedgerEntryType]int
+	// readKeys holds, by type, the hashes of the keys read from the
+	// checkpoint state, to find the extra entries of the storage. See
+	// ExtraEntryTypes and WriteExtra.
+	readKeys       map[xdr.LedgerEntryType][]uint64
+	readKeysSorted bool
 }
 
 func NewStateVerifier(stateReader ingest.ChangeReader, tf TransformLedgerEntryFunction) *StateVerifier {
@@ -56,6 +87,37 @@ func NewStateVerifier(stateReader ingest.ChangeReader, tf TransformLedgerEntryFu
 	}
 }
 
+// CollectMismatches makes the verifier record the entries which do not match
+// the checkpoint state instead of returning a StateError for the first one,
+// so that all of them can be reported. Up to maxMismatches are kept and
+// returned by Mismatches. Verify returns a StateError if any mismatch was
+// found.
+func (v *StateVerifier) CollectMismatches(maxMismatches int) {
+	v.collectMismatches = true
+	v.maxMismatches = maxMismatches
+	v.storedByType = map[xdr.LedgerEntryType]int{}
+	v.readKeys = map[xdr.LedgerEntryType][]uint64{}
+}
+
+// Mismatches returns the mismatches recorded since CollectMismatches was
+// called.
+func (v *StateVerifier) Mismatches() []Mismatch {
+	return v.mismatches
+}
+
+// MismatchCount returns the number of mismatches found since
+// CollectMismatches was called, including the ones which were not kept.
+func (v *StateVerifier) MismatchCount() int {
+	return v.mismatchCount
+}
+
+func (v *StateVerifier) addMismatch(key xdr.LedgerKey, expected, actual *xdr.LedgerEntry) {
+	v.mismatchCount++
+	if len(v.mismatches) < v.maxMismatches {
+		v.mismatches = append(v.mismatches, Mismatch{Key: key, Expected: expected, Actual: actual})
+	}
+}
+
 // GetLedgerKeys returns up to `count` ledger keys from history buckets
 // storing actual entries in cache to compare in Write.
 func (v *StateVerifier) GetLedgerKeys(count int) ([]xdr.LedgerKey, error) {
@@ -95,6 +157,10 @@ func (v *StateVerifier) GetLedgerKeys(count int) ([]xdr.LedgerKey, error) {
 		keys = append(keys, ledgerKey)
 		entry.Normalize()
 		v.currentEntries[string(key)] = entry
+		if v.collectMismatches {
+			v.storedByType[ledgerKey.Type]++
+			v.readKeys[ledgerKey.Type] = append(v.readKeys[ledgerKey.Type], hashKey(key))
+		}
 
 		count--
 		v.readEntries++
@@ -123,6 +189,10 @@ func (v *StateVerifier) Write(entry xdr.LedgerEntry) error {
 
 	expectedEntry, exist := v.currentEntries[string(key)]
 	if !exist {
+		if v.collectMismatches {
+			v.addMismatch(actualEntry.LedgerKey(), nil, actualEntry)
+			return nil
+		}
 		return ingest.NewStateError(errors.Errorf(
 			"Cannot find entry in currentEntries map: %s (key = %s)",
 			base64.StdEncoding.EncodeToString(actualEntryMarshaled),
@@ -156,6 +226,10 @@ func (v *StateVerifier) Write(entry xdr.LedgerEntry) error {
 	}
 
 	if !bytes.Equal(actualEntryMarshaled, expectedEntryMarshaled) {
+		if v.collectMismatches {
+			v.addMismatch(actualEntry.LedgerKey(), &expectedEntry, actualEntry)
+			return nil
+		}
 		return ingest.NewStateError(errors.Errorf(
 			"Entry does not match the fetched entry. Expected: %s (pretransform = %s), actual: %s",
 			base64.StdEncoding.EncodeToString(expectedEntryMarshaled),
@@ -185,18 +259,94 @@ func (v *StateVerifier) Verify(countAll int) error {
 		return errors.New("There are unread entries in state reader. Process all entries before calling Verify.")
 	}
 
-	if v.readEntries != countAll {
+	// Entries recorded as missing from the storage are not counted in countAll.
+	if v.readEntries-v.missingEntries != countAll {
 		return ingest.NewStateError(errors.Errorf(
 			"Number of entries read using GetEntries (%d) does not match number of entries in your storage (%d).",
-			v.readEntries,
+			v.readEntries-v.missingEntries,
 			countAll,
 		))
 	}
 
+	if v.mismatchCount > 0 {
+		return ingest.NewStateError(errors.Errorf(
+			"%d entries do not match the entries in your storage",
+			v.mismatchCount,
+		))
+	}
+
 	return nil
 }
 
+// ExtraEntryTypes returns, once all entries have been read, the types of
+// entries which have more entries in the storage, as given by countByType,
+// than entries read from the checkpoint state and found in the storage. The
+// storage entries of these types can then be passed to WriteExtra to record
+// the ones missing from the checkpoint state as mismatches. It can only be
+// used after CollectMismatches was called.
+func (v *StateVerifier) ExtraEntryTypes(countByType map[xdr.LedgerEntryType]int) []xdr.LedgerEntryType {
+	var types []xdr.LedgerEntryType
+	for typ, count := range countByType {
+		if count > v.storedByType[typ] {
+			types = append(types, typ)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// WriteExtra records entry as a mismatch if it was not read from the
+// checkpoint state. It must be called once all entries have been read, with
+// the entries of the storage of the types returned by ExtraEntryTypes. Keys
+// are compared by a 64-bit hash, so an extra entry is missed only in the
+// unlikely case of a collision.
+func (v *StateVerifier) WriteExtra(entry xdr.LedgerEntry) error {
+	if !v.collectMismatches || !v.readingDone {
+		return errors.New("WriteExtra can only be called once all entries have been read while collecting mismatches")
+	}
+	if !v.readKeysSorted {
+		for _, hashes := range v.readKeys {
+			sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+		}
+		v.readKeysSorted = true
+	}
+
+	actualEntry := entry.Normalize()
+	ledgerKey := actualEntry.LedgerKey()
+	key, err := v.encodingBuffer.UnsafeMarshalBinary(ledgerKey)
+	if err != nil {
+		return errors.Wrap(err, "Error marshaling ledgerKey")
+	}
+	hash := hashKey(key)
+	hashes := v.readKeys[ledgerKey.Type]
+	i := sort.Search(len(hashes), func(i int) bool { return hashes[i] >= hash })
+	if i == len(hashes) || hashes[i] != hash {
+		v.addMismatch(ledgerKey, nil, actualEntry)
+	}
+	return nil
+}
+
+func hashKey(key []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	return h.Sum64()
+}
+
 func (v *StateVerifier) checkUnreadEntries() error {
+	if len(v.currentEntries) > 0 && v.collectMismatches {
+		for _, entry := range v.currentEntries {
+			entry := entry
+			if v.transformFunction != nil {
+				_, entry = v.transformFunction(entry)
+			}
+			v.addMismatch(entry.LedgerKey(), &entry, nil)
+			v.missingEntries++
+			v.storedByType[entry.Data.Type]--
+		}
+		v.currentEntries = nil
+		return nil
+	}
+
 	if len(v.currentEntries) > 0 {
 		var entry xdr.LedgerEntry
 		for _, e := range v.currentEntries {
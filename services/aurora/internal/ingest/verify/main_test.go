@@ -279,6 +279,95 @@ func (s *StateVerifierTestSuite) TestVerifyCountersMatch() {
 	s.Assert().NoError(err)
 }
 
+func (s *StateVerifierTestSuite) TestCollectMismatches() {
+	s.verifier.CollectMismatches(10)
+
+	changedEntry := makeAccountLedgerEntry()
+	missingEntry := makeOfferLedgerEntry()
+	s.mockStateReader.
+		On("Read").
+		Return(ingest.Change{
+			Type: xdr.LedgerEntryTypeAccount,
+			Post: &changedEntry,
+		}, nil).Once()
+	s.mockStateReader.
+		On("Read").
+		Return(ingest.Change{
+			Type: xdr.LedgerEntryTypeOffer,
+			Post: &missingEntry,
+		}, nil).Once()
+	s.mockStateReader.On("Read").Return(ingest.Change{}, io.EOF).Once()
+
+	keys, err := s.verifier.GetLedgerKeys(10)
+	s.Assert().NoError(err)
+	s.Assert().Len(keys, 2)
+
+	actualEntry := makeAccountLedgerEntry()
+	actualEntry.Data.Account.Thresholds = [4]byte{1, 1, 1, 0}
+	actualEntry.Normalize()
+	s.Assert().NoError(s.verifier.Write(actualEntry))
+
+	// Only the changed entry is in the storage.
+	err = s.verifier.Verify(1)
+	s.Assert().Error(err)
+	assertStateError(s.T(), err, true)
+	s.Assert().EqualError(err, "2 entries do not match the entries in your storage")
+
+	mismatches := s.verifier.Mismatches()
+	s.Assert().Len(mismatches, 2)
+	s.Assert().Equal(2, s.verifier.MismatchCount())
+
+	s.Assert().Equal(changedEntry.LedgerKey(), mismatches[0].Key)
+	s.Assert().Equal(changedEntry, *mismatches[0].Expected)
+	s.Assert().Equal(actualEntry, *mismatches[0].Actual)
+
+	s.Assert().Equal(missingEntry.LedgerKey(), mismatches[1].Key)
+	s.Assert().Equal(missingEntry, *mismatches[1].Expected)
+	s.Assert().Nil(mismatches[1].Actual)
+}
+
+func (s *StateVerifierTestSuite) TestWriteExtraEntries() {
+	s.verifier.CollectMismatches(10)
+
+	entry := makeAccountLedgerEntry()
+	s.mockStateReader.
+		On("Read").
+		Return(ingest.Change{
+			Type: xdr.LedgerEntryTypeAccount,
+			Post: &entry,
+		}, nil).Once()
+	s.mockStateReader.On("Read").Return(ingest.Change{}, io.EOF).Once()
+
+	keys, err := s.verifier.GetLedgerKeys(10)
+	s.Assert().NoError(err)
+	s.Assert().Len(keys, 1)
+	s.Assert().NoError(s.verifier.Write(makeAccountLedgerEntry()))
+	keys, err = s.verifier.GetLedgerKeys(10)
+	s.Assert().NoError(err)
+	s.Assert().Len(keys, 0)
+
+	types := s.verifier.ExtraEntryTypes(map[xdr.LedgerEntryType]int{
+		xdr.LedgerEntryTypeAccount: 2,
+		xdr.LedgerEntryTypeOffer:   0,
+	})
+	s.Assert().Equal([]xdr.LedgerEntryType{xdr.LedgerEntryTypeAccount}, types)
+
+	extraEntry := makeAccountLedgerEntry()
+	extraEntry.Data.Account.AccountId = xdr.MustAddress("GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB")
+	s.Assert().NoError(s.verifier.WriteExtra(makeAccountLedgerEntry()))
+	s.Assert().NoError(s.verifier.WriteExtra(extraEntry))
+
+	err = s.verifier.Verify(2)
+	assertStateError(s.T(), err, true)
+	s.Assert().EqualError(err, "Number of entries read using GetEntries (1) does not match number of entries in your storage (2).")
+
+	mismatches := s.verifier.Mismatches()
+	s.Assert().Len(mismatches, 1)
+	s.Assert().Equal(extraEntry.LedgerKey(), mismatches[0].Key)
+	s.Assert().Nil(mismatches[0].Expected)
+	s.Assert().Equal(extraEntry, *mismatches[0].Actual)
+}
+
 func makeAccountLedgerEntry() xdr.LedgerEntry {
 	entry := xdr.LedgerEntry{
 		Data: xdr.LedgerEntryData{
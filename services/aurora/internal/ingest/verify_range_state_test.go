@@ -272,14 +272,20 @@ func (s *VerifyRangeStateTestSuite) TestSuccessWithVerify() {
 	s.historyQ.On("RebuildTradeAggregationBuckets", s.ctx, uint32(100), uint32(110)).Return(nil).Once()
 
 	clonedQ := &mockDBQ{}
-	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Once()
+	s.historyQ.On("CloneIngestionQ").Return(clonedQ).Twice()
 
 	clonedQ.On("BeginTx", mock.AnythingOfType("*sql.TxOptions")).Run(func(args mock.Arguments) {
 		arg := args.Get(0).(*sql.TxOptions)
 		s.Assert().Equal(sql.LevelRepeatableRead, arg.Isolation)
 		s.Assert().True(arg.ReadOnly)
 	}).Return(nil).Once()
-	clonedQ.On("Rollback").Return(nil).Once()
+	clonedQ.On("Rollback").Return(nil).Twice()
+	clonedQ.On("Begin").Return(nil).Once()
+	clonedQ.On("InsertStateVerificationReport", s.ctx, mock.MatchedBy(func(report history.StateVerificationReport) bool {
+		return report.Valid && report.LedgerSequence == 63
+	}), []history.StateVerificationMismatch(nil), stateVerificationReportBatchSize).Return(int64(1), nil).Once()
+	clonedQ.On("DeleteStateVerificationReportsBefore", s.ctx, int64(1-stateVerificationReportsRetained+1)).Return(nil).Once()
+	clonedQ.On("Commit").Return(nil).Once()
 	clonedQ.On("GetLastLedgerIngestNonBlocking", s.ctx).Return(uint32(63), nil).Once()
 	mockChangeReader := &ingest.MockChangeReader{}
 	mockChangeReader.On("Close").Return(nil).Once()
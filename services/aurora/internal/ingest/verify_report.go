@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/guregu/null"
+
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/ingest/verify"
+	"github.com/diamcircle/go/support/errors"
+	logpkg "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	// maxReportedMismatches is the maximum number of mismatching entries
+	// stored in a state verification report.
+	maxReportedMismatches = 10000
+	// stateVerificationReportsRetained is the number of state verification
+	// reports kept in the database.
+	stateVerificationReportsRetained = 1000
+	// stateVerificationReportBatchSize is the batch size used to insert the
+	// mismatches of a state verification report.
+	stateVerificationReportBatchSize = 1000
+)
+
+// storeStateVerificationReport stores the result of the verification of the
+// state at the given ledger. verifyErr is the error returned by the
+// verification, nil if the state is valid.
+func (s *system) storeStateVerificationReport(
+	ledgerSequence uint32, entries int64, verifier *verify.StateVerifier, verifyErr error,
+) {
+	localLog := log.WithFields(logpkg.F{
+		"subservice": "state_verify",
+		"sequence":   ledgerSequence,
+	})
+
+	report, mismatches, err := newStateVerificationReport(ledgerSequence, entries, verifier, verifyErr)
+	if err != nil {
+		localLog.WithError(err).Error("Error building state verification report")
+		return
+	}
+
+	historyQ := s.historyQ.CloneIngestionQ()
+	if err = historyQ.Begin(); err != nil {
+		localLog.WithError(err).Error("Error starting transaction")
+		return
+	}
+	defer historyQ.Rollback()
+
+	id, err := historyQ.InsertStateVerificationReport(s.ctx, report, mismatches, stateVerificationReportBatchSize)
+	if err != nil {
+		localLog.WithError(err).Error("Error storing state verification report")
+		return
+	}
+	if err = historyQ.DeleteStateVerificationReportsBefore(s.ctx, id-stateVerificationReportsRetained+1); err != nil {
+		localLog.WithError(err).Error("Error deleting old state verification reports")
+		return
+	}
+	if err = historyQ.Commit(); err != nil {
+		localLog.WithError(err).Error(commitErrMsg)
+		return
+	}
+
+	if !report.Valid {
+		localLog.WithFields(logpkg.F{
+			"report_id":  id,
+			"mismatches": report.Mismatches,
+		}).Error("Stored state verification report")
+	}
+}
+
+func newStateVerificationReport(
+	ledgerSequence uint32, entries int64, verifier *verify.StateVerifier, verifyErr error,
+) (history.StateVerificationReport, []history.StateVerificationMismatch, error) {
+	report := history.StateVerificationReport{
+		LedgerSequence: ledgerSequence,
+		CreatedAt:      time.Now().UTC(),
+		Valid:          verifyErr == nil,
+		Entries:        entries,
+		Mismatches:     int32(verifier.MismatchCount()),
+	}
+	if verifyErr != nil {
+		report.Error = null.StringFrom(verifyErr.Error())
+	}
+
+	var mismatches []history.StateVerificationMismatch
+	for _, mismatch := range verifier.Mismatches() {
+		row := history.StateVerificationMismatch{
+			EntryType: mismatch.Key.Type,
+			Processor: mismatchProcessor(mismatch),
+		}
+
+		var err error
+		if row.LedgerKey, err = xdr.MarshalBase64(mismatch.Key); err != nil {
+			return report, nil, errors.Wrap(err, "could not marshal ledger key")
+		}
+		if mismatch.Expected != nil {
+			expected, err := xdr.MarshalBase64(mismatch.Expected)
+			if err != nil {
+				return report, nil, errors.Wrap(err, "could not marshal expected entry")
+			}
+			row.ExpectedEntry = null.StringFrom(expected)
+			row.LastModifiedLedger = null.IntFrom(int64(mismatch.Expected.LastModifiedLedgerSeq))
+		}
+		if mismatch.Actual != nil {
+			actual, err := xdr.MarshalBase64(mismatch.Actual)
+			if err != nil {
+				return report, nil, errors.Wrap(err, "could not marshal actual entry")
+			}
+			row.ActualEntry = null.StringFrom(actual)
+			// The entry was last written by aurora in this ledger.
+			row.LastModifiedLedger = null.IntFrom(int64(mismatch.Actual.LastModifiedLedgerSeq))
+		}
+		mismatches = append(mismatches, row)
+	}
+	return report, mismatches, nil
+}
+
+// mismatchProcessor returns the name of the change processor which writes
+// the mismatching entry to the state tables.
+func mismatchProcessor(mismatch verify.Mismatch) string {
+	switch mismatch.Key.Type {
+	case xdr.LedgerEntryTypeAccount:
+		// Account signers are stored in their own table by SignersProcessor.
+		if mismatch.Expected != nil && mismatch.Actual != nil {
+			expected, actual := mismatch.Expected.Data.MustAccount(), mismatch.Actual.Data.MustAccount()
+			if !reflect.DeepEqual(expected.Signers, actual.Signers) ||
+				!reflect.DeepEqual(expected.SignerSponsoringIDs(), actual.SignerSponsoringIDs()) {
+				return "SignersProcessor"
+			}
+		}
+		return "AccountsProcessor"
+	case xdr.LedgerEntryTypeData:
+		return "AccountDataProcessor"
+	case xdr.LedgerEntryTypeOffer:
+		return "OffersProcessor"
+	case xdr.LedgerEntryTypeTrustline:
+		return "TrustLinesProcessor"
+	case xdr.LedgerEntryTypeClaimableBalance:
+		return "ClaimableBalancesChangeProcessor"
+	case xdr.LedgerEntryTypeLiquidityPool:
+		return "LiquidityPoolsChangeProcessor"
+	default:
+		return ""
+	}
+}
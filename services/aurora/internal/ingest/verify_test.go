@@ -10,9 +10,11 @@ import (
 	"github.com/diamcircle/go/historyarchive"
 	"github.com/diamcircle/go/ingest"
 	"github.com/diamcircle/go/randxdr"
+	"github.com/diamcircle/go/services/aurora/internal/db2"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/test"
 	"github.com/diamcircle/go/support/db"
+	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/xdr"
 )
 
@@ -202,3 +204,64 @@ func TestStateVerifier(t *testing.T) {
 	mockChangeReader.AssertExpectations(t)
 	mockHistoryAdapter.AssertExpectations(t)
 }
+
+func TestStateVerifierReportsExtraEntries(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{&db.Session{DB: tt.AuroraDB}}
+
+	checkpointLedger := uint32(63)
+	changeProcessor := buildChangeProcessor(q, &ingest.StatsChangeProcessor{}, ledgerSource, checkpointLedger)
+	mockChangeReader := &ingest.MockChangeReader{}
+
+	gen := randxdr.NewGenerator()
+	// The offer is in the state tables but not in the checkpoint state.
+	changes := ingest.GetChangesFromLedgerEntryChanges([]xdr.LedgerEntryChange{
+		genAccount(tt, gen),
+		genOffer(tt, gen),
+	})
+	for _, change := range changes {
+		tt.Assert.NoError(changeProcessor.ProcessChange(tt.Ctx, change))
+	}
+	tt.Assert.NoError(changeProcessor.Commit(tt.Ctx))
+	q.UpdateLastLedgerIngest(tt.Ctx, checkpointLedger)
+
+	mockChangeReader.On("Read").Return(changes[0], nil).Once()
+	mockChangeReader.On("Read").Return(ingest.Change{}, io.EOF).Twice()
+	mockChangeReader.On("Close").Return(nil).Once()
+
+	mockHistoryAdapter := &mockHistoryArchiveAdapter{}
+	mockHistoryAdapter.On("GetState", tt.Ctx, uint32(checkpointLedger)).Return(mockChangeReader, nil).Once()
+
+	sys := &system{
+		ctx:               tt.Ctx,
+		historyQ:          q,
+		historyAdapter:    mockHistoryAdapter,
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+	sys.initMetrics()
+
+	err := sys.verifyState(false)
+	tt.Assert.Error(err)
+	_, ok := errors.Cause(err).(ingest.StateError)
+	tt.Assert.True(ok)
+
+	reports, err := q.StateVerificationReports(tt.Ctx, db2.PageQuery{Order: db2.OrderDescending, Limit: 1})
+	tt.Assert.NoError(err)
+	tt.Assert.Len(reports, 1)
+	tt.Assert.False(reports[0].Valid)
+	tt.Assert.Equal(int32(1), reports[0].Mismatches)
+
+	mismatches, err := q.StateVerificationMismatches(tt.Ctx, reports[0].ID)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(mismatches, 1)
+	offerKey, err := xdr.MarshalBase64(changes[1].Post.LedgerKey())
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(offerKey, mismatches[0].LedgerKey)
+	tt.Assert.Equal(xdr.LedgerEntryTypeOffer, mismatches[0].EntryType)
+	tt.Assert.False(mismatches[0].ExpectedEntry.Valid)
+	tt.Assert.True(mismatches[0].ActualEntry.Valid)
+	mockChangeReader.AssertExpectations(t)
+	mockHistoryAdapter.AssertExpectations(t)
+}
@@ -2,13 +2,18 @@ package aurora
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"runtime"
 
 	"github.com/getsentry/raven-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
+	"github.com/diamcircle/go/services/aurora/internal/db2/embedded"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/services/aurora/internal/db2/replicas"
 	"github.com/diamcircle/go/services/aurora/internal/ingest"
 	"github.com/diamcircle/go/services/aurora/internal/simplepath"
 	"github.com/diamcircle/go/services/aurora/internal/txsub"
@@ -42,7 +47,7 @@ func mustInitAuroraDB(app *App) {
 		}
 	}
 
-	if app.config.RoDatabaseURL == "" {
+	if len(app.config.RoDatabaseURLs) == 0 {
 		app.historyQ = &history.Q{mustNewDBSession(
 			db.HistorySubservice,
 			app.config.DatabaseURL,
@@ -52,13 +57,26 @@ func mustInitAuroraDB(app *App) {
 		)}
 	} else {
 		// If RO set, use it for all DB queries
-		app.historyQ = &history.Q{mustNewDBSession(
-			db.HistorySubservice,
-			app.config.RoDatabaseURL,
-			maxIdle,
-			maxOpen,
-			app.prometheusRegistry,
-		)}
+		var readReplicas []replicas.Replica
+		for i, databaseURL := range app.config.RoDatabaseURLs {
+			// The first replica keeps the metrics of the single read replica
+			// supported before.
+			subservice := db.HistorySubservice
+			if i > 0 {
+				subservice = db.Subservice(fmt.Sprintf("history_replica_%d", i))
+			}
+			readReplicas = append(readReplicas, replicas.Replica{
+				Name: fmt.Sprintf("replica_%d", i),
+				Session: mustNewDBSession(
+					subservice,
+					databaseURL,
+					maxIdle,
+					maxOpen,
+					app.prometheusRegistry,
+				),
+			})
+		}
+		app.historyQ = &history.Q{readReplicas[0].Session}
 
 		app.primaryHistoryQ = &history.Q{mustNewDBSession(
 			db.HistoryPrimarySubservice,
@@ -67,6 +85,7 @@ func mustInitAuroraDB(app *App) {
 			maxOpen,
 			app.prometheusRegistry,
 		)}
+		app.replicas = replicas.NewPool(app.primaryHistoryQ.SessionInterface, readReplicas)
 	}
 }
 
@@ -97,6 +116,10 @@ func initIngester(app *App) {
 		EnableCaptiveCore:            app.config.EnableCaptiveCoreIngestion,
 		DisableStateVerification:     app.config.IngestDisableStateVerification,
 		EnableExtendedLogLedgerStats: app.config.IngestEnableExtendedLogLedgerStats,
+		LeaderElection:               app.config.IngestLeaderElection,
+		LeaderNodeID:                 app.config.IngestLeaderNodeID,
+		CaptiveCoreMaxRestarts:       int(app.config.CaptiveCoreMaxRestarts),
+		CaptiveCoreLogLines:          int(app.config.CaptiveCoreLogLines),
 	})
 
 	if err != nil {
@@ -104,16 +127,67 @@ func initIngester(app *App) {
 	}
 }
 
+func initEmbeddedIngester(app *App) {
+	store, err := embedded.Open(app.config.EmbeddedDataDir)
+	if err != nil {
+		log.Fatalf("cannot open embedded store: %v", err)
+	}
+	app.embeddedStore = store
+
+	app.embeddedIngester, err = ingest.NewEmbeddedSystem(ingest.Config{
+		NetworkPassphrase:      app.config.NetworkPassphrase,
+		HistoryArchiveURL:      app.config.HistoryArchiveURLs[0],
+		CheckpointFrequency:    app.config.CheckpointFrequency,
+		CaptiveCoreBinaryPath:  app.config.CaptiveCoreBinaryPath,
+		CaptiveCoreStoragePath: app.config.CaptiveCoreStoragePath,
+		CaptiveCoreToml:        app.config.CaptiveCoreToml,
+		RemoteCaptiveCoreURL:   app.config.RemoteCaptiveCoreURL,
+		EnableCaptiveCore:      app.config.EnableCaptiveCoreIngestion,
+	}, store)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 func initPathFinder(app *App) {
+	if app.config.PathFinderURL != nil {
+		app.paths = simplepath.NewRemoteFinder(app.config.PathFinderURL, &http.Client{
+			Timeout: app.config.ConnectionTimeout,
+		})
+		return
+	}
+
 	orderBookGraph := orderbook.NewOrderBookGraph()
 	app.orderBookStream = ingest.NewOrderBookStream(
 		&history.Q{app.AuroraSession()},
 		orderBookGraph,
 	)
+	if app.config.OrderBookSnapshotPath != "" {
+		app.orderBookStream.EnableSnapshots(
+			app.config.OrderBookSnapshotPath,
+			app.config.OrderBookSnapshotInterval,
+		)
+	}
 
 	app.paths = simplepath.NewInMemoryFinder(orderBookGraph, !app.config.DisablePoolPathFinding)
 }
 
+func initColdStorage(app *App) {
+	if app.config.HistoryColdStorageURL == "" {
+		return
+	}
+
+	store, err := coldstorage.Connect(
+		app.config.HistoryColdStorageURL,
+		historyarchive.ConnectOptions{Context: app.ctx},
+		coldstorage.DefaultPartitionSize,
+	)
+	if err != nil {
+		log.Fatalf("cannot connect to history cold storage: %v", err)
+	}
+	app.coldStorage = store
+}
+
 // initSentry initialized the default sentry client with the configured DSN
 func initSentry(app *App) {
 	if app.config.SentryDSN == "" {
@@ -165,9 +239,15 @@ func initDbMetrics(app *App) {
 
 	app.ledgerState.RegisterMetrics(app.prometheusRegistry)
 
+	if app.replicas != nil {
+		app.replicas.RegisterMetrics(app.prometheusRegistry)
+	}
+
 	app.coreState.RegisterMetrics(app.prometheusRegistry)
 
-	app.prometheusRegistry.MustRegister(app.orderBookStream.LatestLedgerGauge)
+	if app.orderBookStream != nil {
+		app.prometheusRegistry.MustRegister(app.orderBookStream.LatestLedgerGauge)
+	}
 }
 
 // initGoMetrics registers the Go collector provided by prometheus package which
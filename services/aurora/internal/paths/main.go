@@ -27,6 +27,32 @@ type Path struct {
 	DestinationAmount xdr.Int64
 }
 
+// SplitQuery is a query for a payment divided across several paths. Exactly
+// one of SourceAmount and DestinationAmount is set: a SourceAmount plans a
+// strict send payment and a DestinationAmount a strict receive payment.
+type SplitQuery struct {
+	SourceAsset       xdr.Asset
+	SourceAmount      xdr.Int64
+	DestinationAsset  xdr.Asset
+	DestinationAmount xdr.Int64
+	// SourceAccount is optional, offers from this account are ignored in
+	// strict receive plans
+	SourceAccount *xdr.AccountId
+}
+
+// StrictSend returns true if the query is for a strict send payment
+func (q SplitQuery) StrictSend() bool {
+	return q.SourceAmount > 0
+}
+
+// SplitPlan is the result returned by a split path finder. Each path is meant
+// to be submitted as a path payment operation, in order, in one transaction.
+type SplitPlan struct {
+	Paths             []Path
+	SourceAmount      xdr.Int64
+	DestinationAmount xdr.Int64
+}
+
 // Finder finds paths.
 type Finder interface {
 	// Find returns a list of payment paths and the most recent ledger
@@ -44,4 +70,9 @@ type Finder interface {
 		destinationAssets []xdr.Asset,
 		maxLength uint,
 	) ([]Path, uint32, error)
+	// FindSplitPaths returns a plan dividing the payment described by the
+	// SplitQuery across several payment paths of a maximum length
+	// `maxLength`, and the most recent ledger. An empty plan is returned if
+	// the payment cannot be made in full.
+	FindSplitPaths(ctx context.Context, q SplitQuery, maxLength uint) (SplitPlan, uint32, error)
 }
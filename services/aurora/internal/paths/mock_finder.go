@@ -31,3 +31,9 @@ func (m *MockFinder) FindFixedPaths(
 
 	return args.Get(0).([]Path), args.Get(1).(uint32), args.Error(2)
 }
+
+func (m *MockFinder) FindSplitPaths(ctx context.Context, q SplitQuery, maxLength uint) (SplitPlan, uint32, error) {
+	args := m.Called(ctx, q, maxLength)
+
+	return args.Get(0).(SplitPlan), args.Get(1).(uint32), args.Error(2)
+}
@@ -7,6 +7,7 @@ package reap
 import (
 	"context"
 
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
 	"github.com/diamcircle/go/support/db"
@@ -16,9 +17,12 @@ import (
 type System struct {
 	HistoryQ       *history.Q
 	RetentionCount uint
-	ledgerState    *ledger.State
-	ctx            context.Context
-	cancel         context.CancelFunc
+	// ColdStorage, if set, receives a copy of the history before it is
+	// deleted.
+	ColdStorage *coldstorage.Store
+	ledgerState *ledger.State
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 // New initializes the reaper, causing it to begin polling the diamcircle-core
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	herrors "github.com/diamcircle/go/services/aurora/internal/errors"
 	"github.com/diamcircle/go/services/aurora/internal/toid"
 	"github.com/diamcircle/go/support/errors"
@@ -22,11 +23,23 @@ func (r *System) DeleteUnretainedHistory(ctx context.Context) error {
 		targetElder = (latest.HistoryLatest - int32(r.RetentionCount)) + 1
 	)
 
+	partitions, err := r.HistoryQ.GetHistoryPartitions(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Error loading history partitions")
+	}
+	// Partitions are dropped as a whole, the ledgers of a partition are
+	// retained until all of them are expired.
+	for _, partition := range partitions {
+		if !partition.Legacy() && partition.Contains(uint32(targetElder)) {
+			targetElder = int32(partition.StartLedger)
+		}
+	}
+
 	if targetElder < latest.HistoryElder {
 		return nil
 	}
 
-	err := r.clearBefore(ctx, latest.HistoryElder, targetElder)
+	err = r.clearBefore(ctx, latest.HistoryElder, targetElder, partitions)
 	if err != nil {
 		return err
 	}
@@ -82,37 +95,104 @@ func (r *System) runOnce(ctx context.Context) {
 var batchSize = int32(100_000)
 var sleep = 1 * time.Second
 
-func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32) error {
-	for batchEndSeq := endSeq - 1; batchEndSeq >= startSeq; batchEndSeq -= batchSize {
-		batchStartSeq := batchEndSeq - batchSize
-		if batchStartSeq < startSeq {
-			batchStartSeq = startSeq
+// reapSegment is a range of ledgers cleared in a single transaction. The
+// segments of history partitions are cleared by dropping the partition.
+type reapSegment struct {
+	startSeq  int32
+	endSeq    int32
+	partition *history.HistoryPartition
+}
+
+// reapSegments splits the ledgers from startSeq to endSeq (inclusive) into the
+// segments to clear: the partitions only holding ledgers of the range and
+// batches of batchSize ledgers in between.
+func reapSegments(partitions []history.HistoryPartition, startSeq, endSeq int32) []reapSegment {
+	var segments []reapSegment
+	for seq := startSeq; seq <= endSeq; {
+		gapEndSeq := endSeq
+		var dropped *history.HistoryPartition
+		for i := range partitions {
+			partition := partitions[i]
+			if partition.Legacy() {
+				continue
+			}
+			if partition.Contains(uint32(seq)) && int32(partition.EndLedger)-1 <= endSeq {
+				dropped = &partition
+				break
+			}
+			if int32(partition.StartLedger) > seq && int32(partition.StartLedger)-1 < gapEndSeq {
+				gapEndSeq = int32(partition.StartLedger) - 1
+			}
 		}
-		log.WithField("start_ledger", batchStartSeq).WithField("end_ledger", batchEndSeq).Info("reaper: clearing")
 
-		batchStart, batchEnd, err := toid.LedgerRangeInclusive(batchStartSeq, batchEndSeq)
-		if err != nil {
-			return err
+		if dropped != nil {
+			segmentEndSeq := int32(dropped.EndLedger) - 1
+			segments = append(segments, reapSegment{startSeq: seq, endSeq: segmentEndSeq, partition: dropped})
+			seq = segmentEndSeq + 1
+			continue
 		}
 
-		err = r.HistoryQ.Begin()
-		if err != nil {
-			return errors.Wrap(err, "Error in begin")
+		for ; seq <= gapEndSeq; seq += batchSize {
+			batchEndSeq := seq + batchSize - 1
+			if batchEndSeq > gapEndSeq {
+				batchEndSeq = gapEndSeq
+			}
+			segments = append(segments, reapSegment{startSeq: seq, endSeq: batchEndSeq})
+		}
+		seq = gapEndSeq + 1
+	}
+	return segments
+}
+
+func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32, partitions []history.HistoryPartition) error {
+	segments := reapSegments(partitions, startSeq, endSeq-1)
+	for i := len(segments) - 1; i >= 0; i-- {
+		if err := r.clearSegment(ctx, segments[i]); err != nil {
+			return err
 		}
-		defer r.HistoryQ.Rollback()
+		time.Sleep(sleep)
+	}
 
-		err = r.HistoryQ.DeleteRangeAll(ctx, batchStart, batchEnd)
+	return nil
+}
+
+func (r *System) clearSegment(ctx context.Context, segment reapSegment) error {
+	log.WithField("start_ledger", segment.startSeq).WithField("end_ledger", segment.endSeq).Info("reaper: clearing")
+
+	start, end, err := toid.LedgerRangeInclusive(segment.startSeq, segment.endSeq)
+	if err != nil {
+		return err
+	}
+
+	err = r.HistoryQ.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Error in begin")
+	}
+	defer r.HistoryQ.Rollback()
+
+	if r.ColdStorage != nil {
+		err = r.ColdStorage.Export(ctx, r.HistoryQ, segment.startSeq, segment.endSeq)
 		if err != nil {
-			return errors.Wrap(err, "Error in DeleteRangeAll")
+			return errors.Wrap(err, "Error in cold storage export")
 		}
+	}
 
-		err = r.HistoryQ.Commit()
+	if segment.partition != nil {
+		err = r.HistoryQ.DropHistoryPartition(ctx, *segment.partition)
 		if err != nil {
-			return errors.Wrap(err, "Error in commit")
+			return errors.Wrap(err, "Error in DropHistoryPartition")
 		}
+	}
 
-		time.Sleep(sleep)
+	// The rows of the tables which are not partitioned are deleted.
+	err = r.HistoryQ.DeleteRangeAll(ctx, start, end)
+	if err != nil {
+		return errors.Wrap(err, "Error in DeleteRangeAll")
 	}
 
+	err = r.HistoryQ.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Error in commit")
+	}
 	return nil
 }
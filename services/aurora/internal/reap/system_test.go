@@ -1,8 +1,12 @@
 package reap
 
 import (
+	"database/sql"
 	"testing"
 
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/services/aurora/internal/coldstorage"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
 	"github.com/diamcircle/go/services/aurora/internal/ledger"
 	"github.com/diamcircle/go/services/aurora/internal/test"
 )
@@ -52,3 +56,74 @@ func TestDeleteUnretainedHistory(t *testing.T) {
 		tt.Assert.Equal(1, cur)
 	}
 }
+
+func TestDeleteUnretainedHistoryToColdStorage(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	ledgerState := &ledger.State{}
+	ledgerState.SetStatus(tt.Scenario("kahuna"))
+
+	db := tt.AuroraSession()
+	backend, err := historyarchive.ConnectBackend("mock://test", historyarchive.ConnectOptions{})
+	tt.Require.NoError(err)
+
+	sys := New(10, db, ledgerState)
+	sys.ColdStorage = coldstorage.NewStore(backend, 0)
+
+	// Disable sleeps for this.
+	sleep = 0
+
+	ledgerState.SetStatus(tt.LoadLedgerStatus())
+	status := ledgerState.CurrentStatus()
+	targetElder := status.HistoryLatest - 10 + 1
+
+	var reapedTransactions, coldTransactions int
+	err = db.GetRaw(tt.Ctx, &reapedTransactions, `SELECT COUNT(*) + COUNT(inner_transaction_hash) FROM history_transactions WHERE ledger_sequence < $1`, targetElder)
+	tt.Require.NoError(err)
+
+	err = sys.DeleteUnretainedHistory(tt.Ctx)
+	tt.Require.NoError(err)
+
+	err = db.GetRaw(tt.Ctx, &coldTransactions, `SELECT COUNT(*) FROM history_cold_transactions`)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(reapedTransactions, coldTransactions)
+
+	for seq := status.HistoryElder; seq < targetElder; seq++ {
+		ledger, err := sys.ColdStorage.LedgerBySequence(tt.Ctx, seq)
+		if tt.Assert.NoError(err) {
+			tt.Assert.Equal(seq, ledger.Sequence)
+		}
+	}
+	_, err = sys.ColdStorage.LedgerBySequence(tt.Ctx, targetElder)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+}
+
+func TestReapSegments(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+
+	oldBatchSize := batchSize
+	batchSize = 100
+	defer func() { batchSize = oldBatchSize }()
+
+	partitions := []history.HistoryPartition{
+		{StartLedger: 0, EndLedger: 150},
+		{StartLedger: 150, EndLedger: 200},
+		{StartLedger: 300, EndLedger: 400},
+		{StartLedger: 400, EndLedger: 500},
+	}
+	tt.Assert.Equal([]reapSegment{
+		{startSeq: 2, endSeq: 101},
+		{startSeq: 102, endSeq: 149},
+		{startSeq: 150, endSeq: 199, partition: &partitions[1]},
+		{startSeq: 200, endSeq: 299},
+		{startSeq: 300, endSeq: 399, partition: &partitions[2]},
+		// The partition [400, 500) is not expired, its rows are deleted.
+		{startSeq: 400, endSeq: 449},
+	}, reapSegments(partitions, 2, 449))
+
+	tt.Assert.Equal([]reapSegment{
+		{startSeq: 2, endSeq: 101},
+		{startSeq: 102, endSeq: 149},
+	}, reapSegments(nil, 2, 149))
+}
@@ -0,0 +1,86 @@
+package resourceadapter
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/diamcircle/go/amount"
+	protocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// millisPerYear is the number of milliseconds in a 365 days year
+const millisPerYear = 365 * 24 * 60 * 60 * 1000
+
+// PopulateLiquidityPoolStats fills out the details of the stats of a
+// liquidity pool over a bucket of the given resolution, in milliseconds.
+// assets are the reserve assets of the pool.
+func PopulateLiquidityPoolStats(
+	ctx context.Context,
+	dest *protocol.LiquidityPoolStats,
+	row history.LiquidityPoolStats,
+	assets [2]xdr.Asset,
+	resolution int64,
+) error {
+	var err error
+	dest.Timestamp = row.Timestamp
+	dest.TradeCount = row.TradeCount
+	dest.TotalTrustlines = row.TrustlineCount
+	dest.TotalShares = amount.StringFromInt64(int64(row.ShareCount))
+
+	reserves := [2]uint64{row.ReserveA, row.ReserveB}
+	volumes := [2]string{row.VolumeA, row.VolumeB}
+	fees := [2]string{row.FeeA, row.FeeB}
+	apr := new(big.Rat)
+
+	dest.Reserves = make([]protocol.LiquidityPoolReserve, 2)
+	dest.Volume = make([]protocol.LiquidityPoolReserve, 2)
+	dest.FeesEarned = make([]protocol.LiquidityPoolReserve, 2)
+	dest.SharePrice = make([]protocol.LiquidityPoolReserve, 2)
+	for i, asset := range assets {
+		canonical := asset.StringCanonical()
+		reserve := new(big.Int).SetUint64(reserves[i])
+
+		dest.Reserves[i] = protocol.LiquidityPoolReserve{
+			Asset:  canonical,
+			Amount: amount.StringFromInt64(int64(reserves[i])),
+		}
+
+		dest.Volume[i].Asset = canonical
+		dest.Volume[i].Amount, err = amount.IntStringToAmount(volumes[i])
+		if err != nil {
+			return err
+		}
+
+		fee, ok := new(big.Int).SetString(fees[i], 10)
+		if !ok {
+			return errors.Errorf("invalid fee: %s", fees[i])
+		}
+		dest.FeesEarned[i] = protocol.LiquidityPoolReserve{
+			Asset:  canonical,
+			Amount: amount.StringFromInt64(fee.Int64()),
+		}
+
+		dest.SharePrice[i] = protocol.LiquidityPoolReserve{
+			Asset:  canonical,
+			Amount: ratio(reserve, new(big.Int).SetUint64(row.ShareCount)).FloatString(7),
+		}
+
+		apr.Add(apr, ratio(fee, reserve))
+	}
+
+	// average the yield of both reserves and annualize it
+	apr.Mul(apr, big.NewRat(millisPerYear, 2*resolution))
+	dest.APR = apr.FloatString(7)
+	return nil
+}
+
+// ratio returns n / d, or 0 if d is 0
+func ratio(n, d *big.Int) *big.Rat {
+	if d.Sign() == 0 {
+		return new(big.Rat)
+	}
+	return new(big.Rat).SetFrac(n, d)
+}
@@ -0,0 +1,62 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPopulateLiquidityPoolStats(t *testing.T) {
+	eur := xdr.MustNewCreditAsset("EUR", "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB")
+	native := xdr.MustNewNativeAsset()
+	row := history.LiquidityPoolStats{
+		Timestamp:      86400000,
+		TradeCount:     2,
+		TrustlineCount: 3,
+		ShareCount:     200000000,
+		ReserveA:       1000000000,
+		ReserveB:       4000000000,
+		VolumeA:        "100000000",
+		VolumeB:        "400000000",
+		FeeA:           "100000",
+		FeeB:           "400000",
+	}
+
+	var dest aurora.LiquidityPoolStats
+	require.NoError(t, PopulateLiquidityPoolStats(context.Background(), &dest, row, [2]xdr.Asset{eur, native}, 86400000))
+	assert.Equal(t, int64(86400000), dest.Timestamp)
+	assert.Equal(t, int64(2), dest.TradeCount)
+	assert.Equal(t, uint64(3), dest.TotalTrustlines)
+	assert.Equal(t, "20.0000000", dest.TotalShares)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "100.0000000"},
+		{Asset: "native", Amount: "400.0000000"},
+	}, dest.Reserves)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "10.0000000"},
+		{Asset: "native", Amount: "40.0000000"},
+	}, dest.Volume)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "0.0100000"},
+		{Asset: "native", Amount: "0.0400000"},
+	}, dest.FeesEarned)
+	assert.Equal(t, []aurora.LiquidityPoolReserve{
+		{Asset: eur.StringCanonical(), Amount: "5.0000000"},
+		{Asset: "native", Amount: "20.0000000"},
+	}, dest.SharePrice)
+	// 0.01% of the reserves earned in a day
+	assert.Equal(t, "0.0365000", dest.APR)
+
+	row.ShareCount, row.ReserveA, row.ReserveB = 0, 0, 0
+	require.NoError(t, PopulateLiquidityPoolStats(context.Background(), &dest, row, [2]xdr.Asset{eur, native}, 86400000))
+	assert.Equal(t, "0.0000000", dest.SharePrice[0].Amount)
+	assert.Equal(t, "0.0000000", dest.APR)
+
+	row.FeeA = "invalid"
+	assert.Error(t, PopulateLiquidityPoolStats(context.Background(), &dest, row, [2]xdr.Asset{eur, native}, 86400000))
+}
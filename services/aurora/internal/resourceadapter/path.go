@@ -8,6 +8,7 @@ import (
 	"github.com/diamcircle/go/amount"
 	"github.com/diamcircle/go/protocols/aurora"
 	"github.com/diamcircle/go/services/aurora/internal/paths"
+	"github.com/diamcircle/go/xdr"
 )
 
 func extractAsset(asset string, t, c, i *string) error {
@@ -61,3 +62,107 @@ func PopulatePath(ctx context.Context, dest *aurora.Path, p paths.Path) (err err
 	}
 	return
 }
+
+// PopulateSplitPaths converts the paths.SplitPlan into a SplitPaths. If
+// destination is not empty the path payment operations paying it are
+// included as well.
+func PopulateSplitPaths(
+	ctx context.Context,
+	dest *aurora.SplitPaths,
+	q paths.SplitQuery,
+	plan paths.SplitPlan,
+	destination string,
+) error {
+	dest.SourceAmount = amount.String(plan.SourceAmount)
+	dest.DestinationAmount = amount.String(plan.DestinationAmount)
+	if err := q.SourceAsset.Extract(
+		&dest.SourceAssetType,
+		&dest.SourceAssetCode,
+		&dest.SourceAssetIssuer,
+	); err != nil {
+		return err
+	}
+	if err := q.DestinationAsset.Extract(
+		&dest.DestinationAssetType,
+		&dest.DestinationAssetCode,
+		&dest.DestinationAssetIssuer,
+	); err != nil {
+		return err
+	}
+
+	dest.Paths = make([]aurora.Path, len(plan.Paths))
+	for i, p := range plan.Paths {
+		if err := PopulatePath(ctx, &dest.Paths[i], p); err != nil {
+			return err
+		}
+	}
+
+	if destination == "" {
+		return nil
+	}
+	muxed, err := xdr.AddressToMuxedAccount(destination)
+	if err != nil {
+		return err
+	}
+	dest.Operations = make([]string, len(plan.Paths))
+	for i, p := range plan.Paths {
+		op, err := pathPaymentOperation(p, q.StrictSend(), muxed)
+		if err != nil {
+			return err
+		}
+		if dest.Operations[i], err = xdr.MarshalBase64(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func xdrAsset(asset string) (xdr.Asset, error) {
+	var t, c, i string
+	if err := extractAsset(asset, &t, &c, &i); err != nil {
+		return xdr.Asset{}, err
+	}
+	return xdr.BuildAsset(t, i, c)
+}
+
+// pathPaymentOperation returns the path payment operation sending the path's
+// source amount, or receiving its destination amount for strict receive
+// payments.
+func pathPaymentOperation(p paths.Path, strictSend bool, destination xdr.MuxedAccount) (xdr.Operation, error) {
+	sendAsset, err := xdrAsset(p.Source)
+	if err != nil {
+		return xdr.Operation{}, err
+	}
+	destAsset, err := xdrAsset(p.Destination)
+	if err != nil {
+		return xdr.Operation{}, err
+	}
+	path := make([]xdr.Asset, len(p.Path))
+	for i, a := range p.Path {
+		if path[i], err = xdrAsset(a); err != nil {
+			return xdr.Operation{}, err
+		}
+	}
+
+	var body xdr.OperationBody
+	if strictSend {
+		body, err = xdr.NewOperationBody(xdr.OperationTypePathPaymentStrictSend, xdr.PathPaymentStrictSendOp{
+			SendAsset:   sendAsset,
+			SendAmount:  p.SourceAmount,
+			Destination: destination,
+			DestAsset:   destAsset,
+			DestMin:     p.DestinationAmount,
+			Path:        path,
+		})
+	} else {
+		body, err = xdr.NewOperationBody(xdr.OperationTypePathPaymentStrictReceive, xdr.PathPaymentStrictReceiveOp{
+			SendAsset:   sendAsset,
+			SendMax:     p.SourceAmount,
+			Destination: destination,
+			DestAsset:   destAsset,
+			DestAmount:  p.DestinationAmount,
+			Path:        path,
+		})
+	}
+	return xdr.Operation{Body: body}, err
+}
@@ -2,11 +2,13 @@ package resourceadapter
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/diamcircle/go/amount"
 	"github.com/diamcircle/go/price"
 	protocol "github.com/diamcircle/go/protocols/aurora"
 	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/diamcircle/go/support/errors"
 )
 
 // PopulateTradeAggregation fills out the details of a trade aggregation using a row from the trade aggregations
@@ -48,5 +50,53 @@ func PopulateTradeAggregation(
 		D: row.CloseD,
 	}
 	dest.Close = dest.CloseR.String()
-	return nil
+
+	dest.VWAP, err = vwap(row.BaseVolume, row.CounterVolume)
+	if err != nil {
+		return err
+	}
+	dest.LiquidityPoolBaseVolume, err = amount.IntStringToAmount(row.LiquidityPoolBaseVolume)
+	if err != nil {
+		return err
+	}
+	dest.LiquidityPoolCounterVolume, err = amount.IntStringToAmount(row.LiquidityPoolCounterVolume)
+	if err != nil {
+		return err
+	}
+	dest.OrderbookBaseVolume, err = volumeDifference(row.BaseVolume, row.LiquidityPoolBaseVolume)
+	if err != nil {
+		return err
+	}
+	dest.OrderbookCounterVolume, err = volumeDifference(row.CounterVolume, row.LiquidityPoolCounterVolume)
+	return err
+}
+
+// vwap returns the volume weighted average price of a trade aggregation,
+// counterVolume / baseVolume, with 7 decimal places.
+func vwap(baseVolume, counterVolume string) (string, error) {
+	base, ok := new(big.Int).SetString(baseVolume, 10)
+	if !ok {
+		return "", errors.Errorf("invalid base volume: %s", baseVolume)
+	}
+	counter, ok := new(big.Int).SetString(counterVolume, 10)
+	if !ok {
+		return "", errors.Errorf("invalid counter volume: %s", counterVolume)
+	}
+	if base.Sign() == 0 {
+		return "0.0000000", nil
+	}
+	return new(big.Rat).SetFrac(counter, base).FloatString(7), nil
+}
+
+// volumeDifference returns total - part as an amount string.
+func volumeDifference(total, part string) (string, error) {
+	t, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		return "", errors.Errorf("invalid volume: %s", total)
+	}
+	p, ok := new(big.Int).SetString(part, 10)
+	if !ok {
+		return "", errors.Errorf("invalid volume: %s", part)
+	}
+	return amount.IntStringToAmount(t.Sub(t, p).String())
 }
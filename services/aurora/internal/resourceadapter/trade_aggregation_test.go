@@ -0,0 +1,47 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/db2/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPopulateTradeAggregation(t *testing.T) {
+	row := history.TradeAggregation{
+		Timestamp:                  3600000,
+		TradeCount:                 3,
+		BaseVolume:                 "30000000",
+		CounterVolume:              "10000000",
+		Average:                    0.3333333333,
+		HighN:                      1,
+		HighD:                      2,
+		LowN:                       1,
+		LowD:                       4,
+		OpenN:                      1,
+		OpenD:                      4,
+		CloseN:                     1,
+		CloseD:                     2,
+		LiquidityPoolBaseVolume:    "20000000",
+		LiquidityPoolCounterVolume: "5000000",
+	}
+
+	var dest aurora.TradeAggregation
+	require.NoError(t, PopulateTradeAggregation(context.Background(), &dest, row))
+	assert.Equal(t, int64(3600000), dest.Timestamp)
+	assert.Equal(t, "3.0000000", dest.BaseVolume)
+	assert.Equal(t, "1.0000000", dest.CounterVolume)
+	assert.Equal(t, "0.3333333", dest.VWAP)
+	assert.Equal(t, "2.0000000", dest.LiquidityPoolBaseVolume)
+	assert.Equal(t, "0.5000000", dest.LiquidityPoolCounterVolume)
+	assert.Equal(t, "1.0000000", dest.OrderbookBaseVolume)
+	assert.Equal(t, "0.5000000", dest.OrderbookCounterVolume)
+	assert.Equal(t, "0.5000000", dest.High)
+	assert.Equal(t, "0.2500000", dest.Open)
+
+	row.LiquidityPoolBaseVolume = "invalid"
+	assert.Error(t, PopulateTradeAggregation(context.Background(), &dest, row))
+}
@@ -11,6 +11,9 @@ import (
 
 const (
 	maxAssetsPerPath = 5
+	// maxPathsPerSplit is the maximum number of paths a split payment is
+	// divided into
+	maxPathsPerSplit = 5
 	// MaxInMemoryPathLength is the maximum path length which can be queried by the InMemoryFinder
 	MaxInMemoryPathLength = 5
 )
@@ -117,3 +120,64 @@ func (finder InMemoryFinder) FindFixedPaths(
 	}
 	return results, lastLedger, err
 }
+
+// FindSplitPaths returns a plan which divides the payment described by `q`
+// across several payment paths, and the most recent ledger.
+func (finder InMemoryFinder) FindSplitPaths(
+	ctx context.Context,
+	q paths.SplitQuery,
+	maxLength uint,
+) (paths.SplitPlan, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return paths.SplitPlan{}, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	if maxLength > MaxInMemoryPathLength {
+		return paths.SplitPlan{}, 0, errors.New("invalid value of maxLength")
+	}
+
+	var plan orderbook.SplitPlan
+	var lastLedger uint32
+	var err error
+	if q.StrictSend() {
+		plan, lastLedger, err = finder.graph.FindSplitStrictSendPaths(
+			ctx,
+			int(maxLength),
+			q.SourceAsset,
+			q.SourceAmount,
+			q.DestinationAsset,
+			maxPathsPerSplit,
+			finder.includePools,
+		)
+	} else {
+		plan, lastLedger, err = finder.graph.FindSplitStrictReceivePaths(
+			ctx,
+			int(maxLength),
+			q.SourceAsset,
+			q.SourceAccount,
+			q.DestinationAsset,
+			q.DestinationAmount,
+			maxPathsPerSplit,
+			finder.includePools,
+		)
+	}
+
+	result := paths.SplitPlan{
+		Paths:             make([]paths.Path, len(plan.Paths)),
+		SourceAmount:      plan.SourceAmount,
+		DestinationAmount: plan.DestinationAmount,
+	}
+	for i, path := range plan.Paths {
+		result.Paths[i] = paths.Path{
+			Path:              path.InteriorNodes,
+			Source:            path.SourceAsset,
+			SourceAmount:      path.SourceAmount,
+			Destination:       path.DestinationAsset,
+			DestinationAmount: path.DestinationAmount,
+		}
+	}
+	return result, lastLedger, err
+}
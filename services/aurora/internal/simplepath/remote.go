@@ -0,0 +1,219 @@
+package simplepath
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/diamcircle/go/amount"
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/services/aurora/internal/paths"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/diamcircle/go/xdr"
+)
+
+// latestLedgerHeader is the header the path finding service uses to return
+// the ledger its paths are consistent with
+const latestLedgerHeader = "Latest-Ledger"
+
+// RemoteFinder is an implementation of the path finding interface which
+// queries a standalone path finding service over HTTP
+type RemoteFinder struct {
+	url    *url.URL
+	client *http.Client
+}
+
+// NewRemoteFinder constructs a new RemoteFinder instance querying the path
+// finding service at serviceURL. http.DefaultClient is used if client is nil.
+func NewRemoteFinder(serviceURL *url.URL, client *http.Client) RemoteFinder {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return RemoteFinder{url: serviceURL, client: client}
+}
+
+// Find implements the path payments finder interface
+func (finder RemoteFinder) Find(ctx context.Context, q paths.Query, maxLength uint) ([]paths.Path, uint32, error) {
+	params := url.Values{}
+	addAssetParams(params, "destination_", q.DestinationAsset)
+	params.Set("destination_amount", amount.String(q.DestinationAmount))
+	params.Set("source_assets", canonicalAssets(q.SourceAssets))
+	if q.ValidateSourceBalance {
+		balances := make([]string, len(q.SourceAssetBalances))
+		for i, balance := range q.SourceAssetBalances {
+			balances[i] = amount.String(balance)
+		}
+		params.Set("source_asset_balances", strings.Join(balances, ","))
+	}
+	if q.SourceAccount != nil {
+		params.Set("source_account", q.SourceAccount.Address())
+	}
+
+	var page aurora.PathsPage
+	lastLedger, err := finder.get(ctx, "paths/strict-receive", params, maxLength, &page)
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := fromRemotePaths(page.Embedded.Records)
+	return results, lastLedger, err
+}
+
+// FindFixedPaths implements the path payments finder interface
+func (finder RemoteFinder) FindFixedPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+) ([]paths.Path, uint32, error) {
+	params := url.Values{}
+	addAssetParams(params, "source_", sourceAsset)
+	params.Set("source_amount", amount.String(amountToSpend))
+	params.Set("destination_assets", canonicalAssets(destinationAssets))
+
+	var page aurora.PathsPage
+	lastLedger, err := finder.get(ctx, "paths/strict-send", params, maxLength, &page)
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := fromRemotePaths(page.Embedded.Records)
+	return results, lastLedger, err
+}
+
+// FindSplitPaths implements the path payments finder interface
+func (finder RemoteFinder) FindSplitPaths(
+	ctx context.Context,
+	q paths.SplitQuery,
+	maxLength uint,
+) (paths.SplitPlan, uint32, error) {
+	params := url.Values{}
+	addAssetParams(params, "source_", q.SourceAsset)
+	addAssetParams(params, "destination_", q.DestinationAsset)
+	if q.StrictSend() {
+		params.Set("source_amount", amount.String(q.SourceAmount))
+	} else {
+		params.Set("destination_amount", amount.String(q.DestinationAmount))
+	}
+	if q.SourceAccount != nil {
+		params.Set("source_account", q.SourceAccount.Address())
+	}
+
+	var split aurora.SplitPaths
+	lastLedger, err := finder.get(ctx, "paths/split", params, maxLength, &split)
+	if err != nil {
+		return paths.SplitPlan{}, 0, err
+	}
+
+	plan := paths.SplitPlan{}
+	if plan.SourceAmount, err = amount.Parse(split.SourceAmount); err != nil {
+		return paths.SplitPlan{}, 0, err
+	}
+	if plan.DestinationAmount, err = amount.Parse(split.DestinationAmount); err != nil {
+		return paths.SplitPlan{}, 0, err
+	}
+	if plan.Paths, err = fromRemotePaths(split.Paths); err != nil {
+		return paths.SplitPlan{}, 0, err
+	}
+	return plan, lastLedger, nil
+}
+
+// get queries the given endpoint of the path finding service, decodes the
+// response into dest and returns the ledger the response is consistent with.
+func (finder RemoteFinder) get(
+	ctx context.Context,
+	endpoint string,
+	params url.Values,
+	maxLength uint,
+	dest interface{},
+) (uint32, error) {
+	if maxLength > 0 {
+		params.Set("max_path_length", strconv.FormatUint(uint64(maxLength), 10))
+	}
+	u := finder.url.ResolveReference(&url.URL{Path: endpoint, RawQuery: params.Encode()})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := finder.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not query path finding service")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusServiceUnavailable:
+		return 0, ErrEmptyInMemoryOrderBook
+	default:
+		var p problem.P
+		if err = json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			return 0, errors.Errorf("path finding service responded with status %d", resp.StatusCode)
+		}
+		return 0, errors.Errorf("path finding service responded with status %d: %s", resp.StatusCode, p.Detail)
+	}
+
+	lastLedger, err := strconv.ParseUint(resp.Header.Get(latestLedgerHeader), 10, 32)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid "+latestLedgerHeader+" header")
+	}
+	if err = json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return 0, errors.Wrap(err, "could not decode path finding service response")
+	}
+	return uint32(lastLedger), nil
+}
+
+func addAssetParams(params url.Values, prefix string, asset xdr.Asset) {
+	var t, c, i string
+	asset.MustExtract(&t, &c, &i)
+	params.Set(prefix+"asset_type", t)
+	if c != "" {
+		params.Set(prefix+"asset_code", c)
+		params.Set(prefix+"asset_issuer", i)
+	}
+}
+
+func canonicalAssets(assets []xdr.Asset) string {
+	encoded := make([]string, len(assets))
+	for i, asset := range assets {
+		encoded[i] = asset.StringCanonical()
+	}
+	return strings.Join(encoded, ",")
+}
+
+func fromRemotePaths(records []aurora.Path) ([]paths.Path, error) {
+	results := make([]paths.Path, len(records))
+	for i, record := range records {
+		sourceAmount, err := amount.Parse(record.SourceAmount)
+		if err != nil {
+			return nil, err
+		}
+		destinationAmount, err := amount.Parse(record.DestinationAmount)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = paths.Path{
+			Path:              make([]string, len(record.Path)),
+			Source:            assetString(record.SourceAssetType, record.SourceAssetCode, record.SourceAssetIssuer),
+			SourceAmount:      sourceAmount,
+			Destination:       assetString(record.DestinationAssetType, record.DestinationAssetCode, record.DestinationAssetIssuer),
+			DestinationAmount: destinationAmount,
+		}
+		for j, asset := range record.Path {
+			results[i].Path[j] = assetString(asset.Type, asset.Code, asset.Issuer)
+		}
+	}
+	return results, nil
+}
+
+// assetString returns the asset in the format used by the in memory order
+// book, see xdr.Asset.String
+func assetString(assetType, code, issuer string) string {
+	if assetType == "native" {
+		return assetType
+	}
+	return assetType + "/" + code + "/" + issuer
+}
@@ -0,0 +1,165 @@
+package simplepath
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/diamcircle/go/services/aurora/internal/paths"
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	issuer         = "GAXI33UCLQTCKM2NMRBS7XYBR535LLEVAHL5YBN4FTCB4HZHT7ZA5CVK"
+	sourceAccount  = "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+	remotePathJSON = `{
+		"source_asset_type": "native",
+		"source_amount": "20.0000000",
+		"destination_asset_type": "credit_alphanum4",
+		"destination_asset_code": "USD",
+		"destination_asset_issuer": "` + issuer + `",
+		"destination_amount": "10.0000000",
+		"path": [{"asset_type": "credit_alphanum4", "asset_code": "EUR", "asset_issuer": "` + issuer + `"}]
+	}`
+)
+
+var expectedPath = paths.Path{
+	Path:              []string{"credit_alphanum4/EUR/" + issuer},
+	Source:            "native",
+	SourceAmount:      200000000,
+	Destination:       "credit_alphanum4/USD/" + issuer,
+	DestinationAmount: 100000000,
+}
+
+type remoteTestServer struct {
+	*httptest.Server
+	requests []*http.Request
+	status   int
+	body     string
+}
+
+func newRemoteTestServer(t *testing.T) (*remoteTestServer, RemoteFinder) {
+	s := &remoteTestServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests = append(s.requests, r)
+		w.Header().Set("Latest-Ledger", "123")
+		w.WriteHeader(s.status)
+		w.Write([]byte(s.body))
+	}))
+	serviceURL, err := url.Parse(s.URL)
+	require.NoError(t, err)
+	return s, NewRemoteFinder(serviceURL, nil)
+}
+
+func TestRemoteFinderFind(t *testing.T) {
+	s, finder := newRemoteTestServer(t)
+	defer s.Close()
+	s.body = `{"_embedded": {"records": [` + remotePathJSON + `]}}`
+
+	account := xdr.MustAddress(sourceAccount)
+	found, lastLedger, err := finder.Find(context.Background(), paths.Query{
+		DestinationAsset:      xdr.MustNewCreditAsset("USD", issuer),
+		DestinationAmount:     100000000,
+		SourceAssets:          []xdr.Asset{xdr.MustNewNativeAsset(), xdr.MustNewCreditAsset("EUR", issuer)},
+		SourceAssetBalances:   []xdr.Int64{500000000, 10},
+		ValidateSourceBalance: true,
+		SourceAccount:         &account,
+	}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(123), lastLedger)
+	assert.Equal(t, []paths.Path{expectedPath}, found)
+
+	require.Len(t, s.requests, 1)
+	assert.Equal(t, "/paths/strict-receive", s.requests[0].URL.Path)
+	assert.Equal(t, url.Values{
+		"destination_asset_type":   {"credit_alphanum4"},
+		"destination_asset_code":   {"USD"},
+		"destination_asset_issuer": {issuer},
+		"destination_amount":       {"10.0000000"},
+		"source_assets":            {"native,EUR:" + issuer},
+		"source_asset_balances":    {"50.0000000,0.0000010"},
+		"source_account":           {sourceAccount},
+		"max_path_length":          {"3"},
+	}, s.requests[0].URL.Query())
+}
+
+func TestRemoteFinderFindFixedPaths(t *testing.T) {
+	s, finder := newRemoteTestServer(t)
+	defer s.Close()
+	s.body = `{"_embedded": {"records": [` + remotePathJSON + `]}}`
+
+	found, lastLedger, err := finder.FindFixedPaths(
+		context.Background(),
+		xdr.MustNewNativeAsset(),
+		200000000,
+		[]xdr.Asset{xdr.MustNewCreditAsset("USD", issuer)},
+		0,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(123), lastLedger)
+	assert.Equal(t, []paths.Path{expectedPath}, found)
+
+	require.Len(t, s.requests, 1)
+	assert.Equal(t, "/paths/strict-send", s.requests[0].URL.Path)
+	assert.Equal(t, url.Values{
+		"source_asset_type":  {"native"},
+		"source_amount":      {"20.0000000"},
+		"destination_assets": {"USD:" + issuer},
+	}, s.requests[0].URL.Query())
+}
+
+func TestRemoteFinderFindSplitPaths(t *testing.T) {
+	s, finder := newRemoteTestServer(t)
+	defer s.Close()
+	s.body = `{
+		"source_asset_type": "native",
+		"source_amount": "20.0000000",
+		"destination_asset_type": "credit_alphanum4",
+		"destination_asset_code": "USD",
+		"destination_asset_issuer": "` + issuer + `",
+		"destination_amount": "10.0000000",
+		"paths": [` + remotePathJSON + `]
+	}`
+
+	plan, lastLedger, err := finder.FindSplitPaths(context.Background(), paths.SplitQuery{
+		SourceAsset:       xdr.MustNewNativeAsset(),
+		DestinationAsset:  xdr.MustNewCreditAsset("USD", issuer),
+		DestinationAmount: 100000000,
+	}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(123), lastLedger)
+	assert.Equal(t, paths.SplitPlan{
+		Paths:             []paths.Path{expectedPath},
+		SourceAmount:      200000000,
+		DestinationAmount: 100000000,
+	}, plan)
+
+	require.Len(t, s.requests, 1)
+	assert.Equal(t, "/paths/split", s.requests[0].URL.Path)
+	assert.Equal(t, "10.0000000", s.requests[0].URL.Query().Get("destination_amount"))
+	assert.Empty(t, s.requests[0].URL.Query().Get("source_amount"))
+}
+
+func TestRemoteFinderErrors(t *testing.T) {
+	s, finder := newRemoteTestServer(t)
+	defer s.Close()
+	query := paths.Query{
+		DestinationAsset:  xdr.MustNewNativeAsset(),
+		DestinationAmount: 100000000,
+		SourceAssets:      []xdr.Asset{xdr.MustNewCreditAsset("USD", issuer)},
+	}
+
+	s.status = http.StatusServiceUnavailable
+	s.body = `{"type": "still_ingesting", "status": 503}`
+	_, _, err := finder.Find(context.Background(), query, 3)
+	assert.Equal(t, ErrEmptyInMemoryOrderBook, err)
+
+	s.status = http.StatusBadRequest
+	s.body = `{"type": "bad_request", "status": 400, "detail": "max_path_length is invalid"}`
+	_, _, err = finder.Find(context.Background(), query, 3)
+	assert.EqualError(t, err, "path finding service responded with status 400: max_path_length is invalid")
+}
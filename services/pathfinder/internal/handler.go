@@ -0,0 +1,388 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/diamcircle/go/amount"
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/support/render/hal"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/diamcircle/go/xdr"
+)
+
+const (
+	// LatestLedgerHeader is the response header holding the ledger the
+	// returned paths are consistent with.
+	LatestLedgerHeader = "Latest-Ledger"
+
+	maxAssetsPerPath = 5
+	// maxPathsPerSplit is the maximum number of paths a split payment is
+	// divided into
+	maxPathsPerSplit = 5
+)
+
+// StillIngesting is returned while the order book graph has not been
+// populated yet. It matches the problem returned by Aurora in the same
+// situation.
+var StillIngesting = problem.P{
+	Type:   "still_ingesting",
+	Title:  "Still Ingesting",
+	Status: http.StatusServiceUnavailable,
+	Detail: "Data cannot be presented because it's still being ingested. Please " +
+		"wait for several minutes before trying your request again.",
+}
+
+// PathsHandler serves payment paths found in an order book graph. The
+// responses have the same shape as the corresponding Aurora endpoints.
+type PathsHandler struct {
+	Graph                *orderbook.OrderBookGraph
+	MaxPathLength        uint
+	MaxAssetsParamLength int
+	IncludePools         bool
+}
+
+// StrictReceive finds paths delivering destination_amount of the destination
+// asset and starting with one of source_assets.
+//
+// If source_asset_balances is given, it lists the balance available for each
+// of the source assets and paths requiring more than that balance are not
+// returned. Offers from source_account, if given, are ignored.
+func (h PathsHandler) StrictReceive(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, func(ctx context.Context, maxLength int) (interface{}, uint32, error) {
+		q := r.URL.Query()
+		destinationAsset, err := assetParam(q.Get, "destination_")
+		if err != nil {
+			return nil, 0, err
+		}
+		destinationAmount, err := amountParam(q.Get, "destination_amount")
+		if err != nil {
+			return nil, 0, err
+		}
+		sourceAssets, err := h.assetsParam(q.Get, "source_assets")
+		if err != nil {
+			return nil, 0, err
+		}
+		sourceAccount, err := accountParam(q.Get, "source_account")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		balances := make([]xdr.Int64, len(sourceAssets))
+		validateBalances := q.Get("source_asset_balances") != ""
+		if validateBalances {
+			parts := strings.Split(q.Get("source_asset_balances"), ",")
+			if len(parts) != len(sourceAssets) {
+				return nil, 0, problem.MakeInvalidFieldProblem(
+					"source_asset_balances",
+					fmt.Errorf("expected %d balances", len(sourceAssets)),
+				)
+			}
+			for i, part := range parts {
+				if balances[i], err = amount.Parse(part); err != nil {
+					return nil, 0, problem.MakeInvalidFieldProblem("source_asset_balances", err)
+				}
+			}
+		}
+
+		found, lastLedger, err := h.Graph.FindPaths(
+			ctx,
+			maxLength,
+			destinationAsset,
+			destinationAmount,
+			sourceAccount,
+			sourceAssets,
+			balances,
+			validateBalances,
+			maxAssetsPerPath,
+			h.IncludePools,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		page, err := pathsPage(found)
+		return page, lastLedger, err
+	})
+}
+
+// StrictSend finds paths spending source_amount of the source asset and
+// ending with one of destination_assets.
+func (h PathsHandler) StrictSend(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, func(ctx context.Context, maxLength int) (interface{}, uint32, error) {
+		q := r.URL.Query()
+		sourceAsset, err := assetParam(q.Get, "source_")
+		if err != nil {
+			return nil, 0, err
+		}
+		sourceAmount, err := amountParam(q.Get, "source_amount")
+		if err != nil {
+			return nil, 0, err
+		}
+		destinationAssets, err := h.assetsParam(q.Get, "destination_assets")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		found, lastLedger, err := h.Graph.FindFixedPaths(
+			ctx,
+			maxLength,
+			sourceAsset,
+			sourceAmount,
+			destinationAssets,
+			maxAssetsPerPath,
+			h.IncludePools,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		page, err := pathsPage(found)
+		return page, lastLedger, err
+	})
+}
+
+// Split plans a payment divided across several paths. Exactly one of
+// source_amount, for strict send payments, and destination_amount, for
+// strict receive payments, must be given. Offers from source_account, if
+// given, are ignored in strict receive plans.
+func (h PathsHandler) Split(w http.ResponseWriter, r *http.Request) {
+	h.render(w, r, func(ctx context.Context, maxLength int) (interface{}, uint32, error) {
+		q := r.URL.Query()
+		if (q.Get("source_amount") != "") == (q.Get("destination_amount") != "") {
+			p := problem.BadRequest
+			p.Detail = "The request requires either a source amount, for strict send payments, " +
+				"or a destination amount, for strict receive payments. Both fields cannot be present."
+			return nil, 0, &p
+		}
+		sourceAsset, err := assetParam(q.Get, "source_")
+		if err != nil {
+			return nil, 0, err
+		}
+		destinationAsset, err := assetParam(q.Get, "destination_")
+		if err != nil {
+			return nil, 0, err
+		}
+		sourceAccount, err := accountParam(q.Get, "source_account")
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var plan orderbook.SplitPlan
+		var lastLedger uint32
+		if q.Get("source_amount") != "" {
+			sourceAmount, err := amountParam(q.Get, "source_amount")
+			if err != nil {
+				return nil, 0, err
+			}
+			plan, lastLedger, err = h.Graph.FindSplitStrictSendPaths(
+				ctx,
+				maxLength,
+				sourceAsset,
+				sourceAmount,
+				destinationAsset,
+				maxPathsPerSplit,
+				h.IncludePools,
+			)
+			if err != nil {
+				return nil, 0, err
+			}
+		} else {
+			destinationAmount, err := amountParam(q.Get, "destination_amount")
+			if err != nil {
+				return nil, 0, err
+			}
+			plan, lastLedger, err = h.Graph.FindSplitStrictReceivePaths(
+				ctx,
+				maxLength,
+				sourceAsset,
+				sourceAccount,
+				destinationAsset,
+				destinationAmount,
+				maxPathsPerSplit,
+				h.IncludePools,
+			)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+
+		res := aurora.SplitPaths{
+			SourceAmount:      amount.String(plan.SourceAmount),
+			DestinationAmount: amount.String(plan.DestinationAmount),
+			Paths:             make([]aurora.Path, len(plan.Paths)),
+		}
+		if err = sourceAsset.Extract(&res.SourceAssetType, &res.SourceAssetCode, &res.SourceAssetIssuer); err != nil {
+			return nil, 0, err
+		}
+		if err = destinationAsset.Extract(
+			&res.DestinationAssetType,
+			&res.DestinationAssetCode,
+			&res.DestinationAssetIssuer,
+		); err != nil {
+			return nil, 0, err
+		}
+		for i, p := range plan.Paths {
+			if err = populatePath(&res.Paths[i], p); err != nil {
+				return nil, 0, err
+			}
+		}
+		return res, lastLedger, nil
+	})
+}
+
+// Health responds with 200 once the order book graph has been populated and
+// with a still_ingesting problem before that.
+func (h PathsHandler) Health(w http.ResponseWriter, r *http.Request) {
+	lastLedger := h.Graph.LastLedger()
+	if lastLedger == 0 {
+		problem.Render(r.Context(), w, StillIngesting)
+		return
+	}
+	w.Header().Set(LatestLedgerHeader, strconv.FormatUint(uint64(lastLedger), 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+// render parses the common max_path_length parameter, runs find and renders
+// its result with the ledger it is consistent with.
+func (h PathsHandler) render(
+	w http.ResponseWriter,
+	r *http.Request,
+	find func(ctx context.Context, maxLength int) (interface{}, uint32, error),
+) {
+	ctx := r.Context()
+	if h.Graph.LastLedger() == 0 {
+		problem.Render(ctx, w, StillIngesting)
+		return
+	}
+
+	maxLength := h.MaxPathLength
+	if s := r.URL.Query().Get("max_path_length"); s != "" {
+		requested, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || requested == 0 || uint(requested) > h.MaxPathLength {
+			problem.Render(ctx, w, problem.MakeInvalidFieldProblem(
+				"max_path_length",
+				fmt.Errorf("must be between 1 and %d", h.MaxPathLength),
+			))
+			return
+		}
+		maxLength = uint(requested)
+	}
+
+	result, lastLedger, err := find(ctx, int(maxLength))
+	if err != nil {
+		problem.Render(ctx, w, err)
+		return
+	}
+	w.Header().Set(LatestLedgerHeader, strconv.FormatUint(uint64(lastLedger), 10))
+	hal.Render(w, result)
+}
+
+func assetParam(get func(string) string, prefix string) (xdr.Asset, error) {
+	asset, err := xdr.BuildAsset(
+		get(prefix+"asset_type"),
+		get(prefix+"asset_issuer"),
+		get(prefix+"asset_code"),
+	)
+	if err != nil {
+		return xdr.Asset{}, problem.MakeInvalidFieldProblem(prefix+"asset_type", err)
+	}
+	return asset, nil
+}
+
+func amountParam(get func(string) string, name string) (xdr.Int64, error) {
+	parsed, err := amount.Parse(get(name))
+	if err != nil {
+		return 0, problem.MakeInvalidFieldProblem(name, err)
+	}
+	if parsed <= 0 {
+		return 0, problem.MakeInvalidFieldProblem(name, fmt.Errorf("must be positive"))
+	}
+	return parsed, nil
+}
+
+func (h PathsHandler) assetsParam(get func(string) string, name string) ([]xdr.Asset, error) {
+	assets, err := xdr.BuildAssets(get(name))
+	if err != nil {
+		return nil, problem.MakeInvalidFieldProblem(name, err)
+	}
+	if len(assets) == 0 {
+		return nil, problem.MakeInvalidFieldProblem(name, fmt.Errorf("at least one asset is required"))
+	}
+	if len(assets) > h.MaxAssetsParamLength {
+		return nil, problem.MakeInvalidFieldProblem(
+			name,
+			fmt.Errorf("list of assets exceeds maximum length of %d", h.MaxAssetsParamLength),
+		)
+	}
+	return assets, nil
+}
+
+func accountParam(get func(string) string, name string) (*xdr.AccountId, error) {
+	address := get(name)
+	if address == "" {
+		return nil, nil
+	}
+	var account xdr.AccountId
+	if err := account.SetAddress(address); err != nil {
+		return nil, problem.MakeInvalidFieldProblem(name, err)
+	}
+	return &account, nil
+}
+
+func pathsPage(found []orderbook.Path) (hal.BasePage, error) {
+	var page hal.BasePage
+	page.Init()
+	for _, p := range found {
+		var res aurora.Path
+		if err := populatePath(&res, p); err != nil {
+			return hal.BasePage{}, err
+		}
+		page.Add(res)
+	}
+	return page, nil
+}
+
+func populatePath(dest *aurora.Path, p orderbook.Path) error {
+	dest.SourceAmount = amount.String(p.SourceAmount)
+	dest.DestinationAmount = amount.String(p.DestinationAmount)
+
+	err := extractAsset(p.SourceAsset, &dest.SourceAssetType, &dest.SourceAssetCode, &dest.SourceAssetIssuer)
+	if err != nil {
+		return err
+	}
+	err = extractAsset(
+		p.DestinationAsset,
+		&dest.DestinationAssetType,
+		&dest.DestinationAssetCode,
+		&dest.DestinationAssetIssuer,
+	)
+	if err != nil {
+		return err
+	}
+
+	dest.Path = make([]aurora.Asset, len(p.InteriorNodes))
+	for i, a := range p.InteriorNodes {
+		if err = extractAsset(a, &dest.Path[i].Type, &dest.Path[i].Code, &dest.Path[i].Issuer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractAsset splits an asset string of the order book graph, which is
+// either "native" or "type/code/issuer".
+func extractAsset(asset string, t, c, i *string) error {
+	if asset == "native" {
+		*t = asset
+		return nil
+	}
+	parts := strings.Split(asset, "/")
+	if len(parts) != 3 {
+		return fmt.Errorf("expected length to be 3 but got %v", parts)
+	}
+	*t, *c, *i = parts[0], parts[1], parts[2]
+	return nil
+}
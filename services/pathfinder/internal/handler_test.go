@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	issuer   = xdr.MustAddress("GAXI33UCLQTCKM2NMRBS7XYBR535LLEVAHL5YBN4FTCB4HZHT7ZA5CVK")
+	seller   = xdr.MustAddress("GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU")
+	usdAsset = xdr.MustNewCreditAsset("USD", issuer.Address())
+)
+
+func newTestHandler(t *testing.T) PathsHandler {
+	graph := orderbook.NewOrderBookGraph()
+	graph.AddOffers(xdr.OfferEntry{
+		SellerId: seller,
+		OfferId:  1,
+		Selling:  usdAsset,
+		Buying:   xdr.MustNewNativeAsset(),
+		Price:    xdr.Price{N: 2, D: 1},
+		Amount:   1000000000,
+	})
+	require.NoError(t, graph.Apply(100))
+	return PathsHandler{
+		Graph:                graph,
+		MaxPathLength:        3,
+		MaxAssetsParamLength: 2,
+		IncludePools:         true,
+	}
+}
+
+func serve(handler http.HandlerFunc, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest("GET", url, nil))
+	return w
+}
+
+func decodePaths(t *testing.T, w *httptest.ResponseRecorder) []aurora.Path {
+	var page aurora.PathsPage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	return page.Embedded.Records
+}
+
+func TestStrictReceive(t *testing.T) {
+	handler := newTestHandler(t)
+	usdParams := "destination_asset_type=credit_alphanum4&destination_asset_code=USD&destination_asset_issuer=" +
+		issuer.Address()
+
+	w := serve(handler.StrictReceive, "/paths/strict-receive?"+usdParams+
+		"&destination_amount=10&source_assets=native")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get(LatestLedgerHeader))
+	assert.Equal(t, []aurora.Path{{
+		SourceAssetType:        "native",
+		SourceAmount:           "20.0000000",
+		DestinationAssetType:   "credit_alphanum4",
+		DestinationAssetCode:   "USD",
+		DestinationAssetIssuer: issuer.Address(),
+		DestinationAmount:      "10.0000000",
+		Path:                   []aurora.Asset{},
+	}}, decodePaths(t, w))
+
+	// paths exceeding the source asset balance are not returned
+	w = serve(handler.StrictReceive, "/paths/strict-receive?"+usdParams+
+		"&destination_amount=10&source_assets=native&source_asset_balances=15")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodePaths(t, w))
+
+	// offers from the source account are ignored
+	w = serve(handler.StrictReceive, "/paths/strict-receive?"+usdParams+
+		"&destination_amount=10&source_assets=native&source_account="+seller.Address())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodePaths(t, w))
+
+	for _, query := range []string{
+		usdParams + "&destination_amount=10",
+		usdParams + "&destination_amount=-1&source_assets=native",
+		usdParams + "&destination_amount=10&source_assets=native&source_asset_balances=1,2",
+		usdParams + "&destination_amount=10&source_assets=native&max_path_length=4",
+		"destination_asset_type=credit_alphanum4&destination_amount=10&source_assets=native",
+	} {
+		w = serve(handler.StrictReceive, "/paths/strict-receive?"+query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestStrictSend(t *testing.T) {
+	handler := newTestHandler(t)
+
+	w := serve(handler.StrictSend, "/paths/strict-send?source_asset_type=native&source_amount=20"+
+		"&destination_assets=USD:"+issuer.Address())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get(LatestLedgerHeader))
+	assert.Equal(t, []aurora.Path{{
+		SourceAssetType:        "native",
+		SourceAmount:           "20.0000000",
+		DestinationAssetType:   "credit_alphanum4",
+		DestinationAssetCode:   "USD",
+		DestinationAssetIssuer: issuer.Address(),
+		DestinationAmount:      "10.0000000",
+		Path:                   []aurora.Asset{},
+	}}, decodePaths(t, w))
+
+	w = serve(handler.StrictSend, "/paths/strict-send?source_asset_type=native&source_amount=20"+
+		"&destination_assets=native,native,native")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSplit(t *testing.T) {
+	handler := newTestHandler(t)
+	assetParams := "source_asset_type=native&destination_asset_type=credit_alphanum4" +
+		"&destination_asset_code=USD&destination_asset_issuer=" + issuer.Address()
+
+	w := serve(handler.Split, "/paths/split?"+assetParams+"&destination_amount=10")
+	require.Equal(t, http.StatusOK, w.Code)
+	var split aurora.SplitPaths
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&split))
+	assert.Equal(t, "native", split.SourceAssetType)
+	assert.Equal(t, "USD", split.DestinationAssetCode)
+	assert.Equal(t, "20.0000000", split.SourceAmount)
+	assert.Equal(t, "10.0000000", split.DestinationAmount)
+	assert.Len(t, split.Paths, 1)
+
+	w = serve(handler.Split, "/paths/split?"+assetParams+"&destination_amount=10&source_amount=20")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStillIngesting(t *testing.T) {
+	handler := PathsHandler{
+		Graph:                orderbook.NewOrderBookGraph(),
+		MaxPathLength:        3,
+		MaxAssetsParamLength: 2,
+	}
+
+	w := serve(handler.StrictSend, "/paths/strict-send?source_asset_type=native&source_amount=20"+
+		"&destination_assets=native")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = serve(handler.Health, "/health")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	handler = newTestHandler(t)
+	w = serve(handler.Health, "/health")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get(LatestLedgerHeader))
+}
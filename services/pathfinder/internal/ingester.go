@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/diamcircle/go/exp/orderbook"
+	"github.com/diamcircle/go/exp/orderbook/replay"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/log"
+)
+
+// retryInterval is how long the Ingester waits before trying again after
+// ingestion fails
+const retryInterval = 5 * time.Second
+
+// Ingester keeps an order book graph in sync with the network. The graph is
+// first populated from the latest history archive checkpoint, or from a more
+// recent snapshot if there is one, and then follows the ledgers closed by
+// the network.
+type Ingester struct {
+	config   replay.Config
+	replayer *replay.Replayer
+}
+
+// NewIngester constructs an Ingester. Snapshots of the graph are saved to and
+// restored from config.SnapshotDir, if it is set.
+func NewIngester(config replay.Config) (*Ingester, error) {
+	replayer, err := replay.NewReplayer(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Ingester{config: config, replayer: replayer}, nil
+}
+
+// Graph returns the order book graph maintained by the Ingester. It is safe
+// to find paths in the graph while the Ingester is running.
+func (i *Ingester) Graph() *orderbook.OrderBookGraph {
+	return i.replayer.Graph()
+}
+
+// Run ingests ledgers until the context is cancelled. Errors are logged and
+// ingestion resumes from the last ledger applied to the graph.
+func (i *Ingester) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := i.run(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		log.WithField("err", err).
+			WithField("ledger", i.replayer.Ledger()).
+			Error("Error ingesting order book, retrying")
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (i *Ingester) run(ctx context.Context) error {
+	if i.replayer.Ledger() == 0 {
+		if err := i.catchup(ctx); err != nil {
+			return err
+		}
+	}
+
+	from := i.replayer.Ledger() + 1
+	if err := i.config.LedgerBackend.PrepareRange(ctx, ledgerbackend.UnboundedRange(from)); err != nil {
+		return errors.Wrap(err, "could not prepare ledger range")
+	}
+	for sequence := from; ctx.Err() == nil; sequence++ {
+		if err := i.replayer.Seek(ctx, sequence); err != nil {
+			return err
+		}
+		log.WithField("ledger", sequence).Debug("Applied ledger to order book")
+	}
+	return nil
+}
+
+// catchup populates the graph from the latest checkpoint or snapshot.
+func (i *Ingester) catchup(ctx context.Context) error {
+	has, err := i.config.HistoryArchive.GetRootHAS()
+	if err != nil {
+		return errors.Wrap(err, "could not get root HAS")
+	}
+	start := has.CurrentLedger
+
+	snapshot, err := i.replayer.LatestSnapshot()
+	if err != nil {
+		return err
+	}
+	if snapshot > start {
+		start = snapshot
+	}
+
+	log.WithField("ledger", start).Info("Populating order book")
+	if err = i.replayer.Seek(ctx, start); err != nil {
+		return errors.Wrapf(err, "could not populate order book at ledger %d", start)
+	}
+	log.WithField("ledger", start).Info("Populated order book")
+	return nil
+}
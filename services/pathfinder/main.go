@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	stdhttp "net/http"
+	"os"
+
+	"github.com/diamcircle/go/exp/orderbook/replay"
+	"github.com/diamcircle/go/historyarchive"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/services/pathfinder/internal"
+	"github.com/diamcircle/go/support/app"
+	"github.com/diamcircle/go/support/config"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/support/http"
+	"github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/go-chi/chi"
+	"github.com/spf13/cobra"
+)
+
+// Config represents the configuration of a path finding server
+type Config struct {
+	Port                   int         `toml:"port" valid:"required"`
+	NetworkPassphrase      string      `toml:"network_passphrase" valid:"required"`
+	HistoryArchiveURLs     []string    `toml:"history_archive_urls" valid:"optional"`
+	CaptiveCoreBinaryPath  string      `toml:"captive_core_binary_path" valid:"optional"`
+	CaptiveCoreConfigPath  string      `toml:"captive_core_config_path" valid:"optional"`
+	RemoteCaptiveCoreURL   string      `toml:"remote_captive_core_url" valid:"optional"`
+	MaxPathLength          uint        `toml:"max_path_length" valid:"optional"`
+	MaxAssetsParamLength   int         `toml:"max_assets_per_path_request" valid:"optional"`
+	DisablePoolPathFinding bool        `toml:"disable_pool_path_finding" valid:"optional"`
+	SnapshotDir            string      `toml:"snapshot_dir" valid:"optional"`
+	SnapshotInterval       uint32      `toml:"snapshot_interval" valid:"optional"`
+	TLS                    *config.TLS `valid:"optional"`
+}
+
+func main() {
+
+	rootCmd := &cobra.Command{
+		Use:   "pathfinder",
+		Short: "payment path finding service for the Diamcircle network",
+		Long: "API server finding payment paths in an in memory order book kept in sync with the " +
+			"Diamcircle network. Aurora can use it as a remote path finder.",
+		Run: run,
+	}
+
+	rootCmd.PersistentFlags().String("conf", "./pathfinder.cfg", "config file path")
+	if err := rootCmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(cmd *cobra.Command, args []string) {
+	var (
+		cfg     Config
+		cfgPath = cmd.PersistentFlags().Lookup("conf").Value.String()
+	)
+	log.SetLevel(log.InfoLevel)
+
+	err := config.Read(cfgPath, &cfg)
+	if err != nil {
+		switch cause := errors.Cause(err).(type) {
+		case *config.InvalidConfigError:
+			log.Error("config file: ", cause)
+		default:
+			log.Error(err)
+		}
+		os.Exit(1)
+	}
+	setDefaults(&cfg)
+
+	ingester, err := initIngester(cfg)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	router := initRouter(cfg, ingester)
+	addr := fmt.Sprintf("0.0.0.0:%d", cfg.Port)
+
+	http.Run(http.Config{
+		ListenAddr: addr,
+		Handler:    router,
+		TLS:        cfg.TLS,
+		OnStarting: func() {
+			log.Infof("starting pathfinder server - %s", app.Version())
+			log.Infof("listening on %s", addr)
+			go ingester.Run(ctx)
+		},
+		OnStopping: func() {
+			cancel()
+		},
+	})
+}
+
+func setDefaults(cfg *Config) {
+	if cfg.MaxPathLength == 0 {
+		cfg.MaxPathLength = 3
+	}
+	if cfg.MaxAssetsParamLength == 0 {
+		cfg.MaxAssetsParamLength = 15
+	}
+	if cfg.SnapshotInterval == 0 {
+		cfg.SnapshotInterval = 1000
+	}
+}
+
+func initIngester(cfg Config) (*internal.Ingester, error) {
+	if len(cfg.HistoryArchiveURLs) == 0 {
+		return nil, errors.New("history_archive_urls must contain at least one url")
+	}
+	archive, err := historyarchive.NewArchivePool(cfg.HistoryArchiveURLs, historyarchive.ConnectOptions{
+		NetworkPassphrase: cfg.NetworkPassphrase,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to history archives")
+	}
+
+	var backend ledgerbackend.LedgerBackend
+	switch {
+	case cfg.RemoteCaptiveCoreURL != "":
+		backend, err = ledgerbackend.NewRemoteCaptive(cfg.RemoteCaptiveCoreURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create remote captive core client")
+		}
+	case cfg.CaptiveCoreBinaryPath != "":
+		params := ledgerbackend.CaptiveCoreTomlParams{
+			NetworkPassphrase:  cfg.NetworkPassphrase,
+			HistoryArchiveURLs: cfg.HistoryArchiveURLs,
+		}
+		var toml *ledgerbackend.CaptiveCoreToml
+		if cfg.CaptiveCoreConfigPath != "" {
+			toml, err = ledgerbackend.NewCaptiveCoreTomlFromFile(cfg.CaptiveCoreConfigPath, params)
+		} else {
+			toml, err = ledgerbackend.NewCaptiveCoreToml(params)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create captive core config")
+		}
+		backend, err = ledgerbackend.NewCaptive(ledgerbackend.CaptiveCoreConfig{
+			BinaryPath:         cfg.CaptiveCoreBinaryPath,
+			NetworkPassphrase:  cfg.NetworkPassphrase,
+			HistoryArchiveURLs: cfg.HistoryArchiveURLs,
+			Toml:               toml,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create captive core")
+		}
+	default:
+		return nil, errors.New("either captive_core_binary_path or remote_captive_core_url is required")
+	}
+
+	return internal.NewIngester(replay.Config{
+		HistoryArchive:    archive,
+		LedgerBackend:     backend,
+		NetworkPassphrase: cfg.NetworkPassphrase,
+		SnapshotDir:       cfg.SnapshotDir,
+		SnapshotInterval:  cfg.SnapshotInterval,
+	})
+}
+
+func initRouter(cfg Config, ingester *internal.Ingester) *chi.Mux {
+	mux := http.NewAPIMux(log.DefaultLogger)
+
+	handler := internal.PathsHandler{
+		Graph:                ingester.Graph(),
+		MaxPathLength:        cfg.MaxPathLength,
+		MaxAssetsParamLength: cfg.MaxAssetsParamLength,
+		IncludePools:         !cfg.DisablePoolPathFinding,
+	}
+	mux.Get("/health", handler.Health)
+	mux.Get("/paths/strict-receive", handler.StrictReceive)
+	mux.Get("/paths/strict-send", handler.StrictSend)
+	mux.Get("/paths/split", handler.Split)
+	mux.NotFound(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		problem.Render(r.Context(), w, problem.NotFound)
+	}))
+
+	return mux
+}
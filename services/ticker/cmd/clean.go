@@ -50,5 +50,9 @@ var cmdCleanTrades = &cobra.Command{
 		if err != nil {
 			Logger.Fatal("could not delete trade entries:", err)
 		}
+		err = session.DeleteOldIngestedLedgers(context.Background(), minDate)
+		if err != nil {
+			Logger.Fatal("could not delete ingested ledger entries:", err)
+		}
 	},
 }
@@ -2,15 +2,26 @@ package cmd
 
 import (
 	"context"
+	"errors"
 
 	"github.com/lib/pq"
 	"github.com/spf13/cobra"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
+	"github.com/diamcircle/go/network"
 	ticker "github.com/diamcircle/go/services/ticker/internal"
 	"github.com/diamcircle/go/services/ticker/internal/tickerdb"
 )
 
 var ShouldStream bool
 var BackfillHours int
+var FromLedgers bool
+var StartLedger uint32
+var EndLedger uint32
+var FillGaps bool
+var CaptiveCoreBinaryPath string
+var CaptiveCoreConfigPath string
+var RemoteCaptiveCoreURL string
+var HistoryArchiveURLs []string
 
 func init() {
 	rootCmd.AddCommand(cmdIngest)
@@ -22,7 +33,7 @@ func init() {
 		&ShouldStream,
 		"stream",
 		false,
-		"Continuously stream new trades from the Aurora Stream API as a daemon",
+		"Continuously stream new trades from the Aurora Stream API, or new ledgers with --from-ledgers, as a daemon",
 	)
 
 	cmdIngestTrades.Flags().IntVar(
@@ -31,6 +42,62 @@ func init() {
 		7*24,
 		"Number of past hours to backfill trade data",
 	)
+
+	cmdIngestTrades.Flags().BoolVar(
+		&FromLedgers,
+		"from-ledgers",
+		false,
+		"Ingest trades directly from ledgers, using captive diamcircle-core, instead of Aurora",
+	)
+
+	cmdIngestTrades.Flags().Uint32Var(
+		&StartLedger,
+		"start-ledger",
+		0,
+		"[with --from-ledgers] First ledger to ingest. When --stream is set and no ledger has been ingested yet, streaming starts at this ledger",
+	)
+
+	cmdIngestTrades.Flags().Uint32Var(
+		&EndLedger,
+		"end-ledger",
+		0,
+		"[with --from-ledgers] Last ledger to ingest, to backfill the range of ledgers from --start-ledger to --end-ledger",
+	)
+
+	cmdIngestTrades.Flags().BoolVar(
+		&FillGaps,
+		"fill-gaps",
+		false,
+		"[with --from-ledgers] Backfill the ledgers missing between the oldest and the newest ingested ledgers",
+	)
+
+	cmdIngestTrades.Flags().StringVar(
+		&CaptiveCoreBinaryPath,
+		"captive-core-binary-path",
+		"",
+		"[with --from-ledgers] Path to the diamcircle-core binary run as captive core",
+	)
+
+	cmdIngestTrades.Flags().StringVar(
+		&CaptiveCoreConfigPath,
+		"captive-core-config-path",
+		"",
+		"[with --from-ledgers] Path to an optional captive core configuration file",
+	)
+
+	cmdIngestTrades.Flags().StringVar(
+		&RemoteCaptiveCoreURL,
+		"remote-captive-core-url",
+		"",
+		"[with --from-ledgers] URL of a remote captive core server, used instead of running captive core",
+	)
+
+	cmdIngestTrades.Flags().StringSliceVar(
+		&HistoryArchiveURLs,
+		"history-archive-urls",
+		nil,
+		"[with --from-ledgers] Comma separated history archive URLs used by captive core",
+	)
 }
 
 var cmdIngest = &cobra.Command{
@@ -64,7 +131,7 @@ var cmdIngestAssets = &cobra.Command{
 
 var cmdIngestTrades = &cobra.Command{
 	Use:   "trades",
-	Short: "Fills the trade database with data retrieved form Aurora.",
+	Short: "Fills the trade database with data retrieved form Aurora or directly from ledgers.",
 	Run: func(cmd *cobra.Command, args []string) {
 		dbInfo, err := pq.ParseURL(DatabaseURL)
 		if err != nil {
@@ -78,6 +145,11 @@ var cmdIngestTrades = &cobra.Command{
 		defer session.DB.Close()
 
 		ctx := context.Background()
+		if FromLedgers {
+			ingestLedgerTrades(ctx, &session)
+			return
+		}
+
 		numDays := float32(BackfillHours) / 24.0
 		Logger.Infof(
 			"Backfilling Trade data for the past %d hour(s) [%.2f days]\n",
@@ -121,3 +193,96 @@ var cmdIngestOrderbooks = &cobra.Command{
 		}
 	},
 }
+
+// ingestLedgerTrades runs the trades ingestion from ledgers: the range of
+// ledgers given by --start-ledger and --end-ledger is ingested first, then the
+// gaps are backfilled and finally new ledgers are streamed, as requested.
+func ingestLedgerTrades(ctx context.Context, session *tickerdb.TickerSession) {
+	backend, err := newLedgerBackend()
+	if err != nil {
+		Logger.Fatal("could not create ledger backend:", err)
+	}
+	defer backend.Close()
+
+	networkPassphrase := getNetworkPassphrase()
+
+	if EndLedger != 0 {
+		if StartLedger == 0 || StartLedger > EndLedger {
+			Logger.Fatal("--start-ledger must be set and not be greater than --end-ledger")
+		}
+		err = ticker.IngestLedgerTrades(ctx, session, backend, networkPassphrase, Logger, StartLedger, EndLedger)
+		if err != nil {
+			Logger.Fatal("could not ingest ledger range:", err)
+		}
+	}
+
+	if FillGaps {
+		err = ticker.BackfillLedgerGaps(ctx, session, backend, networkPassphrase, Logger)
+		if err != nil {
+			Logger.Fatal("could not backfill ledger gaps:", err)
+		}
+	}
+
+	if ShouldStream {
+		latest, err := session.GetLatestIngestedLedger(ctx)
+		if err != nil {
+			Logger.Fatal("could not get latest ingested ledger:", err)
+		}
+		from := latest + 1
+		if latest == 0 {
+			if StartLedger == 0 {
+				Logger.Fatal("no ledger has been ingested yet, --start-ledger is required")
+			}
+			from = StartLedger
+		}
+
+		Logger.Info("Streaming new ledgers (this is a continuous process)")
+		err = ticker.IngestLedgerTrades(ctx, session, backend, networkPassphrase, Logger, from, 0)
+		if err != nil {
+			Logger.Fatal("could not ingest new ledgers:", err)
+		}
+	}
+}
+
+// newLedgerBackend returns a remote captive core client if
+// --remote-captive-core-url is set, or runs captive core otherwise.
+func newLedgerBackend() (ledgerbackend.LedgerBackend, error) {
+	if RemoteCaptiveCoreURL != "" {
+		return ledgerbackend.NewRemoteCaptive(RemoteCaptiveCoreURL)
+	}
+	if CaptiveCoreBinaryPath == "" || len(HistoryArchiveURLs) == 0 {
+		return nil, errors.New(
+			"either --remote-captive-core-url or both --captive-core-binary-path and --history-archive-urls are required",
+		)
+	}
+
+	networkPassphrase := getNetworkPassphrase()
+	params := ledgerbackend.CaptiveCoreTomlParams{
+		NetworkPassphrase:  networkPassphrase,
+		HistoryArchiveURLs: HistoryArchiveURLs,
+	}
+	var toml *ledgerbackend.CaptiveCoreToml
+	var err error
+	if CaptiveCoreConfigPath != "" {
+		toml, err = ledgerbackend.NewCaptiveCoreTomlFromFile(CaptiveCoreConfigPath, params)
+	} else {
+		toml, err = ledgerbackend.NewCaptiveCoreToml(params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ledgerbackend.NewCaptive(ledgerbackend.CaptiveCoreConfig{
+		BinaryPath:         CaptiveCoreBinaryPath,
+		NetworkPassphrase:  networkPassphrase,
+		HistoryArchiveURLs: HistoryArchiveURLs,
+		Toml:               toml,
+	})
+}
+
+func getNetworkPassphrase() string {
+	if UseTestNet {
+		return network.TestNetworkPassphrase
+	}
+	return network.PublicNetworkPassphrase
+}
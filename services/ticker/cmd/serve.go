@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/lib/pq"
 	"github.com/spf13/cobra"
 	ticker "github.com/diamcircle/go/services/ticker/internal"
@@ -8,6 +10,7 @@ import (
 )
 
 var ServerAddr string
+var CacheTTL time.Duration
 
 func init() {
 	rootCmd.AddCommand(cmdServe)
@@ -18,11 +21,17 @@ func init() {
 		"0.0.0.0:3000",
 		"Server address and port",
 	)
+	cmdServe.Flags().DurationVar(
+		&CacheTTL,
+		"cache-ttl",
+		30*time.Second,
+		"How long the responses of the exchange API endpoints are cached (0 disables caching)",
+	)
 }
 
 var cmdServe = &cobra.Command{
 	Use:   "serve",
-	Short: "Runs a GraphQL interface to get Ticker data",
+	Short: "Runs a GraphQL interface and CoinGecko/CoinMarketCap compatible endpoints to get Ticker data",
 	Run: func(cmd *cobra.Command, args []string) {
 		Logger.Info("Starting GraphQL Server")
 		dbInfo, err := pq.ParseURL(DatabaseURL)
@@ -36,6 +45,6 @@ var cmdServe = &cobra.Command{
 		}
 		defer session.DB.Close()
 
-		ticker.StartGraphQLServer(&session, Logger, ServerAddr)
+		ticker.StartGraphQLServer(&session, Logger, ServerAddr, CacheTTL)
 	},
 }
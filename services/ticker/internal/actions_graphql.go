@@ -1,13 +1,19 @@
 package ticker
 
 import (
+	"time"
+
+	"github.com/diamcircle/go/services/ticker/internal/exchangeapi"
 	"github.com/diamcircle/go/services/ticker/internal/gql"
 	"github.com/diamcircle/go/services/ticker/internal/tickerdb"
 	hlog "github.com/diamcircle/go/support/log"
 )
 
-func StartGraphQLServer(s *tickerdb.TickerSession, l *hlog.Entry, port string) {
+// StartGraphQLServer serves the GraphQL interface along with the exchange API
+// endpoints, whose responses are cached for cacheTTL.
+func StartGraphQLServer(s *tickerdb.TickerSession, l *hlog.Entry, port string, cacheTTL time.Duration) {
 	graphql := gql.New(s, l)
+	exchangeAPI := exchangeapi.New(s, l, cacheTTL)
 
-	graphql.Serve(port)
+	graphql.Serve(port, exchangeAPI.Handlers())
 }
@@ -2,6 +2,7 @@ package ticker
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	auroraclient "github.com/diamcircle/go/clients/auroraclient"
@@ -40,7 +41,11 @@ func RefreshOrderbookEntries(s *tickerdb.TickerSession, c *auroraclient.Client,
 			continue
 		}
 
-		dbOS := orderbookStatsToDBOrderbookStats(ob, mkt.BaseAssetID, mkt.CounterAssetID)
+		dbOS, err := orderbookStatsToDBOrderbookStats(ob, mkt.BaseAssetID, mkt.CounterAssetID)
+		if err != nil {
+			l.Error(errors.Wrap(err, "could not convert orderbook stats"))
+			continue
+		}
 		err = s.InsertOrUpdateOrderbookStats(ctx, &dbOS, []string{"base_asset_id", "counter_asset_id"})
 		if err != nil {
 			l.Error(errors.Wrap(err, "could not insert orderbook stats into db"))
@@ -50,7 +55,16 @@ func RefreshOrderbookEntries(s *tickerdb.TickerSession, c *auroraclient.Client,
 	return nil
 }
 
-func orderbookStatsToDBOrderbookStats(os scraper.OrderbookStats, bID, cID int32) tickerdb.OrderbookStats {
+func orderbookStatsToDBOrderbookStats(os scraper.OrderbookStats, bID, cID int32) (tickerdb.OrderbookStats, error) {
+	bids, err := encodeOrderbookLevels(os.Bids)
+	if err != nil {
+		return tickerdb.OrderbookStats{}, err
+	}
+	asks, err := encodeOrderbookLevels(os.Asks)
+	if err != nil {
+		return tickerdb.OrderbookStats{}, err
+	}
+
 	return tickerdb.OrderbookStats{
 		BaseAssetID:    bID,
 		CounterAssetID: cID,
@@ -63,5 +77,17 @@ func orderbookStatsToDBOrderbookStats(os scraper.OrderbookStats, bID, cID int32)
 		Spread:         os.Spread,
 		SpreadMidPoint: os.SpreadMidPoint,
 		UpdatedAt:      time.Now(),
+		Bids:           bids,
+		Asks:           asks,
+	}, nil
+}
+
+// encodeOrderbookLevels encodes orderbook price levels in the JSON format
+// they are stored in
+func encodeOrderbookLevels(levels [][2]float64) (string, error) {
+	if len(levels) == 0 {
+		return "[]", nil
 	}
+	encoded, err := json.Marshal(levels)
+	return string(encoded), err
 }
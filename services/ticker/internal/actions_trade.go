@@ -9,10 +9,12 @@ import (
 	"time"
 
 	auroraclient "github.com/diamcircle/go/clients/auroraclient"
+	"github.com/diamcircle/go/ingest/ledgerbackend"
 	hProtocol "github.com/diamcircle/go/protocols/aurora"
 	"github.com/diamcircle/go/services/ticker/internal/scraper"
 	"github.com/diamcircle/go/services/ticker/internal/tickerdb"
 	hlog "github.com/diamcircle/go/support/log"
+	"github.com/diamcircle/go/xdr"
 )
 
 // StreamTrades constantly streams and ingests new trades directly from aurora.
@@ -105,6 +107,123 @@ func BackfillTrades(
 	return nil
 }
 
+// IngestLedgerTrades ingests the trades of the ledgers from startLedger to
+// endLedger directly from a ledger backend into the database. If endLedger is
+// 0, new ledgers are ingested as they close until the context is cancelled.
+func IngestLedgerTrades(
+	ctx context.Context,
+	s *tickerdb.TickerSession,
+	backend ledgerbackend.LedgerBackend,
+	networkPassphrase string,
+	l *hlog.Entry,
+	startLedger uint32,
+	endLedger uint32,
+) error {
+	ledgerRange := ledgerbackend.UnboundedRange(startLedger)
+	if endLedger != 0 {
+		ledgerRange = ledgerbackend.BoundedRange(startLedger, endLedger)
+	}
+	l.Infof("Ingesting trades of ledgers %s\n", ledgerRange)
+	if err := backend.PrepareRange(ctx, ledgerRange); err != nil {
+		return fmt.Errorf("could not prepare range %s: %w", ledgerRange, err)
+	}
+
+	for sequence := startLedger; endLedger == 0 || sequence <= endLedger; sequence++ {
+		ledger, err := backend.GetLedger(ctx, sequence)
+		if err != nil {
+			return fmt.Errorf("could not get ledger %d: %w", sequence, err)
+		}
+		if err = ingestLedgerTrades(ctx, s, networkPassphrase, l, ledger); err != nil {
+			return fmt.Errorf("could not ingest trades of ledger %d: %w", sequence, err)
+		}
+	}
+	return nil
+}
+
+// BackfillLedgerGaps ingests the trades of the ledgers missing between the
+// oldest and the newest ingested ledgers.
+func BackfillLedgerGaps(
+	ctx context.Context,
+	s *tickerdb.TickerSession,
+	backend ledgerbackend.LedgerBackend,
+	networkPassphrase string,
+	l *hlog.Entry,
+) error {
+	gaps, err := s.GetIngestedLedgerGaps(ctx)
+	if err != nil {
+		return err
+	}
+	l.Infof("Found %d gap(s) in the ingested ledgers\n", len(gaps))
+
+	for _, gap := range gaps {
+		err = IngestLedgerTrades(ctx, s, backend, networkPassphrase, l, gap.StartSequence, gap.EndSequence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ingestLedgerTrades inserts the trades of a ledger in the database and
+// records the ledger as ingested, in a single transaction. Trades of assets
+// which aren't in the database are skipped, as when ingesting from Aurora.
+func ingestLedgerTrades(
+	ctx context.Context,
+	s *tickerdb.TickerSession,
+	networkPassphrase string,
+	l *hlog.Entry,
+	ledger xdr.LedgerCloseMeta,
+) error {
+	trades, err := scraper.FetchLedgerTrades(networkPassphrase, ledger)
+	if err != nil {
+		return err
+	}
+
+	var dbTrades []tickerdb.Trade
+	for _, trade := range trades {
+		scraper.NormalizeTradeAssets(&trade)
+
+		var bID, cID int32
+		bID, cID, err = findBaseAndCounter(ctx, s, trade)
+		if err != nil {
+			continue
+		}
+
+		var dbTrade tickerdb.Trade
+		dbTrade, err = hProtocolTradeToDBTrade(trade, bID, cID)
+		if err != nil {
+			l.Error("Could not convert entry to DB Trade: ", err)
+			continue
+		}
+		dbTrades = append(dbTrades, dbTrade)
+	}
+
+	if err = s.Begin(); err != nil {
+		return err
+	}
+	defer s.Rollback()
+
+	if len(dbTrades) > 0 {
+		if err = s.BulkInsertTrades(ctx, dbTrades); err != nil {
+			return err
+		}
+	}
+	header := ledger.V0.LedgerHeader.Header
+	err = s.InsertIngestedLedger(ctx, tickerdb.IngestedLedger{
+		Sequence:  uint32(header.LedgerSeq),
+		CloseTime: time.Unix(int64(header.ScpValue.CloseTime), 0).UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.Commit(); err != nil {
+		return err
+	}
+
+	l.Debugf("Ingested %d of %d trade(s) of ledger %d\n", len(dbTrades), len(trades), header.LedgerSeq)
+	return nil
+}
+
 // findBaseAndCounter tries to find the Base and Counter assets IDs in the database,
 // and returns an error if it doesn't find any.
 func findBaseAndCounter(ctx context.Context, s *tickerdb.TickerSession, trade hProtocol.Trade) (bID int32, cID int32, err error) {
@@ -166,6 +285,14 @@ func hProtocolTradeToDBTrade(
 		CounterAssetID:  counterAssetID,
 		BaseIsSeller:    hpt.BaseIsSeller,
 		Price:           fPrice,
+		TradeType:       hpt.TradeType,
+		LiquidityPoolID: hpt.BaseLiquidityPoolID,
+	}
+	if trade.TradeType == "" {
+		trade.TradeType = "orderbook"
+	}
+	if trade.LiquidityPoolID == "" {
+		trade.LiquidityPoolID = hpt.CounterLiquidityPoolID
 	}
 
 	return
@@ -0,0 +1,87 @@
+package exchangeapi
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries is the maximum number of responses cached at any time.
+const maxCacheEntries = 1000
+
+// responseCache caches rendered responses for a fixed amount of time
+type responseCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// cacheKey returns the key a request is cached under. Only the given query
+// parameters, the ones the endpoint reads, are part of the key and they are
+// sorted, so that requests which only differ in parameter order or in unknown
+// parameters share the same key.
+func cacheKey(r *http.Request, params []string) string {
+	query := r.URL.Query()
+	recognized := url.Values{}
+	for _, param := range params {
+		if value := query.Get(param); value != "" {
+			recognized.Set(param, value)
+		}
+	}
+	return r.URL.Path + "?" + recognized.Encode()
+}
+
+// get returns the cached response for the given key, if it has not expired
+func (c *responseCache) get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// set caches a response for the given key and evicts the expired ones. When
+// the cache is full the entry expiring first is evicted too.
+func (c *responseCache) set(key string, body []byte) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		var oldest string
+		for k, entry := range c.entries {
+			if oldest == "" || entry.expiresAt.Before(c.entries[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = cacheEntry{body: body, expiresAt: now.Add(c.ttl)}
+}
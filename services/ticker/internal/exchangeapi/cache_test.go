@@ -0,0 +1,79 @@
+package exchangeapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	hlog "github.com/diamcircle/go/support/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheKey(t *testing.T) {
+	params := []string{"ticker_id", "depth"}
+	r1 := httptest.NewRequest("GET", "/orderbook?ticker_id=XLM_BTC&depth=10", nil)
+	r2 := httptest.NewRequest("GET", "/orderbook?depth=10&ticker_id=XLM_BTC", nil)
+	r3 := httptest.NewRequest("GET", "/orderbook?depth=20&ticker_id=XLM_BTC", nil)
+	r4 := httptest.NewRequest("GET", "/orderbook?ticker_id=XLM_BTC&depth=10&nonce=123", nil)
+	assert.Equal(t, cacheKey(r1, params), cacheKey(r2, params))
+	assert.NotEqual(t, cacheKey(r1, params), cacheKey(r3, params))
+	assert.Equal(t, cacheKey(r1, params), cacheKey(r4, params))
+}
+
+func TestResponseCache(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	s := &Server{logger: hlog.New(), cache: newResponseCache(30 * time.Second)}
+	s.cache.now = func() time.Time { return now }
+
+	calls := 0
+	h := s.handler(func(r *http.Request) (interface{}, error) {
+		calls++
+		if r.URL.Query().Get("fail") != "" {
+			return nil, requestError{http.StatusBadRequest, "invalid request"}
+		}
+		return map[string]int{"calls": calls}, nil
+	}, "fail", "id")
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+
+	w := get("/pairs")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"calls": 1}`, w.Body.String())
+
+	// Cached until the ttl expires:
+	now = now.Add(29 * time.Second)
+	assert.JSONEq(t, `{"calls": 1}`, get("/pairs").Body.String())
+	now = now.Add(time.Second)
+	assert.JSONEq(t, `{"calls": 2}`, get("/pairs").Body.String())
+
+	// Errors are not cached:
+	w = get("/pairs?fail=true")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"error": "invalid request"}`, w.Body.String())
+	get("/pairs?fail=true")
+	assert.Equal(t, 4, calls)
+
+	// Expired entries are evicted:
+	now = now.Add(time.Minute)
+	get("/tickers")
+	assert.Len(t, s.cache.entries, 1)
+
+	// The cache is bounded:
+	for i := 0; i < maxCacheEntries+10; i++ {
+		get(fmt.Sprintf("/tickers?id=%d", i))
+	}
+	assert.Len(t, s.cache.entries, maxCacheEntries)
+
+	// A ttl of 0 disables caching:
+	s.cache = newResponseCache(0)
+	get("/pairs")
+	assert.JSONEq(t, `{"calls": 1017}`, get("/pairs").Body.String())
+	assert.Empty(t, s.cache.entries)
+}
@@ -0,0 +1,325 @@
+package exchangeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diamcircle/go/services/ticker/internal/tickerdb"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTradesLimit = 100
+	maxTradesLimit     = 1000
+)
+
+// pairs lists the trade pairs which were active in the last 7 days.
+func (s *Server) pairs(r *http.Request) (interface{}, error) {
+	markets, err := s.db.RetrieveMarketData(r.Context())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve market data")
+	}
+
+	pairs := []pair{}
+	for _, mkt := range markets {
+		base, target, ok := splitTickerID(mkt.TradePair)
+		if !ok {
+			continue
+		}
+		pairs = append(pairs, pair{TickerID: mkt.TradePair, Base: base, Target: target})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].TickerID < pairs[j].TickerID })
+	return pairs, nil
+}
+
+// tickers returns the 24h market data of the trade pairs which were active in
+// the last 7 days.
+func (s *Server) tickers(r *http.Request) (interface{}, error) {
+	markets, err := s.db.RetrieveMarketData(r.Context())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve market data")
+	}
+
+	tickers := []ticker{}
+	for _, mkt := range markets {
+		t, ok := dbMarketToTicker(mkt)
+		if !ok {
+			continue
+		}
+		tickers = append(tickers, t)
+	}
+	sort.Slice(tickers, func(i, j int) bool { return tickers[i].TickerID < tickers[j].TickerID })
+	return tickers, nil
+}
+
+// orderbook returns the aggregated orderbook of a trade pair. The depth
+// parameter is the total number of levels returned, half of them on each
+// side, and 0 returns the full orderbook.
+func (s *Server) orderbook(r *http.Request) (interface{}, error) {
+	tickerID, err := tickerIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := intParam(r, "depth", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	obStats, err := s.db.RetrieveOrderbookStatsForPair(r.Context(), tickerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve orderbook stats")
+	}
+	if len(obStats) == 0 {
+		return nil, requestError{http.StatusNotFound, "ticker_id not found"}
+	}
+
+	var bids, asks [][2]float64
+	var updatedAt time.Time
+	for _, os := range obStats {
+		osBids, err := decodeOrderbookLevels(os.Bids)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode bids")
+		}
+		osAsks, err := decodeOrderbookLevels(os.Asks)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode asks")
+		}
+		bids = append(bids, osBids...)
+		asks = append(asks, osAsks...)
+		if os.UpdatedAt.After(updatedAt) {
+			updatedAt = os.UpdatedAt
+		}
+	}
+
+	sideDepth := depth / 2
+	if depth == 1 {
+		sideDepth = 1
+	}
+
+	return orderbook{
+		TickerID:  tickerID,
+		Timestamp: unixMilli(updatedAt),
+		Bids:      formatLevels(mergeLevels(bids, true), sideDepth),
+		Asks:      formatLevels(mergeLevels(asks, false), sideDepth),
+	}, nil
+}
+
+// historicalTrades returns the trades of a trade pair, newest first. Trades
+// are paged with the before parameter, which takes the trade_id of the
+// oldest trade of the previous page.
+func (s *Server) historicalTrades(r *http.Request) (interface{}, error) {
+	tickerID, err := tickerIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := intParam(r, "limit", defaultTradesLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 || limit > maxTradesLimit {
+		limit = maxTradesLimit
+	}
+	before, err := intParam(r, "before", 0)
+	if err != nil {
+		return nil, err
+	}
+	since, err := timeParam(r, "start_time")
+	if err != nil {
+		return nil, err
+	}
+	until, err := timeParam(r, "end_time")
+	if err != nil {
+		return nil, err
+	}
+
+	var baseIsSeller *bool
+	switch tradeType := r.URL.Query().Get("type"); tradeType {
+	case "":
+	case "buy", "sell":
+		baseIsSeller = new(bool)
+		*baseIsSeller = tradeType == "buy"
+	default:
+		return nil, requestError{http.StatusBadRequest, "type must be either buy or sell"}
+	}
+
+	dbTrades, err := s.db.RetrieveHistoricalTrades(r.Context(),
+		tickerID,
+		int32(before),
+		since,
+		until,
+		baseIsSeller,
+		limit,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve trades")
+	}
+
+	response := historicalTrades{Buy: []trade{}, Sell: []trade{}}
+	for _, dbTrade := range dbTrades {
+		t := dbTradeToTrade(dbTrade)
+		if t.Type == "buy" {
+			response.Buy = append(response.Buy, t)
+		} else {
+			response.Sell = append(response.Sell, t)
+		}
+	}
+	return response, nil
+}
+
+// dbMarketToTicker converts a tickerdb.Market into a ticker. Trade prices are
+// stored in units of base per unit of counter, so they are inverted, while
+// the orderbook prices already are in units of counter per unit of base.
+func dbMarketToTicker(mkt tickerdb.Market) (ticker, bool) {
+	base, target, ok := splitTickerID(mkt.TradePair)
+	if !ok {
+		return ticker{}, false
+	}
+
+	return ticker{
+		TickerID:       mkt.TradePair,
+		BaseCurrency:   base,
+		TargetCurrency: target,
+		LastPrice:      invert(mkt.LastPrice),
+		BaseVolume:     mkt.BaseVolume24h,
+		TargetVolume:   mkt.CounterVolume24h,
+		Bid:            mkt.HighestBid,
+		Ask:            mkt.LowestAsk,
+		High:           invert(mkt.LowestPrice24h),
+		Low:            invert(mkt.HighestPrice24h),
+	}, true
+}
+
+// dbTradeToTrade converts a tickerdb.Trade into a trade. A trade is a buy if
+// the counter account, which took the offer, bought the base asset.
+func dbTradeToTrade(t tickerdb.Trade) trade {
+	tradeType := "sell"
+	if t.BaseIsSeller {
+		tradeType = "buy"
+	}
+
+	var price float64
+	if t.BaseAmount > 0 {
+		price = t.CounterAmount / t.BaseAmount
+	}
+
+	return trade{
+		TradeID:        t.ID,
+		Price:          price,
+		BaseVolume:     t.BaseAmount,
+		TargetVolume:   t.CounterAmount,
+		TradeTimestamp: unixMilli(t.LedgerCloseTime),
+		Type:           tradeType,
+	}
+}
+
+// decodeOrderbookLevels decodes the orderbook levels stored in the database
+func decodeOrderbookLevels(encoded string) (levels [][2]float64, err error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	err = json.Unmarshal([]byte(encoded), &levels)
+	return
+}
+
+// mergeLevels sorts orderbook levels by price, best price first, and merges
+// the levels with the same price.
+func mergeLevels(levels [][2]float64, descending bool) [][2]float64 {
+	sort.SliceStable(levels, func(i, j int) bool {
+		if descending {
+			return levels[i][0] > levels[j][0]
+		}
+		return levels[i][0] < levels[j][0]
+	})
+
+	var merged [][2]float64
+	for _, level := range levels {
+		if len(merged) > 0 && merged[len(merged)-1][0] == level[0] {
+			merged[len(merged)-1][1] += level[1]
+			continue
+		}
+		merged = append(merged, level)
+	}
+	return merged
+}
+
+// formatLevels formats at most limit orderbook levels, or all of them if
+// limit is 0
+func formatLevels(levels [][2]float64, limit int) [][2]string {
+	if limit > 0 && len(levels) > limit {
+		levels = levels[:limit]
+	}
+
+	formatted := [][2]string{}
+	for _, level := range levels {
+		formatted = append(formatted, [2]string{
+			strconv.FormatFloat(level[0], 'f', -1, 64),
+			strconv.FormatFloat(level[1], 'f', 7, 64),
+		})
+	}
+	return formatted
+}
+
+// splitTickerID returns the base and target asset codes of a ticker id
+// (e.g.: XLM_BTC)
+func splitTickerID(tickerID string) (string, string, bool) {
+	codes := strings.Split(tickerID, "_")
+	if len(codes) != 2 || codes[0] == "" || codes[1] == "" {
+		return "", "", false
+	}
+	return codes[0], codes[1], true
+}
+
+// invert returns 1/price, or 0 if price is 0
+func invert(price float64) float64 {
+	if price == 0 {
+		return 0
+	}
+	return 1 / price
+}
+
+// unixMilli returns the unix timestamp of t in milliseconds
+func unixMilli(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+}
+
+func tickerIDParam(r *http.Request) (string, error) {
+	tickerID := r.URL.Query().Get("ticker_id")
+	if _, _, ok := splitTickerID(tickerID); !ok {
+		return "", requestError{http.StatusBadRequest, "ticker_id must be a trade pair such as XLM_BTC"}
+	}
+	return tickerID, nil
+}
+
+// intParam parses a non-negative integer query parameter
+func intParam(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || i < 0 {
+		return 0, requestError{http.StatusBadRequest, name + " must be a non-negative integer"}
+	}
+	return int(i), nil
+}
+
+// timeParam parses a query parameter holding a unix timestamp in
+// milliseconds
+func timeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms < 0 {
+		return nil, requestError{http.StatusBadRequest, name + " must be a unix timestamp in milliseconds"}
+	}
+	t := time.Unix(0, ms*int64(time.Millisecond))
+	return &t, nil
+}
@@ -0,0 +1,103 @@
+package exchangeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diamcircle/go/services/ticker/internal/tickerdb/tickerdbtest"
+	hlog "github.com/diamcircle/go/support/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeAPI(t *testing.T) {
+	session := tickerdbtest.SetupTickerTestSession(t, "../tickerdb/migrations")
+	defer session.DB.Close()
+
+	handlers := New(&session, hlog.New(), 0).Handlers()
+	get := func(path, query string, response interface{}) int {
+		w := httptest.NewRecorder()
+		handlers[path].ServeHTTP(w, httptest.NewRequest("GET", path+"?"+query, nil))
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), response))
+		return w.Code
+	}
+
+	var pairs []pair
+	require.Equal(t, http.StatusOK, get("/pairs", "", &pairs))
+	assert.Equal(t, []pair{
+		{TickerID: "BTC_ETH", Base: "BTC", Target: "ETH"},
+		{TickerID: "XLM_BTC", Base: "XLM", Target: "BTC"},
+	}, pairs)
+
+	var tickers []ticker
+	require.Equal(t, http.StatusOK, get("/tickers", "", &tickers))
+	require.Len(t, tickers, 2)
+	assert.Equal(t, ticker{
+		TickerID:       "BTC_ETH",
+		BaseCurrency:   "BTC",
+		TargetCurrency: "ETH",
+		LastPrice:      1 / 0.92,
+		BaseVolume:     174,
+		TargetVolume:   86,
+		Bid:            200,
+		Ask:            0.1,
+		High:           10,
+		Low:            1,
+	}, tickers[0])
+
+	var ob orderbook
+	require.Equal(t, http.StatusOK, get("/orderbook", "ticker_id=BTC_ETH&depth=10", &ob))
+	assert.Equal(t, "BTC_ETH", ob.TickerID)
+	assert.Empty(t, ob.Bids)
+	assert.Empty(t, ob.Asks)
+
+	var errResponse map[string]string
+	assert.Equal(t, http.StatusNotFound, get("/orderbook", "ticker_id=ETH_XLM", &errResponse))
+	assert.Equal(t, "ticker_id not found", errResponse["error"])
+	assert.Equal(t, http.StatusBadRequest, get("/orderbook", "ticker_id=XLM", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get("/orderbook", "ticker_id=XLM_BTC&depth=-1", &errResponse))
+
+	// The BTC_ETH trades are, newest first, hrzid3, hrzid1, hrzid2 and hrzid4.
+	var trades historicalTrades
+	require.Equal(t, http.StatusOK, get("/historical_trades", "ticker_id=BTC_ETH&limit=2", &trades))
+	assert.Empty(t, trades.Buy)
+	require.Len(t, trades.Sell, 2)
+	assert.Equal(t, 24.0, trades.Sell[0].BaseVolume)
+	assert.Equal(t, 26.0/24.0, trades.Sell[0].Price)
+	assert.Equal(t, "sell", trades.Sell[0].Type)
+	assert.Equal(t, 100.0, trades.Sell[1].BaseVolume)
+
+	query := fmt.Sprintf("ticker_id=BTC_ETH&limit=2&before=%d", trades.Sell[1].TradeID)
+	require.Equal(t, http.StatusOK, get("/historical_trades", query, &trades))
+	require.Len(t, trades.Sell, 2)
+	assert.Equal(t, 50.0, trades.Sell[0].BaseVolume)
+	assert.Equal(t, 1.0, trades.Sell[0].Price)
+	assert.Equal(t, 6.0, trades.Sell[1].TargetVolume)
+
+	require.Equal(t, http.StatusOK, get("/historical_trades", "ticker_id=BTC_ETH&type=buy", &trades))
+	assert.Empty(t, trades.Buy)
+	assert.Empty(t, trades.Sell)
+	assert.Equal(t, http.StatusBadRequest, get("/historical_trades", "ticker_id=BTC_ETH&type=swap", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get("/historical_trades", "ticker_id=BTC_ETH&start_time=yesterday", &errResponse))
+}
+
+func TestOrderbookLevels(t *testing.T) {
+	bids := mergeLevels([][2]float64{{1, 10}, {3, 5}, {2, 1}, {3, 2.5}}, true)
+	assert.Equal(t, [][2]float64{{3, 7.5}, {2, 1}, {1, 10}}, bids)
+	asks := mergeLevels([][2]float64{{4, 1}, {3.5, 2}, {4, 1}}, false)
+	assert.Equal(t, [][2]float64{{3.5, 2}, {4, 2}}, asks)
+
+	assert.Equal(t, [][2]string{{"3", "7.5000000"}}, formatLevels(bids, 1))
+	assert.Len(t, formatLevels(bids, 0), 3)
+	assert.Equal(t, [][2]string{}, formatLevels(nil, 10))
+
+	levels, err := decodeOrderbookLevels("")
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+	levels, err = decodeOrderbookLevels("[[0.5,100]]")
+	require.NoError(t, err)
+	assert.Equal(t, [][2]float64{{0.5, 100}}, levels)
+}
@@ -0,0 +1,152 @@
+// Package exchangeapi implements the CoinGecko and CoinMarketCap compatible
+// exchange endpoints of the ticker (/pairs, /tickers, /orderbook and
+// /historical_trades), built on top of the ticker database.
+package exchangeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/diamcircle/go/services/ticker/internal/tickerdb"
+	hlog "github.com/diamcircle/go/support/log"
+)
+
+// pair represents a trade pair in the /pairs response
+type pair struct {
+	TickerID string `json:"ticker_id"`
+	Base     string `json:"base"`
+	Target   string `json:"target"`
+}
+
+// ticker represents the 24h market data of a trade pair in the /tickers
+// response. Prices are in units of target per unit of base.
+type ticker struct {
+	TickerID       string  `json:"ticker_id"`
+	BaseCurrency   string  `json:"base_currency"`
+	TargetCurrency string  `json:"target_currency"`
+	LastPrice      float64 `json:"last_price"`
+	BaseVolume     float64 `json:"base_volume"`
+	TargetVolume   float64 `json:"target_volume"`
+	Bid            float64 `json:"bid"`
+	Ask            float64 `json:"ask"`
+	High           float64 `json:"high"`
+	Low            float64 `json:"low"`
+}
+
+// orderbook represents the /orderbook response. Each level is a
+// [price, amount] pair, with the amount in units of base.
+type orderbook struct {
+	TickerID  string      `json:"ticker_id"`
+	Timestamp int64       `json:"timestamp"`
+	Bids      [][2]string `json:"bids"`
+	Asks      [][2]string `json:"asks"`
+}
+
+// trade represents a trade in the /historical_trades response
+type trade struct {
+	TradeID        int32   `json:"trade_id"`
+	Price          float64 `json:"price"`
+	BaseVolume     float64 `json:"base_volume"`
+	TargetVolume   float64 `json:"target_volume"`
+	TradeTimestamp int64   `json:"trade_timestamp"`
+	Type           string  `json:"type"`
+}
+
+// historicalTrades represents the /historical_trades response
+type historicalTrades struct {
+	Buy  []trade `json:"buy"`
+	Sell []trade `json:"sell"`
+}
+
+// requestError is an error caused by an invalid request, which is reported
+// to the client with the given status code
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e requestError) Error() string {
+	return e.message
+}
+
+// endpoint computes the response of an exchange API endpoint
+type endpoint func(r *http.Request) (interface{}, error)
+
+// Server serves the exchange API endpoints, caching their responses.
+type Server struct {
+	db     *tickerdb.TickerSession
+	logger *hlog.Entry
+	cache  *responseCache
+}
+
+// New creates a new exchange API server. Responses are cached for cacheTTL,
+// a cacheTTL of 0 disables caching.
+func New(s *tickerdb.TickerSession, l *hlog.Entry, cacheTTL time.Duration) *Server {
+	if s == nil {
+		panic("A valid database session must be provided for the exchange API")
+	}
+	return &Server{db: s, logger: l, cache: newResponseCache(cacheTTL)}
+}
+
+// Handlers returns the handlers of the exchange API endpoints, keyed by path.
+func (s *Server) Handlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/pairs":     s.handler(s.pairs),
+		"/tickers":   s.handler(s.tickers),
+		"/orderbook": s.handler(s.orderbook, "ticker_id", "depth"),
+		"/historical_trades": s.handler(
+			s.historicalTrades, "ticker_id", "type", "limit", "before", "start_time", "end_time",
+		),
+	}
+}
+
+// handler wraps an endpoint in an http.Handler which renders its response
+// as JSON and caches it by path and by the given query parameters, which
+// must be all the parameters the endpoint reads.
+func (s *Server) handler(e endpoint, params ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.logger.Infof("%s %s %s\n", r.RemoteAddr, r.Method, r.URL)
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		key := cacheKey(r, params)
+		if body, ok := s.cache.get(key); ok {
+			w.Write(body)
+			return
+		}
+
+		response, err := e(r)
+		if err != nil {
+			if reqErr, ok := err.(requestError); ok {
+				writeError(w, reqErr.status, reqErr.message)
+				return
+			}
+			s.logger.Error(err)
+			// obfuscating sql errors to avoid exposing underlying
+			// implementation
+			writeError(w, http.StatusInternalServerError, "could not retrieve the requested data")
+			return
+		}
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			s.logger.Error(err)
+			writeError(w, http.StatusInternalServerError, "could not render the requested data")
+			return
+		}
+		s.cache.set(key, body)
+		w.Write(body)
+	})
+}
+
+// writeError writes a JSON error response with the given status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	w.WriteHeader(status)
+	w.Write(body)
+}
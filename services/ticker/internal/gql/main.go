@@ -90,8 +90,9 @@ func New(s *tickerdb.TickerSession, l *hlog.Entry) *resolver {
 	return &resolver{db: s, logger: l}
 }
 
-// Serve creates a GraphQL interface on <address>/graphql and a GraphiQL explorer on /graphiql
-func (r *resolver) Serve(address string) {
+// Serve creates a GraphQL interface on <address>/graphql and a GraphiQL explorer on /graphiql,
+// along with the additional handlers, keyed by path
+func (r *resolver) Serve(address string, handlers map[string]http.Handler) {
 	relayHandler := r.NewRelayHandler()
 	mux := http.NewServeMux()
 	mux.Handle("/graphql", http.HandlerFunc(func(wr http.ResponseWriter, re *http.Request) {
@@ -99,6 +100,9 @@ func (r *resolver) Serve(address string) {
 		relayHandler.ServeHTTP(wr, re)
 	}))
 	mux.Handle("/graphiql", GraphiQL{})
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
 
 	server := &http.Server{
 		Addr:        address,
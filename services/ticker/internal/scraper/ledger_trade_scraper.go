@@ -0,0 +1,189 @@
+package scraper
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/diamcircle/go/amount"
+	"github.com/diamcircle/go/ingest"
+	hProtocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// counterOfferIDTOIDType is the type Aurora encodes in the two highest bits of
+// synthetic counter offer ids, which are derived from the operation id when
+// the buyer did not create an offer.
+const counterOfferIDTOIDType = 1
+
+// FetchLedgerTrades extracts the trades, including liquidity pool trades, of
+// the successful transactions in the given ledger. The trades are identical
+// to the ones Aurora would return for the ledger, except for the liquidity
+// pool fees which are not extracted.
+func FetchLedgerTrades(networkPassphrase string, ledger xdr.LedgerCloseMeta) ([]hProtocol.Trade, error) {
+	reader, err := ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(networkPassphrase, ledger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read ledger transactions")
+	}
+	defer reader.Close()
+
+	header := reader.GetHeader()
+	closeTime := time.Unix(int64(header.Header.ScpValue.CloseTime), 0).UTC()
+
+	var trades []hProtocol.Trade
+	for {
+		tx, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read transaction")
+		}
+		if !tx.Result.Successful() {
+			continue
+		}
+
+		txTrades, err := extractTransactionTrades(reader.GetSequence(), closeTime, tx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not extract trades of transaction %d", tx.Index)
+		}
+		trades = append(trades, txTrades...)
+	}
+	return trades, nil
+}
+
+// extractTransactionTrades returns the trades of the operations of a
+// successful transaction.
+func extractTransactionTrades(ledgerSeq uint32, closeTime time.Time, tx ingest.LedgerTransaction) ([]hProtocol.Trade, error) {
+	var trades []hProtocol.Trade
+
+	opResults, ok := tx.Result.OperationResults()
+	if !ok {
+		return nil, errors.New("transaction has no operation results")
+	}
+	for opIdx, op := range tx.Envelope.Operations() {
+		claims, buyOffer, buyOfferExists := operationClaims(op, opResults[opIdx])
+
+		opID := operationID(ledgerSeq, tx.Index, uint32(opIdx+1))
+		counterOfferID := int64(uint64(opID) | counterOfferIDTOIDType<<62)
+		if buyOfferExists {
+			counterOfferID = int64(buyOffer.OfferId)
+		}
+
+		buyer := tx.Envelope.SourceAccount().ToAccountId()
+		if op.SourceAccount != nil {
+			buyer = op.SourceAccount.ToAccountId()
+		}
+
+		for order, claim := range claims {
+			// Offers garbage collected by diamcircle-core are emitted with zero
+			// amounts, they are not trades.
+			if claim.AmountBought() == 0 && claim.AmountSold() == 0 {
+				continue
+			}
+
+			trade := hProtocol.Trade{
+				ID:              fmt.Sprintf("%d-%d", opID, order),
+				LedgerCloseTime: closeTime,
+				BaseAmount:      amount.String(claim.AmountSold()),
+				CounterOfferID:  strconv.FormatInt(counterOfferID, 10),
+				CounterAccount:  buyer.Address(),
+				CounterAmount:   amount.String(claim.AmountBought()),
+				BaseIsSeller:    true,
+			}
+			trade.PT = trade.ID
+
+			if claim.Type == xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool {
+				poolID := claim.MustLiquidityPool().LiquidityPoolId
+				trade.TradeType = "liquidity_pool"
+				trade.BaseLiquidityPoolID = hex.EncodeToString(poolID[:])
+				trade.Price = hProtocol.TradePrice{
+					N: int64(claim.AmountBought()),
+					D: int64(claim.AmountSold()),
+				}
+			} else {
+				price, err := offerPrice(tx, opIdx, claim)
+				if err != nil {
+					return nil, err
+				}
+				trade.TradeType = "orderbook"
+				trade.OfferID = strconv.FormatInt(int64(claim.OfferId()), 10)
+				trade.BaseOfferID = trade.OfferID
+				trade.BaseAccount = claim.SellerId().Address()
+				trade.Price = hProtocol.TradePrice{N: int64(price.N), D: int64(price.D)}
+			}
+
+			err := claim.AssetSold().Extract(&trade.BaseAssetType, &trade.BaseAssetCode, &trade.BaseAssetIssuer)
+			if err != nil {
+				return nil, err
+			}
+			err = claim.AssetBought().Extract(&trade.CounterAssetType, &trade.CounterAssetCode, &trade.CounterAssetIssuer)
+			if err != nil {
+				return nil, err
+			}
+
+			trades = append(trades, trade)
+		}
+	}
+	return trades, nil
+}
+
+// operationClaims returns the offers and liquidity pools claimed by an
+// operation and the offer it created, if any.
+func operationClaims(op xdr.Operation, result xdr.OperationResult) ([]xdr.ClaimAtom, xdr.OfferEntry, bool) {
+	switch op.Body.Type {
+	case xdr.OperationTypePathPaymentStrictReceive:
+		return result.MustTr().MustPathPaymentStrictReceiveResult().MustSuccess().Offers, xdr.OfferEntry{}, false
+	case xdr.OperationTypePathPaymentStrictSend:
+		return result.MustTr().MustPathPaymentStrictSendResult().MustSuccess().Offers, xdr.OfferEntry{}, false
+	case xdr.OperationTypeManageBuyOffer:
+		success := result.MustTr().MustManageBuyOfferResult().MustSuccess()
+		offer, ok := success.Offer.GetOffer()
+		return success.OffersClaimed, offer, ok
+	case xdr.OperationTypeManageSellOffer:
+		success := result.MustTr().MustManageSellOfferResult().MustSuccess()
+		offer, ok := success.Offer.GetOffer()
+		return success.OffersClaimed, offer, ok
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		tr := result.MustTr()
+		// diamcircle-core sets the manage sell offer arm in the results of
+		// some create passive sell offer operations.
+		var success xdr.ManageOfferSuccessResult
+		if tr.Type == xdr.OperationTypeManageSellOffer {
+			success = tr.MustManageSellOfferResult().MustSuccess()
+		} else {
+			success = tr.MustCreatePassiveSellOfferResult().MustSuccess()
+		}
+		offer, ok := success.Offer.GetOffer()
+		return success.OffersClaimed, offer, ok
+	}
+	return nil, xdr.OfferEntry{}, false
+}
+
+// offerPrice returns the price of the offer claimed by an operation, as it was
+// before the operation was applied.
+func offerPrice(tx ingest.LedgerTransaction, opIdx int, claim xdr.ClaimAtom) (xdr.Price, error) {
+	key := xdr.LedgerKey{}
+	if err := key.SetOffer(claim.SellerId(), uint64(claim.OfferId())); err != nil {
+		return xdr.Price{}, errors.Wrap(err, "could not create offer ledger key")
+	}
+
+	changes, err := tx.GetOperationChanges(uint32(opIdx))
+	if err != nil {
+		return xdr.Price{}, errors.Wrap(err, "could not determine changes for operation")
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Pre != nil && key.Equals(changes[i].Pre.LedgerKey()) {
+			return changes[i].Pre.Data.MustOffer().Price, nil
+		}
+	}
+	return xdr.Price{}, errors.Errorf("could not find change for offer %d", claim.OfferId())
+}
+
+// operationID returns the total order id Aurora assigns to an operation.
+func operationID(ledgerSeq, txIndex, opIndex uint32) int64 {
+	return int64(ledgerSeq)<<32 | int64(txIndex)<<12 | int64(opIndex)
+}
@@ -0,0 +1,196 @@
+package scraper
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/diamcircle/go/ingest"
+	hProtocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTransactionTrades(t *testing.T) {
+	issuer := "GCF3TQXKZJNFJK7HCMNE2O2CUNKCJH2Y2ROISTBPLC7C5EIA5NNG2XZB"
+	seller := xdr.MustAddress("GA5WBPYA5Y4WAEHXWR2UKO2UO4BUGHUQ74EUPKON2QHV4WRHOIRNKKH2")
+	source := xdr.MustMuxedAddress("GAXI33UCLQTCKM2NMRBS7XYBR535LLEVAHL5YBN4FTCB4HZHT7ZA5CVK")
+	opSource := xdr.MustMuxedAddress("GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU")
+	usd := xdr.MustNewCreditAsset("USD", issuer)
+	eur := xdr.MustNewCreditAsset("EUR", issuer)
+	poolID := xdr.PoolId{0xca, 0xfe}
+
+	offerClaim := xdr.ClaimAtom{
+		Type: xdr.ClaimAtomTypeClaimAtomTypeOrderBook,
+		OrderBook: &xdr.ClaimOfferAtom{
+			SellerId:     seller,
+			OfferId:      42,
+			AssetSold:    usd,
+			AmountSold:   200,
+			AssetBought:  xdr.MustNewNativeAsset(),
+			AmountBought: 100,
+		},
+	}
+	garbageCollectedClaim := xdr.ClaimAtom{
+		Type: xdr.ClaimAtomTypeClaimAtomTypeOrderBook,
+		OrderBook: &xdr.ClaimOfferAtom{
+			SellerId:    seller,
+			OfferId:     43,
+			AssetSold:   eur,
+			AssetBought: xdr.MustNewNativeAsset(),
+		},
+	}
+	poolClaim := xdr.ClaimAtom{
+		Type: xdr.ClaimAtomTypeClaimAtomTypeLiquidityPool,
+		LiquidityPool: &xdr.ClaimLiquidityAtom{
+			LiquidityPoolId: poolID,
+			AssetSold:       eur,
+			AmountSold:      50,
+			AssetBought:     xdr.MustNewNativeAsset(),
+			AmountBought:    25,
+		},
+	}
+
+	operationResults := []xdr.OperationResult{
+		{
+			Code: xdr.OperationResultCodeOpInner,
+			Tr: &xdr.OperationResultTr{
+				Type: xdr.OperationTypeManageSellOffer,
+				ManageSellOfferResult: &xdr.ManageSellOfferResult{
+					Code: xdr.ManageSellOfferResultCodeManageSellOfferSuccess,
+					Success: &xdr.ManageOfferSuccessResult{
+						OffersClaimed: []xdr.ClaimAtom{offerClaim},
+						Offer: xdr.ManageOfferSuccessResultOffer{
+							Effect: xdr.ManageOfferEffectManageOfferDeleted,
+						},
+					},
+				},
+			},
+		},
+		{
+			Code: xdr.OperationResultCodeOpInner,
+			Tr: &xdr.OperationResultTr{
+				Type: xdr.OperationTypePathPaymentStrictSend,
+				PathPaymentStrictSendResult: &xdr.PathPaymentStrictSendResult{
+					Code: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+					Success: &xdr.PathPaymentStrictSendResultSuccess{
+						Offers: []xdr.ClaimAtom{garbageCollectedClaim, poolClaim},
+					},
+				},
+			},
+		},
+	}
+
+	tx := ingest.LedgerTransaction{
+		Index: 1,
+		Envelope: xdr.TransactionEnvelope{
+			Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+			V1: &xdr.TransactionV1Envelope{
+				Tx: xdr.Transaction{
+					SourceAccount: source,
+					Operations: []xdr.Operation{
+						{
+							Body: xdr.OperationBody{
+								Type:              xdr.OperationTypeManageSellOffer,
+								ManageSellOfferOp: &xdr.ManageSellOfferOp{},
+							},
+						},
+						{
+							Body: xdr.OperationBody{
+								Type:                    xdr.OperationTypePathPaymentStrictSend,
+								PathPaymentStrictSendOp: &xdr.PathPaymentStrictSendOp{},
+							},
+							SourceAccount: &opSource,
+						},
+					},
+				},
+			},
+		},
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code:    xdr.TransactionResultCodeTxSuccess,
+					Results: &operationResults,
+				},
+			},
+		},
+		UnsafeMeta: xdr.TransactionMeta{
+			V: 2,
+			V2: &xdr.TransactionMetaV2{
+				Operations: []xdr.OperationMeta{
+					{
+						Changes: xdr.LedgerEntryChanges{
+							{
+								Type: xdr.LedgerEntryChangeTypeLedgerEntryState,
+								State: &xdr.LedgerEntry{
+									Data: xdr.LedgerEntryData{
+										Type: xdr.LedgerEntryTypeOffer,
+										Offer: &xdr.OfferEntry{
+											SellerId: seller,
+											OfferId:  42,
+											Price:    xdr.Price{N: 1, D: 2},
+										},
+									},
+								},
+							},
+							{
+								Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved,
+								Removed: &xdr.LedgerKey{
+									Type: xdr.LedgerEntryTypeOffer,
+									Offer: &xdr.LedgerKeyOffer{
+										SellerId: seller,
+										OfferId:  42,
+									},
+								},
+							},
+						},
+					},
+					{Changes: xdr.LedgerEntryChanges{}},
+				},
+			},
+		},
+	}
+
+	closeTime := time.Unix(1600000000, 0).UTC()
+	trades, err := extractTransactionTrades(100, closeTime, tx)
+	require.NoError(t, err)
+	assert.Equal(t, []hProtocol.Trade{
+		{
+			ID:               "429496733697-0",
+			PT:               "429496733697-0",
+			LedgerCloseTime:  closeTime,
+			OfferID:          "42",
+			TradeType:        "orderbook",
+			BaseOfferID:      "42",
+			BaseAccount:      seller.Address(),
+			BaseAmount:       "0.0000200",
+			BaseAssetType:    "credit_alphanum4",
+			BaseAssetCode:    "USD",
+			BaseAssetIssuer:  issuer,
+			CounterOfferID:   "4611686447924121601",
+			CounterAccount:   source.Address(),
+			CounterAmount:    "0.0000100",
+			CounterAssetType: "native",
+			BaseIsSeller:     true,
+			Price:            hProtocol.TradePrice{N: 1, D: 2},
+		},
+		{
+			ID:                  "429496733698-1",
+			PT:                  "429496733698-1",
+			LedgerCloseTime:     closeTime,
+			TradeType:           "liquidity_pool",
+			BaseLiquidityPoolID: hex.EncodeToString(poolID[:]),
+			BaseAmount:          "0.0000050",
+			BaseAssetType:       "credit_alphanum4",
+			BaseAssetCode:       "EUR",
+			BaseAssetIssuer:     issuer,
+			CounterOfferID:      "4611686447924121602",
+			CounterAccount:      opSource.Address(),
+			CounterAmount:       "0.0000025",
+			CounterAssetType:    "native",
+			BaseIsSeller:        true,
+			Price:               hProtocol.TradePrice{N: 25, D: 50},
+		},
+	}, trades)
+}
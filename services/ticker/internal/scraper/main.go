@@ -108,6 +108,10 @@ type OrderbookStats struct {
 	LowestAsk          float64
 	Spread             float64
 	SpreadMidPoint     float64
+	// Bids and Asks are the price levels of the orderbook, as [price, amount]
+	// pairs, with prices in units of counter and amounts in units of base.
+	Bids [][2]float64
+	Asks [][2]float64
 }
 
 // FetchAllAssets fetches assets from the Aurora public net. If limit = 0, will fetch all assets.
@@ -67,6 +67,11 @@ func calcOrderbookStats(obStats *OrderbookStats, summary hProtocol.OrderBookSumm
 			return errors.Wrap(err, "invalid bid amount")
 		}
 		obStats.BidVolume += amountf
+
+		// Bid amounts are in units of counter on Aurora.
+		if pricef > 0 {
+			obStats.Bids = append(obStats.Bids, [2]float64{pricef, amountf / pricef})
+		}
 	}
 
 	// Calculate Ask Data:
@@ -88,6 +93,7 @@ func calcOrderbookStats(obStats *OrderbookStats, summary hProtocol.OrderBookSumm
 		if pricef < obStats.LowestAsk {
 			obStats.LowestAsk = pricef
 		}
+		obStats.Asks = append(obStats.Asks, [2]float64{pricef, amountf})
 	}
 
 	obStats.Spread, obStats.SpreadMidPoint = utils.CalcSpread(obStats.HighestBid, obStats.LowestAsk)
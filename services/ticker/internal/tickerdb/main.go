@@ -81,6 +81,22 @@ type Trade struct {
 	CounterAssetID  int32     `db:"counter_asset_id"`
 	BaseIsSeller    bool      `db:"base_is_seller"`
 	Price           float64   `db:"price"`
+	TradeType       string    `db:"trade_type"`
+	LiquidityPoolID string    `db:"liquidity_pool_id"`
+}
+
+// IngestedLedger represents an entry on the ingested_ledgers table, a ledger
+// whose trades have been ingested
+type IngestedLedger struct {
+	Sequence  uint32    `db:"sequence"`
+	CloseTime time.Time `db:"close_time"`
+}
+
+// LedgerRange represents a range of ledgers, including both ends
+// Note: this struct does *not* directly map to a db entity.
+type LedgerRange struct {
+	StartSequence uint32 `db:"start_sequence"`
+	EndSequence   uint32 `db:"end_sequence"`
 }
 
 // OrderbookStats represents an entry on the orderbook_stats table
@@ -97,6 +113,8 @@ type OrderbookStats struct {
 	Spread         float64   `db:"spread"`
 	SpreadMidPoint float64   `db:"spread_mid_point"`
 	UpdatedAt      time.Time `db:"updated_at"`
+	Bids           string    `db:"bids"`
+	Asks           string    `db:"asks"`
 }
 
 // Market represent the aggregated market data retrieved from the database.
@@ -0,0 +1,15 @@
+-- +migrate Up
+CREATE TABLE ingested_ledgers (
+    sequence integer NOT NULL PRIMARY KEY,
+    close_time timestamptz NOT NULL
+);
+
+CREATE INDEX ingested_ledgers_close_time_idx ON ingested_ledgers (close_time);
+
+ALTER TABLE trades ADD trade_type text NOT NULL DEFAULT 'orderbook';
+ALTER TABLE trades ADD liquidity_pool_id text NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE trades DROP liquidity_pool_id;
+ALTER TABLE trades DROP trade_type;
+DROP TABLE ingested_ledgers;
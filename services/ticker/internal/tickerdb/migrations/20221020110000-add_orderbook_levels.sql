@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE orderbook_stats ADD bids text NOT NULL DEFAULT '[]';
+ALTER TABLE orderbook_stats ADD asks text NOT NULL DEFAULT '[]';
+
+-- +migrate Down
+ALTER TABLE orderbook_stats DROP asks;
+ALTER TABLE orderbook_stats DROP bids;
@@ -10,6 +10,8 @@
 // migrations/20190411165735-data_seed_and_indices.sql (1.522kB)
 // migrations/20190425110313-add_orderbook_stats.sql (749B)
 // migrations/20190426092321-add_aggregated_orderbook_view.sql (831B)
+// migrations/20221018093000-add_ingested_ledgers_table.sql (472B)
+// migrations/20221020110000-add_orderbook_levels.sql (241B)
 
 package bdata
 
@@ -278,6 +280,46 @@ func migrations20190426092321Add_aggregated_orderbook_viewSql() (*asset, error)
 	return a, nil
 }
 
+var _migrations20221018093000Add_ingested_ledgers_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x75\x91\x4d\x6e\xc3\x20\x10\x85\xf7\x9c\x62\x76\x69\xd5\xfa\x04\x5e\xd1\x42\xa5\xa8\xd4\x8e\x90\x2d\x35\x2b\xe4\x86\x91\x85\x6a\x1b\x07\x26\x6a\xd2\xd3\x97\xb8\x3f\xae\x14\x87\x05\x42\x33\x8f\xef\xbd\x81\x2c\x83\xbb\xde\xb5\xa1\x21\x84\x7a\x64\x8f\x5a\xf2\x4a\x42\xc5\x1f\x94\x04\x37\xb4\x18\x09\xad\xe9\xd0\xb6\x18\x22\xdc\x30\x48\x2b\xe2\xfe\x80\xc3\x0e\x53\x9f\x30\xd5\xa1\x28\x2b\x28\x6a\xa5\x60\xa3\xd7\x2f\x5c\x6f\xe1\x59\x6e\xef\x27\xe9\xae\xf3\x11\x0d\xb9\x1e\xe1\xbc\x45\x6a\xfa\x91\x3e\xff\x2e\xb0\xdb\x9c\xfd\x5a\xae\x0b\x21\x5f\x2f\x2c\xcd\x4c\x30\xce\x1e\xa1\x2c\x16\x52\xcd\x9a\x33\x8f\xab\x4a\xea\x9f\x09\x28\x34\x16\x23\x70\x21\xbe\x8f\x86\x4e\x63\x8a\x82\x47\x9a\x43\x0b\xf9\xc4\x6b\x55\xc1\xca\x07\x8b\xe1\xcd\xfb\xf7\x55\x7e\x0d\xd2\xb9\xfd\xc1\x59\x47\x27\x33\x7a\xdf\xa5\x44\xd7\x58\x09\xc1\xb2\x7f\x4f\x2b\xfc\xc7\xb0\x04\x15\xba\xdc\x5c\x52\x17\xfd\x27\xe9\x3c\x45\xce\xa6\xc2\xf2\x4f\xe5\xec\x0b\x20\xc0\xb6\xcf\xd8\x01\x00\x00")
+
+func migrations20221018093000Add_ingested_ledgers_tableSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations20221018093000Add_ingested_ledgers_tableSql,
+		"migrations/20221018093000-add_ingested_ledgers_table.sql",
+	)
+}
+
+func migrations20221018093000Add_ingested_ledgers_tableSql() (*asset, error) {
+	bytes, err := migrations20221018093000Add_ingested_ledgers_tableSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/20221018093000-add_ingested_ledgers_table.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xa8, 0xee, 0x74, 0x61, 0xfc, 0xf0, 0x3a, 0x3d, 0x90, 0xd1, 0xfb, 0x8c, 0xf8, 0xdb, 0xb, 0x7c, 0xcf, 0xa3, 0x45, 0x38, 0x4f, 0x8c, 0x4e, 0x62, 0x85, 0xe4, 0xdc, 0x23, 0xe3, 0x15, 0x58, 0x7d}}
+	return a, nil
+}
+
+var _migrations20221020110000Add_orderbook_levelsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd3\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\x72\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\xc8\x2f\x4a\x49\x2d\x4a\xca\xcf\xcf\x8e\x2f\x2e\x49\x2c\x29\x56\x70\x74\x71\x51\x48\xca\x4c\x29\x56\x28\x49\xad\x28\x51\xf0\xf3\x0f\x51\xf0\x0b\xf5\xf1\x51\x70\x71\x75\x73\x0c\xf5\x09\x51\x50\x8f\x8e\x55\xb7\x26\x68\x40\x62\x71\x36\x7e\x03\xb8\x74\x91\x5c\xe4\x92\x5f\x9e\x87\xd7\x48\x97\x20\xff\x00\xb0\x99\xf8\x6d\x06\x2b\x03\xb9\xdd\x9a\x0b\x00\xe2\xc3\x87\xf1\xf1\x00\x00\x00")
+
+func migrations20221020110000Add_orderbook_levelsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations20221020110000Add_orderbook_levelsSql,
+		"migrations/20221020110000-add_orderbook_levels.sql",
+	)
+}
+
+func migrations20221020110000Add_orderbook_levelsSql() (*asset, error) {
+	bytes, err := migrations20221020110000Add_orderbook_levelsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/20221020110000-add_orderbook_levels.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xaa, 0xc9, 0xa2, 0xe2, 0x72, 0x9a, 0xc0, 0x9c, 0x21, 0x95, 0x71, 0x63, 0xc5, 0x8d, 0xb9, 0x2a, 0x23, 0x67, 0xdc, 0x79, 0x76, 0x86, 0x7a, 0x26, 0x31, 0x62, 0x8e, 0x66, 0x1d, 0x4c, 0x80, 0x28}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -379,6 +421,8 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/20190411165735-data_seed_and_indices.sql":           migrations20190411165735Data_seed_and_indicesSql,
 	"migrations/20190425110313-add_orderbook_stats.sql":             migrations20190425110313Add_orderbook_statsSql,
 	"migrations/20190426092321-add_aggregated_orderbook_view.sql":   migrations20190426092321Add_aggregated_orderbook_viewSql,
+	"migrations/20221018093000-add_ingested_ledgers_table.sql":      migrations20221018093000Add_ingested_ledgers_tableSql,
+	"migrations/20221020110000-add_orderbook_levels.sql":            migrations20221020110000Add_orderbook_levelsSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -433,6 +477,8 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"20190411165735-data_seed_and_indices.sql":           &bintree{migrations20190411165735Data_seed_and_indicesSql, map[string]*bintree{}},
 		"20190425110313-add_orderbook_stats.sql":             &bintree{migrations20190425110313Add_orderbook_statsSql, map[string]*bintree{}},
 		"20190426092321-add_aggregated_orderbook_view.sql":   &bintree{migrations20190426092321Add_aggregated_orderbook_viewSql, map[string]*bintree{}},
+		"20221018093000-add_ingested_ledgers_table.sql":      &bintree{migrations20221018093000Add_ingested_ledgers_tableSql, map[string]*bintree{}},
+		"20221020110000-add_orderbook_levels.sql":            &bintree{migrations20221020110000Add_orderbook_levelsSql, map[string]*bintree{}},
 	}},
 }}
 
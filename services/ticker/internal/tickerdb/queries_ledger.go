@@ -0,0 +1,48 @@
+package tickerdb
+
+import (
+	"context"
+	"time"
+)
+
+// InsertIngestedLedger records that the trades of the given ledger have been
+// ingested. Ledgers which are already recorded are ignored.
+func (s *TickerSession) InsertIngestedLedger(ctx context.Context, ledger IngestedLedger) error {
+	_, err := s.ExecRaw(ctx, `
+		INSERT INTO ingested_ledgers (sequence, close_time)
+		VALUES (?, ?)
+		ON CONFLICT (sequence) DO NOTHING`,
+		ledger.Sequence,
+		ledger.CloseTime,
+	)
+	return err
+}
+
+// GetLatestIngestedLedger returns the sequence of the newest ingested ledger,
+// or 0 if no ledger has been ingested.
+func (s *TickerSession) GetLatestIngestedLedger(ctx context.Context) (sequence uint32, err error) {
+	err = s.GetRaw(ctx, &sequence, "SELECT COALESCE(MAX(sequence), 0) FROM ingested_ledgers")
+	return
+}
+
+// GetIngestedLedgerGaps returns the ranges of ledgers which have not been
+// ingested between the oldest and the newest ingested ledgers.
+func (s *TickerSession) GetIngestedLedgerGaps(ctx context.Context) (gaps []LedgerRange, err error) {
+	err = s.SelectRaw(ctx, &gaps, `
+		SELECT sequence + 1 AS start_sequence, next_sequence - 1 AS end_sequence
+		FROM (
+			SELECT sequence, lead(sequence) OVER (ORDER BY sequence) AS next_sequence
+			FROM ingested_ledgers
+		) AS ledgers
+		WHERE next_sequence - sequence > 1
+		ORDER BY start_sequence`,
+	)
+	return
+}
+
+// DeleteOldIngestedLedgers deletes the ingested ledgers which closed before
+// minDate.
+func (s *TickerSession) DeleteOldIngestedLedgers(ctx context.Context, minDate time.Time) error {
+	_, err := s.ExecRaw(ctx, "DELETE FROM ingested_ledgers WHERE close_time < ?", minDate)
+	return err
+}
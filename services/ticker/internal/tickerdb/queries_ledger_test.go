@@ -0,0 +1,68 @@
+package tickerdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/diamcircle/go/support/db/dbtest"
+	_ "github.com/lib/pq"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestedLedgers(t *testing.T) {
+	db := dbtest.Postgres(t)
+	defer db.Close()
+
+	var session TickerSession
+	session.DB = db.Open()
+	ctx := context.Background()
+	defer session.DB.Close()
+
+	// Run migrations to make sure the tests are run
+	// on the most updated schema version
+	migrations := &migrate.FileMigrationSource{
+		Dir: "./migrations",
+	}
+	_, err := migrate.Exec(session.DB.DB, "postgres", migrations, migrate.Up)
+	require.NoError(t, err)
+
+	// Sanity Check (there are no ledgers in the database)
+	latest, err := session.GetLatestIngestedLedger(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0), latest)
+	gaps, err := session.GetIngestedLedgerGaps(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, gaps)
+
+	now := time.Now()
+	for _, sequence := range []uint32{10, 11, 12, 15, 20, 21} {
+		err = session.InsertIngestedLedger(ctx, IngestedLedger{
+			Sequence:  sequence,
+			CloseTime: now.Add(time.Duration(sequence) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+	// Ledgers which were already ingested are ignored
+	err = session.InsertIngestedLedger(ctx, IngestedLedger{Sequence: 12, CloseTime: now})
+	require.NoError(t, err)
+
+	latest, err = session.GetLatestIngestedLedger(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(21), latest)
+
+	gaps, err = session.GetIngestedLedgerGaps(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{
+		{StartSequence: 13, EndSequence: 14},
+		{StartSequence: 16, EndSequence: 19},
+	}, gaps)
+
+	err = session.DeleteOldIngestedLedgers(ctx, now.Add(16*time.Minute))
+	require.NoError(t, err)
+	gaps, err = session.GetIngestedLedgerGaps(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{{StartSequence: 16, EndSequence: 19}}, gaps)
+}
@@ -9,3 +9,22 @@ import (
 func (s *TickerSession) InsertOrUpdateOrderbookStats(ctx context.Context, o *OrderbookStats, preserveFields []string) (err error) {
 	return s.performUpsertQuery(ctx, *o, "orderbook_stats", "orderbook_stats_base_counter_asset_key", preserveFields)
 }
+
+// RetrieveOrderbookStatsForPair retrieves the orderbook stats of all the
+// markets of valid assets aggregated under the given pair name (e.g. XLM_BTC).
+func (s *TickerSession) RetrieveOrderbookStatsForPair(ctx context.Context, pairName string) (obStats []OrderbookStats, err error) {
+	q := `
+	SELECT os.*
+	FROM orderbook_stats AS os
+		JOIN assets AS bAsset ON os.base_asset_id = bAsset.id
+		JOIN assets AS cAsset ON os.counter_asset_id = cAsset.id
+	WHERE bAsset.is_valid = TRUE AND cAsset.is_valid = TRUE
+		AND concat(
+			COALESCE(NULLIF(bAsset.anchor_asset_code, ''), bAsset.code),
+			'_',
+			COALESCE(NULLIF(cAsset.anchor_asset_code, ''), cAsset.code)
+		) = ?
+	ORDER BY os.id`
+	err = s.SelectRaw(ctx, &obStats, q, pairName)
+	return
+}
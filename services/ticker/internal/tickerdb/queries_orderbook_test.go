@@ -179,3 +179,88 @@ func TestInsertOrUpdateOrderbokStats(t *testing.T) {
 	assert.Equal(t, 0.7, dbOS2.SpreadMidPoint)
 	assert.WithinDuration(t, obTime2.Local(), dbOS2.UpdatedAt.Local(), 10*time.Millisecond)
 }
+
+func TestRetrieveOrderbookStatsForPair(t *testing.T) {
+	db := dbtest.Postgres(t)
+	defer db.Close()
+
+	var session TickerSession
+	session.DB = db.Open()
+	ctx := context.Background()
+	defer session.DB.Close()
+
+	// Run migrations to make sure the tests are run
+	// on the most updated schema version
+	migrations := &migrate.FileMigrationSource{
+		Dir: "./migrations",
+	}
+	_, err := migrate.Exec(session.DB.DB, "postgres", migrations, migrate.Up)
+	require.NoError(t, err)
+
+	// Adding a seed issuer to be used later:
+	tbl := session.GetTable("issuers")
+	_, err = tbl.Insert(Issuer{
+		PublicKey: "GCF3TQXKZJNFJK7HCMNE2O2CUNKCJH2Y2ROISTBPLC7C5EIA5NNG2XZB",
+		Name:      "FOO BAR",
+	}).IgnoreCols("id").Exec(ctx)
+	require.NoError(t, err)
+	var issuer Issuer
+	err = session.GetRaw(ctx, &issuer, `
+		SELECT *
+		FROM issuers
+		ORDER BY id DESC
+		LIMIT 1`,
+	)
+	require.NoError(t, err)
+
+	// Adding the assets of the XLM_BTC pair, the invalid one should be ignored
+	// and the one anchored to BTC included:
+	var assets []Asset
+	for _, a := range []Asset{
+		{Code: "XLM", IssuerAccount: "native", IsValid: true},
+		{Code: "BTC", IssuerAccount: "issuer1", IsValid: true},
+		{Code: "BTC", IssuerAccount: "issuer2", IsValid: true},
+		{Code: "BTC", IssuerAccount: "issuer3", IsValid: false},
+		{Code: "XBTC", IssuerAccount: "issuer4", IsValid: true, AnchorAssetCode: "BTC"},
+	} {
+		a.IssuerID = issuer.ID
+		err = session.InsertOrUpdateAsset(ctx, &a, []string{"code", "issuer_account", "issuer_id"})
+		require.NoError(t, err)
+		var dbAsset Asset
+		err = session.GetRaw(ctx, &dbAsset, `
+			SELECT *
+			FROM assets
+			ORDER BY id DESC
+			LIMIT 1`,
+		)
+		require.NoError(t, err)
+		assets = append(assets, dbAsset)
+	}
+
+	for i, counter := range assets[1:] {
+		err = session.InsertOrUpdateOrderbookStats(ctx, &OrderbookStats{
+			BaseAssetID:    assets[0].ID,
+			CounterAssetID: counter.ID,
+			NumBids:        i + 1,
+			Bids:           "[[2,10]]",
+			Asks:           "[[3,5]]",
+			UpdatedAt:      time.Now(),
+		}, []string{"base_asset_id", "counter_asset_id"})
+		require.NoError(t, err)
+	}
+
+	obStats, err := session.RetrieveOrderbookStatsForPair(ctx, "XLM_BTC")
+	require.NoError(t, err)
+	require.Len(t, obStats, 3)
+	assert.Equal(t, assets[1].ID, obStats[0].CounterAssetID)
+	assert.Equal(t, 1, obStats[0].NumBids)
+	assert.Equal(t, "[[2,10]]", obStats[0].Bids)
+	assert.Equal(t, "[[3,5]]", obStats[0].Asks)
+	assert.Equal(t, assets[2].ID, obStats[1].CounterAssetID)
+	assert.Equal(t, 2, obStats[1].NumBids)
+	assert.Equal(t, assets[4].ID, obStats[2].CounterAssetID)
+
+	obStats, err = session.RetrieveOrderbookStatsForPair(ctx, "BTC_XLM")
+	require.NoError(t, err)
+	assert.Empty(t, obStats)
+}
@@ -38,6 +38,54 @@ func (s *TickerSession) DeleteOldTrades(ctx context.Context, minDate time.Time)
 	return err
 }
 
+// RetrieveHistoricalTrades retrieves the trades of valid assets aggregated
+// under the given pair name (e.g. XLM_BTC), newest first. Only trades with an
+// id lower than before are returned if before is not 0. since, until and
+// baseIsSeller optionally filter the trades by ledger close time and side.
+func (s *TickerSession) RetrieveHistoricalTrades(ctx context.Context,
+	pairName string,
+	before int32,
+	since *time.Time,
+	until *time.Time,
+	baseIsSeller *bool,
+	limit int,
+) (trades []Trade, err error) {
+	q := `
+	SELECT t.*
+	FROM trades AS t
+		JOIN assets AS bAsset ON t.base_asset_id = bAsset.id
+		JOIN assets AS cAsset ON t.counter_asset_id = cAsset.id
+	WHERE bAsset.is_valid = TRUE AND cAsset.is_valid = TRUE
+		AND concat(
+			COALESCE(NULLIF(bAsset.anchor_asset_code, ''), bAsset.code),
+			'_',
+			COALESCE(NULLIF(cAsset.anchor_asset_code, ''), cAsset.code)
+		) = ?`
+	args := []interface{}{pairName}
+
+	if before > 0 {
+		q += " AND t.id < ?"
+		args = append(args, before)
+	}
+	if since != nil {
+		q += " AND t.ledger_close_time >= ?"
+		args = append(args, *since)
+	}
+	if until != nil {
+		q += " AND t.ledger_close_time <= ?"
+		args = append(args, *until)
+	}
+	if baseIsSeller != nil {
+		q += " AND t.base_is_seller = ?"
+		args = append(args, *baseIsSeller)
+	}
+	q += " ORDER BY t.id DESC LIMIT ?"
+	args = append(args, limit)
+
+	err = s.SelectRaw(ctx, &trades, q, args...)
+	return
+}
+
 // chunkifyDBTrades transforms a slice into a slice of chunks (also slices) of chunkSize
 // e.g.: Chunkify([b, c, d, e, f], 2) = [[b c] [d e] [f]]
 func chunkifyDBTrades(sl []Trade, chunkSize int) [][]Trade {
@@ -351,3 +351,130 @@ func TestDeleteOldTrades(t *testing.T) {
 	assert.WithinDuration(t, now.Local(), trade1.LedgerCloseTime.Local(), 10*time.Millisecond)
 	assert.WithinDuration(t, oneDayAgo.Local(), trade2.LedgerCloseTime.Local(), 10*time.Millisecond)
 }
+
+func TestRetrieveHistoricalTrades(t *testing.T) {
+	db := dbtest.Postgres(t)
+	defer db.Close()
+
+	var session TickerSession
+	session.DB = db.Open()
+	ctx := context.Background()
+	defer session.DB.Close()
+
+	// Run migrations to make sure the tests are run
+	// on the most updated schema version
+	migrations := &migrate.FileMigrationSource{
+		Dir: "./migrations",
+	}
+	_, err := migrate.Exec(session.DB.DB, "postgres", migrations, migrate.Up)
+	require.NoError(t, err)
+
+	// Adding a seed issuer to be used later:
+	tbl := session.GetTable("issuers")
+	_, err = tbl.Insert(Issuer{
+		PublicKey: "GCF3TQXKZJNFJK7HCMNE2O2CUNKCJH2Y2ROISTBPLC7C5EIA5NNG2XZB",
+		Name:      "FOO BAR",
+	}).IgnoreCols("id").Exec(ctx)
+	require.NoError(t, err)
+	var issuer Issuer
+	err = session.GetRaw(ctx, &issuer, `
+		SELECT *
+		FROM issuers
+		ORDER BY id DESC
+		LIMIT 1`,
+	)
+	require.NoError(t, err)
+
+	// Adding the assets of the XLM_BTC pair, the invalid one should be ignored:
+	var assets []Asset
+	for _, a := range []Asset{
+		{Code: "XLM", IssuerAccount: "native", IsValid: true},
+		{Code: "BTC", IssuerAccount: "issuer1", IsValid: true},
+		{Code: "BTC", IssuerAccount: "issuer2", IsValid: false},
+	} {
+		a.IssuerID = issuer.ID
+		err = session.InsertOrUpdateAsset(ctx, &a, []string{"code", "issuer_account", "issuer_id"})
+		require.NoError(t, err)
+		var dbAsset Asset
+		err = session.GetRaw(ctx, &dbAsset, `
+			SELECT *
+			FROM assets
+			ORDER BY id DESC
+			LIMIT 1`,
+		)
+		require.NoError(t, err)
+		assets = append(assets, dbAsset)
+	}
+
+	now := time.Now()
+	oneHourAgo := now.Add(-1 * time.Hour)
+	oneDayAgo := now.AddDate(0, 0, -1)
+
+	trades := []Trade{
+		Trade{
+			AuroraID:        "hrzid1",
+			BaseAssetID:     assets[0].ID,
+			CounterAssetID:  assets[1].ID,
+			BaseIsSeller:    true,
+			LedgerCloseTime: oneDayAgo,
+		},
+		Trade{
+			AuroraID:        "hrzid2",
+			BaseAssetID:     assets[0].ID,
+			CounterAssetID:  assets[1].ID,
+			BaseIsSeller:    false,
+			LedgerCloseTime: oneHourAgo,
+		},
+		Trade{
+			AuroraID:        "hrzid3",
+			BaseAssetID:     assets[0].ID,
+			CounterAssetID:  assets[2].ID,
+			LedgerCloseTime: now,
+		},
+		Trade{
+			AuroraID:        "hrzid4",
+			BaseAssetID:     assets[0].ID,
+			CounterAssetID:  assets[1].ID,
+			BaseIsSeller:    true,
+			LedgerCloseTime: now,
+		},
+	}
+	err = session.BulkInsertTrades(ctx, trades)
+	require.NoError(t, err)
+
+	auroraIDs := func(trades []Trade) []string {
+		var ids []string
+		for _, trade := range trades {
+			ids = append(ids, trade.AuroraID)
+		}
+		return ids
+	}
+
+	dbTrades, err := session.RetrieveHistoricalTrades(ctx, "XLM_BTC", 0, nil, nil, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hrzid4", "hrzid2", "hrzid1"}, auroraIDs(dbTrades))
+
+	// Paging with the before cursor:
+	dbTrades, err = session.RetrieveHistoricalTrades(ctx, "XLM_BTC", 0, nil, nil, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hrzid4", "hrzid2"}, auroraIDs(dbTrades))
+	dbTrades, err = session.RetrieveHistoricalTrades(ctx, "XLM_BTC", dbTrades[1].ID, nil, nil, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hrzid1"}, auroraIDs(dbTrades))
+
+	// Filtering by time and side:
+	since := now.Add(-2 * time.Hour)
+	until := now.Add(-1 * time.Minute)
+	dbTrades, err = session.RetrieveHistoricalTrades(ctx, "XLM_BTC", 0, &since, &until, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hrzid2"}, auroraIDs(dbTrades))
+
+	baseIsSeller := true
+	dbTrades, err = session.RetrieveHistoricalTrades(ctx, "XLM_BTC", 0, nil, nil, &baseIsSeller, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hrzid4", "hrzid1"}, auroraIDs(dbTrades))
+
+	dbTrades, err = session.RetrieveHistoricalTrades(ctx, "XLM_ETH", 0, nil, nil, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, dbTrades)
+}
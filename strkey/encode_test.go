@@ -83,13 +83,6 @@ func TestEncode(t *testing.T) {
 	assert.Error(t, err)
 
 	// test too long payload
-	_, err = Encode(VersionByteAccountID, []byte{
-		0x69, 0xa8, 0xc4, 0xcb, 0xb9, 0xf6, 0x4e, 0x8a,
-		0x07, 0x98, 0xf6, 0xe1, 0xac, 0x65, 0xd0, 0x6c,
-		0x31, 0x62, 0x92, 0x90, 0x56, 0xbc, 0xf4, 0xcd,
-		0xb7, 0xd3, 0x73, 0x8d, 0x18, 0x55, 0xf3, 0x63,
-		0x69, 0xa8, 0xc4, 0xcb, 0xb9, 0xf6, 0x4e, 0x8a,
-		0x01,
-	})
+	_, err = Encode(VersionByteAccountID, make([]byte, maxPayloadSize+1))
 	assert.EqualError(t, err, "data exceeds maximum payload size for strkey")
 }
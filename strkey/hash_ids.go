@@ -0,0 +1,47 @@
+package strkey
+
+import (
+	"github.com/diamcircle/go/support/errors"
+)
+
+// EncodeContract encodes a 32-byte contract id as a "C..." address.
+func EncodeContract(id [32]byte) (string, error) {
+	return Encode(VersionByteContract, id[:])
+}
+
+// DecodeContract decodes a "C..." address into its 32-byte contract id.
+func DecodeContract(address string) ([32]byte, error) {
+	return decodeHash(VersionByteContract, address)
+}
+
+// EncodeLiquidityPool encodes a 32-byte liquidity pool id as an "L..."
+// address.
+func EncodeLiquidityPool(id [32]byte) (string, error) {
+	return Encode(VersionByteLiquidityPool, id[:])
+}
+
+// DecodeLiquidityPool decodes an "L..." address into its 32-byte liquidity pool
+// id.
+func DecodeLiquidityPool(address string) ([32]byte, error) {
+	return decodeHash(VersionByteLiquidityPool, address)
+}
+
+// decodeHash decodes a strkey whose payload is a 32-byte hash and checks that
+// re-encoding the hash yields the same address.
+func decodeHash(version VersionByte, address string) ([32]byte, error) {
+	var id [32]byte
+
+	raw, err := Decode(version, address)
+	if err != nil {
+		return id, err
+	}
+	if len(raw) != 32 {
+		return id, errors.Errorf("invalid binary length: %d", len(raw))
+	}
+	copy(id[:], raw)
+
+	if encoded, err := Encode(version, id[:]); err != nil || encoded != address {
+		return id, errors.New("strkey does not round-trip")
+	}
+	return id, nil
+}
@@ -34,10 +34,24 @@ const (
 	//VersionByteHashX is the version byte used for encoded diamcircle hashX
 	//signer keys.
 	VersionByteHashX = 23 << 3 // Base32-encodes to 'X...'
+
+	//VersionByteSignedPayload is the version byte used for encoded diamcircle
+	//signed payload (ed25519 + payload) signer keys.
+	VersionByteSignedPayload = 15 << 3 // Base32-encodes to 'P...'
+
+	//VersionByteContract is the version byte used for encoded contract
+	//identifiers.
+	VersionByteContract = 2 << 3 // Base32-encodes to 'C...'
+
+	//VersionByteLiquidityPool is the version byte used for encoded liquidity
+	//pool identifiers.
+	VersionByteLiquidityPool = 11 << 3 // Base32-encodes to 'L...'
 )
 
-// maxPayloadSize is the maximum length of the payload for all versions.
-const maxPayloadSize = 40
+// maxPayloadSize is the maximum length of the payload for all versions. The
+// largest payload is a signed payload: a 32-byte ed25519 key, a 4-byte length
+// and up to 64 bytes of padded payload.
+const maxPayloadSize = 100
 
 // maxRawSize is the maximum length of a strkey in its raw form not encoded.
 const maxRawSize = 1 + maxPayloadSize + 2
@@ -182,7 +196,8 @@ func Version(src string) (VersionByte, error) {
 // is not one of the defined valid version byte constants.
 func checkValidVersionByte(version VersionByte) error {
 	switch version {
-	case VersionByteAccountID, VersionByteMuxedAccount, VersionByteSeed, VersionByteHashTx, VersionByteHashX:
+	case VersionByteAccountID, VersionByteMuxedAccount, VersionByteSeed, VersionByteHashTx, VersionByteHashX,
+		VersionByteSignedPayload, VersionByteContract, VersionByteLiquidityPool:
 		return nil
 	default:
 		return ErrInvalidVersionByte
@@ -276,3 +291,22 @@ func IsValidEd25519SecretSeed(i interface{}) bool {
 
 	return err == nil
 }
+
+// IsValidSignedPayload validates a Diamcircle signed payload signer key
+// ("P..." address), including the length and padding of its payload.
+func IsValidSignedPayload(s string) bool {
+	_, err := DecodeSignedPayload(s)
+	return err == nil
+}
+
+// IsValidContract validates a contract identifier ("C..." address).
+func IsValidContract(s string) bool {
+	_, err := DecodeContract(s)
+	return err == nil
+}
+
+// IsValidLiquidityPool validates a liquidity pool identifier ("L..." address).
+func IsValidLiquidityPool(s string) bool {
+	_, err := DecodeLiquidityPool(s)
+	return err == nil
+}
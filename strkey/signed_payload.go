@@ -0,0 +1,99 @@
+package strkey
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/diamcircle/go/support/errors"
+)
+
+// MaxSignedPayloadLength is the maximum length of the payload of a signed
+// payload signer.
+const MaxSignedPayloadLength = 64
+
+// SignedPayload is a signer key made of an ed25519 public key and a payload
+// which that key must sign ("P..." address).
+type SignedPayload struct {
+	signer  [32]byte
+	payload []byte
+}
+
+// NewSignedPayload builds a signed payload from a G-address and a payload of
+// between 1 and MaxSignedPayloadLength bytes.
+func NewSignedPayload(signerAddress string, payload []byte) (*SignedPayload, error) {
+	raw, err := Decode(VersionByteAccountID, signerAddress)
+	if err != nil {
+		return nil, errors.New("invalid ed25519 public key")
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid binary length: %d", len(raw))
+	}
+	if len(payload) == 0 {
+		return nil, errors.New("payload must not be empty")
+	}
+	if len(payload) > MaxSignedPayloadLength {
+		return nil, fmt.Errorf("payload length %d exceeds maximum of %d", len(payload), MaxSignedPayloadLength)
+	}
+
+	sp := &SignedPayload{payload: append([]byte(nil), payload...)}
+	copy(sp.signer[:], raw)
+	return sp, nil
+}
+
+// Signer returns the G-address of the key that must sign the payload.
+func (sp *SignedPayload) Signer() string {
+	return MustEncode(VersionByteAccountID, sp.signer[:])
+}
+
+// Payload returns a copy of the payload that must be signed.
+func (sp *SignedPayload) Payload() []byte {
+	return append([]byte(nil), sp.payload...)
+}
+
+// Encode returns the P-address of the signed payload. The raw form is the
+// ed25519 key, followed by the big-endian payload length and the payload
+// zero-padded to a multiple of four bytes, as in the XDR encoding.
+func (sp *SignedPayload) Encode() (string, error) {
+	padded := (len(sp.payload) + 3) &^ 3
+
+	raw := make([]byte, 32+4+padded)
+	copy(raw, sp.signer[:])
+	binary.BigEndian.PutUint32(raw[32:36], uint32(len(sp.payload)))
+	copy(raw[36:], sp.payload)
+
+	return Encode(VersionByteSignedPayload, raw)
+}
+
+// DecodeSignedPayload parses a P-address, checking that the payload length
+// and its zero padding are consistent with the total length.
+func DecodeSignedPayload(address string) (*SignedPayload, error) {
+	raw, err := Decode(VersionByteSignedPayload, address)
+	if err != nil {
+		return nil, errors.New("invalid signed payload")
+	}
+
+	// 32 bytes of ed25519 key, a 4 byte length and at least 4 bytes of padded
+	// payload.
+	if len(raw) < 32+4+4 || len(raw) > 32+4+MaxSignedPayloadLength {
+		return nil, errors.Errorf("invalid binary length: %d", len(raw))
+	}
+
+	length := int(binary.BigEndian.Uint32(raw[32:36]))
+	if length == 0 || length > MaxSignedPayloadLength {
+		return nil, errors.Errorf("invalid payload length: %d", length)
+	}
+
+	padded := (length + 3) &^ 3
+	if len(raw) != 32+4+padded {
+		return nil, errors.Errorf("invalid binary length: %d", len(raw))
+	}
+	for _, b := range raw[36+length:] {
+		if b != 0 {
+			return nil, errors.New("signed payload padding is not zero")
+		}
+	}
+
+	sp := &SignedPayload{payload: append([]byte(nil), raw[36:36+length]...)}
+	copy(sp.signer[:], raw[:32])
+	return sp, nil
+}
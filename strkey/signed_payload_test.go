@@ -0,0 +1,106 @@
+package strkey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedPayload_RoundTrip(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Payload  []byte
+		Expected string
+	}{
+		{
+			Name: "32 byte payload",
+			Payload: []byte{
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+				0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+				0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+			},
+			Expected: "PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAQACAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUPB6IBZGM",
+		},
+		{
+			Name: "29 byte payload",
+			Payload: []byte{
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+				0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+				0x19, 0x1a, 0x1b, 0x1c, 0x1d,
+			},
+			Expected: "PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAOQCAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUAAAAFGBU",
+		},
+	}
+
+	for _, kase := range cases {
+		t.Run(kase.Name, func(t *testing.T) {
+			sp, err := NewSignedPayload("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", kase.Payload)
+			require.NoError(t, err)
+
+			address, err := sp.Encode()
+			require.NoError(t, err)
+			assert.Equal(t, kase.Expected, address)
+			assert.True(t, IsValidSignedPayload(address))
+
+			decoded, err := DecodeSignedPayload(address)
+			require.NoError(t, err)
+			assert.Equal(t, "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", decoded.Signer())
+			assert.Equal(t, kase.Payload, decoded.Payload())
+		})
+	}
+}
+
+func TestNewSignedPayload_Invalid(t *testing.T) {
+	_, err := NewSignedPayload("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZ", []byte{1})
+	assert.EqualError(t, err, "invalid ed25519 public key")
+
+	_, err = NewSignedPayload("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", nil)
+	assert.EqualError(t, err, "payload must not be empty")
+
+	_, err = NewSignedPayload("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ", make([]byte, 65))
+	assert.EqualError(t, err, "payload length 65 exceeds maximum of 64")
+}
+
+func TestDecodeSignedPayload_Invalid(t *testing.T) {
+	// non-zero padding
+	_, err := DecodeSignedPayload("PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAOQCAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUAAAAMHDU")
+	assert.EqualError(t, err, "signed payload padding is not zero")
+
+	// length prefix larger than the payload
+	_, err = DecodeSignedPayload("PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAUACAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUPB6IC5DU")
+	assert.EqualError(t, err, "invalid binary length: 68")
+
+	// wrong version byte
+	_, err = DecodeSignedPayload("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ")
+	assert.EqualError(t, err, "invalid signed payload")
+}
+
+func TestContractAndLiquidityPool(t *testing.T) {
+	id := [32]byte{63, 12, 52, 191, 147, 173, 13, 153, 113, 208, 76, 204, 144, 247, 5, 81, 28, 131, 138, 173, 151, 52, 164, 162, 251, 13, 122, 3, 252, 127, 232, 154}
+
+	contract, err := EncodeContract(id)
+	require.NoError(t, err)
+	assert.Equal(t, "CA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUWDA", contract)
+	decoded, err := DecodeContract(contract)
+	require.NoError(t, err)
+	assert.Equal(t, id, decoded)
+	assert.True(t, IsValidContract(contract))
+
+	pool, err := EncodeLiquidityPool(id)
+	require.NoError(t, err)
+	assert.Equal(t, "LA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUPJN", pool)
+	decoded, err = DecodeLiquidityPool(pool)
+	require.NoError(t, err)
+	assert.Equal(t, id, decoded)
+	assert.True(t, IsValidLiquidityPool(pool))
+
+	// a contract address is not a liquidity pool address
+	assert.False(t, IsValidLiquidityPool(contract))
+
+	// 31 byte payload
+	_, err = DecodeContract("CA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UOXRY")
+	assert.EqualError(t, err, "invalid binary length: 31")
+}
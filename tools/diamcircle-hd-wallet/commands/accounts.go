@@ -3,55 +3,26 @@ package commands
 import (
 	"encoding/hex"
 	"fmt"
-	"regexp"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/diamcircle/go/exp/crypto/derivation"
 	"github.com/diamcircle/go/keypair"
 	"github.com/diamcircle/go/support/errors"
-	"github.com/tyler-smith/go-bip39"
 )
 
-var wordsRegexp = regexp.MustCompile(`^[a-z]+$`)
 var count, startID uint32
-
-var allowedNumbers = map[uint32]bool{12: true, 15: true, 18: true, 21: true, 24: true}
+var keyFileDir string
 
 var AccountsCmd = &cobra.Command{
 	Use:   "accounts",
 	Short: "Display accounts for a given mnemonic code",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		printf("How many words? ")
-		wordsCount := readUint()
-		if _, exist := allowedNumbers[wordsCount]; !exist {
-			return errors.New("Invalid value, allowed values: 12, 15, 18, 21, 24")
-		}
-
-		words := make([]string, wordsCount)
-		for i := uint32(0); i < wordsCount; i++ {
-			printf("Enter word #%-4d", i+1)
-			words[i] = readString()
-			if !wordsRegexp.MatchString(words[i]) {
-				println("Invalid word, try again.")
-				i--
-			}
-		}
-
-		printf("Enter password (leave empty if none): ")
-		password := readString()
-
-		mnemonic := strings.Join(words, " ")
-		println("Mnemonic:", mnemonic)
-
-		seed, err := bip39.NewSeedWithErrorChecking(mnemonic, password)
+		seed, err := readMnemonicSeed()
 		if err != nil {
-			return errors.New("Invalid words or checksum")
+			return err
 		}
 
-		println("BIP39 Seed:", hex.EncodeToString(seed))
-
 		masterKey, err := derivation.DeriveForPath(derivation.DiamcircleAccountPrefix, seed)
 		if err != nil {
 			return errors.Wrap(err, "Error deriving master key")
@@ -61,6 +32,14 @@ var AccountsCmd = &cobra.Command{
 
 		println("")
 
+		var keyFilePassword []byte
+		if keyFileDir != "" {
+			keyFilePassword, err = readNewPassword()
+			if err != nil {
+				return err
+			}
+		}
+
 		for i := uint32(startID); i < startID+count; i++ {
 			key, err := masterKey.Derive(derivation.FirstHardenedIndex + i)
 			if err != nil {
@@ -72,7 +51,17 @@ var AccountsCmd = &cobra.Command{
 				return errors.Wrap(err, "Error creating key pair")
 			}
 
-			println(fmt.Sprintf(derivation.DiamcircleAccountPathFormat, i), kp.Address(), kp.Seed())
+			path := fmt.Sprintf(derivation.DiamcircleAccountPathFormat, i)
+			if keyFileDir == "" {
+				println(path, kp.Address(), kp.Seed())
+				continue
+			}
+
+			filename, err := saveKeyFile(kp, keyFilePassword, path)
+			if err != nil {
+				return err
+			}
+			println(path, kp.Address(), filename)
 		}
 
 		return nil
@@ -82,4 +71,6 @@ var AccountsCmd = &cobra.Command{
 func init() {
 	AccountsCmd.Flags().Uint32VarP(&count, "count", "c", 10, "number of accounts to display")
 	AccountsCmd.Flags().Uint32VarP(&startID, "start", "s", 0, "ID of the first wallet to display")
+	AccountsCmd.Flags().StringVarP(&keyFileDir, "keyfile-dir", "k", "", "save accounts to encrypted key files in this directory instead of displaying secret seeds")
+	AccountsCmd.Flags().StringVarP(&language, "language", "l", "", "BIP-39 word list of the mnemonic code (default: detect)")
 }
@@ -82,6 +82,14 @@ m/44'/148'/6' GD7A7EACTPTBCYCURD43IEZXGIBCEXNBHN3OFWV2FOX67XKUIGRCTBNU SDJMWY4KF
 m/44'/148'/7' GAF4AGPVLQXFKEWQV3DZU5YEFU6YP7XJHAEEQH4G3R664MSF77FLLRK3 SDOJH5JRCNGT57QTPTJEQGBEBZJPXE7XUDYDB24VTOPP7PH3ALKHAHFG
 m/44'/148'/8' GABTYCZJMCP55SS6I46SR76IHETZDLG4L37MLZRZKQDGBLS5RMP65TSX SC6N6GYQ2VA4T7CUP2BWGBRT2P6L2HQSZIUNQRHNDLISF6ND7TW4P4ER
 m/44'/148'/9' GAKFARYSPI33KUJE7HYLT47DCX2PFWJ77W3LZMRBPSGPGYPMSDBE7W7X SALJ5LPBTXCFML2CQ7ORP7WJNJOZSVBVRQAAODMVHMUF4P4XXFZB7MKY`,
+		},
+		// French mnemonic and passphrase typed with precomposed characters:
+		{
+			Words:      "limonade turbine massif vinaigre parole calomnie oculaire infusion bison h\u00e9ron lundi physique",
+			Passphrase: "cl\u00e9 secr\u00e8te",
+			Want: `m/44'/148'/0' GAWA7KWT5PE3VQB7GU7S7IA7K7LWCDULG4QYYHY6DY7R7ZZFHU7B3H2R SD3UORBTKNMP47EVXKIOMEGJFNASHWMGXOY65JWHMQWHGRQIJDRP4SCR
+m/44'/148'/1' GBWRVASYAWLA3COYUNRCWEQ57WVOC74IRVDARIHG2FXA2ZQEKOXOR5H7 SCMBKPAT7SHVNYFEK47TGGALXDHIQLFSY5KEPMRDZB55RG2EDZVXQVAE
+m/44'/148'/2' GBYFGVJOLVJIDYSW4INIZDFXKGSDFYUI7GVE43DD7JZL44WIFKU53HCM SDB3FRZVHHDZG42GSNFGMA3QPMSEZOMCOBANNVJZJIV3D3XK3S7CLBLO`,
 		},
 		// Invalid:
 		{
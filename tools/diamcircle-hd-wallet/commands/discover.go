@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/diamcircle/go/clients/auroraclient"
+	"github.com/diamcircle/go/exp/crypto/derivation"
+	"github.com/diamcircle/go/keypair"
+	hProtocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/spf13/cobra"
+)
+
+var auroraURL string
+var gap uint32
+var exportFile, exportFormat string
+
+// newAuroraClient builds the client used by the discover command. It is a
+// variable so tests can replace it with a mock.
+var newAuroraClient = func(url string) auroraclient.ClientInterface {
+	return &auroraclient.Client{AuroraURL: url, HTTP: http.DefaultClient}
+}
+
+// discoveredAccount is a funded account found by the discover command.
+type discoveredAccount struct {
+	Path     string              `json:"path"`
+	Address  string              `json:"address"`
+	Balances []discoveredBalance `json:"balances"`
+}
+
+type discoveredBalance struct {
+	Asset   string `json:"asset"`
+	Balance string `json:"balance"`
+}
+
+var DiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Find funded accounts for a given mnemonic code",
+	Long: `Derives accounts for a given mnemonic code in order and looks each of them up
+in Aurora, stopping after --gap consecutive accounts which do not exist.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if gap == 0 {
+			return errors.New("Invalid value, --gap must be greater than 0")
+		}
+		if exportFormat != "json" && exportFormat != "csv" {
+			return errors.New("Invalid value, allowed export formats: json, csv")
+		}
+
+		seed, err := readMnemonicSeed()
+		if err != nil {
+			return err
+		}
+
+		masterKey, err := derivation.DeriveForPath(derivation.DiamcircleAccountPrefix, seed)
+		if err != nil {
+			return errors.Wrap(err, "Error deriving master key")
+		}
+
+		println("")
+
+		accounts, err := discoverAccounts(newAuroraClient(auroraURL), masterKey, gap)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			println(account.Path, account.Address)
+			for _, balance := range account.Balances {
+				println("  ", balance.Balance, balance.Asset)
+			}
+		}
+		printf("Found %d funded accounts.\n", len(accounts))
+
+		if exportFile == "" {
+			return nil
+		}
+
+		f, err := os.Create(exportFile)
+		if err != nil {
+			return errors.Wrap(err, "Error creating export file")
+		}
+		if err = exportAccounts(f, accounts, exportFormat); err != nil {
+			f.Close()
+			return errors.Wrap(err, "Error exporting balances")
+		}
+		if err = f.Close(); err != nil {
+			return errors.Wrap(err, "Error exporting balances")
+		}
+
+		println("Balances exported to", exportFile)
+		return nil
+	},
+}
+
+// discoverAccounts walks account indexes from 0 and returns every account that
+// exists on the network, stopping once gap consecutive accounts do not.
+func discoverAccounts(client auroraclient.ClientInterface, masterKey *derivation.Key, gap uint32) ([]discoveredAccount, error) {
+	var accounts []discoveredAccount
+
+	for i, unfunded := uint32(0), uint32(0); unfunded < gap; i++ {
+		key, err := masterKey.Derive(derivation.FirstHardenedIndex + i)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error deriving child key")
+		}
+
+		kp, err := keypair.FromRawSeed(key.RawSeed())
+		if err != nil {
+			return nil, errors.Wrap(err, "Error creating key pair")
+		}
+
+		account, err := client.AccountDetail(auroraclient.AccountRequest{AccountID: kp.Address()})
+		if auroraclient.IsNotFoundError(err) {
+			unfunded++
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "Error loading account %s", kp.Address())
+		}
+		unfunded = 0
+
+		accounts = append(accounts, discoveredAccount{
+			Path:     fmt.Sprintf(derivation.DiamcircleAccountPathFormat, i),
+			Address:  kp.Address(),
+			Balances: balancesOf(account),
+		})
+	}
+
+	return accounts, nil
+}
+
+func balancesOf(account hProtocol.Account) []discoveredBalance {
+	balances := make([]discoveredBalance, 0, len(account.Balances))
+	for _, b := range account.Balances {
+		var asset string
+		switch b.Type {
+		case "native":
+			asset = "native"
+		case "liquidity_pool_shares":
+			asset = "liquidity_pool:" + b.LiquidityPoolId
+		default:
+			asset = b.Code + ":" + b.Issuer
+		}
+		balances = append(balances, discoveredBalance{Asset: asset, Balance: b.Balance})
+	}
+	return balances
+}
+
+// exportAccounts writes the balances of accounts in the given format. Secret
+// seeds are never exported.
+func exportAccounts(w io.Writer, accounts []discoveredAccount, format string) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(accounts)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"path", "address", "asset", "balance"}); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		for _, balance := range account.Balances {
+			if err := cw.Write([]string{account.Path, account.Address, balance.Asset, balance.Balance}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func init() {
+	DiscoverCmd.Flags().StringVarP(&auroraURL, "aurora-url", "u", auroraclient.DefaultPublicNetClient.AuroraURL, "Aurora server used to look up accounts")
+	DiscoverCmd.Flags().Uint32VarP(&gap, "gap", "g", 20, "number of consecutive unfunded accounts after which discovery stops")
+	DiscoverCmd.Flags().StringVarP(&exportFile, "export", "e", "", "export the balances of discovered accounts to this file")
+	DiscoverCmd.Flags().StringVar(&exportFormat, "export-format", "json", "format of the export file: json or csv")
+	DiscoverCmd.Flags().StringVarP(&language, "language", "l", "", "BIP-39 word list of the mnemonic code (default: detect)")
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diamcircle/go/clients/auroraclient"
+	hProtocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/protocols/aurora/base"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diamcircle-hd-wallet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	notFound := &auroraclient.Error{Problem: problem.P{Type: "https://diamcircle.org/aurora-errors/not_found", Status: 404}}
+	client := &auroraclient.MockClient{}
+	accounts := []struct {
+		Address string
+		Funded  bool
+	}{
+		{"GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6", true},
+		{"GBAW5XGWORWVFE2XTJYDTLDHXTY2Q2MO73HYCGB3XMFMQ562Q2W2GJQX", false},
+		{"GAY5PRAHJ2HIYBYCLZXTHID6SPVELOOYH2LBPH3LD4RUMXUW3DOYTLXW", true},
+		{"GAOD5NRAEORFE34G5D4EOSKIJB6V4Z2FGPBCJNQI6MNICVITE6CSYIAE", false},
+		{"GBCUXLFLSL2JE3NWLHAWXQZN6SQC6577YMAU3M3BEMWKYPFWXBSRCWV4", false},
+		{"GBRQY5JFN5UBG5PGOSUOL4M6D7VRMAYU6WW2ZWXBMCKB7GPT3YCBU2XZ", false},
+	}
+	for _, account := range accounts {
+		request := auroraclient.AccountRequest{AccountID: account.Address}
+		if !account.Funded {
+			client.On("AccountDetail", request).Return(hProtocol.Account{}, notFound).Once()
+			continue
+		}
+		client.On("AccountDetail", request).Return(hProtocol.Account{
+			AccountID: account.Address,
+			Balances: []hProtocol.Balance{
+				{Balance: "10.0000000", Asset: base.Asset{Type: "credit_alphanum4", Code: "USD", Issuer: "GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"}},
+				{Balance: "100.0000000", Asset: base.Asset{Type: "native"}},
+			},
+		}, nil).Once()
+	}
+
+	newAuroraClient = func(url string) auroraclient.ClientInterface { return client }
+	exportPath := filepath.Join(dir, "balances.csv")
+	gap, exportFile, exportFormat = 3, exportPath, "csv"
+	defer func() { gap, exportFile, exportFormat = 20, "", "json" }()
+
+	words := strings.Split("illness spike retreat truth genius clock brain pass fit cave bargain toe", " ")
+	reader = bufio.NewReader(bytes.NewBufferString("12\n" + strings.Join(words, "\n") + "\n\n"))
+	out = &bytes.Buffer{}
+
+	err = DiscoverCmd.RunE(nil, []string{})
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+
+	output := out.(*bytes.Buffer).String()
+	assert.Contains(t, output, "Word list: english")
+	assert.Contains(t, output, "m/44'/148'/0' GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6")
+	assert.Contains(t, output, "m/44'/148'/2' GAY5PRAHJ2HIYBYCLZXTHID6SPVELOOYH2LBPH3LD4RUMXUW3DOYTLXW")
+	assert.NotContains(t, output, "GBAW5XGWORWVFE2XTJYDTLDHXTY2Q2MO73HYCGB3XMFMQ562Q2W2GJQX")
+	assert.Contains(t, output, "Found 2 funded accounts.")
+
+	exported, err := ioutil.ReadFile(exportPath)
+	require.NoError(t, err)
+	assert.Equal(t, `path,address,asset,balance
+m/44'/148'/0',GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6,USD:GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5,10.0000000
+m/44'/148'/0',GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6,native,100.0000000
+m/44'/148'/2',GAY5PRAHJ2HIYBYCLZXTHID6SPVELOOYH2LBPH3LD4RUMXUW3DOYTLXW,USD:GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5,10.0000000
+m/44'/148'/2',GAY5PRAHJ2HIYBYCLZXTHID6SPVELOOYH2LBPH3LD4RUMXUW3DOYTLXW,native,100.0000000
+`, string(exported))
+	assert.NotContains(t, string(exported), "SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN")
+}
+
+func TestMnemonicLanguage(t *testing.T) {
+	words := "illness spike retreat truth genius clock brain pass fit cave bargain toe"
+
+	name, err := findWordList(words)
+	require.NoError(t, err)
+	assert.Equal(t, "english", name)
+
+	language = "spanish"
+	defer func() { language = "" }()
+	_, err = findWordList(words)
+	assert.EqualError(t, err, "Invalid words or checksum")
+
+	language = "klingon"
+	_, err = findWordList(words)
+	assert.EqualError(t, err, "Unknown language klingon")
+}
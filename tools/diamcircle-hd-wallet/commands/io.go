@@ -8,11 +8,18 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/howeyc/gopass"
 )
 
 var reader = bufio.NewReader(os.Stdin)
 var out io.Writer = os.Stdout
 
+// readPassword reads a password from the terminal without echoing it.
+var readPassword = func(prompt string) ([]byte, error) {
+	return gopass.GetPasswdPrompt(prompt, true, os.Stdin, out)
+}
+
 func readString() string {
 	line, _ := reader.ReadString('\n')
 	return strings.TrimRight(line, "\r\n")
@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"bytes"
+	"path/filepath"
+
+	"github.com/diamcircle/go/keypair"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/spf13/cobra"
+)
+
+var KeyFileCmd = &cobra.Command{
+	Use:   "keyfile",
+	Short: "Manage encrypted key files",
+	Long:  "",
+}
+
+var keyFileShowCmd = &cobra.Command{
+	Use:   "show [file]",
+	Short: "Display the public metadata of a key file",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := keypair.LoadKeyFile(args[0])
+		if err != nil {
+			return errors.Wrap(err, "Error loading key file")
+		}
+
+		println("Address:", f.Address)
+		println("Label:", f.Label)
+		println("Created at:", f.CreatedAt.Format("2006-01-02T15:04:05Z07:00"))
+		println("KDF:", f.Crypto.KDF.Name)
+		return nil
+	},
+}
+
+var keyFilePasswdCmd = &cobra.Command{
+	Use:   "passwd [file]",
+	Short: "Change the password of a key file",
+	Long:  "",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := keypair.LoadKeyFile(args[0])
+		if err != nil {
+			return errors.Wrap(err, "Error loading key file")
+		}
+
+		oldPassword, err := readPassword("Enter current password: ")
+		if err != nil {
+			return err
+		}
+		// Check the current password before asking for the new one.
+		if _, err = f.Decrypt(oldPassword); err != nil {
+			return errors.New("Invalid password")
+		}
+
+		newPassword, err := readNewPassword()
+		if err != nil {
+			return err
+		}
+
+		if err = f.ChangePassword(oldPassword, newPassword); err != nil {
+			return errors.Wrap(err, "Error changing password")
+		}
+		if err = f.Save(args[0]); err != nil {
+			return errors.Wrap(err, "Error saving key file")
+		}
+
+		println("Password changed.")
+		return nil
+	},
+}
+
+// readNewPassword asks for a new key file password twice and returns it if
+// both entries match.
+func readNewPassword() ([]byte, error) {
+	password, err := readPassword("Enter new key file password: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(password) == 0 {
+		return nil, errors.New("Password must not be empty")
+	}
+
+	confirm, err := readPassword("Confirm new key file password: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(password, confirm) {
+		return nil, errors.New("Passwords do not match")
+	}
+	return password, nil
+}
+
+// saveKeyFile encrypts kp into <keyFileDir>/<address>.json and returns the
+// file name.
+func saveKeyFile(kp *keypair.Full, password []byte, label string) (string, error) {
+	f, err := keypair.NewKeyFile(kp, password, label)
+	if err != nil {
+		return "", errors.Wrap(err, "Error encrypting key file")
+	}
+
+	filename := filepath.Join(keyFileDir, kp.Address()+".json")
+	if err := f.Save(filename); err != nil {
+		return "", errors.Wrap(err, "Error saving key file")
+	}
+	return filename, nil
+}
+
+func init() {
+	KeyFileCmd.AddCommand(keyFileShowCmd)
+	KeyFileCmd.AddCommand(keyFilePasswdCmd)
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diamcircle/go/keypair"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountsKeyFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diamcircle-hd-wallet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	words := strings.Split("illness spike retreat truth genius clock brain pass fit cave bargain toe", " ")
+	input := "12\n" + strings.Join(words, "\n") + "\n\n"
+
+	origReader, origOut, origReadPassword := reader, out, readPassword
+	defer func() { reader, out, readPassword = origReader, origOut, origReadPassword }()
+	reader = bufio.NewReader(bytes.NewBufferString(input))
+	out = &bytes.Buffer{}
+	readPassword = func(prompt string) ([]byte, error) {
+		return []byte("hunter2"), nil
+	}
+	keyFileDir, count = dir, 2
+	defer func() { keyFileDir, count = "", 10 }()
+
+	err = AccountsCmd.RunE(nil, []string{})
+	require.NoError(t, err)
+
+	output := out.(*bytes.Buffer).String()
+	assert.NotContains(t, output, "SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN")
+
+	path := filepath.Join(dir, "GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6.json")
+	assert.Contains(t, output, "m/44'/148'/0' GDRXE2BQUC3AZNPVFSCEZ76NJ3WWL25FYFK6RGZGIEKWE4SOOHSUJUJ6 "+path)
+
+	f, err := keypair.LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "m/44'/148'/0'", f.Label)
+
+	kp, err := f.Decrypt([]byte("hunter2"))
+	require.NoError(t, err)
+	assert.Equal(t, "SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN", kp.Seed())
+
+	// Change the password of the first key file.
+	passwords := [][]byte{[]byte("hunter2"), []byte("correct horse"), []byte("correct horse")}
+	readPassword = func(prompt string) ([]byte, error) {
+		p := passwords[0]
+		passwords = passwords[1:]
+		return p, nil
+	}
+
+	err = keyFilePasswdCmd.RunE(nil, []string{path})
+	require.NoError(t, err)
+
+	f, err = keypair.LoadKeyFile(path)
+	require.NoError(t, err)
+	_, err = f.Decrypt([]byte("hunter2"))
+	assert.Equal(t, keypair.ErrWrongPassword, err)
+	kp, err = f.Decrypt([]byte("correct horse"))
+	require.NoError(t, err)
+	assert.Equal(t, "SBGWSG6BTNCKCOB3DIFBGCVMUPQFYPA2G4O34RMTB343OYPXU5DJDVMN", kp.Seed())
+}
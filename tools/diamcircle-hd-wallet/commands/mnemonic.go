@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/diamcircle/go/support/errors"
+	"github.com/tyler-smith/go-bip39"
+	"github.com/tyler-smith/go-bip39/wordlists"
+	"golang.org/x/text/unicode/norm"
+)
+
+// wordsRegexp matches a single word. Words are NFKD-normalized so accented
+// letters are split into a letter and combining marks.
+var wordsRegexp = regexp.MustCompile(`^[\p{L}\p{M}]+$`)
+
+var allowedNumbers = map[uint32]bool{12: true, 15: true, 18: true, 21: true, 24: true}
+
+// language forces the BIP-39 word list used to validate the mnemonic. When
+// empty every known word list is tried.
+var language string
+
+// wordLists are the BIP-39 word lists a mnemonic can be restored from, in the
+// order they are tried.
+var wordLists = []struct {
+	name  string
+	words []string
+}{
+	{"english", wordlists.English},
+	{"spanish", wordlists.Spanish},
+	{"french", wordlists.French},
+	{"italian", wordlists.Italian},
+	{"japanese", wordlists.Japanese},
+	{"korean", wordlists.Korean},
+	{"chinese_simplified", wordlists.ChineseSimplified},
+	{"chinese_traditional", wordlists.ChineseTraditional},
+	{"czech", wordlists.Czech},
+}
+
+// findWordList returns the name of the first word list (or only the one
+// selected with --language) in which mnemonic is valid, including its
+// checksum.
+func findWordList(mnemonic string) (string, error) {
+	defer bip39.SetWordList(wordlists.English)
+
+	known := false
+	for _, list := range wordLists {
+		if language != "" && language != list.name {
+			continue
+		}
+		known = true
+
+		bip39.SetWordList(list.words)
+		if bip39.IsMnemonicValid(mnemonic) {
+			return list.name, nil
+		}
+	}
+
+	if !known {
+		return "", errors.Errorf("Unknown language %s", language)
+	}
+	return "", errors.New("Invalid words or checksum")
+}
+
+// readMnemonicSeed asks for the words of a mnemonic code and an optional
+// BIP-39 passphrase and returns the seed they produce.
+func readMnemonicSeed() ([]byte, error) {
+	printf("How many words? ")
+	wordsCount := readUint()
+	if _, exist := allowedNumbers[wordsCount]; !exist {
+		return nil, errors.New("Invalid value, allowed values: 12, 15, 18, 21, 24")
+	}
+
+	words := make([]string, wordsCount)
+	for i := uint32(0); i < wordsCount; i++ {
+		printf("Enter word #%-4d", i+1)
+		words[i] = norm.NFKD.String(strings.ToLower(readString()))
+		if !wordsRegexp.MatchString(words[i]) {
+			println("Invalid word, try again.")
+			i--
+		}
+	}
+
+	printf("Enter password (leave empty if none): ")
+	// BIP-39 requires the mnemonic and the passphrase to be NFKD-normalized
+	// before deriving the seed. bip39.NewSeed does not normalize them.
+	password := norm.NFKD.String(readString())
+
+	mnemonic := strings.Join(words, " ")
+	println("Mnemonic:", mnemonic)
+
+	name, err := findWordList(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	println("Word list:", name)
+
+	seed := bip39.NewSeed(mnemonic, password)
+	println("BIP39 Seed:", hex.EncodeToString(seed))
+
+	return seed, nil
+}
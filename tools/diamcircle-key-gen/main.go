@@ -1,14 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"os"
 
+	"github.com/howeyc/gopass"
 	"github.com/spf13/cobra"
 	"github.com/diamcircle/go/keypair"
 )
 
+// readPassword reads a password from the terminal without echoing it.
+var readPassword = func(prompt string, w io.Writer) ([]byte, error) {
+	return gopass.GetPasswdPrompt(prompt, true, os.Stdin, w)
+}
+
 func main() {
 	exitCode := run(os.Args[1:], os.Stdout, os.Stderr)
 	os.Exit(exitCode)
@@ -23,9 +32,17 @@ func run(args []string, stdout io.Writer, stderr io.Writer) int {
 	cmd.SetOutput(stderr)
 
 	outFormat := "{{.PublicKey}}\n{{.SecretKey}}\n"
+	keyFile := ""
+	label := ""
 	cmd.Flags().StringVarP(&outFormat, "format", "f", outFormat, "Format of output")
+	cmd.Flags().StringVarP(&keyFile, "keyfile", "k", "", "Write the key to an encrypted key file instead of printing the secret key")
+	cmd.Flags().StringVarP(&label, "label", "l", "", "Label stored in the encrypted key file")
 
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if keyFile != "" && !cmd.Flags().Changed("format") {
+			outFormat = "{{.PublicKey}}\n"
+		}
+
 		tmpl, err := template.New("").Parse(outFormat)
 		if err != nil {
 			return err
@@ -41,6 +58,15 @@ func run(args []string, stdout io.Writer, stderr io.Writer) int {
 			SecretKey: key.Seed(),
 		}
 
+		if keyFile != "" {
+			err = writeKeyFile(key, keyFile, label, stderr)
+			if err != nil {
+				return err
+			}
+			data.SecretKey = ""
+			data.KeyFile = keyFile
+		}
+
 		err = tmpl.Execute(stdout, data)
 		if err != nil {
 			return err
@@ -56,7 +82,35 @@ func run(args []string, stdout io.Writer, stderr io.Writer) int {
 	return 0
 }
 
+func writeKeyFile(key *keypair.Full, path, label string, stderr io.Writer) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	password, err := readPassword("Enter key file password: ", stderr)
+	if err != nil {
+		return err
+	}
+	if len(password) == 0 {
+		return errors.New("password must not be empty")
+	}
+	confirm, err := readPassword("Confirm key file password: ", stderr)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(password, confirm) {
+		return errors.New("passwords do not match")
+	}
+
+	f, err := keypair.NewKeyFile(key, password, label)
+	if err != nil {
+		return err
+	}
+	return f.Save(path)
+}
+
 type outData struct {
 	PublicKey string
 	SecretKey string
+	KeyFile   string
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/diamcircle/go/keypair"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestRun_defaultFormat(t *testing.T) {
@@ -122,3 +127,48 @@ func TestRun_random(t *testing.T) {
 		})
 	}
 }
+
+func TestRun_keyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diamcircle-key-gen")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	readPassword = func(prompt string, w io.Writer) ([]byte, error) {
+		return []byte("hunter2"), nil
+	}
+
+	path := filepath.Join(dir, "key.json")
+	args := []string{
+		"--keyfile", path,
+		"--label", "test key",
+	}
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+
+	exitCode := run(args, &stdout, &stderr)
+
+	t.Logf("exit code: %d", exitCode)
+	t.Logf("stdout: %q", stdout.String())
+	t.Logf("stderr: %q", stderr.String())
+
+	// Exit code should be zero for success.
+	assert.Equal(t, 0, exitCode)
+
+	// Stdout should only contain the public key.
+	lines := strings.Split(stdout.String(), "\n")
+	require.Len(t, lines, 2)
+
+	// The key file should decrypt to the printed public key.
+	f, err := keypair.LoadKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "test key", f.Label)
+	kp, err := f.Decrypt([]byte("hunter2"))
+	require.NoError(t, err)
+	assert.Equal(t, kp.Address(), lines[0])
+
+	// Generating into an existing key file should fail.
+	stdout.Reset()
+	exitCode = run(args, &stdout, &stderr)
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, stderr.String(), "already exists")
+}
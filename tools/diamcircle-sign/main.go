@@ -23,6 +23,7 @@ import (
 var in *bufio.Reader
 
 var infile = flag.String("infile", "", "transaction envelope")
+var keyfile = flag.String("keyfile", "", "encrypted key file to sign with instead of entering a seed")
 
 func main() {
 	flag.Parse()
@@ -77,17 +78,39 @@ func main() {
 
 	// TODO: add operation details
 
-	// read seed
-	seed, err := readLine("Enter seed: ", true)
-	if err != nil {
-		log.Fatal(err)
+	// read the signing key
+	var kp *keypair.Full
+	if *keyfile == "" {
+		var seed string
+		seed, err = readLine("Enter seed: ", true)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		kp, err = keypair.ParseFull(seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		var f *keypair.KeyFile
+		f, err = keypair.LoadKeyFile(*keyfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var password string
+		password, err = readLine(fmt.Sprintf("Enter password for %s: ", f.Address), true)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		kp, err = f.Decrypt([]byte(password))
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// sign the transaction
-	kp, err := keypair.ParseFull(seed)
-	if err != nil {
-		log.Fatal(err)
-	}
 
 	parsed, err := txnbuild.TransactionFromXDR(env)
 	if err != nil {
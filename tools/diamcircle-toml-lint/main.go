@@ -0,0 +1,163 @@
+// diamcircle-toml-lint checks a diamcircle.toml file against SEP-1 and
+// cross-checks the accounts and assets it lists against the network.
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/diamcircle/go/clients/auroraclient"
+	"github.com/diamcircle/go/clients/diamcircletoml"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	exitCode := run(os.Args[1:], os.Stdout, os.Stderr)
+	os.Exit(exitCode)
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	cmd := &cobra.Command{
+		Use:   "diamcircle-toml-lint [domain]",
+		Short: "Validate a diamcircle.toml file against SEP-1 and the network.",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	cmd.SetArgs(args)
+	cmd.SetOutput(stderr)
+
+	file := ""
+	auroraURL := auroraclient.DefaultPublicNetClient.AuroraURL
+	offline := false
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Lint a local diamcircle.toml file instead of fetching it from the domain")
+	cmd.Flags().StringVarP(&auroraURL, "aurora-url", "u", auroraURL, "Aurora server used to cross-check accounts and assets")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Skip the checks which require Aurora")
+
+	problems := 0
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		domain := ""
+		if len(args) == 1 {
+			domain = args[0]
+		}
+		if domain == "" && file == "" {
+			return fmt.Errorf("a domain or --file is required")
+		}
+
+		resp, err := load(domain, file)
+		if err != nil {
+			return err
+		}
+
+		var client auroraclient.ClientInterface
+		if !offline {
+			client = &auroraclient.Client{AuroraURL: auroraURL, HTTP: http.DefaultClient}
+		}
+
+		problems = lint(resp, domain, client, stdout)
+		if problems == 0 {
+			fmt.Fprintln(stdout, "OK")
+		}
+		return nil
+	}
+
+	err := cmd.Execute()
+	if err != nil || problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// load reads the diamcircle.toml file from disk when file is set, otherwise
+// from the domain's well known location.
+func load(domain, file string) (*diamcircletoml.Response, error) {
+	if file == "" {
+		return diamcircletoml.DefaultClient.GetDiamcircleToml(domain)
+	}
+
+	var resp diamcircletoml.Response
+	if _, err := toml.DecodeFile(file, &resp); err != nil {
+		return nil, fmt.Errorf("toml decode failed: %v", err)
+	}
+	return &resp, nil
+}
+
+// lint prints every problem found with resp to out and returns the number of
+// problems. When client is nil only the SEP-1 checks are run. When domain is
+// empty the issuers' home domains are not checked.
+func lint(resp *diamcircletoml.Response, domain string, client auroraclient.ClientInterface, out io.Writer) int {
+	problems := 0
+	report := func(format string, a ...interface{}) {
+		problems++
+		fmt.Fprintf(out, format+"\n", a...)
+	}
+
+	if err := resp.Validate(); err != nil {
+		for _, verr := range err.(diamcircletoml.ValidationErrors) {
+			report("%s", verr.Error())
+		}
+	}
+
+	if client == nil {
+		return problems
+	}
+
+	root, err := client.Root()
+	if err != nil {
+		report("aurora: %v", err)
+		return problems
+	}
+	if resp.NetworkPassphrase != "" && resp.NetworkPassphrase != root.NetworkPassphrase {
+		report("NETWORK_PASSPHRASE: does not match the aurora network passphrase %q", root.NetworkPassphrase)
+	}
+
+	for i, address := range resp.Accounts {
+		_, err := client.AccountDetail(auroraclient.AccountRequest{AccountID: address})
+		if auroraclient.IsNotFoundError(err) {
+			report("ACCOUNTS[%d]: account %s does not exist", i, address)
+		} else if err != nil {
+			report("ACCOUNTS[%d]: %v", i, err)
+		}
+	}
+
+	for i, currency := range resp.Currencies {
+		if currency.Issuer == "" {
+			continue
+		}
+		field := fmt.Sprintf("CURRENCIES[%d]", i)
+
+		issuer, err := client.AccountDetail(auroraclient.AccountRequest{AccountID: currency.Issuer})
+		if auroraclient.IsNotFoundError(err) {
+			report("%s.issuer: account %s does not exist", field, currency.Issuer)
+			continue
+		} else if err != nil {
+			report("%s.issuer: %v", field, err)
+			continue
+		}
+
+		if domain != "" && !strings.EqualFold(issuer.HomeDomain, domain) {
+			report("%s.issuer: home_domain of %s is %q, expected %q", field, currency.Issuer, issuer.HomeDomain, domain)
+		}
+
+		// Templated codes describe a family of assets which can't be looked
+		// up individually.
+		if currency.Code == "" || currency.Status == "dead" {
+			continue
+		}
+
+		assets, err := client.Assets(auroraclient.AssetRequest{
+			ForAssetCode:   currency.Code,
+			ForAssetIssuer: currency.Issuer,
+			Limit:          1,
+		})
+		if err != nil {
+			report("%s: %v", field, err)
+		} else if len(assets.Embedded.Records) == 0 {
+			report("%s: asset %s:%s does not exist", field, currency.Code, currency.Issuer)
+		}
+	}
+
+	return problems
+}
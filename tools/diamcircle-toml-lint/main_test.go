@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/diamcircle/go/clients/auroraclient"
+	"github.com/diamcircle/go/clients/diamcircletoml"
+	hProtocol "github.com/diamcircle/go/protocols/aurora"
+	"github.com/diamcircle/go/support/render/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	issuer  = "GA2C5RFPE6GCKMY3US5PAB6UZLKIGSPIUKSLRB6Q723BM2OARMDUYEJ5"
+	account = "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ"
+)
+
+func TestLint_offline(t *testing.T) {
+	resp := &diamcircletoml.Response{
+		FederationServer: "http://example.com/federation",
+	}
+
+	out := strings.Builder{}
+	problems := lint(resp, "example.com", nil, &out)
+
+	assert.Equal(t, 2, problems)
+	assert.Equal(t, "NETWORK_PASSPHRASE: is required\nFEDERATION_SERVER: must use https\n", out.String())
+}
+
+func TestLint_aurora(t *testing.T) {
+	resp := &diamcircletoml.Response{
+		NetworkPassphrase: "Test Network",
+		Accounts:          []string{account},
+		Currencies: []diamcircletoml.Currency{
+			{Code: "USD", Issuer: issuer},
+		},
+	}
+
+	notFound := &auroraclient.Error{Problem: problem.P{Type: "https://diamcircle.org/aurora-errors/not_found", Status: 404}}
+
+	client := &auroraclient.MockClient{}
+	client.On("Root").Return(hProtocol.Root{NetworkPassphrase: "Other Network"}, nil)
+	client.On("AccountDetail", auroraclient.AccountRequest{AccountID: account}).
+		Return(hProtocol.Account{}, notFound)
+	client.On("AccountDetail", auroraclient.AccountRequest{AccountID: issuer}).
+		Return(hProtocol.Account{HomeDomain: "elsewhere.com"}, nil)
+	client.On("Assets", auroraclient.AssetRequest{ForAssetCode: "USD", ForAssetIssuer: issuer, Limit: 1}).
+		Return(hProtocol.AssetsPage{}, nil)
+
+	out := strings.Builder{}
+	problems := lint(resp, "example.com", client, &out)
+
+	assert.Equal(t, 4, problems)
+	assert.Equal(t, strings.Join([]string{
+		`NETWORK_PASSPHRASE: does not match the aurora network passphrase "Other Network"`,
+		`ACCOUNTS[0]: account ` + account + ` does not exist`,
+		`CURRENCIES[0].issuer: home_domain of ` + issuer + ` is "elsewhere.com", expected "example.com"`,
+		`CURRENCIES[0]: asset USD:` + issuer + ` does not exist`,
+		``,
+	}, "\n"), out.String())
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,106 @@
+package txnbuild
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/diamcircle/go/support/errors"
+)
+
+// HTTPSigner is a KeySigner which delegates signing to a remote service,
+// so that the secret key never needs to be present on the application host.
+//
+// For every signature the signer POSTs a JSON document of the form
+//
+//	{"public_key": "G...", "payload": "<base64 payload>"}
+//
+// to URL and expects a 200 response of the form
+//
+//	{"signature": "<base64 ed25519 signature>"}
+type HTTPSigner struct {
+	// URL is the endpoint of the remote signing service.
+	URL string
+	// Address is the public key of the account key held by the service.
+	Address string
+	// Header is added to every request, e.g. to authenticate with the service.
+	Header http.Header
+	// HTTP is the client used to make requests. http.DefaultClient is used if
+	// it is nil.
+	HTTP *http.Client
+}
+
+type httpSignerRequest struct {
+	PublicKey string `json:"public_key"`
+	Payload   string `json:"payload"`
+}
+
+type httpSignerResponse struct {
+	Signature string `json:"signature"`
+}
+
+// Hint implements KeySigner.
+func (s *HTTPSigner) Hint() [4]byte {
+	return hintForAddress(s.Address)
+}
+
+// PublicKey implements KeySigner.
+func (s *HTTPSigner) PublicKey() string {
+	return s.Address
+}
+
+// Sign implements KeySigner.
+func (s *HTTPSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	body, err := json.Marshal(httpSignerRequest{
+		PublicKey: s.Address,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create signing request")
+	}
+	req = req.WithContext(ctx)
+	for key, values := range s.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "signing request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Drain a little of the body so the error is useful without
+		// trusting the remote service to bound its response.
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, errors.Errorf("signing service returned %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+
+	var signed httpSignerResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&signed); err != nil {
+		return nil, errors.Wrap(err, "failed to decode signing response")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to base64-decode the signature")
+	}
+	return sig, nil
+}
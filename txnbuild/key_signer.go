@@ -0,0 +1,112 @@
+package txnbuild
+
+import (
+	"context"
+
+	"github.com/diamcircle/go/keypair"
+	"github.com/diamcircle/go/network"
+	"github.com/diamcircle/go/strkey"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/xdr"
+)
+
+// KeySigner produces signatures for a single account key. Unlike
+// *keypair.Full the secret key does not have to be held in process memory:
+// implementations may forward the payload to a remote signing service or a
+// hardware token.
+type KeySigner interface {
+	// Hint returns the last four bytes of the signer's public key.
+	Hint() [4]byte
+	// PublicKey returns the signer's public key as a G... address.
+	PublicKey() string
+	// Sign returns the ed25519 signature of payload.
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// KeypairSigner is a KeySigner backed by an in-memory keypair.
+type KeypairSigner struct {
+	KP *keypair.Full
+}
+
+// NewKeypairSigner returns a KeySigner which signs with kp.
+func NewKeypairSigner(kp *keypair.Full) *KeypairSigner {
+	return &KeypairSigner{KP: kp}
+}
+
+// Hint implements KeySigner.
+func (s *KeypairSigner) Hint() [4]byte {
+	return s.KP.Hint()
+}
+
+// PublicKey implements KeySigner.
+func (s *KeypairSigner) PublicKey() string {
+	return s.KP.Address()
+}
+
+// Sign implements KeySigner.
+func (s *KeypairSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	return s.KP.Sign(payload)
+}
+
+// hintForAddress returns the signature hint of a G... address, or the zero
+// hint if the address is invalid.
+func hintForAddress(address string) (r [4]byte) {
+	raw, err := strkey.Decode(strkey.VersionByteAccountID, address)
+	if err != nil {
+		return
+	}
+	copy(r[:], raw[len(raw)-4:])
+	return
+}
+
+// signDecoratedWith signs payload with signer and checks the returned
+// signature against the signer's public key, so that a misbehaving remote
+// signer cannot add an invalid signature to a transaction.
+func signDecoratedWith(ctx context.Context, signer KeySigner, payload []byte) (xdr.DecoratedSignature, error) {
+	kp, err := keypair.ParseAddress(signer.PublicKey())
+	if err != nil {
+		return xdr.DecoratedSignature{}, errors.Wrapf(err, "failed to parse the public key %s", signer.PublicKey())
+	}
+
+	sig, err := signer.Sign(ctx, payload)
+	if err != nil {
+		return xdr.DecoratedSignature{}, errors.Wrapf(err, "failed to sign with %s", signer.PublicKey())
+	}
+
+	if err = kp.Verify(payload, sig); err != nil {
+		return xdr.DecoratedSignature{}, errors.Wrapf(err, "invalid signature returned by %s", signer.PublicKey())
+	}
+
+	return xdr.DecoratedSignature{
+		Hint:      xdr.SignatureHint(signer.Hint()),
+		Signature: xdr.Signature(sig),
+	}, nil
+}
+
+func concatSignaturesWith(
+	ctx context.Context,
+	e xdr.TransactionEnvelope,
+	networkStr string,
+	signatures []xdr.DecoratedSignature,
+	signers ...KeySigner,
+) ([]xdr.DecoratedSignature, error) {
+	h, err := network.HashTransactionInEnvelope(e, networkStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash transaction")
+	}
+
+	extended := make(
+		[]xdr.DecoratedSignature,
+		len(signatures),
+		len(signatures)+len(signers),
+	)
+	copy(extended, signatures)
+	for _, signer := range signers {
+		sig, err := signDecoratedWith(ctx, signer, h[:])
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign transaction")
+		}
+		extended = append(extended, sig)
+	}
+	return extended, nil
+}
@@ -0,0 +1,149 @@
+package txnbuild
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/diamcircle/go/keypair"
+	"github.com/diamcircle/go/network"
+	"github.com/diamcircle/go/strkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSignerTestTransaction(t *testing.T) *Transaction {
+	kp0 := newKeypair0()
+	txSource := NewSimpleAccount(kp0.Address(), int64(9605939170639897))
+	tx, err := NewTransaction(
+		TransactionParams{
+			SourceAccount:        &txSource,
+			IncrementSequenceNum: true,
+			Operations:           []Operation{&BumpSequence{BumpTo: 9606132444168300}},
+			BaseFee:              MinBaseFee,
+			Timebounds:           NewInfiniteTimeout(),
+		},
+	)
+	require.NoError(t, err)
+	return tx
+}
+
+// signingHandler is a minimal remote signing service for HTTPSigner.
+func signingHandler(t *testing.T, kp *keypair.Full) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var req httpSignerRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.PublicKey != kp.Address() {
+			http.Error(w, "unknown key", http.StatusNotFound)
+			return
+		}
+
+		payload, err := base64.StdEncoding.DecodeString(req.Payload)
+		require.NoError(t, err)
+		sig, err := kp.SignBase64(payload)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(httpSignerResponse{Signature: sig}))
+	}
+}
+
+type softToken struct {
+	keys map[string]ed25519.PrivateKey
+}
+
+func (s softToken) SignEdDSA(ctx context.Context, keyLabel string, data []byte) ([]byte, error) {
+	return ed25519.Sign(s.keys[keyLabel], data), nil
+}
+
+type badSigner struct {
+	*KeypairSigner
+}
+
+func (s badSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	return s.KeypairSigner.Sign(ctx, []byte("something else"))
+}
+
+func TestSignWithSigner(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+	kp2 := newKeypair2()
+	tx := newSignerTestTransaction(t)
+
+	expected, err := tx.Sign(network.TestNetworkPassphrase, kp0, kp1, kp2)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(signingHandler(t, kp1))
+	defer server.Close()
+
+	token := softToken{keys: map[string]ed25519.PrivateKey{
+		"diamcircle": ed25519.NewKeyFromSeed(strkey.MustDecode(strkey.VersionByteSeed, kp2.Seed())),
+	}}
+
+	signed, err := tx.SignWithSigner(
+		context.Background(),
+		network.TestNetworkPassphrase,
+		NewKeypairSigner(kp0),
+		&HTTPSigner{
+			URL:     server.URL,
+			Address: kp1.Address(),
+			Header:  http.Header{"Authorization": []string{"Bearer token"}},
+		},
+		&PKCS11Signer{Session: token, KeyLabel: "diamcircle", Address: kp2.Address()},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected.Signatures(), signed.Signatures())
+	// The original transaction must not be modified.
+	assert.Empty(t, tx.Signatures())
+}
+
+func TestSignWithSignerRejectsInvalidSignature(t *testing.T) {
+	tx := newSignerTestTransaction(t)
+
+	_, err := tx.SignWithSigner(
+		context.Background(),
+		network.TestNetworkPassphrase,
+		badSigner{NewKeypairSigner(newKeypair0())},
+	)
+	assert.EqualError(t, err, "failed to sign transaction: invalid signature returned by GDQNY3PBOJOKYZSRMK2S7LHHGWZIUISD4QORETLMXEWXBI7KFZZMKTL3: signature verification failed")
+}
+
+func TestHTTPSignerErrors(t *testing.T) {
+	server := httptest.NewServer(signingHandler(t, newKeypair1()))
+	defer server.Close()
+
+	signer := &HTTPSigner{
+		URL:     server.URL,
+		Address: newKeypair0().Address(),
+		Header:  http.Header{"Authorization": []string{"Bearer token"}},
+	}
+	_, err := signer.Sign(context.Background(), []byte("payload"))
+	assert.EqualError(t, err, "signing service returned 404: unknown key")
+}
+
+func TestFeeBumpSignWithSigner(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+
+	inner, err := newSignerTestTransaction(t).Sign(network.TestNetworkPassphrase, kp0)
+	require.NoError(t, err)
+
+	feeBump, err := NewFeeBumpTransaction(FeeBumpTransactionParams{
+		Inner:      inner,
+		FeeAccount: kp1.Address(),
+		BaseFee:    MinBaseFee,
+	})
+	require.NoError(t, err)
+
+	expected, err := feeBump.Sign(network.TestNetworkPassphrase, kp1)
+	require.NoError(t, err)
+
+	signed, err := feeBump.SignWithSigner(context.Background(), network.TestNetworkPassphrase, NewKeypairSigner(kp1))
+	require.NoError(t, err)
+	assert.Equal(t, expected.Signatures(), signed.Signatures())
+}
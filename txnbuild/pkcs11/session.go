@@ -0,0 +1,231 @@
+// Package pkcs11 implements txnbuild.PKCS11Session on top of a PKCS#11
+// library, such as SoftHSM or the library shipped with a hardware security
+// module. It requires cgo.
+package pkcs11
+
+import (
+	"context"
+	"sync"
+
+	p11 "github.com/miekg/pkcs11"
+
+	"github.com/diamcircle/go/strkey"
+	"github.com/diamcircle/go/support/errors"
+	"github.com/diamcircle/go/txnbuild"
+)
+
+// Mechanism and key type values defined by PKCS#11 v3.0 for Ed25519 keys.
+const (
+	ckmECEdwardsKeyPairGen = 0x00001055
+	ckmEdDSA               = 0x00001057
+	ckkECEdwards           = 0x00000040
+)
+
+// ed25519Params is the DER encoding of the Ed25519 curve OID (1.3.101.112),
+// used as CKA_EC_PARAMS of Ed25519 keys.
+var ed25519Params = []byte{0x06, 0x03, 0x2b, 0x65, 0x70}
+
+// Config configures the token a Session is opened on.
+type Config struct {
+	// ModulePath is the path of the PKCS#11 library, for example
+	// /usr/lib/softhsm/libsofthsm2.so.
+	ModulePath string
+	// TokenLabel is the label of the token holding the keys.
+	TokenLabel string
+	// PIN is the user PIN of the token.
+	PIN string
+}
+
+// Session is a logged-in session on a PKCS#11 token. It implements
+// txnbuild.PKCS11Session and is safe for concurrent use: PKCS#11 sessions
+// are not, so calls are serialized.
+type Session struct {
+	lock    sync.Mutex
+	ctx     *p11.Ctx
+	session p11.SessionHandle
+}
+
+var _ txnbuild.PKCS11Session = (*Session)(nil)
+
+// Open loads the PKCS#11 library, opens a session on the token with the
+// configured label and logs in as user.
+func Open(config Config) (*Session, error) {
+	ctx := p11.New(config.ModulePath)
+	if ctx == nil {
+		return nil, errors.Errorf("cannot load PKCS#11 library %s", config.ModulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, errors.Wrap(err, "cannot initialize PKCS#11 library")
+	}
+
+	session, err := openTokenSession(ctx, config)
+	if err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return &Session{ctx: ctx, session: session}, nil
+}
+
+func openTokenSession(ctx *p11.Ctx, config Config) (p11.SessionHandle, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot list PKCS#11 slots")
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, errors.Wrapf(err, "cannot get token info of slot %d", slot)
+		}
+		if info.Label != config.TokenLabel {
+			continue
+		}
+
+		session, err := ctx.OpenSession(slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+		if err != nil {
+			return 0, errors.Wrapf(err, "cannot open session on token %q", config.TokenLabel)
+		}
+		err = ctx.Login(session, p11.CKU_USER, config.PIN)
+		if err != nil && err != p11.Error(p11.CKR_USER_ALREADY_LOGGED_IN) {
+			ctx.CloseSession(session)
+			return 0, errors.Wrapf(err, "cannot log in to token %q", config.TokenLabel)
+		}
+		return session, nil
+	}
+	return 0, errors.Errorf("token %q not found", config.TokenLabel)
+}
+
+// Close logs out, closes the session and unloads the PKCS#11 library.
+func (s *Session) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ctx.Logout(s.session)
+	err := s.ctx.CloseSession(s.session)
+	s.ctx.Finalize()
+	s.ctx.Destroy()
+	return errors.Wrap(err, "cannot close PKCS#11 session")
+}
+
+// findKey returns the handle of the single key object of the given class
+// labelled keyLabel. The caller must hold the lock.
+func (s *Session) findKey(class uint, keyLabel string) (p11.ObjectHandle, error) {
+	template := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, class),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, ckkECEdwards),
+		p11.NewAttribute(p11.CKA_LABEL, keyLabel),
+	}
+	if err := s.ctx.FindObjectsInit(s.session, template); err != nil {
+		return 0, errors.Wrap(err, "cannot search PKCS#11 objects")
+	}
+	objects, _, err := s.ctx.FindObjects(s.session, 2)
+	finalErr := s.ctx.FindObjectsFinal(s.session)
+	if err != nil {
+		return 0, errors.Wrap(err, "cannot search PKCS#11 objects")
+	}
+	if finalErr != nil {
+		return 0, errors.Wrap(finalErr, "cannot search PKCS#11 objects")
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, errors.Errorf("no Ed25519 key labelled %q", keyLabel)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, errors.Errorf("more than one Ed25519 key labelled %q", keyLabel)
+	}
+}
+
+// SignEdDSA implements txnbuild.PKCS11Session.
+func (s *Session) SignEdDSA(ctx context.Context, keyLabel string, data []byte) ([]byte, error) {
+	// PKCS#11 calls cannot be interrupted.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, err := s.findKey(p11.CKO_PRIVATE_KEY, keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	mechanism := []*p11.Mechanism{p11.NewMechanism(ckmEdDSA, nil)}
+	if err := s.ctx.SignInit(s.session, mechanism, key); err != nil {
+		return nil, errors.Wrap(err, "cannot initialize EdDSA signing")
+	}
+	signature, err := s.ctx.Sign(s.session, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot sign")
+	}
+	return signature, nil
+}
+
+// Address returns the G... address of the Ed25519 public key labelled
+// keyLabel.
+func (s *Session) Address(keyLabel string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key, err := s.findKey(p11.CKO_PUBLIC_KEY, keyLabel)
+	if err != nil {
+		return "", err
+	}
+	attributes, err := s.ctx.GetAttributeValue(s.session, key, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get public key")
+	}
+	point := attributes[0].Value
+	// Tokens return the point either raw or as a DER OCTET STRING.
+	if len(point) == 34 && point[0] == 0x04 && point[1] == 32 {
+		point = point[2:]
+	}
+	if len(point) != 32 {
+		return "", errors.Errorf("unexpected Ed25519 public key length %d", len(point))
+	}
+	return strkey.Encode(strkey.VersionByteAccountID, point)
+}
+
+// GenerateKey generates an Ed25519 key pair labelled keyLabel on the token
+// and returns the address of its public key. The private key cannot be
+// extracted from the token.
+func (s *Session) GenerateKey(keyLabel string) (string, error) {
+	s.lock.Lock()
+	public := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_VERIFY, true),
+		p11.NewAttribute(p11.CKA_LABEL, keyLabel),
+		p11.NewAttribute(p11.CKA_EC_PARAMS, ed25519Params),
+	}
+	private := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_PRIVATE, true),
+		p11.NewAttribute(p11.CKA_SIGN, true),
+		p11.NewAttribute(p11.CKA_SENSITIVE, true),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+		p11.NewAttribute(p11.CKA_LABEL, keyLabel),
+	}
+	mechanism := []*p11.Mechanism{p11.NewMechanism(ckmECEdwardsKeyPairGen, nil)}
+	_, _, err := s.ctx.GenerateKeyPair(s.session, mechanism, public, private)
+	s.lock.Unlock()
+	if err != nil {
+		return "", errors.Wrap(err, "cannot generate Ed25519 key pair")
+	}
+	return s.Address(keyLabel)
+}
+
+// NewSigner returns a txnbuild.PKCS11Signer signing with the Ed25519 key
+// labelled keyLabel, with its address read from the token.
+func NewSigner(session *Session, keyLabel string) (*txnbuild.PKCS11Signer, error) {
+	address, err := session.Address(keyLabel)
+	if err != nil {
+		return nil, err
+	}
+	return &txnbuild.PKCS11Signer{
+		Session:  session,
+		KeyLabel: keyLabel,
+		Address:  address,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package pkcs11
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/diamcircle/go/keypair"
+	"github.com/diamcircle/go/network"
+	"github.com/diamcircle/go/txnbuild"
+)
+
+// TestSoftHSMSigner signs a transaction with a key generated on a SoftHSM
+// token. It runs when PKCS11_MODULE is set, for example after:
+//
+//	softhsm2-util --init-token --free --label test --pin 1234 --so-pin 5678
+//	PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so PKCS11_TOKEN_LABEL=test PKCS11_PIN=1234 go test ./txnbuild/pkcs11
+func TestSoftHSMSigner(t *testing.T) {
+	modulePath := os.Getenv("PKCS11_MODULE")
+	if modulePath == "" {
+		t.Skip("PKCS11_MODULE is not set")
+	}
+
+	session, err := Open(Config{
+		ModulePath: modulePath,
+		TokenLabel: os.Getenv("PKCS11_TOKEN_LABEL"),
+		PIN:        os.Getenv("PKCS11_PIN"),
+	})
+	require.NoError(t, err)
+	defer session.Close()
+
+	keyLabel := fmt.Sprintf("txnbuild-test-%d", time.Now().UnixNano())
+	address, err := session.GenerateKey(keyLabel)
+	require.NoError(t, err)
+
+	signer, err := NewSigner(session, keyLabel)
+	require.NoError(t, err)
+	require.Equal(t, address, signer.PublicKey())
+
+	_, err = NewSigner(session, "missing-"+keyLabel)
+	require.EqualError(t, err, fmt.Sprintf("no Ed25519 key labelled %q", "missing-"+keyLabel))
+
+	source := txnbuild.NewSimpleAccount(address, 1)
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &source,
+		IncrementSequenceNum: true,
+		Operations:           []txnbuild.Operation{&txnbuild.BumpSequence{BumpTo: 10}},
+		BaseFee:              txnbuild.MinBaseFee,
+		Timebounds:           txnbuild.NewInfiniteTimeout(),
+	})
+	require.NoError(t, err)
+
+	// SignWithSigner verifies the signature returned by the token.
+	signed, err := tx.SignWithSigner(context.Background(), network.TestNetworkPassphrase, signer)
+	require.NoError(t, err)
+	require.Len(t, signed.Signatures(), 1)
+
+	hash, err := signed.Hash(network.TestNetworkPassphrase)
+	require.NoError(t, err)
+	kp, err := keypair.ParseAddress(address)
+	require.NoError(t, err)
+	require.NoError(t, kp.Verify(hash[:], signed.Signatures()[0].Signature))
+}
@@ -0,0 +1,45 @@
+package txnbuild
+
+import (
+	"context"
+
+	"github.com/diamcircle/go/support/errors"
+)
+
+// PKCS11Session is the subset of a logged-in PKCS#11 session used by
+// PKCS11Signer. The txnbuild/pkcs11 package implements it with a PKCS#11
+// library; it is kept out of this package as it requires cgo.
+type PKCS11Session interface {
+	// SignEdDSA signs data with the Ed25519 private key object labelled
+	// keyLabel on the token.
+	SignEdDSA(ctx context.Context, keyLabel string, data []byte) ([]byte, error)
+}
+
+// PKCS11Signer is a KeySigner backed by an Ed25519 key held on a PKCS#11
+// token. The secret key never leaves the token.
+type PKCS11Signer struct {
+	Session PKCS11Session
+	// KeyLabel is the CKA_LABEL of the private key object on the token.
+	KeyLabel string
+	// Address is the public key matching the private key on the token.
+	Address string
+}
+
+// Hint implements KeySigner.
+func (s *PKCS11Signer) Hint() [4]byte {
+	return hintForAddress(s.Address)
+}
+
+// PublicKey implements KeySigner.
+func (s *PKCS11Signer) PublicKey() string {
+	return s.Address
+}
+
+// Sign implements KeySigner.
+func (s *PKCS11Signer) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	sig, err := s.Session.SignEdDSA(ctx, s.KeyLabel, payload)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pkcs11 signing with key %q failed", s.KeyLabel)
+	}
+	return sig, nil
+}
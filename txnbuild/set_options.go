@@ -1,6 +1,7 @@
 package txnbuild
 
 import (
+	"github.com/diamcircle/go/strkey"
 	"github.com/diamcircle/go/support/errors"
 	"github.com/diamcircle/go/xdr"
 )
@@ -35,6 +36,21 @@ type Signer struct {
 	Weight  Threshold
 }
 
+// NewSignedPayloadSigner builds a Signer for a signed payload signer key
+// ("P..." address), which is satisfied by a signature of payload by the
+// ed25519 key signerAddress.
+func NewSignedPayloadSigner(signerAddress string, payload []byte, weight Threshold) (*Signer, error) {
+	sp, err := strkey.NewSignedPayload(signerAddress, payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signed payload")
+	}
+	address, err := sp.Encode()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode signed payload")
+	}
+	return &Signer{Address: address, Weight: weight}, nil
+}
+
 // NewHomeDomain is syntactic sugar that makes instantiating SetOptions more convenient.
 func NewHomeDomain(hd string) *string {
 	return &hd
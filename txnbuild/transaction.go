@@ -11,6 +11,7 @@ package txnbuild
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -160,6 +161,28 @@ func concatHashX(signatures []xdr.DecoratedSignature, preimage []byte) ([]xdr.De
 	return append(extended, sig), nil
 }
 
+func concatSignedPayloads(signatures []xdr.DecoratedSignature, payload []byte, kps ...*keypair.Full) ([]xdr.DecoratedSignature, error) {
+	if len(payload) == 0 || len(payload) > strkey.MaxSignedPayloadLength {
+		return nil, errors.Errorf(
+			"payload must be between 1 and %d bytes", strkey.MaxSignedPayloadLength,
+		)
+	}
+	extended := make(
+		[]xdr.DecoratedSignature,
+		len(signatures),
+		len(signatures)+len(kps),
+	)
+	copy(extended, signatures)
+	for i, kp := range kps {
+		sig, err := kp.SignPayloadDecorated(payload)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign payload")
+		}
+		extended[len(signatures)+i] = sig
+	}
+	return extended, nil
+}
+
 func marshallBinary(e xdr.TransactionEnvelope, signatures []xdr.DecoratedSignature) ([]byte, error) {
 	switch e.Type {
 	case xdr.EnvelopeTypeEnvelopeTypeTx:
@@ -301,6 +324,18 @@ func (t *Transaction) Sign(network string, kps ...*keypair.Full) (*Transaction,
 	return t.clone(extendedSignatures), nil
 }
 
+// SignWithSigner returns a new Transaction instance which extends the current instance
+// with additional signatures produced by the given signers. Use it instead of Sign
+// when the secret keys are held outside the process, e.g. by a remote signing service.
+func (t *Transaction) SignWithSigner(ctx context.Context, network string, signers ...KeySigner) (*Transaction, error) {
+	extendedSignatures, err := concatSignaturesWith(ctx, t.envelope, network, t.Signatures(), signers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.clone(extendedSignatures), nil
+}
+
 // SignWithKeyString returns a new Transaction instance which extends the current instance
 // with additional signatures derived from the given list of private key strings.
 func (t *Transaction) SignWithKeyString(network string, keys ...string) (*Transaction, error) {
@@ -323,6 +358,18 @@ func (t *Transaction) SignHashX(preimage []byte) (*Transaction, error) {
 	return t.clone(extendedSignatures), nil
 }
 
+// SignPayload returns a new Transaction instance which extends the current instance
+// with signatures of payload, satisfying signed payload signers ("P..." addresses)
+// of the given keypairs.
+func (t *Transaction) SignPayload(payload []byte, kps ...*keypair.Full) (*Transaction, error) {
+	extendedSignatures, err := concatSignedPayloads(t.Signatures(), payload, kps...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.clone(extendedSignatures), nil
+}
+
 // AddSignatureDecorated returns a new Transaction instance which extends the current instance
 // with an additional decorated signature(s).
 func (t *Transaction) AddSignatureDecorated(signature ...xdr.DecoratedSignature) (*Transaction, error) {
@@ -497,6 +544,17 @@ func (t *FeeBumpTransaction) Sign(network string, kps ...*keypair.Full) (*FeeBum
 	return t.clone(extendedSignatures), nil
 }
 
+// SignWithSigner returns a new FeeBumpTransaction instance which extends the current instance
+// with additional signatures produced by the given signers.
+func (t *FeeBumpTransaction) SignWithSigner(ctx context.Context, network string, signers ...KeySigner) (*FeeBumpTransaction, error) {
+	extendedSignatures, err := concatSignaturesWith(ctx, t.envelope, network, t.Signatures(), signers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.clone(extendedSignatures), nil
+}
+
 // SignWithKeyString returns a new FeeBumpTransaction instance which extends the current instance
 // with additional signatures derived from the given list of private key strings.
 func (t *FeeBumpTransaction) SignWithKeyString(network string, keys ...string) (*FeeBumpTransaction, error) {
@@ -519,6 +577,18 @@ func (t *FeeBumpTransaction) SignHashX(preimage []byte) (*FeeBumpTransaction, er
 	return t.clone(extendedSignatures), nil
 }
 
+// SignPayload returns a new FeeBumpTransaction instance which extends the current instance
+// with signatures of payload, satisfying signed payload signers ("P..." addresses)
+// of the given keypairs.
+func (t *FeeBumpTransaction) SignPayload(payload []byte, kps ...*keypair.Full) (*FeeBumpTransaction, error) {
+	extendedSignatures, err := concatSignedPayloads(t.Signatures(), payload, kps...)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.clone(extendedSignatures), nil
+}
+
 // AddSignatureBase64 returns a new FeeBumpTransaction instance which extends the current instance
 // with an additional signature derived from the given base64-encoded signature.
 func (t *FeeBumpTransaction) AddSignatureBase64(network, publicKey, signature string) (*FeeBumpTransaction, error) {
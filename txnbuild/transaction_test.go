@@ -1360,6 +1360,64 @@ func TestHashXTransaction(t *testing.T) {
 
 }
 
+func TestSignedPayloadTransaction(t *testing.T) {
+	kp0 := newKeypair0()
+	kp1 := newKeypair1()
+	payload := []byte("signed payload for the diamcircle network")
+
+	signer, err := NewSignedPayloadSigner(kp1.Address(), payload, Threshold(1))
+	assert.NoError(t, err)
+	assert.True(t, strkey.IsValidSignedPayload(signer.Address))
+
+	_, err = NewSignedPayloadSigner(kp1.Address(), nil, Threshold(1))
+	assert.Error(t, err)
+
+	sourceAccount := NewSimpleAccount(kp0.Address(), int64(9605939170639897))
+	setOptions := SetOptions{Signer: signer}
+	revoke := RevokeSponsorship{
+		SponsorshipType: RevokeSponsorshipTypeSigner,
+		Signer: &SignerID{
+			AccountID:     kp0.Address(),
+			SignerAddress: signer.Address,
+		},
+	}
+
+	tx, err := NewTransaction(
+		TransactionParams{
+			SourceAccount:        &sourceAccount,
+			IncrementSequenceNum: true,
+			Operations:           []Operation{&setOptions, &revoke},
+			BaseFee:              MinBaseFee,
+			Timebounds:           NewInfiniteTimeout(),
+		},
+	)
+	assert.NoError(t, err)
+
+	tx, err = tx.SignPayload(payload, kp1)
+	assert.NoError(t, err)
+	assert.Len(t, tx.Signatures(), 1)
+	assert.NoError(t, keypair.VerifySignedPayload(signer.Address, tx.Signatures()[0]))
+
+	_, err = tx.SignPayload(make([]byte, 65), kp1)
+	assert.EqualError(t, err, "payload must be between 1 and 64 bytes")
+
+	txeB64, err := tx.Base64()
+	assert.NoError(t, err)
+
+	parsed, err := TransactionFromXDR(txeB64)
+	assert.NoError(t, err)
+	parsedTx, ok := parsed.Transaction()
+	assert.True(t, ok)
+
+	parsedSetOptions, ok := parsedTx.Operations()[0].(*SetOptions)
+	assert.True(t, ok)
+	assert.Equal(t, signer, parsedSetOptions.Signer)
+
+	parsedRevoke, ok := parsedTx.Operations()[1].(*RevokeSponsorship)
+	assert.True(t, ok)
+	assert.Equal(t, signer.Address, parsedRevoke.Signer.SignerAddress)
+}
+
 func TestFromXDR(t *testing.T) {
 	txeB64 := "AAAAACYWIvM98KlTMs0IlQBZ06WkYpZ+gILsQN6ega0++I/sAAAAZAAXeEkAAAABAAAAAAAAAAEAAAAQMkExVjZKNTcwM0c0N1hIWQAAAAEAAAABAAAAACYWIvM98KlTMs0IlQBZ06WkYpZ+gILsQN6ega0++I/sAAAAAQAAAADMSEvcRKXsaUNna++Hy7gWm/CfqTjEA7xoGypfrFGUHAAAAAAAAAACCPHRAAAAAAAAAAABPviP7AAAAEBu6BCKf4WZHPum5+29Nxf6SsJNN8bgjp1+e1uNBaHjRg3rdFZYgUqEqbHxVEs7eze3IeRbjMZxS3zPf/xwJCEI"
 
@@ -1,6 +1,7 @@
 package xdr
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/diamcircle/go/strkey"
@@ -40,6 +41,17 @@ func (skey *SignerKey) GetAddress() (string, error) {
 		vb = strkey.VersionByteHashTx
 		key := skey.MustPreAuthTx()
 		copy(raw, key[:])
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		sp := skey.MustEd25519SignedPayload()
+		signer, err := strkey.Encode(strkey.VersionByteAccountID, sp.Ed25519[:])
+		if err != nil {
+			return "", err
+		}
+		payload, err := strkey.NewSignedPayload(signer, sp.Payload)
+		if err != nil {
+			return "", err
+		}
+		return payload.Encode()
 	default:
 		return "", fmt.Errorf("unknown signer key type: %v", skey.Type)
 	}
@@ -66,6 +78,10 @@ func (skey *SignerKey) Equals(other SignerKey) bool {
 		l := skey.MustPreAuthTx()
 		r := other.MustPreAuthTx()
 		return l == r
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		l := skey.MustEd25519SignedPayload()
+		r := other.MustEd25519SignedPayload()
+		return l.Ed25519 == r.Ed25519 && bytes.Equal(l.Payload, r.Payload)
 	default:
 		panic(fmt.Errorf("Unknown signer key type: %v", skey.Type))
 	}
@@ -101,6 +117,20 @@ func (skey *SignerKey) SetAddress(address string) error {
 		keytype = SignerKeyTypeSignerKeyTypeHashX
 	case strkey.VersionByteHashTx:
 		keytype = SignerKeyTypeSignerKeyTypePreAuthTx
+	case strkey.VersionByteSignedPayload:
+		sp, err := strkey.DecodeSignedPayload(address)
+		if err != nil {
+			return err
+		}
+
+		var signer Uint256
+		copy(signer[:], strkey.MustDecode(strkey.VersionByteAccountID, sp.Signer()))
+
+		*skey, err = NewSignerKey(SignerKeyTypeSignerKeyTypeEd25519SignedPayload, SignerKeyEd25519SignedPayload{
+			Ed25519: signer,
+			Payload: sp.Payload(),
+		})
+		return err
 	default:
 		return errors.Errorf("invalid version byte: %v", vb)
 	}
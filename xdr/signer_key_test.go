@@ -28,6 +28,10 @@ func TestSignerKey_GetAddress(t *testing.T) {
 			"HashX",
 			"XBU2RRGLXH3E5CQHTD3ODLDF2BWDCYUSSBLLZ5GNW7JXHDIYKXZWGTOG",
 		},
+		{
+			"SignedPayload",
+			"PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAQACAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUPB6IBZGM",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -64,6 +68,10 @@ func TestSignerKey_SetAddress(t *testing.T) {
 			Name:    "HashX",
 			Address: "XBU2RRGLXH3E5CQHTD3ODLDF2BWDCYUSSBLLZ5GNW7JXHDIYKXZWGTOG",
 		},
+		{
+			Name:    "SignedPayload",
+			Address: "PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAQACAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUPB6IBZGM",
+		},
 	}
 
 	for _, kase := range cases {
@@ -80,3 +88,17 @@ func TestSignerKey_SetAddress(t *testing.T) {
 	err := dest.SetAddress("SBU2RRGLXH3E5CQHTD3ODLDF2BWDCYUSSBLLZ5GNW7JXHDIYKXZWHOKR")
 	assert.Error(t, err)
 }
+
+func TestSignerKey_SignedPayloadXDR(t *testing.T) {
+	signer := MustSigner("PA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJUAAAAAQACAQDAQCQMBYIBEFAWDANBYHRAEISCMKBKFQXDAMRUGY4DUPB6IBZGM")
+	assert.Equal(t, SignerKeyTypeSignerKeyTypeEd25519SignedPayload, signer.Type)
+	assert.Len(t, signer.MustEd25519SignedPayload().Payload, 32)
+
+	b64, err := MarshalBase64(signer)
+	assert.NoError(t, err)
+
+	var decoded SignerKey
+	assert.NoError(t, SafeUnmarshalBase64(b64, &decoded))
+	assert.True(t, signer.Equals(decoded))
+	assert.Equal(t, signer.Address(), decoded.Address())
+}
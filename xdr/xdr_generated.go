@@ -33667,6 +33667,7 @@ var _ xdrType = (*Int64)(nil)
 //        KEY_TYPE_ED25519 = 0,
 //        KEY_TYPE_PRE_AUTH_TX = 1,
 //        KEY_TYPE_HASH_X = 2,
+//        KEY_TYPE_ED25519_SIGNED_PAYLOAD = 3,
 //        // MUXED enum values for supported type are derived from the enum values
 //        // above by ORing them with 0x100
 //        KEY_TYPE_MUXED_ED25519 = 0x100
@@ -33675,16 +33676,18 @@ var _ xdrType = (*Int64)(nil)
 type CryptoKeyType int32
 
 const (
-	CryptoKeyTypeKeyTypeEd25519      CryptoKeyType = 0
-	CryptoKeyTypeKeyTypePreAuthTx    CryptoKeyType = 1
-	CryptoKeyTypeKeyTypeHashX        CryptoKeyType = 2
-	CryptoKeyTypeKeyTypeMuxedEd25519 CryptoKeyType = 256
+	CryptoKeyTypeKeyTypeEd25519              CryptoKeyType = 0
+	CryptoKeyTypeKeyTypePreAuthTx            CryptoKeyType = 1
+	CryptoKeyTypeKeyTypeHashX                CryptoKeyType = 2
+	CryptoKeyTypeKeyTypeEd25519SignedPayload CryptoKeyType = 3
+	CryptoKeyTypeKeyTypeMuxedEd25519         CryptoKeyType = 256
 )
 
 var cryptoKeyTypeMap = map[int32]string{
 	0:   "CryptoKeyTypeKeyTypeEd25519",
 	1:   "CryptoKeyTypeKeyTypePreAuthTx",
 	2:   "CryptoKeyTypeKeyTypeHashX",
+	3:   "CryptoKeyTypeKeyTypeEd25519SignedPayload",
 	256: "CryptoKeyTypeKeyTypeMuxedEd25519",
 }
 
@@ -33839,21 +33842,24 @@ var _ xdrType = (*PublicKeyType)(nil)
 //    {
 //        SIGNER_KEY_TYPE_ED25519 = KEY_TYPE_ED25519,
 //        SIGNER_KEY_TYPE_PRE_AUTH_TX = KEY_TYPE_PRE_AUTH_TX,
-//        SIGNER_KEY_TYPE_HASH_X = KEY_TYPE_HASH_X
+//        SIGNER_KEY_TYPE_HASH_X = KEY_TYPE_HASH_X,
+//        SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD = KEY_TYPE_ED25519_SIGNED_PAYLOAD
 //    };
 //
 type SignerKeyType int32
 
 const (
-	SignerKeyTypeSignerKeyTypeEd25519   SignerKeyType = 0
-	SignerKeyTypeSignerKeyTypePreAuthTx SignerKeyType = 1
-	SignerKeyTypeSignerKeyTypeHashX     SignerKeyType = 2
+	SignerKeyTypeSignerKeyTypeEd25519              SignerKeyType = 0
+	SignerKeyTypeSignerKeyTypePreAuthTx            SignerKeyType = 1
+	SignerKeyTypeSignerKeyTypeHashX                SignerKeyType = 2
+	SignerKeyTypeSignerKeyTypeEd25519SignedPayload SignerKeyType = 3
 )
 
 var signerKeyTypeMap = map[int32]string{
 	0: "SignerKeyTypeSignerKeyTypeEd25519",
 	1: "SignerKeyTypeSignerKeyTypePreAuthTx",
 	2: "SignerKeyTypeSignerKeyTypeHashX",
+	3: "SignerKeyTypeSignerKeyTypeEd25519SignedPayload",
 }
 
 // ValidEnum validates a proposed value for this enum.  Implements
@@ -34056,6 +34062,79 @@ func (s PublicKey) xdrType() {}
 
 var _ xdrType = (*PublicKey)(nil)
 
+// SignerKeyEd25519SignedPayload is an XDR NestedStruct defines as:
+//
+//   struct
+//        {
+//            /* Public key that must sign the payload. */
+//            uint256 ed25519;
+//            /* Payload to be raw signed by ed25519. */
+//            opaque payload<64>;
+//        }
+//
+type SignerKeyEd25519SignedPayload struct {
+	Ed25519 Uint256
+	Payload []byte `xdrmaxsize:"64"`
+}
+
+// EncodeTo encodes this value using the Encoder.
+func (s *SignerKeyEd25519SignedPayload) EncodeTo(e *xdr.Encoder) error {
+	var err error
+	if err = s.Ed25519.EncodeTo(e); err != nil {
+		return err
+	}
+	if _, err = e.EncodeOpaque(s.Payload[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+var _ decoderFrom = (*SignerKeyEd25519SignedPayload)(nil)
+
+// DecodeFrom decodes this value using the Decoder.
+func (s *SignerKeyEd25519SignedPayload) DecodeFrom(d *xdr.Decoder) (int, error) {
+	var err error
+	var n, nTmp int
+	nTmp, err = s.Ed25519.DecodeFrom(d)
+	n += nTmp
+	if err != nil {
+		return n, fmt.Errorf("decoding Uint256: %s", err)
+	}
+	s.Payload, nTmp, err = d.DecodeOpaque(64)
+	n += nTmp
+	if err != nil {
+		return n, fmt.Errorf("decoding Payload: %s", err)
+	}
+	return n, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s SignerKeyEd25519SignedPayload) MarshalBinary() ([]byte, error) {
+	b := bytes.Buffer{}
+	e := xdr.NewEncoder(&b)
+	err := s.EncodeTo(e)
+	return b.Bytes(), err
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *SignerKeyEd25519SignedPayload) UnmarshalBinary(inp []byte) error {
+	r := bytes.NewReader(inp)
+	d := xdr.NewDecoder(r)
+	_, err := s.DecodeFrom(d)
+	return err
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*SignerKeyEd25519SignedPayload)(nil)
+	_ encoding.BinaryUnmarshaler = (*SignerKeyEd25519SignedPayload)(nil)
+)
+
+// xdrType signals that this type is an type representing
+// representing XDR values defined by this package.
+func (s SignerKeyEd25519SignedPayload) xdrType() {}
+
+var _ xdrType = (*SignerKeyEd25519SignedPayload)(nil)
+
 // SignerKey is an XDR Union defines as:
 //
 //   union SignerKey switch (SignerKeyType type)
@@ -34068,13 +34147,22 @@ var _ xdrType = (*PublicKey)(nil)
 //    case SIGNER_KEY_TYPE_HASH_X:
 //        /* Hash of random 256 bit preimage X */
 //        uint256 hashX;
+//    case SIGNER_KEY_TYPE_ED25519_SIGNED_PAYLOAD:
+//        struct
+//        {
+//            /* Public key that must sign the payload. */
+//            uint256 ed25519;
+//            /* Payload to be raw signed by ed25519. */
+//            opaque payload<64>;
+//        } ed25519SignedPayload;
 //    };
 //
 type SignerKey struct {
-	Type      SignerKeyType
-	Ed25519   *Uint256
-	PreAuthTx *Uint256
-	HashX     *Uint256
+	Type                 SignerKeyType
+	Ed25519              *Uint256
+	PreAuthTx            *Uint256
+	HashX                *Uint256
+	Ed25519SignedPayload *SignerKeyEd25519SignedPayload
 }
 
 // SwitchFieldName returns the field name in which this union's
@@ -34093,6 +34181,8 @@ func (u SignerKey) ArmForSwitch(sw int32) (string, bool) {
 		return "PreAuthTx", true
 	case SignerKeyTypeSignerKeyTypeHashX:
 		return "HashX", true
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		return "Ed25519SignedPayload", true
 	}
 	return "-", false
 }
@@ -34122,6 +34212,13 @@ func NewSignerKey(aType SignerKeyType, value interface{}) (result SignerKey, err
 			return
 		}
 		result.HashX = &tv
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		tv, ok := value.(SignerKeyEd25519SignedPayload)
+		if !ok {
+			err = fmt.Errorf("invalid value, must be SignerKeyEd25519SignedPayload")
+			return
+		}
+		result.Ed25519SignedPayload = &tv
 	}
 	return
 }
@@ -34201,6 +34298,31 @@ func (u SignerKey) GetHashX() (result Uint256, ok bool) {
 	return
 }
 
+// MustEd25519SignedPayload retrieves the Ed25519SignedPayload value from the union,
+// panicing if the value is not set.
+func (u SignerKey) MustEd25519SignedPayload() SignerKeyEd25519SignedPayload {
+	val, ok := u.GetEd25519SignedPayload()
+
+	if !ok {
+		panic("arm Ed25519SignedPayload is not set")
+	}
+
+	return val
+}
+
+// GetEd25519SignedPayload retrieves the Ed25519SignedPayload value from the union,
+// returning ok if the union's switch indicated the value is valid.
+func (u SignerKey) GetEd25519SignedPayload() (result SignerKeyEd25519SignedPayload, ok bool) {
+	armName, _ := u.ArmForSwitch(int32(u.Type))
+
+	if armName == "Ed25519SignedPayload" {
+		result = *u.Ed25519SignedPayload
+		ok = true
+	}
+
+	return
+}
+
 // EncodeTo encodes this value using the Encoder.
 func (u SignerKey) EncodeTo(e *xdr.Encoder) error {
 	var err error
@@ -34223,6 +34345,11 @@ func (u SignerKey) EncodeTo(e *xdr.Encoder) error {
 			return err
 		}
 		return nil
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		if err = (*u.Ed25519SignedPayload).EncodeTo(e); err != nil {
+			return err
+		}
+		return nil
 	}
 	return fmt.Errorf("Type (SignerKeyType) switch value '%d' is not valid for union SignerKey", u.Type)
 }
@@ -34263,6 +34390,14 @@ func (u *SignerKey) DecodeFrom(d *xdr.Decoder) (int, error) {
 			return n, fmt.Errorf("decoding Uint256: %s", err)
 		}
 		return n, nil
+	case SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		u.Ed25519SignedPayload = new(SignerKeyEd25519SignedPayload)
+		nTmp, err = (*u.Ed25519SignedPayload).DecodeFrom(d)
+		n += nTmp
+		if err != nil {
+			return n, fmt.Errorf("decoding SignerKeyEd25519SignedPayload: %s", err)
+		}
+		return n, nil
 	}
 	return n, fmt.Errorf("union SignerKey has invalid Type (SignerKeyType) switch value '%d'", u.Type)
 }